@@ -35,6 +35,9 @@ Example:
   uniroute keys create --name "My API Key"
   uniroute keys create --name "Long-lived"   (no expiration = never expires)
   uniroute keys create --expires-at 2030-12-31
+  uniroute keys create --budget 50                      ($50/month hard limit)
+  uniroute keys create --budget 5 --budget-period daily --budget-soft 4
+  uniroute keys create --token-limit-minute 100000 --token-limit-day 2000000
   uniroute keys create --url http://localhost:8084 --jwt-token YOUR_JWT`,
 	RunE: runKeysCreate,
 }
@@ -81,6 +84,13 @@ var (
 	keysExpiresAt       string
 	keysRateLimitMin    int
 	keysRateLimitDay    int
+	keysBudget          float64
+	keysBudgetSoft      float64
+	keysBudgetPeriod    string
+	keysTokenLimitMin   int
+	keysTokenLimitDay   int
+	keysHedgePercentile int
+	keysRedactPII       []string
 )
 
 func init() {
@@ -95,6 +105,13 @@ func init() {
 	keysCreateCmd.Flags().StringVar(&keysExpiresAt, "expires-at", "", "Expiration date (YYYY-MM-DD); omit for no expiration (key lasts until revoked)")
 	keysCreateCmd.Flags().IntVar(&keysRateLimitMin, "rate-limit-minute", 0, "Rate limit per minute (default 60)")
 	keysCreateCmd.Flags().IntVar(&keysRateLimitDay, "rate-limit-day", 0, "Rate limit per day (default 10000)")
+	keysCreateCmd.Flags().Float64Var(&keysBudget, "budget", 0, "Spend limit in USD; requests are rejected once it is reached")
+	keysCreateCmd.Flags().Float64Var(&keysBudgetSoft, "budget-soft", 0, "Spend in USD after which responses carry a budget warning")
+	keysCreateCmd.Flags().StringVar(&keysBudgetPeriod, "budget-period", "monthly", "Budget period: daily, monthly or lifetime")
+	keysCreateCmd.Flags().IntVar(&keysTokenLimitMin, "token-limit-minute", 0, "Tokens per minute (default unlimited)")
+	keysCreateCmd.Flags().IntVar(&keysTokenLimitDay, "token-limit-day", 0, "Tokens per day (default unlimited)")
+	keysCreateCmd.Flags().IntVar(&keysHedgePercentile, "hedge-percentile", 0, "Hedge chat requests slower than this latency percentile, 1-99 (default off)")
+	keysCreateCmd.Flags().StringSliceVar(&keysRedactPII, "redact-pii", nil, "Redact PII from prompts sent to cloud providers: all, or any of email,phone,credit_card,national_id")
 
 	keysListCmd.Flags().StringVarP(&keysURL, "url", "u", "", "Gateway server URL (default: public UniRoute server)")
 	keysListCmd.Flags().StringVarP(&keysJWTToken, "jwt-token", "t", "", "JWT token for authentication")
@@ -134,6 +151,24 @@ func runKeysCreate(cmd *cobra.Command, args []string) error {
 	if keysRateLimitDay > 0 {
 		body["rate_limit_per_day"] = keysRateLimitDay
 	}
+	if keysTokenLimitMin > 0 {
+		body["token_limit_per_minute"] = keysTokenLimitMin
+	}
+	if keysTokenLimitDay > 0 {
+		body["token_limit_per_day"] = keysTokenLimitDay
+	}
+	if keysHedgePercentile > 0 {
+		body["hedge_percentile"] = keysHedgePercentile
+	}
+	if len(keysRedactPII) > 0 {
+		types := []string{}
+		for _, t := range keysRedactPII {
+			if !strings.EqualFold(t, "all") {
+				types = append(types, t)
+			}
+		}
+		body["pii_policy"] = map[string]interface{}{"types": types, "skip_providers": nil}
+	}
 	if keysExpiresAt != "" {
 		var expiresAt time.Time
 		if t, err := time.Parse(time.RFC3339, keysExpiresAt); err == nil {
@@ -145,6 +180,16 @@ func runKeysCreate(cmd *cobra.Command, args []string) error {
 		}
 		body["expires_at"] = expiresAt.Format(time.RFC3339)
 	}
+	if keysBudget > 0 || keysBudgetSoft > 0 {
+		budget := map[string]interface{}{"period": keysBudgetPeriod}
+		if keysBudget > 0 {
+			budget["limit_usd"] = keysBudget
+		}
+		if keysBudgetSoft > 0 {
+			budget["soft_limit_usd"] = keysBudgetSoft
+		}
+		body["budgets"] = []interface{}{budget}
+	}
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -192,6 +237,9 @@ func runKeysCreate(cmd *cobra.Command, args []string) error {
 	if id, ok := result["id"].(string); ok {
 		fmt.Printf("ID: %s\n", id)
 	}
+	if budgets, ok := result["budgets"].([]interface{}); ok {
+		printBudgets(budgets)
+	}
 
 	return nil
 }
@@ -295,12 +343,61 @@ func runKeysList(cmd *cobra.Command, args []string) error {
 				fmt.Println()
 			}
 		}
+		tokensPerMinute, _ := keyMap["token_limit_per_minute"].(float64)
+		tokensPerDay, _ := keyMap["token_limit_per_day"].(float64)
+		if tokensPerMinute > 0 || tokensPerDay > 0 {
+			fmt.Printf("   Token Limit: %s/min, %s/day\n", formatTokenLimit(tokensPerMinute), formatTokenLimit(tokensPerDay))
+		}
+		if hedgePercentile, ok := keyMap["hedge_percentile"].(float64); ok && hedgePercentile > 0 {
+			fmt.Printf("   Hedging: after p%.0f latency\n", hedgePercentile)
+		}
+		if policy, ok := keyMap["pii_policy"].(map[string]interface{}); ok {
+			types, _ := policy["types"].([]interface{})
+			if len(types) == 0 {
+				fmt.Println("   PII Redaction: all types")
+			} else {
+				fmt.Printf("   PII Redaction: %v\n", types)
+			}
+		}
+		if budgets, ok := keyMap["budgets"].([]interface{}); ok {
+			printBudgets(budgets)
+		}
 	}
 	fmt.Println()
 
 	return nil
 }
 
+func formatTokenLimit(limit float64) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%.0f", limit)
+}
+
+func printBudgets(budgets []interface{}) {
+	for _, b := range budgets {
+		budget, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var parts []string
+		if spent, ok := budget["spent_usd"].(float64); ok {
+			parts = append(parts, fmt.Sprintf("$%.4f spent", spent))
+		}
+		if limit, ok := budget["limit_usd"].(float64); ok {
+			parts = append(parts, fmt.Sprintf("limit $%.2f", limit))
+		}
+		if soft, ok := budget["soft_limit_usd"].(float64); ok {
+			parts = append(parts, fmt.Sprintf("warn at $%.2f", soft))
+		}
+		if reached, ok := budget["limit_reached"].(bool); ok && reached {
+			parts = append(parts, "LIMIT REACHED")
+		}
+		fmt.Printf("   Budget (%v): %s\n", budget["period"], strings.Join(parts, ", "))
+	}
+}
+
 func runKeysRevoke(cmd *cobra.Command, args []string) error {
 	keyID := args[0]
 
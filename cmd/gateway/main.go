@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/rs/zerolog"
 
@@ -51,6 +52,9 @@ func main() {
 	var rateLimiter *security.RateLimiter
 	var authRateLimiter *security.AuthRateLimiter
 	var postgresClient *storage.PostgresClient
+	var responseCache *gateway.ResponseCache
+	var budgetTracker *security.BudgetTracker
+	var spendStore security.SpendStore
 
 	if cfg.DatabaseURL != "" && cfg.RedisURL != "" {
 		log.Info().Msg("Initializing database and Redis services...")
@@ -61,6 +65,8 @@ func main() {
 		} else {
 			rateLimiter = security.NewRateLimiter(redisClient)
 			authRateLimiter = security.NewAuthRateLimiter(redisClient)
+			responseCache = gateway.NewResponseCache(gateway.NewRedisCacheStore(redisClient))
+			spendStore = security.NewRedisSpendStore(redisClient)
 			log.Info().Msg("Redis connected - rate limiting enabled")
 		}
 
@@ -74,6 +80,9 @@ func main() {
 			apiKeyRepo := storage.NewAPIKeyRepository(postgresClient.Pool())
 			apiKeyServiceV2 = security.NewAPIKeyServiceV2(apiKeyRepo, cfg.APIKeySecret)
 			log.Info().Msg("PostgreSQL connected - database-backed API keys enabled")
+			if spendStore != nil {
+				budgetTracker = security.NewBudgetTracker(spendStore, storage.NewBudgetRepository(postgresClient.Pool()))
+			}
 		}
 
 		if cfg.JWTSecret != "" {
@@ -96,10 +105,47 @@ func main() {
 	}
 
 	router := gateway.NewRouter()
+	if responseCache != nil {
+		router.SetResponseCache(responseCache)
+	}
+
+	if cfg.TokenizerDataDir != "" {
+		loaded, err := router.GetTokenizers().LoadDir(cfg.TokenizerDataDir)
+		if err != nil {
+			log.Warn().Err(err).Str("dir", cfg.TokenizerDataDir).Msg("Failed to load tokenizer tables, OpenAI token counts will be estimated")
+		} else {
+			log.Info().Strs("encodings", loaded).Msg("Tokenizer tables loaded")
+		}
+	}
+	for pattern, path := range cfg.TokenizerModels {
+		if err := router.GetTokenizers().RegisterFile(pattern, path); err != nil {
+			log.Warn().Err(err).Msg("Failed to load model tokenizer")
+		}
+	}
+
+	var pricingSources []gateway.PricingSource
+	if cfg.PricingCatalogPath != "" {
+		pricingSources = append(pricingSources, gateway.NewFilePricingSource(cfg.PricingCatalogPath))
+	}
+	if postgresClient != nil {
+		pricingSources = append(pricingSources, gateway.NewDatabasePricingSource(storage.NewModelPriceRepository(postgresClient.Pool())))
+	}
+	pricingLoader := gateway.NewPricingLoader(router.GetCostCalculator(), time.Duration(cfg.PricingReloadInterval)*time.Second, log, pricingSources...)
+	if _, err := pricingLoader.Reload(context.Background()); err != nil {
+		log.Warn().Err(err).Msg("Failed to load pricing catalog, using built-in prices")
+	}
+	pricingLoader.Start(context.Background())
+	router.SetPricingLoader(pricingLoader)
 
 	if postgresClient != nil {
 		settingsRepo := storage.NewSystemSettingsRepository(postgresClient.Pool())
 		ctx := context.Background()
+		if disabled, err := settingsRepo.GetDisabledProviders(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to load disabled providers")
+		} else if len(disabled) > 0 {
+			router.SetDisabledProviders(disabled)
+			log.Info().Strs("providers", disabled).Msg("Loaded providers disabled by an admin")
+		}
 		strategy, err := settingsRepo.GetDefaultRoutingStrategy(ctx)
 		if err == nil && strategy != "" {
 			strategyType := gateway.StrategyType(strategy)
@@ -112,42 +158,7 @@ func main() {
 				customRulesRepo := storage.NewCustomRoutingRulesRepository(postgresClient.Pool())
 				rules, err := customRulesRepo.GetActiveRules(ctx)
 				if err == nil && len(rules) > 0 {
-					costCalculator := router.GetCostCalculator()
-					latencyTracker := router.GetLatencyTracker()
-					routingRules := make([]gateway.RoutingRule, 0, len(rules))
-					for _, rule := range rules {
-						condition := func(rule *storage.CustomRoutingRule, costCalc *gateway.CostCalculator, latTracker *gateway.LatencyTracker) func(providers.ChatRequest) bool {
-							return func(req providers.ChatRequest) bool {
-								switch rule.ConditionType {
-								case "model":
-									if model, ok := rule.ConditionValue["model"].(string); ok {
-										return req.Model == model
-									}
-								case "cost_threshold":
-									if maxCost, ok := rule.ConditionValue["max_cost"].(float64); ok {
-										estimatedCost := costCalc.EstimateCost(rule.ProviderName, req.Model, req.Messages)
-										return estimatedCost <= maxCost
-									}
-									return false
-								case "latency_threshold":
-									if maxLatencyMs, ok := rule.ConditionValue["max_latency_ms"].(float64); ok {
-										avgLatency := latTracker.GetAverageLatency(rule.ProviderName)
-										return avgLatency.Milliseconds() <= int64(maxLatencyMs)
-									}
-									return false
-								}
-								return false
-							}
-						}(rule, costCalculator, latencyTracker)
-
-						routingRule := gateway.RoutingRule{
-							Provider:  rule.ProviderName,
-							Priority:  rule.Priority,
-							Condition: condition,
-						}
-						routingRules = append(routingRules, routingRule)
-					}
-
+					routingRules := router.GetRuleEngine().BuildRoutingRules(gateway.CustomRulesFromStorage(rules))
 					customStrategy := gateway.NewCustomStrategy(routingRules)
 					router.SetCustomStrategy(customStrategy)
 					log.Info().
@@ -212,10 +223,89 @@ func main() {
 			Msg("Registered vLLM provider")
 	}
 
+	providers.SetAzureBYOKHosts(cfg.AzureOpenAIBYOKHosts)
+	if cfg.AzureOpenAIAPIKey != "" && cfg.AzureOpenAIEndpoint != "" {
+		azureConfig := providers.AzureOpenAIConfig{
+			Endpoint:    cfg.AzureOpenAIEndpoint,
+			APIVersion:  cfg.AzureOpenAIAPIVersion,
+			Deployments: cfg.AzureOpenAIDeployments,
+		}
+		if err := azureConfig.Validate(); err != nil {
+			log.Fatal().Err(err).Msg("Invalid Azure OpenAI configuration")
+		}
+		router.RegisterProvider(providers.NewAzureOpenAIProvider(cfg.AzureOpenAIAPIKey, azureConfig, log))
+		log.Info().
+			Str("provider", "azure").
+			Str("endpoint", cfg.AzureOpenAIEndpoint).
+			Msg("Registered Azure OpenAI provider")
+	} else {
+		log.Debug().Msg("Azure OpenAI key or endpoint not configured, skipping Azure OpenAI provider")
+	}
+
+	var compatibleBYOK []providers.OpenAICompatibleConfig
+	if cfg.CompatibleProvidersPath != "" {
+		compatibleConfig, err := providers.LoadOpenAICompatibleConfig(cfg.CompatibleProvidersPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load OpenAI-compatible providers")
+		}
+		for _, upstream := range compatibleConfig.Providers {
+			if !upstream.IsEnabled() {
+				log.Debug().Str("provider", upstream.Name).Msg("OpenAI-compatible provider disabled in config, skipping")
+				continue
+			}
+			if upstream.BYOK {
+				compatibleBYOK = append(compatibleBYOK, upstream)
+			}
+			apiKey := upstream.APIKey()
+			if upstream.APIKeyEnv != "" && apiKey == "" {
+				log.Debug().Str("provider", upstream.Name).Str("api_key_env", upstream.APIKeyEnv).Msg("API key not set, skipping server provider")
+				continue
+			}
+			router.RegisterProvider(providers.NewOpenAICompatibleProvider(upstream, apiKey, log))
+			log.Info().
+				Str("provider", upstream.Name).
+				Str("base_url", upstream.BaseURL).
+				Msg("Registered OpenAI-compatible provider")
+		}
+		router.SetCompatibleBYOK(compatibleBYOK)
+	}
+
+	modelCatalog := providers.NewModelCatalog(time.Duration(cfg.ModelDiscoveryTTL) * time.Second)
+	for name, allow := range cfg.ModelAllowlist {
+		modelCatalog.SetModelFilter(name, allow, cfg.ModelDenylist[name])
+	}
+	for name, deny := range cfg.ModelDenylist {
+		if _, ok := cfg.ModelAllowlist[name]; !ok {
+			modelCatalog.SetModelFilter(name, nil, deny)
+		}
+	}
+	router.SetModelCatalog(modelCatalog)
+
 	log.Info().
 		Strs("providers", router.ListProviders()).
 		Msg("All providers registered")
 
+	go func() {
+		// Fill the model catalog so the first requests do not wait on list-models calls.
+		for _, name := range router.ListProviders() {
+			if provider, err := router.GetProvider(name); err == nil {
+				provider.GetModels()
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		for name, err := range router.DiscoverCapabilities(ctx) {
+			log.Warn().Err(err).Str("provider", name).Msg("Failed to discover model capabilities, using built-in data")
+		}
+	}()
+
+	if cfg.ProviderHealthInterval > 0 {
+		gateway.NewHealthProber(router, time.Duration(cfg.ProviderHealthInterval)*time.Second).Start(context.Background())
+		log.Info().
+			Int("interval_seconds", cfg.ProviderHealthInterval).
+			Msg("Provider health prober started")
+	}
+
 	var requestRepo *storage.RequestRepository
 	if postgresClient != nil {
 		requestRepo = storage.NewRequestRepository(postgresClient.Pool())
@@ -235,6 +325,9 @@ func main() {
 			log.Warn().Err(err).Msg("Failed to initialize provider key service, BYOK disabled")
 		} else {
 			providerKeyService = service
+			for _, upstream := range compatibleBYOK {
+				service.AddProviders(upstream.Name)
+			}
 			router.SetProviderKeyService(service)
 			log.Info().Msg("Provider key service initialized - BYOK enabled")
 		}
@@ -253,6 +346,58 @@ func main() {
 		customRulesService := gateway.NewCustomRulesServiceAdapter(customRulesRepo, costCalculator, latencyTracker)
 		router.SetCustomRulesService(customRulesService)
 		log.Info().Msg("Custom rules service initialized - user-specific custom routing rules enabled")
+
+		router.SetModelAliasService(gateway.NewModelAliasServiceAdapter(storage.NewModelAliasRepository(postgresClient.Pool())))
+		router.SetExperimentService(gateway.NewExperimentServiceAdapter(storage.NewExperimentRepository(postgresClient.Pool())))
+	}
+
+	if cfg.ShadowConfigPath != "" {
+		shadowConfig, err := gateway.LoadShadowConfig(cfg.ShadowConfigPath)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to load shadow config, shadow traffic disabled")
+		} else {
+			var recorder gateway.ShadowRecorder
+			if postgresClient != nil {
+				recorder = gateway.NewShadowRecorderAdapter(storage.NewShadowRequestRepository(postgresClient.Pool()))
+			} else {
+				log.Warn().Msg("No database configured, shadow responses will only be counted in metrics")
+			}
+			for _, rule := range shadowConfig.Shadows {
+				if _, err := router.GetProvider(rule.Provider); err != nil {
+					log.Warn().Str("model", rule.Model).Str("provider", rule.Provider).Msg("Shadow provider is not registered")
+				}
+			}
+			router.SetShadowMirror(gateway.NewShadowMirror(shadowConfig, recorder, log))
+			log.Info().Int("rules", len(shadowConfig.Shadows)).Msg("Shadow traffic enabled")
+		}
+	}
+
+	if !gateway.ValidContextStrategy(cfg.ContextStrategy) {
+		log.Fatal().Str("strategy", cfg.ContextStrategy).Msg("CONTEXT_STRATEGY must be off, reject, truncate or summarize")
+	}
+	router.SetContextConfig(gateway.ContextConfig{
+		Strategy:         cfg.ContextStrategy,
+		SummaryModel:     cfg.ContextSummaryModel,
+		SummaryMaxTokens: cfg.ContextSummaryMaxTokens,
+		Windows:          cfg.ContextWindows,
+	})
+	if postgresClient != nil {
+		router.SetConversationSummaryStore(gateway.NewConversationSummaryAdapter(storage.NewConversationRepository(postgresClient.Pool())))
+	}
+	if cfg.ContextStrategy == gateway.ContextStrategySummarize && cfg.ContextSummaryModel == "" {
+		log.Warn().Msg("CONTEXT_SUMMARY_MODEL is not set, conversations over the context window will be truncated instead of summarized")
+	}
+
+	if cfg.HooksConfigPath != "" {
+		hookConfig, err := gateway.LoadHookConfig(cfg.HooksConfigPath)
+		if err != nil {
+			// Hooks may be guardrails, so serving without them is not an option.
+			log.Fatal().Err(err).Msg("Failed to load hooks config")
+		}
+		for _, webhook := range hookConfig.Webhooks {
+			router.AddHook(gateway.NewWebhookHook(webhook, log))
+		}
+		log.Info().Int("webhooks", len(hookConfig.Webhooks)).Msg("Request hooks enabled")
 	}
 
 	emailService := email.NewEmailService(log)
@@ -335,6 +480,8 @@ func main() {
 		jwtService,         // JWT authentication service
 		rateLimiter,        // Rate limiting service
 		authRateLimiter,    // Progressive rate limiting for auth endpoints
+		budgetTracker,      // USD spend budgets (nil without Redis and PostgreSQL)
+		security.TokenLimits{PerMinute: cfg.UserTokenLimitPerMinute, PerDay: cfg.UserTokenLimitPerDay},
 		cfg.IPWhitelist,    // IP whitelist configuration
 		requestRepo,        // Request repository for analytics
 		providerKeyService, // BYOK: Provider key service
@@ -69,7 +69,9 @@ func (h *AnalyticsHandler) GetUsageStats(c *gin.Context) {
 		"average_latency_ms": stats.AverageLatencyMs,
 		"requests_by_provider": stats.RequestsByProvider,
 		"requests_by_model":    stats.RequestsByModel,
+		"requests_by_type":     stats.RequestsByType,
 		"cost_by_provider":     stats.CostByProvider,
+		"cost_by_type":         stats.CostByType,
 	})
 }
 
@@ -109,7 +111,9 @@ func (h *AnalyticsHandler) GetUsageStatsAdmin(c *gin.Context) {
 		"average_latency_ms": stats.AverageLatencyMs,
 		"requests_by_provider": stats.RequestsByProvider,
 		"requests_by_model":   stats.RequestsByModel,
+		"requests_by_type":    stats.RequestsByType,
 		"cost_by_provider":   stats.CostByProvider,
+		"cost_by_type":       stats.CostByType,
 	})
 }
 
@@ -149,7 +153,7 @@ func (h *AnalyticsHandler) GetRequests(c *gin.Context) {
 
 	response := make([]map[string]interface{}, 0, len(requests))
 	for _, req := range requests {
-		response = append(response, map[string]interface{}{
+		item := map[string]interface{}{
 			"id":            req.ID.String(),
 			"provider":      req.Provider,
 			"model":         req.Model,
@@ -159,8 +163,14 @@ func (h *AnalyticsHandler) GetRequests(c *gin.Context) {
 			"cost":          req.Cost,
 			"latency_ms":    req.LatencyMs,
 			"status_code":   req.StatusCode,
+			"cached":        req.Cached,
 			"created_at":   req.CreatedAt.Format(time.RFC3339),
-		})
+		}
+		if req.ExperimentID != nil && req.ExperimentVariant != nil {
+			item["experiment_id"] = req.ExperimentID.String()
+			item["experiment_variant"] = *req.ExperimentVariant
+		}
+		response = append(response, item)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -171,3 +181,49 @@ func (h *AnalyticsHandler) GetRequests(c *gin.Context) {
 	})
 }
 
+// GetExperimentStats returns per-variant latency, cost, token and error figures for one experiment.
+func (h *AnalyticsHandler) GetExperimentStats(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid experiment id",
+		})
+		return
+	}
+
+	startTime := time.Now().AddDate(0, 0, -30)
+	endTime := time.Now()
+
+	if startStr := c.Query("start_time"); startStr != "" {
+		if parsed, err := time.Parse(time.RFC3339, startStr); err == nil {
+			startTime = parsed
+		}
+	}
+
+	if endStr := c.Query("end_time"); endStr != "" {
+		if parsed, err := time.Parse(time.RFC3339, endStr); err == nil {
+			endTime = parsed
+		}
+	}
+
+	variants, err := h.requestRepo.GetExperimentStats(c.Request.Context(), experimentID, startTime, endTime)
+	if err != nil {
+		h.logger.Error().Err(err).Str("experiment_id", experimentID.String()).Msg("Failed to get experiment stats")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to get experiment statistics",
+		})
+		return
+	}
+	if variants == nil {
+		variants = []*storage.VariantStats{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"experiment_id": experimentID,
+		"period": gin.H{
+			"start": startTime.Format(time.RFC3339),
+			"end":   endTime.Format(time.RFC3339),
+		},
+		"variants": variants,
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
 	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,6 +13,8 @@ import (
 
 type APIKeyHandler struct {
 	apiKeyService *security.APIKeyServiceV2
+	budgetRepo    *storage.BudgetRepository
+	budgetTracker *security.BudgetTracker
 }
 
 func NewAPIKeyHandler(apiKeyService *security.APIKeyServiceV2) *APIKeyHandler {
@@ -21,12 +24,37 @@ func NewAPIKeyHandler(apiKeyService *security.APIKeyServiceV2) *APIKeyHandler {
 }
 
 type CreateAPIKeyRequest struct {
-	Name               string     `json:"name" binding:"required"`
-	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
-	RateLimitPerDay    int        `json:"rate_limit_per_day"`
-	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	Name               string          `json:"name" binding:"required"`
+	RateLimitPerMinute int             `json:"rate_limit_per_minute"`
+	RateLimitPerDay    int             `json:"rate_limit_per_day"`
+	ExpiresAt          *time.Time      `json:"expires_at,omitempty"`
+	CacheTTLSeconds    int             `json:"cache_ttl_seconds,omitempty"`
+	Budgets            []BudgetRequest `json:"budgets,omitempty"`
+	// Tokens per sliding minute/day for this key; 0 = unlimited
+	TokenLimitPerMinute int `json:"token_limit_per_minute,omitempty"`
+	TokenLimitPerDay    int `json:"token_limit_per_day,omitempty"`
+	// Hedge chat requests slower than this latency percentile (1-99); 0 = off
+	HedgePercentile int `json:"hedge_percentile,omitempty"`
+	// Redact PII from prompts sent to cloud providers; omit to send prompts as is
+	PIIPolicy *storage.PIIPolicy `json:"pii_policy,omitempty"`
 }
 
+type UpdateAPIKeyCacheRequest struct {
+	CacheTTLSeconds int `json:"cache_ttl_seconds"`
+}
+
+type UpdateAPIKeyHedgingRequest struct {
+	HedgePercentile int `json:"hedge_percentile"`
+}
+
+type UpdateAPIKeyPIIPolicyRequest struct {
+	// null turns redaction off
+	PIIPolicy *storage.PIIPolicy `json:"pii_policy"`
+}
+
+// maxCacheTTLSeconds caps how long cached responses may be served for a key (7 days).
+const maxCacheTTLSeconds = 7 * 24 * 60 * 60
+
 func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 	var req CreateAPIKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -59,6 +87,43 @@ func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 	if req.RateLimitPerDay == 0 {
 		req.RateLimitPerDay = 10000
 	}
+	if req.CacheTTLSeconds < 0 || req.CacheTTLSeconds > maxCacheTTLSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "cache_ttl_seconds must be between 0 and 604800",
+		})
+		return
+	}
+	if req.TokenLimitPerMinute < 0 || req.TokenLimitPerDay < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "token limits must not be negative",
+		})
+		return
+	}
+	if req.HedgePercentile < 0 || req.HedgePercentile > 99 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "hedge_percentile must be between 0 and 99",
+		})
+		return
+	}
+	if err := validatePIIPolicy(req.PIIPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	budgets, err := budgetsFromRequest(req.Budgets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(budgets) > 0 && h.budgetRepo == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "budgets require Redis and are not enabled on this server",
+		})
+		return
+	}
 
 	key, apiKey, err := h.apiKeyService.CreateAPIKey(
 		c.Request.Context(),
@@ -67,6 +132,14 @@ func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 		req.RateLimitPerMinute,
 		req.RateLimitPerDay,
 		req.ExpiresAt,
+		security.APIKeySettings{
+			CacheTTLSeconds:     req.CacheTTLSeconds,
+			TokenLimitPerMinute: req.TokenLimitPerMinute,
+			TokenLimitPerDay:    req.TokenLimitPerDay,
+			HedgePercentile:     req.HedgePercentile,
+			PIIPolicy:           req.PIIPolicy,
+			Budgets:             budgets,
+		},
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -74,14 +147,23 @@ func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 		})
 		return
 	}
+	if len(budgets) > 0 {
+		h.budgetTracker.Invalidate(userID)
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":         apiKey.ID,
-		"key":        key, // Only returned once!
-		"name":       apiKey.Name,
-		"created_at": apiKey.CreatedAt,
-		"expires_at": apiKey.ExpiresAt,
-		"message":    "Save this key - it will not be shown again",
+		"id":                     apiKey.ID,
+		"key":                    key, // Only returned once!
+		"name":                   apiKey.Name,
+		"created_at":             apiKey.CreatedAt,
+		"expires_at":             apiKey.ExpiresAt,
+		"cache_ttl_seconds":      apiKey.CacheTTLSeconds,
+		"token_limit_per_minute": apiKey.TokenLimitPerMinute,
+		"token_limit_per_day":    apiKey.TokenLimitPerDay,
+		"hedge_percentile":       apiKey.HedgePercentile,
+		"pii_policy":             apiKey.PIIPolicy,
+		"budgets":                budgets,
+		"message":                "Save this key - it will not be shown again",
 	})
 }
 
@@ -110,16 +192,38 @@ func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
 		return
 	}
 
+	budgetsByKey := make(map[uuid.UUID][]security.BudgetStatus)
+	if h.budgetRepo != nil {
+		statuses, err := h.budgetStatuses(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, status := range statuses {
+			if status.APIKeyID != nil {
+				budgetsByKey[*status.APIKeyID] = append(budgetsByKey[*status.APIKeyID], status)
+			}
+		}
+	}
+
 	keyList := make([]map[string]interface{}, len(keys))
 	for i, key := range keys {
 		keyList[i] = map[string]interface{}{
-			"id":                    key.ID.String(),
-			"name":                  key.Name,
-			"rate_limit_per_minute": key.RateLimitPerMinute,
-			"rate_limit_per_day":    key.RateLimitPerDay,
-			"created_at":            key.CreatedAt.Format(time.RFC3339),
-			"expires_at":            nil,
-			"is_active":             key.IsActive,
+			"id":                     key.ID.String(),
+			"name":                   key.Name,
+			"rate_limit_per_minute":  key.RateLimitPerMinute,
+			"rate_limit_per_day":     key.RateLimitPerDay,
+			"created_at":             key.CreatedAt.Format(time.RFC3339),
+			"expires_at":             nil,
+			"is_active":              key.IsActive,
+			"cache_ttl_seconds":      key.CacheTTLSeconds,
+			"token_limit_per_minute": key.TokenLimitPerMinute,
+			"token_limit_per_day":    key.TokenLimitPerDay,
+			"hedge_percentile":       key.HedgePercentile,
+			"pii_policy":             key.PIIPolicy,
+			"budgets":                budgetsByKey[key.ID],
 		}
 		if key.ExpiresAt != nil {
 			keyList[i]["expires_at"] = key.ExpiresAt.Format(time.RFC3339)
@@ -231,3 +335,137 @@ func (h *APIKeyHandler) DeleteAPIKeyPermanently(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "API key deleted permanently"})
 }
+
+// UpdateAPIKeyCache sets the response cache TTL for a key. 0 disables caching.
+func (h *APIKeyHandler) UpdateAPIKeyCache(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+	var req UpdateAPIKeyCacheRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.CacheTTLSeconds < 0 || req.CacheTTLSeconds > maxCacheTTLSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cache_ttl_seconds must be between 0 and 604800"})
+		return
+	}
+	key, err := h.apiKeyService.SetCacheTTL(c.Request.Context(), userID, keyID, req.CacheTTLSeconds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":                key.ID.String(),
+		"cache_ttl_seconds": key.CacheTTLSeconds,
+	})
+}
+
+// UpdateAPIKeyHedging sets the latency percentile after which the key's chat requests
+// are hedged. 0 disables hedging.
+func (h *APIKeyHandler) UpdateAPIKeyHedging(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+	var req UpdateAPIKeyHedgingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.HedgePercentile < 0 || req.HedgePercentile > 99 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hedge_percentile must be between 0 and 99"})
+		return
+	}
+	key, err := h.apiKeyService.SetHedgePercentile(c.Request.Context(), userID, keyID, req.HedgePercentile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":               key.ID.String(),
+		"hedge_percentile": key.HedgePercentile,
+	})
+}
+
+// UpdateAPIKeyPIIPolicy sets the PII redaction policy applied to the key's chat requests.
+// A null policy turns redaction off.
+func (h *APIKeyHandler) UpdateAPIKeyPIIPolicy(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+	var req UpdateAPIKeyPIIPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := validatePIIPolicy(req.PIIPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := h.apiKeyService.SetPIIPolicy(c.Request.Context(), userID, keyID, req.PIIPolicy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":         key.ID.String(),
+		"pii_policy": key.PIIPolicy,
+	})
+}
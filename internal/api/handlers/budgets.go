@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BudgetRequest sets a USD budget for one period. At least one limit is required.
+type BudgetRequest struct {
+	Period       string   `json:"period"`
+	LimitUSD     *float64 `json:"limit_usd,omitempty"`
+	SoftLimitUSD *float64 `json:"soft_limit_usd,omitempty"`
+}
+
+type UpdateBudgetsRequest struct {
+	Budgets []BudgetRequest `json:"budgets"`
+}
+
+func (h *APIKeyHandler) SetBudgets(repo *storage.BudgetRepository, tracker *security.BudgetTracker) {
+	h.budgetRepo = repo
+	h.budgetTracker = tracker
+}
+
+// GetUserBudget returns the budgets covering all of the user's requests.
+func (h *APIKeyHandler) GetUserBudget(c *gin.Context) {
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	h.respondBudgets(c, *userID, nil)
+}
+
+// UpdateUserBudget replaces the user-wide budgets. An empty list removes them.
+func (h *APIKeyHandler) UpdateUserBudget(c *gin.Context) {
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	h.replaceBudgets(c, *userID, nil)
+}
+
+func (h *APIKeyHandler) GetAPIKeyBudget(c *gin.Context) {
+	userID, keyID, ok := h.ownedAPIKey(c)
+	if !ok {
+		return
+	}
+	h.respondBudgets(c, userID, &keyID)
+}
+
+// UpdateAPIKeyBudget replaces the budgets of a key. An empty list removes them.
+func (h *APIKeyHandler) UpdateAPIKeyBudget(c *gin.Context) {
+	userID, keyID, ok := h.ownedAPIKey(c)
+	if !ok {
+		return
+	}
+	h.replaceBudgets(c, userID, &keyID)
+}
+
+func (h *APIKeyHandler) replaceBudgets(c *gin.Context, userID uuid.UUID, keyID *uuid.UUID) {
+	var req UpdateBudgetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+	budgets, err := budgetsFromRequest(req.Budgets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.saveBudgets(c.Request.Context(), userID, keyID, budgets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save budgets"})
+		return
+	}
+	h.respondBudgets(c, userID, keyID)
+}
+
+func (h *APIKeyHandler) saveBudgets(ctx context.Context, userID uuid.UUID, keyID *uuid.UUID, budgets []*storage.Budget) error {
+	if err := h.budgetRepo.ReplaceBudgets(ctx, userID, keyID, budgets); err != nil {
+		return err
+	}
+	h.budgetTracker.Invalidate(userID)
+	return nil
+}
+
+func (h *APIKeyHandler) respondBudgets(c *gin.Context, userID uuid.UUID, keyID *uuid.UUID) {
+	statuses, err := h.budgetStatuses(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	matching := make([]security.BudgetStatus, 0)
+	for _, status := range statuses {
+		if (keyID == nil && status.APIKeyID == nil) || (keyID != nil && status.APIKeyID != nil && *status.APIKeyID == *keyID) {
+			matching = append(matching, status)
+		}
+	}
+	response := gin.H{"budgets": matching}
+	if keyID != nil {
+		response["api_key_id"] = keyID.String()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// budgetStatuses returns the user's budgets with their current spend.
+func (h *APIKeyHandler) budgetStatuses(ctx context.Context, userID uuid.UUID) ([]security.BudgetStatus, error) {
+	budgets, err := h.budgetRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return h.budgetTracker.Statuses(ctx, budgets)
+}
+
+func (h *APIKeyHandler) ownedAPIKey(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	keys, err := h.apiKeyService.ListAPIKeysByUser(c.Request.Context(), *userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return uuid.Nil, uuid.Nil, false
+	}
+	for _, key := range keys {
+		if key.ID == keyID {
+			return *userID, keyID, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+	return uuid.Nil, uuid.Nil, false
+}
+
+func budgetsFromRequest(reqs []BudgetRequest) ([]*storage.Budget, error) {
+	budgets := make([]*storage.Budget, 0, len(reqs))
+	seen := make(map[string]bool)
+	for _, req := range reqs {
+		if !security.IsValidBudgetPeriod(req.Period) {
+			return nil, fmt.Errorf("budget period must be daily, monthly or lifetime")
+		}
+		if seen[req.Period] {
+			return nil, fmt.Errorf("only one %s budget is allowed", req.Period)
+		}
+		seen[req.Period] = true
+		if req.LimitUSD == nil && req.SoftLimitUSD == nil {
+			return nil, fmt.Errorf("%s budget needs limit_usd or soft_limit_usd", req.Period)
+		}
+		if (req.LimitUSD != nil && *req.LimitUSD <= 0) || (req.SoftLimitUSD != nil && *req.SoftLimitUSD <= 0) {
+			return nil, fmt.Errorf("%s budget limits must be greater than 0", req.Period)
+		}
+		if req.LimitUSD != nil && req.SoftLimitUSD != nil && *req.SoftLimitUSD > *req.LimitUSD {
+			return nil, fmt.Errorf("%s budget soft_limit_usd must not exceed limit_usd", req.Period)
+		}
+		budgets = append(budgets, &storage.Budget{
+			Period:       req.Period,
+			HardLimitUSD: req.LimitUSD,
+			SoftLimitUSD: req.SoftLimitUSD,
+		})
+	}
+	return budgets, nil
+}
+
+// recordSpend counts a request's cost against the caller's budgets in the background.
+// BYOK requests count too: the budget guards against runaway usage, not billing.
+func (h *ChatHandler) recordSpend(apiKeyID, userID *uuid.UUID, cost float64) {
+	if h.budgets == nil || userID == nil || cost <= 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := h.budgets.RecordSpend(ctx, *userID, apiKeyID, cost); err != nil {
+			h.logger.Warn().Err(err).Msg("Failed to record budget spend")
+		}
+	}()
+}
@@ -31,6 +31,7 @@ type ChatHandler struct {
 	logger      zerolog.Logger
 	upgrader    websocket.Upgrader
 	jwtService  *security.JWTService
+	budgets     *security.BudgetTracker
 }
 
 func NewChatHandler(router *gateway.Router, requestRepo *storage.RequestRepository, convRepo *storage.ConversationRepository, logger zerolog.Logger) *ChatHandler {
@@ -59,6 +60,10 @@ func (h *ChatHandler) SetMCPService(service *mcp.Service) {
 	h.mcpService = service
 }
 
+func (h *ChatHandler) SetBudgetTracker(tracker *security.BudgetTracker) {
+	h.budgets = tracker
+}
+
 type ChatRequestWithConversation struct {
 	providers.ChatRequest
 	ConversationID *string `json:"conversation_id,omitempty"`
@@ -74,11 +79,12 @@ type chatStreamRequest struct {
 	ConversationID        *string               `json:"conversation_id,omitempty"`
 	Model                 string                `json:"model"`
 	Messages              []providers.Message   `json:"messages"`
-	Temperature           float64               `json:"temperature,omitempty"`
+	Temperature           *float64              `json:"temperature,omitempty"`
 	MaxTokens             int                   `json:"max_tokens,omitempty"`
 	GoogleSearchGrounding bool                  `json:"google_search_grounding,omitempty"`
 	WebSearch             bool                  `json:"web_search,omitempty"`
 	MCPToolCalls          []mcpToolCall          `json:"mcp_tool_calls,omitempty"`
+	ResponseFormat        *providers.ResponseFormat `json:"response_format,omitempty"`
 }
 
 func (h *ChatHandler) HandleChat(c *gin.Context) {
@@ -107,6 +113,15 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 		return
 	}
 
+	if req.ResponseFormat != nil {
+		if err := req.ResponseFormat.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
 	apiKeyIDStr, _ := c.Get("api_key_id")
 	userIDStr, _ := c.Get("user_id")
 
@@ -124,8 +139,24 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 		}
 	}
 
+	req, experiment := h.assignExperiment(c.Request.Context(), req, apiKeyID, userID)
+	routeCtx, cacheEnabled := cacheContext(c, req)
+	routeCtx, err := hedgeContext(c, routeCtx)
+	if err == nil {
+		routeCtx, err = structuredOutputContext(c, routeCtx)
+	}
+	if err == nil {
+		routeCtx, err = contextWindowContext(c, routeCtx, reqWithConv.ConversationID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	routeCtx, trace := routingTrace(c, routeCtx)
 	startTime := time.Now()
-	resp, err := h.router.Route(c.Request.Context(), req, userID)
+	resp, err := h.router.Route(routeCtx, req, userID)
 	latency := time.Since(startTime)
 
 	statusCode := http.StatusOK
@@ -134,24 +165,39 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 	provider := "unknown"
 
 	if err != nil {
-		statusCode = http.StatusInternalServerError
+		statusCode, _ = providerErrorStatus(err)
 		status = "error"
 		msg := err.Error()
 		errorMsg = &msg
-		c.JSON(statusCode, gin.H{
+		body := gin.H{
 			"error": err.Error(),
-		})
+		}
+		if trace != nil {
+			body["debug"] = trace
+		}
+		c.JSON(statusCode, body)
 	} else {
 		provider = resp.Provider
-		c.JSON(http.StatusOK, resp)
+		if cacheEnabled {
+			setCacheStatusHeader(c, resp.Cached)
+		}
+		if trace != nil {
+			c.JSON(http.StatusOK, debugChatResponse{ChatResponse: resp, Debug: trace})
+		} else {
+			c.JSON(http.StatusOK, resp)
+		}
 	}
 
 	billableCost := 0.0
+	var hedgeID *uuid.UUID
 	if err == nil && resp != nil {
 		billableCost = resp.Cost
+		hedgeID = h.recordHedgeAttempts(c.Request.Context(), apiKeyID, userID, resp.HedgeAttempts)
 		if userID != nil && h.router.UserHasProviderKey(c.Request.Context(), *userID, provider) {
 			billableCost = 0
 		}
+		h.recordSpend(apiKeyID, userID, resp.Cost)
+		reportTokenUsage(c.Request.Context(), &resp.Usage, resp.Cached)
 
 		if reqWithConv.ConversationID != nil && h.convRepo != nil && userID != nil {
 			conversationID, err := uuid.Parse(*reqWithConv.ConversationID)
@@ -166,7 +212,7 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 					assistantMsg := resp.Choices[0].Message
 					metadata := map[string]interface{}{
 						"tokens":  resp.Usage.TotalTokens,
-						"cost":    resp.Cost,
+						"cost":    gateway.RoundCost(resp.Cost),
 						"provider": resp.Provider,
 						"latency_ms": latency.Milliseconds(),
 					}
@@ -209,8 +255,11 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 				LatencyMs:    int(latency.Milliseconds()),
 				StatusCode:   statusCode,
 				ErrorMessage: errorMsg,
+				Cached:       resp != nil && resp.Cached,
+				HedgeID:      hedgeID,
 				CreatedAt:    time.Now(),
 			}
+			tagExperiment(requestRecord, experiment)
 
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
@@ -223,8 +272,10 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 
 	if err == nil && resp != nil {
 		monitoring.RecordRequest(resp.Provider, resp.Model, status, latency.Seconds())
-		monitoring.RecordTokens(resp.Provider, resp.Model, "input", resp.Usage.PromptTokens)
-		monitoring.RecordTokens(resp.Provider, resp.Model, "output", resp.Usage.CompletionTokens)
+		if !resp.Cached {
+			monitoring.RecordTokens(resp.Provider, resp.Model, "input", resp.Usage.PromptTokens)
+			monitoring.RecordTokens(resp.Provider, resp.Model, "output", resp.Usage.CompletionTokens)
+		}
 		if resp.Cost > 0 {
 			monitoring.RecordCost(resp.Provider, resp.Model, resp.Cost)
 		}
@@ -267,6 +318,7 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 		MaxTokens:             streamReq.MaxTokens,
 		GoogleSearchGrounding: streamReq.GoogleSearchGrounding || streamReq.WebSearch,
 		WebSearch:             streamReq.WebSearch,
+		ResponseFormat:        streamReq.ResponseFormat,
 	}
 	const maxMCPToolCalls = 10
 	const maxMCPContextBytes = 100 << 10
@@ -323,6 +375,15 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 		return
 	}
 
+	if req.ResponseFormat != nil {
+		if err := req.ResponseFormat.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
 	apiKeyIDStr, _ := c.Get("api_key_id")
 	userIDStr, _ := c.Get("user_id")
 
@@ -340,19 +401,37 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 		}
 	}
 
+	if err := streamOptionsError(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	req, experiment := h.assignExperiment(c.Request.Context(), req, apiKeyID, userID)
+	routeCtx, cacheEnabled := cacheContext(c, req)
+	routeCtx, err = contextWindowContext(c, routeCtx, streamReq.ConversationID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no") // Disable nginx buffering
-
 	startTime := time.Now()
-	chunkChan, errChan := h.router.RouteStream(c.Request.Context(), req, userID)
+	chunkChan, errChan := h.router.RouteStream(routeCtx, req, userID)
 
 	var responseID string
 	var fullContent strings.Builder
 	var finalUsage *providers.Usage
 	var provider string = "unknown"
+	servedModel := req.Model // an alias's target model, priced in place of the alias
 	var status string = "success"
+	cached := false
 
 	c.Stream(func(w io.Writer) bool {
 		select {
@@ -381,17 +460,21 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 					}
 				}
 
+				actualCost := 0.0
+				if finalUsage != nil && !cached {
+					actualCost = h.router.GetCostCalculator().CalculateActualCost(provider, servedModel, *finalUsage)
+				}
+				h.recordSpend(apiKeyID, userID, actualCost)
+				reportTokenUsage(c.Request.Context(), finalUsage, cached)
+
 				if h.requestRepo != nil {
 					go func() {
 						ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 						defer cancel()
 
-						streamCost := 0.0
-						if finalUsage != nil {
-							streamCost = h.router.GetCostCalculator().CalculateActualCost(provider, req.Model, *finalUsage)
-							if userID != nil && h.router.UserHasProviderKey(ctx, *userID, provider) {
-								streamCost = 0
-							}
+						streamCost := actualCost
+						if streamCost > 0 && userID != nil && h.router.UserHasProviderKey(ctx, *userID, provider) {
+							streamCost = 0
 						}
 
 						requestRecord := &storage.Request{
@@ -399,7 +482,7 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 							APIKeyID:      apiKeyID,
 							UserID:        userID,
 							Provider:      provider,
-							Model:         req.Model,
+							Model:         servedModel,
 							RequestType:   "chat_stream",
 							InputTokens:   func() int { if finalUsage != nil { return finalUsage.PromptTokens }; return 0 }(),
 							OutputTokens:  func() int { if finalUsage != nil { return finalUsage.CompletionTokens }; return 0 }(),
@@ -408,8 +491,10 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 							LatencyMs:     int(latency.Milliseconds()),
 							StatusCode:    http.StatusOK,
 							ErrorMessage:  nil,
+							Cached:        cached,
 							CreatedAt:     time.Now(),
 						}
+						tagExperiment(requestRecord, experiment)
 
 						if err := h.requestRepo.Create(ctx, requestRecord); err != nil {
 							h.logger.Error().Err(err).Msg("Failed to track streaming request")
@@ -487,20 +572,33 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 			if chunk.Provider != "" {
 				provider = chunk.Provider
 			}
+			if chunk.Model != "" {
+				servedModel = chunk.Model
+			}
+			if chunk.Cached {
+				cached = true
+			}
+			if cacheEnabled && !c.Writer.Written() {
+				setCacheStatusHeader(c, cached)
+			}
 
 			if chunk.Done {
 				latency := time.Since(startTime)
+				actualCost := 0.0
+				if finalUsage != nil && !cached {
+					actualCost = h.router.GetCostCalculator().CalculateActualCost(provider, servedModel, *finalUsage)
+				}
+				h.recordSpend(apiKeyID, userID, actualCost)
+				reportTokenUsage(c.Request.Context(), finalUsage, cached)
+
 				if h.requestRepo != nil {
 					go func() {
 						ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 						defer cancel()
 
-						streamCost := 0.0
-						if finalUsage != nil {
-							streamCost = h.router.GetCostCalculator().CalculateActualCost(provider, req.Model, *finalUsage)
-							if userID != nil && h.router.UserHasProviderKey(ctx, *userID, provider) {
-								streamCost = 0
-							}
+						streamCost := actualCost
+						if streamCost > 0 && userID != nil && h.router.UserHasProviderKey(ctx, *userID, provider) {
+							streamCost = 0
 						}
 
 						requestRecord := &storage.Request{
@@ -508,7 +606,7 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 							APIKeyID:      apiKeyID,
 							UserID:        userID,
 							Provider:      provider,
-							Model:         req.Model,
+							Model:         servedModel,
 							RequestType:   "chat_stream",
 							InputTokens:   func() int { if finalUsage != nil { return finalUsage.PromptTokens }; return 0 }(),
 							OutputTokens:  func() int { if finalUsage != nil { return finalUsage.CompletionTokens }; return 0 }(),
@@ -517,8 +615,10 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 							LatencyMs:     int(latency.Milliseconds()),
 							StatusCode:    http.StatusOK,
 							ErrorMessage:  nil,
+							Cached:        cached,
 							CreatedAt:     time.Now(),
 						}
+						tagExperiment(requestRecord, experiment)
 						if err := h.requestRepo.Create(ctx, requestRecord); err != nil {
 							h.logger.Error().Err(err).Msg("Failed to track streaming request")
 						}
@@ -597,6 +697,7 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 			if h.requestRepo != nil {
 				go func() {
 					errorMsg := err.Error()
+					statusCode, _ := providerErrorStatus(err)
 					requestRecord := &storage.Request{
 						ID:           uuid.New(),
 						APIKeyID:     apiKeyID,
@@ -604,10 +705,11 @@ func (h *ChatHandler) HandleChatStream(c *gin.Context) {
 						Provider:     "unknown",
 						Model:        req.Model,
 						RequestType:  "chat_stream",
-						StatusCode:   http.StatusInternalServerError,
+						StatusCode:   statusCode,
 						ErrorMessage: &errorMsg,
 						CreatedAt:    time.Now(),
 					}
+					tagExperiment(requestRecord, experiment)
 
 					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 					defer cancel()
@@ -798,13 +900,15 @@ func (h *ChatHandler) HandleChatWebSocket(c *gin.Context) {
 		return
 	}
 
+	req, experiment := h.assignExperiment(c.Request.Context(), req, apiKeyID, userID)
 	startTime := time.Now()
-	chunkChan, errChan := h.router.RouteStream(c.Request.Context(), req, userID)
+	chunkChan, errChan := h.router.RouteStream(routingContext(c), req, userID)
 
 	var responseID string
 	var fullContent strings.Builder
 	var finalUsage *providers.Usage
 	var provider string = "unknown"
+	servedModel := req.Model // an alias's target model, priced in place of the alias
 	var status string = "success"
 	defer pingTicker.Stop()
 
@@ -827,17 +931,21 @@ func (h *ChatHandler) HandleChatWebSocket(c *gin.Context) {
 			if !ok {
 				latency := time.Since(startTime)
 
+				actualCost := 0.0
+				if finalUsage != nil {
+					actualCost = h.router.GetCostCalculator().CalculateActualCost(provider, servedModel, *finalUsage)
+				}
+				h.recordSpend(apiKeyID, userID, actualCost)
+				reportTokenUsage(c.Request.Context(), finalUsage, false)
+
 				if h.requestRepo != nil {
 					go func() {
 						ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 						defer cancel()
 
-						streamCost := 0.0
-						if finalUsage != nil {
-							streamCost = h.router.GetCostCalculator().CalculateActualCost(provider, req.Model, *finalUsage)
-							if userID != nil && h.router.UserHasProviderKey(ctx, *userID, provider) {
-								streamCost = 0
-							}
+						streamCost := actualCost
+						if streamCost > 0 && userID != nil && h.router.UserHasProviderKey(ctx, *userID, provider) {
+							streamCost = 0
 						}
 
 						requestRecord := &storage.Request{
@@ -845,7 +953,7 @@ func (h *ChatHandler) HandleChatWebSocket(c *gin.Context) {
 							APIKeyID:      apiKeyID,
 							UserID:        userID,
 							Provider:      provider,
-							Model:         req.Model,
+							Model:         servedModel,
 							RequestType:   "chat_websocket",
 							InputTokens:   func() int { if finalUsage != nil { return finalUsage.PromptTokens }; return 0 }(),
 							OutputTokens:  func() int { if finalUsage != nil { return finalUsage.CompletionTokens }; return 0 }(),
@@ -856,6 +964,7 @@ func (h *ChatHandler) HandleChatWebSocket(c *gin.Context) {
 							ErrorMessage:  nil,
 							CreatedAt:     time.Now(),
 						}
+						tagExperiment(requestRecord, experiment)
 
 						if err := h.requestRepo.Create(ctx, requestRecord); err != nil {
 							h.logger.Error().Err(err).Msg("Failed to track WebSocket streaming request")
@@ -903,6 +1012,9 @@ func (h *ChatHandler) HandleChatWebSocket(c *gin.Context) {
 			if chunk.Provider != "" {
 				provider = chunk.Provider
 			}
+			if chunk.Model != "" {
+				servedModel = chunk.Model
+			}
 
 			ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			response := WebSocketResponse{
@@ -936,6 +1048,7 @@ func (h *ChatHandler) HandleChatWebSocket(c *gin.Context) {
 			if h.requestRepo != nil {
 				go func() {
 					errorMsg := err.Error()
+					statusCode, _ := providerErrorStatus(err)
 					requestRecord := &storage.Request{
 						ID:           uuid.New(),
 						APIKeyID:     apiKeyID,
@@ -943,10 +1056,11 @@ func (h *ChatHandler) HandleChatWebSocket(c *gin.Context) {
 						Provider:     "unknown",
 						Model:        req.Model,
 						RequestType:  "chat_websocket",
-						StatusCode:   http.StatusInternalServerError,
+						StatusCode:   statusCode,
 						ErrorMessage: &errorMsg,
 						CreatedAt:    time.Now(),
 					}
+					tagExperiment(requestRecord, experiment)
 
 					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 					defer cancel()
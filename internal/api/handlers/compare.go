@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxCompareTargets caps how many models one comparison may fan out to.
+const maxCompareTargets = 10
+
+// compareRequestType is the request_type of the rows of a comparison.
+const compareRequestType = "chat_compare"
+
+// CompareRequest is a /v1/chat request sent to several models. Models is shorthand for
+// targets without a pinned provider; the request's own model field is ignored.
+type CompareRequest struct {
+	providers.ChatRequest
+	Targets []gateway.CompareTarget `json:"targets,omitempty"`
+	Models  []string                `json:"models,omitempty"`
+}
+
+// targets validates the request and returns the targets to compare.
+func (r *CompareRequest) targets() ([]gateway.CompareTarget, error) {
+	targets := append([]gateway.CompareTarget(nil), r.Targets...)
+	for _, model := range r.Models {
+		targets = append(targets, gateway.CompareTarget{Model: model})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("targets or models is required")
+	}
+	if len(targets) > maxCompareTargets {
+		return nil, fmt.Errorf("at most %d targets can be compared", maxCompareTargets)
+	}
+	for i, target := range targets {
+		if target.Model == "" {
+			return nil, fmt.Errorf("targets[%d]: model is required", i)
+		}
+	}
+	if len(r.Messages) == 0 {
+		return nil, fmt.Errorf("messages are required")
+	}
+	return targets, nil
+}
+
+// compareResult is one model's answer in a comparison response.
+type compareResult struct {
+	Index        int                `json:"index"`
+	Model        string             `json:"model"`
+	Provider     string             `json:"provider,omitempty"`
+	Message      *providers.Message `json:"message,omitempty"`
+	FinishReason string             `json:"finish_reason,omitempty"`
+	Usage        *providers.Usage   `json:"usage,omitempty"`
+	Cost         float64            `json:"cost"`
+	LatencyMs    int64              `json:"latency_ms"`
+	Cached       bool               `json:"cached,omitempty"`
+	Error        string             `json:"error,omitempty"`
+}
+
+type compareResponse struct {
+	ComparisonID uuid.UUID       `json:"comparison_id"`
+	Results      []compareResult `json:"results"`
+	Succeeded    int             `json:"succeeded"`
+	Failed       int             `json:"failed"`
+}
+
+// Server-sent events of /v1/chat/compare/stream: "chunk" events carry content for the
+// target at index, a "result" event ends that target's stream, and a final "summary"
+// event lists every result.
+type compareStreamChunk struct {
+	Type      string                    `json:"type"`
+	Index     int                       `json:"index"`
+	Provider  string                    `json:"provider,omitempty"`
+	Content   string                    `json:"content,omitempty"`
+	ToolCalls []providers.ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+type compareStreamResult struct {
+	Type string `json:"type"`
+	compareResult
+}
+
+type compareStreamSummary struct {
+	Type string `json:"type"`
+	compareResponse
+}
+
+// HandleChatCompare serves POST /v1/chat/compare: the request is sent to every target
+// concurrently and the answers are returned side by side. The response is 200 if any
+// target succeeded.
+func (h *ChatHandler) HandleChatCompare(c *gin.Context) {
+	var req CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+	targets, err := req.targets()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	apiKeyID, userID := requestIdentity(c)
+	results := h.router.Compare(routingContext(c), req.ChatRequest, targets, userID)
+
+	response := compareResponse{ComparisonID: uuid.New(), Results: make([]compareResult, len(results))}
+	var firstErr error
+	for i, result := range results {
+		response.Results[i] = newCompareResult(i, result.Target, result.Response, result.Latency, result.Err)
+		if result.Err != nil {
+			response.Failed++
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+		} else {
+			response.Succeeded++
+		}
+		h.recordComparisonResult(c.Request.Context(), apiKeyID, userID, response.ComparisonID, response.Results[i], result.Err)
+	}
+
+	status := http.StatusOK
+	if response.Succeeded == 0 {
+		status, _ = providerErrorStatus(firstErr)
+	}
+	c.JSON(status, response)
+}
+
+// HandleChatCompareStream serves POST /v1/chat/compare/stream, the streaming variant of
+// /v1/chat/compare. Chunks from the targets are interleaved as server-sent events.
+func (h *ChatHandler) HandleChatCompareStream(c *gin.Context) {
+	var req CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+	targets, err := req.targets()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	apiKeyID, userID := requestIdentity(c)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	startTime := time.Now()
+	chunks := h.router.CompareStream(routingContext(c), req.ChatRequest, targets, userID)
+	response := compareResponse{ComparisonID: uuid.New(), Results: make([]compareResult, len(targets))}
+	finished := make([]bool, len(targets))
+	providerNames := make([]string, len(targets))
+
+	writeEvent := func(w io.Writer, event interface{}) {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	finish := func(w io.Writer, i int, resp *providers.ChatResponse, err error) {
+		finished[i] = true
+		result := newCompareResult(i, targets[i], resp, time.Since(startTime), err)
+		if result.Provider == "" {
+			result.Provider = providerNames[i]
+		}
+		if err != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results[i] = result
+		h.recordComparisonResult(context.WithoutCancel(c.Request.Context()), apiKeyID, userID, response.ComparisonID, result, err)
+		writeEvent(w, compareStreamResult{Type: "result", compareResult: result})
+	}
+
+	c.Stream(func(w io.Writer) bool {
+		chunk, ok := <-chunks
+		if !ok {
+			for i := range targets {
+				if !finished[i] {
+					finish(w, i, nil, fmt.Errorf("stream ended with no response"))
+				}
+			}
+			writeEvent(w, compareStreamSummary{Type: "summary", compareResponse: response})
+			return false
+		}
+		i := chunk.Index
+		if chunk.Chunk.Provider != "" {
+			providerNames[i] = chunk.Chunk.Provider
+		}
+		switch {
+		case chunk.Err != nil:
+			finish(w, i, nil, chunk.Err)
+		case chunk.Chunk.Done:
+			if chunk.Chunk.Content != "" || len(chunk.Chunk.ToolCalls) > 0 {
+				writeEvent(w, compareStreamChunk{Type: "chunk", Index: i, Provider: providerNames[i], Content: chunk.Chunk.Content, ToolCalls: chunk.Chunk.ToolCalls})
+			}
+			resp := &providers.ChatResponse{Model: targets[i].Model, Provider: providerNames[i], Cached: chunk.Chunk.Cached}
+			if chunk.Chunk.Usage != nil {
+				resp.Usage = *chunk.Chunk.Usage
+				if !resp.Cached {
+					resp.Cost = h.router.GetCostCalculator().CalculateActualCost(resp.Provider, resp.Model, resp.Usage)
+				}
+			}
+			resp.Choices = []providers.Choice{{FinishReason: chunk.Chunk.FinishReason}}
+			finish(w, i, resp, nil)
+		default:
+			writeEvent(w, compareStreamChunk{Type: "chunk", Index: i, Provider: providerNames[i], Content: chunk.Chunk.Content, ToolCalls: chunk.Chunk.ToolCalls})
+		}
+		if flusher, ok := c.Writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return true
+	})
+}
+
+func newCompareResult(index int, target gateway.CompareTarget, resp *providers.ChatResponse, latency time.Duration, err error) compareResult {
+	result := compareResult{
+		Index:     index,
+		Model:     target.Model,
+		Provider:  target.Provider,
+		LatencyMs: latency.Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Provider = resp.Provider
+	result.Usage = &resp.Usage
+	result.Cost = resp.Cost
+	result.Cached = resp.Cached
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		result.FinishReason = choice.FinishReason
+		if choice.Message.Role != "" || choice.Message.Content != nil || len(choice.Message.ToolCalls) > 0 {
+			message := choice.Message
+			result.Message = &message
+		}
+	}
+	return result
+}
+
+// recordComparisonResult records one target of a comparison as a chat_compare request
+// linked to the others by comparisonID.
+func (h *ChatHandler) recordComparisonResult(ctx context.Context, apiKeyID, userID *uuid.UUID, comparisonID uuid.UUID, result compareResult, callErr error) {
+	provider := result.Provider
+	if provider == "" {
+		provider = "unknown"
+	}
+	status := "success"
+	statusCode := http.StatusOK
+	var errorMsg *string
+	if callErr != nil {
+		status = "error"
+		statusCode, _ = providerErrorStatus(callErr)
+		msg := callErr.Error()
+		errorMsg = &msg
+	}
+	monitoring.RecordRequest(provider, result.Model, status, float64(result.LatencyMs)/1000)
+	if result.Usage != nil && !result.Cached {
+		monitoring.RecordTokens(provider, result.Model, "input", result.Usage.PromptTokens)
+		monitoring.RecordTokens(provider, result.Model, "output", result.Usage.CompletionTokens)
+		if result.Cost > 0 {
+			monitoring.RecordCost(provider, result.Model, result.Cost)
+		}
+	}
+	h.recordSpend(apiKeyID, userID, result.Cost)
+	if callErr == nil {
+		reportTokenUsage(ctx, result.Usage, result.Cached)
+	}
+
+	if h.requestRepo == nil {
+		return
+	}
+	billableCost := result.Cost
+	if userID != nil && billableCost > 0 && h.router.UserHasProviderKey(ctx, *userID, provider) {
+		billableCost = 0
+	}
+	record := &storage.Request{
+		ID:           uuid.New(),
+		APIKeyID:     apiKeyID,
+		UserID:       userID,
+		Provider:     provider,
+		Model:        result.Model,
+		RequestType:  compareRequestType,
+		Cost:         billableCost,
+		LatencyMs:    int(result.LatencyMs),
+		StatusCode:   statusCode,
+		ErrorMessage: errorMsg,
+		Cached:       result.Cached,
+		ComparisonID: &comparisonID,
+		CreatedAt:    time.Now(),
+	}
+	if result.Usage != nil {
+		record.InputTokens = result.Usage.PromptTokens
+		record.OutputTokens = result.Usage.CompletionTokens
+		record.TotalTokens = result.Usage.TotalTokens
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := h.requestRepo.Create(ctx, record); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to track comparison request")
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// contextStrategyHeader overrides CONTEXT_STRATEGY for one request: off, reject,
+// truncate or summarize.
+const contextStrategyHeader = "X-UniRoute-Context-Strategy"
+
+// contextWindowContext attaches the context-window policy of the request. Summaries of
+// a conversation are stored on it, so later turns reuse them.
+func contextWindowContext(c *gin.Context, ctx context.Context, conversationID *string) (context.Context, error) {
+	var policy gateway.ContextPolicy
+	if value := strings.ToLower(strings.TrimSpace(c.GetHeader(contextStrategyHeader))); value != "" {
+		if !gateway.ValidContextStrategy(value) {
+			return ctx, fmt.Errorf("%s must be off, reject, truncate or summarize", contextStrategyHeader)
+		}
+		policy.Strategy = value
+	}
+	if conversationID != nil {
+		if id, err := uuid.Parse(*conversationID); err == nil {
+			policy.ConversationID = &id
+		}
+	}
+	if policy.Strategy == "" && policy.ConversationID == nil {
+		return ctx, nil
+	}
+	return gateway.WithContextPolicy(ctx, policy), nil
+}
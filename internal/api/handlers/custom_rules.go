@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
-	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,20 +13,16 @@ import (
 )
 
 type CustomRulesHandler struct {
-	router         *gateway.Router
-	ruleRepo       *storage.CustomRoutingRulesRepository
-	costCalculator *gateway.CostCalculator
-	latencyTracker *gateway.LatencyTracker
-	logger         zerolog.Logger
+	router   *gateway.Router
+	ruleRepo *storage.CustomRoutingRulesRepository
+	logger   zerolog.Logger
 }
 
 func NewCustomRulesHandler(router *gateway.Router, ruleRepo *storage.CustomRoutingRulesRepository, logger zerolog.Logger) *CustomRulesHandler {
 	return &CustomRulesHandler{
-		router:         router,
-		ruleRepo:       ruleRepo,
-		costCalculator: router.GetCostCalculator(),
-		latencyTracker: router.GetLatencyTracker(),
-		logger:         logger,
+		router:   router,
+		ruleRepo: ruleRepo,
+		logger:   logger,
 	}
 }
 
@@ -91,6 +87,16 @@ func (h *CustomRulesHandler) SetCustomRules(c *gin.Context) {
 		return
 	}
 
+	for i, rule := range req.Rules {
+		if err := gateway.ValidateRuleCondition(rule.ConditionType, rule.ConditionValue); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid routing rule",
+				"details": fmt.Sprintf("rules[%d] (%s): %v", i, rule.Name, err),
+			})
+			return
+		}
+	}
+
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -158,7 +164,7 @@ func (h *CustomRulesHandler) SetCustomRules(c *gin.Context) {
 
 type CustomRuleRequest struct {
 	Name          string                 `json:"name" binding:"required"`
-	ConditionType string                 `json:"condition_type" binding:"required"` // 'model', 'cost_threshold', 'latency_threshold'
+	ConditionType string                 `json:"condition_type" binding:"required"` // see the gateway.Condition* constants; 'and', 'or' and 'not' nest conditions
 	ConditionValue map[string]interface{} `json:"condition_value" binding:"required"`
 	ProviderName  string                 `json:"provider_name" binding:"required"`
 	Priority      int                    `json:"priority"`
@@ -184,43 +190,10 @@ func (h *CustomRulesHandler) reloadRouterRules(ctx context.Context) error {
 		return err
 	}
 
-	routingRules := make([]gateway.RoutingRule, 0, len(rules))
-	for _, rule := range rules {
-		routingRule := gateway.RoutingRule{
-			Provider:  rule.ProviderName,
-			Priority:  rule.Priority,
-			Condition: h.buildCondition(rule),
-		}
-		routingRules = append(routingRules, routingRule)
-	}
-
+	routingRules := h.router.GetRuleEngine().BuildRoutingRules(gateway.CustomRulesFromStorage(rules))
 	customStrategy := gateway.NewCustomStrategy(routingRules)
 	h.router.SetCustomStrategy(customStrategy)
 
 	return nil
 }
 
-func (h *CustomRulesHandler) buildCondition(rule *storage.CustomRoutingRule) func(req providers.ChatRequest) bool {
-	return func(req providers.ChatRequest) bool {
-		switch rule.ConditionType {
-		case "model":
-			if model, ok := rule.ConditionValue["model"].(string); ok {
-				return req.Model == model
-			}
-		case "cost_threshold":
-			if maxCost, ok := rule.ConditionValue["max_cost"].(float64); ok {
-				estimatedCost := h.costCalculator.EstimateCost(rule.ProviderName, req.Model, req.Messages)
-				return estimatedCost <= maxCost
-			}
-			return false
-		case "latency_threshold":
-			if maxLatencyMs, ok := rule.ConditionValue["max_latency_ms"].(float64); ok {
-				avgLatency := h.latencyTracker.GetAverageLatency(rule.ProviderName)
-				return avgLatency.Milliseconds() <= int64(maxLatencyMs)
-			}
-			return false
-		}
-		return false
-	}
-}
-
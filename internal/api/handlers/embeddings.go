@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/gin-gonic/gin"
+)
+
+// EmbeddingsRequest follows the OpenAI embeddings request body.
+type EmbeddingsRequest struct {
+	Model          string      `json:"model"`
+	Input          interface{} `json:"input"`
+	Dimensions     int         `json:"dimensions,omitempty"`
+	EncodingFormat string      `json:"encoding_format,omitempty"`
+	User           string      `json:"user,omitempty"`
+}
+
+type embeddingsResponse struct {
+	Object string          `json:"object"`
+	Data   []embeddingData `json:"data"`
+	Model  string          `json:"model"`
+	Usage  embeddingsUsage `json:"usage"`
+}
+
+type embeddingData struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"`
+}
+
+type embeddingsUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// embeddingInputs accepts a single string or an array of strings. Token-array inputs are rejected.
+func embeddingInputs(input interface{}) ([]string, error) {
+	switch v := input.(type) {
+	case string:
+		if v == "" {
+			return nil, fmt.Errorf("input must not be empty")
+		}
+		return []string{v}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("input must not be empty")
+		}
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("input must be a string or an array of strings")
+			}
+			if s == "" {
+				return nil, fmt.Errorf("input must not contain empty strings")
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+}
+
+// encodeEmbeddingBase64 packs a vector as little-endian float32, as OpenAI does for encoding_format=base64.
+func encodeEmbeddingBase64(vector []float64) string {
+	var buf bytes.Buffer
+	for _, v := range vector {
+		_ = binary.Write(&buf, binary.LittleEndian, math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// HandleEmbeddings serves POST /v1/embeddings using the OpenAI wire format.
+func (h *ChatHandler) HandleEmbeddings(c *gin.Context) {
+	var req EmbeddingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Model == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "you must provide a model parameter")
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "encoding_format must be float or base64")
+		return
+	}
+	inputs, err := embeddingInputs(req.Input)
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	apiKeyID, userID := requestIdentity(c)
+	startTime := time.Now()
+	resp, err := h.router.RouteEmbedding(c.Request.Context(), providers.EmbeddingRequest{
+		Model:      req.Model,
+		Input:      inputs,
+		Dimensions: req.Dimensions,
+	}, userID)
+	latency := time.Since(startTime)
+
+	if err != nil {
+		writeProviderError(c, err)
+		h.recordCompletion(c.Request.Context(), apiKeyID, userID, "unknown", req.Model, "embedding", nil, latency, false, nil, nil, err)
+		return
+	}
+
+	data := make([]embeddingData, 0, len(resp.Embeddings))
+	for i, vector := range resp.Embeddings {
+		var embedding interface{} = vector
+		if req.EncodingFormat == "base64" {
+			embedding = encodeEmbeddingBase64(vector)
+		}
+		data = append(data, embeddingData{Object: "embedding", Index: i, Embedding: embedding})
+	}
+
+	c.Header("X-UniRoute-Provider", resp.Provider)
+	c.JSON(http.StatusOK, embeddingsResponse{
+		Object: "list",
+		Data:   data,
+		Model:  resp.Model,
+		Usage: embeddingsUsage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+	})
+
+	h.recordCompletion(c.Request.Context(), apiKeyID, userID, resp.Provider, req.Model, "embedding", &resp.Usage, latency, false, nil, nil, nil)
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type ExperimentHandler struct {
+	router         *gateway.Router
+	experimentRepo *storage.ExperimentRepository
+	logger         zerolog.Logger
+}
+
+func NewExperimentHandler(router *gateway.Router, experimentRepo *storage.ExperimentRepository, logger zerolog.Logger) *ExperimentHandler {
+	return &ExperimentHandler{
+		router:         router,
+		experimentRepo: experimentRepo,
+		logger:         logger,
+	}
+}
+
+type ExperimentRequest struct {
+	Name        string                      `json:"name" binding:"required"`
+	Model       string                      `json:"model" binding:"required"`
+	Variants    []storage.ExperimentVariant `json:"variants" binding:"required"`
+	StickyBy    string                      `json:"sticky_by"`
+	Enabled     *bool                       `json:"enabled"`
+	Description string                      `json:"description"`
+}
+
+func (r *ExperimentRequest) validate() error {
+	if strings.TrimSpace(r.Name) == "" || strings.TrimSpace(r.Model) == "" {
+		return fmt.Errorf("name and model are required")
+	}
+	if r.StickyBy != "" && r.StickyBy != gateway.StickyByUser && r.StickyBy != gateway.StickyByAPIKey {
+		return fmt.Errorf("sticky_by must be %q or %q", gateway.StickyByUser, gateway.StickyByAPIKey)
+	}
+	if len(r.Variants) < 2 {
+		return fmt.Errorf("an experiment needs at least two variants")
+	}
+	seen := make(map[string]bool, len(r.Variants))
+	total := 0
+	for i, v := range r.Variants {
+		name := strings.TrimSpace(v.Name)
+		if name == "" || strings.TrimSpace(v.Model) == "" {
+			return fmt.Errorf("variant %d needs a name and a model", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate variant name %q", name)
+		}
+		seen[name] = true
+		if v.Weight < 0 {
+			return fmt.Errorf("variant %q: weight must not be negative", name)
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one variant needs a positive weight")
+	}
+	return nil
+}
+
+func (r *ExperimentRequest) toModel() *storage.Experiment {
+	exp := &storage.Experiment{
+		Name:     strings.TrimSpace(r.Name),
+		Model:    strings.ToLower(strings.TrimSpace(r.Model)),
+		Variants: make([]storage.ExperimentVariant, 0, len(r.Variants)),
+		StickyBy: r.StickyBy,
+		Enabled:  r.Enabled == nil || *r.Enabled,
+	}
+	if exp.StickyBy == "" {
+		exp.StickyBy = gateway.StickyByUser
+	}
+	for _, v := range r.Variants {
+		v.Name = strings.TrimSpace(v.Name)
+		v.Model = strings.TrimSpace(v.Model)
+		exp.Variants = append(exp.Variants, v)
+	}
+	if r.Description != "" {
+		exp.Description = &r.Description
+	}
+	return exp
+}
+
+func (h *ExperimentHandler) ListExperiments(c *gin.Context) {
+	experiments, err := h.experimentRepo.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list experiments")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve experiments",
+		})
+		return
+	}
+	if experiments == nil {
+		experiments = []*storage.Experiment{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"experiments": experiments,
+	})
+}
+
+func (h *ExperimentHandler) GetExperiment(c *gin.Context) {
+	id, ok := experimentID(c)
+	if !ok {
+		return
+	}
+	exp, err := h.experimentRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.writeRepoError(c, err, "Failed to retrieve experiment")
+		return
+	}
+	c.JSON(http.StatusOK, exp)
+}
+
+func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
+	var req ExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	exp := req.toModel()
+	exp.CreatedBy = requestUserID(c)
+	if err := h.experimentRepo.Create(c.Request.Context(), exp); err != nil {
+		h.logger.Error().Err(err).Str("model", exp.Model).Msg("Failed to create experiment")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create experiment (only one enabled experiment per model is allowed)",
+		})
+		return
+	}
+	h.router.InvalidateExperiments()
+	c.JSON(http.StatusCreated, exp)
+}
+
+func (h *ExperimentHandler) UpdateExperiment(c *gin.Context) {
+	id, ok := experimentID(c)
+	if !ok {
+		return
+	}
+	var req ExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	exp := req.toModel()
+	exp.ID = id
+	if err := h.experimentRepo.Update(c.Request.Context(), exp); err != nil {
+		h.writeRepoError(c, err, "Failed to update experiment")
+		return
+	}
+	h.router.InvalidateExperiments()
+	c.JSON(http.StatusOK, exp)
+}
+
+func (h *ExperimentHandler) DeleteExperiment(c *gin.Context) {
+	id, ok := experimentID(c)
+	if !ok {
+		return
+	}
+	if err := h.experimentRepo.Delete(c.Request.Context(), id); err != nil {
+		h.writeRepoError(c, err, "Failed to delete experiment")
+		return
+	}
+	h.router.InvalidateExperiments()
+	c.JSON(http.StatusOK, gin.H{
+		"message": "experiment deleted",
+	})
+}
+
+func (h *ExperimentHandler) writeRepoError(c *gin.Context, err error, message string) {
+	if errors.Is(err, storage.ErrExperimentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "experiment not found",
+		})
+		return
+	}
+	h.logger.Error().Err(err).Msg(message)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
+
+func experimentID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid experiment id",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// assignExperiment rewrites req.Model to the caller's experiment variant, if an experiment
+// is running for the requested model.
+func (h *ChatHandler) assignExperiment(ctx context.Context, req providers.ChatRequest, apiKeyID, userID *uuid.UUID) (providers.ChatRequest, *gateway.ExperimentAssignment) {
+	var subject gateway.ExperimentSubject
+	if apiKeyID != nil {
+		subject.APIKeyID = apiKeyID.String()
+	}
+	if userID != nil {
+		subject.UserID = userID.String()
+	}
+	return h.router.AssignExperiment(ctx, req, subject)
+}
+
+func tagExperiment(record *storage.Request, assignment *gateway.ExperimentAssignment) {
+	if assignment == nil {
+		return
+	}
+	id := assignment.ExperimentID
+	variant := assignment.Variant
+	record.ExperimentID = &id
+	record.ExperimentVariant = &variant
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// hedgeHeader opts a single request in or out of hedging: "true" hedges at the API key's
+// percentile (p95 if the key has none), "90" or "p90" at that percentile, and "false"
+// turns off hedging the key has enabled.
+const hedgeHeader = "X-UniRoute-Hedge"
+
+// hedgeRequestType is the request_type of the losing calls of a hedged request.
+const hedgeRequestType = "chat_hedge"
+
+// statusClientClosedRequest is recorded for hedge calls cancelled because another call won.
+const statusClientClosedRequest = 499
+
+// hedgeContext attaches the hedge policy of the request: X-UniRoute-Hedge if set,
+// otherwise the API key's hedge_percentile.
+func hedgeContext(c *gin.Context, ctx context.Context) (context.Context, error) {
+	percentile := 0
+	if record, ok := c.Get("api_key_record"); ok {
+		if key, ok := record.(*storage.APIKey); ok && key != nil {
+			percentile = key.HedgePercentile
+		}
+	}
+	if value := strings.TrimSpace(c.GetHeader(hedgeHeader)); value != "" {
+		if on, err := strconv.ParseBool(value); err == nil {
+			if !on {
+				percentile = 0
+			} else if percentile == 0 {
+				percentile = gateway.DefaultHedgePercentile
+			}
+		} else if p, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(value), "p")); err == nil && p >= 1 && p <= 99 {
+			percentile = p
+		} else {
+			return ctx, fmt.Errorf("%s must be true, false or a percentile between 1 and 99", hedgeHeader)
+		}
+	}
+	if percentile <= 0 {
+		return ctx, nil
+	}
+	return gateway.WithHedgePolicy(ctx, gateway.HedgePolicy{Percentile: float64(percentile)}), nil
+}
+
+// recordHedgeAttempts records the losing calls of a hedged request as chat_hedge
+// requests with their cost. It returns the hedge ID that links them to the winning
+// request, or nil if the request was not hedged.
+func (h *ChatHandler) recordHedgeAttempts(ctx context.Context, apiKeyID, userID *uuid.UUID, attempts []providers.HedgeAttempt) *uuid.UUID {
+	if len(attempts) == 0 {
+		return nil
+	}
+	hedgeID := uuid.New()
+	for _, attempt := range attempts {
+		monitoring.RecordTokens(attempt.Provider, attempt.Model, "input", attempt.Usage.PromptTokens)
+		monitoring.RecordTokens(attempt.Provider, attempt.Model, "output", attempt.Usage.CompletionTokens)
+		if attempt.Cost > 0 {
+			monitoring.RecordCost(attempt.Provider, attempt.Model, attempt.Cost)
+		}
+		h.recordSpend(apiKeyID, userID, attempt.Cost)
+
+		if h.requestRepo == nil {
+			continue
+		}
+		billableCost := attempt.Cost
+		if userID != nil && billableCost > 0 && h.router.UserHasProviderKey(ctx, *userID, attempt.Provider) {
+			billableCost = 0
+		}
+		statusCode := http.StatusOK
+		var errorMsg *string
+		switch {
+		case attempt.Cancelled:
+			statusCode = statusClientClosedRequest
+			msg := "cancelled: another provider answered first"
+			errorMsg = &msg
+		case attempt.Error != "":
+			statusCode = http.StatusBadGateway
+			msg := attempt.Error
+			errorMsg = &msg
+		}
+		record := &storage.Request{
+			ID:           uuid.New(),
+			APIKeyID:     apiKeyID,
+			UserID:       userID,
+			Provider:     attempt.Provider,
+			Model:        attempt.Model,
+			RequestType:  hedgeRequestType,
+			InputTokens:  attempt.Usage.PromptTokens,
+			OutputTokens: attempt.Usage.CompletionTokens,
+			TotalTokens:  attempt.Usage.TotalTokens,
+			Cost:         billableCost,
+			LatencyMs:    int(attempt.LatencyMs),
+			StatusCode:   statusCode,
+			ErrorMessage: errorMsg,
+			HedgeID:      &hedgeID,
+			CreatedAt:    time.Now(),
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := h.requestRepo.Create(ctx, record); err != nil {
+				h.logger.Error().Err(err).Msg("Failed to track hedged request")
+			}
+		}()
+	}
+	return &hedgeID
+}
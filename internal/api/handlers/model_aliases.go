@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type ModelAliasHandler struct {
+	router    *gateway.Router
+	aliasRepo *storage.ModelAliasRepository
+	logger    zerolog.Logger
+}
+
+func NewModelAliasHandler(router *gateway.Router, aliasRepo *storage.ModelAliasRepository, logger zerolog.Logger) *ModelAliasHandler {
+	return &ModelAliasHandler{
+		router:    router,
+		aliasRepo: aliasRepo,
+		logger:    logger,
+	}
+}
+
+type ModelAliasRequest struct {
+	Alias       string                     `json:"alias" binding:"required"`
+	Targets     []storage.ModelAliasTarget `json:"targets" binding:"required"`
+	Description string                     `json:"description"`
+	Enabled     *bool                      `json:"enabled"`
+}
+
+// Aliases share the "model" field with concrete names, so they may not look like
+// Ollama ("name:tag") or Hugging Face ("org/name") model IDs.
+var aliasNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+func (r *ModelAliasRequest) validate() error {
+	name := strings.ToLower(strings.TrimSpace(r.Alias))
+	if !aliasNamePattern.MatchString(name) {
+		return fmt.Errorf("alias %q must be 1-100 characters of letters, digits, '.', '_' or '-'", r.Alias)
+	}
+	if len(r.Targets) == 0 {
+		return fmt.Errorf("alias %q needs at least one target", r.Alias)
+	}
+	for i, target := range r.Targets {
+		if strings.TrimSpace(target.Provider) == "" || strings.TrimSpace(target.Model) == "" {
+			return fmt.Errorf("alias %q target %d needs a provider and a model", r.Alias, i)
+		}
+		if target.Temperature != nil && (*target.Temperature < 0 || *target.Temperature > 2) {
+			return fmt.Errorf("alias %q target %d: temperature must be between 0 and 2", r.Alias, i)
+		}
+		if target.MaxTokens != nil && *target.MaxTokens <= 0 {
+			return fmt.Errorf("alias %q target %d: max_tokens must be positive", r.Alias, i)
+		}
+	}
+	return nil
+}
+
+func (r *ModelAliasRequest) toModel() *storage.ModelAlias {
+	alias := &storage.ModelAlias{
+		Alias:   strings.ToLower(strings.TrimSpace(r.Alias)),
+		Targets: make([]storage.ModelAliasTarget, 0, len(r.Targets)),
+		Enabled: r.Enabled == nil || *r.Enabled,
+	}
+	for _, target := range r.Targets {
+		target.Provider = strings.ToLower(strings.TrimSpace(target.Provider))
+		target.Model = strings.TrimSpace(target.Model)
+		alias.Targets = append(alias.Targets, target)
+	}
+	if r.Description != "" {
+		alias.Description = &r.Description
+	}
+	return alias
+}
+
+func isAdminPath(c *gin.Context) bool {
+	return strings.HasPrefix(c.FullPath(), "/admin")
+}
+
+func requestUserID(c *gin.Context) *uuid.UUID {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	if uid, ok := userIDVal.(uuid.UUID); ok {
+		return &uid
+	}
+	if idStr, ok := userIDVal.(string); ok {
+		if uid, err := uuid.Parse(idStr); err == nil {
+			return &uid
+		}
+	}
+	return nil
+}
+
+// GetModelAliases lists global aliases on /admin routes, and the caller's own plus global aliases otherwise.
+func (h *ModelAliasHandler) GetModelAliases(c *gin.Context) {
+	isAdminRequest := isAdminPath(c)
+	var userID *uuid.UUID
+	if !isAdminRequest {
+		userID = requestUserID(c)
+		if userID == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "user not authenticated",
+			})
+			return
+		}
+	}
+
+	aliases, err := h.aliasRepo.GetAliasesForUser(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to get model aliases")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve model aliases",
+		})
+		return
+	}
+	if aliases == nil {
+		aliases = []*storage.ModelAlias{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"aliases":       aliases,
+		"count":         len(aliases),
+		"user_specific": !isAdminRequest,
+	})
+}
+
+// SetModelAliases replaces the global aliases on /admin routes, or the caller's own aliases otherwise.
+func (h *ModelAliasHandler) SetModelAliases(c *gin.Context) {
+	var req struct {
+		Aliases []ModelAliasRequest `json:"aliases"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "user not authenticated",
+		})
+		return
+	}
+
+	seen := make(map[string]bool)
+	aliases := make([]*storage.ModelAlias, 0, len(req.Aliases))
+	for i := range req.Aliases {
+		if err := req.Aliases[i].validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		alias := req.Aliases[i].toModel()
+		if seen[alias.Alias] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("alias %q is defined more than once", alias.Alias),
+			})
+			return
+		}
+		seen[alias.Alias] = true
+		aliases = append(aliases, alias)
+	}
+
+	isAdminRequest := isAdminPath(c)
+	saveUserID := userID
+	if isAdminRequest {
+		saveUserID = nil
+	}
+
+	if err := h.aliasRepo.SaveAliasesForUser(c.Request.Context(), aliases, saveUserID, userID); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to save model aliases")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save model aliases",
+		})
+		return
+	}
+	h.router.InvalidateModelAliases()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Model aliases updated successfully",
+		"count":         len(aliases),
+		"user_specific": !isAdminRequest,
+	})
+}
@@ -0,0 +1,651 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OpenAIChatCompletionRequest mirrors the OpenAI Chat Completions request body so
+// existing SDKs can talk to UniRoute by only changing their base URL. Sampling
+// parameters are forwarded; providers without one reject the request. Other fields
+// are accepted and ignored.
+type OpenAIChatCompletionRequest struct {
+	Model               string                    `json:"model"`
+	Messages            []providers.Message       `json:"messages"`
+	Temperature         *float64                  `json:"temperature,omitempty"`
+	TopP                *float64                  `json:"top_p,omitempty"`
+	N                   *int                      `json:"n,omitempty"`
+	Stream              bool                      `json:"stream,omitempty"`
+	StreamOptions       *openAIStreamOptions      `json:"stream_options,omitempty"`
+	Stop                interface{}               `json:"stop,omitempty"`
+	MaxTokens           *int                      `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int                      `json:"max_completion_tokens,omitempty"`
+	PresencePenalty     *float64                  `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64                  `json:"frequency_penalty,omitempty"`
+	LogitBias           map[string]float64        `json:"logit_bias,omitempty"`
+	Logprobs            bool                      `json:"logprobs,omitempty"`
+	TopLogprobs         *int                      `json:"top_logprobs,omitempty"`
+	Seed                *int                      `json:"seed,omitempty"`
+	User                string                    `json:"user,omitempty"`
+	ResponseFormat      *providers.ResponseFormat `json:"response_format,omitempty"`
+	Tools               []providers.Tool          `json:"tools,omitempty"`
+	ToolChoice          *providers.ToolChoice     `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool                     `json:"parallel_tool_calls,omitempty"`
+	ReasoningEffort     string                    `json:"reasoning_effort,omitempty"`
+	ServiceTier         string                    `json:"service_tier,omitempty"`
+	Store               *bool                     `json:"store,omitempty"`
+	Metadata            map[string]string         `json:"metadata,omitempty"`
+	Modalities          []string                  `json:"modalities,omitempty"`
+	WebSearchOptions    map[string]interface{}    `json:"web_search_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIChatCompletion struct {
+	ID      string                       `json:"id"`
+	Object  string                       `json:"object"`
+	Created int64                        `json:"created"`
+	Model   string                       `json:"model"`
+	Choices []openAIChatCompletionChoice `json:"choices"`
+	Usage   openAIUsage                  `json:"usage"`
+	Cached  bool                         `json:"cached,omitempty"`
+	Debug   *gateway.RoutingTrace        `json:"debug,omitempty"`
+}
+
+type openAIChatCompletionChoice struct {
+	Index        int                   `json:"index"`
+	Message      openAIResponseMessage `json:"message"`
+	Logprobs     interface{}           `json:"logprobs"`
+	FinishReason string                `json:"finish_reason"`
+}
+
+type openAIResponseMessage struct {
+	Role      string               `json:"role"`
+	Content   *string              `json:"content"`
+	ToolCalls []providers.ToolCall `json:"tool_calls,omitempty"`
+}
+
+type openAIChatCompletionChunk struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []openAIChunkChoice `json:"choices"`
+	Usage   *openAIUsage        `json:"usage,omitempty"`
+}
+
+type openAIChunkChoice struct {
+	Index        int         `json:"index"`
+	Delta        openAIDelta `json:"delta"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+type openAIDelta struct {
+	Role      string                    `json:"role,omitempty"`
+	Content   *string                   `json:"content,omitempty"`
+	ToolCalls []providers.ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIErrorBody struct {
+	Error openAIError `json:"error"`
+}
+
+type openAIError struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Param   interface{} `json:"param"`
+	Code    interface{} `json:"code"`
+}
+
+func writeOpenAIError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, openAIErrorBody{Error: openAIError{Message: message, Type: errType}})
+}
+
+// providerErrorStatus maps a routing error to the HTTP status and OpenAI error type
+// returned to the caller. Requests blocked by a hook are 403s, output that does not match
+// response_format is a 422; unclassified errors stay 500s.
+func providerErrorStatus(err error) (int, string) {
+	if _, blocked := gateway.AsHookBlockedError(err); blocked {
+		return http.StatusForbidden, "invalid_request_error"
+	}
+	if _, invalid := gateway.AsStructuredOutputError(err); invalid {
+		return http.StatusUnprocessableEntity, "api_error"
+	}
+	perr, ok := providers.AsProviderError(err)
+	if !ok {
+		return http.StatusInternalServerError, "api_error"
+	}
+	switch perr.Kind {
+	case providers.ErrorKindRateLimit:
+		return perr.HTTPStatus(), "rate_limit_error"
+	case providers.ErrorKindInvalidRequest, providers.ErrorKindContextLength, providers.ErrorKindContentFilter, providers.ErrorKindNotFound:
+		return perr.HTTPStatus(), "invalid_request_error"
+	default:
+		return perr.HTTPStatus(), "api_error"
+	}
+}
+
+// writeProviderError writes a routing error in OpenAI's format, passing on the
+// upstream status and Retry-After.
+func writeProviderError(c *gin.Context, err error) {
+	status, errType := providerErrorStatus(err)
+	body := openAIError{Message: err.Error(), Type: errType}
+	if _, blocked := gateway.AsHookBlockedError(err); blocked {
+		body.Code = "request_blocked"
+	}
+	if _, invalid := gateway.AsStructuredOutputError(err); invalid {
+		body.Code = "structured_output_invalid"
+	}
+	if perr, ok := providers.AsProviderError(err); ok {
+		body.Code = string(perr.Kind)
+		if perr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(perr.RetryAfter.Seconds()))))
+		}
+	}
+	c.JSON(status, openAIErrorBody{Error: body})
+}
+
+// toChatRequest converts the OpenAI wire format into UniRoute's provider-neutral request.
+func (r *OpenAIChatCompletionRequest) toChatRequest() providers.ChatRequest {
+	messages := make([]providers.Message, 0, len(r.Messages))
+	for _, msg := range r.Messages {
+		// "developer" is OpenAI's newer name for the system role; other providers only know "system".
+		if msg.Role == "developer" {
+			msg.Role = "system"
+		}
+		if msg.Content == nil {
+			msg.Content = ""
+		}
+		messages = append(messages, msg)
+	}
+
+	req := providers.ChatRequest{
+		Model:            r.Model,
+		Messages:         messages,
+		Tools:            r.Tools,
+		ToolChoice:       r.ToolChoice,
+		Temperature:      r.Temperature,
+		TopP:             r.TopP,
+		PresencePenalty:  r.PresencePenalty,
+		FrequencyPenalty: r.FrequencyPenalty,
+		Seed:             r.Seed,
+		LogitBias:        r.LogitBias,
+	}
+	req.Stop, _ = stopSequences(r.Stop)
+	if r.ResponseFormat.WantsJSON() {
+		req.ResponseFormat = r.ResponseFormat
+	}
+	if r.MaxCompletionTokens != nil {
+		req.MaxTokens = *r.MaxCompletionTokens
+	} else if r.MaxTokens != nil {
+		req.MaxTokens = *r.MaxTokens
+	}
+	if r.WebSearchOptions != nil {
+		req.WebSearch = true
+		req.GoogleSearchGrounding = true
+	}
+	return req
+}
+
+func (r *OpenAIChatCompletionRequest) validate() string {
+	if r.Model == "" {
+		return "you must provide a model parameter"
+	}
+	if len(r.Messages) == 0 {
+		return "messages must be a non-empty array"
+	}
+	if r.N != nil && *r.N > 1 {
+		return "n > 1 is not supported"
+	}
+	if r.Logprobs || r.TopLogprobs != nil {
+		return "logprobs are not supported"
+	}
+	if _, ok := stopSequences(r.Stop); !ok {
+		return "stop must be a string or an array of strings"
+	}
+	if r.ToolChoice != nil && len(r.Tools) == 0 {
+		return "tool_choice is only allowed when tools are specified"
+	}
+	for _, tool := range r.Tools {
+		if tool.Type != "" && tool.Type != "function" {
+			return fmt.Sprintf("unsupported tool type %q", tool.Type)
+		}
+		if tool.Function.Name == "" {
+			return "tools[].function.name is required"
+		}
+	}
+	if r.ResponseFormat != nil {
+		if err := r.ResponseFormat.Validate(); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+// stopSequences reads stop, which OpenAI accepts as a string or an array of strings.
+func stopSequences(stop interface{}) ([]string, bool) {
+	switch stop := stop.(type) {
+	case nil:
+		return nil, true
+	case string:
+		return []string{stop}, true
+	case []interface{}:
+		out := make([]string, 0, len(stop))
+		for _, s := range stop {
+			str, ok := s.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, str)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func messageContentText(content interface{}) string {
+	text, parts := providers.NormalizeMessageContent(content)
+	if parts == nil {
+		return text
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if part.Type == "text" {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
+
+// HandleChatCompletions serves POST /v1/chat/completions using the OpenAI wire format.
+func (h *ChatHandler) HandleChatCompletions(c *gin.Context) {
+	var oaReq OpenAIChatCompletionRequest
+	if err := c.ShouldBindJSON(&oaReq); err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if msg := oaReq.validate(); msg != "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", msg)
+		return
+	}
+
+	apiKeyID, userID := requestIdentity(c)
+	req, experiment := h.assignExperiment(c.Request.Context(), oaReq.toChatRequest(), apiKeyID, userID)
+
+	if oaReq.Stream {
+		includeUsage := oaReq.StreamOptions != nil && oaReq.StreamOptions.IncludeUsage
+		h.streamChatCompletion(c, req, apiKeyID, userID, includeUsage, experiment)
+		return
+	}
+
+	routeCtx, cacheEnabled := cacheContext(c, req)
+	routeCtx, err := hedgeContext(c, routeCtx)
+	if err == nil {
+		routeCtx, err = structuredOutputContext(c, routeCtx)
+	}
+	if err == nil {
+		routeCtx, err = contextWindowContext(c, routeCtx, nil)
+	}
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	routeCtx, trace := routingTrace(c, routeCtx)
+	startTime := time.Now()
+	resp, err := h.router.Route(routeCtx, req, userID)
+	latency := time.Since(startTime)
+
+	if err != nil {
+		writeProviderError(c, err)
+		h.recordCompletion(c.Request.Context(), apiKeyID, userID, "unknown", req.Model, "chat", nil, latency, false, experiment, nil, err)
+		return
+	}
+
+	created := time.Now().Unix()
+	id := resp.ID
+	if id == "" {
+		id = "chatcmpl-" + uuid.New().String()
+	}
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+
+	choices := make([]openAIChatCompletionChoice, 0, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		content := messageContentText(choice.Message.Content)
+		role := choice.Message.Role
+		if role == "" {
+			role = "assistant"
+		}
+		message := openAIResponseMessage{Role: role, Content: &content, ToolCalls: choice.Message.ToolCalls}
+		if content == "" && len(message.ToolCalls) > 0 {
+			message.Content = nil
+		}
+		choices = append(choices, openAIChatCompletionChoice{
+			Index:        i,
+			Message:      message,
+			FinishReason: completionFinishReason(choice.FinishReason, len(message.ToolCalls) > 0),
+		})
+	}
+
+	c.Header("X-UniRoute-Provider", resp.Provider)
+	if cacheEnabled {
+		setCacheStatusHeader(c, resp.Cached)
+	}
+	c.JSON(http.StatusOK, openAIChatCompletion{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   model,
+		Choices: choices,
+		Usage: openAIUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+		Cached: resp.Cached,
+		Debug:  trace,
+	})
+
+	h.recordCompletion(c.Request.Context(), apiKeyID, userID, resp.Provider, model, "chat", &resp.Usage, latency, resp.Cached, experiment, resp.HedgeAttempts, nil)
+}
+
+// streamOptionsError rejects per-request options that only work once the whole
+// response is known: hedging, structured output retries and the debug trace. An API
+// key's hedge_percentile does not apply to streams.
+func streamOptionsError(c *gin.Context) error {
+	if value := strings.TrimSpace(c.GetHeader(hedgeHeader)); value != "" {
+		if on, err := strconv.ParseBool(value); err != nil || on {
+			return fmt.Errorf("%s is not supported for streaming requests", hedgeHeader)
+		}
+	}
+	if value := strings.TrimSpace(c.GetHeader(outputRetriesHeader)); value != "" && value != "0" {
+		return fmt.Errorf("%s is not supported for streaming requests", outputRetriesHeader)
+	}
+	if repair, _ := strconv.ParseBool(c.GetHeader(outputRepairHeader)); repair {
+		return fmt.Errorf("%s is not supported for streaming requests", outputRepairHeader)
+	}
+	if debug, _ := strconv.ParseBool(c.GetHeader(debugHeader)); debug {
+		return fmt.Errorf("%s is not supported for streaming requests; use /v1/routing/explain", debugHeader)
+	}
+	return nil
+}
+
+func (h *ChatHandler) streamChatCompletion(c *gin.Context, req providers.ChatRequest, apiKeyID, userID *uuid.UUID, includeUsage bool, experiment *gateway.ExperimentAssignment) {
+	if err := streamOptionsError(c); err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	ctx, cacheEnabled := cacheContext(c, req)
+	ctx, err := contextWindowContext(c, ctx, nil)
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	startTime := time.Now()
+	chunkChan, errChan := h.router.RouteStream(ctx, req, userID)
+
+	id := "chatcmpl-" + uuid.New().String()
+	created := time.Now().Unix()
+	provider := "unknown"
+	servedModel := req.Model // an alias's target model, priced in place of the alias
+	var finalUsage *providers.Usage
+	finishReason := ""
+	sawToolCalls := false
+	headersSent := false
+	cached := false
+
+	writeEvent := func(payload interface{}) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Failed to marshal chat completion chunk")
+			return
+		}
+		fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+		c.Writer.Flush()
+	}
+	newChunk := func(delta openAIDelta, finishReason *string) openAIChatCompletionChunk {
+		return openAIChatCompletionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   req.Model,
+			Choices: []openAIChunkChoice{{Index: 0, Delta: delta, FinishReason: finishReason}},
+		}
+	}
+	startStream := func() {
+		if headersSent {
+			return
+		}
+		headersSent = true
+		if cacheEnabled {
+			setCacheStatusHeader(c, cached)
+		}
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		empty := ""
+		writeEvent(newChunk(openAIDelta{Role: "assistant", Content: &empty}, nil))
+	}
+	fail := func(err error) {
+		if !headersSent {
+			writeProviderError(c, err)
+		} else {
+			_, errType := providerErrorStatus(err)
+			writeEvent(openAIErrorBody{Error: openAIError{Message: err.Error(), Type: errType}})
+		}
+		h.recordCompletion(context.WithoutCancel(c.Request.Context()), apiKeyID, userID, provider, servedModel, "chat_stream", finalUsage, time.Since(startTime), cached, experiment, nil, err)
+	}
+	finish := func() {
+		startStream()
+		stop := completionFinishReason(finishReason, sawToolCalls)
+		writeEvent(newChunk(openAIDelta{}, &stop))
+		if includeUsage {
+			usage := openAIUsage{}
+			if finalUsage != nil {
+				usage = openAIUsage{
+					PromptTokens:     finalUsage.PromptTokens,
+					CompletionTokens: finalUsage.CompletionTokens,
+					TotalTokens:      finalUsage.TotalTokens,
+				}
+			}
+			writeEvent(openAIChatCompletionChunk{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   req.Model,
+				Choices: []openAIChunkChoice{},
+				Usage:   &usage,
+			})
+		}
+		fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+		c.Writer.Flush()
+		h.recordCompletion(context.WithoutCancel(c.Request.Context()), apiKeyID, userID, provider, servedModel, "chat_stream", finalUsage, time.Since(startTime), cached, experiment, nil, nil)
+	}
+
+	for {
+		select {
+		case chunk, ok := <-chunkChan:
+			if !ok {
+				if errChan != nil {
+					if streamErr, ok := <-errChan; ok && streamErr != nil {
+						fail(streamErr)
+						return
+					}
+				}
+				if !headersSent {
+					fail(fmt.Errorf("stream ended with no response from provider"))
+					return
+				}
+				finish()
+				return
+			}
+			if chunk.Provider != "" {
+				provider = chunk.Provider
+			}
+			if chunk.Model != "" {
+				servedModel = chunk.Model
+			}
+			if chunk.Usage != nil {
+				finalUsage = chunk.Usage
+			}
+			if chunk.Cached {
+				cached = true
+			}
+			if chunk.Error != "" {
+				fail(fmt.Errorf("%s", chunk.Error))
+				return
+			}
+			if chunk.Content != "" {
+				startStream()
+				content := chunk.Content
+				writeEvent(newChunk(openAIDelta{Content: &content}, nil))
+			}
+			if len(chunk.ToolCalls) > 0 {
+				startStream()
+				sawToolCalls = true
+				writeEvent(newChunk(openAIDelta{ToolCalls: chunk.ToolCalls}, nil))
+			}
+			if chunk.FinishReason != "" {
+				finishReason = chunk.FinishReason
+			}
+			if chunk.Done {
+				finish()
+				return
+			}
+
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func completionFinishReason(reason string, hasToolCalls bool) string {
+	if reason != "" {
+		return reason
+	}
+	if hasToolCalls {
+		return providers.FinishReasonToolCalls
+	}
+	return providers.FinishReasonStop
+}
+
+func requestIdentity(c *gin.Context) (apiKeyID, userID *uuid.UUID) {
+	if id, ok := c.Get("api_key_id"); ok {
+		if s, ok := id.(string); ok {
+			if parsed, err := uuid.Parse(s); err == nil {
+				apiKeyID = &parsed
+			}
+		}
+	}
+	if id, ok := c.Get("user_id"); ok {
+		if s, ok := id.(string); ok {
+			if parsed, err := uuid.Parse(s); err == nil {
+				userID = &parsed
+			}
+		}
+	}
+	return apiKeyID, userID
+}
+
+// recordCompletion records a finished request: metrics, budget spend, token usage and a
+// requests row. The losing calls of a hedged request are recorded alongside it.
+func (h *ChatHandler) recordCompletion(ctx context.Context, apiKeyID, userID *uuid.UUID, provider, model, requestType string, usage *providers.Usage, latency time.Duration, cached bool, experiment *gateway.ExperimentAssignment, hedged []providers.HedgeAttempt, callErr error) {
+	status := "success"
+	statusCode := http.StatusOK
+	var errorMsg *string
+	if callErr != nil {
+		status = "error"
+		statusCode, _ = providerErrorStatus(callErr)
+		msg := callErr.Error()
+		errorMsg = &msg
+	}
+
+	cost := 0.0
+	if usage != nil && callErr == nil && !cached {
+		cost = h.router.GetCostCalculator().CalculateActualCost(provider, model, *usage)
+		monitoring.RecordTokens(provider, model, "input", usage.PromptTokens)
+		monitoring.RecordTokens(provider, model, "output", usage.CompletionTokens)
+		if cost > 0 {
+			monitoring.RecordCost(provider, model, cost)
+		}
+	}
+	monitoring.RecordRequest(provider, model, status, latency.Seconds())
+	h.recordSpend(apiKeyID, userID, cost)
+	if callErr == nil {
+		reportTokenUsage(ctx, usage, cached)
+	}
+	hedgeID := h.recordHedgeAttempts(ctx, apiKeyID, userID, hedged)
+
+	if h.requestRepo == nil {
+		return
+	}
+	billableCost := cost
+	if userID != nil && cost > 0 && h.router.UserHasProviderKey(ctx, *userID, provider) {
+		billableCost = 0
+	}
+	record := &storage.Request{
+		ID:           uuid.New(),
+		APIKeyID:     apiKeyID,
+		UserID:       userID,
+		Provider:     provider,
+		Model:        model,
+		RequestType:  requestType,
+		Cost:         billableCost,
+		LatencyMs:    int(latency.Milliseconds()),
+		StatusCode:   statusCode,
+		ErrorMessage: errorMsg,
+		Cached:       cached,
+		HedgeID:      hedgeID,
+		CreatedAt:    time.Now(),
+	}
+	if usage != nil {
+		record.InputTokens = usage.PromptTokens
+		record.OutputTokens = usage.CompletionTokens
+		record.TotalTokens = usage.TotalTokens
+	}
+	tagExperiment(record, experiment)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := h.requestRepo.Create(ctx, record); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to track chat completion request")
+		}
+	}()
+}
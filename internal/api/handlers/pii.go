@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/pii"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// redactionContext attaches the PII policy of the request's API key, if it has one.
+func redactionContext(c *gin.Context, ctx context.Context) context.Context {
+	record, ok := c.Get("api_key_record")
+	if !ok {
+		return ctx
+	}
+	key, ok := record.(*storage.APIKey)
+	if !ok || key == nil || key.PIIPolicy == nil {
+		return ctx
+	}
+	policy := gateway.RedactionPolicy{SkipProviders: key.PIIPolicy.SkipProviders}
+	for _, name := range key.PIIPolicy.Types {
+		if t, ok := pii.ParseType(name); ok {
+			policy.Types = append(policy.Types, t)
+		}
+	}
+	return gateway.WithRedactionPolicy(ctx, policy)
+}
+
+// validatePIIPolicy normalizes the policy's type names and rejects unknown ones.
+func validatePIIPolicy(policy *storage.PIIPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	for i, name := range policy.Types {
+		t, ok := pii.ParseType(name)
+		if !ok {
+			return fmt.Errorf("unknown PII type %q (expected one of %v)", name, pii.AllTypes())
+		}
+		policy.Types[i] = string(t)
+	}
+	return nil
+}
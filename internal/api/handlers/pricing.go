@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// PricingHandler exposes the pricing catalog. Edits are stored in model_prices and
+// applied immediately by reloading the catalog.
+type PricingHandler struct {
+	router    *gateway.Router
+	priceRepo *storage.ModelPriceRepository
+	logger    zerolog.Logger
+}
+
+func NewPricingHandler(router *gateway.Router, priceRepo *storage.ModelPriceRepository, logger zerolog.Logger) *PricingHandler {
+	return &PricingHandler{
+		router:    router,
+		priceRepo: priceRepo,
+		logger:    logger,
+	}
+}
+
+// GetPricing returns the catalog in use, optionally filtered by ?provider=.
+func (h *PricingHandler) GetPricing(c *gin.Context) {
+	catalog := h.router.GetCostCalculator().Catalog()
+	prices := catalog.Prices
+	if provider := c.Query("provider"); provider != "" {
+		prices = make([]gateway.PriceEntry, 0)
+		for _, entry := range catalog.Prices {
+			if strings.EqualFold(entry.Provider, provider) {
+				prices = append(prices, entry)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": h.status(),
+		"prices": prices,
+	})
+}
+
+// LookupPrice resolves the price the gateway would charge for ?provider=&model=, at
+// ?at= (YYYY-MM-DD or RFC 3339) or now.
+func (h *PricingHandler) LookupPrice(c *gin.Context) {
+	provider := c.Query("provider")
+	model := c.Query("model")
+	if provider == "" || model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "provider and model are required",
+		})
+		return
+	}
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		date, err := gateway.ParsePriceDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		at = date.Time
+	}
+
+	entry, ok := h.router.GetCostCalculator().Lookup(provider, model, at)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":    gateway.ErrUnknownPrice.Error(),
+			"provider": provider,
+			"model":    model,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"provider": provider,
+		"model":    model,
+		"price":    entry,
+	})
+}
+
+// ReloadPricing re-reads the pricing file and database now.
+func (h *PricingHandler) ReloadPricing(c *gin.Context) {
+	loader := h.router.GetPricingLoader()
+	if loader == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "pricing catalog reloading is not configured",
+		})
+		return
+	}
+	changed, err := loader.Reload(c.Request.Context())
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to reload pricing catalog")
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  err.Error(),
+			"status": loader.Status(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"changed": changed,
+		"status":  loader.Status(),
+	})
+}
+
+func (h *PricingHandler) CreatePrice(c *gin.Context) {
+	entry, ok := bindPriceEntry(c)
+	if !ok {
+		return
+	}
+	price := modelPriceFromEntry(entry)
+	price.CreatedBy = requestUserID(c)
+	if err := h.priceRepo.Create(c.Request.Context(), price); err != nil {
+		h.logger.Error().Err(err).Str("provider", price.Provider).Str("model", price.Model).Msg("Failed to create model price")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create model price (only one price per model and effective_from is allowed)",
+		})
+		return
+	}
+	h.respondSaved(c, http.StatusCreated, price)
+}
+
+func (h *PricingHandler) UpdatePrice(c *gin.Context) {
+	id, ok := modelPriceID(c)
+	if !ok {
+		return
+	}
+	entry, ok := bindPriceEntry(c)
+	if !ok {
+		return
+	}
+	price := modelPriceFromEntry(entry)
+	price.ID = id
+	if err := h.priceRepo.Update(c.Request.Context(), price); err != nil {
+		h.writeRepoError(c, err, "Failed to update model price")
+		return
+	}
+	h.respondSaved(c, http.StatusOK, price)
+}
+
+func (h *PricingHandler) DeletePrice(c *gin.Context) {
+	id, ok := modelPriceID(c)
+	if !ok {
+		return
+	}
+	if err := h.priceRepo.Delete(c.Request.Context(), id); err != nil {
+		h.writeRepoError(c, err, "Failed to delete model price")
+		return
+	}
+	h.reload(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "model price deleted",
+		"status":  h.status(),
+	})
+}
+
+func (h *PricingHandler) respondSaved(c *gin.Context, code int, price *storage.ModelPrice) {
+	h.reload(c)
+	c.JSON(code, gin.H{
+		"price":  gateway.PriceEntryFromModelPrice(price),
+		"status": h.status(),
+	})
+}
+
+// reload applies a database edit right away instead of waiting for the next poll.
+func (h *PricingHandler) reload(c *gin.Context) {
+	if loader := h.router.GetPricingLoader(); loader != nil {
+		if _, err := loader.Reload(c.Request.Context()); err != nil {
+			h.logger.Warn().Err(err).Msg("Failed to reload pricing catalog after edit")
+		}
+	}
+}
+
+func (h *PricingHandler) status() gateway.PricingStatus {
+	if loader := h.router.GetPricingLoader(); loader != nil {
+		return loader.Status()
+	}
+	catalog := h.router.GetCostCalculator().Catalog()
+	return gateway.PricingStatus{
+		Version: catalog.Version,
+		Entries: len(catalog.Prices),
+		Sources: []string{gateway.PricingSourceBuiltin},
+	}
+}
+
+func (h *PricingHandler) writeRepoError(c *gin.Context, err error, message string) {
+	if errors.Is(err, storage.ErrModelPriceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "model price not found",
+		})
+		return
+	}
+	h.logger.Error().Err(err).Msg(message)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
+
+func bindPriceEntry(c *gin.Context) (gateway.PriceEntry, bool) {
+	var entry gateway.PriceEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return entry, false
+	}
+	entry.Provider = strings.TrimSpace(entry.Provider)
+	entry.Model = strings.TrimSpace(entry.Model)
+	if err := entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return entry, false
+	}
+	return entry, true
+}
+
+func modelPriceFromEntry(entry gateway.PriceEntry) *storage.ModelPrice {
+	price := &storage.ModelPrice{
+		Provider:        entry.Provider,
+		Model:           entry.Model,
+		InputCost:       entry.InputCost,
+		OutputCost:      entry.OutputCost,
+		CachedInputCost: entry.CachedInputCost,
+		BatchInputCost:  entry.BatchInputCost,
+		BatchOutputCost: entry.BatchOutputCost,
+		ImageCost:       entry.ImageCost,
+	}
+	if entry.EffectiveFrom != nil {
+		t := entry.EffectiveFrom.Time
+		price.EffectiveFrom = &t
+	}
+	if entry.EffectiveUntil != nil {
+		t := entry.EffectiveUntil.Time
+		price.EffectiveUntil = &t
+	}
+	return price
+}
+
+func modelPriceID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid price id",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
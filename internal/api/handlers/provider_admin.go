@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// ProviderAdminHandler lets admins enable and disable providers at runtime. The
+// disabled list is saved in the system settings when a database is configured.
+type ProviderAdminHandler struct {
+	router       *gateway.Router
+	settingsRepo *storage.SystemSettingsRepository
+	logger       zerolog.Logger
+}
+
+func NewProviderAdminHandler(router *gateway.Router, settingsRepo *storage.SystemSettingsRepository, logger zerolog.Logger) *ProviderAdminHandler {
+	return &ProviderAdminHandler{
+		router:       router,
+		settingsRepo: settingsRepo,
+		logger:       logger,
+	}
+}
+
+// ListProviders serves GET /admin/providers.
+func (h *ProviderAdminHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": h.router.ProviderStatuses(),
+	})
+}
+
+type SetProviderEnabledRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// SetProviderEnabled serves PUT /admin/providers/:name with {"enabled": bool}.
+func (h *ProviderAdminHandler) SetProviderEnabled(c *gin.Context) {
+	var req SetProviderEnabledRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := c.Param("name")
+	if err := h.router.SetProviderEnabled(name, *req.Enabled); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if h.settingsRepo != nil {
+		if err := h.settingsRepo.SetDisabledProviders(c.Request.Context(), h.router.DisabledProviders(), requestUserID(c)); err != nil {
+			h.logger.Error().Err(err).Str("provider", name).Msg("Failed to save disabled providers")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "provider updated but not saved; it will revert on restart",
+			})
+			return
+		}
+	}
+
+	h.logger.Info().Str("provider", name).Bool("enabled", *req.Enabled).Msg("Provider enabled state changed")
+	c.JSON(http.StatusOK, gin.H{
+		"provider": name,
+		"enabled":  *req.Enabled,
+	})
+}
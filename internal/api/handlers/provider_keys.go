@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
 	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
 )
@@ -14,6 +17,12 @@ type ProviderKeyValidator interface {
 	ValidateKey(ctx context.Context, provider string, apiKey string) error
 }
 
+// providerKeyMetadataValidator is implemented by validators that can test keys
+// needing stored settings, such as Azure OpenAI keys.
+type providerKeyMetadataValidator interface {
+	ValidateKeyWithMetadata(ctx context.Context, provider string, apiKey string, metadata []byte) error
+}
+
 type ProviderKeyHandler struct {
 	providerKeyService *security.ProviderKeyService
 	keyValidator       ProviderKeyValidator // optional; if set, TestProviderKey calls the provider API
@@ -29,6 +38,27 @@ func NewProviderKeyHandler(providerKeyService *security.ProviderKeyService, keyV
 type AddProviderKeyRequest struct {
 	Provider string `json:"provider" binding:"required"` // 'openai', 'anthropic', 'google'
 	APIKey   string `json:"api_key" binding:"required"`  // Plaintext API key (will be encrypted)
+	// Provider settings; required for azure: {"endpoint", "api_version", "deployments"}
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// providerKeyMetadata checks the settings sent with a key. Only Azure OpenAI keys
+// carry settings; nil is returned when none were sent.
+func providerKeyMetadata(provider string, metadata json.RawMessage, required bool) ([]byte, error) {
+	if len(metadata) == 0 || string(metadata) == "null" {
+		if provider == "azure" && required {
+			return nil, fmt.Errorf("azure keys need metadata with the resource endpoint")
+		}
+		return nil, nil
+	}
+	if provider != "azure" {
+		return nil, fmt.Errorf("metadata is not used for %s keys", provider)
+	}
+	config, err := providers.ParseAzureOpenAIMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(config)
 }
 
 func (h *ProviderKeyHandler) AddProviderKey(c *gin.Context) {
@@ -57,7 +87,16 @@ func (h *ProviderKeyHandler) AddProviderKey(c *gin.Context) {
 		return
 	}
 
-	if err := h.providerKeyService.AddProviderKey(c.Request.Context(), userID, req.Provider, req.APIKey); err != nil {
+	metadata, err := providerKeyMetadata(req.Provider, req.Metadata, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.providerKeyService.AddProviderKeyWithMetadata(c.Request.Context(), userID, req.Provider, req.APIKey, metadata); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -104,6 +143,9 @@ func (h *ProviderKeyHandler) ListProviderKeys(c *gin.Context) {
 			"created_at": key.CreatedAt,
 			"updated_at": key.UpdatedAt,
 		}
+		if len(key.Metadata) > 0 {
+			response[i]["metadata"] = json.RawMessage(key.Metadata)
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -112,7 +154,8 @@ func (h *ProviderKeyHandler) ListProviderKeys(c *gin.Context) {
 }
 
 type UpdateProviderKeyRequest struct {
-	APIKey string `json:"api_key" binding:"required"` // Plaintext API key (will be encrypted)
+	APIKey   string          `json:"api_key" binding:"required"` // Plaintext API key (will be encrypted)
+	Metadata json.RawMessage `json:"metadata,omitempty"`         // Replaces the stored settings when set
 }
 
 func (h *ProviderKeyHandler) UpdateProviderKey(c *gin.Context) {
@@ -149,7 +192,16 @@ func (h *ProviderKeyHandler) UpdateProviderKey(c *gin.Context) {
 		return
 	}
 
-	if err := h.providerKeyService.UpdateProviderKey(c.Request.Context(), userID, provider, req.APIKey); err != nil {
+	metadata, err := providerKeyMetadata(provider, req.Metadata, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.providerKeyService.UpdateProviderKeyWithMetadata(c.Request.Context(), userID, provider, req.APIKey, metadata); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -225,7 +277,7 @@ func (h *ProviderKeyHandler) TestProviderKey(c *gin.Context) {
 		return
 	}
 
-	apiKey, err := h.providerKeyService.GetProviderKey(c.Request.Context(), userID, provider)
+	apiKey, metadata, err := h.providerKeyService.GetProviderKeyWithMetadata(c.Request.Context(), userID, provider)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to retrieve provider key",
@@ -242,7 +294,13 @@ func (h *ProviderKeyHandler) TestProviderKey(c *gin.Context) {
 	}
 
 	if h.keyValidator != nil {
-		if err := h.keyValidator.ValidateKey(c.Request.Context(), provider, apiKey); err != nil {
+		validate := h.keyValidator.ValidateKey
+		if v, ok := h.keyValidator.(providerKeyMetadataValidator); ok {
+			validate = func(ctx context.Context, provider string, apiKey string) error {
+				return v.ValidateKeyWithMetadata(ctx, provider, apiKey, metadata)
+			}
+		}
+		if err := validate(c.Request.Context(), provider, apiKey); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "provider key test failed",
 				"details": err.Error(),
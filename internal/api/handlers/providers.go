@@ -25,11 +25,13 @@ func NewProviderHandler(router *gateway.Router, logger zerolog.Logger) *Provider
 func (h *ProviderHandler) ListProviders(c *gin.Context) {
 	ctx := c.Request.Context()
 	var providerDetails []map[string]interface{}
+	var userID *uuid.UUID
 
 	if userIDVal, exists := c.Get("user_id"); exists {
 		if userIDStr, ok := userIDVal.(string); ok {
-			if userID, err := uuid.Parse(userIDStr); err == nil {
-				providerDetails = h.Router.ListProviderDetailsForUser(ctx, &userID)
+			if parsed, err := uuid.Parse(userIDStr); err == nil {
+				userID = &parsed
+				providerDetails = h.Router.ListProviderDetailsForUser(ctx, userID)
 			}
 		}
 	}
@@ -38,18 +40,36 @@ func (h *ProviderHandler) ListProviders(c *gin.Context) {
 		providerDetails = make([]map[string]interface{}, 0, len(names))
 		for _, name := range names {
 			provider, err := h.Router.GetProvider(name)
-			if err != nil {
+			if err != nil || !h.Router.IsProviderEnabled(name) {
 				continue
 			}
-			healthy := provider.HealthCheck(ctx) == nil
+			health, err := h.Router.ProviderHealth(name)
+			healthy := err == nil && health.State != gateway.BreakerOpen
+			models := provider.GetModels()
 			providerDetails = append(providerDetails, map[string]interface{}{
-				"name":    name,
-				"healthy": healthy,
-				"models":  provider.GetModels(),
+				"name":         name,
+				"healthy":      healthy,
+				"models":       models,
+				"capabilities": h.Router.ModelCapabilities(provider, models),
 			})
 		}
 	}
 
+	// Model aliases are listed as the models of a virtual "aliases" provider.
+	if aliases := h.Router.ListModelAliases(ctx, userID); len(aliases) > 0 {
+		names := make([]string, 0, len(aliases))
+		for _, alias := range aliases {
+			names = append(names, alias.Name)
+		}
+		providerDetails = append(providerDetails, map[string]interface{}{
+			"name":    "aliases",
+			"virtual": true,
+			"healthy": true,
+			"models":  names,
+			"aliases": aliases,
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"providers": providerDetails,
 	})
@@ -66,12 +86,22 @@ func (h *ProviderHandler) GetProviderHealth(c *gin.Context) {
 		return
 	}
 
-	err = provider.HealthCheck(c.Request.Context())
+	// Health comes from the circuit breaker, which tracks real traffic and background probes.
+	health, err := h.Router.ProviderHealth(providerName)
 	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": errors.ErrProviderNotFound.Error(),
+		})
+		return
+	}
+
+	if health.State == gateway.BreakerOpen {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"provider": providerName,
 			"healthy":  false,
-			"error":    err.Error(),
+			"state":    health.State,
+			"error":    health.LastError,
+			"breaker":  health,
 		})
 		return
 	}
@@ -79,6 +109,8 @@ func (h *ProviderHandler) GetProviderHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"provider": providerName,
 		"healthy":  true,
+		"state":    health.State,
+		"breaker":  health,
 		"models":   provider.GetModels(),
 	})
 }
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+const cacheStatusHeader = "X-UniRoute-Cache"
+
+// cacheContext attaches the API key's response cache policy to the request context and
+// reports whether req is cacheable under it. "Cache-Control: no-cache" skips the lookup.
+func cacheContext(c *gin.Context, req providers.ChatRequest) (context.Context, bool) {
+	ctx := routingContext(c)
+	record, _ := c.Get("api_key_record")
+	key, ok := record.(*storage.APIKey)
+	if !ok || key == nil || key.CacheTTLSeconds <= 0 || !gateway.Cacheable(req) {
+		return ctx, false
+	}
+	policy := gateway.CachePolicy{
+		Scope:  key.ID.String(),
+		TTL:    time.Duration(key.CacheTTLSeconds) * time.Second,
+		Bypass: strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache"),
+	}
+	return gateway.WithCachePolicy(ctx, policy), true
+}
+
+func setCacheStatusHeader(c *gin.Context, cached bool) {
+	if cached {
+		c.Header(cacheStatusHeader, "HIT")
+	} else {
+		c.Header(cacheStatusHeader, "MISS")
+	}
+}
@@ -144,8 +144,10 @@ func (h *RoutingHandler) GetRoutingStrategy(c *gin.Context) {
 
 func (h *RoutingHandler) GetCostEstimate(c *gin.Context) {
 	var req struct {
-		Model    string                  `json:"model" binding:"required"`
-		Messages []providers.Message `json:"messages" binding:"required"`
+		Model     string              `json:"model" binding:"required"`
+		Messages  []providers.Message `json:"messages" binding:"required"`
+		MaxTokens int                 `json:"max_tokens"`
+		Tools     []providers.Tool    `json:"tools"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -155,6 +157,7 @@ func (h *RoutingHandler) GetCostEstimate(c *gin.Context) {
 	}
 
 	estimates := make(map[string]float64)
+	var unknownPrice []string
 	calculator := h.Router.GetCostCalculator()
 	ctx := c.Request.Context()
 
@@ -195,14 +198,27 @@ func (h *RoutingHandler) GetCostEstimate(c *gin.Context) {
 		if !supports {
 			continue
 		}
-		cost := calculator.EstimateCost(entry.Name, req.Model, req.Messages)
+		cost, err := calculator.Estimate(entry.Name, providers.ChatRequest{
+			Model:     req.Model,
+			Messages:  req.Messages,
+			MaxTokens: req.MaxTokens,
+			Tools:     req.Tools,
+		})
+		if err != nil {
+			unknownPrice = append(unknownPrice, entry.Name)
+			continue
+		}
 		estimates[entry.Name] = cost
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"model":    req.Model,
 		"estimates": estimates,
-	})
+	}
+	if len(unknownPrice) > 0 {
+		response["unknown_price"] = unknownPrice
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *RoutingHandler) GetLatencyStats(c *gin.Context) {
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// debugHeader asks for the routing trace in the response body ("X-UniRoute-Debug: true").
+const debugHeader = "X-UniRoute-Debug"
+
+// routingContext attaches the API key, user roles and headers of the request, which
+// custom routing rules can match on, and the key's PII redaction policy.
+func routingContext(c *gin.Context) context.Context {
+	attrs := gateway.RequestAttributes{Headers: c.Request.Header}
+	if id, ok := c.Get("api_key_id"); ok {
+		attrs.APIKeyID, _ = id.(string)
+	}
+	if roles, ok := c.Get("user_roles"); ok {
+		attrs.UserRoles, _ = roles.([]string)
+	}
+	return redactionContext(c, gateway.WithRequestAttributes(c.Request.Context(), attrs))
+}
+
+// routingTrace records the routing decision when the request asks for it with
+// X-UniRoute-Debug. Only non-streaming handlers use it: they read the trace after
+// Route has returned.
+func routingTrace(c *gin.Context, ctx context.Context) (context.Context, *gateway.RoutingTrace) {
+	if debug, _ := strconv.ParseBool(c.GetHeader(debugHeader)); !debug {
+		return ctx, nil
+	}
+	return gateway.WithRoutingTrace(ctx)
+}
+
+// debugChatResponse is a chat response with the routing trace added as "debug".
+type debugChatResponse struct {
+	*providers.ChatResponse
+	Debug *gateway.RoutingTrace `json:"debug"`
+}
+
+// HandleExplainRouting serves POST /v1/routing/explain: it takes a /v1/chat request and
+// returns how it would be routed, without calling a provider.
+func (h *ChatHandler) HandleExplainRouting(c *gin.Context) {
+	var req providers.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.ErrInvalidRequest.Error(),
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.Model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "model is required",
+		})
+		return
+	}
+
+	_, userID := requestIdentity(c)
+	c.JSON(http.StatusOK, h.router.Explain(routingContext(c), req, userID))
+}
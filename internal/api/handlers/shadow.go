@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// ShadowHandler serves the admin view of shadow traffic: the configured rules and the
+// stored live/shadow response pairs.
+type ShadowHandler struct {
+	router     *gateway.Router
+	shadowRepo *storage.ShadowRequestRepository
+	logger     zerolog.Logger
+}
+
+func NewShadowHandler(router *gateway.Router, shadowRepo *storage.ShadowRequestRepository, logger zerolog.Logger) *ShadowHandler {
+	return &ShadowHandler{
+		router:     router,
+		shadowRepo: shadowRepo,
+		logger:     logger,
+	}
+}
+
+// GetShadowRules serves GET /admin/shadow/rules.
+func (h *ShadowHandler) GetShadowRules(c *gin.Context) {
+	rules := []gateway.ShadowRule{}
+	if mirror := h.router.GetShadowMirror(); mirror != nil {
+		rules = mirror.Rules()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"shadows": rules,
+	})
+}
+
+// ListShadowRequests serves GET /admin/shadow/requests?model=&shadow_provider=&limit=&offset=.
+func (h *ShadowHandler) ListShadowRequests(c *gin.Context) {
+	filters := storage.ShadowRequestFilters{
+		Model:          strings.TrimSpace(c.Query("model")),
+		ShadowProvider: strings.TrimSpace(c.Query("shadow_provider")),
+		Limit:          100,
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 && limit <= 1000 {
+		filters.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filters.Offset = offset
+	}
+
+	requests, err := h.shadowRepo.List(c.Request.Context(), filters)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list shadow requests")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve shadow requests",
+		})
+		return
+	}
+	if requests == nil {
+		requests = []*storage.ShadowRequest{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+		"limit":    filters.Limit,
+		"offset":   filters.Offset,
+	})
+}
+
+// GetShadowSummary serves GET /admin/shadow/summary?model=&start_time=, comparing live
+// and shadow latency, cost, length and similarity per model (last 7 days by default).
+func (h *ShadowHandler) GetShadowSummary(c *gin.Context) {
+	startTime := time.Now().AddDate(0, 0, -7)
+	if startStr := c.Query("start_time"); startStr != "" {
+		if parsed, err := time.Parse(time.RFC3339, startStr); err == nil {
+			startTime = parsed
+		}
+	}
+
+	summary, err := h.shadowRepo.Summarize(c.Request.Context(), strings.TrimSpace(c.Query("model")), startTime)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to summarize shadow requests")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to summarize shadow requests",
+		})
+		return
+	}
+	if summary == nil {
+		summary = []storage.ShadowSummary{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"start":   startTime.Format(time.RFC3339),
+		"summary": summary,
+	})
+}
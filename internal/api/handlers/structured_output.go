@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers controlling what happens when a response does not match the request's
+// response_format: X-UniRoute-Output-Retries is the number of retries (0-3), and
+// X-UniRoute-Output-Repair: true sends the invalid output back to the model with the
+// validation errors instead of resending the request.
+const (
+	outputRetriesHeader = "X-UniRoute-Output-Retries"
+	outputRepairHeader  = "X-UniRoute-Output-Repair"
+)
+
+// structuredOutputContext attaches the structured output policy of the request.
+func structuredOutputContext(c *gin.Context, ctx context.Context) (context.Context, error) {
+	var policy gateway.StructuredOutputPolicy
+	if value := strings.TrimSpace(c.GetHeader(outputRetriesHeader)); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 || retries > gateway.MaxStructuredOutputRetries {
+			return ctx, fmt.Errorf("%s must be between 0 and %d", outputRetriesHeader, gateway.MaxStructuredOutputRetries)
+		}
+		policy.MaxRetries = retries
+	}
+	if value := strings.TrimSpace(c.GetHeader(outputRepairHeader)); value != "" {
+		repair, err := strconv.ParseBool(value)
+		if err != nil {
+			return ctx, fmt.Errorf("%s must be true or false", outputRepairHeader)
+		}
+		policy.Repair = repair
+		if repair && policy.MaxRetries == 0 {
+			policy.MaxRetries = 1
+		}
+	}
+	if policy.MaxRetries == 0 {
+		return ctx, nil
+	}
+	return gateway.WithStructuredOutputPolicy(ctx, policy), nil
+}
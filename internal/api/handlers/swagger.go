@@ -161,6 +161,7 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 			{"name": "Health", "description": "Health check endpoints"},
 			{"name": "Authentication", "description": "User authentication and registration"},
 			{"name": "Chat", "description": "AI chat completion endpoints"},
+			{"name": "Embeddings", "description": "Text embedding endpoints"},
 			{"name": "Conversations", "description": "Conversation management"},
 			{"name": "Providers", "description": "LLM provider management"},
 			{"name": "Analytics", "description": "Usage analytics and metrics"},
@@ -563,7 +564,7 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 												"properties": map[string]interface{}{
 													"role": map[string]interface{}{
 														"type":    "string",
-														"enum":    []string{"user", "assistant", "system"},
+														"enum":    []string{"user", "assistant", "system", "tool"},
 														"example": "user",
 													},
 													"content": map[string]interface{}{
@@ -582,6 +583,20 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 											"type":    "integer",
 											"example": 1000,
 										},
+										"tools": map[string]interface{}{
+											"type":        "array",
+											"description": "Functions the model may call, in OpenAI tools format. Translated for Anthropic, Gemini and Ollama",
+											"items": map[string]interface{}{
+												"type": "object",
+											},
+										},
+										"tool_choice": map[string]interface{}{
+											"description": "auto, none, required, or {\"type\":\"function\",\"function\":{\"name\":\"...\"}}",
+										},
+										"response_format": map[string]interface{}{
+											"type":        "object",
+											"description": "{\"type\":\"json_object\"} or {\"type\":\"json_schema\",\"json_schema\":{\"name\":\"...\",\"schema\":{...}}}. The output is validated against the schema; X-UniRoute-Output-Retries and X-UniRoute-Output-Repair retry invalid output",
+										},
 									},
 								},
 							},
@@ -594,17 +609,262 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 						"401": map[string]interface{}{
 							"description": "Unauthorized - Invalid API key",
 						},
+						"422": map[string]interface{}{
+							"description": "Output does not match response_format",
+						},
 						"429": map[string]interface{}{
 							"description": "Rate limit exceeded",
 						},
 					},
 				},
 			},
+			"/v1/chat/completions": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Chat"},
+					"summary":     "OpenAI-compatible chat completion",
+					"description": "Accepts the OpenAI Chat Completions request schema and returns chat.completion objects, or chat.completion.chunk SSE events ending with data: [DONE] when stream is true",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Chat completion (JSON) or chat.completion.chunk event stream",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request (OpenAI error format)",
+						},
+						"401": map[string]interface{}{
+							"description": "Unauthorized - Invalid API key",
+						},
+					},
+				},
+			},
+			"/v1/chat/compare": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Chat"},
+					"summary":     "Compare models",
+					"description": "Sends a /v1/chat request to every model in models or targets (up to 10) concurrently. Each result has the message, usage, cost and latency, or the error of a failed target. Set provider on a target to pin it to that provider without failover",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"messages"},
+									"properties": map[string]interface{}{
+										"models": map[string]interface{}{
+											"type":    "array",
+											"items":   map[string]interface{}{"type": "string"},
+											"example": []string{"gpt-4o-mini", "claude-3-5-haiku-20241022"},
+										},
+										"targets": map[string]interface{}{
+											"type": "array",
+											"items": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"model":    map[string]interface{}{"type": "string"},
+													"provider": map[string]interface{}{"type": "string"},
+												},
+											},
+										},
+										"messages": map[string]interface{}{
+											"type": "array",
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "comparison_id, results in target order, succeeded and failed counts",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request",
+						},
+						"401": map[string]interface{}{
+							"description": "Unauthorized - Invalid API key",
+						},
+						"502": map[string]interface{}{
+							"description": "Every target failed",
+						},
+					},
+				},
+			},
+			"/v1/chat/compare/stream": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Chat"},
+					"summary":     "Compare models (streaming)",
+					"description": "Streaming variant of /v1/chat/compare. Emits chunk events tagged with the target index, a result event when each target finishes, and a final summary event",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Server-sent events",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request",
+						},
+					},
+				},
+			},
+			"/v1/embeddings": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Embeddings"},
+					"summary":     "Create embeddings",
+					"description": "OpenAI-compatible embeddings. Routed to the provider serving the model (OpenAI, Google, Ollama, vLLM) without cross-provider failover",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"model", "input"},
+									"properties": map[string]interface{}{
+										"model": map[string]interface{}{
+											"type":    "string",
+											"example": "text-embedding-3-small",
+										},
+										"input": map[string]interface{}{
+											"description": "A string or an array of strings",
+											"example":     []string{"Hello world"},
+										},
+										"dimensions": map[string]interface{}{
+											"type": "integer",
+										},
+										"encoding_format": map[string]interface{}{
+											"type": "string",
+											"enum": []string{"float", "base64"},
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "List of embeddings",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request (OpenAI error format)",
+						},
+						"401": map[string]interface{}{
+							"description": "Unauthorized - Invalid API key",
+						},
+					},
+				},
+			},
+			"/v1/tokenize": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Tokens"},
+					"summary":     "Tokenize text",
+					"description": "Tokenize text with the model's tokenizer. Token IDs are returned only when the tokenizer is exact (OpenAI BPE tables loaded, or a registered local tokenizer); otherwise only an estimated count",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"model", "text"},
+									"properties": map[string]interface{}{
+										"model": map[string]interface{}{
+											"type":    "string",
+											"example": "gpt-4o",
+										},
+										"text": map[string]interface{}{
+											"type":    "string",
+											"example": "Hello world",
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Token count, tokenizer name and (for exact tokenizers) token IDs",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request (OpenAI error format)",
+						},
+					},
+				},
+			},
+			"/v1/count-tokens": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Tokens"},
+					"summary":     "Count prompt tokens",
+					"description": "Count the prompt tokens of a chat completion request (messages, image parts and tool definitions) without calling a provider. Reports whether prompt plus max_tokens fits the model's context window when it is known",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":        "object",
+									"description": "Chat Completions request body; only model, messages, tools and max_tokens are used",
+									"required":    []string{"model", "messages"},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Prompt token breakdown",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request (OpenAI error format)",
+						},
+					},
+				},
+			},
+			"/v1/routing/explain": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Routing"},
+					"summary":     "Explain routing",
+					"description": "Show how a chat request would be routed, without calling a provider: the strategy and whether it is the locked default, the user's own or the default, each provider with why it is excluded (open circuit breaker, no server key or BYOK key), how custom routing rules evaluated, and the failover order with estimated cost and average latency per candidate",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":        "object",
+									"description": "Same body as POST /v1/chat",
+									"required":    []string{"model"},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Routing explanation",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request",
+						},
+					},
+				},
+			},
 			"/v1/providers": map[string]interface{}{
 				"get": map[string]interface{}{
 					"tags":        []string{"Providers"},
 					"summary":     "List providers",
-					"description": "Get list of available LLM providers",
+					"description": "Get list of available LLM providers with their models and each model's capabilities",
 					"security": []map[string]interface{}{
 						{"BearerAuth": []string{}},
 					},
@@ -622,7 +882,7 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 				"get": map[string]interface{}{
 					"tags":        []string{"Providers"},
 					"summary":     "Get provider health",
-					"description": "Check health status of a specific provider",
+					"description": "Circuit breaker state of a provider (closed, open or half_open), built from real traffic and background health probes. Returns 503 while the breaker is open.",
 					"security": []map[string]interface{}{
 						{"BearerAuth": []string{}},
 					},
@@ -642,6 +902,9 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 						"200": map[string]interface{}{
 							"description": "Provider health status",
 						},
+						"503": map[string]interface{}{
+							"description": "Circuit breaker open",
+						},
 						"401": map[string]interface{}{
 							"description": "Unauthorized",
 						},
@@ -748,66 +1011,433 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 											"type":    "integer",
 											"example": 10000,
 										},
+										"cache_ttl_seconds": map[string]interface{}{
+											"type":        "integer",
+											"description": "Response cache TTL for temperature-0 chat requests (0 = disabled, max 604800)",
+											"example":     300,
+										},
+										"token_limit_per_minute": map[string]interface{}{
+											"type":        "integer",
+											"description": "Prompt plus completion tokens per sliding minute (0 = unlimited). Responses carry X-RateLimit-*-Tokens headers",
+											"example":     100000,
+										},
+										"token_limit_per_day": map[string]interface{}{
+											"type":        "integer",
+											"description": "Tokens per sliding 24 hours (0 = unlimited)",
+											"example":     2000000,
+										},
+										"hedge_percentile": map[string]interface{}{
+											"type":        "integer",
+											"description": "Hedge non-streaming chat requests that take longer than this percentile of the provider's recent latency (1-99, 0 = off)",
+											"example":     95,
+										},
+										"pii_policy": map[string]interface{}{
+											"type":        "object",
+											"description": "Redact PII from prompts sent to cloud providers; omit to send prompts as is",
+											"properties": map[string]interface{}{
+												"types": map[string]interface{}{
+													"type":        "array",
+													"description": "email, phone, credit_card, national_id (all when empty)",
+													"items":       map[string]interface{}{"type": "string"},
+												},
+												"skip_providers": map[string]interface{}{
+													"type":        "array",
+													"description": "Providers sent the original prompt (default local, vllm)",
+													"items":       map[string]interface{}{"type": "string"},
+												},
+											},
+										},
+										"budgets": map[string]interface{}{
+											"type":        "array",
+											"description": "USD spend budgets for the key (requires Redis)",
+											"items": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"period": map[string]interface{}{
+														"type":    "string",
+														"enum":    []string{"daily", "monthly", "lifetime"},
+														"example": "monthly",
+													},
+													"limit_usd": map[string]interface{}{
+														"type":        "number",
+														"description": "Hard limit: requests get 402 BUDGET_EXCEEDED once spend reaches it",
+														"example":     50,
+													},
+													"soft_limit_usd": map[string]interface{}{
+														"type":        "number",
+														"description": "Responses carry X-UniRoute-Budget-Warning once spend reaches it",
+														"example":     40,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{
+							"description": "API key created",
+						},
+						"401": map[string]interface{}{
+							"description": "Unauthorized",
+						},
+					},
+				},
+				"get": map[string]interface{}{
+					"tags":        []string{"Authentication"},
+					"summary":     "List API keys",
+					"description": "List all API keys for the authenticated user",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "List of API keys",
+						},
+						"401": map[string]interface{}{
+							"description": "Unauthorized",
+						},
+					},
+				},
+			},
+			"/auth/api-keys/{id}": map[string]interface{}{
+				"delete": map[string]interface{}{
+					"tags":        []string{"Authentication"},
+					"summary":     "Revoke API key",
+					"description": "Revoke an API key belonging to the authenticated user",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"description": "API key ID",
+							"schema": map[string]interface{}{
+								"type":    "string",
+								"example": "uuid-here",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "API key revoked",
+						},
+						"401": map[string]interface{}{
+							"description": "Unauthorized",
+						},
+						"404": map[string]interface{}{
+							"description": "API key not found",
+						},
+					},
+				},
+			},
+			"/auth/api-keys/{id}/cache": map[string]interface{}{
+				"put": map[string]interface{}{
+					"tags":        []string{"Authentication"},
+					"summary":     "Set API key response cache TTL",
+					"description": "Enable or disable the exact-match response cache for an API key. Cache hits return cached=true and the X-UniRoute-Cache: HIT header, and are billed at zero cost. Send Cache-Control: no-cache on a request to skip the lookup.",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"description": "API key ID",
+							"schema": map[string]interface{}{
+								"type":    "string",
+								"example": "uuid-here",
+							},
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"cache_ttl_seconds": map[string]interface{}{
+											"type":    "integer",
+											"example": 300,
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Cache TTL updated",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid TTL",
+						},
+						"404": map[string]interface{}{
+							"description": "API key not found",
+						},
+					},
+				},
+			},
+			"/auth/api-keys/{id}/hedging": map[string]interface{}{
+				"put": map[string]interface{}{
+					"tags":        []string{"Authentication"},
+					"summary":     "Set API key request hedging",
+					"description": "Hedge the key's non-streaming chat requests: when the selected provider has not answered within hedge_percentile of its recent latency, the request is also sent to the next provider in the failover order and the first success wins. The losing call is cancelled and recorded as a chat_hedge request with its cost. A request can override this with the X-UniRoute-Hedge header (true, false or a percentile).",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"description": "API key ID",
+							"schema": map[string]interface{}{
+								"type":    "string",
+								"example": "uuid-here",
+							},
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"hedge_percentile": map[string]interface{}{
+											"type":    "integer",
+											"example": 95,
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Hedging updated",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid percentile",
+						},
+						"404": map[string]interface{}{
+							"description": "API key not found",
+						},
+					},
+				},
+			},
+			"/auth/api-keys/{id}/pii-policy": map[string]interface{}{
+				"put": map[string]interface{}{
+					"tags":        []string{"Authentication"},
+					"summary":     "Set API key PII redaction policy",
+					"description": "Replace emails, phone numbers, card numbers and national IDs in the key's chat prompts with placeholders such as [PII_EMAIL_1] before they are sent to a provider, and restore them in the response (streamed responses included). Providers in skip_providers (default local and vllm) receive the original prompt. A null pii_policy turns redaction off.",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"description": "API key ID",
+							"schema": map[string]interface{}{
+								"type":    "string",
+								"example": "uuid-here",
+							},
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"pii_policy": map[string]interface{}{
+											"type": "object",
+											"example": map[string]interface{}{
+												"types": []string{"email", "credit_card"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "PII policy updated",
+						},
+						"400": map[string]interface{}{
+							"description": "Unknown PII type",
+						},
+						"404": map[string]interface{}{
+							"description": "API key not found",
+						},
+					},
+				},
+			},
+			"/auth/api-keys/{id}/budget": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Authentication"},
+					"summary":     "Get API key budgets",
+					"description": "Budgets with the USD spent in the current period, the remaining amount and when the period resets (UTC)",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"description": "API key ID",
+							"schema": map[string]interface{}{
+								"type":    "string",
+								"example": "uuid-here",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Budgets and spend",
+						},
+					},
+				},
+				"put": map[string]interface{}{
+					"tags":        []string{"Authentication"},
+					"summary":     "Set API key budgets",
+					"description": "Set daily, monthly or lifetime USD budgets for an API key. Spend is counted in real time from the actual cost of each request.",
+					"security": []map[string]interface{}{
+						{"BearerAuth": []string{}},
+					},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"description": "API key ID",
+							"schema": map[string]interface{}{
+								"type":    "string",
+								"example": "uuid-here",
+							},
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"budgets": map[string]interface{}{
+											"type":        "array",
+											"description": "Replaces all budgets; an empty list removes them",
+											"items": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"period": map[string]interface{}{
+														"type":    "string",
+														"enum":    []string{"daily", "monthly", "lifetime"},
+														"example": "monthly",
+													},
+													"limit_usd": map[string]interface{}{
+														"type":        "number",
+														"description": "Hard limit: requests get 402 BUDGET_EXCEEDED once spend reaches it",
+														"example":     50,
+													},
+													"soft_limit_usd": map[string]interface{}{
+														"type":        "number",
+														"description": "Responses carry X-UniRoute-Budget-Warning once spend reaches it",
+														"example":     40,
+													},
+												},
+											},
+										},
 									},
 								},
 							},
 						},
 					},
 					"responses": map[string]interface{}{
-						"201": map[string]interface{}{
-							"description": "API key created",
+						"200": map[string]interface{}{
+							"description": "Budgets updated",
 						},
-						"401": map[string]interface{}{
-							"description": "Unauthorized",
+						"400": map[string]interface{}{
+							"description": "Invalid budget",
 						},
 					},
 				},
+			},
+			"/auth/budget": map[string]interface{}{
 				"get": map[string]interface{}{
 					"tags":        []string{"Authentication"},
-					"summary":     "List API keys",
-					"description": "List all API keys for the authenticated user",
+					"summary":     "Get account budgets",
+					"description": "Budgets with the USD spent in the current period, the remaining amount and when the period resets (UTC)",
 					"security": []map[string]interface{}{
 						{"BearerAuth": []string{}},
 					},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "List of API keys",
-						},
-						"401": map[string]interface{}{
-							"description": "Unauthorized",
+							"description": "Budgets and spend",
 						},
 					},
 				},
-			},
-			"/auth/api-keys/{id}": map[string]interface{}{
-				"delete": map[string]interface{}{
+				"put": map[string]interface{}{
 					"tags":        []string{"Authentication"},
-					"summary":     "Revoke API key",
-					"description": "Revoke an API key belonging to the authenticated user",
+					"summary":     "Set account budgets",
+					"description": "Set USD budgets covering all of the user's requests, across every API key.",
 					"security": []map[string]interface{}{
 						{"BearerAuth": []string{}},
 					},
-					"parameters": []map[string]interface{}{
-						{
-							"name":        "id",
-							"in":          "path",
-							"required":    true,
-							"description": "API key ID",
-							"schema": map[string]interface{}{
-								"type":    "string",
-								"example": "uuid-here",
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"budgets": map[string]interface{}{
+											"type":        "array",
+											"description": "Replaces all budgets; an empty list removes them",
+											"items": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"period": map[string]interface{}{
+														"type":    "string",
+														"enum":    []string{"daily", "monthly", "lifetime"},
+														"example": "monthly",
+													},
+													"limit_usd": map[string]interface{}{
+														"type":        "number",
+														"description": "Hard limit: requests get 402 BUDGET_EXCEEDED once spend reaches it",
+														"example":     50,
+													},
+													"soft_limit_usd": map[string]interface{}{
+														"type":        "number",
+														"description": "Responses carry X-UniRoute-Budget-Warning once spend reaches it",
+														"example":     40,
+													},
+												},
+											},
+										},
+									},
+								},
 							},
 						},
 					},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "API key revoked",
-						},
-						"401": map[string]interface{}{
-							"description": "Unauthorized",
+							"description": "Budgets updated",
 						},
-						"404": map[string]interface{}{
-							"description": "API key not found",
+						"400": map[string]interface{}{
+							"description": "Invalid budget",
 						},
 					},
 				},
@@ -836,6 +1466,14 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 											"type":    "string",
 											"example": "sk-...",
 										},
+										"metadata": map[string]interface{}{
+											"type":        "object",
+											"description": "Provider settings; required for azure",
+											"example": map[string]interface{}{
+												"endpoint":    "https://contoso.openai.azure.com",
+												"deployments": map[string]string{"gpt-4o": "prod-gpt4o"},
+											},
+										},
 									},
 								},
 							},
@@ -1314,6 +1952,29 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 					},
 				},
 			},
+			"/auth/routing/model-aliases": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags": []string{"Routing"},
+					"summary": "Get model aliases",
+					"description": "List the user's model aliases followed by global aliases",
+					"security": []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Model aliases"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+					},
+				},
+				"post": map[string]interface{}{
+					"tags": []string{"Routing"},
+					"summary": "Set model aliases",
+					"description": "Replace the user's model aliases. Each alias maps a virtual model name (e.g. \"fast\") to an ordered list of {provider, model, temperature, max_tokens} targets tried in turn.",
+					"security": []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Aliases updated"},
+						"400": map[string]interface{}{"description": "Invalid alias definition"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+					},
+				},
+			},
 			"/v1/user": map[string]interface{}{
 				"get": map[string]interface{}{
 					"tags": []string{"Authentication"},
@@ -1361,6 +2022,249 @@ func (h *SwaggerHandler) HandleSwaggerJSON(c *gin.Context) {
 					},
 				},
 			},
+			"/admin/routing/model-aliases": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Get global model aliases",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Model aliases"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+				"post": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Set global model aliases",
+					"description": "Replace the global model aliases available to every user",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Aliases updated"},
+						"400": map[string]interface{}{"description": "Invalid alias definition"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/pricing": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Get pricing catalog",
+					"description": "Catalog version, sources and every price entry (USD per 1M tokens, with cached-input, batch and per-image tiers and effective dates). Filter with ?provider=",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Pricing catalog"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+				"post": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Add model price",
+					"description": "Store a price in the database. It overrides built-in and file prices with the same provider, model and effective_from and applies immediately",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{"description": "Price created"},
+						"400": map[string]interface{}{"description": "Invalid price"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/pricing/{id}": map[string]interface{}{
+				"put": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Update model price",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Price updated"},
+						"400": map[string]interface{}{"description": "Invalid price"},
+						"404": map[string]interface{}{"description": "Price not found"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+				"delete": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Delete model price",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Price deleted"},
+						"404": map[string]interface{}{"description": "Price not found"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/pricing/lookup": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Look up a model price",
+					"description": "Resolve the price charged for ?provider=&model= at ?at= (default now). Dated model versions fall back to their base model and providers may define a \"*\" price",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Price entry"},
+						"404": map[string]interface{}{"description": "Unknown price"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/pricing/reload": map[string]interface{}{
+				"post": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Reload pricing catalog",
+					"description": "Re-read the pricing file and database now instead of waiting for PRICING_RELOAD_INTERVAL",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Reload result"},
+						"422": map[string]interface{}{"description": "Catalog invalid, previous catalog kept"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/experiments": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "List experiments",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Experiments"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+				"post": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Create experiment",
+					"description": "Split traffic for a model or alias across weighted variants. Only one enabled experiment per model is allowed",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{"description": "Experiment created"},
+						"400": map[string]interface{}{"description": "Invalid experiment definition"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/experiments/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Get experiment",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Experiment"},
+						"404": map[string]interface{}{"description": "Experiment not found"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+				"put": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Update experiment",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Experiment updated"},
+						"400": map[string]interface{}{"description": "Invalid experiment definition"},
+						"404": map[string]interface{}{"description": "Experiment not found"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+				"delete": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Delete experiment",
+					"description": "Delete an experiment. Recorded requests keep their variant but lose the experiment reference",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Experiment deleted"},
+						"404": map[string]interface{}{"description": "Experiment not found"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/analytics/experiments/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Get experiment results",
+					"description": "Per-variant request count, error rate, latency (avg/p95), tokens and cost. Accepts start_time and end_time (RFC3339)",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Per-variant statistics"},
+						"400": map[string]interface{}{"description": "Invalid experiment id"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/shadow/rules": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "List shadow traffic rules",
+					"description": "Rules loaded from SHADOW_CONFIG_PATH",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Shadow rules"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/shadow/requests": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "List shadow requests",
+					"description": "Live and shadow responses to the same request, newest first. Filters: model, shadow_provider, limit (max 1000), offset",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Shadow requests"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/shadow/summary": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Summarize shadow traffic",
+					"description": "Per model and candidate: requests, errors, average latency, length and output tokens, total cost and average similarity. Accepts model and start_time (RFC3339, default 7 days ago)",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Shadow summary"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/providers": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "List providers",
+					"description": "Registered and BYOK-capable providers, including OpenAI-compatible upstreams from OPENAI_COMPATIBLE_CONFIG_PATH, and whether each is enabled",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Providers"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+					},
+				},
+			},
+			"/admin/providers/{name}": map[string]interface{}{
+				"put": map[string]interface{}{
+					"tags":        []string{"Admin"},
+					"summary":     "Enable or disable a provider",
+					"description": "Body {\"enabled\": bool}. Disabled providers are left out of routing, BYOK and provider listings until enabled again",
+					"security":    []map[string]interface{}{{"BearerAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Provider updated"},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+						"403": map[string]interface{}{"description": "Forbidden - Admin required"},
+						"404": map[string]interface{}{"description": "Unknown provider"},
+					},
+				},
+			},
 			"/admin/tunnels/stats": map[string]interface{}{
 				"get": map[string]interface{}{
 					"tags":        []string{"Admin"},
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/tokenizer"
+	"github.com/gin-gonic/gin"
+)
+
+type TokenizeRequest struct {
+	Model string `json:"model"`
+	Text  string `json:"text"`
+}
+
+type tokenizeResponse struct {
+	Model     string `json:"model"`
+	Tokenizer string `json:"tokenizer"`
+	Exact     bool   `json:"exact"`
+	Count     int    `json:"count"`
+	Tokens    []int  `json:"tokens,omitempty"` // only for exact tokenizers
+}
+
+type countTokensResponse struct {
+	Model     string `json:"model"`
+	Tokenizer string `json:"tokenizer"`
+	Exact     bool   `json:"exact"`
+	gateway.PromptTokenCount
+	ContextWindow int   `json:"context_window,omitempty"`
+	MaxTokens     int   `json:"max_tokens,omitempty"`
+	Fits          *bool `json:"fits,omitempty"`
+}
+
+func (h *ChatHandler) HandleTokenize(c *gin.Context) {
+	var req TokenizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Model == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "you must provide a model parameter")
+		return
+	}
+
+	t := h.router.GetTokenizers().ForModel(req.Model)
+	resp := tokenizeResponse{
+		Model:     req.Model,
+		Tokenizer: t.Name(),
+		Exact:     tokenizer.Exact(t),
+	}
+	if encoder, ok := t.(tokenizer.Encoder); ok {
+		resp.Tokens = encoder.Encode(req.Text)
+		resp.Count = len(resp.Tokens)
+	} else {
+		resp.Count = t.Count(req.Text)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// HandleCountTokens counts the prompt tokens of a chat completion request without sending it.
+func (h *ChatHandler) HandleCountTokens(c *gin.Context) {
+	var oaReq OpenAIChatCompletionRequest
+	if err := c.ShouldBindJSON(&oaReq); err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if msg := oaReq.validate(); msg != "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", msg)
+		return
+	}
+
+	req := oaReq.toChatRequest()
+	t := h.router.GetTokenizers().ForModel(req.Model)
+	resp := countTokensResponse{
+		Model:            req.Model,
+		Tokenizer:        t.Name(),
+		Exact:            tokenizer.Exact(t),
+		PromptTokenCount: gateway.CountPromptTokens(t, req),
+		MaxTokens:        req.MaxTokens,
+	}
+	if window, ok := h.router.ModelContextWindow(req.Model); ok {
+		resp.ContextWindow = window
+		fits := resp.Total+req.MaxTokens <= window
+		resp.Fits = &fits
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RequestTokenEstimator estimates the tokens of /v1 requests that reach a provider,
+// for token rate limits. Other requests, and bodies that do not parse, count as 0 and
+// are left to the handler to reject.
+func RequestTokenEstimator(router *gateway.Router) func(*gin.Context) int {
+	return func(c *gin.Context) int {
+		path := c.FullPath()
+		if path != "/v1/chat" && path != "/v1/chat/stream" && path != "/v1/chat/completions" && path != "/v1/embeddings" &&
+			path != "/v1/chat/compare" && path != "/v1/chat/compare/stream" {
+			return 0
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return 0
+		}
+
+		if path == "/v1/embeddings" {
+			var req EmbeddingsRequest
+			if json.Unmarshal(body, &req) != nil {
+				return 0
+			}
+			inputs, err := embeddingInputs(req.Input)
+			if err != nil {
+				return 0
+			}
+			t := router.GetTokenizers().ForModel(req.Model)
+			total := 0
+			for _, input := range inputs {
+				total += t.Count(input)
+			}
+			return total
+		}
+
+		if path == "/v1/chat/compare" || path == "/v1/chat/compare/stream" {
+			var req CompareRequest
+			if json.Unmarshal(body, &req) != nil {
+				return 0
+			}
+			targets, err := req.targets()
+			if err != nil {
+				return 0
+			}
+			total := 0
+			for _, target := range targets {
+				req.ChatRequest.Model = target.Model
+				total += gateway.EstimateRequestTokens(router.GetTokenizers().ForModel(target.Model), req.ChatRequest)
+			}
+			return total
+		}
+
+		var oaReq OpenAIChatCompletionRequest
+		if json.Unmarshal(body, &oaReq) != nil || oaReq.Model == "" {
+			return 0
+		}
+		req := oaReq.toChatRequest()
+		return gateway.EstimateRequestTokens(router.GetTokenizers().ForModel(req.Model), req)
+	}
+}
+
+// reportTokenUsage settles the request's token rate limit reservation with the actual
+// usage. Cached responses used no provider tokens.
+func reportTokenUsage(ctx context.Context, usage *providers.Usage, cached bool) {
+	switch {
+	case cached:
+		security.ReportTokenUsage(ctx, 0)
+	case usage != nil:
+		security.ReportTokenUsage(ctx, usage.TotalTokens)
+	}
+}
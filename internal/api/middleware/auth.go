@@ -61,6 +61,9 @@ func AuthMiddleware(apiKeyService *security.APIKeyServiceV2, userRepo *storage.U
 				c.Abort()
 				return
 			}
+			if err == nil && user != nil {
+				c.Set("user_roles", user.Roles)
+			}
 		}
 
 		c.Set("api_key", apiKey)
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	BudgetWarningHeader   = "X-UniRoute-Budget-Warning"
+	BudgetRemainingHeader = "X-UniRoute-Budget-Remaining-USD"
+)
+
+// BudgetMiddleware rejects requests with 402 once the user or API key has reached a
+// hard spend limit, and adds a warning header once a soft limit is reached. It must
+// run after AuthMiddleware or JWTAuthMiddleware. Like the rate limits, it lets
+// requests through when spend cannot be read.
+func BudgetMiddleware(tracker *security.BudgetTracker, logger zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var userID uuid.UUID
+		var keyID *uuid.UUID
+		if keyRecord, ok := c.Value("api_key_record").(*storage.APIKey); ok && keyRecord != nil {
+			userID, keyID = keyRecord.UserID, &keyRecord.ID
+		} else if id, err := uuid.Parse(c.GetString("user_id")); err == nil {
+			userID = id
+		} else {
+			c.Next()
+			return
+		}
+
+		statuses, err := tracker.Check(c.Request.Context(), userID, keyID)
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", userID.String()).Msg("Budget check failed, allowing request")
+			c.Next()
+			return
+		}
+
+		var remaining *float64
+		for _, status := range statuses {
+			if status.LimitReached {
+				c.JSON(http.StatusPaymentRequired, gin.H{
+					"error":  errors.ErrBudgetExceeded.Error(),
+					"code":   "BUDGET_EXCEEDED",
+					"budget": status,
+					"message": fmt.Sprintf("The %s budget of $%.2f for this %s has been used up (spent $%.4f)",
+						status.Period, *status.LimitUSD, budgetScopeName(status.Scope), status.SpentUSD),
+				})
+				c.Abort()
+				return
+			}
+			if status.SoftLimitReached {
+				c.Writer.Header().Add(BudgetWarningHeader, fmt.Sprintf("%s %s budget: $%.4f spent of $%.2f soft limit",
+					status.Scope, status.Period, status.SpentUSD, *status.SoftLimitUSD))
+			}
+			if status.RemainingUSD != nil && (remaining == nil || *status.RemainingUSD < *remaining) {
+				remaining = status.RemainingUSD
+			}
+		}
+		if remaining != nil {
+			c.Header(BudgetRemainingHeader, strconv.FormatFloat(*remaining, 'f', 4, 64))
+		}
+
+		c.Next()
+	}
+}
+
+func budgetScopeName(scope string) string {
+	if scope == security.BudgetScopeAPIKey {
+		return "API key"
+	}
+	return "account"
+}
@@ -1,15 +1,23 @@
 package middleware
 
 import (
+	"context"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
 	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
+	"github.com/gin-gonic/gin"
 )
 
+// RateLimitMiddleware limits requests per sliding minute and day. Limits come from
+// the API key record, or from getLimits for other callers. Besides the per-window
+// X-RateLimit-*-PerMinute/PerDay headers it sets X-RateLimit-Limit, -Remaining and
+// -Reset for the tightest window, the same with a -Requests suffix, and Retry-After
+// when the request is rejected.
 func RateLimitMiddleware(rateLimiter *security.RateLimiter, getLimits func(string) (int, int)) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := getIdentifier(c)
@@ -26,7 +34,7 @@ func RateLimitMiddleware(rateLimiter *security.RateLimiter, getLimits func(strin
 			limitPerMinute, limitPerDay = getLimits(identifier)
 		}
 
-		allowed, err := rateLimiter.CheckRateLimit(c.Request.Context(), identifier, limitPerMinute, limitPerDay)
+		allowed, windows, err := rateLimiter.Take(c.Request.Context(), "requests:"+identifier, security.MinuteAndDayLimits(limitPerMinute, limitPerDay), 1)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "rate limit check failed",
@@ -35,14 +43,17 @@ func RateLimitMiddleware(rateLimiter *security.RateLimiter, getLimits func(strin
 			return
 		}
 
+		c.Header("X-RateLimit-Limit-PerMinute", strconv.Itoa(limitPerMinute))
+		c.Header("X-RateLimit-Limit-PerDay", strconv.Itoa(limitPerDay))
+		c.Header("X-RateLimit-Remaining-PerMinute", strconv.FormatInt(windows[0].Remaining, 10))
+		c.Header("X-RateLimit-Remaining-PerDay", strconv.FormatInt(windows[1].Remaining, 10))
+		tightest := setRateLimitHeaders(c, "Requests", windows)
+		c.Header("X-RateLimit-Limit", strconv.FormatInt(tightest.Limit, 10))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(tightest.Remaining, 10))
+		c.Header("X-RateLimit-Reset", headerSeconds(tightest.Reset))
+
 		if !allowed {
-			minRemaining, dayRemaining, _ := rateLimiter.GetRemainingRequests(c.Request.Context(), identifier, limitPerMinute, limitPerDay)
-			
-			c.Header("X-RateLimit-Limit-PerMinute", strconv.Itoa(limitPerMinute))
-			c.Header("X-RateLimit-Limit-PerDay", strconv.Itoa(limitPerDay))
-			c.Header("X-RateLimit-Remaining-PerMinute", strconv.FormatInt(minRemaining, 10))
-			c.Header("X-RateLimit-Remaining-PerDay", strconv.FormatInt(dayRemaining, 10))
-			
+			c.Header("Retry-After", headerSeconds(tightest.RetryAfter))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": errors.ErrRateLimitExceeded.Error(),
 			})
@@ -50,14 +61,104 @@ func RateLimitMiddleware(rateLimiter *security.RateLimiter, getLimits func(strin
 			return
 		}
 
-		minRemaining, dayRemaining, _ := rateLimiter.GetRemainingRequests(c.Request.Context(), identifier, limitPerMinute, limitPerDay)
-		c.Header("X-RateLimit-Limit-PerMinute", strconv.Itoa(limitPerMinute))
-		c.Header("X-RateLimit-Limit-PerDay", strconv.Itoa(limitPerDay))
-		c.Header("X-RateLimit-Remaining-PerMinute", strconv.FormatInt(minRemaining, 10))
-		c.Header("X-RateLimit-Remaining-PerDay", strconv.FormatInt(dayRemaining, 10))
+		c.Next()
+	}
+}
+
+// TokenRateLimitMiddleware limits tokens per sliding minute and day, for the API key
+// (token_limit_per_minute/day) and for its user across all keys (userLimits). estimate
+// returns the tokens a request is expected to use; they are taken before routing and
+// settled with the usage the handler reports through security.ReportTokenUsage.
+// Requests without an estimate, such as MCP calls, are not limited.
+func TokenRateLimitMiddleware(rateLimiter *security.RateLimiter, estimate func(*gin.Context) int, userLimits security.TokenLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("api_key_record")
+		if !exists {
+			c.Next()
+			return
+		}
+		keyRecord, ok := value.(*storage.APIKey)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		var scopes []security.TokenLimitScope
+		keyLimits := security.TokenLimits{PerMinute: keyRecord.TokenLimitPerMinute, PerDay: keyRecord.TokenLimitPerDay}
+		if keyLimits.Enabled() {
+			scopes = append(scopes, security.TokenLimitScope{Key: "key:" + keyRecord.ID.String(), Limits: keyLimits})
+		}
+		if userLimits.Enabled() {
+			scopes = append(scopes, security.TokenLimitScope{Key: "user:" + keyRecord.UserID.String(), Limits: userLimits})
+		}
+		if len(scopes) == 0 {
+			c.Next()
+			return
+		}
+		tokens := estimate(c)
+		if tokens <= 0 {
+			c.Next()
+			return
+		}
+
+		reservation, windows, allowed, err := rateLimiter.ReserveTokens(c.Request.Context(), int64(tokens), scopes...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "rate limit check failed",
+			})
+			c.Abort()
+			return
+		}
+		tightest := setRateLimitHeaders(c, "Tokens", windows)
+		if !allowed {
+			c.Header("Retry-After", headerSeconds(tightest.RetryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":            errors.ErrRateLimitExceeded.Error(),
+				"details":          "token limit exceeded",
+				"estimated_tokens": tokens,
+			})
+			c.Abort()
+			return
+		}
 
+		c.Request = c.Request.WithContext(security.WithTokenReservation(c.Request.Context(), reservation))
 		c.Next()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		reservation.Settle(ctx, c.Writer.Status() >= http.StatusBadRequest)
+	}
+}
+
+// setRateLimitHeaders sets X-RateLimit-{Limit,Remaining,Reset}-<unit> for the window
+// closest to its limit, or the one that rejected the request, and returns it.
+func setRateLimitHeaders(c *gin.Context, unit string, windows []security.RateLimitWindow) security.RateLimitWindow {
+	var tightest security.RateLimitWindow
+	found := false
+	for _, w := range windows {
+		if w.Limit <= 0 {
+			continue
+		}
+		if w.RetryAfter > 0 {
+			tightest, found = w, true
+			break
+		}
+		if !found || w.Remaining < tightest.Remaining {
+			tightest, found = w, true
+		}
+	}
+	if !found {
+		return tightest
 	}
+	c.Header("X-RateLimit-Limit-"+unit, strconv.FormatInt(tightest.Limit, 10))
+	c.Header("X-RateLimit-Remaining-"+unit, strconv.FormatInt(tightest.Remaining, 10))
+	c.Header("X-RateLimit-Reset-"+unit, headerSeconds(tightest.Reset))
+	return tightest
+}
+
+// headerSeconds rounds d up to whole seconds, at least 1.
+func headerSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Max(1, math.Ceil(d.Seconds()))), 10)
 }
 
 func getIdentifier(c *gin.Context) string {
@@ -68,4 +169,3 @@ func getIdentifier(c *gin.Context) string {
 	}
 	return "ip:" + c.ClientIP()
 }
-
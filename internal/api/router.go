@@ -24,6 +24,8 @@ func SetupRouter(
 	jwtService *security.JWTService,
 	rateLimiter *security.RateLimiter,
 	authRateLimiter *security.AuthRateLimiter,
+	budgetTracker *security.BudgetTracker,
+	userTokenLimits security.TokenLimits,
 	ipWhitelist []string,
 	requestRepo *storage.RequestRepository,
 	providerKeyService *security.ProviderKeyService,
@@ -127,12 +129,25 @@ func SetupRouter(
 			apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyServiceV2)
 			authProtected.POST("/api-keys", apiKeyHandler.CreateAPIKey)
 			authProtected.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+			authProtected.PUT("/api-keys/:id/cache", apiKeyHandler.UpdateAPIKeyCache)
+			authProtected.PUT("/api-keys/:id/hedging", apiKeyHandler.UpdateAPIKeyHedging)
+			authProtected.PUT("/api-keys/:id/pii-policy", apiKeyHandler.UpdateAPIKeyPIIPolicy)
+			if budgetTracker != nil && postgresClient != nil {
+				apiKeyHandler.SetBudgets(storage.NewBudgetRepository(postgresClient.Pool()), budgetTracker)
+				authProtected.GET("/api-keys/:id/budget", apiKeyHandler.GetAPIKeyBudget)
+				authProtected.PUT("/api-keys/:id/budget", apiKeyHandler.UpdateAPIKeyBudget)
+				authProtected.GET("/budget", apiKeyHandler.GetUserBudget)
+				authProtected.PUT("/budget", apiKeyHandler.UpdateUserBudget)
+			}
 			authProtected.DELETE("/api-keys/:id/permanent", apiKeyHandler.DeleteAPIKeyPermanently)
 			authProtected.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
 		}
 
 		if providerKeyService != nil {
 			keyValidator := providers.NewKeyValidator(zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
+			for _, config := range router.CompatibleBYOK() {
+				keyValidator.AddCompatibleProvider(config)
+			}
 			providerKeyHandler := handlers.NewProviderKeyHandler(providerKeyService, keyValidator)
 			authProtected.POST("/provider-keys", providerKeyHandler.AddProviderKey)
 			authProtected.GET("/provider-keys", providerKeyHandler.ListProviderKeys)
@@ -155,9 +170,14 @@ func SetupRouter(
 		chatHandler := handlers.NewChatHandler(router, requestRepo, convRepo, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
 		chatHandler.SetJWTService(jwtService)
 		chatHandler.SetMCPService(mcpService)
-		authProtected.POST("/chat", chatHandler.HandleChat)
-		authProtected.POST("/chat/stream", chatHandler.HandleChatStream)
-		authProtected.GET("/chat/ws", chatHandler.HandleChatWebSocket)
+		chatHandler.SetBudgetTracker(budgetTracker)
+		chatGroup := authProtected.Group("")
+		if budgetTracker != nil {
+			chatGroup.Use(middleware.BudgetMiddleware(budgetTracker, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger()))
+		}
+		chatGroup.POST("/chat", chatHandler.HandleChat)
+		chatGroup.POST("/chat/stream", chatHandler.HandleChatStream)
+		chatGroup.GET("/chat/ws", chatHandler.HandleChatWebSocket)
 
 		authProtected.GET("/mcp/servers", mcpHandler.ListServers)
 		authProtected.GET("/mcp/tools", mcpHandler.ListTools)
@@ -218,6 +238,10 @@ func SetupRouter(
 				userCustomRulesHandler := handlers.NewCustomRulesHandler(router, customRulesRepo, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
 				authProtected.GET("/routing/custom-rules", userCustomRulesHandler.GetCustomRules)
 				authProtected.POST("/routing/custom-rules", userCustomRulesHandler.SetCustomRules)
+
+				userAliasHandler := handlers.NewModelAliasHandler(router, storage.NewModelAliasRepository(postgresClient.Pool()), zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
+				authProtected.GET("/routing/model-aliases", userAliasHandler.GetModelAliases)
+				authProtected.POST("/routing/model-aliases", userAliasHandler.SetModelAliases)
 			}
 		}
 	}
@@ -233,6 +257,10 @@ func SetupRouter(
 		api.Use(middleware.RateLimitMiddleware(rateLimiter, func(identifier string) (int, int) {
 			return 60, 10000
 		}))
+		api.Use(middleware.TokenRateLimitMiddleware(rateLimiter, handlers.RequestTokenEstimator(router), userTokenLimits))
+		if budgetTracker != nil {
+			api.Use(middleware.BudgetMiddleware(budgetTracker, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger()))
+		}
 	} else {
 		api.Use(func(c *gin.Context) {
 			c.JSON(http.StatusServiceUnavailable, gin.H{
@@ -244,9 +272,17 @@ func SetupRouter(
 
 	chatHandler := handlers.NewChatHandler(router, requestRepo, nil, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
 	chatHandler.SetMCPService(mcpService)
+	chatHandler.SetBudgetTracker(budgetTracker)
 	api.POST("/chat", chatHandler.HandleChat)
 	api.POST("/chat/stream", chatHandler.HandleChatStream)
+	api.POST("/chat/completions", chatHandler.HandleChatCompletions)
+	api.POST("/chat/compare", chatHandler.HandleChatCompare)
+	api.POST("/chat/compare/stream", chatHandler.HandleChatCompareStream)
+	api.POST("/embeddings", chatHandler.HandleEmbeddings)
+	api.POST("/tokenize", chatHandler.HandleTokenize)
+	api.POST("/count-tokens", chatHandler.HandleCountTokens)
 	api.GET("/chat/ws", chatHandler.HandleChatWebSocket)
+	api.POST("/routing/explain", chatHandler.HandleExplainRouting)
 
 	api.GET("/mcp/servers", mcpHandler.ListServers)
 	api.GET("/mcp/tools", mcpHandler.ListTools)
@@ -294,6 +330,7 @@ func SetupRouter(
 		if requestRepo != nil {
 			adminAnalyticsHandler := handlers.NewAnalyticsHandler(requestRepo, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
 			admin.GET("/analytics/usage", adminAnalyticsHandler.GetUsageStatsAdmin)
+			admin.GET("/analytics/experiments/:id", adminAnalyticsHandler.GetExperimentStats)
 		}
 
 		if settingsRepo != nil {
@@ -306,9 +343,45 @@ func SetupRouter(
 				customRulesHandler := handlers.NewCustomRulesHandler(router, customRulesRepo, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
 				admin.GET("/routing/custom-rules", customRulesHandler.GetCustomRules)
 				admin.POST("/routing/custom-rules", customRulesHandler.SetCustomRules)
+
+				aliasHandler := handlers.NewModelAliasHandler(router, storage.NewModelAliasRepository(postgresClient.Pool()), zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
+				admin.GET("/routing/model-aliases", aliasHandler.GetModelAliases)
+				admin.POST("/routing/model-aliases", aliasHandler.SetModelAliases)
 			}
 		}
 
+		providerAdminHandler := handlers.NewProviderAdminHandler(router, settingsRepo, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
+		admin.GET("/providers", providerAdminHandler.ListProviders)
+		admin.PUT("/providers/:name", providerAdminHandler.SetProviderEnabled)
+
+		var priceRepo *storage.ModelPriceRepository
+		if postgresClient != nil {
+			priceRepo = storage.NewModelPriceRepository(postgresClient.Pool())
+		}
+		pricingHandler := handlers.NewPricingHandler(router, priceRepo, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
+		admin.GET("/pricing", pricingHandler.GetPricing)
+		admin.GET("/pricing/lookup", pricingHandler.LookupPrice)
+		admin.POST("/pricing/reload", pricingHandler.ReloadPricing)
+		if priceRepo != nil {
+			admin.POST("/pricing", pricingHandler.CreatePrice)
+			admin.PUT("/pricing/:id", pricingHandler.UpdatePrice)
+			admin.DELETE("/pricing/:id", pricingHandler.DeletePrice)
+		}
+
+		if postgresClient != nil {
+			experimentHandler := handlers.NewExperimentHandler(router, storage.NewExperimentRepository(postgresClient.Pool()), zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
+			admin.GET("/experiments", experimentHandler.ListExperiments)
+			admin.POST("/experiments", experimentHandler.CreateExperiment)
+			admin.GET("/experiments/:id", experimentHandler.GetExperiment)
+			admin.PUT("/experiments/:id", experimentHandler.UpdateExperiment)
+			admin.DELETE("/experiments/:id", experimentHandler.DeleteExperiment)
+
+			shadowHandler := handlers.NewShadowHandler(router, storage.NewShadowRequestRepository(postgresClient.Pool()), zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
+			admin.GET("/shadow/rules", shadowHandler.GetShadowRules)
+			admin.GET("/shadow/requests", shadowHandler.ListShadowRequests)
+			admin.GET("/shadow/summary", shadowHandler.GetShadowSummary)
+		}
+
 		if postgresClient != nil {
 			tunnelRepo := tunnel.NewTunnelRepository(postgresClient.Pool(), zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
 			tunnelHandler := handlers.NewTunnelHandler(tunnelRepo, zerolog.New(gin.DefaultWriter).With().Timestamp().Logger())
@@ -51,7 +51,45 @@ type Config struct {
 	// vLLM (OpenAI-compatible local server)
 	VLLMBaseURL string
 	VLLMAPIKey  string
+	// Azure OpenAI resource; deployments map model names to deployment names ("gpt-4o=prod-gpt4o,...")
+	AzureOpenAIAPIKey      string
+	AzureOpenAIEndpoint    string
+	AzureOpenAIAPIVersion  string
+	AzureOpenAIDeployments map[string]string
+	// Hosts besides *.openai.azure.com and *.cognitiveservices.azure.com that BYOK Azure endpoints may use
+	AzureOpenAIBYOKHosts []string
 	MCPServers []string
+	// Background provider health probe interval in seconds (0 disables probing)
+	ProviderHealthInterval int
+	// Directory holding cl100k_base.tiktoken / o200k_base.tiktoken for exact OpenAI token counts
+	TokenizerDataDir string
+	// Extra tiktoken-format tokenizers by model pattern ("llama3*=/models/llama3/tokenizer.model,...")
+	TokenizerModels map[string]string
+	// JSON or YAML pricing catalog layered over the built-in prices (optional)
+	PricingCatalogPath string
+	// How often the pricing file and model_prices table are re-read, in seconds (0 disables)
+	PricingReloadInterval int
+	// Token limits per user across all of their API keys (0 = unlimited)
+	UserTokenLimitPerMinute int
+	UserTokenLimitPerDay    int
+	// JSON or YAML file of shadow rules mirroring sampled requests to candidate providers (optional)
+	ShadowConfigPath string
+	// JSON or YAML file of webhook hooks that check chat requests with external policy services (optional)
+	HooksConfigPath string
+	// JSON or YAML file of named OpenAI-compatible upstreams such as Groq or LM Studio (optional)
+	CompatibleProvidersPath string
+	// What to do with conversations over their model's context window: off, reject, truncate or summarize
+	ContextStrategy string
+	// Model that summarizes older turns for the summarize strategy, and the summary length
+	ContextSummaryModel     string
+	ContextSummaryMaxTokens int
+	// Context length of models missing from the built-in list ("llama3:8b=8192,...")
+	ContextWindows map[string]int
+	// How long model lists discovered from provider APIs are cached, in seconds
+	ModelDiscoveryTTL int
+	// Models listed per provider, as provider:pattern entries ("openai:gpt-4o*,anthropic:claude-sonnet-*")
+	ModelAllowlist map[string][]string
+	ModelDenylist  map[string][]string
 }
 
 func Load() *Config {
@@ -105,10 +143,79 @@ func Load() *Config {
 		SeedAdminPassword:        getEnv("SEED_ADMIN_PASSWORD", ""),
 		VLLMBaseURL:              getEnv("VLLM_BASE_URL", ""),
 		VLLMAPIKey:               getEnv("VLLM_API_KEY", ""),
+		AzureOpenAIAPIKey:        getEnv("AZURE_OPENAI_API_KEY", ""),
+		AzureOpenAIEndpoint:      getEnv("AZURE_OPENAI_ENDPOINT", ""),
+		AzureOpenAIAPIVersion:    getEnv("AZURE_OPENAI_API_VERSION", ""),
+		AzureOpenAIDeployments:   parseAzureDeployments(getEnv("AZURE_OPENAI_DEPLOYMENTS", "")),
+		AzureOpenAIBYOKHosts:     parseCORSOrigins(getEnv("AZURE_OPENAI_BYOK_HOSTS", "")),
 		MCPServers:               parseMCPServers(getEnv("MCP_SERVERS", "")),
+		ProviderHealthInterval:   getEnvAsInt("PROVIDER_HEALTH_INTERVAL", 60),
+		TokenizerDataDir:         getEnv("TOKENIZER_DATA_DIR", ""),
+		TokenizerModels:          parseTokenizerModels(getEnv("TOKENIZER_MODELS", "")),
+		PricingCatalogPath:       getEnv("PRICING_CATALOG_PATH", ""),
+		PricingReloadInterval:    getEnvAsInt("PRICING_RELOAD_INTERVAL", 60),
+		UserTokenLimitPerMinute:  getEnvAsInt("USER_TOKEN_LIMIT_PER_MINUTE", 0),
+		UserTokenLimitPerDay:     getEnvAsInt("USER_TOKEN_LIMIT_PER_DAY", 0),
+		ShadowConfigPath:         getEnv("SHADOW_CONFIG_PATH", ""),
+		HooksConfigPath:          getEnv("HOOKS_CONFIG_PATH", ""),
+		CompatibleProvidersPath:  getEnv("OPENAI_COMPATIBLE_CONFIG_PATH", ""),
+		ContextStrategy:          getEnv("CONTEXT_STRATEGY", ""),
+		ContextSummaryModel:      getEnv("CONTEXT_SUMMARY_MODEL", ""),
+		ContextSummaryMaxTokens:  getEnvAsInt("CONTEXT_SUMMARY_MAX_TOKENS", 512),
+		ContextWindows:           parseContextWindows(getEnv("CONTEXT_WINDOWS", "")),
+		ModelDiscoveryTTL:        getEnvAsInt("MODEL_DISCOVERY_TTL", 3600),
+		ModelAllowlist:           parseModelPatterns(getEnv("MODEL_ALLOWLIST", "")),
+		ModelDenylist:            parseModelPatterns(getEnv("MODEL_DENYLIST", "")),
 	}
 }
 
+func parseContextWindows(s string) map[string]int {
+	windows := make(map[string]int)
+	for _, entry := range strings.Split(s, ",") {
+		model, value, ok := strings.Cut(entry, "=")
+		tokens, err := strconv.Atoi(strings.TrimSpace(value))
+		if ok && err == nil && tokens > 0 && strings.TrimSpace(model) != "" {
+			windows[strings.TrimSpace(model)] = tokens
+		}
+	}
+	return windows
+}
+
+func parseAzureDeployments(s string) map[string]string {
+	deployments := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		model, deployment, ok := strings.Cut(entry, "=")
+		model, deployment = strings.TrimSpace(model), strings.TrimSpace(deployment)
+		if ok && model != "" && deployment != "" {
+			deployments[model] = deployment
+		}
+	}
+	return deployments
+}
+
+func parseModelPatterns(s string) map[string][]string {
+	patterns := make(map[string][]string)
+	for _, entry := range strings.Split(s, ",") {
+		provider, pattern, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if ok && provider != "" && pattern != "" {
+			patterns[provider] = append(patterns[provider], pattern)
+		}
+	}
+	return patterns
+}
+
+func parseTokenizerModels(s string) map[string]string {
+	models := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		pattern, path, ok := strings.Cut(entry, "=")
+		pattern, path = strings.TrimSpace(pattern), strings.TrimSpace(path)
+		if ok && pattern != "" && path != "" {
+			models[pattern] = path
+		}
+	}
+	return models
+}
+
 func parseMCPServers(s string) []string {
 	if s == "" {
 		return nil
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+func (r *Router) GetCapabilities() *providers.CapabilityRegistry {
+	return r.capabilities
+}
+
+// DiscoverCapabilities returns the discovery errors by provider name.
+func (r *Router) DiscoverCapabilities(ctx context.Context) map[string]error {
+	errs := make(map[string]error)
+	for _, provider := range r.getAllProviders() {
+		if err := r.capabilities.Discover(ctx, provider); err != nil {
+			errs[provider.Name()] = err
+		}
+	}
+	return errs
+}
+
+func (r *Router) ModelCapabilities(provider providers.Provider, models []string) map[string]providers.Capabilities {
+	out := make(map[string]providers.Capabilities, len(models))
+	for _, model := range models {
+		out[model] = r.capabilities.Lookup(provider, model)
+	}
+	return out
+}
+
+func (r *Router) capableProviders(available []providers.Provider, model string, needs providers.Capabilities) []providers.Provider {
+	out := make([]providers.Provider, 0, len(available))
+	for _, provider := range available {
+		if len(r.capabilities.Lookup(provider, model).Missing(needs)) == 0 {
+			out = append(out, provider)
+		}
+	}
+	return out
+}
+
+func (r *Router) capableTargets(targets []routeTarget, needs providers.Capabilities) []routeTarget {
+	out := make([]routeTarget, 0, len(targets))
+	for _, target := range targets {
+		if len(r.capabilities.Lookup(target.provider, target.req.Model).Missing(needs)) == 0 {
+			out = append(out, target)
+		}
+	}
+	return out
+}
+
+func capabilityError(model string, needs providers.Capabilities) error {
+	return fmt.Errorf("no available provider supports %s for model %s",
+		strings.Join(providers.Capabilities{}.Missing(needs), " and "), model)
+}
@@ -0,0 +1,315 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+type CircuitBreakerConfig struct {
+	FailureThreshold   int           // consecutive failures that open the breaker
+	ErrorRateThreshold float64       // failure ratio over the window that opens the breaker
+	WindowSize         int           // number of recent calls the error rate is computed over
+	MinRequests        int           // calls required in the window before the error rate applies
+	OpenTimeout        time.Duration // time spent open before probe requests are let through
+	HalfOpenProbes     int           // concurrent probe requests allowed while half-open
+}
+
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold:   5,
+		ErrorRateThreshold: 0.5,
+		WindowSize:         20,
+		MinRequests:        10,
+		OpenTimeout:        30 * time.Second,
+		HalfOpenProbes:     1,
+	}
+}
+
+// BreakerSnapshot is the externally visible state of one provider's breaker.
+type BreakerSnapshot struct {
+	Provider            string       `json:"provider"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	ErrorRate           float64      `json:"error_rate"`
+	WindowRequests      int          `json:"window_requests"`
+	Timeouts            int64        `json:"timeouts"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time   `json:"last_failure_at,omitempty"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	LastProbeAt         *time.Time   `json:"last_probe_at,omitempty"`
+}
+
+// CircuitBreaker tracks outcomes of real traffic to one provider. Open breakers
+// reject calls until OpenTimeout passes, then admit a few half-open probes.
+type CircuitBreaker struct {
+	mu                  sync.Mutex
+	provider            string
+	config              CircuitBreakerConfig
+	state               BreakerState
+	consecutiveFailures int
+	window              []bool // true = failure
+	windowPos           int
+	timeouts            int64
+	probesInFlight      int
+	lastError           string
+	lastFailureAt       time.Time
+	openedAt            time.Time
+	lastProbeAt         time.Time
+}
+
+func NewCircuitBreaker(provider string, config CircuitBreakerConfig) *CircuitBreaker {
+	if config.WindowSize <= 0 {
+		config.WindowSize = 1
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	return &CircuitBreaker{
+		provider: provider,
+		config:   config,
+		state:    BreakerClosed,
+		window:   make([]bool, 0, config.WindowSize),
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Available reports whether the provider may be offered to routing strategies.
+// It does not reserve a probe slot; Allow does that right before the call.
+func (b *CircuitBreaker) Available() bool {
+	return b.State() != BreakerOpen
+}
+
+// Allow reports whether a call may be made now. In half-open state only
+// HalfOpenProbes calls are admitted until one of them reports back.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	switch b.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.probesInFlight >= b.config.HalfOpenProbes {
+			return false
+		}
+		b.probesInFlight++
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures = 0
+	b.push(false)
+	if b.state == BreakerHalfOpen {
+		b.transition(BreakerClosed)
+	}
+}
+
+// RecordFailure counts err against the provider. Client cancellations are ignored.
+func (b *CircuitBreaker) RecordFailure(err error) {
+	if errors.Is(err, context.Canceled) {
+		b.release()
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures++
+	b.push(true)
+	if isTimeout(err) {
+		b.timeouts++
+	}
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	b.lastFailureAt = time.Now()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.transition(BreakerOpen)
+	case BreakerClosed:
+		if b.consecutiveFailures >= b.config.FailureThreshold || b.errorRateExceeded() {
+			b.transition(BreakerOpen)
+		}
+	}
+}
+
+// RecordProbe applies a background health check result. A passing check moves an
+// open breaker to half-open so real traffic can confirm recovery.
+func (b *CircuitBreaker) RecordProbe(err error) {
+	b.mu.Lock()
+	b.lastProbeAt = time.Now()
+	if err == nil {
+		if b.state == BreakerOpen {
+			b.transition(BreakerHalfOpen)
+		}
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Unlock()
+	b.RecordFailure(err)
+}
+
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	snap := BreakerSnapshot{
+		Provider:            b.provider,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		ErrorRate:           b.errorRate(),
+		WindowRequests:      len(b.window),
+		Timeouts:            b.timeouts,
+		LastError:           b.lastError,
+	}
+	if !b.lastFailureAt.IsZero() {
+		t := b.lastFailureAt
+		snap.LastFailureAt = &t
+	}
+	if b.state != BreakerClosed && !b.openedAt.IsZero() {
+		t := b.openedAt
+		snap.OpenedAt = &t
+	}
+	if !b.lastProbeAt.IsZero() {
+		t := b.lastProbeAt
+		snap.LastProbeAt = &t
+	}
+	return snap
+}
+
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+}
+
+// advance moves an open breaker to half-open once OpenTimeout has passed. Caller holds mu.
+func (b *CircuitBreaker) advance() {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.config.OpenTimeout {
+		b.transition(BreakerHalfOpen)
+	}
+}
+
+// transition changes state and resets per-state counters. Caller holds mu.
+func (b *CircuitBreaker) transition(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	b.probesInFlight = 0
+	switch state {
+	case BreakerOpen:
+		b.openedAt = time.Now()
+	case BreakerClosed:
+		b.consecutiveFailures = 0
+		b.window = b.window[:0]
+		b.windowPos = 0
+	}
+	monitoring.SetProviderHealth(b.provider, state != BreakerOpen)
+}
+
+func (b *CircuitBreaker) push(failed bool) {
+	if b.state == BreakerHalfOpen && b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+	if len(b.window) < b.config.WindowSize {
+		b.window = append(b.window, failed)
+		return
+	}
+	b.window[b.windowPos] = failed
+	b.windowPos = (b.windowPos + 1) % b.config.WindowSize
+}
+
+func (b *CircuitBreaker) errorRate() float64 {
+	if len(b.window) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, failed := range b.window {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(b.window))
+}
+
+func (b *CircuitBreaker) errorRateExceeded() bool {
+	if b.config.ErrorRateThreshold <= 0 || len(b.window) < b.config.MinRequests {
+		return false
+	}
+	return b.errorRate() >= b.config.ErrorRateThreshold
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// BreakerRegistry holds one CircuitBreaker per provider name.
+type BreakerRegistry struct {
+	mu       sync.RWMutex
+	config   CircuitBreakerConfig
+	breakers map[string]*CircuitBreaker
+}
+
+func NewBreakerRegistry(config CircuitBreakerConfig) *BreakerRegistry {
+	return &BreakerRegistry{
+		config:   config,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+func (r *BreakerRegistry) Get(provider string) *CircuitBreaker {
+	r.mu.RLock()
+	b, ok := r.breakers[provider]
+	r.mu.RUnlock()
+	if ok {
+		return b
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.breakers[provider]; ok {
+		return b
+	}
+	b = NewCircuitBreaker(provider, r.config)
+	r.breakers[provider] = b
+	monitoring.SetProviderHealth(provider, true)
+	return b
+}
+
+// SetConfig replaces the config used for breakers created from now on and resets existing ones.
+func (r *BreakerRegistry) SetConfig(config CircuitBreakerConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	r.breakers = make(map[string]*CircuitBreaker)
+}
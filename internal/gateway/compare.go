@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+)
+
+// CompareTarget is one model of a comparison. Provider pins the call to that provider,
+// without failover; otherwise the model is routed like any other request.
+type CompareTarget struct {
+	Model    string `json:"model"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// CompareResult is the outcome of one target. Err is set when the target failed.
+type CompareResult struct {
+	Target   CompareTarget
+	Response *providers.ChatResponse
+	Latency  time.Duration
+	Err      error
+}
+
+// CompareChunk is a stream chunk from one target, identified by its index. A chunk with
+// Err set ends the target's stream without output.
+type CompareChunk struct {
+	Index int
+	Chunk providers.StreamChunk
+	Err   error
+}
+
+// Compare sends req to every target concurrently and returns the results in target
+// order. A failed target does not affect the others.
+func (r *Router) Compare(ctx context.Context, req providers.ChatRequest, targets []CompareTarget, userID *uuid.UUID) []CompareResult {
+	results := make([]CompareResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			targetReq := req
+			targetReq.Model = target.Model
+			start := time.Now()
+			var resp *providers.ChatResponse
+			var err error
+			if target.Provider == "" {
+				resp, err = r.Route(ctx, targetReq, userID)
+			} else {
+				resp, err = r.withHooks(ctx, targetReq, func(req providers.ChatRequest) (*providers.ChatResponse, error) {
+					pinned, err := r.pinnedTarget(ctx, req, target.Provider, userID)
+					if err != nil {
+						return nil, err
+					}
+					return r.chatTargets(ctx, req, pinned)
+				})
+			}
+			results[i] = CompareResult{Target: target, Response: resp, Latency: time.Since(start), Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// CompareStream streams req from every target concurrently. Chunks of different targets
+// are interleaved; each target's stream ends with a Done chunk or an error. The channel
+// is closed once every target has finished.
+func (r *Router) CompareStream(ctx context.Context, req providers.ChatRequest, targets []CompareTarget, userID *uuid.UUID) <-chan CompareChunk {
+	out := make(chan CompareChunk, len(targets))
+	send := func(chunk CompareChunk) {
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+		}
+	}
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			targetReq := req
+			targetReq.Model = target.Model
+			var chunks <-chan providers.StreamChunk
+			var errs <-chan error
+			if target.Provider == "" {
+				chunks, errs = r.RouteStream(ctx, targetReq, userID)
+			} else {
+				chunks, errs = r.streamWithHooks(ctx, targetReq, func(req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+					return r.streamPinned(ctx, req, target.Provider, userID)
+				})
+			}
+			// Keep draining after the caller has gone so the router's stream can finish.
+			done := false
+			for chunk := range chunks {
+				done = done || chunk.Done
+				send(CompareChunk{Index: i, Chunk: chunk})
+			}
+			if err, ok := <-errs; ok && err != nil && !done {
+				send(CompareChunk{Index: i, Err: err})
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// pinnedTarget is the named provider as the only target, if it is available to the user.
+func (r *Router) pinnedTarget(ctx context.Context, req providers.ChatRequest, name string, userID *uuid.UUID) ([]routeTarget, error) {
+	for _, p := range r.getAvailableProviders(ctx, userID) {
+		if strings.EqualFold(p.Name(), name) {
+			return []routeTarget{{provider: p, req: req}}, nil
+		}
+	}
+	return nil, fmt.Errorf("provider %s is not available", name)
+}
+
+func (r *Router) streamPinned(ctx context.Context, req providers.ChatRequest, name string, userID *uuid.UUID) (<-chan providers.StreamChunk, <-chan error) {
+	chunkChan := make(chan providers.StreamChunk, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+		targets, err := r.pinnedTarget(ctx, req, name, userID)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		r.streamTargets(ctx, req, targets, chunkChan, errChan)
+	}()
+	return chunkChan, errChan
+}
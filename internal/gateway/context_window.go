@@ -0,0 +1,404 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/tokenizer"
+	"github.com/google/uuid"
+)
+
+// What the router does with a request whose prompt does not fit its model's context
+// window. Off leaves it to the provider, apart from the max_tokens check every request gets.
+const (
+	ContextStrategyOff       = "off"
+	ContextStrategyReject    = "reject"
+	ContextStrategyTruncate  = "truncate"
+	ContextStrategySummarize = "summarize"
+)
+
+// DefaultContextSummaryTokens is the length of a conversation summary when
+// ContextConfig.SummaryMaxTokens is unset.
+const DefaultContextSummaryTokens = 512
+
+// ContextConfig configures context-window management for the whole router.
+type ContextConfig struct {
+	// Strategy applies to requests that do not choose one (see WithContextPolicy).
+	Strategy string
+	// SummaryModel writes the summaries of the summarize strategy, preferably a cheap
+	// model with a large context window. Without it, summarize truncates.
+	SummaryModel     string
+	SummaryMaxTokens int
+	// Windows sets the context length of models by exact name, over the built-in ones.
+	Windows map[string]int
+}
+
+func ValidContextStrategy(strategy string) bool {
+	switch strategy {
+	case "", ContextStrategyOff, ContextStrategyReject, ContextStrategyTruncate, ContextStrategySummarize:
+		return true
+	}
+	return false
+}
+
+func (r *Router) SetContextConfig(config ContextConfig) {
+	r.contextConfig = config
+}
+
+func (r *Router) SetConversationSummaryStore(store ConversationSummaryStore) {
+	r.summaryStore = store
+}
+
+// ModelContextWindow returns the context length of model: a configured window, the one
+// discovered from a provider, or the built-in one of a well-known model.
+func (r *Router) ModelContextWindow(model string) (int, bool) {
+	if window, ok := r.contextConfig.Windows[model]; ok && window > 0 {
+		return window, true
+	}
+	return r.capabilities.ContextLength(model)
+}
+
+// ContextPolicy is the context-window strategy of one request. ConversationID lets the
+// summarize strategy store its summary on the conversation, so later turns reuse it.
+type ContextPolicy struct {
+	Strategy       string
+	ConversationID *uuid.UUID
+}
+
+type contextPolicyKey struct{}
+
+func WithContextPolicy(ctx context.Context, policy ContextPolicy) context.Context {
+	return context.WithValue(ctx, contextPolicyKey{}, policy)
+}
+
+func (r *Router) contextPolicy(ctx context.Context) ContextPolicy {
+	policy, _ := ctx.Value(contextPolicyKey{}).(ContextPolicy)
+	if policy.Strategy == "" {
+		policy.Strategy = r.contextConfig.Strategy
+	}
+	return policy
+}
+
+// ConversationSummary replaces the first MessageCount non-system messages of a
+// conversation.
+type ConversationSummary struct {
+	Text         string
+	MessageCount int
+}
+
+// ConversationSummaryStore persists conversation summaries.
+type ConversationSummaryStore interface {
+	GetConversationSummary(ctx context.Context, conversationID, userID uuid.UUID) (*ConversationSummary, error)
+	SaveConversationSummary(ctx context.Context, conversationID, userID uuid.UUID, summary ConversationSummary) error
+}
+
+// contextFit is a request fitted to its model's context window, with the usage and
+// cost of summarizing it.
+type contextFit struct {
+	req   providers.ChatRequest
+	usage providers.Usage
+	cost  float64
+}
+
+// contextTurn is a message that is not a system message, with the tool results that
+// answer it. Turns are dropped whole so tool calls keep their results.
+type contextTurn struct {
+	indices []int
+	tokens  int
+}
+
+// routeInContext is routeStructured for the request fitted to its model's context window.
+func (r *Router) routeInContext(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (*providers.ChatResponse, error) {
+	fit, err := r.fitContext(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.routeStructured(ctx, fit.req, userID)
+	if err == nil {
+		resp.Usage.PromptTokens += fit.usage.PromptTokens
+		resp.Usage.CompletionTokens += fit.usage.CompletionTokens
+		resp.Usage.TotalTokens += fit.usage.TotalTokens
+		resp.Cost += fit.cost
+	}
+	return resp, err
+}
+
+// routeStreamInContext is routeStream for the request fitted to its model's context
+// window. The usage of a summary is added to the final chunk.
+func (r *Router) routeStreamInContext(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (<-chan providers.StreamChunk, <-chan error) {
+	fit, err := r.fitContext(ctx, req, userID)
+	if err != nil {
+		chunkChan := make(chan providers.StreamChunk)
+		errChan := make(chan error, 1)
+		errChan <- err
+		close(errChan)
+		close(chunkChan)
+		return chunkChan, errChan
+	}
+	chunks, errs := r.routeStream(ctx, fit.req, userID)
+	if fit.usage.TotalTokens == 0 {
+		return chunks, errs
+	}
+
+	chunkChan := make(chan providers.StreamChunk, 10)
+	go func() {
+		defer close(chunkChan)
+		forward := true
+		for chunk := range chunks {
+			if !forward {
+				continue
+			}
+			if chunk.Done && chunk.Usage != nil {
+				usage := *chunk.Usage
+				usage.PromptTokens += fit.usage.PromptTokens
+				usage.CompletionTokens += fit.usage.CompletionTokens
+				usage.TotalTokens += fit.usage.TotalTokens
+				chunk.Usage = &usage
+			}
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+				forward = false
+			}
+		}
+	}()
+	return chunkChan, errs
+}
+
+// fitContext applies the request's context strategy when its prompt plus max_tokens
+// (or the assumed completion length) does not fit the model's context window.
+func (r *Router) fitContext(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (contextFit, error) {
+	fit := contextFit{req: req}
+	policy := r.contextPolicy(ctx)
+	if policy.Strategy == "" || policy.Strategy == ContextStrategyOff {
+		return fit, nil
+	}
+	model, window, reserve, ok := r.contextLimit(ctx, req, userID)
+	if !ok {
+		return fit, nil
+	}
+	t := r.tokenizers.ForModel(model)
+	promptTokens := CountPromptTokens(t, req).Total
+	if promptTokens+reserve <= window {
+		return fit, nil
+	}
+	budget := window - reserve
+	turns := contextTurns(t, req.Messages)
+
+	if policy.Strategy == ContextStrategySummarize && r.contextConfig.SummaryModel != "" {
+		if r.summarizeContext(ctx, &fit, turns, promptTokens, budget, policy.ConversationID, userID) {
+			return fit, nil
+		}
+	}
+	if policy.Strategy != ContextStrategyReject {
+		remaining := promptTokens
+		for drop := 1; drop < len(turns); drop++ {
+			remaining -= turns[drop-1].tokens
+			if remaining <= budget {
+				monitoring.RecordContextWindowAction(req.Model, "truncated")
+				fit.req = dropTurns(req, turns, drop, "")
+				return fit, nil
+			}
+		}
+	}
+	monitoring.RecordContextWindowAction(req.Model, "rejected")
+	return fit, &providers.ProviderError{
+		Kind:       providers.ErrorKindContextLength,
+		StatusCode: http.StatusBadRequest,
+		Message: fmt.Sprintf("model %s has a context window of %d tokens, but the conversation has about %d prompt tokens and needs %d for the response",
+			model, window, promptTokens, reserve),
+	}
+}
+
+// contextLimit returns the model whose context window leaves the prompt the least room,
+// with that window and the tokens reserved for the response. Any target of an alias
+// may serve the request, so the tightest one counts.
+func (r *Router) contextLimit(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (model string, window, reserve int, ok bool) {
+	candidates := []providers.ChatRequest{req}
+	if alias := r.resolveAlias(ctx, req.Model, userID); alias != nil {
+		candidates = candidates[:0]
+		for _, target := range alias.Targets {
+			candidates = append(candidates, target.apply(req))
+		}
+	}
+	for _, candidate := range candidates {
+		candidateWindow, known := r.ModelContextWindow(candidate.Model)
+		if !known {
+			continue
+		}
+		candidateReserve := defaultEstimatedOutputTokens
+		if candidate.MaxTokens > 0 {
+			candidateReserve = candidate.MaxTokens
+		}
+		if !ok || candidateWindow-candidateReserve < window-reserve {
+			model, window, reserve, ok = candidate.Model, candidateWindow, candidateReserve, true
+		}
+	}
+	return model, window, reserve, ok
+}
+
+// summarizeContext replaces the oldest turns of fit.req with a summary, reusing the
+// conversation's stored summary when it covers enough of them. It reports whether the
+// request now fits.
+func (r *Router) summarizeContext(ctx context.Context, fit *contextFit, turns []contextTurn, promptTokens, budget int, conversationID, userID *uuid.UUID) bool {
+	req := fit.req
+	summaryTokens := r.contextConfig.SummaryMaxTokens
+	if summaryTokens <= 0 {
+		summaryTokens = DefaultContextSummaryTokens
+	}
+	// Leave at least half of a small window to the conversation itself.
+	if summaryTokens > budget/2 {
+		summaryTokens = budget / 2
+	}
+	needed := 0
+	remaining := promptTokens
+	for drop := 1; drop < len(turns); drop++ {
+		remaining -= turns[drop-1].tokens
+		if remaining+summaryTokens <= budget {
+			needed = drop
+			break
+		}
+	}
+	if needed == 0 {
+		return false
+	}
+
+	persist := r.summaryStore != nil && conversationID != nil && userID != nil
+	var stored *ConversationSummary
+	storedTurns := 0
+	if persist {
+		if summary, err := r.summaryStore.GetConversationSummary(ctx, *conversationID, *userID); err == nil && summary != nil {
+			if n := turnsCovering(turns, summary.MessageCount); n > 0 {
+				stored, storedTurns = summary, n
+			}
+		}
+	}
+	if stored != nil && storedTurns >= needed {
+		monitoring.RecordContextWindowAction(req.Model, "summary_reused")
+		fit.req = dropTurns(req, turns, storedTurns, stored.Text)
+		return true
+	}
+
+	// Extend the stored summary with the turns it does not cover yet.
+	previous, from := "", 0
+	if stored != nil {
+		previous, from = stored.Text, storedTurns
+	}
+	resp, err := r.route(ctx, summaryRequest(r.contextConfig.SummaryModel, summaryTokens, previous, req.Messages, turns[from:needed]), userID)
+	if err != nil || len(resp.Choices) == 0 {
+		monitoring.RecordContextWindowAction(req.Model, "summary_failed")
+		return false
+	}
+	text := strings.TrimSpace(messageText(resp.Choices[0].Message))
+	if text == "" {
+		monitoring.RecordContextWindowAction(req.Model, "summary_failed")
+		return false
+	}
+	monitoring.RecordContextWindowAction(req.Model, "summarized")
+	fit.usage = resp.Usage
+	fit.cost = resp.Cost
+	fit.req = dropTurns(req, turns, needed, text)
+
+	if persist {
+		summary := ConversationSummary{Text: text, MessageCount: turnMessages(turns[:needed])}
+		conversation, user := *conversationID, *userID
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = r.summaryStore.SaveConversationSummary(ctx, conversation, user, summary)
+		}()
+	}
+	return true
+}
+
+// contextTurns groups the messages that are not system messages into turns, oldest
+// first, with their token counts.
+func contextTurns(t tokenizer.Tokenizer, messages []providers.Message) []contextTurn {
+	var turns []contextTurn
+	for i, msg := range messages {
+		if msg.Role == "system" {
+			continue
+		}
+		tokens := CountPromptTokens(t, providers.ChatRequest{Messages: []providers.Message{msg}}).Total - tokensPerReply
+		if msg.Role == "tool" && len(turns) > 0 {
+			last := &turns[len(turns)-1]
+			last.indices = append(last.indices, i)
+			last.tokens += tokens
+			continue
+		}
+		turns = append(turns, contextTurn{indices: []int{i}, tokens: tokens})
+	}
+	return turns
+}
+
+func turnMessages(turns []contextTurn) int {
+	count := 0
+	for _, turn := range turns {
+		count += len(turn.indices)
+	}
+	return count
+}
+
+// turnsCovering returns how many turns hold the first messageCount messages, or 0 if
+// messageCount does not end on a turn or would leave no turn.
+func turnsCovering(turns []contextTurn, messageCount int) int {
+	count := 0
+	for i := 0; i < len(turns)-1; i++ {
+		count += len(turns[i].indices)
+		if count == messageCount {
+			return i + 1
+		}
+		if count > messageCount {
+			break
+		}
+	}
+	return 0
+}
+
+// dropTurns removes the first drop turns from req, putting summary, if any, in their place.
+func dropTurns(req providers.ChatRequest, turns []contextTurn, drop int, summary string) providers.ChatRequest {
+	dropped := make(map[int]bool)
+	for _, turn := range turns[:drop] {
+		for _, i := range turn.indices {
+			dropped[i] = true
+		}
+	}
+	first := turns[drop].indices[0]
+	messages := make([]providers.Message, 0, len(req.Messages)-len(dropped)+1)
+	for i, msg := range req.Messages {
+		if i == first && summary != "" {
+			messages = append(messages, providers.Message{Role: "system", Content: "Summary of the earlier conversation:\n" + summary})
+		}
+		if !dropped[i] {
+			messages = append(messages, msg)
+		}
+	}
+	req.Messages = messages
+	return req
+}
+
+// summaryRequest asks model to summarize the messages of turns, extending previous.
+func summaryRequest(model string, maxTokens int, previous string, messages []providers.Message, turns []contextTurn) providers.ChatRequest {
+	var transcript strings.Builder
+	if previous != "" {
+		transcript.WriteString("Summary so far:\n" + previous + "\n\nConversation since then:\n")
+	}
+	for _, turn := range turns {
+		for _, i := range turn.indices {
+			fmt.Fprintf(&transcript, "%s: %s\n", messages[i].Role, messageText(messages[i]))
+		}
+	}
+	return providers.ChatRequest{
+		Model: model,
+		Messages: []providers.Message{
+			{Role: "system", Content: "Summarize the conversation below so that it can continue without it. Keep facts, names, numbers, decisions, instructions and open questions. Write plain prose, at most a few paragraphs."},
+			{Role: "user", Content: transcript.String()},
+		},
+		MaxTokens: maxTokens,
+	}
+}
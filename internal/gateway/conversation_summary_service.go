@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/google/uuid"
+)
+
+type ConversationSummaryAdapter struct {
+	repo *storage.ConversationRepository
+}
+
+func NewConversationSummaryAdapter(repo *storage.ConversationRepository) *ConversationSummaryAdapter {
+	return &ConversationSummaryAdapter{repo: repo}
+}
+
+func (a *ConversationSummaryAdapter) GetConversationSummary(ctx context.Context, conversationID, userID uuid.UUID) (*ConversationSummary, error) {
+	conv, err := a.repo.GetConversation(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if conv.Summary == nil || conv.SummaryMessageCount == 0 {
+		return nil, nil
+	}
+	return &ConversationSummary{Text: *conv.Summary, MessageCount: conv.SummaryMessageCount}, nil
+}
+
+func (a *ConversationSummaryAdapter) SaveConversationSummary(ctx context.Context, conversationID, userID uuid.UUID, summary ConversationSummary) error {
+	return a.repo.UpdateConversationSummary(ctx, conversationID, userID, summary.Text, summary.MessageCount)
+}
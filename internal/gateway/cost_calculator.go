@@ -1,134 +1,147 @@
 package gateway
 
 import (
+	"errors"
+	"fmt"
 	"math"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/tokenizer"
 )
 
-type CostCalculator struct {
-	pricing map[string]map[string]Pricing
-}
+// ErrUnknownPrice is returned when the pricing catalog has no price for a model.
+var ErrUnknownPrice = errors.New("unknown price")
+
+// UnknownPriceCost ranks models without a price last in cost-based routing.
+const UnknownPriceCost = 999999.0
 
-type Pricing struct {
-	InputCost  float64 // Cost per 1M input tokens
-	OutputCost float64 // Cost per 1M output tokens
+type CostCalculator struct {
+	mu         sync.RWMutex
+	catalog    *PricingCatalog
+	index      *pricingIndex
+	tokenizers *tokenizer.Registry
 }
 
 func NewCostCalculator() *CostCalculator {
-	pricing := make(map[string]map[string]Pricing)
-
-	pricing["openai"] = map[string]Pricing{
-		"gpt-4o":                  {InputCost: 5.0, OutputCost: 15.0},
-		"gpt-4o-mini":             {InputCost: 0.15, OutputCost: 0.60},
-		"gpt-4":                   {InputCost: 30.0, OutputCost: 60.0},
-		"gpt-4-turbo":             {InputCost: 10.0, OutputCost: 30.0},
-		"gpt-4-turbo-preview":     {InputCost: 10.0, OutputCost: 30.0},
-		"gpt-3.5-turbo":           {InputCost: 0.5, OutputCost: 1.5},
-		"gpt-3.5-turbo-0125":      {InputCost: 0.5, OutputCost: 1.5},
+	catalog := DefaultPricingCatalog()
+	return &CostCalculator{
+		catalog:    catalog,
+		index:      newPricingIndex(catalog),
+		tokenizers: tokenizer.NewRegistry(),
 	}
+}
 
-	pricing["anthropic"] = map[string]Pricing{
-		"claude-3-5-sonnet-20241022": {InputCost: 3.0, OutputCost: 15.0},
-		"claude-3-5-haiku-20241022":  {InputCost: 0.80, OutputCost: 4.0},
-		"claude-3-opus-20240229":     {InputCost: 15.0, OutputCost: 75.0},
-		"claude-3-sonnet-20240229":   {InputCost: 3.0, OutputCost: 15.0},
-		"claude-3-haiku-20240307":   {InputCost: 0.25, OutputCost: 1.25},
-	}
+func (c *CostCalculator) SetTokenizers(registry *tokenizer.Registry) {
+	c.tokenizers = registry
+}
 
-	pricing["google"] = map[string]Pricing{
-		"gemini-pro":             {InputCost: 0.0, OutputCost: 0.0},
-		"gemini-1.5-pro":         {InputCost: 1.25, OutputCost: 5.0},
-		"gemini-1.5-pro-latest":  {InputCost: 1.25, OutputCost: 5.0},
-		"gemini-1.5-flash":       {InputCost: 0.075, OutputCost: 0.30},
-		"gemini-1.5-flash-8b":    {InputCost: 0.0375, OutputCost: 0.15},
-		"gemini-2.5-pro":         {InputCost: 1.25, OutputCost: 5.0},
-		"gemini-2.5-flash":       {InputCost: 0.15, OutputCost: 0.60},
-		"gemini-2.0-flash-exp":   {InputCost: 0.10, OutputCost: 0.40},
+func (c *CostCalculator) SetCatalog(catalog *PricingCatalog) error {
+	if err := catalog.Validate(); err != nil {
+		return err
 	}
+	index := newPricingIndex(catalog)
+	c.mu.Lock()
+	c.catalog = catalog
+	c.index = index
+	c.mu.Unlock()
+	return nil
+}
 
-	pricing["local"] = map[string]Pricing{
-		"llama2":    {InputCost: 0.0, OutputCost: 0.0},
-		"mistral":   {InputCost: 0.0, OutputCost: 0.0},
-		"codellama": {InputCost: 0.0, OutputCost: 0.0},
-	}
+// Catalog returns the catalog in use. Callers must not modify it.
+func (c *CostCalculator) Catalog() *PricingCatalog {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.catalog
+}
 
-	return &CostCalculator{
-		pricing: pricing,
-	}
+func (c *CostCalculator) Lookup(providerName, model string, at time.Time) (PriceEntry, bool) {
+	c.mu.RLock()
+	index := c.index
+	c.mu.RUnlock()
+	return index.lookup(providerName, model, at)
 }
 
-func (c *CostCalculator) EstimateCost(providerName, model string, messages []providers.Message) float64 {
-	providerPricing, exists := c.pricing[providerName]
-	if !exists {
-		return 999999.0
+func (c *CostCalculator) GetPricing(providerName, model string) (Pricing, bool) {
+	entry, ok := c.Lookup(providerName, model, time.Now())
+	if !ok {
+		return Pricing{}, false
 	}
+	return entry.Pricing, true
+}
 
-	modelPricing, exists := providerPricing[model]
-	if !exists {
-		for _, p := range providerPricing {
-			modelPricing = p
-			break
-		}
-		if modelPricing.InputCost == 0 && modelPricing.OutputCost == 0 {
-			return 0.0 // Free if no pricing found
+// UpdatePricing changes a price in memory until the next catalog reload.
+func (c *CostCalculator) UpdatePricing(providerName, model string, pricing Pricing) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := PriceEntry{Provider: providerName, Model: model, Pricing: pricing}
+	prices := make([]PriceEntry, 0, len(c.catalog.Prices)+1)
+	for _, existing := range c.catalog.Prices {
+		if strings.EqualFold(existing.key(), entry.key()) {
+			continue
 		}
+		prices = append(prices, existing)
 	}
+	c.catalog = &PricingCatalog{Version: c.catalog.Version, Prices: append(prices, entry)}
+	c.index = newPricingIndex(c.catalog)
+}
 
-	totalChars := 0
-	for _, msg := range messages {
-		switch content := msg.Content.(type) {
-		case string:
-			totalChars += len(content)
-		case []providers.ContentPart:
-			for _, part := range content {
-				if part.Type == "text" {
-					totalChars += len(part.Text)
-				}
-			}
-		}
+// Estimate assumes the completion uses max_tokens, or a default when unset.
+func (c *CostCalculator) Estimate(providerName string, req providers.ChatRequest) (float64, error) {
+	pricing, ok := c.GetPricing(providerName, req.Model)
+	if !ok {
+		return 0, fmt.Errorf("%w for %s/%s", ErrUnknownPrice, providerName, req.Model)
 	}
-	estimatedInputTokens := float64(totalChars) / 4.0
-	estimatedOutputTokens := estimatedInputTokens * 0.5
 
-	inputCost := (estimatedInputTokens / 1_000_000.0) * modelPricing.InputCost
-	outputCost := (estimatedOutputTokens / 1_000_000.0) * modelPricing.OutputCost
-
-	return inputCost + outputCost
+	count := CountPromptTokens(c.tokenizers.ForModel(req.Model), req)
+	usage := providers.Usage{
+		PromptTokens:     count.Total,
+		CompletionTokens: defaultEstimatedOutputTokens,
+	}
+	if req.MaxTokens > 0 {
+		usage.CompletionTokens = req.MaxTokens
+	}
+	imageCost := 0.0
+	if pricing.ImageCost > 0 {
+		usage.PromptTokens -= count.Images
+		imageCost = float64(count.ImageCount) * pricing.ImageCost
+	}
+	return pricing.Cost(usage, false) + imageCost, nil
 }
 
-func (c *CostCalculator) GetPricing(providerName, model string) (Pricing, bool) {
-	providerPricing, exists := c.pricing[providerName]
-	if !exists {
-		return Pricing{}, false
+// EstimateCost returns UnknownPriceCost for models without a price.
+func (c *CostCalculator) EstimateCost(providerName string, req providers.ChatRequest) float64 {
+	cost, err := c.Estimate(providerName, req)
+	if err != nil {
+		return UnknownPriceCost
 	}
+	return cost
+}
 
-	pricing, exists := providerPricing[model]
-	if !exists {
-		for _, p := range providerPricing {
-			return p, true
-		}
-		return Pricing{}, false
+// ActualCost prices the usage a provider reported. It is not rounded, so the spend of
+// many small requests adds up; see RoundCost for display.
+func (c *CostCalculator) ActualCost(providerName, model string, usage providers.Usage) (float64, error) {
+	pricing, ok := c.GetPricing(providerName, model)
+	if !ok {
+		return 0, fmt.Errorf("%w for %s/%s", ErrUnknownPrice, providerName, model)
 	}
-
-	return pricing, true
+	return pricing.Cost(usage, false), nil
 }
 
-func (c *CostCalculator) UpdatePricing(providerName, model string, pricing Pricing) {
-	if c.pricing[providerName] == nil {
-		c.pricing[providerName] = make(map[string]Pricing)
-	}
-	c.pricing[providerName][model] = pricing
+// RoundCost rounds a USD cost to 4 decimal places for display.
+func RoundCost(cost float64) float64 {
+	return math.Round(cost*10000) / 10000
 }
 
+// CalculateActualCost records models without a price as free.
 func (c *CostCalculator) CalculateActualCost(providerName, model string, usage providers.Usage) float64 {
-	pricing, exists := c.GetPricing(providerName, model)
-	if !exists {
+	cost, err := c.ActualCost(providerName, model, usage)
+	if err != nil {
+		monitoring.RecordUnknownPrice(providerName, model)
 		return 0.0
 	}
-
-	inputCost := (float64(usage.PromptTokens) / 1_000_000.0) * pricing.InputCost
-	outputCost := (float64(usage.CompletionTokens) / 1_000_000.0) * pricing.OutputCost
-
-	return math.Round((inputCost+outputCost)*10000) / 10000 // Round to 4 decimal places
+	return cost
 }
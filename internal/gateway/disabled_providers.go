@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"sort"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
+)
+
+// ProviderStatus is a provider as admins see it: registered with a server key, usable
+// with BYOK keys, or both, and whether it is enabled.
+type ProviderStatus struct {
+	Name    string `json:"name"`
+	Server  bool   `json:"server"`
+	BYOK    bool   `json:"byok"`
+	Enabled bool   `json:"enabled"`
+}
+
+// SetProviderEnabled turns a registered or BYOK-capable provider on or off at runtime.
+// Disabled providers are left out of routing, BYOK and the provider listings.
+func (r *Router) SetProviderEnabled(name string, enabled bool) error {
+	if !r.knownProvider(name) {
+		return errors.ErrProviderNotFound
+	}
+	r.disabledMu.Lock()
+	defer r.disabledMu.Unlock()
+	if enabled {
+		delete(r.disabledProviders, name)
+	} else {
+		r.disabledProviders[name] = true
+	}
+	return nil
+}
+
+// SetDisabledProviders replaces the disabled providers, as loaded from the settings.
+func (r *Router) SetDisabledProviders(names []string) {
+	r.disabledMu.Lock()
+	defer r.disabledMu.Unlock()
+	r.disabledProviders = make(map[string]bool, len(names))
+	for _, name := range names {
+		r.disabledProviders[name] = true
+	}
+}
+
+func (r *Router) DisabledProviders() []string {
+	r.disabledMu.RLock()
+	defer r.disabledMu.RUnlock()
+	names := make([]string, 0, len(r.disabledProviders))
+	for name := range r.disabledProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Router) IsProviderEnabled(name string) bool {
+	r.disabledMu.RLock()
+	defer r.disabledMu.RUnlock()
+	return !r.disabledProviders[name]
+}
+
+// ProviderStatuses lists registered providers in registration order, then the BYOK
+// providers without a server key.
+func (r *Router) ProviderStatuses() []ProviderStatus {
+	byok := make(map[string]bool)
+	for _, name := range r.byokProviderNames() {
+		byok[name] = true
+	}
+	out := make([]ProviderStatus, 0, len(r.providerNames)+len(byok))
+	for _, name := range r.providerNames {
+		out = append(out, ProviderStatus{Name: name, Server: true, BYOK: byok[name], Enabled: r.IsProviderEnabled(name)})
+		delete(byok, name)
+	}
+	for _, name := range r.byokProviderNames() {
+		if byok[name] {
+			out = append(out, ProviderStatus{Name: name, BYOK: true, Enabled: r.IsProviderEnabled(name)})
+		}
+	}
+	return out
+}
+
+func (r *Router) knownProvider(name string) bool {
+	if _, ok := r.providers[name]; ok {
+		return true
+	}
+	for _, byok := range r.byokProviderNames() {
+		if byok == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+)
+
+const (
+	StickyByUser   = "user"
+	StickyByAPIKey = "api_key"
+)
+
+// Experiment splits requests for Model across weighted variants.
+type Experiment struct {
+	ID       uuid.UUID
+	Name     string
+	Model    string
+	StickyBy string
+	Variants []ExperimentVariant
+}
+
+// ExperimentVariant replaces the requested model with Model (a concrete model or an alias)
+// for Weight/sum(weights) of the experiment's traffic.
+type ExperimentVariant struct {
+	Name   string
+	Model  string
+	Weight int
+}
+
+// ExperimentAssignment records which variant a request was assigned to.
+type ExperimentAssignment struct {
+	ExperimentID uuid.UUID
+	Experiment   string
+	Variant      string
+	Model        string
+}
+
+// ExperimentSubject identifies the caller so assignment can stay sticky.
+type ExperimentSubject struct {
+	UserID   string
+	APIKeyID string
+}
+
+type ExperimentServiceInterface interface {
+	ActiveExperiment(ctx context.Context, model string) (*Experiment, error)
+}
+
+// key returns the identity assignment is sticky on, falling back to the other one.
+func (s ExperimentSubject) key(stickyBy string) string {
+	if stickyBy == StickyByAPIKey && s.APIKeyID != "" {
+		return "key:" + s.APIKeyID
+	}
+	if s.UserID != "" {
+		return "user:" + s.UserID
+	}
+	if s.APIKeyID != "" {
+		return "key:" + s.APIKeyID
+	}
+	return ""
+}
+
+// AssignVariant picks a variant by weight. The same non-empty subject always gets the
+// same variant as long as the variants and weights do not change.
+func (e *Experiment) AssignVariant(subject string) (ExperimentVariant, bool) {
+	total := 0
+	for _, v := range e.Variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		return ExperimentVariant{}, false
+	}
+
+	var point int
+	if subject == "" {
+		point = rand.Intn(total)
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(e.ID.String()))
+		h.Write([]byte{0})
+		h.Write([]byte(subject))
+		point = int(h.Sum64() % uint64(total))
+	}
+	for _, v := range e.Variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < v.Weight {
+			return v, true
+		}
+		point -= v.Weight
+	}
+	return ExperimentVariant{}, false
+}
+
+// AssignExperiment applies the running experiment for req.Model, if any, and returns the
+// request with the variant's model. Callers record the assignment with the request.
+func (r *Router) AssignExperiment(ctx context.Context, req providers.ChatRequest, subject ExperimentSubject) (providers.ChatRequest, *ExperimentAssignment) {
+	if r.experimentService == nil || req.Model == "" {
+		return req, nil
+	}
+	exp, err := r.experimentService.ActiveExperiment(ctx, req.Model)
+	if err != nil || exp == nil {
+		return req, nil
+	}
+	variant, ok := exp.AssignVariant(subject.key(exp.StickyBy))
+	if !ok {
+		return req, nil
+	}
+	monitoring.RecordExperimentAssignment(exp.Name, variant.Name)
+	req.Model = variant.Model
+	return req, &ExperimentAssignment{
+		ExperimentID: exp.ID,
+		Experiment:   exp.Name,
+		Variant:      variant.Name,
+		Model:        variant.Model,
+	}
+}
+
+// InvalidateExperiments drops cached experiment lookups after experiments are changed.
+func (r *Router) InvalidateExperiments() {
+	if cache, ok := r.experimentService.(interface{ Invalidate() }); ok {
+		cache.Invalidate()
+	}
+}
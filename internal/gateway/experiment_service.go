@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+)
+
+// experimentCacheTTL bounds how long the running experiment for a model (or its
+// absence) is reused, since every chat request looks it up before routing.
+const experimentCacheTTL = 30 * time.Second
+
+type cachedExperiment struct {
+	experiment *Experiment
+	expiresAt  time.Time
+}
+
+type ExperimentServiceAdapter struct {
+	repo *storage.ExperimentRepository
+
+	mu    sync.Mutex
+	cache map[string]cachedExperiment
+}
+
+func NewExperimentServiceAdapter(repo *storage.ExperimentRepository) *ExperimentServiceAdapter {
+	return &ExperimentServiceAdapter{repo: repo, cache: make(map[string]cachedExperiment)}
+}
+
+func (a *ExperimentServiceAdapter) ActiveExperiment(ctx context.Context, model string) (*Experiment, error) {
+	now := time.Now()
+	a.mu.Lock()
+	cached, ok := a.cache[model]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.experiment, nil
+	}
+
+	exp, err := a.repo.FindActiveForModel(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	var active *Experiment
+	if exp != nil {
+		variants := make([]ExperimentVariant, 0, len(exp.Variants))
+		for _, v := range exp.Variants {
+			variants = append(variants, ExperimentVariant{Name: v.Name, Model: v.Model, Weight: v.Weight})
+		}
+		active = &Experiment{
+			ID:       exp.ID,
+			Name:     exp.Name,
+			Model:    exp.Model,
+			StickyBy: exp.StickyBy,
+			Variants: variants,
+		}
+	}
+	a.mu.Lock()
+	for k, entry := range a.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(a.cache, k)
+		}
+	}
+	a.cache[model] = cachedExperiment{experiment: active, expiresAt: now.Add(experimentCacheTTL)}
+	a.mu.Unlock()
+	return active, nil
+}
+
+// Invalidate drops every cached lookup, so experiment changes apply to the next request.
+func (a *ExperimentServiceAdapter) Invalidate() {
+	a.mu.Lock()
+	a.cache = make(map[string]cachedExperiment)
+	a.mu.Unlock()
+}
@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+)
+
+// Where a provider's credentials come from.
+const (
+	ProviderSourceServer = "server"
+	ProviderSourceBYOK   = "byok"
+)
+
+// RoutingExplanation is what Route would do with a request, worked out without calling
+// any provider.
+type RoutingExplanation struct {
+	Model          string                `json:"model"`
+	Strategy       StrategyType          `json:"strategy,omitempty"`
+	StrategySource string                `json:"strategy_source,omitempty"`
+	Alias          string                `json:"alias,omitempty"`
+	Providers      []ProviderExplanation `json:"providers"`
+	Rules          []RuleTrace           `json:"rules,omitempty"`
+	Selected       string                `json:"selected,omitempty"`
+	FailoverOrder  []FailoverCandidate   `json:"failover_order"`
+	// Error is the error Route would return before trying any provider.
+	Error string `json:"error,omitempty"`
+}
+
+// ProviderExplanation says whether a provider can serve the caller, and why not.
+type ProviderExplanation struct {
+	Name          string       `json:"name"`
+	Source        string       `json:"source,omitempty"`
+	Available     bool         `json:"available"`
+	Health        BreakerState `json:"health,omitempty"`
+	SupportsModel bool         `json:"supports_model"`
+	Excluded      string       `json:"excluded,omitempty"`
+}
+
+// FailoverCandidate is one attempt in the order Route would make them. Skipped
+// candidates would be passed over without a request.
+type FailoverCandidate struct {
+	Provider         string   `json:"provider"`
+	Model            string   `json:"model"`
+	Source           string   `json:"source"`
+	EstimatedCostUSD *float64 `json:"estimated_cost_usd"` // nil when the model has no price
+	AverageLatencyMs *int64   `json:"average_latency_ms"` // nil without latency history
+	LatencySamples   int      `json:"latency_samples"`
+	Skipped          string   `json:"skipped,omitempty"`
+}
+
+// Explain works out how Route would handle req for userID: the strategy and where it
+// came from, which providers are available, how custom rules evaluated, and the
+// failover order with cost and latency estimates. No provider is called and no
+// routing state, such as the load balancer's rotation, is advanced.
+func (r *Router) Explain(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) *RoutingExplanation {
+	ctx, trace := WithRoutingTrace(ctx)
+	explanation := &RoutingExplanation{Model: req.Model, FailoverOrder: []FailoverCandidate{}}
+	available := r.getAvailableProviders(ctx, userID)
+	explanation.Providers = r.explainProviders(req, userID, available)
+	if len(r.providers) == 0 || len(available) == 0 {
+		explanation.Error = "no providers available"
+		return explanation
+	}
+
+	var targets []routeTarget
+	if alias := r.resolveAlias(ctx, req.Model, userID); alias != nil {
+		explanation.Alias = alias.Name
+		targets = r.aliasTargets(alias, req, available)
+		if len(targets) == 0 {
+			explanation.Error = "no provider available for model alias " + alias.Name
+			return explanation
+		}
+	} else {
+		if needs := providers.RequiredCapabilities(req); needs != (providers.Capabilities{}) {
+			available = r.capableProviders(available, req.Model, needs)
+			if len(available) == 0 {
+				explanation.Error = capabilityError(req.Model, needs).Error()
+				return explanation
+			}
+		}
+		strategy := r.GetStrategyInstanceForUser(ctx, userID)
+		var selected providers.Provider
+		var err error
+		if balanced, ok := strategy.(*LoadBalancedStrategy); ok {
+			selected, err = balanced.peek(req, available)
+		} else {
+			selected, err = strategy.SelectProvider(ctx, req, available)
+		}
+		explanation.Strategy, explanation.StrategySource = trace.Strategy, trace.StrategySource
+		explanation.Rules = trace.Rules
+		if err != nil {
+			explanation.Error = "failed to select provider: " + err.Error()
+			return explanation
+		}
+		explanation.Selected = selected.Name()
+		targets = routeTargets(failoverOrder(selected, available), req)
+	}
+
+	explanation.FailoverOrder = r.explainTargets(req, targets)
+	if explanation.Selected == "" && len(targets) > 0 {
+		explanation.Selected = targets[0].provider.Name()
+	}
+	return explanation
+}
+
+// explainProviders lists registered and BYOK-capable providers, mirroring
+// getAvailableProviders: a user's own key replaces the server's, and server providers
+// with an open circuit breaker or disabled by an admin are left out. Providers whose
+// model lacks a capability the request needs are marked excluded.
+func (r *Router) explainProviders(req providers.ChatRequest, userID *uuid.UUID, available []providers.Provider) []ProviderExplanation {
+	needs := providers.RequiredCapabilities(req)
+	listed := make(map[string]bool)
+	byokNames := r.byokProviderNames()
+	out := make([]ProviderExplanation, 0, len(r.providerNames)+len(byokNames))
+	add := func(p providers.Provider) {
+		listed[p.Name()] = true
+		entry := ProviderExplanation{
+			Name:          p.Name(),
+			Source:        r.providerSource(p),
+			Available:     r.providerInList(available, p.Name()),
+			SupportsModel: supportsModel(p, req.Model),
+		}
+		if breaker := r.breakerFor(p); breaker != nil {
+			entry.Health = breaker.State()
+			if !entry.Available {
+				entry.Excluded = "circuit breaker open"
+			}
+		}
+		if !r.IsProviderEnabled(p.Name()) {
+			entry.Excluded = "disabled by an admin"
+		}
+		if missing := r.capabilities.Lookup(p, req.Model).Missing(needs); entry.Excluded == "" && len(missing) > 0 {
+			entry.Excluded = "does not support " + strings.Join(missing, " and ")
+		}
+		out = append(out, entry)
+	}
+	for _, p := range available {
+		add(p)
+	}
+	for _, p := range r.getAllProviders() {
+		if !listed[p.Name()] {
+			add(p)
+		}
+	}
+	for _, name := range byokNames {
+		if listed[name] {
+			continue
+		}
+		reason := "no server API key configured"
+		if !r.IsProviderEnabled(name) {
+			reason = "disabled by an admin"
+		} else if userID != nil && r.providerKeyService != nil {
+			reason = "no server API key and no provider key (BYOK) for this user"
+		}
+		out = append(out, ProviderExplanation{Name: name, Excluded: reason})
+	}
+	return out
+}
+
+// explainTargets mirrors chatTargets: targets without a capability the request needs,
+// such as tool calling, and targets that fail the context window check are skipped.
+func (r *Router) explainTargets(req providers.ChatRequest, targets []routeTarget) []FailoverCandidate {
+	needs := providers.RequiredCapabilities(req)
+	promptTokens := make(map[string]int)
+	out := make([]FailoverCandidate, 0, len(targets))
+	for _, target := range targets {
+		name := target.provider.Name()
+		candidate := FailoverCandidate{
+			Provider: name,
+			Model:    target.req.Model,
+			Source:   r.providerSource(target.provider),
+		}
+		if cost, err := r.costCalculator.Estimate(name, target.req); err == nil {
+			candidate.EstimatedCostUSD = &cost
+		}
+		avg, _, _, samples := r.latencyTracker.GetLatencyStats(name)
+		candidate.LatencySamples = samples
+		if samples > 0 {
+			ms := avg.Milliseconds()
+			candidate.AverageLatencyMs = &ms
+		}
+		if missing := r.capabilities.Lookup(target.provider, target.req.Model).Missing(needs); len(missing) > 0 {
+			candidate.Skipped = "does not support " + strings.Join(missing, " and ")
+		} else if err := r.checkTokenLimits(name, target.req, promptTokens); err != nil {
+			candidate.Skipped = err.Error()
+		}
+		out = append(out, candidate)
+	}
+	return out
+}
+
+func (r *Router) providerSource(p providers.Provider) string {
+	if r.breakerFor(p) != nil {
+		return ProviderSourceServer
+	}
+	return ProviderSourceBYOK
+}
+
+func supportsModel(p providers.Provider, model string) bool {
+	for _, m := range p.GetModels() {
+		if strings.EqualFold(m, model) {
+			return true
+		}
+	}
+	return false
+}
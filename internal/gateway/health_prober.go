@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultProbeTimeout = 10 * time.Second
+
+// HealthProber runs provider health checks in the background and feeds the results
+// into the router's circuit breakers, so requests never wait on a health check.
+type HealthProber struct {
+	router   *Router
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func NewHealthProber(router *Router, interval time.Duration) *HealthProber {
+	return &HealthProber{
+		router:   router,
+		interval: interval,
+		timeout:  defaultProbeTimeout,
+	}
+}
+
+// Start probes immediately and then every interval until ctx is cancelled.
+func (p *HealthProber) Start(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		p.ProbeOnce(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				p.ProbeOnce(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// ProbeOnce checks every registered provider concurrently and waits for all results.
+func (p *HealthProber) ProbeOnce(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, provider := range p.router.getAllProviders() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+			err := provider.HealthCheck(probeCtx)
+			if ctx.Err() != nil {
+				return
+			}
+			p.router.breakers.Get(provider.Name()).RecordProbe(err)
+		}()
+	}
+	wg.Wait()
+}
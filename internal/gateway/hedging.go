@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+// DefaultHedgePercentile is used when a request asks for hedging without a percentile.
+const DefaultHedgePercentile = 95
+
+// minHedgeSamples is the latency history a provider needs before its calls are hedged;
+// with fewer samples the percentile says little.
+const minHedgeSamples = 10
+
+// HedgePolicy asks Route to hedge a request: when the first provider has not answered
+// within Percentile (1-99) of its recent latencies, the request is also sent to the next
+// provider in the failover order. The first success wins and the other call is cancelled.
+type HedgePolicy struct {
+	Percentile float64
+}
+
+type hedgePolicyKey struct{}
+
+func WithHedgePolicy(ctx context.Context, policy HedgePolicy) context.Context {
+	return context.WithValue(ctx, hedgePolicyKey{}, policy)
+}
+
+func hedgePolicyFromContext(ctx context.Context) (HedgePolicy, bool) {
+	policy, ok := ctx.Value(hedgePolicyKey{}).(HedgePolicy)
+	return policy, ok && policy.Percentile > 0 && policy.Percentile < 100
+}
+
+// hedgeDelay is how long to wait for provider before hedging. It reports false when the
+// request is not hedged or the provider has too little latency history.
+func (r *Router) hedgeDelay(ctx context.Context, provider string) (time.Duration, bool) {
+	policy, ok := hedgePolicyFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	delay, samples := r.latencyTracker.Percentile(provider, policy.Percentile)
+	if samples < minHedgeSamples {
+		return 0, false
+	}
+	return delay, true
+}
+
+type hedgeCall struct {
+	target    routeTarget
+	resp      *providers.ChatResponse
+	latency   time.Duration
+	err       error
+	cancel    context.CancelFunc
+	done      bool
+	cancelled bool // cancelled while in flight
+}
+
+// chatHedged calls primary and, if it has not answered after delay, also the target
+// returned by next. The first success wins; the other call is cancelled and reported in
+// the winner's HedgeAttempts. If every call fails the error is returned, and failover
+// carries on after the targets next has handed out.
+func (r *Router) chatHedged(ctx context.Context, primary routeTarget, breaker *CircuitBreaker, delay time.Duration, next func() (routeTarget, *CircuitBreaker, bool)) (*hedgeCall, error) {
+	results := make(chan *hedgeCall, 2)
+	var calls []*hedgeCall
+	start := func(target routeTarget, breaker *CircuitBreaker) {
+		callCtx, cancel := context.WithCancel(ctx)
+		call := &hedgeCall{target: target, cancel: cancel}
+		calls = append(calls, call)
+		go func() {
+			call.resp, call.latency, call.err = r.chatWithRetry(callCtx, target.provider, breaker, target.req)
+			results <- call
+		}()
+	}
+	cancelOthers := func(keep *hedgeCall) {
+		for _, call := range calls {
+			if call != keep && !call.done && !call.cancelled {
+				call.cancelled = true
+				call.cancel()
+			}
+		}
+	}
+	defer func() {
+		for _, call := range calls {
+			call.cancel()
+		}
+	}()
+
+	start(primary, breaker)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var winner *hedgeCall
+	var lastErr, clientErr error
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer.C:
+			if target, breaker, ok := next(); ok {
+				start(target, breaker)
+				pending++
+			}
+		case call := <-results:
+			pending--
+			call.done = true
+			switch {
+			case winner != nil || clientErr != nil:
+				// Finished after the outcome was decided; reported with the winner.
+			case call.err == nil:
+				winner = call
+				timer.Stop()
+				cancelOthers(call)
+			case isClientError(call.err):
+				clientErr = call.err
+				timer.Stop()
+				cancelOthers(nil)
+			default:
+				lastErr = call.err
+			}
+		}
+	}
+
+	if winner == nil {
+		if clientErr != nil {
+			return nil, clientErr
+		}
+		return nil, lastErr
+	}
+	if len(calls) > 1 {
+		outcome := "primary"
+		if winner != calls[0] {
+			outcome = "hedge"
+		}
+		monitoring.RecordHedgedRequest(winner.target.provider.Name(), outcome)
+		for _, call := range calls {
+			if call != winner {
+				attempt := r.hedgeAttempt(call)
+				monitoring.RecordHedgeCost(attempt.Provider, attempt.Model, attempt.Cost)
+				winner.resp.HedgeAttempts = append(winner.resp.HedgeAttempts, attempt)
+			}
+		}
+	}
+	return winner, nil
+}
+
+// hedgeAttempt prices a losing call. A cancelled call is charged for its prompt, which
+// the provider has usually processed by then; any partial completion is not visible.
+func (r *Router) hedgeAttempt(call *hedgeCall) providers.HedgeAttempt {
+	attempt := providers.HedgeAttempt{
+		Provider:  call.target.provider.Name(),
+		Model:     call.target.req.Model,
+		LatencyMs: call.latency.Milliseconds(),
+	}
+	switch {
+	case call.err == nil:
+		attempt.Usage = call.resp.Usage
+		if call.resp.Model != "" {
+			attempt.Model = call.resp.Model
+		}
+	case call.cancelled:
+		attempt.Cancelled = true
+		prompt := CountPromptTokens(r.tokenizers.ForModel(call.target.req.Model), call.target.req).Total
+		attempt.Usage = providers.Usage{PromptTokens: prompt, TotalTokens: prompt, Estimated: true}
+	default:
+		attempt.Error = call.err.Error()
+	}
+	if attempt.Usage.TotalTokens > 0 {
+		attempt.Cost = r.costCalculator.CalculateActualCost(attempt.Provider, attempt.Model, attempt.Usage)
+	}
+	return attempt
+}
@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+// Hook events, as used in metrics and by the webhook hook.
+const (
+	HookEventBeforeRequest = "before_request"
+	HookEventAfterResponse = "after_response"
+	HookEventChunk         = "chunk"
+	HookEventError         = "error"
+)
+
+// Hook runs around every chat request routed by Route, RouteStream and Compare, e.g. for
+// guardrails, prompt rewriting or auditing. Hooks run in the order they were added.
+// Embed NopHook to implement only some of the methods.
+type Hook interface {
+	Name() string
+	// BeforeRequest may modify req. An error (see Block) rejects the request before
+	// any provider is called.
+	BeforeRequest(ctx context.Context, req *providers.ChatRequest) error
+	// OnChunk sees each streamed chunk before the caller does and may modify it. An
+	// error ends the stream.
+	OnChunk(ctx context.Context, req *providers.ChatRequest, chunk *providers.StreamChunk) error
+	// AfterResponse may modify resp or add to resp.Annotations. An error rejects the
+	// response. For streams, resp is assembled from the chunks and runs before the
+	// final chunk, which carries the annotations, is sent.
+	AfterResponse(ctx context.Context, req *providers.ChatRequest, resp *providers.ChatResponse) error
+	// OnError is told about requests that failed or were rejected.
+	OnError(ctx context.Context, req *providers.ChatRequest, err error)
+}
+
+// NopHook implements Hook with methods that do nothing.
+type NopHook struct{}
+
+func (NopHook) BeforeRequest(ctx context.Context, req *providers.ChatRequest) error {
+	return nil
+}
+
+func (NopHook) OnChunk(ctx context.Context, req *providers.ChatRequest, chunk *providers.StreamChunk) error {
+	return nil
+}
+
+func (NopHook) AfterResponse(ctx context.Context, req *providers.ChatRequest, resp *providers.ChatResponse) error {
+	return nil
+}
+
+func (NopHook) OnError(ctx context.Context, req *providers.ChatRequest, err error) {}
+
+// HookBlockedError is returned for requests and responses a hook rejected.
+type HookBlockedError struct {
+	Hook   string
+	Event  string
+	Reason string
+}
+
+func (e *HookBlockedError) Error() string {
+	if e.Event == HookEventBeforeRequest {
+		return fmt.Sprintf("request blocked by %s: %s", e.Hook, e.Reason)
+	}
+	return fmt.Sprintf("response blocked by %s: %s", e.Hook, e.Reason)
+}
+
+// Block is the error a hook returns to reject a request or response with reason.
+func Block(reason string) error {
+	return &HookBlockedError{Reason: reason}
+}
+
+func AsHookBlockedError(err error) (*HookBlockedError, bool) {
+	var blocked *HookBlockedError
+	if errors.As(err, &blocked) {
+		return blocked, true
+	}
+	return nil, false
+}
+
+// AddHook appends hook to the hooks run around every chat request. Hooks must be added
+// before the router serves requests.
+func (r *Router) AddHook(hook Hook) {
+	r.hooks = append(r.hooks, hook)
+}
+
+func (r *Router) Hooks() []Hook {
+	return r.hooks
+}
+
+// blocked turns the error of hook into a HookBlockedError and records the outcome.
+func blocked(hook Hook, event string, err error) error {
+	monitoring.RecordHookOutcome(hook.Name(), event, "block")
+	reason := err.Error()
+	if b, ok := AsHookBlockedError(err); ok {
+		reason = b.Reason
+	}
+	return &HookBlockedError{Hook: hook.Name(), Event: event, Reason: reason}
+}
+
+func (r *Router) beforeRequest(ctx context.Context, req *providers.ChatRequest) error {
+	for _, hook := range r.hooks {
+		if err := hook.BeforeRequest(ctx, req); err != nil {
+			return blocked(hook, HookEventBeforeRequest, err)
+		}
+		monitoring.RecordHookOutcome(hook.Name(), HookEventBeforeRequest, "allow")
+	}
+	return nil
+}
+
+func (r *Router) afterResponse(ctx context.Context, req *providers.ChatRequest, resp *providers.ChatResponse) error {
+	for _, hook := range r.hooks {
+		if err := hook.AfterResponse(ctx, req, resp); err != nil {
+			return blocked(hook, HookEventAfterResponse, err)
+		}
+		monitoring.RecordHookOutcome(hook.Name(), HookEventAfterResponse, "allow")
+	}
+	return nil
+}
+
+func (r *Router) onChunk(ctx context.Context, req *providers.ChatRequest, chunk *providers.StreamChunk) error {
+	for _, hook := range r.hooks {
+		if err := hook.OnChunk(ctx, req, chunk); err != nil {
+			return blocked(hook, HookEventChunk, err)
+		}
+	}
+	return nil
+}
+
+func (r *Router) onError(ctx context.Context, req *providers.ChatRequest, err error) {
+	for _, hook := range r.hooks {
+		hook.OnError(ctx, req, err)
+	}
+}
+
+// withHooks runs call between the hooks' BeforeRequest and AfterResponse.
+func (r *Router) withHooks(ctx context.Context, req providers.ChatRequest, call func(providers.ChatRequest) (*providers.ChatResponse, error)) (*providers.ChatResponse, error) {
+	if len(r.hooks) == 0 {
+		return call(req)
+	}
+	if err := r.beforeRequest(ctx, &req); err != nil {
+		r.onError(ctx, &req, err)
+		return nil, err
+	}
+	resp, err := call(req)
+	if err == nil {
+		err = r.afterResponse(ctx, &req, resp)
+	}
+	if err != nil {
+		r.onError(ctx, &req, err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// streamWithHooks is withHooks for streams: chunks pass through OnChunk, and the final
+// chunk is held back until AfterResponse has accepted the assembled response.
+func (r *Router) streamWithHooks(ctx context.Context, req providers.ChatRequest, call func(providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error)) (<-chan providers.StreamChunk, <-chan error) {
+	if len(r.hooks) == 0 {
+		return call(req)
+	}
+	chunkChan := make(chan providers.StreamChunk, 10)
+	errChan := make(chan error, 1)
+	if err := r.beforeRequest(ctx, &req); err != nil {
+		r.onError(ctx, &req, err)
+		errChan <- err
+		close(errChan)
+		close(chunkChan)
+		return chunkChan, errChan
+	}
+	in, inErrs := call(req)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		assembled := newStreamAssembler(req.Model)
+		var failed error
+		finished := false
+		// Keep draining after a failure so the router's stream can finish.
+		for chunk := range in {
+			if failed != nil || finished {
+				continue
+			}
+			if err := r.onChunk(ctx, &req, &chunk); err != nil {
+				failed = err
+				continue
+			}
+			assembled.add(chunk)
+			if chunk.Done && chunk.Error == "" {
+				finished = true
+				resp := assembled.response()
+				if err := r.afterResponse(ctx, &req, resp); err != nil {
+					failed = err
+					continue
+				}
+				chunk.Annotations = resp.Annotations
+			}
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+				failed = ctx.Err()
+			}
+		}
+		for err := range inErrs {
+			if err != nil && failed == nil && !finished {
+				failed = err
+			}
+		}
+		if failed != nil {
+			r.onError(ctx, &req, failed)
+			errChan <- failed
+		}
+	}()
+	return chunkChan, errChan
+}
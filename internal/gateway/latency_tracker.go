@@ -1,6 +1,8 @@
 package gateway
 
 import (
+	"math"
+	"sort"
 	"sync"
 	"time"
 )
@@ -94,6 +96,27 @@ func (lt *LatencyTracker) GetLatencyStats(providerName string) (avg, min, max ti
 	return avg, min, max, count
 }
 
+// Percentile returns the p-th percentile (0-100, nearest rank) of the provider's recent
+// latencies and the number of samples it was taken from.
+func (lt *LatencyTracker) Percentile(providerName string, p float64) (time.Duration, int) {
+	lt.mu.RLock()
+	sorted := append([]time.Duration(nil), lt.latencies[providerName]...)
+	lt.mu.RUnlock()
+
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1], len(sorted)
+}
+
 func (lt *LatencyTracker) Reset() {
 	lt.mu.Lock()
 	defer lt.mu.Unlock()
@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+)
+
+// ModelAlias is a virtual model name such as "fast" that resolves to an ordered
+// chain of concrete provider/model targets.
+type ModelAlias struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Targets     []AliasTarget `json:"targets"`
+}
+
+// AliasTarget is one step of an alias chain. Nil overrides keep the caller's value.
+type AliasTarget struct {
+	Provider    string   `json:"provider"`
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+}
+
+type ModelAliasServiceInterface interface {
+	ResolveAlias(ctx context.Context, userID *uuid.UUID, name string) (*ModelAlias, error)
+	ListAliases(ctx context.Context, userID *uuid.UUID) ([]ModelAlias, error)
+}
+
+// routeTarget is a provider together with the request it should receive.
+type routeTarget struct {
+	provider providers.Provider
+	req      providers.ChatRequest
+}
+
+func routeTargets(list []providers.Provider, req providers.ChatRequest) []routeTarget {
+	targets := make([]routeTarget, 0, len(list))
+	for _, provider := range list {
+		targets = append(targets, routeTarget{provider: provider, req: req})
+	}
+	return targets
+}
+
+// apply rewrites req for this target.
+func (t AliasTarget) apply(req providers.ChatRequest) providers.ChatRequest {
+	req.Model = t.Model
+	if t.Temperature != nil {
+		req.Temperature = t.Temperature
+	}
+	if t.MaxTokens != nil {
+		req.MaxTokens = *t.MaxTokens
+	}
+	return req
+}
+
+// resolveAlias returns the alias named by model, or nil when model is a concrete model name.
+func (r *Router) resolveAlias(ctx context.Context, model string, userID *uuid.UUID) *ModelAlias {
+	if r.modelAliasService == nil || model == "" {
+		return nil
+	}
+	alias, err := r.modelAliasService.ResolveAlias(ctx, userID, model)
+	if err != nil || alias == nil || len(alias.Targets) == 0 {
+		return nil
+	}
+	return alias
+}
+
+// aliasTargets turns the alias chain into route targets, skipping providers that are
+// not available to the caller or whose circuit breaker is open.
+func (r *Router) aliasTargets(alias *ModelAlias, req providers.ChatRequest, available []providers.Provider) []routeTarget {
+	targets := make([]routeTarget, 0, len(alias.Targets))
+	for _, target := range alias.Targets {
+		provider := r.providerFromListByName(available, strings.ToLower(target.Provider))
+		if provider == nil {
+			continue
+		}
+		targets = append(targets, routeTarget{provider: provider, req: target.apply(req)})
+	}
+	return targets
+}
+
+// ListModelAliases returns the aliases visible to userID (global aliases when nil).
+func (r *Router) ListModelAliases(ctx context.Context, userID *uuid.UUID) []ModelAlias {
+	if r.modelAliasService == nil {
+		return nil
+	}
+	aliases, err := r.modelAliasService.ListAliases(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	return aliases
+}
+
+// InvalidateModelAliases drops cached alias lookups after aliases are changed.
+func (r *Router) InvalidateModelAliases() {
+	if cache, ok := r.modelAliasService.(interface{ Invalidate() }); ok {
+		cache.Invalidate()
+	}
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/google/uuid"
+)
+
+// aliasCacheTTL bounds how long a resolved alias (or its absence) is reused, since
+// every chat request resolves its model name before routing.
+const aliasCacheTTL = 30 * time.Second
+
+type aliasCacheKey struct {
+	userID uuid.UUID
+	name   string
+}
+
+type cachedAlias struct {
+	alias     *ModelAlias
+	expiresAt time.Time
+}
+
+type ModelAliasServiceAdapter struct {
+	repo *storage.ModelAliasRepository
+
+	mu    sync.Mutex
+	cache map[aliasCacheKey]cachedAlias
+}
+
+func NewModelAliasServiceAdapter(repo *storage.ModelAliasRepository) *ModelAliasServiceAdapter {
+	return &ModelAliasServiceAdapter{repo: repo, cache: make(map[aliasCacheKey]cachedAlias)}
+}
+
+func (a *ModelAliasServiceAdapter) ResolveAlias(ctx context.Context, userID *uuid.UUID, name string) (*ModelAlias, error) {
+	key := aliasCacheKey{name: name}
+	if userID != nil {
+		key.userID = *userID
+	}
+	now := time.Now()
+	a.mu.Lock()
+	cached, ok := a.cache[key]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.alias, nil
+	}
+
+	alias, err := a.repo.FindActiveAlias(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	var resolved *ModelAlias
+	if alias != nil {
+		converted := toGatewayAlias(alias)
+		resolved = &converted
+	}
+	a.mu.Lock()
+	for k, entry := range a.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(a.cache, k)
+		}
+	}
+	a.cache[key] = cachedAlias{alias: resolved, expiresAt: now.Add(aliasCacheTTL)}
+	a.mu.Unlock()
+	return resolved, nil
+}
+
+// Invalidate drops every cached lookup. Global aliases apply to all users, so
+// changes are not tracked per user.
+func (a *ModelAliasServiceAdapter) Invalidate() {
+	a.mu.Lock()
+	a.cache = make(map[aliasCacheKey]cachedAlias)
+	a.mu.Unlock()
+}
+
+func (a *ModelAliasServiceAdapter) ListAliases(ctx context.Context, userID *uuid.UUID) ([]ModelAlias, error) {
+	aliases, err := a.repo.GetAliasesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// User aliases come first and shadow global ones with the same name.
+	seen := make(map[string]bool)
+	out := make([]ModelAlias, 0, len(aliases))
+	for _, alias := range aliases {
+		if !alias.Enabled || seen[alias.Alias] {
+			continue
+		}
+		seen[alias.Alias] = true
+		out = append(out, toGatewayAlias(alias))
+	}
+	return out, nil
+}
+
+func toGatewayAlias(alias *storage.ModelAlias) ModelAlias {
+	converted := ModelAlias{Name: alias.Alias, Targets: make([]AliasTarget, 0, len(alias.Targets))}
+	if alias.Description != nil {
+		converted.Description = *alias.Description
+	}
+	for _, t := range alias.Targets {
+		converted.Targets = append(converted.Targets, AliasTarget{
+			Provider:    t.Provider,
+			Model:       t.Model,
+			Temperature: t.Temperature,
+			MaxTokens:   t.MaxTokens,
+		})
+	}
+	return converted
+}
@@ -0,0 +1,359 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Pricing is the price of one model in USD per 1M tokens. Optional tiers left at
+// zero fall back to the regular input/output price.
+type Pricing struct {
+	InputCost       float64 `json:"input" yaml:"input"`
+	OutputCost      float64 `json:"output" yaml:"output"`
+	CachedInputCost float64 `json:"cached_input,omitempty" yaml:"cached_input,omitempty"` // prompt tokens served from the provider's prompt cache
+	BatchInputCost  float64 `json:"batch_input,omitempty" yaml:"batch_input,omitempty"`
+	BatchOutputCost float64 `json:"batch_output,omitempty" yaml:"batch_output,omitempty"`
+	ImageCost       float64 `json:"image,omitempty" yaml:"image,omitempty"` // per input image, for models that bill images per item instead of per token
+}
+
+// Cost prices usage. Cached prompt tokens are billed at the cached-input rate.
+func (p Pricing) Cost(usage providers.Usage, batch bool) float64 {
+	input, output, cached := p.InputCost, p.OutputCost, p.CachedInputCost
+	if batch {
+		if p.BatchInputCost > 0 {
+			input = p.BatchInputCost
+		}
+		if p.BatchOutputCost > 0 {
+			output = p.BatchOutputCost
+		}
+	}
+	if cached <= 0 || cached > input {
+		cached = input
+	}
+	cachedTokens := usage.CachedPromptTokens
+	if cachedTokens > usage.PromptTokens {
+		cachedTokens = usage.PromptTokens
+	}
+	cost := float64(usage.PromptTokens-cachedTokens)/1_000_000.0*input +
+		float64(cachedTokens)/1_000_000.0*cached +
+		float64(usage.CompletionTokens)/1_000_000.0*output
+	return cost
+}
+
+// PriceDate is a catalog date. It accepts "2006-01-02" as well as RFC 3339.
+type PriceDate struct {
+	time.Time
+}
+
+func ParsePriceDate(value string) (PriceDate, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return PriceDate{t}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return PriceDate{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD or RFC 3339", value)
+	}
+	return PriceDate{t}, nil
+}
+
+func (d PriceDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(time.RFC3339))
+}
+
+func (d *PriceDate) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := ParsePriceDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d PriceDate) MarshalYAML() (interface{}, error) {
+	return d.Format(time.RFC3339), nil
+}
+
+func (d *PriceDate) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := ParsePriceDate(node.Value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// PriceEntry prices one provider/model between EffectiveFrom (inclusive) and
+// EffectiveUntil (exclusive). Model "*" prices every model of the provider that has
+// no entry of its own, e.g. self-hosted providers.
+type PriceEntry struct {
+	ID             string `json:"id,omitempty" yaml:"-"`
+	Provider       string `json:"provider" yaml:"provider"`
+	Model          string `json:"model" yaml:"model"`
+	Pricing        `yaml:",inline"`
+	EffectiveFrom  *PriceDate `json:"effective_from,omitempty" yaml:"effective_from,omitempty"`
+	EffectiveUntil *PriceDate `json:"effective_until,omitempty" yaml:"effective_until,omitempty"`
+	Source         string     `json:"source,omitempty" yaml:"-"` // builtin, file or database
+}
+
+func (e PriceEntry) activeAt(at time.Time) bool {
+	if e.EffectiveFrom != nil && at.Before(e.EffectiveFrom.Time) {
+		return false
+	}
+	if e.EffectiveUntil != nil && !at.Before(e.EffectiveUntil.Time) {
+		return false
+	}
+	return true
+}
+
+func (e PriceEntry) key() string {
+	from := ""
+	if e.EffectiveFrom != nil {
+		from = e.EffectiveFrom.UTC().Format(time.RFC3339)
+	}
+	return e.Provider + "\x00" + e.Model + "\x00" + from
+}
+
+func (e PriceEntry) Validate() error {
+	if strings.TrimSpace(e.Provider) == "" || strings.TrimSpace(e.Model) == "" {
+		return fmt.Errorf("provider and model are required")
+	}
+	p := e.Pricing
+	for _, v := range []float64{p.InputCost, p.OutputCost, p.CachedInputCost, p.BatchInputCost, p.BatchOutputCost, p.ImageCost} {
+		if v < 0 {
+			return fmt.Errorf("%s/%s: prices must not be negative", e.Provider, e.Model)
+		}
+	}
+	if e.EffectiveFrom != nil && e.EffectiveUntil != nil && !e.EffectiveUntil.After(e.EffectiveFrom.Time) {
+		return fmt.Errorf("%s/%s: effective_until must be after effective_from", e.Provider, e.Model)
+	}
+	return nil
+}
+
+// PricingCatalog is a versioned list of model prices.
+type PricingCatalog struct {
+	Version string       `json:"version" yaml:"version"`
+	Prices  []PriceEntry `json:"prices" yaml:"prices"`
+}
+
+// ParsePricingCatalog decodes a catalog file. Files ending in .json are read as JSON,
+// everything else as YAML.
+func ParsePricingCatalog(name string, data []byte) (*PricingCatalog, error) {
+	var catalog PricingCatalog
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(data, &catalog)
+	} else {
+		err = yaml.Unmarshal(data, &catalog)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pricing catalog %s: %w", name, err)
+	}
+	if err := catalog.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pricing catalog %s: %w", name, err)
+	}
+	return &catalog, nil
+}
+
+func (c *PricingCatalog) Validate() error {
+	seen := make(map[string]bool, len(c.Prices))
+	for i, entry := range c.Prices {
+		if err := entry.Validate(); err != nil {
+			return fmt.Errorf("price %d: %w", i, err)
+		}
+		key := strings.ToLower(entry.key())
+		if seen[key] {
+			return fmt.Errorf("price %d: duplicate entry for %s/%s with the same effective_from", i, entry.Provider, entry.Model)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// MergePricingCatalogs layers catalogs in order: an entry replaces an earlier one with
+// the same provider, model and effective_from.
+func MergePricingCatalogs(layers ...*PricingCatalog) *PricingCatalog {
+	merged := &PricingCatalog{}
+	index := make(map[string]int)
+	var versions []string
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		if layer.Version != "" {
+			versions = append(versions, layer.Version)
+		}
+		for _, entry := range layer.Prices {
+			key := strings.ToLower(entry.key())
+			if i, ok := index[key]; ok {
+				merged.Prices[i] = entry
+				continue
+			}
+			index[key] = len(merged.Prices)
+			merged.Prices = append(merged.Prices, entry)
+		}
+	}
+	merged.Version = strings.Join(versions, "+")
+	return merged
+}
+
+// modelVersionSuffix matches dated or "-latest" suffixes, so "gpt-4o-2024-08-06" and
+// "claude-3-5-sonnet-latest" find the prices of their base model.
+var modelVersionSuffix = regexp.MustCompile(`-(\d{3,4}|\d{8}|\d{4}-\d{2}-\d{2}|latest)$`)
+
+func baseModelName(model string) string {
+	model = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(model)), "models/")
+	return modelVersionSuffix.ReplaceAllString(model, "")
+}
+
+// pricingIndex resolves prices from a catalog.
+type pricingIndex struct {
+	exact map[string]map[string][]PriceEntry // provider -> model -> entries, newest first
+	base  map[string]map[string][]PriceEntry // provider -> base model name -> entries
+}
+
+func newPricingIndex(catalog *PricingCatalog) *pricingIndex {
+	idx := &pricingIndex{
+		exact: make(map[string]map[string][]PriceEntry),
+		base:  make(map[string]map[string][]PriceEntry),
+	}
+	add := func(m map[string]map[string][]PriceEntry, provider, model string, entry PriceEntry) {
+		if m[provider] == nil {
+			m[provider] = make(map[string][]PriceEntry)
+		}
+		m[provider][model] = append(m[provider][model], entry)
+	}
+	for _, entry := range catalog.Prices {
+		provider := strings.ToLower(entry.Provider)
+		model := strings.ToLower(entry.Model)
+		add(idx.exact, provider, model, entry)
+		if base := baseModelName(model); base != model {
+			add(idx.base, provider, base, entry)
+		}
+	}
+	for _, m := range []map[string]map[string][]PriceEntry{idx.exact, idx.base} {
+		for _, models := range m {
+			for _, entries := range models {
+				sort.SliceStable(entries, func(i, j int) bool {
+					return effectiveFrom(entries[i]).After(effectiveFrom(entries[j]))
+				})
+			}
+		}
+	}
+	return idx
+}
+
+func effectiveFrom(e PriceEntry) time.Time {
+	if e.EffectiveFrom == nil {
+		return time.Time{}
+	}
+	return e.EffectiveFrom.Time
+}
+
+// lookup tries the exact model, then its base name, then the provider's "*" entry.
+func (idx *pricingIndex) lookup(provider, model string, at time.Time) (PriceEntry, bool) {
+	provider = strings.ToLower(provider)
+	model = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(model)), "models/")
+	base := baseModelName(model)
+	candidates := [][]PriceEntry{
+		idx.exact[provider][model],
+		idx.exact[provider][base],
+		idx.base[provider][base],
+		idx.exact[provider]["*"],
+	}
+	for _, entries := range candidates {
+		for _, entry := range entries {
+			if entry.activeAt(at) {
+				return entry, true
+			}
+		}
+	}
+	return PriceEntry{}, false
+}
+
+func priceDate(value string) *PriceDate {
+	d, err := ParsePriceDate(value)
+	if err != nil {
+		panic(err)
+	}
+	return &d
+}
+
+// DefaultPricingCatalog is the catalog compiled into the gateway. Files and the
+// database layer on top of it.
+func DefaultPricingCatalog() *PricingCatalog {
+	price := func(provider, model string, p Pricing) PriceEntry {
+		return PriceEntry{Provider: provider, Model: model, Pricing: p, Source: PricingSourceBuiltin}
+	}
+	until := func(e PriceEntry, date string) PriceEntry {
+		e.EffectiveUntil = priceDate(date)
+		return e
+	}
+	from := func(e PriceEntry, date string) PriceEntry {
+		e.EffectiveFrom = priceDate(date)
+		return e
+	}
+
+	prices := []PriceEntry{
+		until(price("openai", "gpt-4o", Pricing{InputCost: 5.0, OutputCost: 15.0}), "2024-10-02"),
+		from(price("openai", "gpt-4o", Pricing{InputCost: 2.5, OutputCost: 10.0, CachedInputCost: 1.25, BatchInputCost: 1.25, BatchOutputCost: 5.0}), "2024-10-02"),
+		price("openai", "gpt-4o-mini", Pricing{InputCost: 0.15, OutputCost: 0.60, CachedInputCost: 0.075, BatchInputCost: 0.075, BatchOutputCost: 0.30}),
+		price("openai", "gpt-4.1", Pricing{InputCost: 2.0, OutputCost: 8.0, CachedInputCost: 0.50, BatchInputCost: 1.0, BatchOutputCost: 4.0}),
+		price("openai", "gpt-4.1-mini", Pricing{InputCost: 0.40, OutputCost: 1.60, CachedInputCost: 0.10, BatchInputCost: 0.20, BatchOutputCost: 0.80}),
+		price("openai", "gpt-4.1-nano", Pricing{InputCost: 0.10, OutputCost: 0.40, CachedInputCost: 0.025, BatchInputCost: 0.05, BatchOutputCost: 0.20}),
+		price("openai", "o1", Pricing{InputCost: 15.0, OutputCost: 60.0, CachedInputCost: 7.5, BatchInputCost: 7.5, BatchOutputCost: 30.0}),
+		price("openai", "o1-mini", Pricing{InputCost: 1.10, OutputCost: 4.40, CachedInputCost: 0.55}),
+		until(price("openai", "o3", Pricing{InputCost: 10.0, OutputCost: 40.0, CachedInputCost: 2.5}), "2025-06-10"),
+		from(price("openai", "o3", Pricing{InputCost: 2.0, OutputCost: 8.0, CachedInputCost: 0.50, BatchInputCost: 1.0, BatchOutputCost: 4.0}), "2025-06-10"),
+		price("openai", "o3-mini", Pricing{InputCost: 1.10, OutputCost: 4.40, CachedInputCost: 0.55, BatchInputCost: 0.55, BatchOutputCost: 2.20}),
+		price("openai", "o4-mini", Pricing{InputCost: 1.10, OutputCost: 4.40, CachedInputCost: 0.275, BatchInputCost: 0.55, BatchOutputCost: 2.20}),
+		price("openai", "gpt-4", Pricing{InputCost: 30.0, OutputCost: 60.0}),
+		price("openai", "gpt-4-turbo", Pricing{InputCost: 10.0, OutputCost: 30.0}),
+		price("openai", "gpt-4-turbo-preview", Pricing{InputCost: 10.0, OutputCost: 30.0}),
+		price("openai", "gpt-3.5-turbo", Pricing{InputCost: 0.5, OutputCost: 1.5}),
+		price("openai", "text-embedding-3-small", Pricing{InputCost: 0.02}),
+		price("openai", "text-embedding-3-large", Pricing{InputCost: 0.13}),
+		price("openai", "text-embedding-ada-002", Pricing{InputCost: 0.10}),
+
+		price("anthropic", "claude-opus-4-1-20250805", Pricing{InputCost: 15.0, OutputCost: 75.0, CachedInputCost: 1.50, BatchInputCost: 7.5, BatchOutputCost: 37.5}),
+		price("anthropic", "claude-opus-4-20250514", Pricing{InputCost: 15.0, OutputCost: 75.0, CachedInputCost: 1.50, BatchInputCost: 7.5, BatchOutputCost: 37.5}),
+		price("anthropic", "claude-sonnet-4-20250514", Pricing{InputCost: 3.0, OutputCost: 15.0, CachedInputCost: 0.30, BatchInputCost: 1.5, BatchOutputCost: 7.5}),
+		price("anthropic", "claude-3-7-sonnet-20250219", Pricing{InputCost: 3.0, OutputCost: 15.0, CachedInputCost: 0.30, BatchInputCost: 1.5, BatchOutputCost: 7.5}),
+		price("anthropic", "claude-3-5-sonnet-20241022", Pricing{InputCost: 3.0, OutputCost: 15.0, CachedInputCost: 0.30, BatchInputCost: 1.5, BatchOutputCost: 7.5}),
+		price("anthropic", "claude-3-5-sonnet-20240620", Pricing{InputCost: 3.0, OutputCost: 15.0, CachedInputCost: 0.30, BatchInputCost: 1.5, BatchOutputCost: 7.5}),
+		price("anthropic", "claude-3-5-haiku-20241022", Pricing{InputCost: 0.80, OutputCost: 4.0, CachedInputCost: 0.08, BatchInputCost: 0.40, BatchOutputCost: 2.0}),
+		price("anthropic", "claude-3-opus-20240229", Pricing{InputCost: 15.0, OutputCost: 75.0, CachedInputCost: 1.50, BatchInputCost: 7.5, BatchOutputCost: 37.5}),
+		price("anthropic", "claude-3-sonnet-20240229", Pricing{InputCost: 3.0, OutputCost: 15.0}),
+		price("anthropic", "claude-3-haiku-20240307", Pricing{InputCost: 0.25, OutputCost: 1.25, CachedInputCost: 0.03, BatchInputCost: 0.125, BatchOutputCost: 0.625}),
+
+		price("google", "gemini-pro", Pricing{}),
+		price("google", "gemini-1.5-pro", Pricing{InputCost: 1.25, OutputCost: 5.0}),
+		price("google", "gemini-1.5-flash", Pricing{InputCost: 0.075, OutputCost: 0.30}),
+		price("google", "gemini-1.5-flash-8b", Pricing{InputCost: 0.0375, OutputCost: 0.15}),
+		price("google", "gemini-2.0-flash", Pricing{InputCost: 0.10, OutputCost: 0.40, CachedInputCost: 0.025, BatchInputCost: 0.05, BatchOutputCost: 0.20}),
+		price("google", "gemini-2.0-flash-lite", Pricing{InputCost: 0.075, OutputCost: 0.30}),
+		price("google", "gemini-2.0-flash-exp", Pricing{InputCost: 0.10, OutputCost: 0.40}),
+		price("google", "gemini-2.5-pro", Pricing{InputCost: 1.25, OutputCost: 10.0, CachedInputCost: 0.31, BatchInputCost: 0.625, BatchOutputCost: 5.0}),
+		until(price("google", "gemini-2.5-flash", Pricing{InputCost: 0.15, OutputCost: 0.60}), "2025-06-17"),
+		from(price("google", "gemini-2.5-flash", Pricing{InputCost: 0.30, OutputCost: 2.50, CachedInputCost: 0.075, BatchInputCost: 0.15, BatchOutputCost: 1.25}), "2025-06-17"),
+		price("google", "gemini-2.5-flash-lite", Pricing{InputCost: 0.10, OutputCost: 0.40, CachedInputCost: 0.025, BatchInputCost: 0.05, BatchOutputCost: 0.20}),
+		price("google", "gemini-embedding-001", Pricing{InputCost: 0.15}),
+		price("google", "text-embedding-004", Pricing{}),
+
+		price("local", "*", Pricing{}),
+		price("vllm", "*", Pricing{}),
+	}
+	return &PricingCatalog{Version: "builtin-2025-08", Prices: prices}
+}
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	PricingSourceBuiltin  = "builtin"
+	PricingSourceFile     = "file"
+	PricingSourceDatabase = "database"
+)
+
+// PricingSource supplies one layer of the pricing catalog.
+type PricingSource interface {
+	Name() string
+	LoadPricing(ctx context.Context) (*PricingCatalog, error)
+}
+
+// FilePricingSource reads a JSON or YAML catalog file on every load.
+type FilePricingSource struct {
+	path string
+}
+
+func NewFilePricingSource(path string) *FilePricingSource {
+	return &FilePricingSource{path: path}
+}
+
+func (s *FilePricingSource) Name() string {
+	return PricingSourceFile
+}
+
+func (s *FilePricingSource) LoadPricing(ctx context.Context) (*PricingCatalog, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing catalog: %w", err)
+	}
+	catalog, err := ParsePricingCatalog(s.path, data)
+	if err != nil {
+		return nil, err
+	}
+	for i := range catalog.Prices {
+		catalog.Prices[i].Source = PricingSourceFile
+	}
+	return catalog, nil
+}
+
+// PricingStatus describes the catalog a PricingLoader last applied.
+type PricingStatus struct {
+	Version     string     `json:"version"`
+	Entries     int        `json:"entries"`
+	Sources     []string   `json:"sources"`
+	LoadedAt    *time.Time `json:"loaded_at,omitempty"`
+	LastCheckAt *time.Time `json:"last_check_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// PricingLoader builds the catalog from the built-in prices and its sources, in that
+// order, and hands it to the cost calculator. Start re-reads the sources periodically
+// so edits take effect without a restart.
+type PricingLoader struct {
+	mu          sync.Mutex
+	calculator  *CostCalculator
+	sources     []PricingSource
+	interval    time.Duration
+	logger      zerolog.Logger
+	fingerprint string
+	loadedAt    time.Time
+	lastCheckAt time.Time
+	lastError   string
+}
+
+func NewPricingLoader(calculator *CostCalculator, interval time.Duration, logger zerolog.Logger, sources ...PricingSource) *PricingLoader {
+	return &PricingLoader{
+		calculator: calculator,
+		sources:    sources,
+		interval:   interval,
+		logger:     logger,
+	}
+}
+
+// Start reloads every interval until ctx is cancelled. Call Reload once first so the
+// gateway starts with the configured prices.
+func (l *PricingLoader) Start(ctx context.Context) {
+	if l.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := l.Reload(ctx); err != nil && ctx.Err() == nil {
+					l.logger.Warn().Err(err).Msg("Failed to reload pricing catalog, keeping the current one")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Reload reads all sources and swaps the catalog if it changed. On error the current
+// catalog stays in place.
+func (l *PricingLoader) Reload(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastCheckAt = time.Now()
+
+	changed, err := l.reload(ctx)
+	if err != nil {
+		l.lastError = err.Error()
+		return false, err
+	}
+	l.lastError = ""
+	return changed, nil
+}
+
+func (l *PricingLoader) reload(ctx context.Context) (bool, error) {
+	layers := []*PricingCatalog{DefaultPricingCatalog()}
+	for _, source := range l.sources {
+		catalog, err := source.LoadPricing(ctx)
+		if err != nil {
+			return false, fmt.Errorf("%s pricing source: %w", source.Name(), err)
+		}
+		layers = append(layers, catalog)
+	}
+	merged := MergePricingCatalogs(layers...)
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return false, fmt.Errorf("failed to fingerprint pricing catalog: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	fingerprint := hex.EncodeToString(sum[:])
+	if fingerprint == l.fingerprint {
+		return false, nil
+	}
+
+	if err := l.calculator.SetCatalog(merged); err != nil {
+		return false, fmt.Errorf("invalid pricing catalog: %w", err)
+	}
+	l.fingerprint = fingerprint
+	l.loadedAt = time.Now()
+	l.logger.Info().
+		Str("version", merged.Version).
+		Int("entries", len(merged.Prices)).
+		Msg("Pricing catalog loaded")
+	return true, nil
+}
+
+func (l *PricingLoader) Status() PricingStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	catalog := l.calculator.Catalog()
+	status := PricingStatus{
+		Version:   catalog.Version,
+		Entries:   len(catalog.Prices),
+		Sources:   []string{PricingSourceBuiltin},
+		LastError: l.lastError,
+	}
+	for _, source := range l.sources {
+		status.Sources = append(status.Sources, source.Name())
+	}
+	if !l.loadedAt.IsZero() {
+		t := l.loadedAt
+		status.LoadedAt = &t
+	}
+	if !l.lastCheckAt.IsZero() {
+		t := l.lastCheckAt
+		status.LastCheckAt = &t
+	}
+	return status
+}
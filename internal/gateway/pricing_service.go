@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+)
+
+// DatabasePricingSource serves the model_prices table as a pricing catalog layer.
+type DatabasePricingSource struct {
+	repo *storage.ModelPriceRepository
+}
+
+func NewDatabasePricingSource(repo *storage.ModelPriceRepository) *DatabasePricingSource {
+	return &DatabasePricingSource{repo: repo}
+}
+
+func (s *DatabasePricingSource) Name() string {
+	return PricingSourceDatabase
+}
+
+func (s *DatabasePricingSource) LoadPricing(ctx context.Context) (*PricingCatalog, error) {
+	prices, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	catalog := &PricingCatalog{Prices: make([]PriceEntry, 0, len(prices))}
+	var latest time.Time
+	for _, price := range prices {
+		catalog.Prices = append(catalog.Prices, PriceEntryFromModelPrice(price))
+		if price.UpdatedAt.After(latest) {
+			latest = price.UpdatedAt
+		}
+	}
+	if !latest.IsZero() {
+		catalog.Version = "db-" + latest.UTC().Format("20060102T150405Z")
+	}
+	return catalog, nil
+}
+
+func PriceEntryFromModelPrice(price *storage.ModelPrice) PriceEntry {
+	entry := PriceEntry{
+		ID:       price.ID.String(),
+		Provider: price.Provider,
+		Model:    price.Model,
+		Pricing: Pricing{
+			InputCost:       price.InputCost,
+			OutputCost:      price.OutputCost,
+			CachedInputCost: price.CachedInputCost,
+			BatchInputCost:  price.BatchInputCost,
+			BatchOutputCost: price.BatchOutputCost,
+			ImageCost:       price.ImageCost,
+		},
+		Source: PricingSourceDatabase,
+	}
+	if price.EffectiveFrom != nil {
+		entry.EffectiveFrom = &PriceDate{*price.EffectiveFrom}
+	}
+	if price.EffectiveUntil != nil {
+		entry.EffectiveUntil = &PriceDate{*price.EffectiveUntil}
+	}
+	return entry
+}
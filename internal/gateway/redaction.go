@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/pii"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+// DefaultRedactionSkipProviders run on-prem, so prompts sent to them are not redacted
+// unless a policy lists its own skip providers.
+var DefaultRedactionSkipProviders = []string{"local", "vllm"}
+
+// RedactionPolicy asks the router to replace PII in message text with placeholders
+// before calling a provider, and to restore the placeholders in the response. Types
+// limits detection to those types (all when empty). SkipProviders are called with the
+// original prompt; nil means DefaultRedactionSkipProviders.
+type RedactionPolicy struct {
+	Types         []pii.Type
+	SkipProviders []string
+}
+
+type redactionPolicyKey struct{}
+
+func WithRedactionPolicy(ctx context.Context, policy RedactionPolicy) context.Context {
+	return context.WithValue(ctx, redactionPolicyKey{}, policy)
+}
+
+func redactionPolicyFromContext(ctx context.Context) (RedactionPolicy, bool) {
+	policy, ok := ctx.Value(redactionPolicyKey{}).(RedactionPolicy)
+	return policy, ok
+}
+
+// redactorFor returns a fresh redactor if ctx carries a policy that covers provider.
+func redactorFor(ctx context.Context, provider string) *pii.Redactor {
+	policy, ok := redactionPolicyFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	skip := policy.SkipProviders
+	if skip == nil {
+		skip = DefaultRedactionSkipProviders
+	}
+	for _, name := range skip {
+		if strings.EqualFold(name, provider) {
+			return nil
+		}
+	}
+	return pii.NewRedactor(policy.Types...)
+}
+
+// providerChat calls provider.Chat, redacting the request and restoring the response
+// when the request's redaction policy covers the provider. A response_format the
+// provider cannot enforce is turned into a prompt instruction.
+func (r *Router) providerChat(ctx context.Context, provider providers.Provider, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	req = structuredOutputRequest(provider, req)
+	redactor := redactorFor(ctx, provider.Name())
+	if redactor == nil {
+		return provider.Chat(ctx, req)
+	}
+	req = redactRequest(redactor, req)
+	recordRedactions(provider.Name(), redactor)
+	resp, err := provider.Chat(ctx, req)
+	if err == nil && !redactor.Empty() {
+		restoreResponse(redactor, resp)
+	}
+	return resp, err
+}
+
+// providerChatStream is providerChat for streams. Placeholders split across chunks are
+// held back until they are complete.
+func (r *Router) providerChatStream(ctx context.Context, provider providers.Provider, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	streamingProvider := provider.(providers.StreamingProvider)
+	req = structuredOutputRequest(provider, req)
+	redactor := redactorFor(ctx, provider.Name())
+	if redactor == nil {
+		return streamingProvider.ChatStream(ctx, req)
+	}
+	req = redactRequest(redactor, req)
+	recordRedactions(provider.Name(), redactor)
+	chunks, errs := streamingProvider.ChatStream(ctx, req)
+	if redactor.Empty() {
+		return chunks, errs
+	}
+
+	out := make(chan providers.StreamChunk, 10)
+	go func() {
+		defer close(out)
+		content := redactor.NewStreamRestorer()
+		arguments := make(map[int]*pii.StreamRestorer)
+		// flush puts the text still held back into chunk, which ends the stream.
+		flush := func(chunk *providers.StreamChunk) {
+			chunk.Content += content.Flush()
+			for index, restorer := range arguments {
+				if rest := restorer.Flush(); rest != "" {
+					chunk.ToolCalls = append(chunk.ToolCalls, providers.ToolCallDelta{Index: index, Function: providers.ToolCallFunction{Arguments: rest}})
+				}
+			}
+		}
+		send := func(chunk providers.StreamChunk) bool {
+			select {
+			case out <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for chunk := range chunks {
+			chunk.Content = content.Write(chunk.Content)
+			for i := range chunk.ToolCalls {
+				index := chunk.ToolCalls[i].Index
+				if arguments[index] == nil {
+					arguments[index] = redactor.NewStreamRestorer()
+				}
+				chunk.ToolCalls[i].Function.Arguments = arguments[index].Write(chunk.ToolCalls[i].Function.Arguments)
+			}
+			if chunk.Done {
+				flush(&chunk)
+			}
+			if !send(chunk) {
+				return
+			}
+			if chunk.Done {
+				return
+			}
+		}
+		// The provider stopped without a Done chunk; pass on what was held back.
+		var rest providers.StreamChunk
+		flush(&rest)
+		if rest.Content != "" || len(rest.ToolCalls) > 0 {
+			send(rest)
+		}
+	}()
+	return out, errs
+}
+
+func recordRedactions(provider string, redactor *pii.Redactor) {
+	for t, n := range redactor.Redacted() {
+		monitoring.RecordPIIRedactions(provider, string(t), n)
+	}
+}
+
+// redactRequest returns a copy of req with PII in message text and tool call arguments
+// replaced. Images and audio are passed through.
+func redactRequest(redactor *pii.Redactor, req providers.ChatRequest) providers.ChatRequest {
+	messages := make([]providers.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		if msg.Content != nil {
+			text, parts := providers.NormalizeMessageContent(msg.Content)
+			if parts != nil {
+				redacted := make([]providers.ContentPart, len(parts))
+				for j, part := range parts {
+					if part.Type == "text" {
+						part.Text = redactor.Redact(part.Text)
+					}
+					redacted[j] = part
+				}
+				msg.Content = redacted
+			} else {
+				msg.Content = redactor.Redact(text)
+			}
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]providers.ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Function.Arguments = redactor.Redact(call.Function.Arguments)
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		messages[i] = msg
+	}
+	req.Messages = messages
+	return req
+}
+
+func restoreResponse(redactor *pii.Redactor, resp *providers.ChatResponse) {
+	for i := range resp.Choices {
+		msg := &resp.Choices[i].Message
+		switch content := msg.Content.(type) {
+		case string:
+			msg.Content = redactor.Restore(content)
+		case []providers.ContentPart:
+			for j := range content {
+				content[j].Text = redactor.Restore(content[j].Text)
+			}
+		}
+		for j := range msg.ToolCalls {
+			msg.ToolCalls[j].Function.Arguments = redactor.Restore(msg.ToolCalls[j].Function.Arguments)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const responseCachePrefix = "cache:chat:"
+
+// CacheStore is the key/value backend of ResponseCache. Get returns nil, nil on a miss.
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type redisCacheStore struct {
+	client *storage.RedisClient
+}
+
+func NewRedisCacheStore(client *storage.RedisClient) CacheStore {
+	return &redisCacheStore{client: client}
+}
+
+func (s *redisCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Client().Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (s *redisCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Client().Set(ctx, key, value, ttl).Err()
+}
+
+// CachePolicy controls response caching for a single request. A zero TTL disables it.
+type CachePolicy struct {
+	Scope  string // API key or user the cached entries belong to
+	TTL    time.Duration
+	Bypass bool // skip the lookup but still store the fresh response
+}
+
+type cachePolicyKey struct{}
+
+func WithCachePolicy(ctx context.Context, policy CachePolicy) context.Context {
+	return context.WithValue(ctx, cachePolicyKey{}, policy)
+}
+
+func cachePolicyFromContext(ctx context.Context) (CachePolicy, bool) {
+	policy, ok := ctx.Value(cachePolicyKey{}).(CachePolicy)
+	return policy, ok && policy.TTL > 0 && policy.Scope != ""
+}
+
+type ResponseCache struct {
+	store CacheStore
+}
+
+func NewResponseCache(store CacheStore) *ResponseCache {
+	return &ResponseCache{store: store}
+}
+
+// Cacheable reports whether req is deterministic enough to cache: an explicit
+// temperature of 0 and no web search, whose results change over time.
+func Cacheable(req providers.ChatRequest) bool {
+	return req.Temperature != nil && *req.Temperature == 0 && !req.WebSearch && !req.GoogleSearchGrounding
+}
+
+type cacheKeyMessage struct {
+	Role       string                  `json:"role"`
+	Text       string                  `json:"text,omitempty"`
+	Parts      []providers.ContentPart `json:"parts,omitempty"`
+	ToolCalls  []providers.ToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string                  `json:"tool_call_id,omitempty"`
+	Name       string                  `json:"name,omitempty"`
+}
+
+// CacheKey hashes the parts of req that affect the response. Message content is
+// normalized so that string and content-part forms of the same text hash alike.
+func CacheKey(scope string, req providers.ChatRequest) string {
+	messages := make([]cacheKeyMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		text, parts := providers.NormalizeMessageContent(msg.Content)
+		if len(parts) == 1 && parts[0].Type == "text" {
+			text, parts = parts[0].Text, nil
+		}
+		messages = append(messages, cacheKeyMessage{
+			Role:       strings.ToLower(strings.TrimSpace(msg.Role)),
+			Text:       strings.TrimSpace(text),
+			Parts:      parts,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+			Name:       msg.Name,
+		})
+	}
+
+	payload, _ := json.Marshal(struct {
+		Model            string                    `json:"model"`
+		Messages         []cacheKeyMessage         `json:"messages"`
+		Temperature      *float64                  `json:"temperature"`
+		MaxTokens        int                       `json:"max_tokens"`
+		Tools            []providers.Tool          `json:"tools,omitempty"`
+		ToolChoice       *providers.ToolChoice     `json:"tool_choice,omitempty"`
+		Format           *providers.ResponseFormat `json:"response_format,omitempty"`
+		TopP             *float64                  `json:"top_p,omitempty"`
+		Stop             []string                  `json:"stop,omitempty"`
+		PresencePenalty  *float64                  `json:"presence_penalty,omitempty"`
+		FrequencyPenalty *float64                  `json:"frequency_penalty,omitempty"`
+		Seed             *int                      `json:"seed,omitempty"`
+		LogitBias        map[string]float64        `json:"logit_bias,omitempty"`
+	}{
+		Model:            strings.TrimSpace(req.Model),
+		Messages:         messages,
+		Temperature:      req.Temperature,
+		MaxTokens:        req.MaxTokens,
+		Tools:            req.Tools,
+		ToolChoice:       req.ToolChoice,
+		Format:           req.ResponseFormat,
+		TopP:             req.TopP,
+		Stop:             req.Stop,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		Seed:             req.Seed,
+		LogitBias:        req.LogitBias,
+	})
+
+	sum := sha256.Sum256(payload)
+	return responseCachePrefix + scope + ":" + hex.EncodeToString(sum[:])
+}
+
+func (c *ResponseCache) Get(ctx context.Context, key string) (*providers.ChatResponse, bool) {
+	data, err := c.store.Get(ctx, key)
+	if err != nil || data == nil {
+		return nil, false
+	}
+	var resp providers.ChatResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+func (c *ResponseCache) Set(ctx context.Context, key string, resp *providers.ChatResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, key, data, ttl)
+}
+
+// replayChunkRunes is the size of the content chunks a cached response is replayed in.
+const replayChunkRunes = 64
+
+func (r *Router) responseCacheKey(ctx context.Context, req providers.ChatRequest) (string, CachePolicy, bool) {
+	if r.responseCache == nil || !Cacheable(req) {
+		return "", CachePolicy{}, false
+	}
+	policy, ok := cachePolicyFromContext(ctx)
+	if !ok {
+		return "", CachePolicy{}, false
+	}
+	return CacheKey(policy.Scope, req), policy, true
+}
+
+// cacheHit marks a cached response as served from cache. It is not billed again.
+func cacheHit(resp *providers.ChatResponse) *providers.ChatResponse {
+	monitoring.RecordCacheHit(resp.Provider, resp.Model, resp.Cost)
+	resp.Cached = true
+	resp.Cost = 0
+	resp.LatencyMs = 0
+	return resp
+}
+
+// storeCachedResponse is best effort: a failed write only costs a future cache miss.
+func (r *Router) storeCachedResponse(key string, resp *providers.ChatResponse, ttl time.Duration) {
+	if resp == nil || len(resp.Choices) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = r.responseCache.Set(ctx, key, resp, ttl)
+}
+
+// replayCachedResponse streams resp as content chunks followed by a Done chunk carrying usage.
+// chunkChan is unbuffered so errChan is not closed while chunks are still pending.
+func replayCachedResponse(ctx context.Context, resp *providers.ChatResponse) (<-chan providers.StreamChunk, <-chan error) {
+	var content string
+	var toolCalls []providers.ToolCallDelta
+	finishReason := ""
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		content, _ = choice.Message.Content.(string)
+		finishReason = choice.FinishReason
+		for i, call := range choice.Message.ToolCalls {
+			toolCalls = append(toolCalls, providers.ToolCallDelta{Index: i, ID: call.ID, Type: call.Type, Function: call.Function})
+		}
+	}
+
+	runes := []rune(content)
+	chunks := make([]providers.StreamChunk, 0, len(runes)/replayChunkRunes+2)
+	for start := 0; start < len(runes); start += replayChunkRunes {
+		end := start + replayChunkRunes
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, providers.StreamChunk{ID: resp.ID, Content: string(runes[start:end]), Provider: resp.Provider, Cached: true})
+	}
+	usage := resp.Usage
+	chunks = append(chunks, providers.StreamChunk{
+		ID:           resp.ID,
+		Done:         true,
+		Usage:        &usage,
+		Provider:     resp.Provider,
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
+		Cached:       true,
+	})
+
+	chunkChan := make(chan providers.StreamChunk)
+	errChan := make(chan error)
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+		for _, chunk := range chunks {
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return chunkChan, errChan
+}
+
+// cacheStream forwards a live stream and stores the assembled response once it completes cleanly.
+func (r *Router) cacheStream(ctx context.Context, key string, ttl time.Duration, model string, in <-chan providers.StreamChunk, inErrs <-chan error) (<-chan providers.StreamChunk, <-chan error) {
+	chunkChan := make(chan providers.StreamChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		assembled := newStreamAssembler(model)
+		complete := false
+		failed := false // a truncated completion must not be replayed
+		forward := true
+
+		for chunk := range in {
+			assembled.add(chunk)
+			complete = chunk.Done
+			failed = failed || chunk.Error != "" || chunk.Incomplete
+
+			if !forward {
+				continue
+			}
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+				forward = false
+			}
+		}
+
+		for err := range inErrs {
+			failed = true
+			if forward {
+				errChan <- err
+			}
+		}
+
+		if !complete || failed || assembled.empty() {
+			return
+		}
+		resp := assembled.response()
+		if resp.Usage.TotalTokens > 0 {
+			resp.Cost = r.costCalculator.CalculateActualCost(resp.Provider, model, resp.Usage)
+		}
+		r.storeCachedResponse(key, resp, ttl)
+	}()
+
+	return chunkChan, errChan
+}
+
+// streamAssembler rebuilds the chat response a stream delivered, chunk by chunk.
+type streamAssembler struct {
+	resp         providers.ChatResponse
+	content      strings.Builder
+	toolCalls    []providers.ToolCall
+	finishReason string
+}
+
+func newStreamAssembler(model string) *streamAssembler {
+	return &streamAssembler{resp: providers.ChatResponse{Model: model}}
+}
+
+func (a *streamAssembler) add(chunk providers.StreamChunk) {
+	if chunk.ID != "" {
+		a.resp.ID = chunk.ID
+	}
+	if chunk.Provider != "" {
+		a.resp.Provider = chunk.Provider
+	}
+	if chunk.Usage != nil {
+		a.resp.Usage = *chunk.Usage
+	}
+	if chunk.FinishReason != "" {
+		a.finishReason = chunk.FinishReason
+	}
+	if chunk.Cached {
+		a.resp.Cached = true
+	}
+	a.content.WriteString(chunk.Content)
+	for _, delta := range chunk.ToolCalls {
+		for len(a.toolCalls) <= delta.Index {
+			a.toolCalls = append(a.toolCalls, providers.ToolCall{})
+		}
+		call := &a.toolCalls[delta.Index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Type != "" {
+			call.Type = delta.Type
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+}
+
+func (a *streamAssembler) empty() bool {
+	return a.content.Len() == 0 && len(a.toolCalls) == 0
+}
+
+func (a *streamAssembler) response() *providers.ChatResponse {
+	resp := a.resp
+	resp.Choices = []providers.Choice{{
+		Message:      providers.Message{Role: "assistant", Content: a.content.String(), ToolCalls: a.toolCalls},
+		FinishReason: a.finishReason,
+	}}
+	return &resp
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+// RetryPolicy controls retries of the same provider before failing over.
+type RetryPolicy struct {
+	MaxRetries    int           // extra attempts per provider after the first call
+	BaseDelay     time.Duration // backoff before the first retry, doubled on each further retry
+	MaxDelay      time.Duration // cap on the backoff delay
+	MaxRetryAfter time.Duration // longest Retry-After honored; longer waits fail over instead
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:    2,
+		BaseDelay:     250 * time.Millisecond,
+		MaxDelay:      4 * time.Second,
+		MaxRetryAfter: 10 * time.Second,
+	}
+}
+
+// retryDelay returns false when err is not retryable or the retries are used up.
+func (p RetryPolicy) retryDelay(err error, attempt int) (time.Duration, bool) {
+	perr, ok := providers.AsProviderError(err)
+	if !ok || !perr.Retryable() || attempt >= p.MaxRetries {
+		return 0, false
+	}
+	if perr.RetryAfter > 0 {
+		if perr.RetryAfter > p.MaxRetryAfter {
+			return 0, false
+		}
+		return perr.RetryAfter, true
+	}
+	backoff := p.BaseDelay << uint(attempt)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0, true
+	}
+	// Jitter over the upper half so concurrent callers do not retry in lockstep.
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1)), true
+}
+
+func (r *Router) waitForRetry(ctx context.Context, provider string, breaker *CircuitBreaker, err error, attempt int) bool {
+	delay, ok := r.retryPolicy.retryDelay(err, attempt)
+	if !ok {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+	}
+	if breaker != nil && !breaker.Allow() {
+		return false
+	}
+	perr, _ := providers.AsProviderError(err)
+	monitoring.RecordProviderRetry(provider, string(perr.Kind))
+	return true
+}
+
+// isClientError reports whether another provider would reject the request the same way.
+func isClientError(err error) bool {
+	perr, ok := providers.AsProviderError(err)
+	return ok && perr.ClientError()
+}
+
+func isNotFound(err error) bool {
+	perr, ok := providers.AsProviderError(err)
+	return ok && perr.Kind == providers.ErrorKindNotFound
+}
+
+// failoverError keeps classified errors so callers can surface the upstream status.
+func failoverError(lastErr error) error {
+	if _, ok := providers.AsProviderError(lastErr); ok {
+		return lastErr
+	}
+	return fmt.Errorf("all providers failed, last error: %w", lastErr)
+}
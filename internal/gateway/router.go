@@ -3,10 +3,14 @@ package gateway
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/tokenizer"
 	"github.com/Kizsoft-Solution-Limited/uniroute/pkg/errors"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -26,6 +30,7 @@ type CustomRulesServiceInterface interface {
 }
 
 type CustomRule struct {
+	Name           string
 	ConditionType  string
 	ConditionValue map[string]interface{}
 	ProviderName   string
@@ -34,6 +39,7 @@ type CustomRule struct {
 
 type Router struct {
 	providers                  map[string]providers.Provider
+	providerNames              []string // registration order
 	defaultProvider            providers.Provider
 	strategy                   RoutingStrategy
 	currentStrategyType        StrategyType
@@ -44,12 +50,36 @@ type Router struct {
 	routingStrategyService     RoutingStrategyServiceInterface
 	userRoutingStrategyService UserRoutingStrategyServiceInterface
 	customRulesService         CustomRulesServiceInterface
+	ruleEngine                 *RuleEngine
+	responseCache              *ResponseCache
+	breakers                   *BreakerRegistry
+	modelAliasService          ModelAliasServiceInterface
+	experimentService          ExperimentServiceInterface
+	loadBalancer               *LoadBalancedStrategy
+	retryPolicy                RetryPolicy
+	tokenizers                 *tokenizer.Registry
+	pricingLoader              *PricingLoader
+	shadowMirror               *ShadowMirror
+	hooks                      []Hook
+	contextConfig              ContextConfig
+	summaryStore               ConversationSummaryStore
+	capabilities               *providers.CapabilityRegistry
+	modelCatalog               *providers.ModelCatalog
+	compatibleBYOK             map[string]providers.OpenAICompatibleConfig
+	disabledMu                 sync.RWMutex
+	disabledProviders          map[string]bool
 }
 
 type ProviderKeyServiceInterface interface {
 	GetProviderKey(ctx context.Context, userID uuid.UUID, provider string) (string, error)
 }
 
+// ProviderKeyMetadataInterface is implemented by key services that store provider
+// settings with a key, which Azure OpenAI BYOK keys need.
+type ProviderKeyMetadataInterface interface {
+	GetProviderKeyWithMetadata(ctx context.Context, userID uuid.UUID, provider string) (string, []byte, error)
+}
+
 type ServerProviderKeys struct {
 	OpenAI    string
 	Anthropic string
@@ -57,12 +87,22 @@ type ServerProviderKeys struct {
 }
 
 func NewRouter() *Router {
+	costCalculator := NewCostCalculator()
+	latencyTracker := NewLatencyTracker(100)
 	return &Router{
 		providers:           make(map[string]providers.Provider),
 		strategy:            &ModelBasedStrategy{},
 		currentStrategyType: StrategyModelBased,
-		costCalculator:      NewCostCalculator(),
-		latencyTracker:      NewLatencyTracker(100),
+		costCalculator:      costCalculator,
+		tokenizers:          costCalculator.tokenizers,
+		latencyTracker:      latencyTracker,
+		ruleEngine:          NewRuleEngine(costCalculator, latencyTracker),
+		breakers:            NewBreakerRegistry(DefaultCircuitBreakerConfig()),
+		retryPolicy:         DefaultRetryPolicy(),
+		loadBalancer:        NewLoadBalancedStrategy(),
+		capabilities:        providers.NewCapabilityRegistry(),
+		compatibleBYOK:      make(map[string]providers.OpenAICompatibleConfig),
+		disabledProviders:   make(map[string]bool),
 		providerKeyService:  nil,
 		serverProviderKeys:  ServerProviderKeys{},
 	}
@@ -84,6 +124,43 @@ func (r *Router) SetCustomRulesService(service CustomRulesServiceInterface) {
 	r.customRulesService = service
 }
 
+// SetModelAliasService enables model aliases, resolved before provider selection.
+func (r *Router) SetModelAliasService(service ModelAliasServiceInterface) {
+	r.modelAliasService = service
+}
+
+func (r *Router) SetExperimentService(service ExperimentServiceInterface) {
+	r.experimentService = service
+}
+
+// SetResponseCache enables response caching for requests that carry a CachePolicy (see WithCachePolicy).
+func (r *Router) SetResponseCache(cache *ResponseCache) {
+	r.responseCache = cache
+}
+
+func (r *Router) SetCircuitBreakerConfig(config CircuitBreakerConfig) {
+	r.breakers.SetConfig(config)
+}
+
+func (r *Router) SetRetryPolicy(policy RetryPolicy) {
+	r.retryPolicy = policy
+}
+
+// SetModelCatalog makes registered and BYOK providers discover their models through
+// catalog, each with its own key.
+func (r *Router) SetModelCatalog(catalog *providers.ModelCatalog) {
+	r.modelCatalog = catalog
+	for _, provider := range r.providers {
+		r.useModelCatalog(provider)
+	}
+}
+
+func (r *Router) useModelCatalog(provider providers.Provider) {
+	if cp, ok := provider.(providers.CatalogProvider); ok && r.modelCatalog != nil {
+		cp.SetModelCatalog(r.modelCatalog)
+	}
+}
+
 func (r *Router) SetServerProviderKeys(keys ServerProviderKeys) {
 	r.serverProviderKeys = keys
 }
@@ -105,7 +182,7 @@ func (r *Router) SetStrategyType(strategyType StrategyType) {
 	case StrategyLatencyBased:
 		newStrategy = NewLatencyBasedStrategy(r.latencyTracker)
 	case StrategyLoadBalanced:
-		newStrategy = NewLoadBalancedStrategy()
+		newStrategy = r.loadBalancer
 	case StrategyModelBased:
 		newStrategy = &ModelBasedStrategy{}
 	case StrategyCustom:
@@ -143,11 +220,23 @@ func (r *Router) GetStrategyType() StrategyType {
 	}
 }
 
+// Where the strategy of a request comes from.
+const (
+	StrategySourceLocked  = "locked_default" // the default strategy, locked by an admin
+	StrategySourceUser    = "user"
+	StrategySourceDefault = "default"
+)
+
 func (r *Router) GetStrategyForUser(ctx context.Context, userID *uuid.UUID) StrategyType {
+	strategyType, _ := r.strategyForUser(ctx, userID)
+	return strategyType
+}
+
+func (r *Router) strategyForUser(ctx context.Context, userID *uuid.UUID) (StrategyType, string) {
 	if r.routingStrategyService != nil {
 		locked, err := r.routingStrategyService.IsRoutingStrategyLocked(ctx)
 		if err == nil && locked {
-			return r.GetStrategyType()
+			return r.GetStrategyType(), StrategySourceLocked
 		}
 	}
 	if userID != nil && r.userRoutingStrategyService != nil {
@@ -156,36 +245,29 @@ func (r *Router) GetStrategyForUser(ctx context.Context, userID *uuid.UUID) Stra
 			strategyType := StrategyType(userStrategy)
 			switch strategyType {
 			case StrategyModelBased, StrategyCostBased, StrategyLatencyBased, StrategyLoadBalanced, StrategyCustom:
-				return strategyType
+				return strategyType, StrategySourceUser
 			}
 		}
 	}
-	return r.GetStrategyType()
+	return r.GetStrategyType(), StrategySourceDefault
 }
 
 func (r *Router) GetStrategyInstanceForUser(ctx context.Context, userID *uuid.UUID) RoutingStrategy {
-	strategyType := r.GetStrategyForUser(ctx, userID)
+	strategyType, source := r.strategyForUser(ctx, userID)
+	routingTraceFromContext(ctx).setStrategy(strategyType, source)
 	switch strategyType {
 	case StrategyCostBased:
 		return NewCostBasedStrategy(r.costCalculator)
 	case StrategyLatencyBased:
 		return NewLatencyBasedStrategy(r.latencyTracker)
 	case StrategyLoadBalanced:
-		return NewLoadBalancedStrategy()
+		// Shared so the round-robin position survives across requests.
+		return r.loadBalancer
 	case StrategyCustom:
 		if r.customRulesService != nil && userID != nil {
 			customRules, err := r.customRulesService.GetActiveRulesForUser(ctx, userID)
 			if err == nil && len(customRules) > 0 {
-				routingRules := make([]RoutingRule, 0, len(customRules))
-				for _, rule := range customRules {
-					condition := r.buildCustomRuleCondition(rule)
-					routingRules = append(routingRules, RoutingRule{
-						Provider:  rule.ProviderName,
-						Priority:  rule.Priority,
-						Condition: condition,
-					})
-				}
-				return NewCustomStrategy(routingRules)
+				return NewCustomStrategy(r.ruleEngine.BuildRoutingRules(customRules))
 			}
 		}
 		if r.strategy != nil {
@@ -201,38 +283,48 @@ func (r *Router) GetStrategyInstanceForUser(ctx context.Context, userID *uuid.UU
 	}
 }
 
-func (r *Router) buildCustomRuleCondition(rule CustomRule) func(req providers.ChatRequest) bool {
-	return func(req providers.ChatRequest) bool {
-		switch rule.ConditionType {
-		case "model":
-			if model, ok := rule.ConditionValue["model"].(string); ok {
-				return req.Model == model
-			}
-		case "cost_threshold":
-			if maxCost, ok := rule.ConditionValue["max_cost"].(float64); ok {
-				estimatedCost := r.costCalculator.EstimateCost(rule.ProviderName, req.Model, req.Messages)
-				return estimatedCost <= maxCost
-			}
-			return false
-		case "latency_threshold":
-			if maxLatencyMs, ok := rule.ConditionValue["max_latency_ms"].(float64); ok {
-				avgLatency := r.latencyTracker.GetAverageLatency(rule.ProviderName)
-				return avgLatency.Milliseconds() <= int64(maxLatencyMs)
-			}
-			return false
-		}
-		return false
-	}
-}
-
 func (r *Router) RegisterProvider(provider providers.Provider) {
+	if _, exists := r.providers[provider.Name()]; !exists {
+		r.providerNames = append(r.providerNames, provider.Name())
+	}
 	r.providers[provider.Name()] = provider
+	r.useModelCatalog(provider)
 	if r.defaultProvider == nil {
 		r.defaultProvider = provider
 	}
 }
 
+// Route sends a chat request to a provider chosen by the user's strategy, failing over
+// to the others. The request and response pass through the router's hooks, and requests
+// over their model's context window are handled according to the ContextPolicy.
 func (r *Router) Route(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (*providers.ChatResponse, error) {
+	return r.withHooks(ctx, req, func(req providers.ChatRequest) (*providers.ChatResponse, error) {
+		return r.routeCached(ctx, req, userID)
+	})
+}
+
+func (r *Router) routeCached(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (*providers.ChatResponse, error) {
+	cacheKey, policy, useCache := r.responseCacheKey(ctx, req)
+	if useCache && !policy.Bypass {
+		if cached, ok := r.responseCache.Get(ctx, cacheKey); ok {
+			return cacheHit(cached), nil
+		}
+	}
+	if useCache {
+		monitoring.RecordCacheMiss(req.Model)
+	}
+
+	resp, err := r.routeInContext(ctx, req, userID)
+	if err == nil && useCache {
+		r.storeCachedResponse(cacheKey, resp, policy.TTL)
+	}
+	if err == nil {
+		r.mirror(ctx, req, resp, userID)
+	}
+	return resp, err
+}
+
+func (r *Router) route(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (*providers.ChatResponse, error) {
 	if len(r.providers) == 0 {
 		return nil, fmt.Errorf("no providers available")
 	}
@@ -240,48 +332,167 @@ func (r *Router) Route(ctx context.Context, req providers.ChatRequest, userID *u
 	if len(availableProviders) == 0 {
 		return nil, fmt.Errorf("no providers available")
 	}
+	if alias := r.resolveAlias(ctx, req.Model, userID); alias != nil {
+		routingTraceFromContext(ctx).setAlias(alias.Name)
+		targets := r.aliasTargets(alias, req, availableProviders)
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("no provider available for model alias %s", alias.Name)
+		}
+		return r.chatTargets(ctx, req, targets)
+	}
+	if needs := providers.RequiredCapabilities(req); needs != (providers.Capabilities{}) {
+		availableProviders = r.capableProviders(availableProviders, req.Model, needs)
+		if len(availableProviders) == 0 {
+			return nil, capabilityError(req.Model, needs)
+		}
+	}
 	strategy := r.GetStrategyInstanceForUser(ctx, userID)
 	selectedProvider, err := strategy.SelectProvider(ctx, req, availableProviders)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select provider: %w", err)
 	}
-	if !r.providerInList(availableProviders, selectedProvider.Name()) {
-		availableProviders = []providers.Provider{selectedProvider}
+	routingTraceFromContext(ctx).setProvider(selectedProvider.Name())
+	return r.chatTargets(ctx, req, routeTargets(failoverOrder(selectedProvider, availableProviders), req))
+}
+
+// failoverOrder is the strategy's choice followed by the other available providers.
+// A selected provider that is not available is tried alone.
+func failoverOrder(selected providers.Provider, available []providers.Provider) []providers.Provider {
+	primary := selected
+	found := false
+	for _, provider := range available {
+		if provider.Name() == selected.Name() {
+			primary, found = provider, true
+			break
+		}
 	}
-	primary := r.providerFromListByName(availableProviders, selectedProvider.Name())
-	if primary == nil {
-		primary = selectedProvider
+	if !found {
+		return []providers.Provider{selected}
 	}
-	providersToTry := []providers.Provider{primary}
-	for _, provider := range availableProviders {
+	order := []providers.Provider{primary}
+	for _, provider := range available {
 		if provider.Name() != primary.Name() {
-			providersToTry = append(providersToTry, provider)
+			order = append(order, provider)
+		}
+	}
+	return order
+}
+
+// chatTargets tries each target in order until one succeeds. Hedged requests (see
+// HedgePolicy) may have two targets in flight at once.
+func (r *Router) chatTargets(ctx context.Context, req providers.ChatRequest, targets []routeTarget) (*providers.ChatResponse, error) {
+	if needs := providers.RequiredCapabilities(req); needs != (providers.Capabilities{}) {
+		targets = r.capableTargets(targets, needs)
+		if len(targets) == 0 {
+			return nil, capabilityError(req.Model, needs)
 		}
 	}
 	var lastErr error
-	for _, provider := range providersToTry {
-		start := time.Now()
-		resp, err := provider.Chat(ctx, req)
-		latency := time.Since(start)
-		r.latencyTracker.RecordLatency(provider.Name(), latency)
+	promptTokens := make(map[string]int)
+	i := 0
+	// next returns the next target that may be called, skipping the ones that may not.
+	next := func() (routeTarget, *CircuitBreaker, bool) {
+		for ; i < len(targets); i++ {
+			target := targets[i]
+			if err := r.checkTokenLimits(target.provider.Name(), target.req, promptTokens); err != nil {
+				lastErr = err
+				continue
+			}
+			breaker := r.breakerFor(target.provider)
+			if breaker != nil && !breaker.Allow() {
+				lastErr = fmt.Errorf("circuit breaker open for provider %s", target.provider.Name())
+				continue
+			}
+			i++
+			return target, breaker, true
+		}
+		return routeTarget{}, nil, false
+	}
+	for {
+		target, breaker, ok := next()
+		if !ok {
+			break
+		}
+		var resp *providers.ChatResponse
+		var latency time.Duration
+		var err error
+		if delay, hedged := r.hedgeDelay(ctx, target.provider.Name()); hedged {
+			var winner *hedgeCall
+			if winner, err = r.chatHedged(ctx, target, breaker, delay, next); err == nil {
+				target, resp, latency = winner.target, winner.resp, winner.latency
+			}
+		} else {
+			resp, latency, err = r.chatWithRetry(ctx, target.provider, breaker, target.req)
+		}
+		provider := target.provider
 		if err == nil {
 			resp.Provider = provider.Name()
 			resp.LatencyMs = latency.Milliseconds()
+			if resp.Model == "" {
+				resp.Model = target.req.Model
+			}
 			if resp.Usage.TotalTokens > 0 {
 				resp.Cost = r.costCalculator.CalculateActualCost(provider.Name(), resp.Model, resp.Usage)
 			}
 			return resp, nil
 		}
+		if isClientError(err) || ctx.Err() != nil {
+			return nil, err
+		}
 		lastErr = err
 	}
 	if lastErr != nil {
-		return nil, fmt.Errorf("all providers failed, last error: %w", lastErr)
+		return nil, failoverError(lastErr)
 	}
 
 	return nil, fmt.Errorf("no providers available")
 }
 
+func (r *Router) chatWithRetry(ctx context.Context, provider providers.Provider, breaker *CircuitBreaker, req providers.ChatRequest) (*providers.ChatResponse, time.Duration, error) {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		resp, err := r.providerChat(ctx, provider, req)
+		latency := time.Since(start)
+		// A cancelled call says nothing about how fast the provider is.
+		if err == nil || ctx.Err() == nil {
+			r.latencyTracker.RecordLatency(provider.Name(), latency)
+		}
+		recordBreakerOutcome(breaker, err)
+		if err == nil {
+			return resp, latency, nil
+		}
+		if !r.waitForRetry(ctx, provider.Name(), breaker, err, attempt) {
+			return nil, latency, err
+		}
+	}
+}
+
+// RouteStream streams a chat completion. Cache hits are replayed as chunks marked Cached.
 func (r *Router) RouteStream(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (<-chan providers.StreamChunk, <-chan error) {
+	return r.streamWithHooks(ctx, req, func(req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+		return r.routeStreamCached(ctx, req, userID)
+	})
+}
+
+func (r *Router) routeStreamCached(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (<-chan providers.StreamChunk, <-chan error) {
+	cacheKey, policy, useCache := r.responseCacheKey(ctx, req)
+	if useCache && !policy.Bypass {
+		if cached, ok := r.responseCache.Get(ctx, cacheKey); ok {
+			return replayCachedResponse(ctx, cacheHit(cached))
+		}
+	}
+	chunks, errs := r.routeStreamInContext(ctx, req, userID)
+	if req.ResponseFormat.WantsJSON() {
+		chunks, errs = validateStream(ctx, req.ResponseFormat, chunks, errs)
+	}
+	if !useCache {
+		return chunks, errs
+	}
+	monitoring.RecordCacheMiss(req.Model)
+	return r.cacheStream(ctx, cacheKey, policy.TTL, req.Model, chunks, errs)
+}
+
+func (r *Router) routeStream(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (<-chan providers.StreamChunk, <-chan error) {
 	chunkChan := make(chan providers.StreamChunk, 10)
 	errChan := make(chan error, 1)
 
@@ -294,6 +505,22 @@ func (r *Router) RouteStream(ctx context.Context, req providers.ChatRequest, use
 			errChan <- fmt.Errorf("no providers available")
 			return
 		}
+		if alias := r.resolveAlias(ctx, req.Model, userID); alias != nil {
+			targets := r.aliasTargets(alias, req, availableProviders)
+			if len(targets) == 0 {
+				errChan <- fmt.Errorf("no provider available for model alias %s", alias.Name)
+				return
+			}
+			r.streamTargets(ctx, req, targets, chunkChan, errChan)
+			return
+		}
+		if needs := providers.RequiredCapabilities(req); needs != (providers.Capabilities{}) {
+			availableProviders = r.capableProviders(availableProviders, req.Model, needs)
+			if len(availableProviders) == 0 {
+				errChan <- capabilityError(req.Model, needs)
+				return
+			}
+		}
 		strategy := r.GetStrategyInstanceForUser(ctx, userID)
 		selectedProvider, err := strategy.SelectProvider(ctx, req, availableProviders)
 		if err != nil {
@@ -309,7 +536,12 @@ func (r *Router) RouteStream(ctx context.Context, req providers.ChatRequest, use
 		}
 		_, ok := primary.(providers.StreamingProvider)
 		if !ok {
-			resp, err := primary.Chat(ctx, req)
+			breaker := r.breakerFor(primary)
+			if breaker != nil && !breaker.Allow() {
+				errChan <- fmt.Errorf("circuit breaker open for provider %s", primary.Name())
+				return
+			}
+			resp, _, err := r.chatWithRetry(ctx, primary, breaker, req)
 			if err != nil {
 				errChan <- err
 				return
@@ -323,11 +555,19 @@ func (r *Router) RouteStream(ctx context.Context, req providers.ChatRequest, use
 					content = fmt.Sprintf("%v", c)
 				}
 
+				var toolCalls []providers.ToolCallDelta
+				for i, call := range resp.Choices[0].Message.ToolCalls {
+					toolCalls = append(toolCalls, providers.ToolCallDelta{Index: i, ID: call.ID, Type: call.Type, Function: call.Function})
+				}
+
 				chunkChan <- providers.StreamChunk{
-					ID:      resp.ID,
-					Content: content,
-					Done:    true,
-					Usage:   &resp.Usage,
+					ID:           resp.ID,
+					Content:      content,
+					Done:         true,
+					Usage:        &resp.Usage,
+					Model:        req.Model,
+					ToolCalls:    toolCalls,
+					FinishReason: resp.Choices[0].FinishReason,
 				}
 			}
 			return
@@ -343,62 +583,197 @@ func (r *Router) RouteStream(ctx context.Context, req providers.ChatRequest, use
 				}
 			}
 		}
+		r.streamTargets(ctx, req, routeTargets(providersToTry, req), chunkChan, errChan)
+	}()
 
-		var lastErr error
-		for _, provider := range providersToTry {
-			streamingProvider, ok := provider.(providers.StreamingProvider)
-			if !ok {
-				continue
-			}
-			streamChunks, streamErrs := streamingProvider.ChatStream(ctx, req)
+	return chunkChan, errChan
+}
+
+// streamTargets streams from the first target that produces output, retrying and
+// failing over only until the first chunk has been sent.
+func (r *Router) streamTargets(ctx context.Context, req providers.ChatRequest, targets []routeTarget, chunkChan chan<- providers.StreamChunk, errChan chan<- error) {
+	if needs := providers.RequiredCapabilities(req); needs != (providers.Capabilities{}) {
+		targets = r.capableTargets(targets, needs)
+		if len(targets) == 0 {
+			errChan <- capabilityError(req.Model, needs)
+			return
+		}
+	}
+
+	var lastErr error
+	promptTokens := make(map[string]int)
+providerLoop:
+	for _, target := range targets {
+		provider := target.provider
+		if _, ok := provider.(providers.StreamingProvider); !ok {
+			continue
+		}
+		if err := r.checkTokenLimits(provider.Name(), target.req, promptTokens); err != nil {
+			lastErr = err
+			continue
+		}
+		breaker := r.breakerFor(provider)
+		if breaker != nil && !breaker.Allow() {
+			lastErr = fmt.Errorf("circuit breaker open for provider %s", provider.Name())
+			continue
+		}
+		// Retries are only possible until the first chunk reaches the caller.
+		for attempt := 0; ; attempt++ {
+			streamChunks, streamErrs := r.providerChatStream(ctx, provider, target.req)
 			sentAnyChunk := false
+			var completion strings.Builder
+			var streamErr error
 		readLoop:
 			for {
 				select {
 				case chunk, ok := <-streamChunks:
 					if !ok {
+						// Pick up an error sent just before the provider closed its channels.
+						if streamErrs != nil {
+							select {
+							case err, ok := <-streamErrs:
+								if ok && err != nil {
+									lastErr = err
+									streamErr = err
+								}
+							default:
+							}
+						}
 						break readLoop
 					}
 					chunk.Provider = provider.Name()
+					completion.WriteString(chunk.Content)
+					for _, call := range chunk.ToolCalls {
+						completion.WriteString(call.Function.Name)
+						completion.WriteString(call.Function.Arguments)
+					}
+					if chunk.Done && chunk.Usage == nil {
+						chunk.Usage = r.estimateUsage(target.req, completion.String())
+					}
+					if chunk.Done && chunk.Model == "" {
+						chunk.Model = target.req.Model
+					}
 					chunkChan <- chunk
 					sentAnyChunk = true
 					if chunk.Done {
+						recordBreakerOutcome(breaker, nil)
 						return
 					}
 				case err, ok := <-streamErrs:
 					if !ok {
-						break readLoop
+						// Providers close errs before chunks; keep draining buffered chunks.
+						streamErrs = nil
+						continue
 					}
 					lastErr = err
+					streamErr = err
 					break readLoop
 				case <-ctx.Done():
+					recordBreakerOutcome(breaker, ctx.Err())
 					errChan <- ctx.Err()
 					return
 				}
 			}
 
+			if streamErr == nil && !sentAnyChunk {
+				streamErr = fmt.Errorf("stream ended with no response from %s", provider.Name())
+			}
+			recordBreakerOutcome(breaker, streamErr)
 			if sentAnyChunk {
-				chunkChan <- providers.StreamChunk{Content: "", Done: true, Provider: provider.Name()}
+				chunkChan <- providers.StreamChunk{Content: "", Done: true, Provider: provider.Name(), Model: target.req.Model, Usage: r.estimateUsage(target.req, completion.String()), Incomplete: streamErr != nil}
+				return
+			}
+			lastErr = streamErr
+			if isClientError(streamErr) {
+				errChan <- streamErr
 				return
 			}
-			if lastErr == nil {
-				lastErr = fmt.Errorf("stream ended with no response from %s", provider.Name())
+			if !r.waitForRetry(ctx, provider.Name(), breaker, streamErr, attempt) {
+				continue providerLoop
 			}
 		}
-		if lastErr != nil {
-			errChan <- fmt.Errorf("all providers failed, last error: %w", lastErr)
-		} else {
-			errChan <- fmt.Errorf("no streaming providers available")
+	}
+	if lastErr != nil {
+		errChan <- failoverError(lastErr)
+	} else {
+		errChan <- fmt.Errorf("no streaming providers available")
+	}
+}
+
+// RouteEmbedding sends req to the provider serving req.Model. There is no
+// cross-provider failover: vectors from different models are not comparable.
+func (r *Router) RouteEmbedding(ctx context.Context, req providers.EmbeddingRequest, userID *uuid.UUID) (*providers.EmbeddingResponse, error) {
+	availableProviders := r.getAvailableProviders(ctx, userID)
+	embedders := make([]providers.Provider, 0, len(availableProviders))
+	for _, provider := range availableProviders {
+		if _, ok := provider.(providers.EmbeddingProvider); ok {
+			embedders = append(embedders, provider)
 		}
-	}()
+	}
+	if len(embedders) == 0 {
+		return nil, fmt.Errorf("no embedding providers available")
+	}
 
-	return chunkChan, errChan
+	provider := selectEmbeddingProvider(req.Model, embedders)
+	if provider == nil {
+		return nil, fmt.Errorf("no provider serves embedding model %s", req.Model)
+	}
+
+	start := time.Now()
+	resp, err := provider.(providers.EmbeddingProvider).Embed(ctx, req)
+	latency := time.Since(start)
+	r.latencyTracker.RecordLatency(provider.Name(), latency)
+	if err != nil {
+		return nil, err
+	}
+	resp.Provider = provider.Name()
+	resp.LatencyMs = latency.Milliseconds()
+	if resp.Usage.TotalTokens > 0 {
+		resp.Cost = r.costCalculator.CalculateActualCost(provider.Name(), req.Model, resp.Usage)
+	}
+	return resp, nil
+}
+
+// Ollama names models "name:tag" and vLLM serves Hugging Face "org/name" repositories.
+func isOllamaStyleModel(modelLower string) bool {
+	return strings.Contains(modelLower, ":")
+}
+
+func isVLLMStyleModel(modelLower string) bool {
+	return strings.Contains(modelLower, "/")
+}
+
+func selectEmbeddingProvider(model string, embedders []providers.Provider) providers.Provider {
+	modelLower := strings.ToLower(model)
+	for _, provider := range embedders {
+		for _, m := range provider.(providers.EmbeddingProvider).GetEmbeddingModels() {
+			m = strings.ToLower(m)
+			if m == modelLower || m == modelLower+":latest" {
+				return provider
+			}
+		}
+	}
+	var fallback string
+	switch {
+	case isOllamaStyleModel(modelLower):
+		fallback = "local"
+	case isVLLMStyleModel(modelLower):
+		fallback = "vllm"
+	}
+	if fallback != "" {
+		for _, provider := range embedders {
+			if provider.Name() == fallback {
+				return provider
+			}
+		}
+	}
+	return nil
 }
 
 func (r *Router) getAllProviders() []providers.Provider {
-	out := make([]providers.Provider, 0, len(r.providers))
-	for _, p := range r.providers {
-		out = append(out, p)
+	out := make([]providers.Provider, 0, len(r.providerNames))
+	for _, name := range r.providerNames {
+		out = append(out, r.providers[name])
 	}
 	return out
 }
@@ -406,13 +781,13 @@ func (r *Router) getAllProviders() []providers.Provider {
 func (r *Router) getAvailableProviders(ctx context.Context, userID *uuid.UUID) []providers.Provider {
 	seen := make(map[string]bool)
 	available := make([]providers.Provider, 0)
-	addWithHealth := func(p providers.Provider) {
+	addIfAvailable := func(p providers.Provider) {
 		name := p.Name()
 		if seen[name] {
 			return
 		}
 		seen[name] = true
-		if err := p.HealthCheck(ctx); err == nil {
+		if r.breakers.Get(name).Available() {
 			available = append(available, p)
 		}
 	}
@@ -429,12 +804,42 @@ func (r *Router) getAvailableProviders(ctx context.Context, userID *uuid.UUID) [
 			addTrusted(p)
 		}
 	}
-	for _, p := range r.providers {
-		addWithHealth(p)
+	for _, p := range r.getAllProviders() {
+		if r.IsProviderEnabled(p.Name()) {
+			addIfAvailable(p)
+		}
 	}
 	return available
 }
 
+// breakerFor returns nil for BYOK providers, so a user's bad key cannot trip a shared breaker.
+func (r *Router) breakerFor(p providers.Provider) *CircuitBreaker {
+	if registered, ok := r.providers[p.Name()]; !ok || registered != p {
+		return nil
+	}
+	return r.breakers.Get(p.Name())
+}
+
+func recordBreakerOutcome(breaker *CircuitBreaker, err error) {
+	if breaker == nil {
+		return
+	}
+	// A rejected request or an unknown model still means the provider answered.
+	if err == nil || isClientError(err) || isNotFound(err) {
+		breaker.RecordSuccess()
+	} else {
+		breaker.RecordFailure(err)
+	}
+}
+
+// ProviderHealth returns the circuit breaker state of a registered provider.
+func (r *Router) ProviderHealth(name string) (BreakerSnapshot, error) {
+	if _, ok := r.providers[name]; !ok {
+		return BreakerSnapshot{}, errors.ErrProviderNotFound
+	}
+	return r.breakers.Get(name).Snapshot(), nil
+}
+
 func (r *Router) providerInList(list []providers.Provider, name string) bool {
 	for _, p := range list {
 		if p.Name() == name {
@@ -453,11 +858,43 @@ func (r *Router) providerFromListByName(list []providers.Provider, name string)
 	return nil
 }
 
+// byokProviders accept a user's own API key (BYOK) in place of the server's.
+var byokProviders = []string{"openai", "anthropic", "google", "azure"}
+
+// SetCompatibleBYOK lets users bring their own key for OpenAI-compatible upstreams
+// declared in config with byok set.
+func (r *Router) SetCompatibleBYOK(configs []providers.OpenAICompatibleConfig) {
+	for _, config := range configs {
+		r.compatibleBYOK[config.Name] = config
+	}
+}
+
+// CompatibleBYOK returns the OpenAI-compatible upstreams that accept BYOK keys.
+func (r *Router) CompatibleBYOK() []providers.OpenAICompatibleConfig {
+	out := make([]providers.OpenAICompatibleConfig, 0, len(r.compatibleBYOK))
+	for _, config := range r.compatibleBYOK {
+		out = append(out, config)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// byokProviderNames returns the built-in BYOK providers followed by the compatible ones.
+func (r *Router) byokProviderNames() []string {
+	names := append([]string{}, byokProviders...)
+	for _, config := range r.CompatibleBYOK() {
+		names = append(names, config.Name)
+	}
+	return names
+}
+
 func (r *Router) getUserProviders(ctx context.Context, userID uuid.UUID) []providers.Provider {
 	userProviders := make([]providers.Provider, 0)
-	providersToCheck := []string{"openai", "anthropic", "google"}
-	for _, providerName := range providersToCheck {
-		apiKey, err := r.providerKeyService.GetProviderKey(ctx, userID, providerName)
+	for _, providerName := range r.byokProviderNames() {
+		if !r.IsProviderEnabled(providerName) {
+			continue
+		}
+		apiKey, metadata, err := r.userProviderKey(ctx, userID, providerName)
 		if err != nil || apiKey == "" {
 			continue
 		}
@@ -469,9 +906,19 @@ func (r *Router) getUserProviders(ctx context.Context, userID uuid.UUID) []provi
 			provider = providers.NewAnthropicProvider(apiKey, "", zerolog.Nop())
 		case "google":
 			provider = providers.NewGoogleProvider(apiKey, "", zerolog.Nop())
+		case "azure":
+			// Azure keys are only usable with the endpoint stored alongside them.
+			if config, err := providers.ParseAzureOpenAIMetadata(metadata); err == nil {
+				provider = providers.NewAzureOpenAIProvider(apiKey, config, zerolog.Nop())
+			}
+		default:
+			if config, ok := r.compatibleBYOK[providerName]; ok {
+				provider = providers.NewOpenAICompatibleProvider(config, apiKey, zerolog.Nop())
+			}
 		}
 
 		if provider != nil {
+			r.useModelCatalog(provider)
 			userProviders = append(userProviders, provider)
 		}
 	}
@@ -479,6 +926,15 @@ func (r *Router) getUserProviders(ctx context.Context, userID uuid.UUID) []provi
 	return userProviders
 }
 
+// userProviderKey returns a user's key and, when the key service stores them, its settings.
+func (r *Router) userProviderKey(ctx context.Context, userID uuid.UUID, provider string) (string, []byte, error) {
+	if service, ok := r.providerKeyService.(ProviderKeyMetadataInterface); ok {
+		return service.GetProviderKeyWithMetadata(ctx, userID, provider)
+	}
+	apiKey, err := r.providerKeyService.GetProviderKey(ctx, userID, provider)
+	return apiKey, nil, err
+}
+
 func (r *Router) UserHasProviderKey(ctx context.Context, userID uuid.UUID, provider string) bool {
 	if r.providerKeyService == nil {
 		return false
@@ -554,7 +1010,9 @@ func (r *Router) GetProvidersForUser(ctx context.Context, userID *uuid.UUID) []P
 		}
 	}
 	for _, p := range r.providers {
-		add(p)
+		if r.IsProviderEnabled(p.Name()) {
+			add(p)
+		}
 	}
 	return out
 }
@@ -569,11 +1027,18 @@ func (r *Router) ListProviderDetailsForUser(ctx context.Context, userID *uuid.UU
 			return
 		}
 		seen[name] = true
-		healthy := p.HealthCheck(ctx) == nil
+		healthy := false
+		if breaker := r.breakerFor(p); breaker != nil {
+			healthy = breaker.Available()
+		} else {
+			healthy = p.HealthCheck(ctx) == nil
+		}
+		models := p.GetModels()
 		out = append(out, map[string]interface{}{
-			"name":    name,
-			"healthy": healthy,
-			"models":  p.GetModels(),
+			"name":         name,
+			"healthy":      healthy,
+			"models":       models,
+			"capabilities": r.ModelCapabilities(p, models),
 		})
 	}
 
@@ -583,7 +1048,9 @@ func (r *Router) ListProviderDetailsForUser(ctx context.Context, userID *uuid.UU
 		}
 	}
 	for _, p := range r.providers {
-		add(p)
+		if r.IsProviderEnabled(p.Name()) {
+			add(p)
+		}
 	}
 	return out
 }
@@ -592,6 +1059,18 @@ func (r *Router) GetCostCalculator() *CostCalculator {
 	return r.costCalculator
 }
 
+func (r *Router) SetPricingLoader(loader *PricingLoader) {
+	r.pricingLoader = loader
+}
+
+func (r *Router) GetPricingLoader() *PricingLoader {
+	return r.pricingLoader
+}
+
+func (r *Router) GetRuleEngine() *RuleEngine {
+	return r.ruleEngine
+}
+
 func (r *Router) GetLatencyTracker() *LatencyTracker {
 	return r.latencyTracker
 }
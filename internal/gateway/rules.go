@@ -0,0 +1,664 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+)
+
+// Condition types of custom routing rules. and, or and not combine the conditions
+// listed in their condition_value.
+const (
+	ConditionAnd               = "and"
+	ConditionOr                = "or"
+	ConditionNot               = "not"
+	ConditionModel             = "model"
+	ConditionCostThreshold     = "cost_threshold"
+	ConditionLatencyThreshold  = "latency_threshold"
+	ConditionPromptLength      = "prompt_length"
+	ConditionTokenEstimate     = "token_estimate"
+	ConditionHasImage          = "has_image"
+	ConditionHasAudio          = "has_audio"
+	ConditionSystemPromptRegex = "system_prompt_regex"
+	ConditionAPIKey            = "api_key"
+	ConditionUserRole          = "user_role"
+	ConditionHeader            = "header"
+	ConditionTimeOfDay         = "time_of_day"
+	ConditionWebSearch         = "web_search"
+)
+
+const maxRuleDepth = 8
+
+// RequestAttributes describe who sent a request, for rules that match on more than
+// the request body.
+type RequestAttributes struct {
+	APIKeyID  string
+	UserRoles []string
+	Headers   http.Header
+}
+
+type requestAttributesKey struct{}
+
+func WithRequestAttributes(ctx context.Context, attrs RequestAttributes) context.Context {
+	return context.WithValue(ctx, requestAttributesKey{}, attrs)
+}
+
+func requestAttributesFromContext(ctx context.Context) RequestAttributes {
+	attrs, _ := ctx.Value(requestAttributesKey{}).(RequestAttributes)
+	return attrs
+}
+
+// ConditionTrace is the result of one condition of a rule, with the results of the
+// conditions it combines.
+type ConditionTrace struct {
+	Type       string           `json:"type"`
+	Matched    bool             `json:"matched"`
+	Detail     string           `json:"detail,omitempty"`
+	Conditions []ConditionTrace `json:"conditions,omitempty"`
+}
+
+// RuleTrace is the evaluation of one custom routing rule.
+type RuleTrace struct {
+	Name      string          `json:"name,omitempty"`
+	Provider  string          `json:"provider"`
+	Priority  int             `json:"priority"`
+	Matched   bool            `json:"matched"`
+	Selected  bool            `json:"selected"`
+	Reason    string          `json:"reason,omitempty"`
+	Condition *ConditionTrace `json:"condition,omitempty"`
+}
+
+// RoutingTrace records how the router picked a provider for a request.
+type RoutingTrace struct {
+	Strategy       StrategyType `json:"strategy,omitempty"`
+	StrategySource string       `json:"strategy_source,omitempty"`
+	Alias          string       `json:"alias,omitempty"`
+	Rules          []RuleTrace  `json:"rules,omitempty"`
+	Provider       string       `json:"provider,omitempty"`
+}
+
+type routingTraceKey struct{}
+
+// WithRoutingTrace returns a context in which routing decisions are recorded on the
+// returned trace. The trace must not be read before routing has finished.
+func WithRoutingTrace(ctx context.Context) (context.Context, *RoutingTrace) {
+	trace := &RoutingTrace{}
+	return context.WithValue(ctx, routingTraceKey{}, trace), trace
+}
+
+func routingTraceFromContext(ctx context.Context) *RoutingTrace {
+	trace, _ := ctx.Value(routingTraceKey{}).(*RoutingTrace)
+	return trace
+}
+
+func (t *RoutingTrace) setStrategy(strategy StrategyType, source string) {
+	if t != nil {
+		t.Strategy = strategy
+		t.StrategySource = source
+	}
+}
+
+func (t *RoutingTrace) setAlias(alias string) {
+	if t != nil {
+		t.Alias = alias
+	}
+}
+
+func (t *RoutingTrace) setProvider(provider string) {
+	if t != nil {
+		t.Provider = provider
+	}
+}
+
+func (t *RoutingTrace) addRule(rule RuleTrace) {
+	if t != nil {
+		t.Rules = append(t.Rules, rule)
+	}
+}
+
+// RuleEngine compiles custom routing rules into RoutingRules. Cost and latency
+// conditions are evaluated against the rule's provider.
+type RuleEngine struct {
+	costCalculator *CostCalculator
+	latencyTracker *LatencyTracker
+}
+
+func NewRuleEngine(costCalculator *CostCalculator, latencyTracker *LatencyTracker) *RuleEngine {
+	return &RuleEngine{
+		costCalculator: costCalculator,
+		latencyTracker: latencyTracker,
+	}
+}
+
+// ValidateRuleCondition reports why a condition would not compile.
+func ValidateRuleCondition(conditionType string, value map[string]interface{}) error {
+	_, err := compileCondition(conditionType, value, 1)
+	return err
+}
+
+// Compile turns rule into a RoutingRule whose Match explains its result.
+func (e *RuleEngine) Compile(rule CustomRule) (RoutingRule, error) {
+	condition, err := compileCondition(rule.ConditionType, rule.ConditionValue, 1)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	return RoutingRule{
+		Name:     rule.Name,
+		Provider: rule.ProviderName,
+		Priority: rule.Priority,
+		Match: func(ctx context.Context, req providers.ChatRequest) ConditionTrace {
+			return condition.evaluate(&ruleInput{
+				engine:       e,
+				req:          req,
+				provider:     rule.ProviderName,
+				attrs:        requestAttributesFromContext(ctx),
+				promptTokens: -1,
+			})
+		},
+	}, nil
+}
+
+// BuildRoutingRules compiles rules in order. Rules saved before validation existed
+// may not compile; they never match and their trace says why.
+func (e *RuleEngine) BuildRoutingRules(rules []CustomRule) []RoutingRule {
+	routingRules := make([]RoutingRule, 0, len(rules))
+	for _, rule := range rules {
+		routingRule, err := e.Compile(rule)
+		if err != nil {
+			invalid := ConditionTrace{Type: rule.ConditionType, Detail: "invalid rule: " + err.Error()}
+			routingRule = RoutingRule{
+				Name:     rule.Name,
+				Provider: rule.ProviderName,
+				Priority: rule.Priority,
+				Match: func(context.Context, providers.ChatRequest) ConditionTrace {
+					return invalid
+				},
+			}
+		}
+		routingRules = append(routingRules, routingRule)
+	}
+	return routingRules
+}
+
+type ruleInput struct {
+	engine       *RuleEngine
+	req          providers.ChatRequest
+	provider     string
+	attrs        RequestAttributes
+	promptTokens int
+}
+
+func (in *ruleInput) tokens() int {
+	if in.promptTokens < 0 {
+		in.promptTokens = CountPromptTokens(in.engine.costCalculator.tokenizers.ForModel(in.req.Model), in.req).Total
+	}
+	return in.promptTokens
+}
+
+type ruleCondition struct {
+	conditionType string
+	children      []*ruleCondition
+	match         func(in *ruleInput) (bool, string)
+}
+
+// evaluate runs every condition, without short-circuiting, so the trace is complete.
+func (c *ruleCondition) evaluate(in *ruleInput) ConditionTrace {
+	trace := ConditionTrace{Type: c.conditionType}
+	switch c.conditionType {
+	case ConditionAnd, ConditionOr:
+		trace.Matched = c.conditionType == ConditionAnd
+		for _, child := range c.children {
+			result := child.evaluate(in)
+			if c.conditionType == ConditionAnd {
+				trace.Matched = trace.Matched && result.Matched
+			} else {
+				trace.Matched = trace.Matched || result.Matched
+			}
+			trace.Conditions = append(trace.Conditions, result)
+		}
+	case ConditionNot:
+		result := c.children[0].evaluate(in)
+		trace.Matched = !result.Matched
+		trace.Conditions = []ConditionTrace{result}
+	default:
+		trace.Matched, trace.Detail = c.match(in)
+	}
+	return trace
+}
+
+func compileCondition(conditionType string, value map[string]interface{}, depth int) (*ruleCondition, error) {
+	if depth > maxRuleDepth {
+		return nil, fmt.Errorf("conditions are nested more than %d levels deep", maxRuleDepth)
+	}
+	c := &ruleCondition{conditionType: conditionType}
+	var err error
+	switch conditionType {
+	case ConditionAnd, ConditionOr:
+		c.children, err = compileChildren(value, depth)
+	case ConditionNot:
+		var child *ruleCondition
+		if child, err = compileNested(value["condition"], depth); err != nil {
+			err = fmt.Errorf("condition: %w", err)
+		}
+		c.children = []*ruleCondition{child}
+	case ConditionModel:
+		c.match, err = compileModel(value)
+	case ConditionCostThreshold:
+		c.match, err = compileCostThreshold(value)
+	case ConditionLatencyThreshold:
+		c.match, err = compileLatencyThreshold(value)
+	case ConditionPromptLength:
+		c.match, err = compilePromptLength(value)
+	case ConditionTokenEstimate:
+		c.match, err = compileTokenEstimate(value)
+	case ConditionHasImage:
+		c.match = func(in *ruleInput) (bool, string) {
+			return hasContentPart(in.req, "image_url"), ""
+		}
+	case ConditionHasAudio:
+		c.match = func(in *ruleInput) (bool, string) {
+			return hasContentPart(in.req, "audio_url"), ""
+		}
+	case ConditionSystemPromptRegex:
+		c.match, err = compileSystemPromptRegex(value)
+	case ConditionAPIKey:
+		c.match, err = compileAPIKey(value)
+	case ConditionUserRole:
+		c.match, err = compileUserRole(value)
+	case ConditionHeader:
+		c.match, err = compileHeader(value)
+	case ConditionTimeOfDay:
+		c.match, err = compileTimeOfDay(value)
+	case ConditionWebSearch:
+		c.match, err = compileWebSearch(value)
+	case "":
+		return nil, fmt.Errorf("condition_type is required")
+	default:
+		return nil, fmt.Errorf("unknown condition_type %q", conditionType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", conditionType, err)
+	}
+	return c, nil
+}
+
+func compileChildren(value map[string]interface{}, depth int) ([]*ruleCondition, error) {
+	raw, ok := value["conditions"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("conditions must be a non-empty list")
+	}
+	children := make([]*ruleCondition, 0, len(raw))
+	for i, item := range raw {
+		child, err := compileNested(item, depth)
+		if err != nil {
+			return nil, fmt.Errorf("conditions[%d]: %w", i, err)
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// compileNested compiles {"condition_type": ..., "condition_value": {...}}.
+func compileNested(raw interface{}, depth int) (*ruleCondition, error) {
+	nested, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an object with condition_type and condition_value")
+	}
+	conditionType, _ := nested["condition_type"].(string)
+	value, _ := nested["condition_value"].(map[string]interface{})
+	return compileCondition(conditionType, value, depth+1)
+}
+
+func compileModel(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	model, err := requiredString(value, "model")
+	if err != nil {
+		return nil, err
+	}
+	return func(in *ruleInput) (bool, string) {
+		return in.req.Model == model, "model " + in.req.Model
+	}, nil
+}
+
+func compileCostThreshold(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	maxCost, err := requiredNumber(value, "max_cost")
+	if err != nil {
+		return nil, err
+	}
+	return func(in *ruleInput) (bool, string) {
+		cost := in.engine.costCalculator.EstimateCost(in.provider, in.req)
+		return cost <= maxCost, fmt.Sprintf("estimated cost $%.6f on %s", cost, in.provider)
+	}, nil
+}
+
+func compileLatencyThreshold(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	maxLatencyMs, err := requiredNumber(value, "max_latency_ms")
+	if err != nil {
+		return nil, err
+	}
+	return func(in *ruleInput) (bool, string) {
+		latency := in.engine.latencyTracker.GetAverageLatency(in.provider).Milliseconds()
+		return latency <= int64(maxLatencyMs), fmt.Sprintf("average latency %dms on %s", latency, in.provider)
+	}, nil
+}
+
+func compilePromptLength(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	within, err := numberRange(value, "min_chars", "max_chars")
+	if err != nil {
+		return nil, err
+	}
+	return func(in *ruleInput) (bool, string) {
+		length := promptLength(in.req)
+		return within(float64(length)), fmt.Sprintf("%d characters", length)
+	}, nil
+}
+
+func compileTokenEstimate(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	within, err := numberRange(value, "min_tokens", "max_tokens")
+	if err != nil {
+		return nil, err
+	}
+	return func(in *ruleInput) (bool, string) {
+		tokens := in.tokens()
+		return within(float64(tokens)), fmt.Sprintf("about %d prompt tokens", tokens)
+	}, nil
+}
+
+func compileSystemPromptRegex(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	pattern, err := requiredString(value, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return func(in *ruleInput) (bool, string) {
+		for _, msg := range in.req.Messages {
+			if msg.Role == "system" && re.MatchString(messageText(msg)) {
+				return true, ""
+			}
+		}
+		return false, ""
+	}, nil
+}
+
+func compileAPIKey(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	ids, err := stringList(value, "api_key_id", "api_key_ids")
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("invalid API key ID %q", id)
+		}
+	}
+	return func(in *ruleInput) (bool, string) {
+		for _, id := range ids {
+			if strings.EqualFold(id, in.attrs.APIKeyID) {
+				return true, ""
+			}
+		}
+		return false, ""
+	}, nil
+}
+
+func compileUserRole(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	roles, err := stringList(value, "role", "roles")
+	if err != nil {
+		return nil, err
+	}
+	return func(in *ruleInput) (bool, string) {
+		for _, role := range roles {
+			for _, userRole := range in.attrs.UserRoles {
+				if role == userRole {
+					return true, ""
+				}
+			}
+		}
+		return false, "roles " + strings.Join(in.attrs.UserRoles, ",")
+	}, nil
+}
+
+// compileHeader matches a header by exact value, by pattern, or by presence when
+// neither is given.
+func compileHeader(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	name, err := requiredString(value, "name")
+	if err != nil {
+		return nil, err
+	}
+	expected, hasValue, err := optionalString(value, "value")
+	if err != nil {
+		return nil, err
+	}
+	pattern, hasPattern, err := optionalString(value, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	if hasValue && hasPattern {
+		return nil, fmt.Errorf("set value or pattern, not both")
+	}
+	var re *regexp.Regexp
+	if hasPattern {
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	return func(in *ruleInput) (bool, string) {
+		values := in.attrs.Headers.Values(name)
+		for _, v := range values {
+			switch {
+			case hasValue:
+				if v == expected {
+					return true, ""
+				}
+			case re != nil:
+				if re.MatchString(v) {
+					return true, ""
+				}
+			default:
+				return true, ""
+			}
+		}
+		if len(values) == 0 {
+			return false, name + " not set"
+		}
+		return false, ""
+	}, nil
+}
+
+// compileTimeOfDay matches from start up to end, "HH:MM" in timezone (UTC by
+// default). A window whose end is before its start spans midnight.
+func compileTimeOfDay(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	start, err := clockValue(value, "start")
+	if err != nil {
+		return nil, err
+	}
+	end, err := clockValue(value, "end")
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+	location := time.UTC
+	if name, ok, err := optionalString(value, "timezone"); err != nil {
+		return nil, err
+	} else if ok {
+		if location, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q", name)
+		}
+	}
+	return func(in *ruleInput) (bool, string) {
+		now := time.Now().In(location)
+		minute := now.Hour()*60 + now.Minute()
+		if start < end {
+			return minute >= start && minute < end, now.Format("15:04 MST")
+		}
+		return minute >= start || minute < end, now.Format("15:04 MST")
+	}, nil
+}
+
+func compileWebSearch(value map[string]interface{}) (func(*ruleInput) (bool, string), error) {
+	enabled := true
+	if raw, ok := value["enabled"]; ok {
+		if enabled, ok = raw.(bool); !ok {
+			return nil, fmt.Errorf("enabled must be a boolean")
+		}
+	}
+	return func(in *ruleInput) (bool, string) {
+		return (in.req.WebSearch || in.req.GoogleSearchGrounding) == enabled, ""
+	}, nil
+}
+
+func messageText(msg providers.Message) string {
+	text, parts := providers.NormalizeMessageContent(msg.Content)
+	if msg.Content == nil {
+		text = ""
+	}
+	for _, part := range parts {
+		if part.Type == "text" {
+			text += part.Text
+		}
+	}
+	return text
+}
+
+func promptLength(req providers.ChatRequest) int {
+	length := 0
+	for _, msg := range req.Messages {
+		length += utf8.RuneCountInString(messageText(msg))
+	}
+	return length
+}
+
+func hasContentPart(req providers.ChatRequest, partType string) bool {
+	for _, msg := range req.Messages {
+		_, parts := providers.NormalizeMessageContent(msg.Content)
+		for _, part := range parts {
+			if part.Type == partType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func requiredString(value map[string]interface{}, key string) (string, error) {
+	s, ok, err := optionalString(value, key)
+	if err != nil {
+		return "", err
+	}
+	if !ok || s == "" {
+		return "", fmt.Errorf("%s is required", key)
+	}
+	return s, nil
+}
+
+func optionalString(value map[string]interface{}, key string) (string, bool, error) {
+	raw, ok := value[key]
+	if !ok || raw == nil {
+		return "", false, nil
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return "", false, fmt.Errorf("%s must be a string", key)
+	}
+	return s, true, nil
+}
+
+// stringList accepts a single string under singleKey or a list under listKey.
+func stringList(value map[string]interface{}, singleKey, listKey string) ([]string, error) {
+	var list []string
+	if s, ok, err := optionalString(value, singleKey); err != nil {
+		return nil, err
+	} else if ok && s != "" {
+		list = append(list, s)
+	}
+	if raw, ok := value[listKey]; ok && raw != nil {
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of strings", listKey)
+		}
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("%s must be a list of strings", listKey)
+			}
+			list = append(list, s)
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%s or %s is required", singleKey, listKey)
+	}
+	return list, nil
+}
+
+func optionalNumber(value map[string]interface{}, key string) (float64, bool, error) {
+	switch n := value[key].(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		return n, true, nil
+	case int:
+		return float64(n), true, nil
+	case int64:
+		return float64(n), true, nil
+	default:
+		return 0, false, fmt.Errorf("%s must be a number", key)
+	}
+}
+
+func requiredNumber(value map[string]interface{}, key string) (float64, error) {
+	n, ok, err := optionalNumber(value, key)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%s is required", key)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", key)
+	}
+	return n, nil
+}
+
+// numberRange returns an inclusive bounds check; at least one bound must be set.
+func numberRange(value map[string]interface{}, minKey, maxKey string) (func(float64) bool, error) {
+	lo, hasLo, err := optionalNumber(value, minKey)
+	if err != nil {
+		return nil, err
+	}
+	hi, hasHi, err := optionalNumber(value, maxKey)
+	if err != nil {
+		return nil, err
+	}
+	if !hasLo && !hasHi {
+		return nil, fmt.Errorf("%s or %s is required", minKey, maxKey)
+	}
+	if hasLo && hasHi && lo > hi {
+		return nil, fmt.Errorf("%s is greater than %s", minKey, maxKey)
+	}
+	return func(n float64) bool {
+		return (!hasLo || n >= lo) && (!hasHi || n <= hi)
+	}, nil
+}
+
+// clockValue parses "HH:MM" into minutes since midnight.
+func clockValue(value map[string]interface{}, key string) (int, error) {
+	s, err := requiredString(value, key)
+	if err != nil {
+		return 0, err
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be HH:MM", key)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
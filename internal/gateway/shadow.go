@@ -0,0 +1,352 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/pii"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultShadowMaxPerMinute applies to shadow rules without max_per_minute.
+	DefaultShadowMaxPerMinute = 60
+	// maxConcurrentShadows bounds the shadow calls in flight across all rules; requests
+	// sampled while every slot is busy are not mirrored.
+	maxConcurrentShadows = 16
+	shadowTimeout        = 2 * time.Minute
+)
+
+// ShadowRule mirrors SampleRate (0-1) of the non-streaming requests for Model to
+// Provider, at most MaxPerMinute times a minute. The shadow request uses TargetModel,
+// or Model when empty. With EmbeddingModel set, the two responses are also compared by
+// the cosine similarity of their embeddings.
+type ShadowRule struct {
+	Model          string  `json:"model" yaml:"model"`
+	Provider       string  `json:"provider" yaml:"provider"`
+	TargetModel    string  `json:"target_model,omitempty" yaml:"target_model,omitempty"`
+	SampleRate     float64 `json:"sample_rate" yaml:"sample_rate"`
+	MaxPerMinute   int     `json:"max_per_minute,omitempty" yaml:"max_per_minute,omitempty"`
+	EmbeddingModel string  `json:"embedding_model,omitempty" yaml:"embedding_model,omitempty"`
+}
+
+func (r ShadowRule) Validate() error {
+	if strings.TrimSpace(r.Model) == "" {
+		return fmt.Errorf("model is required")
+	}
+	if strings.TrimSpace(r.Provider) == "" {
+		return fmt.Errorf("provider is required")
+	}
+	if r.SampleRate <= 0 || r.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be greater than 0 and at most 1")
+	}
+	if r.MaxPerMinute < 0 {
+		return fmt.Errorf("max_per_minute must not be negative")
+	}
+	return nil
+}
+
+// ShadowConfig is the shadow traffic file (SHADOW_CONFIG_PATH).
+type ShadowConfig struct {
+	Shadows []ShadowRule `json:"shadows" yaml:"shadows"`
+}
+
+// ParseShadowConfig parses a JSON (.json) or YAML shadow traffic file.
+func ParseShadowConfig(name string, data []byte) (*ShadowConfig, error) {
+	var config ShadowConfig
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse shadow config %s: %w", name, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid shadow config %s: %w", name, err)
+	}
+	return &config, nil
+}
+
+func LoadShadowConfig(path string) (*ShadowConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shadow config: %w", err)
+	}
+	return ParseShadowConfig(path, data)
+}
+
+func (c *ShadowConfig) Validate() error {
+	seen := make(map[string]bool, len(c.Shadows))
+	for i, rule := range c.Shadows {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("shadow %d: %w", i, err)
+		}
+		model := strings.ToLower(rule.Model)
+		if seen[model] {
+			return fmt.Errorf("shadow %d: duplicate rule for model %s", i, rule.Model)
+		}
+		seen[model] = true
+	}
+	return nil
+}
+
+// ShadowResult pairs a live response with the shadow response to the same request.
+type ShadowResult struct {
+	ID              uuid.UUID
+	UserID          *uuid.UUID
+	Model           string // model requested by the caller
+	PrimaryProvider string
+	PrimaryModel    string
+	PrimaryContent  string
+	PrimaryUsage    providers.Usage
+	PrimaryCost     float64
+	PrimaryLatency  time.Duration
+	ShadowProvider  string
+	ShadowModel     string
+	ShadowContent   string
+	ShadowUsage     providers.Usage
+	ShadowCost      float64
+	ShadowLatency   time.Duration
+	ShadowError     string
+	Similarity      *float64 // cosine similarity of the two responses' embeddings
+	CreatedAt       time.Time
+}
+
+type ShadowRecorder interface {
+	RecordShadow(ctx context.Context, result ShadowResult) error
+}
+
+type shadowRule struct {
+	ShadowRule
+	mu     sync.Mutex
+	recent []time.Time // mirrored requests in the last minute
+}
+
+// allow reports whether another request may be mirrored in the current one-minute window.
+func (r *shadowRule) allow(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cutoff := now.Add(-time.Minute)
+	kept := r.recent[:0]
+	for _, t := range r.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	r.recent = kept
+	limit := r.MaxPerMinute
+	if limit == 0 {
+		limit = DefaultShadowMaxPerMinute
+	}
+	if len(r.recent) >= limit {
+		return false
+	}
+	r.recent = append(r.recent, now)
+	return true
+}
+
+// ShadowMirror copies sampled requests to candidate providers in the background and
+// records both responses. Mirroring never delays or changes the live response: the
+// shadow call starts after the live one has finished, and requests are dropped rather
+// than queued when the rate limit or concurrency cap is reached.
+type ShadowMirror struct {
+	rules    map[string]*shadowRule // by lower-case model
+	recorder ShadowRecorder
+	logger   zerolog.Logger
+	slots    chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewShadowMirror(config *ShadowConfig, recorder ShadowRecorder, logger zerolog.Logger) *ShadowMirror {
+	m := &ShadowMirror{
+		rules:    make(map[string]*shadowRule),
+		recorder: recorder,
+		logger:   logger,
+		slots:    make(chan struct{}, maxConcurrentShadows),
+	}
+	if config != nil {
+		for _, rule := range config.Shadows {
+			m.rules[strings.ToLower(rule.Model)] = &shadowRule{ShadowRule: rule}
+		}
+	}
+	return m
+}
+
+// Rules returns the configured shadow rules.
+func (m *ShadowMirror) Rules() []ShadowRule {
+	rules := make([]ShadowRule, 0, len(m.rules))
+	for _, rule := range m.rules {
+		rules = append(rules, rule.ShadowRule)
+	}
+	return rules
+}
+
+// Wait blocks until every shadow call started so far has been recorded.
+func (m *ShadowMirror) Wait() {
+	m.wg.Wait()
+}
+
+func (r *Router) SetShadowMirror(mirror *ShadowMirror) {
+	r.shadowMirror = mirror
+}
+
+func (r *Router) GetShadowMirror() *ShadowMirror {
+	return r.shadowMirror
+}
+
+// mirror sends req to the shadow provider configured for its model, if the request is
+// sampled. resp is the live response already returned by the primary route.
+func (r *Router) mirror(ctx context.Context, req providers.ChatRequest, resp *providers.ChatResponse, userID *uuid.UUID) {
+	m := r.shadowMirror
+	if m == nil {
+		return
+	}
+	rule, ok := m.rules[strings.ToLower(req.Model)]
+	if !ok || rand.Float64() >= rule.SampleRate {
+		return
+	}
+	if !rule.allow(time.Now()) {
+		monitoring.RecordShadowRequest(rule.Model, rule.Provider, "rate_limited")
+		return
+	}
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		monitoring.RecordShadowRequest(rule.Model, rule.Provider, "busy")
+		return
+	}
+
+	// Copy what is needed from the live response now; the caller owns it from here on.
+	result := ShadowResult{
+		ID:              uuid.New(),
+		UserID:          userID,
+		Model:           req.Model,
+		PrimaryProvider: resp.Provider,
+		PrimaryModel:    resp.Model,
+		PrimaryContent:  responseText(resp),
+		PrimaryUsage:    resp.Usage,
+		PrimaryCost:     resp.Cost,
+		PrimaryLatency:  time.Duration(resp.LatencyMs) * time.Millisecond,
+		ShadowProvider:  rule.Provider,
+		ShadowModel:     req.Model,
+		CreatedAt:       time.Now(),
+	}
+	// The shadow call outlives the request, but keeps its redaction policy.
+	shadowCtx := context.Background()
+	if policy, ok := redactionPolicyFromContext(ctx); ok {
+		shadowCtx = WithRedactionPolicy(shadowCtx, policy)
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() { <-m.slots }()
+		ctx, cancel := context.WithTimeout(shadowCtx, shadowTimeout)
+		defer cancel()
+		r.shadowCall(ctx, rule.ShadowRule, req, &result)
+		status := "success"
+		if result.ShadowError != "" {
+			status = "error"
+		}
+		monitoring.RecordShadowRequest(rule.Model, rule.Provider, status)
+		monitoring.RecordShadowCost(result.ShadowProvider, result.ShadowModel, result.ShadowCost)
+		if m.recorder == nil {
+			return
+		}
+		if err := m.recorder.RecordShadow(ctx, result); err != nil {
+			m.logger.Error().Err(err).Str("model", rule.Model).Str("shadow_provider", rule.Provider).Msg("Failed to record shadow request")
+		}
+	}()
+}
+
+// shadowCall sends the shadow request and fills in the shadow half of result.
+func (r *Router) shadowCall(ctx context.Context, rule ShadowRule, req providers.ChatRequest, result *ShadowResult) {
+	shadowReq := req
+	if rule.TargetModel != "" {
+		shadowReq.Model = rule.TargetModel
+		result.ShadowModel = rule.TargetModel
+	}
+
+	// The candidate is called directly: no failover, retries or circuit breaker, and its
+	// latency is kept out of the tracker that live routing uses.
+	provider, err := r.GetProvider(rule.Provider)
+	if err != nil {
+		result.ShadowError = fmt.Sprintf("provider %s is not registered", rule.Provider)
+		return
+	}
+	start := time.Now()
+	shadowResp, err := r.providerChat(ctx, provider, shadowReq)
+	result.ShadowLatency = time.Since(start)
+	if err != nil {
+		result.ShadowError = err.Error()
+		return
+	}
+	if shadowResp.Model != "" {
+		result.ShadowModel = shadowResp.Model
+	}
+	result.ShadowContent = responseText(shadowResp)
+	result.ShadowUsage = shadowResp.Usage
+	if shadowResp.Usage.TotalTokens > 0 {
+		result.ShadowCost = r.costCalculator.CalculateActualCost(provider.Name(), result.ShadowModel, shadowResp.Usage)
+	}
+
+	if rule.EmbeddingModel != "" && result.PrimaryContent != "" && result.ShadowContent != "" {
+		// Both responses have their PII restored; one redactor gives the same PII the
+		// same placeholder in each, so the comparison still holds.
+		input := []string{result.PrimaryContent, result.ShadowContent}
+		if policy, ok := redactionPolicyFromContext(ctx); ok {
+			redactor := pii.NewRedactor(policy.Types...)
+			for i := range input {
+				input[i] = redactor.Redact(input[i])
+			}
+		}
+		embeddings, err := r.RouteEmbedding(ctx, providers.EmbeddingRequest{
+			Model: rule.EmbeddingModel,
+			Input: input,
+		}, nil)
+		if err != nil {
+			r.shadowMirror.logger.Warn().Err(err).Str("embedding_model", rule.EmbeddingModel).Msg("Failed to embed shadow responses")
+		} else if len(embeddings.Embeddings) == 2 {
+			if similarity, ok := cosineSimilarity(embeddings.Embeddings[0], embeddings.Embeddings[1]); ok {
+				result.Similarity = &similarity
+			}
+		}
+	}
+}
+
+// responseText is the text of the first choice of resp.
+func responseText(resp *providers.ChatResponse) string {
+	if resp == nil || len(resp.Choices) == 0 {
+		return ""
+	}
+	return messageText(resp.Choices[0].Message)
+}
+
+func cosineSimilarity(a, b []float64) (float64, bool) {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0, false
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0, false
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), true
+}
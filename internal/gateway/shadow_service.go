@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+)
+
+type ShadowRecorderAdapter struct {
+	repo *storage.ShadowRequestRepository
+}
+
+func NewShadowRecorderAdapter(repo *storage.ShadowRequestRepository) *ShadowRecorderAdapter {
+	return &ShadowRecorderAdapter{repo: repo}
+}
+
+func (a *ShadowRecorderAdapter) RecordShadow(ctx context.Context, result ShadowResult) error {
+	record := &storage.ShadowRequest{
+		ID:                  result.ID,
+		UserID:              result.UserID,
+		Model:               result.Model,
+		PrimaryProvider:     result.PrimaryProvider,
+		PrimaryModel:        result.PrimaryModel,
+		PrimaryContent:      result.PrimaryContent,
+		PrimaryInputTokens:  result.PrimaryUsage.PromptTokens,
+		PrimaryOutputTokens: result.PrimaryUsage.CompletionTokens,
+		PrimaryCost:         result.PrimaryCost,
+		PrimaryLatencyMs:    int(result.PrimaryLatency.Milliseconds()),
+		ShadowProvider:      result.ShadowProvider,
+		ShadowModel:         result.ShadowModel,
+		ShadowContent:       result.ShadowContent,
+		ShadowInputTokens:   result.ShadowUsage.PromptTokens,
+		ShadowOutputTokens:  result.ShadowUsage.CompletionTokens,
+		ShadowCost:          result.ShadowCost,
+		ShadowLatencyMs:     int(result.ShadowLatency.Milliseconds()),
+		Similarity:          result.Similarity,
+		CreatedAt:           result.CreatedAt,
+	}
+	if result.ShadowError != "" {
+		shadowError := result.ShadowError
+		record.ShadowError = &shadowError
+	}
+	return a.repo.Create(ctx, record)
+}
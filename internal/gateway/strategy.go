@@ -3,6 +3,7 @@ package gateway
 import (
 	"context"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
@@ -22,14 +23,13 @@ const (
 	StrategyCustom       StrategyType = "custom"
 )
 
+// ModelBasedStrategy falls back to the model's family or naming style, then the first provider.
 type ModelBasedStrategy struct{}
 
-func isOllamaStyleModel(modelLower string) bool {
-	return strings.Contains(modelLower, ":")
-}
-
-func isVLLMStyleModel(modelLower string) bool {
-	return strings.Contains(modelLower, "/")
+// sameModel treats Ollama's implicit ":latest" tag as written.
+func sameModel(listed, requested string) bool {
+	listed, requested = strings.ToLower(listed), strings.ToLower(requested)
+	return listed == requested || strings.TrimSuffix(listed, ":latest") == strings.TrimSuffix(requested, ":latest")
 }
 
 func (s *ModelBasedStrategy) SelectProvider(ctx context.Context, req providers.ChatRequest, availableProviders []providers.Provider) (providers.Provider, error) {
@@ -37,46 +37,26 @@ func (s *ModelBasedStrategy) SelectProvider(ctx context.Context, req providers.C
 		return nil, ErrNoProviders
 	}
 
-	modelLower := strings.ToLower(req.Model)
-
-	// Ollama uses "name:tag" (e.g. llama3.2:latest). Prefer local so we never send these to vLLM.
-	if isOllamaStyleModel(modelLower) {
-		for _, provider := range availableProviders {
-			if provider.Name() == "local" {
-				return provider, nil
-			}
-		}
-	}
-
 	for _, provider := range availableProviders {
-		models := provider.GetModels()
-		for _, model := range models {
-			if strings.ToLower(model) == modelLower {
-				return provider, nil
-			}
-		}
-	}
-	for _, provider := range availableProviders {
-		models := provider.GetModels()
-		for _, model := range models {
-			providerModelLower := strings.ToLower(model)
-			if strings.Contains(providerModelLower, modelLower) || strings.Contains(modelLower, providerModelLower) {
+		for _, model := range provider.GetModels() {
+			if sameModel(model, req.Model) {
 				return provider, nil
 			}
 		}
 	}
-	if isVLLMStyleModel(modelLower) {
-		for _, provider := range availableProviders {
-			if provider.Name() == "vllm" {
-				return provider, nil
-			}
+	name, ok := providers.ModelProvider(req.Model)
+	if !ok {
+		modelLower := strings.ToLower(req.Model)
+		switch {
+		case isOllamaStyleModel(modelLower):
+			name = "local"
+		case isVLLMStyleModel(modelLower):
+			name = "vllm"
 		}
 	}
-	if strings.Contains(modelLower, "llama") || strings.Contains(modelLower, "mistral") ||
-		strings.Contains(modelLower, "phi") || strings.Contains(modelLower, "codellama") ||
-		strings.Contains(modelLower, "neural") || strings.Contains(modelLower, "orca") {
+	if name != "" {
 		for _, provider := range availableProviders {
-			if provider.Name() == "local" {
+			if provider.Name() == name {
 				return provider, nil
 			}
 		}
@@ -113,7 +93,7 @@ func (s *CostBasedStrategy) SelectProvider(ctx context.Context, req providers.Ch
 		if !supportsModel {
 			continue
 		}
-		cost := s.costCalculator.EstimateCost(provider.Name(), req.Model, req.Messages)
+		cost := s.costCalculator.EstimateCost(provider.Name(), req)
 		if cost < lowestCost {
 			lowestCost = cost
 			cheapestProvider = provider
@@ -171,20 +151,32 @@ func (s *LatencyBasedStrategy) SelectProvider(ctx context.Context, req providers
 }
 
 type LoadBalancedStrategy struct {
-	counter int
+	counter atomic.Uint64
 }
 
 func NewLoadBalancedStrategy() *LoadBalancedStrategy {
-	return &LoadBalancedStrategy{
-		counter: 0,
-	}
+	return &LoadBalancedStrategy{}
 }
 
 func (s *LoadBalancedStrategy) SelectProvider(ctx context.Context, req providers.ChatRequest, availableProviders []providers.Provider) (providers.Provider, error) {
 	if len(availableProviders) == 0 {
 		return nil, ErrNoProviders
 	}
+	supportedProviders := balancedProviders(req, availableProviders)
+	n := s.counter.Add(1) - 1
+	return supportedProviders[n%uint64(len(supportedProviders))], nil
+}
 
+// peek returns the provider the next request would get, without taking its turn.
+func (s *LoadBalancedStrategy) peek(req providers.ChatRequest, availableProviders []providers.Provider) (providers.Provider, error) {
+	if len(availableProviders) == 0 {
+		return nil, ErrNoProviders
+	}
+	supportedProviders := balancedProviders(req, availableProviders)
+	return supportedProviders[s.counter.Load()%uint64(len(supportedProviders))], nil
+}
+
+func balancedProviders(req providers.ChatRequest, availableProviders []providers.Provider) []providers.Provider {
 	supportedProviders := make([]providers.Provider, 0)
 	for _, provider := range availableProviders {
 		for _, model := range provider.GetModels() {
@@ -196,12 +188,9 @@ func (s *LoadBalancedStrategy) SelectProvider(ctx context.Context, req providers
 	}
 
 	if len(supportedProviders) == 0 {
-		supportedProviders = availableProviders
+		return availableProviders
 	}
-	selected := supportedProviders[s.counter%len(supportedProviders)]
-	s.counter++
-
-	return selected, nil
+	return supportedProviders
 }
 
 type CustomStrategy struct {
@@ -210,8 +199,18 @@ type CustomStrategy struct {
 
 type RoutingRule struct {
 	Condition func(req providers.ChatRequest) bool
-	Provider  string
-	Priority  int
+	// Match, when set, replaces Condition and explains its result.
+	Match    func(ctx context.Context, req providers.ChatRequest) ConditionTrace
+	Name     string
+	Provider string
+	Priority int
+}
+
+func (rule RoutingRule) evaluate(ctx context.Context, req providers.ChatRequest) ConditionTrace {
+	if rule.Match != nil {
+		return rule.Match(ctx, req)
+	}
+	return ConditionTrace{Type: "custom", Matched: rule.Condition != nil && rule.Condition(req)}
 }
 
 func NewCustomStrategy(rules []RoutingRule) *CustomStrategy {
@@ -229,14 +228,29 @@ func (s *CustomStrategy) SelectProvider(ctx context.Context, req providers.ChatR
 		strategy := &ModelBasedStrategy{}
 		return strategy.SelectProvider(ctx, req, availableProviders)
 	}
+	trace := routingTraceFromContext(ctx)
 	for _, rule := range s.rules {
-		if rule.Condition != nil && rule.Condition(req) {
-			for _, provider := range availableProviders {
-				if provider.Name() == rule.Provider {
-					return provider, nil
-				}
+		result := rule.evaluate(ctx, req)
+		ruleTrace := RuleTrace{
+			Name:      rule.Name,
+			Provider:  rule.Provider,
+			Priority:  rule.Priority,
+			Matched:   result.Matched,
+			Condition: &result,
+		}
+		if !result.Matched {
+			trace.addRule(ruleTrace)
+			continue
+		}
+		for _, provider := range availableProviders {
+			if provider.Name() == rule.Provider {
+				ruleTrace.Selected = true
+				trace.addRule(ruleTrace)
+				return provider, nil
 			}
 		}
+		ruleTrace.Reason = "provider not available"
+		trace.addRule(ruleTrace)
 	}
 	strategy := &ModelBasedStrategy{}
 	return strategy.SelectProvider(ctx, req, availableProviders)
@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/jsonschema"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+)
+
+// MaxStructuredOutputRetries caps StructuredOutputPolicy.MaxRetries.
+const MaxStructuredOutputRetries = 3
+
+// StructuredOutputPolicy controls what happens when a response does not match the
+// request's response_format. Without retries the request fails with a
+// StructuredOutputError. Each retry resends the request, or with Repair, shows the
+// model its invalid output and the validation errors and asks for a corrected answer.
+// Streams are validated but never retried.
+type StructuredOutputPolicy struct {
+	MaxRetries int
+	Repair     bool
+}
+
+type structuredOutputPolicyKey struct{}
+
+func WithStructuredOutputPolicy(ctx context.Context, policy StructuredOutputPolicy) context.Context {
+	return context.WithValue(ctx, structuredOutputPolicyKey{}, policy)
+}
+
+func structuredOutputPolicyFromContext(ctx context.Context) StructuredOutputPolicy {
+	policy, _ := ctx.Value(structuredOutputPolicyKey{}).(StructuredOutputPolicy)
+	if policy.MaxRetries > MaxStructuredOutputRetries {
+		policy.MaxRetries = MaxStructuredOutputRetries
+	}
+	return policy
+}
+
+// StructuredOutputError is returned when the final output does not match the
+// request's response_format.
+type StructuredOutputError struct {
+	Provider string
+	Attempts int
+	Errors   []string
+	Output   string
+}
+
+func (e *StructuredOutputError) Error() string {
+	errs := e.Errors
+	if len(errs) > 3 {
+		errs = append(errs[:3:3], fmt.Sprintf("and %d more", len(e.Errors)-3))
+	}
+	return fmt.Sprintf("output does not match response_format after %d attempt(s): %s", e.Attempts, strings.Join(errs, "; "))
+}
+
+func AsStructuredOutputError(err error) (*StructuredOutputError, bool) {
+	soErr, ok := err.(*StructuredOutputError)
+	return soErr, ok
+}
+
+// routeStructured is route, validating the output of requests with a response_format
+// and retrying according to the request's StructuredOutputPolicy. The usage and cost
+// of rejected attempts are added to the response that is returned.
+func (r *Router) routeStructured(ctx context.Context, req providers.ChatRequest, userID *uuid.UUID) (*providers.ChatResponse, error) {
+	resp, err := r.route(ctx, req, userID)
+	if err != nil || !req.ResponseFormat.WantsJSON() {
+		return resp, err
+	}
+	policy := structuredOutputPolicyFromContext(ctx)
+	var spent providers.Usage
+	var spentCost float64
+	for attempt := 1; ; attempt++ {
+		output, errs := checkStructuredOutput(req.ResponseFormat, resp)
+		if errs == nil {
+			resp.Usage.PromptTokens += spent.PromptTokens
+			resp.Usage.CompletionTokens += spent.CompletionTokens
+			resp.Usage.TotalTokens += spent.TotalTokens
+			resp.Cost += spentCost
+			monitoring.RecordStructuredOutput(resp.Provider, "valid")
+			return resp, nil
+		}
+		if attempt > policy.MaxRetries {
+			monitoring.RecordStructuredOutput(resp.Provider, "invalid")
+			return nil, &StructuredOutputError{Provider: resp.Provider, Attempts: attempt, Errors: errs, Output: output}
+		}
+		monitoring.RecordStructuredOutput(resp.Provider, "retried")
+		spent.PromptTokens += resp.Usage.PromptTokens
+		spent.CompletionTokens += resp.Usage.CompletionTokens
+		spent.TotalTokens += resp.Usage.TotalTokens
+		spentCost += resp.Cost
+
+		retryReq := req
+		if policy.Repair {
+			retryReq = repairRequest(req, output, errs)
+		}
+		if resp, err = r.route(ctx, retryReq, userID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// checkStructuredOutput validates the first choice of resp against format. Valid JSON
+// wrapped in a code fence is unwrapped in place. Responses that call tools are not
+// checked. It returns the output text and the validation errors, nil if it is valid.
+func checkStructuredOutput(format *providers.ResponseFormat, resp *providers.ChatResponse) (string, []string) {
+	if len(resp.Choices) == 0 {
+		return "", []string{"$: the response has no choices"}
+	}
+	msg := &resp.Choices[0].Message
+	if len(msg.ToolCalls) > 0 {
+		return "", nil
+	}
+	output := messageText(*msg)
+	text := extractJSON(output)
+	errs := validateStructuredOutput(format, text)
+	if errs == nil && text != output {
+		msg.Content = text
+	}
+	return output, errs
+}
+
+func validateStructuredOutput(format *providers.ResponseFormat, text string) []string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return []string{fmt.Sprintf("$: not valid JSON: %v", err)}
+	}
+	if schema := format.Schema(); schema != nil {
+		return jsonschema.Validate(schema, value)
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return []string{"$: expected a JSON object"}
+	}
+	return nil
+}
+
+// extractJSON strips whitespace and a surrounding Markdown code fence, which models
+// asked for JSON in the prompt often add.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	inner := text[3 : len(text)-3]
+	if newline := strings.IndexByte(inner, '\n'); newline >= 0 {
+		inner = inner[newline+1:] // language tag, e.g. ```json
+	}
+	return strings.TrimSpace(inner)
+}
+
+// repairRequest continues the conversation with the invalid output and what is wrong
+// with it.
+func repairRequest(req providers.ChatRequest, output string, errs []string) providers.ChatRequest {
+	messages := make([]providers.Message, 0, len(req.Messages)+2)
+	messages = append(messages, req.Messages...)
+	messages = append(messages,
+		providers.Message{Role: "assistant", Content: output},
+		providers.Message{Role: "user", Content: "Your answer does not match the required JSON format:\n- " + strings.Join(errs, "\n- ") +
+			"\nRespond again with only the corrected JSON, without any other text."},
+	)
+	req.Messages = messages
+	return req
+}
+
+// structuredOutputRequest asks for JSON in the system prompt when provider cannot
+// enforce the request's response_format itself. The response is validated either way.
+func structuredOutputRequest(provider providers.Provider, req providers.ChatRequest) providers.ChatRequest {
+	if !req.ResponseFormat.WantsJSON() || providers.SupportsResponseFormat(provider, req.Model) {
+		return req
+	}
+	instruction := "Respond with a single JSON object and nothing else: no explanations and no Markdown code fences."
+	if schema := req.ResponseFormat.Schema(); schema != nil {
+		data, _ := json.Marshal(schema)
+		instruction = "Respond with a single JSON value that matches this JSON Schema, and nothing else: no explanations and no Markdown code fences.\nJSON Schema: " + string(data)
+	}
+	messages := make([]providers.Message, 0, len(req.Messages)+1)
+	if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+		if text, ok := req.Messages[0].Content.(string); ok {
+			system := req.Messages[0]
+			system.Content = text + "\n\n" + instruction
+			messages = append(messages, system)
+			messages = append(messages, req.Messages[1:]...)
+		}
+	}
+	if len(messages) == 0 {
+		messages = append(messages, providers.Message{Role: "system", Content: instruction})
+		messages = append(messages, req.Messages...)
+	}
+	req.Messages = messages
+	req.ResponseFormat = nil
+	return req
+}
+
+// validateStream holds back the final chunk of a stream until the streamed output has
+// been validated against format, and ends the stream with a StructuredOutputError
+// instead if it does not match.
+func validateStream(ctx context.Context, format *providers.ResponseFormat, in <-chan providers.StreamChunk, inErrs <-chan error) (<-chan providers.StreamChunk, <-chan error) {
+	chunkChan := make(chan providers.StreamChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		assembled := newStreamAssembler("")
+		var failed error
+		forward := true
+		for chunk := range in {
+			if !forward {
+				continue
+			}
+			assembled.add(chunk)
+			if chunk.Done && chunk.Error == "" {
+				resp := assembled.response()
+				if output, errs := checkStructuredOutput(format, resp); errs != nil {
+					monitoring.RecordStructuredOutput(chunk.Provider, "invalid")
+					failed = &StructuredOutputError{Provider: chunk.Provider, Attempts: 1, Errors: errs, Output: output}
+					forward = false
+					continue
+				}
+				monitoring.RecordStructuredOutput(chunk.Provider, "valid")
+			}
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+				forward = false
+			}
+		}
+		for err := range inErrs {
+			if failed == nil && forward {
+				failed = err
+			}
+		}
+		if failed != nil {
+			errChan <- failed
+		}
+	}()
+	return chunkChan, errChan
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/tokenizer"
+)
+
+// Chat formatting overhead, following OpenAI's published accounting.
+const (
+	tokensPerMessage = 3
+	tokensPerName    = 1
+	tokensPerReply   = 3
+)
+
+// Assumed completion length for cost estimates when the request sets no max_tokens.
+const defaultEstimatedOutputTokens = 256
+
+// PromptTokenCount breaks down the input tokens of a chat request.
+type PromptTokenCount struct {
+	Total      int `json:"prompt_tokens"`
+	Text       int `json:"text_tokens"`
+	Images     int `json:"image_tokens"`
+	ImageCount int `json:"image_count"`
+	Tools      int `json:"tool_tokens"`
+}
+
+// CountPromptTokens counts messages, image parts and tool definitions of req with t.
+func CountPromptTokens(t tokenizer.Tokenizer, req providers.ChatRequest) PromptTokenCount {
+	var count PromptTokenCount
+	for _, msg := range req.Messages {
+		count.Text += tokensPerMessage + t.Count(msg.Role)
+		if msg.Name != "" {
+			count.Text += tokensPerName + t.Count(msg.Name)
+		}
+		text, parts := providers.NormalizeMessageContent(msg.Content)
+		if msg.Content != nil {
+			count.Text += t.Count(text)
+		}
+		for _, part := range parts {
+			switch part.Type {
+			case "text":
+				count.Text += t.Count(part.Text)
+			case "image_url":
+				width, height := tokenizer.DefaultImageWidth, tokenizer.DefaultImageHeight
+				if part.ImageURL != nil {
+					if w, h, ok := tokenizer.ImageDimensions(part.ImageURL.URL); ok {
+						width, height = w, h
+					}
+				}
+				count.Images += tokenizer.ImageTokens(t, width, height)
+				count.ImageCount++
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			count.Text += t.Count(call.Function.Name) + t.Count(call.Function.Arguments)
+		}
+	}
+	if len(req.Messages) > 0 {
+		count.Text += tokensPerReply
+	}
+	if len(req.Tools) > 0 {
+		if encoded, err := json.Marshal(req.Tools); err == nil {
+			count.Tools = t.Count(string(encoded))
+		}
+	}
+	count.Total = count.Text + count.Images + count.Tools
+	return count
+}
+
+// EstimateRequestTokens is the prompt plus max_tokens, or the assumed completion
+// length when max_tokens is unset.
+func EstimateRequestTokens(t tokenizer.Tokenizer, req providers.ChatRequest) int {
+	output := defaultEstimatedOutputTokens
+	if req.MaxTokens > 0 {
+		output = req.MaxTokens
+	}
+	return CountPromptTokens(t, req).Total + output
+}
+
+// ContextWindow returns the context length of well-known models.
+func ContextWindow(model string) (int, bool) {
+	return providers.ModelContextLength(model)
+}
+
+func (r *Router) GetTokenizers() *tokenizer.Registry {
+	return r.tokenizers
+}
+
+// SetTokenizers replaces the tokenizer registry used for counting and cost estimates.
+func (r *Router) SetTokenizers(registry *tokenizer.Registry) {
+	r.tokenizers = registry
+	r.costCalculator.SetTokenizers(registry)
+}
+
+// checkTokenLimits rejects req before it is sent when the prompt plus max_tokens cannot
+// fit the model's context window. counted caches prompt counts per model across targets.
+func (r *Router) checkTokenLimits(providerName string, req providers.ChatRequest, counted map[string]int) error {
+	if req.MaxTokens <= 0 {
+		return nil
+	}
+	window, ok := r.ModelContextWindow(req.Model)
+	if !ok {
+		return nil
+	}
+	promptTokens, ok := counted[req.Model]
+	if !ok {
+		promptTokens = CountPromptTokens(r.tokenizers.ForModel(req.Model), req).Total
+		counted[req.Model] = promptTokens
+	}
+	if promptTokens+req.MaxTokens <= window {
+		return nil
+	}
+	return &providers.ProviderError{
+		Provider:   providerName,
+		Kind:       providers.ErrorKindContextLength,
+		StatusCode: http.StatusBadRequest,
+		Message: fmt.Sprintf("model %s has a context window of %d tokens, but the request has about %d prompt tokens and max_tokens %d",
+			req.Model, window, promptTokens, req.MaxTokens),
+	}
+}
+
+// estimateUsage counts usage locally for streams whose provider did not report it.
+func (r *Router) estimateUsage(req providers.ChatRequest, completion string) *providers.Usage {
+	t := r.tokenizers.ForModel(req.Model)
+	usage := &providers.Usage{
+		PromptTokens:     CountPromptTokens(t, req).Total,
+		CompletionTokens: t.Count(completion),
+		Estimated:        true,
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
@@ -0,0 +1,279 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/monitoring"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultWebhookTimeout applies to webhook hooks without timeout_ms.
+const DefaultWebhookTimeout = 2 * time.Second
+
+// What a webhook hook does when the policy service cannot be reached or answers with
+// an error: let the request through (open) or reject it (closed).
+const (
+	WebhookFailOpen   = "open"
+	WebhookFailClosed = "closed"
+)
+
+// WebhookHookConfig configures a hook that asks an external policy service about each
+// chat request. Events defaults to before_request; Headers values may reference
+// environment variables ("Bearer ${POLICY_TOKEN}").
+type WebhookHookConfig struct {
+	Name        string            `json:"name" yaml:"name"`
+	URL         string            `json:"url" yaml:"url"`
+	TimeoutMs   int               `json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"`
+	FailureMode string            `json:"failure_mode,omitempty" yaml:"failure_mode,omitempty"` // open or closed (default)
+	Events      []string          `json:"events,omitempty" yaml:"events,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+func (c WebhookHookConfig) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http(s) URL")
+	}
+	if c.TimeoutMs < 0 {
+		return fmt.Errorf("timeout_ms must not be negative")
+	}
+	switch c.FailureMode {
+	case "", WebhookFailOpen, WebhookFailClosed:
+	default:
+		return fmt.Errorf("failure_mode must be %s or %s", WebhookFailOpen, WebhookFailClosed)
+	}
+	for _, event := range c.Events {
+		switch event {
+		case HookEventBeforeRequest, HookEventAfterResponse, HookEventError:
+		default:
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	return nil
+}
+
+// HookConfig is the hooks file (HOOKS_CONFIG_PATH).
+type HookConfig struct {
+	Webhooks []WebhookHookConfig `json:"webhooks" yaml:"webhooks"`
+}
+
+// ParseHookConfig parses a JSON (.json) or YAML hooks file.
+func ParseHookConfig(name string, data []byte) (*HookConfig, error) {
+	var config HookConfig
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hooks config %s: %w", name, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid hooks config %s: %w", name, err)
+	}
+	return &config, nil
+}
+
+func LoadHookConfig(path string) (*HookConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hooks config: %w", err)
+	}
+	return ParseHookConfig(path, data)
+}
+
+func (c *HookConfig) Validate() error {
+	seen := make(map[string]bool, len(c.Webhooks))
+	for i, webhook := range c.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			return fmt.Errorf("webhook %d: %w", i, err)
+		}
+		if seen[webhook.Name] {
+			return fmt.Errorf("webhook %d: duplicate name %s", i, webhook.Name)
+		}
+		seen[webhook.Name] = true
+	}
+	return nil
+}
+
+// WebhookRequest is the body POSTed to a policy service.
+type WebhookRequest struct {
+	Event    string                  `json:"event"`
+	Hook     string                  `json:"hook"`
+	APIKeyID string                  `json:"api_key_id,omitempty"`
+	Request  *providers.ChatRequest  `json:"request"`
+	Response *providers.ChatResponse `json:"response,omitempty"` // after_response
+	Error    string                  `json:"error,omitempty"`    // error
+}
+
+// WebhookResponse is the policy service's answer. Action "block" rejects the request
+// (before_request) or response (after_response); anything else allows it. Request, if
+// set, replaces the request; Annotations are added to the response.
+type WebhookResponse struct {
+	Action      string                 `json:"action"`
+	Reason      string                 `json:"reason,omitempty"`
+	Request     *providers.ChatRequest `json:"request,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// WebhookHook is a Hook backed by an external policy service. Streamed chunks are not
+// sent to the service; after_response sees the assembled stream.
+type WebhookHook struct {
+	config  WebhookHookConfig
+	events  map[string]bool
+	headers map[string]string
+	timeout time.Duration
+	client  *http.Client
+	logger  zerolog.Logger
+}
+
+func NewWebhookHook(config WebhookHookConfig, logger zerolog.Logger) *WebhookHook {
+	events := config.Events
+	if len(events) == 0 {
+		events = []string{HookEventBeforeRequest}
+	}
+	h := &WebhookHook{
+		config:  config,
+		events:  make(map[string]bool, len(events)),
+		headers: make(map[string]string, len(config.Headers)),
+		timeout: DefaultWebhookTimeout,
+		client:  &http.Client{},
+		logger:  logger,
+	}
+	for _, event := range events {
+		h.events[event] = true
+	}
+	for name, value := range config.Headers {
+		h.headers[name] = os.ExpandEnv(value)
+	}
+	if config.TimeoutMs > 0 {
+		h.timeout = time.Duration(config.TimeoutMs) * time.Millisecond
+	}
+	return h
+}
+
+func (h *WebhookHook) Name() string {
+	return h.config.Name
+}
+
+func (h *WebhookHook) BeforeRequest(ctx context.Context, req *providers.ChatRequest) error {
+	if !h.events[HookEventBeforeRequest] {
+		return nil
+	}
+	answer, err := h.call(ctx, WebhookRequest{Event: HookEventBeforeRequest, Request: req})
+	if err != nil || answer == nil {
+		return err
+	}
+	if answer.Request != nil {
+		*req = *answer.Request
+	}
+	return nil
+}
+
+func (h *WebhookHook) OnChunk(ctx context.Context, req *providers.ChatRequest, chunk *providers.StreamChunk) error {
+	return nil
+}
+
+func (h *WebhookHook) AfterResponse(ctx context.Context, req *providers.ChatRequest, resp *providers.ChatResponse) error {
+	if !h.events[HookEventAfterResponse] {
+		return nil
+	}
+	answer, err := h.call(ctx, WebhookRequest{Event: HookEventAfterResponse, Request: req, Response: resp})
+	if err != nil || answer == nil {
+		return err
+	}
+	if len(answer.Annotations) > 0 {
+		if resp.Annotations == nil {
+			resp.Annotations = make(map[string]interface{}, len(answer.Annotations))
+		}
+		for key, value := range answer.Annotations {
+			resp.Annotations[key] = value
+		}
+	}
+	return nil
+}
+
+// OnError notifies the service in the background; the caller does not wait for it.
+func (h *WebhookHook) OnError(ctx context.Context, req *providers.ChatRequest, err error) {
+	if !h.events[HookEventError] {
+		return
+	}
+	body := WebhookRequest{Event: HookEventError, Request: req, Error: err.Error()}
+	body.APIKeyID = requestAttributesFromContext(ctx).APIKeyID
+	go func() {
+		if _, err := h.post(context.Background(), body); err != nil {
+			h.logger.Warn().Err(err).Str("hook", h.config.Name).Msg("Failed to notify policy service of error")
+		}
+	}()
+}
+
+// call asks the service about one event. It returns the service's answer, or nil when
+// the service failed and the hook fails open.
+func (h *WebhookHook) call(ctx context.Context, body WebhookRequest) (*WebhookResponse, error) {
+	body.APIKeyID = requestAttributesFromContext(ctx).APIKeyID
+	answer, err := h.post(ctx, body)
+	if err != nil {
+		monitoring.RecordHookOutcome(h.config.Name, body.Event, "error")
+		if h.config.FailureMode == WebhookFailOpen {
+			h.logger.Warn().Err(err).Str("hook", h.config.Name).Str("event", body.Event).Msg("Policy service failed, allowing request")
+			return nil, nil
+		}
+		h.logger.Error().Err(err).Str("hook", h.config.Name).Str("event", body.Event).Msg("Policy service failed, rejecting request")
+		return nil, Block("policy service unavailable")
+	}
+	if strings.EqualFold(answer.Action, "block") {
+		reason := answer.Reason
+		if reason == "" {
+			reason = "rejected by policy"
+		}
+		return nil, Block(reason)
+	}
+	return answer, nil
+}
+
+func (h *WebhookHook) post(ctx context.Context, body WebhookRequest) (*WebhookResponse, error) {
+	body.Hook = h.config.Name
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook request: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, value := range h.headers {
+		httpReq.Header.Set(name, value)
+	}
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("policy service returned status %d", resp.StatusCode)
+	}
+	var answer WebhookResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&answer); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid policy service response: %w", err)
+	}
+	return &answer, nil
+}
@@ -0,0 +1,372 @@
+// Package jsonschema validates decoded JSON values against a JSON Schema. It covers the
+// subset of the specification that structured-output schemas use: types, enum and const,
+// object properties, arrays, string and number bounds, combinators and local $refs.
+// Unknown keywords, including format, are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRefDepth stops cyclic $refs.
+const maxRefDepth = 32
+
+// Validate checks value, as decoded by encoding/json, against schema. It returns one
+// message per violation, each prefixed with the path of the value ("$.items[0].name").
+func Validate(schema map[string]interface{}, value interface{}) []string {
+	v := &validator{root: schema}
+	v.validate(schema, value, "$", 0)
+	return v.errs
+}
+
+// ValidateJSON decodes data and validates it against schema.
+func ValidateJSON(schema map[string]interface{}, data []byte) []string {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []string{fmt.Sprintf("$: invalid JSON: %v", err)}
+	}
+	return Validate(schema, value)
+}
+
+type validator struct {
+	root map[string]interface{}
+	errs []string
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, path+": "+fmt.Sprintf(format, args...))
+}
+
+// valid reports whether value matches schema without recording errors.
+func (v *validator) valid(schema interface{}, value interface{}, depth int) bool {
+	sub := &validator{root: v.root}
+	sub.validate(schema, value, "$", depth)
+	return len(sub.errs) == 0
+}
+
+func (v *validator) validate(schema interface{}, value interface{}, path string, depth int) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.errorf(path, "no value is allowed here")
+		}
+		return
+	case map[string]interface{}:
+		v.validateObject(s, value, path, depth)
+	}
+}
+
+func (v *validator) validateObject(s map[string]interface{}, value interface{}, path string, depth int) {
+	if ref, ok := s["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			v.errorf(path, "$ref nesting is too deep")
+			return
+		}
+		target, err := v.resolve(ref)
+		if err != nil {
+			v.errorf(path, "%v", err)
+			return
+		}
+		v.validate(target, value, path, depth+1)
+	}
+
+	if value == nil && s["nullable"] == true {
+		return
+	}
+	if t, ok := s["type"]; ok && !typeMatches(t, value) {
+		v.errorf(path, "expected %s, got %s", typeNames(t), typeOf(value))
+		return
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if equal(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.errorf(path, "must be one of %s", compact(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !equal(c, value) {
+		v.errorf(path, "must be %s", compact(c))
+	}
+
+	switch val := value.(type) {
+	case string:
+		v.validateString(s, val, path)
+	case float64:
+		v.validateNumber(s, val, path)
+	case map[string]interface{}:
+		v.validateProperties(s, val, path, depth)
+	case []interface{}:
+		v.validateItems(s, val, path, depth)
+	}
+
+	if allOf, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			v.validate(sub, value, path, depth)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if v.valid(sub, value, depth) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.errorf(path, "does not match any of the allowed schemas (anyOf)")
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if v.valid(sub, value, depth) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.errorf(path, "must match exactly one schema (oneOf), matches %d", matches)
+		}
+	}
+	if not, ok := s["not"]; ok && v.valid(not, value, depth) {
+		v.errorf(path, "must not match the schema in not")
+	}
+}
+
+func (v *validator) validateString(s map[string]interface{}, val, path string) {
+	length := utf8.RuneCountInString(val)
+	if min, ok := number(s["minLength"]); ok && float64(length) < min {
+		v.errorf(path, "must be at least %v characters", min)
+	}
+	if max, ok := number(s["maxLength"]); ok && float64(length) > max {
+		v.errorf(path, "must be at most %v characters", max)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.errorf(path, "invalid pattern %q in schema", pattern)
+		} else if !re.MatchString(val) {
+			v.errorf(path, "must match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(s map[string]interface{}, val float64, path string) {
+	if min, ok := number(s["minimum"]); ok && val < min {
+		v.errorf(path, "must be >= %v", min)
+	}
+	if max, ok := number(s["maximum"]); ok && val > max {
+		v.errorf(path, "must be <= %v", max)
+	}
+	if min, ok := number(s["exclusiveMinimum"]); ok && val <= min {
+		v.errorf(path, "must be > %v", min)
+	}
+	if max, ok := number(s["exclusiveMaximum"]); ok && val >= max {
+		v.errorf(path, "must be < %v", max)
+	}
+	if step, ok := number(s["multipleOf"]); ok && step > 0 {
+		if q := val / step; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.errorf(path, "must be a multiple of %v", step)
+		}
+	}
+}
+
+func (v *validator) validateProperties(s map[string]interface{}, val map[string]interface{}, path string, depth int) {
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, present := val[key]; !present {
+					v.errorf(path, "missing required property %q", key)
+				}
+			}
+		}
+	}
+	if min, ok := number(s["minProperties"]); ok && float64(len(val)) < min {
+		v.errorf(path, "must have at least %v properties", min)
+	}
+	if max, ok := number(s["maxProperties"]); ok && float64(len(val)) > max {
+		v.errorf(path, "must have at most %v properties", max)
+	}
+	properties, _ := s["properties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+	for _, key := range sortedKeys(val) {
+		childPath := propertyPath(path, key)
+		if sub, ok := properties[key]; ok {
+			v.validate(sub, val[key], childPath, depth)
+			continue
+		}
+		if hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				v.errorf(path, "unexpected property %q", key)
+				continue
+			}
+			v.validate(additional, val[key], childPath, depth)
+		}
+	}
+}
+
+func (v *validator) validateItems(s map[string]interface{}, val []interface{}, path string, depth int) {
+	if min, ok := number(s["minItems"]); ok && float64(len(val)) < min {
+		v.errorf(path, "must have at least %v items", min)
+	}
+	if max, ok := number(s["maxItems"]); ok && float64(len(val)) > max {
+		v.errorf(path, "must have at most %v items", max)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		for i := range val {
+			for j := 0; j < i; j++ {
+				if equal(val[i], val[j]) {
+					v.errorf(path, "items %d and %d are equal", j, i)
+				}
+			}
+		}
+	}
+	if items, ok := s["items"]; ok {
+		if _, tuple := items.([]interface{}); !tuple {
+			for i, item := range val {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth)
+			}
+		}
+	}
+}
+
+// resolve looks up a local reference such as "#/$defs/address".
+func (v *validator) resolve(ref string) (interface{}, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+	var node interface{} = v.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = m[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return node, nil
+}
+
+func typeMatches(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesType(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesType(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesType(name string, value interface{}) bool {
+	switch name {
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeOf(value) == name
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func typeNames(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// number reads a numeric keyword, which is float64 once decoded by encoding/json but
+// may be an int in schemas built in Go.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// equal compares JSON values, treating numbers by value.
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compact(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func propertyPath(path, key string) string {
+	if identifierPattern.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + compact(key) + "]"
+}
+
+// sortedKeys keeps the order of error messages stable.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -59,6 +59,119 @@ var (
 		},
 		[]string{"api_key_id", "type"}, // type: per_minute, per_day
 	)
+
+	// Response cache metrics
+	CacheHits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_cache_hits_total",
+			Help: "Total number of chat requests served from the response cache",
+		},
+		[]string{"provider", "model"},
+	)
+
+	CacheMisses = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_cache_misses_total",
+			Help: "Total number of cacheable chat requests not found in the response cache",
+		},
+		[]string{"model"},
+	)
+
+	CacheSavedCost = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_cache_saved_cost_total",
+			Help: "Provider cost in USD avoided by serving cached responses",
+		},
+		[]string{"provider", "model"},
+	)
+
+	ProviderRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_provider_retries_total",
+			Help: "Total number of provider calls retried after a retryable error",
+		},
+		[]string{"provider", "kind"},
+	)
+
+	ExperimentAssignments = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_experiment_assignments_total",
+			Help: "Total number of requests assigned to each experiment variant",
+		},
+		[]string{"experiment", "variant"},
+	)
+
+	UnknownPrices = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_unknown_price_total",
+			Help: "Total number of completed requests whose model has no price in the pricing catalog",
+		},
+		[]string{"provider", "model"},
+	)
+
+	HedgedRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_hedged_requests_total",
+			Help: "Total number of hedged chat requests that sent a second call, by winning provider",
+		},
+		[]string{"provider", "winner"}, // winner: primary, hedge
+	)
+
+	HedgeCost = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_hedge_cost_total",
+			Help: "Cost in USD of the losing calls of hedged requests",
+		},
+		[]string{"provider", "model"},
+	)
+
+	ShadowRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_shadow_requests_total",
+			Help: "Total number of requests considered for mirroring to a shadow provider, by outcome",
+		},
+		[]string{"model", "provider", "status"}, // status: success, error, rate_limited, busy
+	)
+
+	ShadowCost = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_shadow_cost_total",
+			Help: "Cost in USD of mirrored requests sent to shadow providers",
+		},
+		[]string{"provider", "model"},
+	)
+
+	PIIRedactions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_pii_redactions_total",
+			Help: "Total number of PII values replaced with placeholders before calling a provider",
+		},
+		[]string{"provider", "type"},
+	)
+
+	HookOutcomes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_hook_outcomes_total",
+			Help: "Total number of request hook decisions (allow, block, error)",
+		},
+		[]string{"hook", "event", "outcome"},
+	)
+
+	StructuredOutputs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_structured_outputs_total",
+			Help: "Total number of responses checked against a response_format, by result (valid, invalid, retried)",
+		},
+		[]string{"provider", "result"},
+	)
+
+	ContextWindowActions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "uniroute_context_window_actions_total",
+			Help: "Total number of requests over their model's context window, by action (rejected, truncated, summarized, summary_reused, summary_failed)",
+		},
+		[]string{"model", "action"},
+	)
 )
 
 func RecordRequest(provider, model, status string, duration float64) {
@@ -85,3 +198,62 @@ func SetProviderHealth(provider string, healthy bool) {
 func RecordRateLimitHit(apiKeyID, limitType string) {
 	RateLimitHits.WithLabelValues(apiKeyID, limitType).Inc()
 }
+
+func RecordCacheHit(provider, model string, savedCost float64) {
+	CacheHits.WithLabelValues(provider, model).Inc()
+	if savedCost > 0 {
+		CacheSavedCost.WithLabelValues(provider, model).Add(savedCost)
+	}
+}
+
+func RecordCacheMiss(model string) {
+	CacheMisses.WithLabelValues(model).Inc()
+}
+
+func RecordProviderRetry(provider, kind string) {
+	ProviderRetries.WithLabelValues(provider, kind).Inc()
+}
+
+func RecordExperimentAssignment(experiment, variant string) {
+	ExperimentAssignments.WithLabelValues(experiment, variant).Inc()
+}
+
+func RecordUnknownPrice(provider, model string) {
+	UnknownPrices.WithLabelValues(provider, model).Inc()
+}
+
+func RecordHedgedRequest(provider, winner string) {
+	HedgedRequests.WithLabelValues(provider, winner).Inc()
+}
+
+func RecordHedgeCost(provider, model string, cost float64) {
+	if cost > 0 {
+		HedgeCost.WithLabelValues(provider, model).Add(cost)
+	}
+}
+
+func RecordShadowRequest(model, provider, status string) {
+	ShadowRequests.WithLabelValues(model, provider, status).Inc()
+}
+
+func RecordShadowCost(provider, model string, cost float64) {
+	if cost > 0 {
+		ShadowCost.WithLabelValues(provider, model).Add(cost)
+	}
+}
+
+func RecordPIIRedactions(provider, piiType string, count int) {
+	PIIRedactions.WithLabelValues(provider, piiType).Add(float64(count))
+}
+
+func RecordHookOutcome(hook, event, outcome string) {
+	HookOutcomes.WithLabelValues(hook, event, outcome).Inc()
+}
+
+func RecordStructuredOutput(provider, result string) {
+	StructuredOutputs.WithLabelValues(provider, result).Inc()
+}
+
+func RecordContextWindowAction(model, action string) {
+	ContextWindowActions.WithLabelValues(model, action).Inc()
+}
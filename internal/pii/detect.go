@@ -0,0 +1,175 @@
+// Package pii detects personal data in prompt text and replaces it with reversible
+// placeholders, so a prompt can be sent to a third-party model without the raw values
+// and the response can be restored before it reaches the caller.
+package pii
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Type string
+
+const (
+	TypeEmail      Type = "email"
+	TypePhone      Type = "phone"
+	TypeCreditCard Type = "credit_card"
+	TypeNationalID Type = "national_id" // US SSN, UK National Insurance number
+)
+
+// AllTypes lists the detectable types in detection priority: when matches overlap, the
+// type listed first wins.
+func AllTypes() []Type {
+	return []Type{TypeEmail, TypeCreditCard, TypeNationalID, TypePhone}
+}
+
+// ParseType returns the Type named s, case-insensitively.
+func ParseType(s string) (Type, bool) {
+	for _, t := range AllTypes() {
+		if strings.EqualFold(string(t), strings.TrimSpace(s)) {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// Match is one detected value; Start and End are byte offsets into the text.
+type Match struct {
+	Type  Type
+	Start int
+	End   int
+	Value string
+}
+
+type detector struct {
+	pattern *regexp.Regexp
+	valid   func(string) bool
+}
+
+var detectors = map[Type][]detector{
+	TypeEmail: {{
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?)*\.[A-Za-z]{2,}`),
+	}},
+	TypeCreditCard: {{
+		pattern: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		valid:   validCardNumber,
+	}},
+	TypeNationalID: {
+		{
+			pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+			valid:   validSSN,
+		},
+		{
+			pattern: regexp.MustCompile(`(?i)\b[A-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+			valid:   validNINO,
+		},
+	},
+	TypePhone: {{
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{1,4}\)[ .\-]?)?\d{2,4}(?:[ .\-]?\d{2,4}){1,4}`),
+		valid:   validPhone,
+	}},
+}
+
+// Detect finds the values of the given types in text (all types if none are given),
+// ordered by position and without overlaps.
+func Detect(text string, types ...Type) []Match {
+	if len(types) == 0 {
+		types = AllTypes()
+	}
+	enabled := make(map[Type]bool, len(types))
+	for _, t := range types {
+		enabled[t] = true
+	}
+
+	var matches []Match
+	taken := func(start, end int) bool {
+		for _, m := range matches {
+			if start < m.End && m.Start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, t := range AllTypes() {
+		if !enabled[t] {
+			continue
+		}
+		for _, d := range detectors[t] {
+			for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+				value := text[loc[0]:loc[1]]
+				if d.valid != nil && !d.valid(value) {
+					continue
+				}
+				if !taken(loc[0], loc[1]) {
+					matches = append(matches, Match{Type: t, Start: loc[0], End: loc[1], Value: value})
+				}
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// validCardNumber accepts 13-19 digit numbers that pass the Luhn checksum.
+func validCardNumber(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validSSN rejects numbers the SSA never issues: area 000, 666 or 9xx, group 00 and
+// serial 0000.
+func validSSN(s string) bool {
+	digits := digitsOf(s)
+	area, group, serial := digits[:3], digits[3:5], digits[5:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// validNINO applies the prefix rules of UK National Insurance numbers.
+func validNINO(s string) bool {
+	prefix := strings.ToUpper(s[:2])
+	if strings.ContainsAny(prefix[:1], "DFIQUV") || strings.ContainsAny(prefix[1:], "DFIOQUV") {
+		return false
+	}
+	switch prefix {
+	case "BG", "GB", "KN", "NK", "NT", "TN", "ZZ":
+		return false
+	}
+	return true
+}
+
+// validPhone accepts international numbers (+ and 8-15 digits) and national numbers
+// written with 10 or 11 digits, which leaves dates, years and short codes alone.
+func validPhone(s string) bool {
+	digits := len(digitsOf(s))
+	if strings.HasPrefix(s, "+") {
+		return digits >= 8 && digits <= 15
+	}
+	return digits == 10 || digits == 11
+}
@@ -0,0 +1,137 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const placeholderPrefix = "[PII_"
+
+// maxPlaceholderLen bounds how much streamed text is held back while it could still be
+// the start of a placeholder.
+const maxPlaceholderLen = 32
+
+var placeholderPattern = regexp.MustCompile(`\[PII_[A-Z_]+_\d+\]`)
+
+// Redactor replaces detected values with placeholders such as [PII_EMAIL_1] and puts
+// them back with Restore. The same value always gets the same placeholder, so a model
+// can still tell two different addresses apart. A Redactor covers one request and is
+// not safe for concurrent use.
+type Redactor struct {
+	types        []Type
+	placeholders map[string]string // placeholder -> value
+	byValue      map[string]string // value -> placeholder
+	counts       map[Type]int
+}
+
+func NewRedactor(types ...Type) *Redactor {
+	return &Redactor{
+		types:        types,
+		placeholders: make(map[string]string),
+		byValue:      make(map[string]string),
+		counts:       make(map[Type]int),
+	}
+}
+
+// Redact returns text with every detected value replaced by its placeholder.
+func (r *Redactor) Redact(text string) string {
+	matches := Detect(text, r.types...)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(r.placeholder(m))
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func (r *Redactor) placeholder(m Match) string {
+	if p, ok := r.byValue[m.Value]; ok {
+		return p
+	}
+	r.counts[m.Type]++
+	p := fmt.Sprintf("%s%s_%d]", placeholderPrefix, strings.ToUpper(string(m.Type)), r.counts[m.Type])
+	r.placeholders[p] = m.Value
+	r.byValue[m.Value] = p
+	return p
+}
+
+// Restore replaces the placeholders this Redactor issued with the original values.
+// Other text, including placeholders it did not issue, is left as is.
+func (r *Redactor) Restore(text string) string {
+	if len(r.placeholders) == 0 || !strings.Contains(text, placeholderPrefix) {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if value, ok := r.placeholders[p]; ok {
+			return value
+		}
+		return p
+	})
+}
+
+// Redacted reports how many values were replaced, by type.
+func (r *Redactor) Redacted() map[Type]int {
+	out := make(map[Type]int, len(r.counts))
+	for t, n := range r.counts {
+		out[t] = n
+	}
+	return out
+}
+
+// Empty reports whether nothing has been redacted, in which case there is nothing to restore.
+func (r *Redactor) Empty() bool {
+	return len(r.placeholders) == 0
+}
+
+// StreamRestorer restores placeholders in text that arrives in pieces, where a
+// placeholder may be split across pieces.
+type StreamRestorer struct {
+	redactor *Redactor
+	pending  string
+}
+
+func (r *Redactor) NewStreamRestorer() *StreamRestorer {
+	return &StreamRestorer{redactor: r}
+}
+
+// Write takes the next piece of text and returns what can be passed on: everything
+// except a trailing part that may be the start of a placeholder.
+func (s *StreamRestorer) Write(piece string) string {
+	text := s.pending + piece
+	s.pending = ""
+	if i := strings.LastIndex(text, "["); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholderLen && mayStartPlaceholder(text[i:]) {
+		s.pending = text[i:]
+		text = text[:i]
+	}
+	return s.redactor.Restore(text)
+}
+
+// Flush returns the text still held back; call it when the stream ends.
+func (s *StreamRestorer) Flush() string {
+	text := s.pending
+	s.pending = ""
+	return s.redactor.Restore(text)
+}
+
+// mayStartPlaceholder reports whether s could grow into a placeholder.
+func mayStartPlaceholder(s string) bool {
+	if len(s) <= len(placeholderPrefix) {
+		return strings.HasPrefix(placeholderPrefix, s)
+	}
+	if !strings.HasPrefix(s, placeholderPrefix) {
+		return false
+	}
+	for _, c := range s[len(placeholderPrefix):] {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
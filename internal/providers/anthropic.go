@@ -19,6 +19,7 @@ type AnthropicProvider struct {
 	baseURL string
 	client  *http.Client
 	logger  zerolog.Logger
+	catalog *ModelCatalog
 }
 
 func NewAnthropicProvider(apiKey, baseURL string, logger zerolog.Logger) *AnthropicProvider {
@@ -47,6 +48,9 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 	if p.apiKey == "" {
 		return nil, fmt.Errorf("Anthropic API key not configured")
 	}
+	if err := unsupportedSampling(p.Name(), req, "presence_penalty", "frequency_penalty", "seed", "logit_bias"); err != nil {
+		return nil, err
+	}
 
 	anthropicReq := map[string]interface{}{
 		"model":      req.Model,
@@ -54,16 +58,22 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 		"max_tokens": 4096,
 	}
 
-	if req.Temperature > 0 {
-		anthropicReq["temperature"] = req.Temperature
+	if req.Temperature != nil {
+		anthropicReq["temperature"] = *req.Temperature
 	}
 	if req.MaxTokens > 0 {
 		anthropicReq["max_tokens"] = req.MaxTokens
 	}
+	applyAnthropicSampling(anthropicReq, req)
+	tools := anthropicTools(req)
 	if req.WebSearch {
-		anthropicReq["tools"] = []map[string]string{
-			{"type": "web_search_20250305", "name": "web_search"},
-		}
+		tools = append(tools, map[string]interface{}{"type": "web_search_20250305", "name": "web_search"})
+	}
+	if len(tools) > 0 {
+		anthropicReq["tools"] = tools
+	}
+	if req.ToolChoice != nil && len(req.Tools) > 0 {
+		anthropicReq["tool_choice"] = anthropicToolChoice(req.ToolChoice)
 	}
 
 	reqBody, err := json.Marshal(anthropicReq)
@@ -88,7 +98,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, newTransportError("anthropic", err)
 	}
 	defer resp.Body.Close()
 
@@ -104,20 +114,24 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 				Type    string `json:"type"`
 			} `json:"error"`
 		}
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, fmt.Errorf("Anthropic API error: %s", errorResp.Error.Message)
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+			return nil, newStatusError("anthropic", resp, fmt.Sprintf("Anthropic API error: %s", errorResp.Error.Message))
 		}
-		return nil, fmt.Errorf("Anthropic API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError("anthropic", resp, fmt.Sprintf("Anthropic API returned status %d: %s", resp.StatusCode, string(body)))
 	}
 
 	var anthropicResp struct {
 		ID      string `json:"id"`
 		Model   string `json:"model"`
 		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
 		} `json:"content"`
-		Usage struct {
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
 			InputTokens  int `json:"input_tokens"`
 			OutputTokens int `json:"output_tokens"`
 		} `json:"usage"`
@@ -128,9 +142,21 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 	}
 
 	content := ""
+	var toolCalls []ToolCall
 	for _, block := range anthropicResp.Content {
-		if block.Type == "text" {
+		switch block.Type {
+		case "text":
 			content += block.Text
+		case "tool_use":
+			args := "{}"
+			if len(block.Input) > 0 {
+				args = string(block.Input)
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: ToolCallFunction{Name: block.Name, Arguments: args},
+			})
 		}
 	}
 
@@ -140,9 +166,11 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 		Choices: []Choice{
 			{
 				Message: Message{
-					Role:    "assistant",
-					Content: content,
+					Role:      "assistant",
+					Content:   content,
+					ToolCalls: toolCalls,
 				},
+				FinishReason: anthropicFinishReason(anthropicResp.StopReason),
 			},
 		},
 		Usage: Usage{
@@ -209,6 +237,10 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 			errChan <- fmt.Errorf("Anthropic API key not configured")
 			return
 		}
+		if err := unsupportedSampling(p.Name(), req, "presence_penalty", "frequency_penalty", "seed", "logit_bias"); err != nil {
+			errChan <- err
+			return
+		}
 
 		anthropicReq := map[string]interface{}{
 			"model":      req.Model,
@@ -217,16 +249,22 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 			"stream":     true,
 		}
 
-		if req.Temperature > 0 {
-			anthropicReq["temperature"] = req.Temperature
+		if req.Temperature != nil {
+			anthropicReq["temperature"] = *req.Temperature
 		}
 		if req.MaxTokens > 0 {
 			anthropicReq["max_tokens"] = req.MaxTokens
 		}
+		applyAnthropicSampling(anthropicReq, req)
+		tools := anthropicTools(req)
 		if req.WebSearch {
-			anthropicReq["tools"] = []map[string]string{
-				{"type": "web_search_20250305", "name": "web_search"},
-			}
+			tools = append(tools, map[string]interface{}{"type": "web_search_20250305", "name": "web_search"})
+		}
+		if len(tools) > 0 {
+			anthropicReq["tools"] = tools
+		}
+		if req.ToolChoice != nil && len(req.Tools) > 0 {
+			anthropicReq["tool_choice"] = anthropicToolChoice(req.ToolChoice)
 		}
 
 		reqBody, err := json.Marshal(anthropicReq)
@@ -248,7 +286,7 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 
 		resp, err := p.streamClient().Do(httpReq)
 		if err != nil {
-			errChan <- fmt.Errorf("failed to send request: %w", err)
+			errChan <- newTransportError("anthropic", err)
 			return
 		}
 		defer resp.Body.Close()
@@ -261,13 +299,11 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 					Type    string `json:"type"`
 				} `json:"error"`
 			}
-			var apiErr string
 			if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
-				apiErr = fmt.Sprintf("Anthropic API error: %s", errorResp.Error.Message)
+				errChan <- newStatusError("anthropic", resp, fmt.Sprintf("Anthropic API error: %s", errorResp.Error.Message))
 			} else {
-				apiErr = fmt.Sprintf("Anthropic API returned status %d: %s", resp.StatusCode, string(body))
+				errChan <- newStatusError("anthropic", resp, fmt.Sprintf("Anthropic API returned status %d: %s", resp.StatusCode, string(body)))
 			}
-			chunkChan <- StreamChunk{Content: "", Done: true, Error: apiErr}
 			return
 		}
 
@@ -278,6 +314,9 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 		var responseID string
 		var fullContent strings.Builder
 		var finalUsage *Usage
+		var finishReason string
+		// Anthropic indexes all content blocks; tool calls are numbered separately.
+		toolIndexes := make(map[int]int)
 		var dataBuf strings.Builder
 
 		flushData := func() (fatal bool) {
@@ -298,10 +337,14 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 				ContentBlock struct {
 					Type string `json:"type"`
 					Text string `json:"text"`
+					ID   string `json:"id"`
+					Name string `json:"name"`
 				} `json:"content_block"`
 				Delta struct {
-					Type string `json:"type"`
-					Text string `json:"text"`
+					Type        string `json:"type"`
+					Text        string `json:"text"`
+					PartialJSON string `json:"partial_json"`
+					StopReason  string `json:"stop_reason"`
 				} `json:"delta"`
 				Index int `json:"index"`
 				Usage struct {
@@ -321,6 +364,21 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 					responseID = event.Message.ID
 				}
 
+			case "content_block_start":
+				if event.ContentBlock.Type == "tool_use" {
+					toolIndex := len(toolIndexes)
+					toolIndexes[event.Index] = toolIndex
+					chunkChan <- StreamChunk{
+						ID: responseID,
+						ToolCalls: []ToolCallDelta{{
+							Index:    toolIndex,
+							ID:       event.ContentBlock.ID,
+							Type:     "function",
+							Function: ToolCallFunction{Name: event.ContentBlock.Name},
+						}},
+					}
+				}
+
 			case "content_block_delta":
 				if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
 					fullContent.WriteString(event.Delta.Text)
@@ -330,8 +388,22 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 						Done:    false,
 					}
 				}
+				if event.Delta.Type == "input_json_delta" && event.Delta.PartialJSON != "" {
+					if toolIndex, ok := toolIndexes[event.Index]; ok {
+						chunkChan <- StreamChunk{
+							ID: responseID,
+							ToolCalls: []ToolCallDelta{{
+								Index:    toolIndex,
+								Function: ToolCallFunction{Arguments: event.Delta.PartialJSON},
+							}},
+						}
+					}
+				}
 
 			case "message_delta":
+				if event.Delta.StopReason != "" {
+					finishReason = anthropicFinishReason(event.Delta.StopReason)
+				}
 				if event.Usage.TotalTokens > 0 {
 					finalUsage = &Usage{
 						PromptTokens:     event.Usage.InputTokens,
@@ -342,10 +414,11 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 
 			case "message_stop":
 				chunkChan <- StreamChunk{
-					ID:      responseID,
-					Content: "",
-					Done:    true,
-					Usage:   finalUsage,
+					ID:           responseID,
+					Content:      "",
+					Done:         true,
+					Usage:        finalUsage,
+					FinishReason: finishReason,
 				}
 				return true
 
@@ -387,19 +460,92 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-
 	return chunkChan, errChan
 }
 
+var anthropicModels = []string{
+	"claude-opus-4-6", "claude-sonnet-4-6", "claude-haiku-4-5-20251001",
+	"claude-sonnet-4-5-20250929", "claude-opus-4-5-20251101",
+	"claude-opus-4-1-20250805", "claude-sonnet-4-20250514", "claude-opus-4-20250514",
+	"claude-3-5-sonnet-20241022", "claude-3-5-sonnet-20240620", "claude-3-5-haiku-20241022",
+	"claude-3-opus-20240229", "claude-3-sonnet-20240229", "claude-3-haiku-20240307",
+}
+
+func (p *AnthropicProvider) SetModelCatalog(catalog *ModelCatalog) {
+	p.catalog = catalog
+}
+
 func (p *AnthropicProvider) GetModels() []string {
-	return []string{
-		"claude-opus-4-6", "claude-sonnet-4-6", "claude-haiku-4-5-20251001",
-		"claude-sonnet-4-5-20250929", "claude-opus-4-5-20251101",
-		"claude-opus-4-1-20250805", "claude-sonnet-4-20250514", "claude-opus-4-20250514",
-		"claude-3-5-sonnet-20241022", "claude-3-5-sonnet-20240620", "claude-3-5-haiku-20241022",
-		"claude-3-opus-20240229", "claude-3-sonnet-20240229", "claude-3-haiku-20240307",
+	if p.catalog == nil {
+		return anthropicModels
+	}
+	return p.catalog.Models(p.Name(), p.baseURL+"\x00"+p.apiKey, p.listModels, anthropicModels)
+}
+
+func (p *AnthropicProvider) listModels(ctx context.Context) ([]string, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("Anthropic API key not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/models?limit=1000", p.baseURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("models request returned status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		if m.ID != "" {
+			models = append(models, m.ID)
+		}
 	}
+	return models, nil
+}
+
+// SupportsTools reports false for the legacy Claude 2 and Instant models.
+func (p *AnthropicProvider) SupportsTools(model string) bool {
+	m := strings.ToLower(model)
+	return !strings.HasPrefix(m, "claude-2") && !strings.HasPrefix(m, "claude-instant")
 }
 
 func convertMessagesToAnthropic(messages []Message) []map[string]interface{} {
 	result := make([]map[string]interface{}, 0, len(messages))
 	for _, msg := range messages {
+		if msg.Role == "tool" {
+			// Tool results go back as user turns; consecutive results share one turn.
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     messageContentString(msg.Content),
+			}
+			if n := len(result); n > 0 && result[n-1]["role"] == "user" {
+				if blocks, ok := result[n-1]["content"].([]map[string]interface{}); ok && isToolResultTurn(blocks) {
+					result[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			result = append(result, map[string]interface{}{
+				"role":    "user",
+				"content": []map[string]interface{}{block},
+			})
+			continue
+		}
+
 		messageMap := map[string]interface{}{
 			"role": msg.Role,
 		}
@@ -453,9 +599,79 @@ func convertMessagesToAnthropic(messages []Message) []map[string]interface{} {
 			}
 			messageMap["content"] = contentArray
 		}
+		if len(msg.ToolCalls) > 0 {
+			var blocks []map[string]interface{}
+			switch c := messageMap["content"].(type) {
+			case string:
+				if c != "" {
+					blocks = append(blocks, map[string]interface{}{"type": "text", "text": c})
+				}
+			case []map[string]interface{}:
+				blocks = c
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Function.Name,
+					"input": toolArgumentsMap(call.Function.Arguments),
+				})
+			}
+			messageMap["content"] = blocks
+		}
 
 		result = append(result, messageMap)
 	}
 	return result
 }
 
+func isToolResultTurn(blocks []map[string]interface{}) bool {
+	return len(blocks) > 0 && blocks[0]["type"] == "tool_result"
+}
+
+func anthropicTools(req ChatRequest) []interface{} {
+	tools := make([]interface{}, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		entry := map[string]interface{}{
+			"name":         tool.Function.Name,
+			"input_schema": schema,
+		}
+		if tool.Function.Description != "" {
+			entry["description"] = tool.Function.Description
+		}
+		tools = append(tools, entry)
+	}
+	return tools
+}
+
+func anthropicToolChoice(choice *ToolChoice) map[string]interface{} {
+	if choice.Function != "" {
+		return map[string]interface{}{"type": "tool", "name": choice.Function}
+	}
+	switch choice.Mode {
+	case ToolChoiceRequired:
+		return map[string]interface{}{"type": "any"}
+	case ToolChoiceNone:
+		return map[string]interface{}{"type": "none"}
+	default:
+		return map[string]interface{}{"type": "auto"}
+	}
+}
+
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "":
+		return ""
+	case "tool_use":
+		return FinishReasonToolCalls
+	case "max_tokens":
+		return FinishReasonLength
+	default:
+		return FinishReasonStop
+	}
+}
+
@@ -0,0 +1,533 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI data-plane API version used when none is set.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureOpenAIConfig locates an Azure OpenAI resource. Deployments maps the model names
+// clients send to the resource's deployment names; a model without an entry is used
+// as the deployment name. For BYOK keys it is stored as the key's metadata.
+type AzureOpenAIConfig struct {
+	Endpoint    string            `json:"endpoint"` // https://{resource}.openai.azure.com
+	APIVersion  string            `json:"api_version,omitempty"`
+	Deployments map[string]string `json:"deployments,omitempty"`
+
+	byok bool
+}
+
+// azureBYOKHosts are the endpoint hosts BYOK keys may use: a name ending in one of the
+// suffixes, or a host an admin allowed with SetAzureBYOKHosts.
+var (
+	azureBYOKHostSuffixes = []string{".openai.azure.com", ".cognitiveservices.azure.com"}
+	azureBYOKHostsMu      sync.RWMutex
+	azureBYOKHosts        []string
+)
+
+// SetAzureBYOKHosts allows BYOK keys to use endpoints on hosts other than Azure's.
+// An entry starting with "." allows every host under it.
+func SetAzureBYOKHosts(hosts []string) {
+	azureBYOKHostsMu.Lock()
+	defer azureBYOKHostsMu.Unlock()
+	azureBYOKHosts = make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		azureBYOKHosts = append(azureBYOKHosts, strings.ToLower(host))
+	}
+}
+
+func azureBYOKHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	azureBYOKHostsMu.RLock()
+	defer azureBYOKHostsMu.RUnlock()
+	for _, suffix := range azureBYOKHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	for _, allowed := range azureBYOKHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c AzureOpenAIConfig) Validate() error {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("endpoint must be an https URL such as https://my-resource.openai.azure.com")
+	}
+	for model, deployment := range c.Deployments {
+		if model == "" || deployment == "" || strings.ContainsAny(deployment, "/?#") {
+			return fmt.Errorf("invalid deployment %q for model %q", deployment, model)
+		}
+	}
+	return nil
+}
+
+// ParseAzureOpenAIMetadata reads the endpoint and deployments stored with a BYOK key.
+// Users choose the endpoint, so it must be an Azure host (see SetAzureBYOKHosts) and
+// providers built from it only connect to public addresses.
+func ParseAzureOpenAIMetadata(metadata []byte) (AzureOpenAIConfig, error) {
+	var config AzureOpenAIConfig
+	if len(metadata) == 0 {
+		return config, fmt.Errorf("azure keys need an endpoint")
+	}
+	if err := json.Unmarshal(metadata, &config); err != nil {
+		return config, fmt.Errorf("invalid azure metadata: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+	u, _ := url.Parse(config.Endpoint)
+	if !azureBYOKHostAllowed(u.Hostname()) {
+		return config, fmt.Errorf("endpoint host %s is not an Azure OpenAI resource", u.Hostname())
+	}
+	config.byok = true
+	return config, nil
+}
+
+// publicTransport refuses connections to loopback, private and link-local addresses.
+// The check runs on the resolved address, so DNS names cannot point it inside.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("refusing to connect to non-public address %s", host)
+			}
+			return nil
+		},
+	}
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+type AzureOpenAIProvider struct {
+	apiKey     string
+	endpoint   string
+	apiVersion string
+	config     AzureOpenAIConfig
+	client     *http.Client
+	transport  http.RoundTripper
+	logger     zerolog.Logger
+}
+
+func NewAzureOpenAIProvider(apiKey string, config AzureOpenAIConfig, logger zerolog.Logger) *AzureOpenAIProvider {
+	apiVersion := config.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+	var transport http.RoundTripper
+	if config.byok {
+		transport = publicTransport()
+	}
+	return &AzureOpenAIProvider{
+		apiKey:     apiKey,
+		endpoint:   strings.TrimSuffix(config.Endpoint, "/"),
+		apiVersion: apiVersion,
+		config:     config,
+		client: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: transport,
+		},
+		transport: transport,
+		logger:    logger,
+	}
+}
+
+func (p *AzureOpenAIProvider) Name() string {
+	return "azure"
+}
+
+func (p *AzureOpenAIProvider) streamClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Minute, Transport: p.transport}
+}
+
+// Deployment returns the deployment that serves model.
+func (p *AzureOpenAIProvider) Deployment(model string) string {
+	if deployment, ok := p.config.Deployments[model]; ok {
+		return deployment
+	}
+	return model
+}
+
+func (p *AzureOpenAIProvider) url(path string) string {
+	return fmt.Sprintf("%s/openai%s?api-version=%s", p.endpoint, path, url.QueryEscape(p.apiVersion))
+}
+
+func (p *AzureOpenAIProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.url(path), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("api-key", p.apiKey)
+	return req, nil
+}
+
+func (p *AzureOpenAIProvider) requestBody(req ChatRequest, stream bool) map[string]interface{} {
+	// The deployment decides the model; Azure ignores "model" in the body.
+	body := map[string]interface{}{
+		"messages": convertMessagesToOpenAI(req.Messages),
+	}
+	if stream {
+		body["stream"] = true
+		body["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	applyOpenAISampling(body, req)
+	if len(req.Tools) > 0 {
+		body["tools"] = openAITools(req)
+		if req.ToolChoice != nil {
+			body["tool_choice"] = req.ToolChoice
+		}
+	}
+	if req.ResponseFormat.WantsJSON() {
+		body["response_format"] = req.ResponseFormat
+	}
+	return body
+}
+
+// azureFilterResults are Azure's content filter annotations, keyed by category (hate,
+// sexual, violence, self_harm, jailbreak, protected_material_text, ...).
+type azureFilterResults map[string]struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity"`
+	Detected bool   `json:"detected"`
+}
+
+func (r azureFilterResults) filtered() map[string]string {
+	out := make(map[string]string)
+	for category, result := range r {
+		if !result.Filtered {
+			continue
+		}
+		switch {
+		case result.Severity != "":
+			out[category] = result.Severity
+		case result.Detected:
+			out[category] = "detected"
+		default:
+			out[category] = "filtered"
+		}
+	}
+	return out
+}
+
+func (p *AzureOpenAIProvider) contentFilterError(stage string, statusCode int, message string, results azureFilterResults) *ContentFilterError {
+	categories := results.filtered()
+	if message == "" {
+		names := make([]string, 0, len(categories))
+		for category := range categories {
+			names = append(names, category)
+		}
+		sort.Strings(names)
+		message = fmt.Sprintf("Azure OpenAI content filter blocked the %s", stage)
+		if len(names) > 0 {
+			message += " (" + strings.Join(names, ", ") + ")"
+		}
+	}
+	return &ContentFilterError{
+		ProviderError: ProviderError{
+			Provider:   p.Name(),
+			Kind:       ErrorKindContentFilter,
+			StatusCode: statusCode,
+			Message:    message,
+		},
+		Stage:      stage,
+		Categories: categories,
+	}
+}
+
+// statusError reads Azure's error body. Prompts rejected by the content filter come
+// back as 400 with code content_filter and the filter results in innererror.
+func (p *AzureOpenAIProvider) statusError(resp *http.Response, body []byte) error {
+	var errResp struct {
+		Error struct {
+			Message    string `json:"message"`
+			Code       string `json:"code"`
+			InnerError struct {
+				ContentFilterResult azureFilterResults `json:"content_filter_result"`
+			} `json:"innererror"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+		if errResp.Error.Code == "content_filter" {
+			return p.contentFilterError("prompt", resp.StatusCode, "Azure OpenAI content filter: "+errResp.Error.Message, errResp.Error.InnerError.ContentFilterResult)
+		}
+		return newStatusError(p.Name(), resp, fmt.Sprintf("Azure OpenAI API error: %s", errResp.Error.Message))
+	}
+	// Other bodies are not passed on: they may not come from Azure at all.
+	return newStatusError(p.Name(), resp, fmt.Sprintf("Azure OpenAI API returned status %d", resp.StatusCode))
+}
+
+func (p *AzureOpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("Azure OpenAI API key not configured")
+	}
+
+	reqBody, err := json.Marshal(p.requestBody(req, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	deployment := p.Deployment(req.Model)
+	httpReq, err := p.newRequest(ctx, "POST", "/deployments/"+url.PathEscape(deployment)+"/chat/completions", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.logger.Debug().
+		Str("provider", "azure").
+		Str("model", req.Model).
+		Str("deployment", deployment).
+		Msg("sending request to Azure OpenAI")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, newTransportError(p.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.statusError(resp, respBody)
+	}
+
+	var azureResp struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role      string     `json:"role"`
+				Content   string     `json:"content"`
+				ToolCalls []ToolCall `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason         string             `json:"finish_reason"`
+			ContentFilterResults azureFilterResults `json:"content_filter_results"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &azureResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	choices := make([]Choice, 0, len(azureResp.Choices))
+	for _, c := range azureResp.Choices {
+		if c.FinishReason == "content_filter" {
+			return nil, p.contentFilterError("completion", resp.StatusCode, "", c.ContentFilterResults)
+		}
+		choices = append(choices, Choice{
+			Message:      Message{Role: c.Message.Role, Content: c.Message.Content, ToolCalls: c.Message.ToolCalls},
+			FinishReason: c.FinishReason,
+		})
+	}
+
+	return &ChatResponse{
+		ID:      azureResp.ID,
+		Model:   azureResp.Model,
+		Choices: choices,
+		Usage:   azureResp.Usage.toUsage(),
+	}, nil
+}
+
+// ChatStream reads Azure's SSE variant: the first event carries only
+// prompt_filter_results and no choices, choices carry content_filter_results, and
+// with asynchronous filtering the filter results for earlier text can arrive in
+// events without a delta. A completion stopped by the filter ends the stream with a
+// ContentFilterError.
+func (p *AzureOpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamChunk, <-chan error) {
+	chunkChan := make(chan StreamChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		if p.apiKey == "" {
+			errChan <- fmt.Errorf("Azure OpenAI API key not configured")
+			return
+		}
+
+		reqBody, err := json.Marshal(p.requestBody(req, true))
+		if err != nil {
+			errChan <- fmt.Errorf("failed to marshal request: %w", err)
+			return
+		}
+
+		httpReq, err := p.newRequest(ctx, "POST", "/deployments/"+url.PathEscape(p.Deployment(req.Model))+"/chat/completions", reqBody)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to create request: %w", err)
+			return
+		}
+
+		resp, err := p.streamClient().Do(httpReq)
+		if err != nil {
+			errChan <- newTransportError(p.Name(), err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(resp.Body)
+			errChan <- p.statusError(resp, b)
+			return
+		}
+
+		var responseID string
+		var finalUsage *Usage
+		var finishReason string
+		scanner := bufio.NewScanner(resp.Body)
+		const maxLineSize = 1024 * 1024
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, maxLineSize)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				chunkChan <- StreamChunk{ID: responseID, Done: true, Usage: finalUsage, FinishReason: finishReason}
+				return
+			}
+
+			var chunk struct {
+				ID      string `json:"id"`
+				Choices []struct {
+					Delta struct {
+						Content   string          `json:"content"`
+						ToolCalls []ToolCallDelta `json:"tool_calls"`
+					} `json:"delta"`
+					FinishReason         string             `json:"finish_reason"`
+					ContentFilterResults azureFilterResults `json:"content_filter_results"`
+				} `json:"choices"`
+				Usage *openAIUsage `json:"usage"`
+			}
+			if json.Unmarshal([]byte(data), &chunk) != nil {
+				continue
+			}
+
+			if responseID == "" && chunk.ID != "" {
+				responseID = chunk.ID
+			}
+			if chunk.Usage != nil {
+				usage := chunk.Usage.toUsage()
+				finalUsage = &usage
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			choice := chunk.Choices[0]
+			if choice.FinishReason == "content_filter" {
+				errChan <- p.contentFilterError("completion", resp.StatusCode, "", choice.ContentFilterResults)
+				return
+			}
+			if choice.Delta.Content != "" {
+				chunkChan <- StreamChunk{ID: responseID, Content: choice.Delta.Content}
+			}
+			if len(choice.Delta.ToolCalls) > 0 {
+				chunkChan <- StreamChunk{ID: responseID, ToolCalls: choice.Delta.ToolCalls}
+			}
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- err
+		}
+	}()
+
+	return chunkChan, errChan
+}
+
+func (p *AzureOpenAIProvider) HealthCheck(ctx context.Context) error {
+	if p.apiKey == "" {
+		return fmt.Errorf("Azure OpenAI API key not configured")
+	}
+
+	req, err := p.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetModels returns the model names with a deployment. Azure keys cannot list a
+// resource's deployments, so they come from the configuration.
+func (p *AzureOpenAIProvider) GetModels() []string {
+	models := make([]string, 0, len(p.config.Deployments))
+	for model := range p.config.Deployments {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+func (p *AzureOpenAIProvider) SupportsResponseFormat(model string) bool {
+	return true
+}
+
+func (p *AzureOpenAIProvider) SupportsTools(model string) bool {
+	m := strings.ToLower(model)
+	return !strings.HasPrefix(m, "o1-mini") && !strings.HasPrefix(m, "o1-preview")
+}
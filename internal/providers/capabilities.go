@@ -0,0 +1,243 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Capabilities describes what a model accepts. ContextLength and MaxOutputTokens are
+// 0 when unknown.
+type Capabilities struct {
+	ContextLength   int  `json:"context_length,omitempty"`
+	MaxOutputTokens int  `json:"max_output_tokens,omitempty"`
+	Vision          bool `json:"vision"`
+	Audio           bool `json:"audio"`
+	Tools           bool `json:"tools"`
+	JSONMode        bool `json:"json_mode"`
+	Streaming       bool `json:"streaming"`
+	WebSearch       bool `json:"web_search"`
+}
+
+// Missing lists the capabilities in needs that c lacks, as readable names. Only the
+// input capabilities a request can depend on are compared: vision, audio, tools and
+// web search.
+func (c Capabilities) Missing(needs Capabilities) []string {
+	var missing []string
+	if needs.Vision && !c.Vision {
+		missing = append(missing, "image input")
+	}
+	if needs.Audio && !c.Audio {
+		missing = append(missing, "audio input")
+	}
+	if needs.Tools && !c.Tools {
+		missing = append(missing, "tool calling")
+	}
+	if needs.WebSearch && !c.WebSearch {
+		missing = append(missing, "web search")
+	}
+	return missing
+}
+
+// RequiredCapabilities is what req needs from a model: image or audio input when a
+// message carries such parts, tool calling when it has tools, and web search.
+func RequiredCapabilities(req ChatRequest) Capabilities {
+	needs := Capabilities{
+		Tools:     len(req.Tools) > 0,
+		WebSearch: req.WebSearch || req.GoogleSearchGrounding,
+	}
+	for _, msg := range req.Messages {
+		_, parts := NormalizeMessageContent(msg.Content)
+		for _, part := range parts {
+			switch part.Type {
+			case "image_url":
+				needs.Vision = true
+			case "audio_url":
+				needs.Audio = true
+			}
+		}
+	}
+	return needs
+}
+
+// modelFamily is the built-in data for models whose name starts with prefix.
+// Provider is the provider that serves the family, used to route model names no
+// provider lists; it is empty for open-weight families also sold by hosted APIs.
+type modelFamily struct {
+	prefix       string
+	provider     string
+	capabilities Capabilities
+}
+
+// Built-in model families, longest prefixes first within a family. Local families
+// leave the context length unknown: Ollama reports it through discovery.
+var modelFamilies = []modelFamily{
+	{"gpt-5", "openai", Capabilities{ContextLength: 400000, MaxOutputTokens: 128000, Vision: true, WebSearch: true}},
+	{"gpt-4.1", "openai", Capabilities{ContextLength: 1047576, MaxOutputTokens: 32768, Vision: true, WebSearch: true}},
+	{"gpt-4o-audio", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 16384, Audio: true}},
+	{"gpt-4o-mini-audio", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 16384, Audio: true}},
+	{"gpt-4o", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 16384, Vision: true, WebSearch: true}},
+	{"chatgpt-4o", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 16384, Vision: true}},
+	{"gpt-4-turbo", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 4096, Vision: true}},
+	{"gpt-4-1106", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 4096}},
+	{"gpt-4-0125", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 4096}},
+	{"gpt-4-32k", "openai", Capabilities{ContextLength: 32768, MaxOutputTokens: 4096}},
+	{"gpt-4", "openai", Capabilities{ContextLength: 8192, MaxOutputTokens: 8192}},
+	{"gpt-3.5-turbo-instruct", "openai", Capabilities{ContextLength: 4096, MaxOutputTokens: 4096}},
+	{"gpt-3.5-turbo", "openai", Capabilities{ContextLength: 16385, MaxOutputTokens: 4096}},
+	{"o1-mini", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 65536}},
+	{"o1-preview", "openai", Capabilities{ContextLength: 128000, MaxOutputTokens: 32768}},
+	{"o1", "openai", Capabilities{ContextLength: 200000, MaxOutputTokens: 100000, Vision: true}},
+	{"o3-mini", "openai", Capabilities{ContextLength: 200000, MaxOutputTokens: 100000}},
+	{"o3", "openai", Capabilities{ContextLength: 200000, MaxOutputTokens: 100000, Vision: true, WebSearch: true}},
+	{"o4", "openai", Capabilities{ContextLength: 200000, MaxOutputTokens: 100000, Vision: true, WebSearch: true}},
+	{"claude-instant", "anthropic", Capabilities{ContextLength: 100000, MaxOutputTokens: 4096}},
+	{"claude-2", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 4096}},
+	{"claude-3-haiku", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 4096, Vision: true}},
+	{"claude-3-sonnet", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 4096, Vision: true}},
+	{"claude-3-opus", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 4096, Vision: true}},
+	{"claude-3-5", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 8192, Vision: true, WebSearch: true}},
+	{"claude-3-7", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 64000, Vision: true, WebSearch: true}},
+	{"claude-opus-4", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 32000, Vision: true, WebSearch: true}},
+	{"claude", "anthropic", Capabilities{ContextLength: 200000, MaxOutputTokens: 64000, Vision: true, WebSearch: true}},
+	{"gemini-pro-vision", "google", Capabilities{ContextLength: 16384, MaxOutputTokens: 2048, Vision: true}},
+	{"gemini-pro", "google", Capabilities{ContextLength: 32760, MaxOutputTokens: 8192, WebSearch: true}},
+	{"gemini-1.5-pro", "google", Capabilities{ContextLength: 2097152, MaxOutputTokens: 8192, Vision: true, Audio: true, WebSearch: true}},
+	{"gemini-1.5-flash", "google", Capabilities{ContextLength: 1048576, MaxOutputTokens: 8192, Vision: true, Audio: true, WebSearch: true}},
+	{"gemini-2.0", "google", Capabilities{ContextLength: 1048576, MaxOutputTokens: 8192, Vision: true, Audio: true, WebSearch: true}},
+	{"gemini-2", "google", Capabilities{ContextLength: 1048576, MaxOutputTokens: 65536, Vision: true, Audio: true, WebSearch: true}},
+	{"gemini-3", "google", Capabilities{ContextLength: 1048576, MaxOutputTokens: 65536, Vision: true, Audio: true, WebSearch: true}},
+	{"llava", "local", Capabilities{Vision: true}},
+	{"bakllava", "local", Capabilities{Vision: true}},
+	{"llama3.2-vision", "local", Capabilities{Vision: true}},
+	{"llama", "local", Capabilities{}},
+	{"codellama", "local", Capabilities{}},
+	{"mistral", "", Capabilities{}},
+	{"mixtral", "", Capabilities{}},
+	{"phi", "local", Capabilities{}},
+	{"neural-chat", "local", Capabilities{}},
+	{"orca", "local", Capabilities{}},
+	{"qwen", "", Capabilities{}},
+	{"gemma3", "local", Capabilities{Vision: true}},
+	{"gemma", "local", Capabilities{}},
+	{"deepseek", "", Capabilities{}},
+}
+
+func lookupModelFamily(model string) (modelFamily, bool) {
+	name := strings.ToLower(model)
+	for _, family := range modelFamilies {
+		if strings.HasPrefix(name, family.prefix) {
+			return family, true
+		}
+	}
+	return modelFamily{}, false
+}
+
+// ModelProvider returns the provider that serves a well-known model family.
+func ModelProvider(model string) (string, bool) {
+	family, ok := lookupModelFamily(model)
+	return family.provider, ok && family.provider != ""
+}
+
+// ModelContextLength returns the context length of well-known models.
+func ModelContextLength(model string) (int, bool) {
+	family, ok := lookupModelFamily(model)
+	return family.capabilities.ContextLength, ok && family.capabilities.ContextLength > 0
+}
+
+// providerDefaults apply to models a known provider serves but the built-in data
+// does not cover. Other providers are assumed to accept media and reject what their
+// model cannot handle themselves.
+var providerDefaults = map[string]Capabilities{
+	"openai":    {Vision: true, WebSearch: true},
+	"anthropic": {Vision: true, WebSearch: true},
+	"google":    {Vision: true, Audio: true, WebSearch: true},
+	"local":     {},
+	"vllm":      {Vision: true, Audio: true},
+	"azure":     {Vision: true},
+}
+
+// CapabilityDiscoverer is implemented by providers that can describe the models they
+// serve, such as Ollama and vLLM.
+type CapabilityDiscoverer interface {
+	DiscoverCapabilities(ctx context.Context) (map[string]Capabilities, error)
+}
+
+// CapabilityRegistry resolves the capabilities of a model on a provider. Discovered
+// data comes first, then the built-in model families, then the provider's defaults.
+// Tools, JSON mode and streaming also require the provider to implement them.
+type CapabilityRegistry struct {
+	mu         sync.RWMutex
+	discovered map[string]map[string]Capabilities
+}
+
+func NewCapabilityRegistry() *CapabilityRegistry {
+	return &CapabilityRegistry{discovered: make(map[string]map[string]Capabilities)}
+}
+
+// Set replaces the discovered capabilities of provider's models.
+func (r *CapabilityRegistry) Set(provider string, models map[string]Capabilities) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discovered[provider] = models
+}
+
+// Discover asks provider for the capabilities of its models. Providers that cannot
+// describe their models are left to the built-in data.
+func (r *CapabilityRegistry) Discover(ctx context.Context, provider Provider) error {
+	discoverer, ok := provider.(CapabilityDiscoverer)
+	if !ok {
+		return nil
+	}
+	models, err := discoverer.DiscoverCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	r.Set(provider.Name(), models)
+	return nil
+}
+
+func (r *CapabilityRegistry) lookupDiscovered(provider, model string) (Capabilities, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	caps, ok := r.discovered[provider][model]
+	return caps, ok
+}
+
+// Lookup returns the capabilities of model when served by provider.
+func (r *CapabilityRegistry) Lookup(provider Provider, model string) Capabilities {
+	caps, discovered := r.lookupDiscovered(provider.Name(), model)
+	if !discovered {
+		if family, ok := lookupModelFamily(model); ok {
+			caps = family.capabilities
+			// Web search is a feature of the vendor's own API, not of the model, so
+			// the same model on Azure or an OpenAI-compatible upstream has none.
+			caps.WebSearch = caps.WebSearch && family.provider == provider.Name()
+		} else if defaults, ok := providerDefaults[provider.Name()]; ok {
+			caps = defaults
+		} else {
+			caps = Capabilities{Vision: true, Audio: true}
+		}
+	}
+	caps.Tools = SupportsTools(provider, model) && (!discovered || caps.Tools)
+	caps.JSONMode = SupportsResponseFormat(provider, model)
+	_, caps.Streaming = provider.(StreamingProvider)
+	return caps
+}
+
+// ContextLength returns the context length of model as discovered, the smallest when
+// several providers serve it, or from the built-in data.
+func (r *CapabilityRegistry) ContextLength(model string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	length := 0
+	for _, models := range r.discovered {
+		if caps, ok := models[model]; ok && caps.ContextLength > 0 && (length == 0 || caps.ContextLength < length) {
+			length = caps.ContextLength
+		}
+	}
+	if length > 0 {
+		return length, true
+	}
+	return ModelContextLength(model)
+}
@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type EmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// EmbeddingResponse holds one vector per input, in input order.
+type EmbeddingResponse struct {
+	Model      string      `json:"model"`
+	Provider   string      `json:"provider,omitempty"`
+	Embeddings [][]float64 `json:"embeddings"`
+	Usage      Usage       `json:"usage"`
+	Cost       float64     `json:"cost,omitempty"`
+	LatencyMs  int64       `json:"latency_ms,omitempty"`
+}
+
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error)
+	GetEmbeddingModels() []string
+}
+
+// embedOpenAICompatible calls an OpenAI-style /embeddings endpoint (OpenAI and vLLM).
+func embedOpenAICompatible(ctx context.Context, client *http.Client, url, apiKey, providerLabel string, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	body := map[string]interface{}{
+		"model":           req.Model,
+		"input":           req.Input,
+		"encoding_format": "float",
+	}
+	if req.Dimensions > 0 {
+		body["dimensions"] = req.Dimensions
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, newTransportError(strings.ToLower(providerLabel), err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errorResp) == nil && errorResp.Error.Message != "" {
+			return nil, newStatusError(strings.ToLower(providerLabel), resp, fmt.Sprintf("%s API error: %s", providerLabel, errorResp.Error.Message))
+		}
+		return nil, newStatusError(strings.ToLower(providerLabel), resp, fmt.Sprintf("%s API returned status %d: %s", providerLabel, resp.StatusCode, string(respBody)))
+	}
+
+	var embedResp struct {
+		Model string `json:"model"`
+		Data  []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	embeddings := make([][]float64, len(req.Input))
+	for _, d := range embedResp.Data {
+		if d.Index >= 0 && d.Index < len(embeddings) {
+			embeddings[d.Index] = d.Embedding
+		}
+	}
+
+	model := embedResp.Model
+	if model == "" {
+		model = req.Model
+	}
+	return &EmbeddingResponse{
+		Model:      model,
+		Embeddings: embeddings,
+		Usage: Usage{
+			PromptTokens: embedResp.Usage.PromptTokens,
+			TotalTokens:  embedResp.Usage.TotalTokens,
+		},
+	}, nil
+}
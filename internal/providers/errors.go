@@ -0,0 +1,203 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ErrorKind string
+
+const (
+	ErrorKindRateLimit      ErrorKind = "rate_limit"
+	ErrorKindAuth           ErrorKind = "authentication"
+	ErrorKindInvalidRequest ErrorKind = "invalid_request"
+	ErrorKindContextLength  ErrorKind = "context_length_exceeded"
+	ErrorKindContentFilter  ErrorKind = "content_filter"
+	ErrorKindNotFound       ErrorKind = "not_found" // e.g. a model this provider does not serve
+	ErrorKindServer         ErrorKind = "server_error"
+	ErrorKindTimeout        ErrorKind = "timeout"
+	ErrorKindUnavailable    ErrorKind = "unavailable" // connection refused, DNS failure, ...
+)
+
+// ProviderError is a classified upstream failure. Adapters return it so the
+// router can decide between retrying, failing over, and giving up.
+type ProviderError struct {
+	Provider   string
+	Kind       ErrorKind
+	StatusCode int           // upstream HTTP status, 0 for transport errors
+	RetryAfter time.Duration // from the Retry-After header, if any
+	Message    string
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Message
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the same provider may succeed if asked again.
+func (e *ProviderError) Retryable() bool {
+	switch e.Kind {
+	case ErrorKindRateLimit, ErrorKindServer, ErrorKindTimeout, ErrorKindUnavailable:
+		return true
+	}
+	return false
+}
+
+// ClientError reports whether the request itself is at fault, so no other provider would accept it either.
+func (e *ProviderError) ClientError() bool {
+	return e.Kind == ErrorKindInvalidRequest || e.Kind == ErrorKindContextLength || e.Kind == ErrorKindContentFilter
+}
+
+// HTTPStatus is the status the gateway should answer with for this error.
+func (e *ProviderError) HTTPStatus() int {
+	switch e.Kind {
+	case ErrorKindRateLimit:
+		return http.StatusTooManyRequests
+	case ErrorKindAuth:
+		// The caller's credentials are fine; the provider key is not.
+		return http.StatusBadGateway
+	case ErrorKindInvalidRequest, ErrorKindContextLength, ErrorKindContentFilter:
+		if e.StatusCode >= 400 && e.StatusCode < 500 {
+			return e.StatusCode
+		}
+		return http.StatusBadRequest
+	case ErrorKindNotFound:
+		return http.StatusNotFound
+	case ErrorKindTimeout:
+		return http.StatusGatewayTimeout
+	case ErrorKindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		if e.StatusCode >= 500 && e.StatusCode < 600 {
+			return e.StatusCode
+		}
+		return http.StatusBadGateway
+	}
+}
+
+func AsProviderError(err error) (*ProviderError, bool) {
+	var perr *ProviderError
+	if errors.As(err, &perr) {
+		return perr, true
+	}
+	return nil, false
+}
+
+// ContentFilterError is a prompt or completion blocked by the provider's content
+// filter. It is a client error: failing over would route around the filter.
+type ContentFilterError struct {
+	ProviderError
+	Stage      string            // "prompt" or "completion"
+	Categories map[string]string // filtered category to severity, or "detected"
+}
+
+func (e *ContentFilterError) Unwrap() error {
+	return &e.ProviderError
+}
+
+func AsContentFilterError(err error) (*ContentFilterError, bool) {
+	var cerr *ContentFilterError
+	if errors.As(err, &cerr) {
+		return cerr, true
+	}
+	return nil, false
+}
+
+// newStatusError classifies a non-2xx response. message is the adapter's error text.
+func newStatusError(provider string, resp *http.Response, message string) *ProviderError {
+	return &ProviderError{
+		Provider:   provider,
+		Kind:       classifyStatus(resp.StatusCode, message),
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header),
+		Message:    message,
+	}
+}
+
+// newTransportError wraps a failure to reach the provider at all.
+func newTransportError(provider string, err error) *ProviderError {
+	kind := ErrorKindUnavailable
+	if isTimeoutError(err) {
+		kind = ErrorKindTimeout
+	}
+	return &ProviderError{
+		Provider: provider,
+		Kind:     kind,
+		Message:  fmt.Sprintf("failed to send request: %v", err),
+		Err:      err,
+	}
+}
+
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"context length",
+	"maximum context",
+	"context window",
+	"prompt is too long",
+	"too many tokens",
+	"exceeds the maximum number of tokens",
+	"input token count",
+}
+
+func classifyStatus(status int, message string) ErrorKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrorKindRateLimit
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorKindAuth
+	case status == http.StatusNotFound:
+		// Not the caller's fault: another provider may serve the model.
+		return ErrorKindNotFound
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrorKindTimeout
+	case status >= 500:
+		return ErrorKindServer
+	}
+	lower := strings.ToLower(message)
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(lower, marker) {
+			return ErrorKindContextLength
+		}
+	}
+	return ErrorKindInvalidRequest
+}
+
+// parseRetryAfter reads Retry-After (seconds or HTTP date) and OpenAI's retry-after-ms.
+func parseRetryAfter(h http.Header) time.Duration {
+	if ms := h.Get("retry-after-ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Millisecond))
+		}
+	}
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
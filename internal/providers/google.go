@@ -19,6 +19,7 @@ type GoogleProvider struct {
 	baseURL string
 	client  *http.Client
 	logger  zerolog.Logger
+	catalog *ModelCatalog
 }
 
 func NewGoogleProvider(apiKey, baseURL string, logger zerolog.Logger) *GoogleProvider {
@@ -47,19 +48,18 @@ func (p *GoogleProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 	if p.apiKey == "" {
 		return nil, fmt.Errorf("Google API key not configured")
 	}
+	if err := unsupportedSampling(p.Name(), req, "logit_bias"); err != nil {
+		return nil, err
+	}
 
 	googleReq := map[string]interface{}{
 		"contents": convertMessagesToGoogle(req.Messages),
 	}
-	if req.Temperature > 0 {
-		googleReq["temperature"] = req.Temperature
-	}
-	if req.MaxTokens > 0 {
-		googleReq["maxOutputTokens"] = req.MaxTokens
-	}
-	if req.GoogleSearchGrounding {
-		googleReq["tools"] = []map[string]interface{}{{"google_search": map[string]interface{}{}}}
+	if genConfig := googleGenerationConfig(req); len(genConfig) > 0 {
+		googleReq["generationConfig"] = genConfig
 	}
+	applyGoogleTools(googleReq, req)
+	applyGoogleResponseFormat(googleReq, req)
 
 	reqBody, err := json.Marshal(googleReq)
 	if err != nil {
@@ -81,7 +81,7 @@ func (p *GoogleProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, newTransportError("google", err)
 	}
 	defer resp.Body.Close()
 
@@ -97,24 +97,24 @@ func (p *GoogleProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 				Status  string `json:"status"`
 			} `json:"error"`
 		}
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, fmt.Errorf("Google API error: %s", errorResp.Error.Message)
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+			return nil, newStatusError("google", resp, fmt.Sprintf("Google API error: %s", errorResp.Error.Message))
 		}
-		return nil, fmt.Errorf("Google API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError("google", resp, fmt.Sprintf("Google API returned status %d: %s", resp.StatusCode, string(body)))
 	}
 
 	var googleResp struct {
 		Candidates []struct {
 			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
+				Parts []googlePart `json:"parts"`
 			} `json:"content"`
+			FinishReason string `json:"finishReason"`
 		} `json:"candidates"`
 		UsageMetadata struct {
-			PromptTokenCount     int `json:"promptTokenCount"`
-			CandidatesTokenCount int `json:"candidatesTokenCount"`
-			TotalTokenCount      int `json:"totalTokenCount"`
+			PromptTokenCount        int `json:"promptTokenCount"`
+			CandidatesTokenCount    int `json:"candidatesTokenCount"`
+			TotalTokenCount         int `json:"totalTokenCount"`
+			CachedContentTokenCount int `json:"cachedContentTokenCount"`
 		} `json:"usageMetadata"`
 	}
 
@@ -123,8 +123,18 @@ func (p *GoogleProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 	}
 
 	content := ""
-	if len(googleResp.Candidates) > 0 && len(googleResp.Candidates[0].Content.Parts) > 0 {
-		content = googleResp.Candidates[0].Content.Parts[0].Text
+	var toolCalls []ToolCall
+	finishReason := ""
+	if len(googleResp.Candidates) > 0 {
+		candidate := googleResp.Candidates[0]
+		for _, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil {
+				toolCalls = append(toolCalls, part.toolCall(len(toolCalls)))
+			} else if content == "" {
+				content = part.Text
+			}
+		}
+		finishReason = googleFinishReason(candidate.FinishReason, len(toolCalls) > 0)
 	}
 
 	return &ChatResponse{
@@ -133,15 +143,18 @@ func (p *GoogleProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		Choices: []Choice{
 			{
 				Message: Message{
-					Role:    "assistant",
-					Content: content,
+					Role:      "assistant",
+					Content:   content,
+					ToolCalls: toolCalls,
 				},
+				FinishReason: finishReason,
 			},
 		},
 		Usage: Usage{
-			PromptTokens:     googleResp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: googleResp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      googleResp.UsageMetadata.TotalTokenCount,
+			PromptTokens:       googleResp.UsageMetadata.PromptTokenCount,
+			CompletionTokens:   googleResp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:        googleResp.UsageMetadata.TotalTokenCount,
+			CachedPromptTokens: googleResp.UsageMetadata.CachedContentTokenCount,
 		},
 	}, nil
 }
@@ -173,23 +186,98 @@ func (p *GoogleProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+var googleModels = []string{
+	"gemini-3-pro-preview", "gemini-3-flash-preview",
+	"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.5-flash-lite",
+	"gemini-2.0-flash-exp",
+	"gemini-1.5-pro-latest", "gemini-1.5-pro", "gemini-1.5-flash-8b", "gemini-1.5-flash",
+	"gemini-pro", "gemini-pro-vision",
+}
+
+func (p *GoogleProvider) SetModelCatalog(catalog *ModelCatalog) {
+	p.catalog = catalog
+}
+
 func (p *GoogleProvider) GetModels() []string {
-	return []string{
-		"gemini-3-pro-preview", "gemini-3-flash-preview",
-		"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.5-flash-lite",
-		"gemini-2.0-flash-exp",
-		"gemini-1.5-pro-latest", "gemini-1.5-pro", "gemini-1.5-flash-8b", "gemini-1.5-flash",
-		"gemini-pro", "gemini-pro-vision",
+	if p.catalog == nil {
+		return googleModels
 	}
+	return p.catalog.Models(p.Name(), p.baseURL+"\x00"+p.apiKey, p.listModels, googleModels)
+}
+
+// listModels returns the models that support generateContent, without the "models/" prefix.
+func (p *GoogleProvider) listModels(ctx context.Context) ([]string, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("Google API key not configured")
+	}
+	url := fmt.Sprintf("%s/models?pageSize=1000&key=%s", p.baseURL, p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("models request returned status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+	models := make([]string, 0, len(list.Models))
+	for _, m := range list.Models {
+		for _, method := range m.SupportedGenerationMethods {
+			if method == "generateContent" {
+				models = append(models, strings.TrimPrefix(m.Name, "models/"))
+				break
+			}
+		}
+	}
+	return models, nil
 }
 
 func convertMessagesToGoogle(messages []Message) []map[string]interface{} {
 	result := make([]map[string]interface{}, 0, len(messages))
+	callNames := toolCallNames(messages)
 	for _, msg := range messages {
+		if msg.Role == "tool" {
+			part := map[string]interface{}{
+				"functionResponse": map[string]interface{}{
+					"name":     toolResultName(msg, callNames),
+					"response": googleFunctionResponse(messageContentString(msg.Content)),
+				},
+			}
+			// Gemini expects all responses to one model turn in a single user turn.
+			if n := len(result); n > 0 && result[n-1]["role"] == "user" {
+				if parts, ok := result[n-1]["parts"].([]map[string]interface{}); ok && len(parts) > 0 && parts[0]["functionResponse"] != nil {
+					result[n-1]["parts"] = append(parts, part)
+					continue
+				}
+			}
+			result = append(result, map[string]interface{}{
+				"role":  "user",
+				"parts": []map[string]interface{}{part},
+			})
+			continue
+		}
+
 		parts := make([]map[string]interface{}, 0)
 		text, partList := NormalizeMessageContent(msg.Content)
 		if partList == nil {
-			parts = append(parts, map[string]interface{}{"text": text})
+			if text != "" || len(msg.ToolCalls) == 0 {
+				parts = append(parts, map[string]interface{}{"text": text})
+			}
 		} else {
 			for _, part := range partList {
 				if part.Type == "text" {
@@ -237,6 +325,15 @@ func convertMessagesToGoogle(messages []Message) []map[string]interface{} {
 			}
 		}
 
+		for _, call := range msg.ToolCalls {
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{
+					"name": call.Function.Name,
+					"args": toolArgumentsMap(call.Function.Arguments),
+				},
+			})
+		}
+
 		role := msg.Role
 		if role == "assistant" {
 			role = "model"
@@ -269,23 +366,19 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 			errChan <- fmt.Errorf("Google API key not configured")
 			return
 		}
+		if err := unsupportedSampling(p.Name(), req, "logit_bias"); err != nil {
+			errChan <- err
+			return
+		}
 
 		googleReq := map[string]interface{}{
 			"contents": convertMessagesToGoogle(req.Messages),
 		}
-		genConfig := make(map[string]interface{})
-		if req.Temperature > 0 {
-			genConfig["temperature"] = req.Temperature
-		}
-		if req.MaxTokens > 0 {
-			genConfig["maxOutputTokens"] = req.MaxTokens
-		}
-		if len(genConfig) > 0 {
+		if genConfig := googleGenerationConfig(req); len(genConfig) > 0 {
 			googleReq["generationConfig"] = genConfig
 		}
-		if req.GoogleSearchGrounding {
-			googleReq["tools"] = []map[string]interface{}{{"google_search": map[string]interface{}{}}}
-		}
+		applyGoogleTools(googleReq, req)
+		applyGoogleResponseFormat(googleReq, req)
 
 		reqBody, err := json.Marshal(googleReq)
 		if err != nil {
@@ -304,7 +397,7 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 
 		resp, err := p.streamClient().Do(httpReq)
 		if err != nil {
-			errChan <- fmt.Errorf("failed to send request: %w", err)
+			errChan <- newTransportError("google", err)
 			return
 		}
 		defer resp.Body.Close()
@@ -317,13 +410,11 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 					Status  string `json:"status"`
 				} `json:"error"`
 			}
-			var apiErr string
 			if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
-				apiErr = fmt.Sprintf("Google API error: %s", errorResp.Error.Message)
+				errChan <- newStatusError("google", resp, fmt.Sprintf("Google API error: %s", errorResp.Error.Message))
 			} else {
-				apiErr = fmt.Sprintf("Google API returned status %d: %s", resp.StatusCode, string(body))
+				errChan <- newStatusError("google", resp, fmt.Sprintf("Google API returned status %d: %s", resp.StatusCode, string(body)))
 			}
-			chunkChan <- StreamChunk{Content: "", Done: true, Error: apiErr}
 			return
 		}
 
@@ -335,6 +426,8 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 		var previousText string
 		var finalUsage *Usage
 		var isDone bool
+		var finishReason string
+		var toolCallCount int
 		var dataBuf strings.Builder
 
 		flushData := func() (fatal bool) {
@@ -350,17 +443,16 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 			var geminiResp struct {
 				Candidates []struct {
 					Content struct {
-						Parts []struct {
-							Text string `json:"text"`
-						} `json:"parts"`
-						Role string `json:"role,omitempty"`
+						Parts []googlePart `json:"parts"`
+						Role  string       `json:"role,omitempty"`
 					} `json:"content"`
 					FinishReason string `json:"finishReason"`
 				} `json:"candidates"`
 				UsageMetadata struct {
-					PromptTokenCount     int `json:"promptTokenCount"`
-					CandidatesTokenCount int `json:"candidatesTokenCount"`
-					TotalTokenCount      int `json:"totalTokenCount"`
+					PromptTokenCount        int `json:"promptTokenCount"`
+					CandidatesTokenCount    int `json:"candidatesTokenCount"`
+					TotalTokenCount         int `json:"totalTokenCount"`
+					CachedContentTokenCount int `json:"cachedContentTokenCount"`
 				} `json:"usageMetadata"`
 			}
 
@@ -391,8 +483,21 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 
 			if len(geminiResp.Candidates) > 0 {
 				candidate := geminiResp.Candidates[0]
+				// Gemini sends each function call whole, so one delta carries the full call.
+				var toolDeltas []ToolCallDelta
+				for _, part := range candidate.Content.Parts {
+					if part.FunctionCall != nil {
+						call := part.toolCall(toolCallCount)
+						toolDeltas = append(toolDeltas, ToolCallDelta{Index: toolCallCount, ID: call.ID, Type: call.Type, Function: call.Function})
+						toolCallCount++
+					}
+				}
+				if len(toolDeltas) > 0 {
+					chunkChan <- StreamChunk{ID: responseID, ToolCalls: toolDeltas}
+				}
 				if candidate.FinishReason != "" {
 					isDone = true
+					finishReason = googleFinishReason(candidate.FinishReason, toolCallCount > 0)
 				}
 				if len(candidate.Content.Parts) > 0 {
 					var currentText strings.Builder
@@ -423,9 +528,10 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 
 			if geminiResp.UsageMetadata.TotalTokenCount > 0 {
 				finalUsage = &Usage{
-					PromptTokens:     geminiResp.UsageMetadata.PromptTokenCount,
-					CompletionTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
-					TotalTokens:      geminiResp.UsageMetadata.TotalTokenCount,
+					PromptTokens:       geminiResp.UsageMetadata.PromptTokenCount,
+					CompletionTokens:   geminiResp.UsageMetadata.CandidatesTokenCount,
+					TotalTokens:        geminiResp.UsageMetadata.TotalTokenCount,
+					CachedPromptTokens: geminiResp.UsageMetadata.CachedContentTokenCount,
 				}
 			}
 			return false
@@ -458,21 +564,113 @@ func (p *GoogleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 		}
 
 		errMsg := ""
-		if !isDone && previousText == "" {
+		if !isDone && previousText == "" && toolCallCount == 0 {
 			errMsg = "No content in response from model"
 		}
 		chunkChan <- StreamChunk{
-			ID:      responseID,
-			Content: "",
-			Done:    true,
-			Usage:   finalUsage,
-			Error:   errMsg,
+			ID:           responseID,
+			Content:      "",
+			Done:         true,
+			Usage:        finalUsage,
+			Error:        errMsg,
+			FinishReason: finishReason,
 		}
 	}()
 
 	return chunkChan, errChan
 }
 
+type googlePart struct {
+	Text         string `json:"text"`
+	FunctionCall *struct {
+		Name string                 `json:"name"`
+		Args map[string]interface{} `json:"args"`
+	} `json:"functionCall"`
+}
+
+// toolCall converts a functionCall part. Gemini does not assign call IDs, so
+// one is derived from the call's position in the response.
+func (p googlePart) toolCall(index int) ToolCall {
+	return ToolCall{
+		ID:   fmt.Sprintf("call_%d_%s", index, p.FunctionCall.Name),
+		Type: "function",
+		Function: ToolCallFunction{
+			Name:      p.FunctionCall.Name,
+			Arguments: toolArgumentsString(p.FunctionCall.Args),
+		},
+	}
+}
+
+func applyGoogleTools(googleReq map[string]interface{}, req ChatRequest) {
+	var tools []map[string]interface{}
+	if len(req.Tools) > 0 {
+		declarations := make([]map[string]interface{}, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			decl := map[string]interface{}{"name": tool.Function.Name}
+			if tool.Function.Description != "" {
+				decl["description"] = tool.Function.Description
+			}
+			if tool.Function.Parameters != nil {
+				decl["parameters"] = tool.Function.Parameters
+			}
+			declarations = append(declarations, decl)
+		}
+		tools = append(tools, map[string]interface{}{"functionDeclarations": declarations})
+	}
+	if req.GoogleSearchGrounding {
+		tools = append(tools, map[string]interface{}{"google_search": map[string]interface{}{}})
+	}
+	if len(tools) > 0 {
+		googleReq["tools"] = tools
+	}
+
+	if req.ToolChoice != nil && len(req.Tools) > 0 {
+		config := map[string]interface{}{"mode": "AUTO"}
+		switch {
+		case req.ToolChoice.Function != "":
+			config["mode"] = "ANY"
+			config["allowedFunctionNames"] = []string{req.ToolChoice.Function}
+		case req.ToolChoice.Mode == ToolChoiceRequired:
+			config["mode"] = "ANY"
+		case req.ToolChoice.Mode == ToolChoiceNone:
+			config["mode"] = "NONE"
+		}
+		googleReq["toolConfig"] = map[string]interface{}{"functionCallingConfig": config}
+	}
+}
+
+// googleFunctionResponse wraps a tool result; Gemini requires the response to be an object.
+func googleFunctionResponse(content string) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]interface{}{"content": content}
+}
+
+func googleFinishReason(reason string, hasToolCalls bool) string {
+	switch {
+	case reason == "":
+		return ""
+	case hasToolCalls:
+		return FinishReasonToolCalls
+	case reason == "MAX_TOKENS":
+		return FinishReasonLength
+	default:
+		return FinishReasonStop
+	}
+}
+
+// SupportsTools reports false for the legacy vision-only Gemini models.
+// SupportsResponseFormat reports true: JSON output maps to responseMimeType and responseSchema.
+func (p *GoogleProvider) SupportsResponseFormat(model string) bool {
+	return true
+}
+
+func (p *GoogleProvider) SupportsTools(model string) bool {
+	return !strings.Contains(strings.ToLower(model), "pro-vision")
+}
+
 func extractMediaType(dataURL string) string {
 	if strings.HasPrefix(dataURL, "data:") {
 		parts := strings.SplitN(dataURL, ";", 2)
@@ -482,3 +680,83 @@ func extractMediaType(dataURL string) string {
 	}
 	return "image/png"
 }
+
+func (p *GoogleProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("Google API key not configured")
+	}
+
+	requests := make([]map[string]interface{}, 0, len(req.Input))
+	for _, text := range req.Input {
+		r := map[string]interface{}{
+			"model": "models/" + req.Model,
+			"content": map[string]interface{}{
+				"parts": []map[string]interface{}{{"text": text}},
+			},
+		}
+		if req.Dimensions > 0 {
+			r["outputDimensionality"] = req.Dimensions
+		}
+		requests = append(requests, r)
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:batchEmbedContents?key=%s", p.baseURL, req.Model, p.apiKey)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, newTransportError("google", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &errorResp) == nil && errorResp.Error.Message != "" {
+			return nil, newStatusError("google", resp, fmt.Sprintf("Google API error: %s", errorResp.Error.Message))
+		}
+		return nil, newStatusError("google", resp, fmt.Sprintf("Google API returned status %d: %s", resp.StatusCode, string(body)))
+	}
+
+	var googleResp struct {
+		Embeddings []struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &googleResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	embeddings := make([][]float64, 0, len(googleResp.Embeddings))
+	for _, e := range googleResp.Embeddings {
+		embeddings = append(embeddings, e.Values)
+	}
+
+	// The batch endpoint does not report token usage.
+	return &EmbeddingResponse{
+		Model:      req.Model,
+		Embeddings: embeddings,
+	}, nil
+}
+
+func (p *GoogleProvider) GetEmbeddingModels() []string {
+	return []string{"gemini-embedding-001", "text-embedding-004", "embedding-001"}
+}
@@ -5,15 +5,29 @@ import "context"
 type ChatRequest struct {
 	Model                 string    `json:"model"`
 	Messages              []Message `json:"messages"`
-	Temperature           float64   `json:"temperature,omitempty"`
+	Temperature           *float64  `json:"temperature,omitempty"`
 	MaxTokens             int       `json:"max_tokens,omitempty"`
 	GoogleSearchGrounding bool `json:"google_search_grounding,omitempty"`
 	WebSearch             bool `json:"web_search,omitempty"`
+	Tools                 []Tool      `json:"tools,omitempty"`
+	ToolChoice            *ToolChoice `json:"tool_choice,omitempty"`
+	ResponseFormat        *ResponseFormat `json:"response_format,omitempty"`
+	// Sampling parameters, by their OpenAI names. Providers without an equivalent
+	// reject requests that set them.
+	TopP             *float64           `json:"top_p,omitempty"`
+	Stop             []string           `json:"stop,omitempty"`
+	PresencePenalty  *float64           `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64           `json:"frequency_penalty,omitempty"`
+	Seed             *int               `json:"seed,omitempty"`
+	LogitBias        map[string]float64 `json:"logit_bias,omitempty"`
 }
 
 type Message struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"`
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+	Name       string      `json:"name,omitempty"`
 }
 
 type ContentPart struct {
@@ -39,16 +53,37 @@ type ChatResponse struct {
 	Usage     Usage    `json:"usage"`
 	Cost      float64  `json:"cost,omitempty"`
 	LatencyMs int64    `json:"latency_ms,omitempty"`
+	Cached    bool     `json:"cached,omitempty"`
+	// Annotations are added by gateway hooks, e.g. a policy service's verdict.
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+
+	// HedgeAttempts are the losing calls of a hedged request, kept for cost accounting.
+	HedgeAttempts []HedgeAttempt `json:"-"`
+}
+
+// HedgeAttempt is a duplicate call made for a hedged request that did not win.
+type HedgeAttempt struct {
+	Provider  string
+	Model     string
+	Usage     Usage // estimated from the prompt when the call was cancelled
+	Cost      float64
+	LatencyMs int64
+	Cancelled bool
+	Error     string
 }
 
 type Choice struct {
-	Message Message `json:"message"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason,omitempty"`
 }
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	TotalTokens      int  `json:"total_tokens"`
+	Estimated        bool `json:"estimated,omitempty"` // counted by the gateway; the provider reported none
+	// CachedPromptTokens is the part of PromptTokens the provider served from its prompt cache.
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty"`
 }
 
 // SuggestedEdit is a structured code edit for IDE accept/reject.
@@ -67,6 +102,12 @@ type StreamChunk struct {
 	Error          string          `json:"error,omitempty"`
 	Provider       string          `json:"provider,omitempty"`
 	SuggestedEdit  *SuggestedEdit  `json:"suggested_edit,omitempty"`
+	ToolCalls      []ToolCallDelta `json:"tool_calls,omitempty"`
+	FinishReason   string          `json:"finish_reason,omitempty"`
+	Cached         bool            `json:"cached,omitempty"`
+	Model          string          `json:"model,omitempty"` // on the Done chunk, the model that served the request
+	Incomplete     bool            `json:"incomplete,omitempty"` // on the Done chunk, the upstream failed mid-stream
+	Annotations    map[string]interface{} `json:"annotations,omitempty"` // on the Done chunk, see ChatResponse.Annotations
 }
 
 type StreamingProvider interface {
@@ -18,6 +18,7 @@ type LocalProvider struct {
 	baseURL string
 	client  *http.Client
 	logger  zerolog.Logger
+	catalog *ModelCatalog
 }
 
 func NewLocalProvider(baseURL string, logger zerolog.Logger) *LocalProvider {
@@ -40,8 +41,26 @@ func (p *LocalProvider) Name() string {
 
 func convertToOllamaMessages(messages []Message) []map[string]interface{} {
 	out := make([]map[string]interface{}, 0, len(messages))
+	callNames := toolCallNames(messages)
 	for _, msg := range messages {
 		om := map[string]interface{}{"role": msg.Role}
+		if msg.Role == "tool" {
+			if name := toolResultName(msg, callNames); name != "" {
+				om["tool_name"] = name
+			}
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				calls = append(calls, map[string]interface{}{
+					"function": map[string]interface{}{
+						"name":      call.Function.Name,
+						"arguments": toolArgumentsMap(call.Function.Arguments),
+					},
+				})
+			}
+			om["tool_calls"] = calls
+		}
 		text, partList := NormalizeMessageContent(msg.Content)
 		if partList == nil {
 			om["content"] = text
@@ -87,6 +106,9 @@ func convertToOllamaMessages(messages []Message) []map[string]interface{} {
 }
 
 func (p *LocalProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := unsupportedSampling(p.Name(), req, "logit_bias"); err != nil {
+		return nil, err
+	}
 	ollamaMessages := convertToOllamaMessages(req.Messages)
 
 	ollamaReq := map[string]interface{}{
@@ -94,13 +116,17 @@ func (p *LocalProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespons
 		"messages": ollamaMessages,
 	}
 
-	if req.Temperature > 0 {
-		ollamaReq["temperature"] = req.Temperature
+	if req.Temperature != nil {
+		ollamaReq["temperature"] = *req.Temperature
 	}
-	if req.MaxTokens > 0 {
-		ollamaReq["options"] = map[string]interface{}{
-			"num_predict": req.MaxTokens,
-		}
+	if options := ollamaOptions(req); len(options) > 0 {
+		ollamaReq["options"] = options
+	}
+	if !toolsDisabled(req) {
+		ollamaReq["tools"] = openAITools(req)
+	}
+	if req.ResponseFormat.WantsJSON() {
+		ollamaReq["format"] = ollamaFormat(req)
 	}
 
 	reqBody, err := json.Marshal(ollamaReq)
@@ -124,19 +150,20 @@ func (p *LocalProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespons
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, newTransportError("local", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError("local", resp, fmt.Sprintf("provider returned status %d: %s", resp.StatusCode, string(body)))
 	}
 
 	var ollamaResp struct {
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string           `json:"role"`
+			Content   string           `json:"content"`
+			ToolCalls []ollamaToolCall `json:"tool_calls"`
 		} `json:"message"`
 		Done bool `json:"done"`
 	}
@@ -150,15 +177,26 @@ func (p *LocalProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespons
 		content = contentStr
 	}
 
+	var toolCalls []ToolCall
+	for i, call := range ollamaResp.Message.ToolCalls {
+		toolCalls = append(toolCalls, call.toolCall(i))
+	}
+	finishReason := FinishReasonStop
+	if len(toolCalls) > 0 {
+		finishReason = FinishReasonToolCalls
+	}
+
 	return &ChatResponse{
 		ID:    fmt.Sprintf("chat-%d", time.Now().Unix()),
 		Model: req.Model,
 		Choices: []Choice{
 			{
 				Message: Message{
-					Role:    ollamaResp.Message.Role,
-					Content: content,
+					Role:      ollamaResp.Message.Role,
+					Content:   content,
+					ToolCalls: toolCalls,
 				},
+				FinishReason: finishReason,
 			},
 		},
 		Usage: Usage{
@@ -197,6 +235,10 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 		defer close(chunkChan)
 		defer close(errChan)
 
+		if err := unsupportedSampling(p.Name(), req, "logit_bias"); err != nil {
+			errChan <- err
+			return
+		}
 		ollamaMessages := convertToOllamaMessages(req.Messages)
 		ollamaReq := map[string]interface{}{
 			"model":    req.Model,
@@ -204,13 +246,17 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 			"stream":   true,
 		}
 
-		if req.Temperature > 0 {
-			ollamaReq["temperature"] = req.Temperature
+		if req.Temperature != nil {
+			ollamaReq["temperature"] = *req.Temperature
 		}
-		if req.MaxTokens > 0 {
-			ollamaReq["options"] = map[string]interface{}{
-				"num_predict": req.MaxTokens,
-			}
+		if options := ollamaOptions(req); len(options) > 0 {
+			ollamaReq["options"] = options
+		}
+		if !toolsDisabled(req) {
+			ollamaReq["tools"] = openAITools(req)
+		}
+		if req.ResponseFormat.WantsJSON() {
+			ollamaReq["format"] = ollamaFormat(req)
 		}
 
 		reqBody, err := json.Marshal(ollamaReq)
@@ -230,14 +276,14 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 
 		resp, err := p.streamClient().Do(httpReq)
 		if err != nil {
-			errChan <- fmt.Errorf("failed to send request: %w", err)
+			errChan <- newTransportError("local", err)
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			errChan <- fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+			errChan <- newStatusError("local", resp, fmt.Sprintf("provider returned status %d: %s", resp.StatusCode, string(body)))
 			return
 		}
 
@@ -248,6 +294,7 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 		var responseID string
 		var previousContent string
 		var finalUsage *Usage
+		var toolCallCount int
 
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -257,8 +304,9 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 
 			var ollamaResp struct {
 				Message struct {
-					Role    string `json:"role"`
-					Content string `json:"content"`
+					Role      string           `json:"role"`
+					Content   string           `json:"content"`
+					ToolCalls []ollamaToolCall `json:"tool_calls"`
 				} `json:"message"`
 				Done            bool  `json:"done"`
 				TotalDuration   int64 `json:"total_duration,omitempty"`
@@ -290,6 +338,16 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 				previousContent = currentContent
 			}
 
+			if len(ollamaResp.Message.ToolCalls) > 0 {
+				deltas := make([]ToolCallDelta, 0, len(ollamaResp.Message.ToolCalls))
+				for _, tc := range ollamaResp.Message.ToolCalls {
+					call := tc.toolCall(toolCallCount)
+					deltas = append(deltas, ToolCallDelta{Index: toolCallCount, ID: call.ID, Type: call.Type, Function: call.Function})
+					toolCallCount++
+				}
+				chunkChan <- StreamChunk{ID: responseID, ToolCalls: deltas}
+			}
+
 			if ollamaResp.Done {
 				if ollamaResp.PromptEvalCount > 0 || ollamaResp.EvalCount > 0 {
 					finalUsage = &Usage{
@@ -299,11 +357,16 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 					}
 				}
 
+				finishReason := FinishReasonStop
+				if toolCallCount > 0 {
+					finishReason = FinishReasonToolCalls
+				}
 				chunkChan <- StreamChunk{
-					ID:      responseID,
-					Content: "",
-					Done:    true,
-					Usage:   finalUsage,
+					ID:           responseID,
+					Content:      "",
+					Done:         true,
+					Usage:        finalUsage,
+					FinishReason: finishReason,
 				}
 				return
 			}
@@ -325,26 +388,73 @@ func (p *LocalProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 	return chunkChan, errChan
 }
 
+type ollamaToolCall struct {
+	Function struct {
+		Name      string      `json:"name"`
+		Arguments interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+// toolCall converts an Ollama tool call, which carries no ID of its own.
+func (c ollamaToolCall) toolCall(index int) ToolCall {
+	return ToolCall{
+		ID:   fmt.Sprintf("call_%d_%s", index, c.Function.Name),
+		Type: "function",
+		Function: ToolCallFunction{
+			Name:      c.Function.Name,
+			Arguments: toolArgumentsString(c.Function.Arguments),
+		},
+	}
+}
+
+// SupportsResponseFormat reports true: JSON output maps to Ollama's format parameter.
+func (p *LocalProvider) SupportsResponseFormat(model string) bool {
+	return true
+}
+
+// SupportsTools is optimistic: Ollama rejects tools for models without a tool
+// template, and the router then fails over.
+func (p *LocalProvider) SupportsTools(model string) bool {
+	return true
+}
+
+// SetModelCatalog caches the pulled models instead of asking Ollama on every call.
+func (p *LocalProvider) SetModelCatalog(catalog *ModelCatalog) {
+	p.catalog = catalog
+}
+
 func (p *LocalProvider) GetModels() []string {
+	if p.catalog != nil {
+		return p.catalog.Models(p.Name(), p.baseURL, p.listModels, []string{})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	modelNames, err := p.listModels(ctx)
+	if err != nil {
+		p.logger.Error().Err(err).Msg("failed to fetch models")
+		return []string{}
+	}
+	return modelNames
+}
+
+// listModels returns the pulled models from /api/tags.
+func (p *LocalProvider) listModels(ctx context.Context) ([]string, error) {
 	url := fmt.Sprintf("%s/api/tags", p.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		p.logger.Error().Err(err).Msg("failed to create models request")
-		return []string{}
+		return nil, fmt.Errorf("failed to create models request: %w", err)
 	}
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		p.logger.Error().Err(err).Msg("failed to fetch models")
-		return []string{}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return []string{}
+		return nil, fmt.Errorf("models request returned status %d", resp.StatusCode)
 	}
 
 	var modelsResp struct {
@@ -354,8 +464,7 @@ func (p *LocalProvider) GetModels() []string {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
-		p.logger.Error().Err(err).Msg("failed to decode models response")
-		return []string{}
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
 	}
 
 	modelNames := make([]string, 0, len(modelsResp.Models))
@@ -363,6 +472,141 @@ func (p *LocalProvider) GetModels() []string {
 		modelNames = append(modelNames, model.Name)
 	}
 
-	return modelNames
+	return modelNames, nil
+}
+
+// DiscoverCapabilities reads the capabilities and context length of every pulled model
+// from /api/show. Ollama versions that do not report capabilities keep the built-in
+// data for vision and are assumed to accept tools.
+func (p *LocalProvider) DiscoverCapabilities(ctx context.Context) (map[string]Capabilities, error) {
+	models, err := p.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]Capabilities, len(models))
+	for _, model := range models {
+		caps, err := p.showModel(ctx, model)
+		if err != nil {
+			p.logger.Debug().Err(err).Str("model", model).Msg("failed to read model capabilities")
+			continue
+		}
+		out[model] = caps
+	}
+	return out, nil
+}
+
+func (p *LocalProvider) showModel(ctx context.Context, model string) (Capabilities, error) {
+	reqBody, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return Capabilities{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/show", p.baseURL), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return Capabilities{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Capabilities{}, fmt.Errorf("show returned status %d", resp.StatusCode)
+	}
+
+	var show struct {
+		Capabilities []string               `json:"capabilities"`
+		ModelInfo    map[string]interface{} `json:"model_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return Capabilities{}, fmt.Errorf("failed to decode show response: %w", err)
+	}
+
+	var caps Capabilities
+	if show.Capabilities == nil {
+		if family, ok := lookupModelFamily(model); ok {
+			caps = family.capabilities
+		}
+		caps.Tools = true
+	}
+	for _, capability := range show.Capabilities {
+		switch capability {
+		case "vision":
+			caps.Vision = true
+		case "tools":
+			caps.Tools = true
+		}
+	}
+	// The context length is keyed by architecture, e.g. "llama.context_length".
+	for key, value := range show.ModelInfo {
+		if length, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			caps.ContextLength = int(length)
+		}
+	}
+	return caps, nil
 }
 
+
+func (p *LocalProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	ollamaReq := map[string]interface{}{
+		"model": req.Model,
+		"input": req.Input,
+	}
+	if req.Dimensions > 0 {
+		ollamaReq["dimensions"] = req.Dimensions
+	}
+
+	reqBody, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/embed", p.baseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, newTransportError("local", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError("local", resp, fmt.Sprintf("provider returned status %d: %s", resp.StatusCode, string(body)))
+	}
+
+	var ollamaResp struct {
+		Model           string      `json:"model"`
+		Embeddings      [][]float64 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	model := ollamaResp.Model
+	if model == "" {
+		model = req.Model
+	}
+	return &EmbeddingResponse{
+		Model:      model,
+		Embeddings: ollamaResp.Embeddings,
+		Usage: Usage{
+			PromptTokens: ollamaResp.PromptEvalCount,
+			TotalTokens:  ollamaResp.PromptEvalCount,
+		},
+	}, nil
+}
+
+// GetEmbeddingModels returns every pulled model; Ollama can embed with any of them.
+func (p *LocalProvider) GetEmbeddingModels() []string {
+	return p.GetModels()
+}
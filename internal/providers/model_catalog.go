@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// DefaultModelDiscoveryTTL is how long a discovered model list is used.
+	DefaultModelDiscoveryTTL = time.Hour
+	// modelDiscoveryRetry is how long a failed discovery falls back before retrying.
+	modelDiscoveryRetry   = time.Minute
+	modelDiscoveryTimeout = 5 * time.Second
+	// maxCatalogEntries bounds the cache, which gains an entry per BYOK key.
+	maxCatalogEntries = 1024
+)
+
+// CatalogProvider is implemented by providers that can discover their models through
+// a ModelCatalog. Until discovery succeeds they list their built-in models.
+type CatalogProvider interface {
+	SetModelCatalog(catalog *ModelCatalog)
+}
+
+// ModelLister is a provider's list-models call.
+type ModelLister func(ctx context.Context) ([]string, error)
+
+// ModelCatalog caches the model lists providers discover from their vendor's API, per
+// provider and credential, and applies the admin allow and deny lists. A list is used
+// for the TTL; after that the cached list keeps being served while it is refreshed in
+// the background. When discovery fails and nothing was cached, the provider's static
+// list is used and discovery is retried after a minute.
+type ModelCatalog struct {
+	ttl      time.Duration
+	mu       sync.Mutex
+	entries  map[string]*catalogEntry
+	allow    map[string][]string
+	deny     map[string][]string
+	discover singleflight.Group
+}
+
+type catalogEntry struct {
+	models     []string
+	discovered bool
+	expires    time.Time
+	refreshing bool
+}
+
+func NewModelCatalog(ttl time.Duration) *ModelCatalog {
+	if ttl <= 0 {
+		ttl = DefaultModelDiscoveryTTL
+	}
+	return &ModelCatalog{
+		ttl:     ttl,
+		entries: make(map[string]*catalogEntry),
+		allow:   make(map[string][]string),
+		deny:    make(map[string][]string),
+	}
+}
+
+// SetModelFilter sets the allow and deny lists of provider. Patterns may use * and ?
+// wildcards. With an allow list only matching models are listed; denied models never
+// are.
+func (c *ModelCatalog) SetModelFilter(provider string, allow, deny []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.allow[provider] = allow
+	c.deny[provider] = deny
+}
+
+// Models returns the models of provider for credential. Only the first lookup waits
+// for discovery, shared by concurrent callers; a stale list is served while list
+// refreshes it. static is the fallback list.
+func (c *ModelCatalog) Models(provider, credential string, list ModelLister, static []string) []string {
+	key := provider + "\x00" + credentialHash(credential)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		c.discover.Do(key, func() (interface{}, error) {
+			c.refresh(key, list)
+			return nil, nil
+		})
+		c.mu.Lock()
+		entry = c.entries[key]
+	} else if !time.Now().Before(entry.expires) && !entry.refreshing {
+		entry.refreshing = true
+		go c.refresh(key, list)
+	}
+	models := static
+	if entry != nil && entry.discovered {
+		models = entry.models
+	}
+	models = c.filter(provider, models)
+	c.mu.Unlock()
+	return models
+}
+
+func (c *ModelCatalog) refresh(key string, list ModelLister) {
+	ctx, cancel := context.WithTimeout(context.Background(), modelDiscoveryTimeout)
+	defer cancel()
+	models, err := list(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		c.evictLocked()
+		entry = &catalogEntry{}
+		c.entries[key] = entry
+	}
+	entry.refreshing = false
+	if err != nil || len(models) == 0 {
+		// Keep serving the last discovered list, or the static one, until the retry.
+		if entry.models == nil {
+			entry.models = []string{}
+		}
+		entry.expires = time.Now().Add(min(c.ttl, modelDiscoveryRetry))
+		return
+	}
+	entry.models = models
+	entry.discovered = true
+	entry.expires = time.Now().Add(c.ttl)
+}
+
+// evictLocked makes room for a new entry, dropping the one that expires first.
+func (c *ModelCatalog) evictLocked() {
+	if len(c.entries) < maxCatalogEntries {
+		return
+	}
+	var oldest string
+	var oldestExpires time.Time
+	for key, entry := range c.entries {
+		if entry.refreshing {
+			continue
+		}
+		if oldest == "" || entry.expires.Before(oldestExpires) {
+			oldest, oldestExpires = key, entry.expires
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+func (c *ModelCatalog) filter(provider string, models []string) []string {
+	allow, deny := c.allow[provider], c.deny[provider]
+	if len(allow) == 0 && len(deny) == 0 {
+		return models
+	}
+	out := make([]string, 0, len(models))
+	for _, model := range models {
+		if (len(allow) == 0 || matchesAny(allow, model)) && !matchesAny(deny, model) {
+			out = append(out, model)
+		}
+	}
+	return out
+}
+
+func matchesAny(patterns []string, model string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialHash keys the cache by a digest, so API keys are not kept as map keys.
+func credentialHash(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:8])
+}
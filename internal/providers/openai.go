@@ -19,6 +19,7 @@ type OpenAIProvider struct {
 	baseURL string
 	client  *http.Client
 	logger  zerolog.Logger
+	catalog *ModelCatalog
 }
 
 func NewOpenAIProvider(apiKey, baseURL string, logger zerolog.Logger) *OpenAIProvider {
@@ -53,14 +54,25 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		"messages": convertMessagesToOpenAI(req.Messages),
 	}
 
-	if req.Temperature > 0 {
-		openAIReq["temperature"] = req.Temperature
+	if req.Temperature != nil {
+		openAIReq["temperature"] = *req.Temperature
 	}
 	if req.MaxTokens > 0 {
 		openAIReq["max_tokens"] = req.MaxTokens
 	}
+	applyOpenAISampling(openAIReq, req)
+	tools := openAITools(req)
 	if req.WebSearch {
-		openAIReq["tools"] = []map[string]string{{"type": "web_search"}}
+		tools = append(tools, map[string]string{"type": "web_search"})
+	}
+	if len(tools) > 0 {
+		openAIReq["tools"] = tools
+	}
+	if req.ToolChoice != nil && len(req.Tools) > 0 {
+		openAIReq["tool_choice"] = req.ToolChoice
+	}
+	if req.ResponseFormat.WantsJSON() {
+		openAIReq["response_format"] = req.ResponseFormat
 	}
 
 	reqBody, err := json.Marshal(openAIReq)
@@ -84,7 +96,7 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, newTransportError("openai", err)
 	}
 	defer resp.Body.Close()
 
@@ -100,10 +112,10 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 				Type    string `json:"type"`
 			} `json:"error"`
 		}
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, fmt.Errorf("OpenAI API error: %s", errorResp.Error.Message)
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+			return nil, newStatusError("openai", resp, fmt.Sprintf("OpenAI API error: %s", errorResp.Error.Message))
 		}
-		return nil, fmt.Errorf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError("openai", resp, fmt.Sprintf("OpenAI API returned status %d: %s", resp.StatusCode, string(body)))
 	}
 
 	var openAIResp struct {
@@ -111,15 +123,13 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
-				Role    string      `json:"role"`
-				Content interface{} `json:"content"`
+				Role      string      `json:"role"`
+				Content   interface{} `json:"content"`
+				ToolCalls []ToolCall  `json:"tool_calls"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
-		Usage struct {
-			PromptTokens     int `json:"prompt_tokens"`
-			CompletionTokens int `json:"completion_tokens"`
-			TotalTokens      int `json:"total_tokens"`
-		} `json:"usage"`
+		Usage openAIUsage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
@@ -143,15 +153,18 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 					}
 				}
 			}
+		case nil:
 		default:
 			contentStr = fmt.Sprintf("%v", c)
 		}
 
 		choices = append(choices, Choice{
 			Message: Message{
-				Role:    choice.Message.Role,
-				Content: contentStr,
+				Role:      choice.Message.Role,
+				Content:   contentStr,
+				ToolCalls: choice.Message.ToolCalls,
 			},
+			FinishReason: choice.FinishReason,
 		})
 	}
 
@@ -159,11 +172,7 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		ID:      openAIResp.ID,
 		Model:   openAIResp.Model,
 		Choices: choices,
-		Usage: Usage{
-			PromptTokens:     openAIResp.Usage.PromptTokens,
-			CompletionTokens: openAIResp.Usage.CompletionTokens,
-			TotalTokens:      openAIResp.Usage.TotalTokens,
-		},
+		Usage:   openAIResp.Usage.toUsage(),
 	}, nil
 }
 
@@ -220,14 +229,25 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 			"stream":   true,
 		}
 
-		if req.Temperature > 0 {
-			openAIReq["temperature"] = req.Temperature
+		if req.Temperature != nil {
+			openAIReq["temperature"] = *req.Temperature
 		}
 		if req.MaxTokens > 0 {
 			openAIReq["max_tokens"] = req.MaxTokens
 		}
+		applyOpenAISampling(openAIReq, req)
+		tools := openAITools(req)
 		if req.WebSearch {
-			openAIReq["tools"] = []map[string]string{{"type": "web_search"}}
+			tools = append(tools, map[string]string{"type": "web_search"})
+		}
+		if len(tools) > 0 {
+			openAIReq["tools"] = tools
+		}
+		if req.ToolChoice != nil && len(req.Tools) > 0 {
+			openAIReq["tool_choice"] = req.ToolChoice
+		}
+		if req.ResponseFormat.WantsJSON() {
+			openAIReq["response_format"] = req.ResponseFormat
 		}
 
 		reqBody, err := json.Marshal(openAIReq)
@@ -248,8 +268,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 
 		resp, err := p.streamClient().Do(httpReq)
 		if err != nil {
-			msg := fmt.Sprintf("failed to send request: %v", err)
-			chunkChan <- StreamChunk{Content: "", Done: true, Error: msg}
+			errChan <- newTransportError("openai", err)
 			return
 		}
 		defer resp.Body.Close()
@@ -262,13 +281,11 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 					Type    string `json:"type"`
 				} `json:"error"`
 			}
-			var apiErr string
 			if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
-				apiErr = fmt.Sprintf("OpenAI API error: %s", errorResp.Error.Message)
+				errChan <- newStatusError("openai", resp, fmt.Sprintf("OpenAI API error: %s", errorResp.Error.Message))
 			} else {
-				apiErr = fmt.Sprintf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+				errChan <- newStatusError("openai", resp, fmt.Sprintf("OpenAI API returned status %d: %s", resp.StatusCode, string(body)))
 			}
-			chunkChan <- StreamChunk{Content: "", Done: true, Error: apiErr}
 			return
 		}
 
@@ -279,6 +296,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 		var responseID string
 		var fullContent strings.Builder
 		var finalUsage *Usage
+		var finishReason string
 		var dataBuf strings.Builder
 
 		flushData := func() bool {
@@ -290,10 +308,11 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 			if data == "" || data == "[DONE]" {
 				if data == "[DONE]" {
 					chunkChan <- StreamChunk{
-						ID:      responseID,
-						Content: "",
-						Done:    true,
-						Usage:   finalUsage,
+						ID:           responseID,
+						Content:      "",
+						Done:         true,
+						Usage:        finalUsage,
+						FinishReason: finishReason,
 					}
 					return true
 				}
@@ -305,16 +324,13 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 				Model   string `json:"model"`
 				Choices []struct {
 					Delta struct {
-						Content string `json:"content"`
-						Role    string `json:"role"`
+						Content   string          `json:"content"`
+						Role      string          `json:"role"`
+						ToolCalls []ToolCallDelta `json:"tool_calls"`
 					} `json:"delta"`
 					FinishReason string `json:"finish_reason"`
 				} `json:"choices"`
-				Usage struct {
-					PromptTokens     int `json:"prompt_tokens"`
-					CompletionTokens int `json:"completion_tokens"`
-					TotalTokens      int `json:"total_tokens"`
-				} `json:"usage"`
+				Usage openAIUsage `json:"usage"`
 			}
 
 			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
@@ -336,13 +352,17 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 						Done:    false,
 					}
 				}
+				if toolCalls := streamResp.Choices[0].Delta.ToolCalls; len(toolCalls) > 0 {
+					chunkChan <- StreamChunk{
+						ID:        responseID,
+						ToolCalls: toolCalls,
+					}
+				}
 
 				if streamResp.Choices[0].FinishReason != "" {
-					finalUsage = &Usage{
-						PromptTokens:     streamResp.Usage.PromptTokens,
-						CompletionTokens: streamResp.Usage.CompletionTokens,
-						TotalTokens:      streamResp.Usage.TotalTokens,
-					}
+					finishReason = streamResp.Choices[0].FinishReason
+					usage := streamResp.Usage.toUsage()
+					finalUsage = &usage
 				}
 			}
 			return false
@@ -376,13 +396,104 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 	return chunkChan, errChan
 }
 
+var openAIModels = []string{
+	"gpt-5.2", "gpt-5.2-pro", "gpt-5.1", "gpt-5", "gpt-5-mini", "gpt-5-nano",
+	"gpt-4.1", "gpt-4.1-mini", "gpt-4.1-nano",
+	"o3", "o3-mini", "o4-mini", "o1", "o1-pro",
+	"gpt-4o", "gpt-4o-2024-08-06", "gpt-4o-mini", "gpt-4o-mini-2024-07-18",
+	"gpt-4-turbo", "gpt-4-turbo-preview", "gpt-4", "gpt-3.5-turbo",
+}
+
+func (p *OpenAIProvider) SetModelCatalog(catalog *ModelCatalog) {
+	p.catalog = catalog
+}
+
 func (p *OpenAIProvider) GetModels() []string {
-	return []string{
-		"gpt-5.2", "gpt-5.2-pro", "gpt-5.1", "gpt-5", "gpt-5-mini", "gpt-5-nano",
-		"gpt-4.1", "gpt-4.1-mini", "gpt-4.1-nano",
-		"o3", "o3-mini", "o4-mini", "o1", "o1-pro",
-		"gpt-4o", "gpt-4o-2024-08-06", "gpt-4o-mini", "gpt-4o-mini-2024-07-18",
-		"gpt-4-turbo", "gpt-4-turbo-preview", "gpt-4", "gpt-3.5-turbo",
+	if p.catalog == nil {
+		return openAIModels
+	}
+	return p.catalog.Models(p.Name(), p.baseURL+"\x00"+p.apiKey, p.listModels, openAIModels)
+}
+
+// listModels returns the chat models from /models, which also lists embedding, audio
+// and image models.
+func (p *OpenAIProvider) listModels(ctx context.Context) ([]string, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/models", p.baseURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("models request returned status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		if isOpenAIChatModel(m.ID) {
+			models = append(models, m.ID)
+		}
+	}
+	return models, nil
+}
+
+func isOpenAIChatModel(id string) bool {
+	id = strings.ToLower(id)
+	if !strings.HasPrefix(id, "gpt-") && !strings.HasPrefix(id, "chatgpt-") && !strings.HasPrefix(id, "ft:gpt-") &&
+		!strings.HasPrefix(id, "o1") && !strings.HasPrefix(id, "o3") && !strings.HasPrefix(id, "o4") {
+		return false
+	}
+	for _, excluded := range []string{"embedding", "tts", "whisper", "transcribe", "realtime", "image", "instruct"} {
+		if strings.Contains(id, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// SupportsResponseFormat reports true: JSON mode and structured outputs are passed through.
+func (p *OpenAIProvider) SupportsResponseFormat(model string) bool {
+	return true
+}
+
+// SupportsTools reports false only for the early reasoning previews that reject tools.
+func (p *OpenAIProvider) SupportsTools(model string) bool {
+	m := strings.ToLower(model)
+	return !strings.HasPrefix(m, "o1-mini") && !strings.HasPrefix(m, "o1-preview")
+}
+
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+func (u openAIUsage) toUsage() Usage {
+	return Usage{
+		PromptTokens:       u.PromptTokens,
+		CompletionTokens:   u.CompletionTokens,
+		TotalTokens:        u.TotalTokens,
+		CachedPromptTokens: u.PromptTokensDetails.CachedTokens,
 	}
 }
 
@@ -430,8 +541,51 @@ func convertMessagesToOpenAI(messages []Message) []interface{} {
 			}
 			messageMap["content"] = contentArray
 		}
+		if len(msg.ToolCalls) > 0 {
+			messageMap["tool_calls"] = openAIToolCalls(msg.ToolCalls)
+			if partList == nil && text == "" {
+				messageMap["content"] = nil
+			}
+		}
+		if msg.ToolCallID != "" {
+			messageMap["tool_call_id"] = msg.ToolCallID
+		}
 
 		result = append(result, messageMap)
 	}
 	return result
 }
+
+// openAITools converts function tools to the OpenAI "tools" array.
+func openAITools(req ChatRequest) []interface{} {
+	tools := make([]interface{}, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		tools = append(tools, Tool{Type: "function", Function: tool.Function})
+	}
+	return tools
+}
+
+func openAIToolCalls(calls []ToolCall) []ToolCall {
+	out := make([]ToolCall, 0, len(calls))
+	for _, call := range calls {
+		if call.Type == "" {
+			call.Type = "function"
+		}
+		if call.Function.Arguments == "" {
+			call.Function.Arguments = "{}"
+		}
+		out = append(out, call)
+	}
+	return out
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not configured")
+	}
+	return embedOpenAICompatible(ctx, p.client, fmt.Sprintf("%s/embeddings", p.baseURL), p.apiKey, "OpenAI", req)
+}
+
+func (p *OpenAIProvider) GetEmbeddingModels() []string {
+	return []string{"text-embedding-3-small", "text-embedding-3-large", "text-embedding-ada-002"}
+}
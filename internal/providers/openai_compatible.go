@@ -0,0 +1,467 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAICompatibleConfig declares an upstream that speaks the OpenAI chat completions
+// API, such as Groq, Together, DeepSeek, Mistral, OpenRouter, LM Studio or llama.cpp's
+// server. Each one is registered as its own provider under Name.
+type OpenAICompatibleConfig struct {
+	Name    string `json:"name" yaml:"name"`
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// APIKeyEnv names the environment variable holding the server's key. Leave it
+	// empty for upstreams that take no key, such as a local LM Studio.
+	APIKeyEnv string `json:"api_key_env" yaml:"api_key_env"`
+	// Models is the model list; with DiscoverModels it is the fallback when /models fails.
+	Models         []string               `json:"models" yaml:"models"`
+	DiscoverModels bool                   `json:"discover_models" yaml:"discover_models"`
+	Headers        map[string]string      `json:"headers" yaml:"headers"` // values may use ${ENV_VAR}
+	Quirks         OpenAICompatibleQuirks `json:"quirks" yaml:"quirks"`
+	Enabled        *bool                  `json:"enabled" yaml:"enabled"` // defaults to true
+	// BYOK lets users store their own key for this upstream.
+	BYOK bool `json:"byok" yaml:"byok"`
+}
+
+// OpenAICompatibleQuirks turns off the parts of the OpenAI API an upstream rejects.
+type OpenAICompatibleQuirks struct {
+	NoStreamOptions  bool `json:"no_stream_options" yaml:"no_stream_options"`
+	NoTools          bool `json:"no_tools" yaml:"no_tools"`
+	NoResponseFormat bool `json:"no_response_format" yaml:"no_response_format"`
+}
+
+// reservedProviderNames are taken by the built-in providers.
+var reservedProviderNames = map[string]bool{
+	"openai": true, "anthropic": true, "google": true, "local": true, "ollama": true, "vllm": true, "aliases": true,
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func (c OpenAICompatibleConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// APIKey returns the server's key from APIKeyEnv.
+func (c OpenAICompatibleConfig) APIKey() string {
+	if c.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(c.APIKeyEnv)
+}
+
+func (c OpenAICompatibleConfig) Validate() error {
+	if !providerNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits, - or _", c.Name)
+	}
+	if reservedProviderNames[c.Name] {
+		return fmt.Errorf("name %s is taken by a built-in provider", c.Name)
+	}
+	u, err := url.Parse(c.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("base_url must be an http or https URL")
+	}
+	if len(c.Models) == 0 && !c.DiscoverModels {
+		return fmt.Errorf("models is required unless discover_models is set")
+	}
+	return nil
+}
+
+// OpenAICompatibleFile is the upstreams file (OPENAI_COMPATIBLE_CONFIG_PATH).
+type OpenAICompatibleFile struct {
+	Providers []OpenAICompatibleConfig `json:"providers" yaml:"providers"`
+}
+
+// ParseOpenAICompatibleConfig parses a JSON (.json) or YAML upstreams file.
+func ParseOpenAICompatibleConfig(name string, data []byte) (*OpenAICompatibleFile, error) {
+	var file OpenAICompatibleFile
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAI-compatible config %s: %w", name, err)
+	}
+	seen := make(map[string]bool, len(file.Providers))
+	for i, provider := range file.Providers {
+		if err := provider.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid OpenAI-compatible config %s: provider %d: %w", name, i, err)
+		}
+		if seen[provider.Name] {
+			return nil, fmt.Errorf("invalid OpenAI-compatible config %s: provider %d: duplicate name %s", name, i, provider.Name)
+		}
+		seen[provider.Name] = true
+	}
+	return &file, nil
+}
+
+func LoadOpenAICompatibleConfig(path string) (*OpenAICompatibleFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAI-compatible config: %w", err)
+	}
+	return ParseOpenAICompatibleConfig(path, data)
+}
+
+// OpenAICompatibleProvider is a provider declared in config rather than in code.
+type OpenAICompatibleProvider struct {
+	config  OpenAICompatibleConfig
+	apiKey  string
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+	logger  zerolog.Logger
+	catalog *ModelCatalog
+}
+
+func NewOpenAICompatibleProvider(config OpenAICompatibleConfig, apiKey string, logger zerolog.Logger) *OpenAICompatibleProvider {
+	headers := make(map[string]string, len(config.Headers))
+	for name, value := range config.Headers {
+		headers[name] = os.ExpandEnv(value)
+	}
+	return &OpenAICompatibleProvider{
+		config:  config,
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		headers: headers,
+		client: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.config.Name
+}
+
+func (p *OpenAICompatibleProvider) streamClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Minute}
+}
+
+func (p *OpenAICompatibleProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+func (p *OpenAICompatibleProvider) requestBody(req ChatRequest, stream bool) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":    req.Model,
+		"messages": convertMessagesToOpenAI(req.Messages),
+	}
+	if stream {
+		body["stream"] = true
+		if !p.config.Quirks.NoStreamOptions {
+			body["stream_options"] = map[string]bool{"include_usage": true}
+		}
+	}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	applyOpenAISampling(body, req)
+	if len(req.Tools) > 0 && !p.config.Quirks.NoTools {
+		body["tools"] = openAITools(req)
+		if req.ToolChoice != nil {
+			body["tool_choice"] = req.ToolChoice
+		}
+	}
+	if req.ResponseFormat.WantsJSON() && !p.config.Quirks.NoResponseFormat {
+		body["response_format"] = req.ResponseFormat
+	}
+	return body
+}
+
+// statusError reads an OpenAI-style error body.
+func (p *OpenAICompatibleProvider) statusError(resp *http.Response, body []byte) error {
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+		return newStatusError(p.Name(), resp, fmt.Sprintf("%s API error: %s", p.Name(), errResp.Error.Message))
+	}
+	return newStatusError(p.Name(), resp, fmt.Sprintf("%s API returned status %d: %s", p.Name(), resp.StatusCode, string(body)))
+}
+
+func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	reqBody, err := json.Marshal(p.requestBody(req, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := p.newRequest(ctx, "POST", "/chat/completions", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.logger.Debug().
+		Str("provider", p.Name()).
+		Str("model", req.Model).
+		Msg("sending request to OpenAI-compatible upstream")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, newTransportError(p.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.statusError(resp, respBody)
+	}
+
+	var openAIResp struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role      string     `json:"role"`
+				Content   string     `json:"content"`
+				ToolCalls []ToolCall `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	choices := make([]Choice, 0, len(openAIResp.Choices))
+	for _, c := range openAIResp.Choices {
+		choices = append(choices, Choice{
+			Message:      Message{Role: c.Message.Role, Content: c.Message.Content, ToolCalls: c.Message.ToolCalls},
+			FinishReason: c.FinishReason,
+		})
+	}
+
+	return &ChatResponse{
+		ID:      openAIResp.ID,
+		Model:   openAIResp.Model,
+		Choices: choices,
+		Usage:   openAIResp.Usage.toUsage(),
+	}, nil
+}
+
+func (p *OpenAICompatibleProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamChunk, <-chan error) {
+	chunkChan := make(chan StreamChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		reqBody, err := json.Marshal(p.requestBody(req, true))
+		if err != nil {
+			errChan <- fmt.Errorf("failed to marshal request: %w", err)
+			return
+		}
+
+		httpReq, err := p.newRequest(ctx, "POST", "/chat/completions", reqBody)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to create request: %w", err)
+			return
+		}
+
+		resp, err := p.streamClient().Do(httpReq)
+		if err != nil {
+			errChan <- newTransportError(p.Name(), err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(resp.Body)
+			errChan <- p.statusError(resp, b)
+			return
+		}
+
+		var responseID string
+		var finalUsage *Usage
+		var finishReason string
+		scanner := bufio.NewScanner(resp.Body)
+		const maxLineSize = 1024 * 1024
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, maxLineSize)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				chunkChan <- StreamChunk{ID: responseID, Done: true, Usage: finalUsage, FinishReason: finishReason}
+				return
+			}
+
+			var chunk struct {
+				ID      string `json:"id"`
+				Choices []struct {
+					Delta struct {
+						Content   string          `json:"content"`
+						ToolCalls []ToolCallDelta `json:"tool_calls"`
+					} `json:"delta"`
+					FinishReason string `json:"finish_reason"`
+				} `json:"choices"`
+				// Usage arrives in a final chunk without choices when stream_options
+				// asks for it; some upstreams put it on the finishing chunk instead.
+				Usage *openAIUsage `json:"usage"`
+			}
+			if json.Unmarshal([]byte(data), &chunk) != nil {
+				continue
+			}
+
+			if responseID == "" && chunk.ID != "" {
+				responseID = chunk.ID
+			}
+			if chunk.Usage != nil {
+				usage := chunk.Usage.toUsage()
+				finalUsage = &usage
+			}
+			if len(chunk.Choices) > 0 {
+				if delta := chunk.Choices[0].Delta.Content; delta != "" {
+					chunkChan <- StreamChunk{ID: responseID, Content: delta}
+				}
+				if toolCalls := chunk.Choices[0].Delta.ToolCalls; len(toolCalls) > 0 {
+					chunkChan <- StreamChunk{ID: responseID, ToolCalls: toolCalls}
+				}
+				if chunk.Choices[0].FinishReason != "" {
+					finishReason = chunk.Choices[0].FinishReason
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- err
+			return
+		}
+		// Some servers close the stream without [DONE].
+		chunkChan <- StreamChunk{ID: responseID, Done: true, Usage: finalUsage, FinishReason: finishReason}
+	}()
+
+	return chunkChan, errChan
+}
+
+func (p *OpenAICompatibleProvider) HealthCheck(ctx context.Context) error {
+	req, err := p.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *OpenAICompatibleProvider) SupportsTools(model string) bool {
+	return !p.config.Quirks.NoTools
+}
+
+func (p *OpenAICompatibleProvider) SupportsResponseFormat(model string) bool {
+	return !p.config.Quirks.NoResponseFormat
+}
+
+func (p *OpenAICompatibleProvider) SetModelCatalog(catalog *ModelCatalog) {
+	p.catalog = catalog
+}
+
+// GetModels returns the configured models, or with discover_models the ones /models
+// lists, falling back to the configured ones.
+func (p *OpenAICompatibleProvider) GetModels() []string {
+	if !p.config.DiscoverModels {
+		return p.config.Models
+	}
+	if p.catalog == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), modelDiscoveryTimeout)
+		defer cancel()
+		models, err := p.listModels(ctx)
+		if err != nil || len(models) == 0 {
+			return p.config.Models
+		}
+		return models
+	}
+	return p.catalog.Models(p.Name(), p.baseURL+"\x00"+p.apiKey, p.listModels, p.config.Models)
+}
+
+func (p *OpenAICompatibleProvider) listModels(ctx context.Context) ([]string, error) {
+	req, err := p.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("models request returned status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		if m.ID != "" {
+			models = append(models, m.ID)
+		}
+	}
+	return models, nil
+}
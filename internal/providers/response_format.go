@@ -0,0 +1,186 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat asks for JSON output, in the shape of OpenAI's "response_format":
+// {"type":"json_object"} for any JSON object, or {"type":"json_schema","json_schema":
+// {"name":...,"schema":{...}}} for output matching a schema. Each provider maps it to
+// its own feature; the gateway validates the output either way.
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+type JSONSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	Strict      bool                   `json:"strict,omitempty"`
+}
+
+var schemaNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func (f *ResponseFormat) Validate() error {
+	switch f.Type {
+	case ResponseFormatText, ResponseFormatJSONObject:
+		return nil
+	case ResponseFormatJSONSchema:
+		if f.JSONSchema == nil {
+			return fmt.Errorf("response_format.json_schema is required for type json_schema")
+		}
+		if !schemaNamePattern.MatchString(f.JSONSchema.Name) {
+			return fmt.Errorf("response_format.json_schema.name must be 1-64 letters, digits, underscores or dashes")
+		}
+		if len(f.JSONSchema.Schema) == 0 {
+			return fmt.Errorf("response_format.json_schema.schema is required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("response_format.type must be %s, %s or %s", ResponseFormatText, ResponseFormatJSONObject, ResponseFormatJSONSchema)
+	}
+}
+
+// WantsJSON reports whether f asks for JSON output; a nil or "text" format does not.
+func (f *ResponseFormat) WantsJSON() bool {
+	return f != nil && (f.Type == ResponseFormatJSONObject || f.Type == ResponseFormatJSONSchema)
+}
+
+// Schema returns the JSON Schema the output must match, or nil for json_object.
+func (f *ResponseFormat) Schema() map[string]interface{} {
+	if f == nil || f.Type != ResponseFormatJSONSchema || f.JSONSchema == nil {
+		return nil
+	}
+	return f.JSONSchema.Schema
+}
+
+// StructuredOutputProvider is implemented by providers that constrain output to a
+// ResponseFormat natively. For other providers the gateway asks for JSON in the prompt.
+type StructuredOutputProvider interface {
+	SupportsResponseFormat(model string) bool
+}
+
+// SupportsResponseFormat reports whether provider enforces response formats for model.
+func SupportsResponseFormat(provider Provider, model string) bool {
+	sp, ok := provider.(StructuredOutputProvider)
+	return ok && sp.SupportsResponseFormat(model)
+}
+
+// googleSchemaKeywords are the schema fields Gemini's responseSchema accepts.
+var googleSchemaKeywords = map[string]bool{
+	"type": true, "format": true, "description": true, "nullable": true, "enum": true,
+	"properties": true, "required": true, "items": true, "minItems": true, "maxItems": true,
+	"minimum": true, "maximum": true, "anyOf": true, "propertyOrdering": true,
+}
+
+// googleSchema converts a JSON Schema to Gemini's OpenAPI-style responseSchema: local
+// $refs are inlined, ["string","null"] types become nullable, and keywords Gemini
+// rejects (additionalProperties, $defs, pattern, ...) are dropped. The gateway's
+// validation still applies the full schema.
+func googleSchema(schema map[string]interface{}) map[string]interface{} {
+	return googleSchemaNode(schema, schema, 0).(map[string]interface{})
+}
+
+func googleSchemaNode(node interface{}, root map[string]interface{}, depth int) interface{} {
+	m, ok := node.(map[string]interface{})
+	if !ok || depth > 32 {
+		return map[string]interface{}{}
+	}
+	if ref, ok := m["$ref"].(string); ok && strings.HasPrefix(ref, "#/") {
+		var target interface{} = root
+		for _, token := range strings.Split(ref[2:], "/") {
+			if tm, ok := target.(map[string]interface{}); ok {
+				target = tm[token]
+			}
+		}
+		return googleSchemaNode(target, root, depth+1)
+	}
+	out := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if !googleSchemaKeywords[key] {
+			continue
+		}
+		switch key {
+		case "type":
+			if types, ok := value.([]interface{}); ok {
+				for _, t := range types {
+					if t == "null" {
+						out["nullable"] = true
+					} else if _, set := out["type"]; !set {
+						out["type"] = t
+					}
+				}
+				continue
+			}
+			out["type"] = value
+		case "properties":
+			props := make(map[string]interface{})
+			if pm, ok := value.(map[string]interface{}); ok {
+				for name, sub := range pm {
+					props[name] = googleSchemaNode(sub, root, depth+1)
+				}
+			}
+			out["properties"] = props
+		case "items":
+			out["items"] = googleSchemaNode(value, root, depth+1)
+		case "anyOf":
+			var subs []interface{}
+			if list, ok := value.([]interface{}); ok {
+				for _, sub := range list {
+					subs = append(subs, googleSchemaNode(sub, root, depth+1))
+				}
+			}
+			out["anyOf"] = subs
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// applyGoogleResponseFormat sets responseMimeType (and responseSchema) in the request's
+// generationConfig.
+func applyGoogleResponseFormat(googleReq map[string]interface{}, req ChatRequest) {
+	if !req.ResponseFormat.WantsJSON() {
+		return
+	}
+	genConfig, _ := googleReq["generationConfig"].(map[string]interface{})
+	if genConfig == nil {
+		genConfig = make(map[string]interface{})
+		googleReq["generationConfig"] = genConfig
+	}
+	genConfig["responseMimeType"] = "application/json"
+	if schema := req.ResponseFormat.Schema(); schema != nil {
+		genConfig["responseSchema"] = googleSchema(schema)
+	}
+}
+
+// ollamaFormat is Ollama's "format": "json", or the schema itself.
+func ollamaFormat(req ChatRequest) interface{} {
+	if schema := req.ResponseFormat.Schema(); schema != nil {
+		return schema
+	}
+	return "json"
+}
+
+// applyVLLMResponseFormat uses vLLM's guided decoding, which constrains output to the
+// schema (any object for json_object) on both the chat and completions endpoints.
+func applyVLLMResponseFormat(body map[string]interface{}, req ChatRequest) {
+	if !req.ResponseFormat.WantsJSON() {
+		return
+	}
+	schema := req.ResponseFormat.Schema()
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object"}
+	}
+	body["guided_json"] = schema
+}
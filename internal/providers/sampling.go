@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// applyOpenAISampling sets the sampling parameters of req on an OpenAI-style body.
+func applyOpenAISampling(body map[string]interface{}, req ChatRequest) {
+	if req.TopP != nil {
+		body["top_p"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		body["stop"] = req.Stop
+	}
+	if req.PresencePenalty != nil {
+		body["presence_penalty"] = *req.PresencePenalty
+	}
+	if req.FrequencyPenalty != nil {
+		body["frequency_penalty"] = *req.FrequencyPenalty
+	}
+	if req.Seed != nil {
+		body["seed"] = *req.Seed
+	}
+	if len(req.LogitBias) > 0 {
+		body["logit_bias"] = req.LogitBias
+	}
+}
+
+func applyAnthropicSampling(body map[string]interface{}, req ChatRequest) {
+	if req.TopP != nil {
+		body["top_p"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		body["stop_sequences"] = req.Stop
+	}
+}
+
+// googleGenerationConfig is Gemini's generationConfig for the length and sampling
+// parameters of req.
+func googleGenerationConfig(req ChatRequest) map[string]interface{} {
+	genConfig := make(map[string]interface{})
+	if req.Temperature != nil {
+		genConfig["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		genConfig["maxOutputTokens"] = req.MaxTokens
+	}
+	if req.TopP != nil {
+		genConfig["topP"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		genConfig["stopSequences"] = req.Stop
+	}
+	if req.PresencePenalty != nil {
+		genConfig["presencePenalty"] = *req.PresencePenalty
+	}
+	if req.FrequencyPenalty != nil {
+		genConfig["frequencyPenalty"] = *req.FrequencyPenalty
+	}
+	if req.Seed != nil {
+		genConfig["seed"] = *req.Seed
+	}
+	return genConfig
+}
+
+// ollamaOptions is Ollama's options for the length and sampling parameters of req.
+func ollamaOptions(req ChatRequest) map[string]interface{} {
+	options := make(map[string]interface{})
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+	if req.PresencePenalty != nil {
+		options["presence_penalty"] = *req.PresencePenalty
+	}
+	if req.FrequencyPenalty != nil {
+		options["frequency_penalty"] = *req.FrequencyPenalty
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	return options
+}
+
+// unsupportedSampling returns an invalid request error if req sets any of params
+// (OpenAI names), which provider has no equivalent for.
+func unsupportedSampling(provider string, req ChatRequest, params ...string) error {
+	set := map[string]bool{
+		"top_p":             req.TopP != nil,
+		"stop":              len(req.Stop) > 0,
+		"presence_penalty":  req.PresencePenalty != nil,
+		"frequency_penalty": req.FrequencyPenalty != nil,
+		"seed":              req.Seed != nil,
+		"logit_bias":        len(req.LogitBias) > 0,
+	}
+	var unsupported []string
+	for _, param := range params {
+		if set[param] {
+			unsupported = append(unsupported, param)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	return &ProviderError{
+		Provider: provider,
+		Kind:     ErrorKindInvalidRequest,
+		Message:  fmt.Sprintf("%s does not support %s", provider, strings.Join(unsupported, ", ")),
+	}
+}
@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Tool is a function the model may call. The shape follows OpenAI's "tools"
+// entries; each provider translates it to its own wire format.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model. Arguments is a JSON-encoded object.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolCallDelta is a streamed fragment of a tool call. Fragments sharing an Index
+// belong to the same call: the first carries ID and name, later ones append to Arguments.
+type ToolCallDelta struct {
+	Index    int              `json:"index"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// Finish reasons reported on Choice and StreamChunk, normalized to OpenAI's values.
+const (
+	FinishReasonStop      = "stop"
+	FinishReasonLength    = "length"
+	FinishReasonToolCalls = "tool_calls"
+)
+
+// ToolChoice controls whether and which tool the model calls. On the wire it is
+// either a mode string ("auto", "none", "required") or
+// {"type":"function","function":{"name":"..."}} to force a specific function.
+type ToolChoice struct {
+	Mode     string
+	Function string
+}
+
+func (tc ToolChoice) MarshalJSON() ([]byte, error) {
+	if tc.Function != "" {
+		return json.Marshal(map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": tc.Function},
+		})
+	}
+	return json.Marshal(tc.Mode)
+}
+
+func (tc *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		switch mode {
+		case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+			*tc = ToolChoice{Mode: mode}
+			return nil
+		}
+		return fmt.Errorf("invalid tool_choice %q", mode)
+	}
+	var named struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil {
+		return fmt.Errorf("invalid tool_choice: %w", err)
+	}
+	if named.Function.Name == "" {
+		return fmt.Errorf("tool_choice function name is required")
+	}
+	*tc = ToolChoice{Function: named.Function.Name}
+	return nil
+}
+
+// ToolCallingProvider is implemented by providers that can tell whether a model accepts tools.
+type ToolCallingProvider interface {
+	SupportsTools(model string) bool
+}
+
+// SupportsTools reports whether provider can serve a tool-calling request for model.
+func SupportsTools(provider Provider, model string) bool {
+	tp, ok := provider.(ToolCallingProvider)
+	return ok && tp.SupportsTools(model)
+}
+
+func toolsDisabled(req ChatRequest) bool {
+	return len(req.Tools) == 0 || (req.ToolChoice != nil && req.ToolChoice.Mode == ToolChoiceNone)
+}
+
+// toolArgumentsMap decodes a JSON-encoded arguments string for providers that take objects.
+func toolArgumentsMap(arguments string) map[string]interface{} {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(arguments) == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return map[string]interface{}{}
+	}
+	return args
+}
+
+// toolArgumentsString encodes an arguments object returned by a provider.
+func toolArgumentsString(args interface{}) string {
+	if args == nil {
+		return "{}"
+	}
+	if s, ok := args.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// toolCallNames maps tool call IDs to function names so tool results can be
+// labelled for providers that key results by name instead of ID.
+func toolCallNames(messages []Message) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Function.Name
+		}
+	}
+	return names
+}
+
+func toolResultName(msg Message, names map[string]string) string {
+	if msg.Name != "" {
+		return msg.Name
+	}
+	return names[msg.ToolCallID]
+}
@@ -9,14 +9,26 @@ import (
 )
 
 type KeyValidator struct {
-	logger zerolog.Logger
+	logger     zerolog.Logger
+	compatible map[string]OpenAICompatibleConfig
 }
 
 func NewKeyValidator(logger zerolog.Logger) *KeyValidator {
-	return &KeyValidator{logger: logger}
+	return &KeyValidator{logger: logger, compatible: make(map[string]OpenAICompatibleConfig)}
+}
+
+// AddCompatibleProvider lets keys for an OpenAI-compatible upstream be tested.
+func (v *KeyValidator) AddCompatibleProvider(config OpenAICompatibleConfig) {
+	v.compatible[config.Name] = config
 }
 
 func (v *KeyValidator) ValidateKey(ctx context.Context, provider string, apiKey string) error {
+	return v.ValidateKeyWithMetadata(ctx, provider, apiKey, nil)
+}
+
+// ValidateKeyWithMetadata validates keys that need settings stored with them, such as
+// the endpoint of an Azure OpenAI resource.
+func (v *KeyValidator) ValidateKeyWithMetadata(ctx context.Context, provider string, apiKey string, metadata []byte) error {
 	if apiKey == "" {
 		return fmt.Errorf("API key is empty")
 	}
@@ -31,7 +43,16 @@ func (v *KeyValidator) ValidateKey(ctx context.Context, provider string, apiKey
 	case "google":
 		p := NewGoogleProvider(apiKey, "", v.logger)
 		return p.HealthCheck(ctx)
+	case "azure":
+		config, err := ParseAzureOpenAIMetadata(metadata)
+		if err != nil {
+			return err
+		}
+		return NewAzureOpenAIProvider(apiKey, config, v.logger).HealthCheck(ctx)
 	default:
+		if config, ok := v.compatible[provider]; ok {
+			return NewOpenAICompatibleProvider(config, apiKey, v.logger).HealthCheck(ctx)
+		}
 		return fmt.Errorf("unsupported provider: %s", provider)
 	}
 }
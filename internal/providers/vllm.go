@@ -46,12 +46,20 @@ func (p *VLLMProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 		"model":    req.Model,
 		"messages": convertMessagesToOpenAI(req.Messages),
 	}
-	if req.Temperature > 0 {
-		body["temperature"] = req.Temperature
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
 	}
 	if req.MaxTokens > 0 {
 		body["max_tokens"] = req.MaxTokens
 	}
+	applyOpenAISampling(body, req)
+	if len(req.Tools) > 0 {
+		body["tools"] = openAITools(req)
+		if req.ToolChoice != nil {
+			body["tool_choice"] = req.ToolChoice
+		}
+	}
+	applyVLLMResponseFormat(body, req)
 
 	reqBody, err := json.Marshal(body)
 	if err != nil {
@@ -77,7 +85,7 @@ func (p *VLLMProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, newTransportError("vllm", err)
 	}
 	defer resp.Body.Close()
 
@@ -96,9 +104,9 @@ func (p *VLLMProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 			if resp.StatusCode == http.StatusBadRequest && strings.Contains(errResp.Error.Message, "chat template") {
 				return p.chatViaCompletions(ctx, req)
 			}
-			return nil, fmt.Errorf("vLLM API error: %s", errResp.Error.Message)
+			return nil, newStatusError("vllm", resp, fmt.Sprintf("vLLM API error: %s", errResp.Error.Message))
 		}
-		return nil, fmt.Errorf("vLLM API returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, newStatusError("vllm", resp, fmt.Sprintf("vLLM API returned status %d: %s", resp.StatusCode, string(respBody)))
 	}
 
 	var openAIResp struct {
@@ -106,9 +114,11 @@ func (p *VLLMProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
-				Role    string      `json:"role"`
-				Content interface{} `json:"content"`
+				Role      string      `json:"role"`
+				Content   interface{} `json:"content"`
+				ToolCalls []ToolCall  `json:"tool_calls"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 		Usage struct {
 			PromptTokens     int `json:"prompt_tokens"`
@@ -138,12 +148,14 @@ func (p *VLLMProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 					}
 				}
 			}
+		case nil:
 		default:
 			contentStr = fmt.Sprintf("%v", v)
 		}
 
 		choices = append(choices, Choice{
-			Message: Message{Role: c.Message.Role, Content: contentStr},
+			Message:      Message{Role: c.Message.Role, Content: contentStr, ToolCalls: c.Message.ToolCalls},
+			FinishReason: c.FinishReason,
 		})
 	}
 
@@ -165,12 +177,14 @@ func (p *VLLMProvider) chatViaCompletions(ctx context.Context, req ChatRequest)
 		"model":  req.Model,
 		"prompt": prompt,
 	}
-	if req.Temperature > 0 {
-		body["temperature"] = req.Temperature
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
 	}
 	if req.MaxTokens > 0 {
 		body["max_tokens"] = req.MaxTokens
 	}
+	applyOpenAISampling(body, req)
+	applyVLLMResponseFormat(body, req)
 
 	reqBody, err := json.Marshal(body)
 	if err != nil {
@@ -189,7 +203,7 @@ func (p *VLLMProvider) chatViaCompletions(ctx context.Context, req ChatRequest)
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("vLLM completions request failed: %w", err)
+		return nil, newTransportError("vllm", err)
 	}
 	defer resp.Body.Close()
 
@@ -199,7 +213,7 @@ func (p *VLLMProvider) chatViaCompletions(ctx context.Context, req ChatRequest)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("vLLM completions returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, newStatusError("vllm", resp, fmt.Sprintf("vLLM completions returned status %d: %s", resp.StatusCode, string(respBody)))
 	}
 
 	var compResp struct {
@@ -308,12 +322,20 @@ func (p *VLLMProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 			"messages": convertMessagesToOpenAI(req.Messages),
 			"stream":   true,
 		}
-		if req.Temperature > 0 {
-			body["temperature"] = req.Temperature
+		if req.Temperature != nil {
+			body["temperature"] = *req.Temperature
 		}
 		if req.MaxTokens > 0 {
 			body["max_tokens"] = req.MaxTokens
 		}
+		applyOpenAISampling(body, req)
+		if len(req.Tools) > 0 {
+			body["tools"] = openAITools(req)
+			if req.ToolChoice != nil {
+				body["tool_choice"] = req.ToolChoice
+			}
+		}
+		applyVLLMResponseFormat(body, req)
 
 		reqBody, err := json.Marshal(body)
 		if err != nil {
@@ -334,19 +356,20 @@ func (p *VLLMProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 
 		resp, err := p.streamClient().Do(httpReq)
 		if err != nil {
-			errChan <- err
+			errChan <- newTransportError("vllm", err)
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			b, _ := io.ReadAll(resp.Body)
-			errChan <- fmt.Errorf("vLLM API returned status %d: %s", resp.StatusCode, string(b))
+			errChan <- newStatusError("vllm", resp, fmt.Sprintf("vLLM API returned status %d: %s", resp.StatusCode, string(b)))
 			return
 		}
 
 		var responseID string
 		var finalUsage *Usage
+		var finishReason string
 		scanner := bufio.NewScanner(resp.Body)
 		const maxLineSize = 1024 * 1024
 		buf := make([]byte, 0, 64*1024)
@@ -360,14 +383,17 @@ func (p *VLLMProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 
 			data := strings.TrimPrefix(line, "data: ")
 			if data == "[DONE]" {
-				chunkChan <- StreamChunk{ID: responseID, Content: "", Done: true, Usage: finalUsage}
+				chunkChan <- StreamChunk{ID: responseID, Content: "", Done: true, Usage: finalUsage, FinishReason: finishReason}
 				return
 			}
 
 			var chunk struct {
 				ID      string `json:"id"`
 				Choices []struct {
-					Delta        struct{ Content string `json:"content"` }
+					Delta        struct {
+						Content   string          `json:"content"`
+						ToolCalls []ToolCallDelta `json:"tool_calls"`
+					}
 					FinishReason string `json:"finish_reason"`
 				} `json:"choices"`
 				Usage struct {
@@ -389,7 +415,11 @@ func (p *VLLMProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 				if delta := chunk.Choices[0].Delta.Content; delta != "" {
 					chunkChan <- StreamChunk{ID: responseID, Content: delta, Done: false}
 				}
+				if toolCalls := chunk.Choices[0].Delta.ToolCalls; len(toolCalls) > 0 {
+					chunkChan <- StreamChunk{ID: responseID, ToolCalls: toolCalls}
+				}
 				if chunk.Choices[0].FinishReason != "" {
+					finishReason = chunk.Choices[0].FinishReason
 					finalUsage = &Usage{
 						PromptTokens:     chunk.Usage.PromptTokens,
 						CompletionTokens: chunk.Usage.CompletionTokens,
@@ -407,44 +437,93 @@ func (p *VLLMProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan
 	return chunkChan, errChan
 }
 
+// SupportsResponseFormat reports true: JSON output uses guided decoding (guided_json).
+func (p *VLLMProvider) SupportsResponseFormat(model string) bool {
+	return true
+}
+
+// SupportsTools is optimistic: tool parsing depends on how the vLLM server was launched.
+func (p *VLLMProvider) SupportsTools(model string) bool {
+	return true
+}
+
 func (p *VLLMProvider) GetModels() []string {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	models, err := p.listModels(ctx)
 	if err != nil {
-		p.logger.Debug().Err(err).Msg("vLLM GetModels request failed")
+		p.logger.Debug().Err(err).Msg("vLLM GetModels failed")
 		return nil
 	}
+
+	names := make([]string, 0, len(models))
+	for _, m := range models {
+		names = append(names, m.ID)
+	}
+	return names
+}
+
+type vllmModel struct {
+	ID          string `json:"id"`
+	MaxModelLen int    `json:"max_model_len"`
+}
+
+func (p *VLLMProvider) listModels(ctx context.Context) ([]vllmModel, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
 	if p.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		p.logger.Debug().Err(err).Msg("vLLM GetModels failed")
-		return nil
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil
+		return nil, fmt.Errorf("models request returned status %d", resp.StatusCode)
 	}
 
 	var list struct {
-		Data []struct {
-			ID string `json:"id"`
-		} `json:"data"`
+		Data []vllmModel `json:"data"`
 	}
-	if json.NewDecoder(resp.Body).Decode(&list) != nil {
-		return nil
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
 	}
 
-	names := make([]string, 0, len(list.Data))
+	models := make([]vllmModel, 0, len(list.Data))
 	for _, m := range list.Data {
 		if m.ID != "" {
-			names = append(names, m.ID)
+			models = append(models, m)
 		}
 	}
-	return names
+	return models, nil
+}
+
+// DiscoverCapabilities reads each served model's max_model_len. vLLM does not say
+// which inputs a model accepts, so images and audio are passed through and rejected
+// by the server when unsupported.
+func (p *VLLMProvider) DiscoverCapabilities(ctx context.Context) (map[string]Capabilities, error) {
+	models, err := p.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]Capabilities, len(models))
+	for _, m := range models {
+		out[m.ID] = Capabilities{ContextLength: m.MaxModelLen, Vision: true, Audio: true, Tools: true}
+	}
+	return out, nil
+}
+
+func (p *VLLMProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	return embedOpenAICompatible(ctx, p.client, p.baseURL+"/embeddings", p.apiKey, "vLLM", req)
+}
+
+// GetEmbeddingModels returns every served model; vLLM rejects embedding calls to generative ones.
+func (p *VLLMProvider) GetEmbeddingModels() []string {
+	return p.GetModels()
 }
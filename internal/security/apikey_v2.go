@@ -26,7 +26,17 @@ func NewAPIKeyServiceV2(repo storage.APIKeyRepositoryInterface, secret string) *
 	}
 }
 
-func (s *APIKeyServiceV2) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, rateLimitPerMinute, rateLimitPerDay int, expiresAt *time.Time) (string, *storage.APIKey, error) {
+// APIKeySettings are the optional settings a key is created with.
+type APIKeySettings struct {
+	CacheTTLSeconds     int
+	TokenLimitPerMinute int
+	TokenLimitPerDay    int
+	HedgePercentile     int
+	PIIPolicy           *storage.PIIPolicy
+	Budgets             []*storage.Budget
+}
+
+func (s *APIKeyServiceV2) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, rateLimitPerMinute, rateLimitPerDay int, expiresAt *time.Time, settings APIKeySettings) (string, *storage.APIKey, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", nil, fmt.Errorf("failed to generate random bytes: %w", err)
@@ -41,19 +51,24 @@ func (s *APIKeyServiceV2) CreateAPIKey(ctx context.Context, userID uuid.UUID, na
 	}
 
 	apiKey := &storage.APIKey{
-		ID:                 uuid.New(),
-		UserID:             userID,
-		LookupHash:         lookupHash,         // SHA256 for fast lookup
-		VerificationHash:   string(bcryptHash), // bcrypt for verification
-		Name:               name,
-		RateLimitPerMinute: rateLimitPerMinute,
-		RateLimitPerDay:    rateLimitPerDay,
-		CreatedAt:          time.Now(),
-		ExpiresAt:          expiresAt,
-		IsActive:           true,
+		ID:                  uuid.New(),
+		UserID:              userID,
+		LookupHash:          lookupHash,         // SHA256 for fast lookup
+		VerificationHash:    string(bcryptHash), // bcrypt for verification
+		Name:                name,
+		RateLimitPerMinute:  rateLimitPerMinute,
+		RateLimitPerDay:     rateLimitPerDay,
+		CreatedAt:           time.Now(),
+		ExpiresAt:           expiresAt,
+		IsActive:            true,
+		CacheTTLSeconds:     settings.CacheTTLSeconds,
+		TokenLimitPerMinute: settings.TokenLimitPerMinute,
+		TokenLimitPerDay:    settings.TokenLimitPerDay,
+		HedgePercentile:     settings.HedgePercentile,
+		PIIPolicy:           settings.PIIPolicy,
 	}
 
-	if err := s.repo.Create(ctx, apiKey); err != nil {
+	if err := s.repo.Create(ctx, apiKey, settings.Budgets); err != nil {
 		return "", nil, fmt.Errorf("failed to store API key: %w", err)
 	}
 
@@ -83,6 +98,35 @@ func (s *APIKeyServiceV2) ListAPIKeysByUser(ctx context.Context, userID uuid.UUI
 	return s.repo.ListByUserID(ctx, userID)
 }
 
+// SetCacheTTL sets the response cache TTL of one of userID's keys. It returns nil if the key is not found.
+func (s *APIKeyServiceV2) SetCacheTTL(ctx context.Context, userID, keyID uuid.UUID, ttlSeconds int) (*storage.APIKey, error) {
+	key, err := s.repo.UpdateCacheTTL(ctx, userID, keyID, ttlSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update API key: %w", err)
+	}
+	return key, nil
+}
+
+// SetHedgePercentile sets the latency percentile after which chat requests made with one
+// of userID's keys are hedged (0 = off). It returns nil if the key is not found.
+func (s *APIKeyServiceV2) SetHedgePercentile(ctx context.Context, userID, keyID uuid.UUID, percentile int) (*storage.APIKey, error) {
+	key, err := s.repo.UpdateHedgePercentile(ctx, userID, keyID, percentile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update API key: %w", err)
+	}
+	return key, nil
+}
+
+// SetPIIPolicy sets the PII redaction policy of one of userID's keys (nil = off). It
+// returns nil if the key is not found.
+func (s *APIKeyServiceV2) SetPIIPolicy(ctx context.Context, userID, keyID uuid.UUID, policy *storage.PIIPolicy) (*storage.APIKey, error) {
+	key, err := s.repo.UpdatePIIPolicy(ctx, userID, keyID, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update API key: %w", err)
+	}
+	return key, nil
+}
+
 func (s *APIKeyServiceV2) DeleteAPIKey(ctx context.Context, keyID uuid.UUID) error {
 	return s.repo.Delete(ctx, keyID)
 }
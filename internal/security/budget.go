@@ -0,0 +1,247 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	BudgetScopeUser   = "user"
+	BudgetScopeAPIKey = "api_key"
+
+	budgetSpendPrefix = "budget:spend:"
+	budgetCacheTTL    = 30 * time.Second
+)
+
+var budgetPeriods = []string{storage.BudgetPeriodDaily, storage.BudgetPeriodMonthly, storage.BudgetPeriodLifetime}
+
+func IsValidBudgetPeriod(period string) bool {
+	for _, p := range budgetPeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// SpendStore keeps running USD totals. Get returns 0 for keys that do not exist.
+type SpendStore interface {
+	Add(ctx context.Context, key string, amount float64, ttl time.Duration) error
+	Get(ctx context.Context, keys []string) ([]float64, error)
+}
+
+type redisSpendStore struct {
+	client *storage.RedisClient
+}
+
+func NewRedisSpendStore(client *storage.RedisClient) SpendStore {
+	return &redisSpendStore{client: client}
+}
+
+func (s *redisSpendStore) Add(ctx context.Context, key string, amount float64, ttl time.Duration) error {
+	pipe := s.client.Client().TxPipeline()
+	pipe.IncrByFloat(ctx, key, amount)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisSpendStore) Get(ctx context.Context, keys []string) ([]float64, error) {
+	values, err := s.client.Client().MGet(ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	totals := make([]float64, len(keys))
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if totals[i], err = strconv.ParseFloat(str, 64); err != nil {
+			return nil, fmt.Errorf("invalid spend total for %s: %w", keys[i], err)
+		}
+	}
+	return totals, nil
+}
+
+// BudgetLister loads the budgets of a user, including the budgets of their keys.
+type BudgetLister interface {
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*storage.Budget, error)
+}
+
+// BudgetStatus is a budget together with the spend counted against it in the current period.
+type BudgetStatus struct {
+	Scope            string     `json:"scope"`
+	APIKeyID         *uuid.UUID `json:"api_key_id,omitempty"`
+	Period           string     `json:"period"`
+	SpentUSD         float64    `json:"spent_usd"`
+	SoftLimitUSD     *float64   `json:"soft_limit_usd,omitempty"`
+	LimitUSD         *float64   `json:"limit_usd,omitempty"`
+	RemainingUSD     *float64   `json:"remaining_usd,omitempty"`
+	ResetsAt         *time.Time `json:"resets_at,omitempty"`
+	SoftLimitReached bool       `json:"soft_limit_reached"`
+	LimitReached     bool       `json:"limit_reached"`
+}
+
+type cachedBudgets struct {
+	budgets   []*storage.Budget
+	expiresAt time.Time
+}
+
+// BudgetTracker counts the USD spend of every user and API key per day, month and
+// lifetime, and compares it with their budgets. Spend is recorded after a request
+// completes, so the request that crosses a hard limit is still served and the next
+// one is rejected.
+type BudgetTracker struct {
+	store   SpendStore
+	budgets BudgetLister
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedBudgets
+}
+
+func NewBudgetTracker(store SpendStore, budgets BudgetLister) *BudgetTracker {
+	return &BudgetTracker{
+		store:   store,
+		budgets: budgets,
+		cache:   make(map[uuid.UUID]cachedBudgets),
+	}
+}
+
+// RecordSpend adds cost to the user's and the key's totals for every period, whether
+// or not they have a budget, so a budget added later counts the period's earlier spend.
+func (t *BudgetTracker) RecordSpend(ctx context.Context, userID uuid.UUID, apiKeyID *uuid.UUID, cost float64) error {
+	if cost <= 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, period := range budgetPeriods {
+		key, ttl, _ := spendKey(BudgetScopeUser, userID, period, now)
+		if err := t.store.Add(ctx, key, cost, ttl); err != nil {
+			return fmt.Errorf("failed to record spend: %w", err)
+		}
+		if apiKeyID != nil {
+			key, ttl, _ = spendKey(BudgetScopeAPIKey, *apiKeyID, period, now)
+			if err := t.store.Add(ctx, key, cost, ttl); err != nil {
+				return fmt.Errorf("failed to record spend: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns the status of every budget that applies to a request made by the
+// user with the given key: the user-wide budgets and the key's own.
+func (t *BudgetTracker) Check(ctx context.Context, userID uuid.UUID, apiKeyID *uuid.UUID) ([]BudgetStatus, error) {
+	budgets, err := t.userBudgets(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	applicable := make([]*storage.Budget, 0, len(budgets))
+	for _, b := range budgets {
+		if b.APIKeyID == nil || (apiKeyID != nil && *b.APIKeyID == *apiKeyID) {
+			applicable = append(applicable, b)
+		}
+	}
+	return t.Statuses(ctx, applicable)
+}
+
+// Statuses reads the current spend for each budget.
+func (t *BudgetTracker) Statuses(ctx context.Context, budgets []*storage.Budget) ([]BudgetStatus, error) {
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	keys := make([]string, len(budgets))
+	statuses := make([]BudgetStatus, len(budgets))
+	for i, b := range budgets {
+		scope, id := BudgetScopeUser, b.UserID
+		if b.APIKeyID != nil {
+			scope, id = BudgetScopeAPIKey, *b.APIKeyID
+		}
+		var resetsAt *time.Time
+		keys[i], _, resetsAt = spendKey(scope, id, b.Period, now)
+		statuses[i] = BudgetStatus{
+			Scope:        scope,
+			APIKeyID:     b.APIKeyID,
+			Period:       b.Period,
+			SoftLimitUSD: b.SoftLimitUSD,
+			LimitUSD:     b.HardLimitUSD,
+			ResetsAt:     resetsAt,
+		}
+	}
+
+	spent, err := t.store.Get(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spend: %w", err)
+	}
+	for i := range statuses {
+		s := &statuses[i]
+		s.SpentUSD = spent[i]
+		if s.SoftLimitUSD != nil {
+			s.SoftLimitReached = s.SpentUSD >= *s.SoftLimitUSD
+		}
+		if s.LimitUSD != nil {
+			remaining := *s.LimitUSD - s.SpentUSD
+			if remaining < 0 {
+				remaining = 0
+			}
+			s.RemainingUSD = &remaining
+			s.LimitReached = s.SpentUSD >= *s.LimitUSD
+		}
+	}
+	return statuses, nil
+}
+
+// Invalidate drops the cached budgets of a user after they were edited.
+func (t *BudgetTracker) Invalidate(userID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.cache, userID)
+}
+
+func (t *BudgetTracker) userBudgets(ctx context.Context, userID uuid.UUID) ([]*storage.Budget, error) {
+	t.mu.Lock()
+	cached, ok := t.cache[userID]
+	t.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.budgets, nil
+	}
+
+	budgets, err := t.budgets.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budgets: %w", err)
+	}
+	t.mu.Lock()
+	t.cache[userID] = cachedBudgets{budgets: budgets, expiresAt: time.Now().Add(budgetCacheTTL)}
+	t.mu.Unlock()
+	return budgets, nil
+}
+
+// spendKey returns the counter for a scope and period at now, how long the counter
+// must be kept, and when the period ends. Periods follow the UTC calendar.
+func spendKey(scope string, id uuid.UUID, period string, now time.Time) (string, time.Duration, *time.Time) {
+	now = now.UTC()
+	prefix := budgetSpendPrefix + scope + ":" + id.String() + ":" + period
+	switch period {
+	case storage.BudgetPeriodDaily:
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		resetsAt := start.AddDate(0, 0, 1)
+		return prefix + ":" + start.Format("20060102"), resetsAt.Sub(now) + time.Hour, &resetsAt
+	case storage.BudgetPeriodMonthly:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		resetsAt := start.AddDate(0, 1, 0)
+		return prefix + ":" + start.Format("200601"), resetsAt.Sub(now) + time.Hour, &resetsAt
+	default:
+		return prefix, 0, nil
+	}
+}
@@ -16,8 +16,9 @@ import (
 )
 
 type ProviderKeyService struct {
-	repo          ProviderKeyRepositoryInterface
-	encryptionKey []byte // Master encryption key (should be from config)
+	repo           ProviderKeyRepositoryInterface
+	encryptionKey  []byte // Master encryption key (should be from config)
+	extraProviders map[string]bool
 }
 
 type ProviderKeyRepositoryInterface interface {
@@ -45,7 +46,13 @@ func deriveEncryptionKey(key string) []byte {
 }
 
 func (s *ProviderKeyService) AddProviderKey(ctx context.Context, userID uuid.UUID, provider string, apiKey string) error {
-	if !isValidProvider(provider) {
+	return s.AddProviderKeyWithMetadata(ctx, userID, provider, apiKey, nil)
+}
+
+// AddProviderKeyWithMetadata stores a key with its provider settings (JSON, not
+// encrypted), such as the endpoint and deployments of an Azure OpenAI resource.
+func (s *ProviderKeyService) AddProviderKeyWithMetadata(ctx context.Context, userID uuid.UUID, provider string, apiKey string, metadata []byte) error {
+	if !s.isValidProvider(provider) {
 		return fmt.Errorf("invalid provider: %s", provider)
 	}
 
@@ -59,6 +66,7 @@ func (s *ProviderKeyService) AddProviderKey(ctx context.Context, userID uuid.UUI
 		UserID:          userID,
 		Provider:        provider,
 		APIKeyEncrypted: encrypted,
+		Metadata:        metadata,
 		IsActive:        true,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
@@ -68,20 +76,26 @@ func (s *ProviderKeyService) AddProviderKey(ctx context.Context, userID uuid.UUI
 }
 
 func (s *ProviderKeyService) GetProviderKey(ctx context.Context, userID uuid.UUID, provider string) (string, error) {
+	apiKey, _, err := s.GetProviderKeyWithMetadata(ctx, userID, provider)
+	return apiKey, err
+}
+
+// GetProviderKeyWithMetadata returns the decrypted key and the settings stored with it.
+func (s *ProviderKeyService) GetProviderKeyWithMetadata(ctx context.Context, userID uuid.UUID, provider string) (string, []byte, error) {
 	key, err := s.repo.FindByUserAndProvider(ctx, userID, provider)
 	if err != nil {
-		return "", fmt.Errorf("failed to find provider key: %w", err)
+		return "", nil, fmt.Errorf("failed to find provider key: %w", err)
 	}
 	if key == nil {
-		return "", nil
+		return "", nil, nil
 	}
 
 	decrypted, err := s.decrypt(key.APIKeyEncrypted)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt API key: %w", err)
+		return "", nil, fmt.Errorf("failed to decrypt API key: %w", err)
 	}
 
-	return decrypted, nil
+	return decrypted, key.Metadata, nil
 }
 
 func (s *ProviderKeyService) ListProviderKeys(ctx context.Context, userID uuid.UUID) ([]*storage.UserProviderKey, error) {
@@ -89,12 +103,17 @@ func (s *ProviderKeyService) ListProviderKeys(ctx context.Context, userID uuid.U
 }
 
 func (s *ProviderKeyService) UpdateProviderKey(ctx context.Context, userID uuid.UUID, provider string, apiKey string) error {
+	return s.UpdateProviderKeyWithMetadata(ctx, userID, provider, apiKey, nil)
+}
+
+// UpdateProviderKeyWithMetadata replaces a key; nil metadata keeps the stored settings.
+func (s *ProviderKeyService) UpdateProviderKeyWithMetadata(ctx context.Context, userID uuid.UUID, provider string, apiKey string, metadata []byte) error {
 	existing, err := s.repo.FindByUserAndProvider(ctx, userID, provider)
 	if err != nil {
 		return fmt.Errorf("failed to find provider key: %w", err)
 	}
 	if existing == nil {
-		return s.AddProviderKey(ctx, userID, provider, apiKey)
+		return s.AddProviderKeyWithMetadata(ctx, userID, provider, apiKey, metadata)
 	}
 
 	encrypted, err := s.encrypt(apiKey)
@@ -103,6 +122,9 @@ func (s *ProviderKeyService) UpdateProviderKey(ctx context.Context, userID uuid.
 	}
 
 	existing.APIKeyEncrypted = encrypted
+	if metadata != nil {
+		existing.Metadata = metadata
+	}
 	existing.UpdatedAt = time.Now()
 	existing.IsActive = true
 
@@ -171,14 +193,26 @@ func (s *ProviderKeyService) decrypt(encrypted string) (string, error) {
 	return string(plaintext), nil
 }
 
-func isValidProvider(provider string) bool {
+// AddProviders accepts keys for providers declared in config, such as OpenAI-compatible
+// upstreams that allow BYOK.
+func (s *ProviderKeyService) AddProviders(names ...string) {
+	if s.extraProviders == nil {
+		s.extraProviders = make(map[string]bool)
+	}
+	for _, name := range names {
+		s.extraProviders[name] = true
+	}
+}
+
+func (s *ProviderKeyService) isValidProvider(provider string) bool {
 	validProviders := map[string]bool{
 		"openai":    true,
 		"anthropic": true,
 		"google":    true,
 		"ollama":    true,
 		"vllm":      true,
+		"azure":     true,
 	}
-	return validProviders[provider]
+	return validProviders[provider] || s.extraProviders[provider]
 }
 
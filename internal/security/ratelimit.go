@@ -3,89 +3,257 @@ package security
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
 	"github.com/redis/go-redis/v9"
 )
 
+// CounterStore keeps the integer counters behind rate limits. Get returns 0 for keys
+// that do not exist.
+type CounterStore interface {
+	IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	Get(ctx context.Context, keys []string) ([]int64, error)
+}
+
+type redisCounterStore struct {
+	client *storage.RedisClient
+}
+
+func NewRedisCounterStore(client *storage.RedisClient) CounterStore {
+	return &redisCounterStore{client: client}
+}
+
+func (s *redisCounterStore) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	pipe := s.client.Client().TxPipeline()
+	incr := pipe.IncrBy(ctx, key, delta)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (s *redisCounterStore) Get(ctx context.Context, keys []string) ([]int64, error) {
+	values, err := s.client.Client().MGet(ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	counts := make([]int64, len(keys))
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if counts[i], err = strconv.ParseInt(str, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid counter %s: %w", keys[i], err)
+		}
+	}
+	return counts, nil
+}
+
+// RateLimit allows Limit units (requests or tokens) per sliding Window. A Limit of 0
+// or less disables it.
+type RateLimit struct {
+	Window time.Duration
+	Limit  int64
+}
+
+// RateLimitWindow is the state of one RateLimit after a check.
+type RateLimitWindow struct {
+	RateLimit
+	Remaining  int64
+	Reset      time.Duration // until the current bucket ends and older usage starts to expire
+	RetryAfter time.Duration // set when the check was rejected: until the cost would fit
+}
+
+// RateLimiter enforces limits over sliding windows. Each window is approximated from
+// two fixed buckets: the current one plus the previous one, weighted by how much of
+// it still overlaps the window. This avoids the burst that fixed windows allow at
+// the boundary while needing only two counters per limit.
 type RateLimiter struct {
-	redis *storage.RedisClient
+	store CounterStore
 }
 
 func NewRateLimiter(redis *storage.RedisClient) *RateLimiter {
+	return NewRateLimiterWithStore(NewRedisCounterStore(redis))
+}
+
+func NewRateLimiterWithStore(store CounterStore) *RateLimiter {
 	return &RateLimiter{
-		redis: redis,
+		store: store,
 	}
 }
 
+// CheckRateLimit takes one request from the per-minute and per-day limits of key.
 func (r *RateLimiter) CheckRateLimit(ctx context.Context, key string, limitPerMinute, limitPerDay int) (bool, error) {
-	client := r.redis.Client()
+	allowed, _, err := r.Take(ctx, "requests:"+key, MinuteAndDayLimits(limitPerMinute, limitPerDay), 1)
+	return allowed, err
+}
 
-	// Check per-minute limit
-	minuteKey := fmt.Sprintf("ratelimit:minute:%s", key)
-	minuteCount, err := client.Incr(ctx, minuteKey).Result()
+func (r *RateLimiter) GetRemainingRequests(ctx context.Context, key string, limitPerMinute, limitPerDay int) (minuteRemaining, dayRemaining int64, err error) {
+	windows, err := r.Peek(ctx, "requests:"+key, MinuteAndDayLimits(limitPerMinute, limitPerDay))
 	if err != nil {
-		return false, fmt.Errorf("failed to check rate limit: %w", err)
+		return 0, 0, err
+	}
+	return windows[0].Remaining, windows[1].Remaining, nil
+}
+
+// RequestLimits returns per-minute and per-day limits, in that order.
+func MinuteAndDayLimits(perMinute, perDay int) []RateLimit {
+	return []RateLimit{
+		{Window: time.Minute, Limit: int64(perMinute)},
+		{Window: 24 * time.Hour, Limit: int64(perDay)},
 	}
+}
+
+// Take counts cost against every limit of key, or against none of them if any limit
+// would be exceeded. Against each limit the cost is capped at the limit itself, so a
+// request larger than a limit can still run once the window is empty.
+func (r *RateLimiter) Take(ctx context.Context, key string, limits []RateLimit, cost int64) (bool, []RateLimitWindow, error) {
+	return r.take(ctx, key, limits, cost, time.Now())
+}
+
+func (r *RateLimiter) take(ctx context.Context, key string, limits []RateLimit, cost int64, now time.Time) (bool, []RateLimitWindow, error) {
+	windows := make([]RateLimitWindow, len(limits))
+	taken := make([]int64, 0, len(limits))
+	allowed := true
 
-	if minuteCount == 1 {
-		// Set expiration for minute window
-		client.Expire(ctx, minuteKey, time.Minute)
+	for i, limit := range limits {
+		windows[i].RateLimit = limit
+		if limit.Limit <= 0 {
+			taken = append(taken, 0)
+			continue
+		}
+		cur, prev, weight, elapsed, err := r.counters(ctx, key, limit.Window, now)
+		if err != nil {
+			return false, nil, err
+		}
+		windows[i].Reset = limit.Window - elapsed
+
+		// After a rejection the remaining limits are only read, for the headers.
+		var charge int64
+		if allowed {
+			charge = min(cost, limit.Limit)
+		}
+		if charge > 0 {
+			if cur, err = r.store.IncrBy(ctx, bucketKey(key, limit.Window, now), charge, 2*limit.Window); err != nil {
+				return false, nil, fmt.Errorf("failed to check rate limit: %w", err)
+			}
+		}
+		taken = append(taken, charge)
+		used := float64(prev)*weight + float64(cur)
+		if allowed && used > float64(limit.Limit) {
+			allowed = false
+			used -= float64(charge)
+			windows[i].RetryAfter = retryAfter(prev, cur-charge, weight, elapsed, limit, charge)
+		}
+		windows[i].Remaining = remaining(limit.Limit, used)
 	}
 
-	if minuteCount > int64(limitPerMinute) {
-		return false, nil
+	if !allowed {
+		for i, charge := range taken {
+			if charge > 0 {
+				if _, err := r.store.IncrBy(ctx, bucketKey(key, limits[i].Window, now), -charge, 2*limits[i].Window); err != nil {
+					return false, nil, fmt.Errorf("failed to roll back rate limit: %w", err)
+				}
+			}
+		}
 	}
+	return allowed, windows, nil
+}
 
-	// Check per-day limit
-	dayKey := fmt.Sprintf("ratelimit:day:%s:%s", key, time.Now().Format("2006-01-02"))
-	dayCount, err := client.Incr(ctx, dayKey).Result()
-	if err != nil {
-		return false, fmt.Errorf("failed to check daily rate limit: %w", err)
+// Peek returns the state of every limit of key without counting anything.
+func (r *RateLimiter) Peek(ctx context.Context, key string, limits []RateLimit) ([]RateLimitWindow, error) {
+	now := time.Now()
+	windows := make([]RateLimitWindow, len(limits))
+	for i, limit := range limits {
+		windows[i].RateLimit = limit
+		if limit.Limit <= 0 {
+			continue
+		}
+		cur, prev, weight, elapsed, err := r.counters(ctx, key, limit.Window, now)
+		if err != nil {
+			return nil, err
+		}
+		windows[i].Remaining = remaining(limit.Limit, float64(prev)*weight+float64(cur))
+		windows[i].Reset = limit.Window - elapsed
 	}
+	return windows, nil
+}
 
-	if dayCount == 1 {
-		// Set expiration for day window (25 hours to be safe)
-		client.Expire(ctx, dayKey, 25*time.Hour)
+// Adjust adds delta, which may be negative, to the bucket of every limit of key that
+// was current at taken. It reconciles an estimate taken up front with the actual
+// amount. Buckets no longer in their window are left alone, and counters stop at 0.
+func (r *RateLimiter) Adjust(ctx context.Context, key string, limits []RateLimit, delta int64, taken time.Time) error {
+	if delta == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, limit := range limits {
+		if limit.Limit <= 0 || bucketIndex(limit.Window, now)-bucketIndex(limit.Window, taken) > 1 {
+			continue
+		}
+		bucket := bucketKey(key, limit.Window, taken)
+		count, err := r.store.IncrBy(ctx, bucket, delta, 2*limit.Window)
+		if err != nil {
+			return fmt.Errorf("failed to adjust rate limit: %w", err)
+		}
+		if count < 0 {
+			if _, err := r.store.IncrBy(ctx, bucket, -count, 2*limit.Window); err != nil {
+				return fmt.Errorf("failed to adjust rate limit: %w", err)
+			}
+		}
 	}
+	return nil
+}
 
-	if dayCount > int64(limitPerDay) {
-		return false, nil
+// counters reads the current and previous bucket of a window, the weight of the
+// previous bucket and how far into the current bucket now is.
+func (r *RateLimiter) counters(ctx context.Context, key string, window time.Duration, now time.Time) (cur, prev int64, weight float64, elapsed time.Duration, err error) {
+	counts, err := r.store.Get(ctx, []string{bucketKey(key, window, now), bucketKey(key, window, now.Add(-window))})
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to check rate limit: %w", err)
 	}
+	elapsed = time.Duration(now.UnixNano() % int64(window))
+	weight = 1 - float64(elapsed)/float64(window)
+	return counts[0], counts[1], weight, elapsed, nil
+}
 
-	return true, nil
+func bucketKey(key string, window time.Duration, at time.Time) string {
+	return fmt.Sprintf("ratelimit:%s:%d:%d", key, int64(window/time.Second), bucketIndex(window, at))
 }
 
-func (r *RateLimiter) GetRemainingRequests(ctx context.Context, key string, limitPerMinute, limitPerDay int) (minuteRemaining, dayRemaining int64, err error) {
-	client := r.redis.Client()
+func bucketIndex(window time.Duration, at time.Time) int64 {
+	return at.UnixNano() / int64(window)
+}
 
-	minuteKey := fmt.Sprintf("ratelimit:minute:%s", key)
-	minuteCount, err := client.Get(ctx, minuteKey).Int64()
-	if err == redis.Nil {
-		minuteCount = 0
-	} else if err != nil {
-		return 0, 0, err
+func remaining(limit int64, used float64) int64 {
+	left := limit - int64(math.Ceil(used))
+	if left < 0 {
+		return 0
 	}
+	return left
+}
 
-	dayKey := fmt.Sprintf("ratelimit:day:%s:%s", key, time.Now().Format("2006-01-02"))
-	dayCount, err := client.Get(ctx, dayKey).Int64()
-	if err == redis.Nil {
-		dayCount = 0
-	} else if err != nil {
-		return 0, 0, err
+// retryAfter is how long until cost fits under the limit, given that the previous
+// bucket's share of the window shrinks linearly and the current bucket becomes the
+// previous one when it ends.
+func retryAfter(prev, cur int64, weight float64, elapsed time.Duration, limit RateLimit, cost int64) time.Duration {
+	window := float64(limit.Window)
+	need := float64(prev)*weight + float64(cur) + float64(cost) - float64(limit.Limit)
+	if need <= 0 {
+		return 0
 	}
-
-	minuteRemaining = int64(limitPerMinute) - minuteCount
-	if minuteRemaining < 0 {
-		minuteRemaining = 0
+	if prev > 0 && float64(prev)*weight >= need {
+		return time.Duration(need / float64(prev) * window)
 	}
-
-	dayRemaining = int64(limitPerDay) - dayCount
-	if dayRemaining < 0 {
-		dayRemaining = 0
+	untilReset := limit.Window - elapsed
+	need = float64(cur) + float64(cost) - float64(limit.Limit)
+	if need <= 0 || cur <= 0 {
+		return untilReset
 	}
-
-	return minuteRemaining, dayRemaining, nil
+	return untilReset + time.Duration(need/float64(cur)*window)
 }
-
@@ -0,0 +1,122 @@
+package security
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenLimits caps prompt plus completion tokens per sliding minute and day. 0
+// disables a window.
+type TokenLimits struct {
+	PerMinute int
+	PerDay    int
+}
+
+func (l TokenLimits) Enabled() bool {
+	return l.PerMinute > 0 || l.PerDay > 0
+}
+
+// TokenLimitScope applies limits to the tokens of one API key or user.
+type TokenLimitScope struct {
+	Key    string
+	Limits TokenLimits
+}
+
+type reservedLimit struct {
+	key     string
+	limit   RateLimit
+	charged int64
+	taken   time.Time // picks the bucket the charge went to
+}
+
+// TokenReservation holds the tokens a request was estimated to use, taken from its
+// token limits before routing, until the handler reports the actual usage.
+type TokenReservation struct {
+	limiter  *RateLimiter
+	reserved []reservedLimit
+
+	mu       sync.Mutex
+	used     int64
+	reported bool
+}
+
+// ReserveTokens takes estimate tokens from every scope, or from none of them if any
+// limit would be exceeded. The returned windows follow the order of scopes, per-minute
+// before per-day.
+func (r *RateLimiter) ReserveTokens(ctx context.Context, estimate int64, scopes ...TokenLimitScope) (*TokenReservation, []RateLimitWindow, bool, error) {
+	reservation := &TokenReservation{limiter: r}
+	var windows []RateLimitWindow
+	now := time.Now()
+	for _, scope := range scopes {
+		key := "tokens:" + scope.Key
+		allowed, scopeWindows, err := r.take(ctx, key, MinuteAndDayLimits(scope.Limits.PerMinute, scope.Limits.PerDay), estimate, now)
+		if err != nil {
+			reservation.release(ctx)
+			return nil, nil, false, err
+		}
+		windows = append(windows, scopeWindows...)
+		if !allowed {
+			if err := reservation.release(ctx); err != nil {
+				return nil, nil, false, err
+			}
+			return nil, windows, false, nil
+		}
+		for _, w := range scopeWindows {
+			if w.Limit > 0 {
+				reservation.reserved = append(reservation.reserved, reservedLimit{key: key, limit: w.RateLimit, charged: min(estimate, w.Limit), taken: now})
+			}
+		}
+	}
+	return reservation, windows, true, nil
+}
+
+// Report adds tokens the request actually used. Cached responses report 0.
+func (t *TokenReservation) Report(tokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.used += int64(tokens)
+	t.reported = true
+}
+
+// Settle replaces the estimate with the reported usage. Without a report the estimate
+// stands, unless the request failed, in which case it is returned.
+func (t *TokenReservation) Settle(ctx context.Context, failed bool) error {
+	t.mu.Lock()
+	used, reported := t.used, t.reported
+	t.mu.Unlock()
+	if !reported {
+		if !failed {
+			return nil
+		}
+		return t.release(ctx)
+	}
+	for _, r := range t.reserved {
+		if err := t.limiter.Adjust(ctx, r.key, []RateLimit{r.limit}, used-r.charged, r.taken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TokenReservation) release(ctx context.Context) error {
+	for _, r := range t.reserved {
+		if err := t.limiter.Adjust(ctx, r.key, []RateLimit{r.limit}, -r.charged, r.taken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tokenReservationKey struct{}
+
+func WithTokenReservation(ctx context.Context, reservation *TokenReservation) context.Context {
+	return context.WithValue(ctx, tokenReservationKey{}, reservation)
+}
+
+// ReportTokenUsage records actual token usage on the request's reservation, if any.
+func ReportTokenUsage(ctx context.Context, tokens int) {
+	if reservation, ok := ctx.Value(tokenReservationKey{}).(*TokenReservation); ok {
+		reservation.Report(tokens)
+	}
+}
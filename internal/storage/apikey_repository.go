@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,13 +24,24 @@ func NewAPIKeyRepository(pool *pgxpool.Pool) *APIKeyRepository {
 	}
 }
 
-func (r *APIKeyRepository) Create(ctx context.Context, key *APIKey) error {
+// Create inserts key and its budgets in one transaction.
+func (r *APIKeyRepository) Create(ctx context.Context, key *APIKey, budgets []*Budget) error {
 	query := `
-		INSERT INTO api_keys (id, user_id, lookup_hash, verification_hash, name, rate_limit_per_minute, rate_limit_per_day, expires_at, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO api_keys (id, user_id, lookup_hash, verification_hash, name, rate_limit_per_minute, rate_limit_per_day, expires_at, is_active, cache_ttl_seconds, token_limit_per_minute, token_limit_per_day, hedge_percentile, pii_policy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	piiPolicyJSON, err := marshalPIIPolicy(key.PIIPolicy)
+	if err != nil {
+		return err
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query,
 		key.ID,
 		key.UserID,
 		key.LookupHash,
@@ -38,20 +51,50 @@ func (r *APIKeyRepository) Create(ctx context.Context, key *APIKey) error {
 		key.RateLimitPerDay,
 		key.ExpiresAt,
 		key.IsActive,
+		key.CacheTTLSeconds,
+		key.TokenLimitPerMinute,
+		key.TokenLimitPerDay,
+		key.HedgePercentile,
+		piiPolicyJSON,
 	)
+	if err != nil {
+		return err
+	}
+	if err := insertBudgets(ctx, tx, key.UserID, &key.ID, budgets); err != nil {
+		return err
+	}
 
-	return err
+	return tx.Commit(ctx)
 }
 
-func (r *APIKeyRepository) FindByLookupHash(ctx context.Context, lookupHash string) (*APIKey, error) {
-	query := `
-		SELECT id, user_id, lookup_hash, verification_hash, name, rate_limit_per_minute, rate_limit_per_day, created_at, expires_at, is_active
-		FROM api_keys
-		WHERE lookup_hash = $1 AND is_active = true
-	`
+func marshalPIIPolicy(policy *PIIPolicy) ([]byte, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pii policy: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalPIIPolicy(data []byte) (*PIIPolicy, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var policy PIIPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pii policy: %w", err)
+	}
+	return &policy, nil
+}
+
+const apiKeyColumns = `id, user_id, lookup_hash, verification_hash, name, rate_limit_per_minute, rate_limit_per_day, created_at, expires_at, is_active, cache_ttl_seconds, token_limit_per_minute, token_limit_per_day, hedge_percentile, pii_policy`
 
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
 	var key APIKey
-	err := r.pool.QueryRow(ctx, query, lookupHash).Scan(
+	var piiPolicyJSON []byte
+	err := row.Scan(
 		&key.ID,
 		&key.UserID,
 		&key.LookupHash,
@@ -62,8 +105,29 @@ func (r *APIKeyRepository) FindByLookupHash(ctx context.Context, lookupHash stri
 		&key.CreatedAt,
 		&key.ExpiresAt,
 		&key.IsActive,
+		&key.CacheTTLSeconds,
+		&key.TokenLimitPerMinute,
+		&key.TokenLimitPerDay,
+		&key.HedgePercentile,
+		&piiPolicyJSON,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if key.PIIPolicy, err = unmarshalPIIPolicy(piiPolicyJSON); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) FindByLookupHash(ctx context.Context, lookupHash string) (*APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE lookup_hash = $1 AND is_active = true
+	`
 
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, query, lookupHash))
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -75,12 +139,12 @@ func (r *APIKeyRepository) FindByLookupHash(ctx context.Context, lookupHash stri
 		return nil, nil
 	}
 
-	return &key, nil
+	return key, nil
 }
 
 func (r *APIKeyRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*APIKey, error) {
 	query := `
-		SELECT id, user_id, lookup_hash, verification_hash, name, rate_limit_per_minute, rate_limit_per_day, created_at, expires_at, is_active
+		SELECT ` + apiKeyColumns + `
 		FROM api_keys
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -94,23 +158,11 @@ func (r *APIKeyRepository) ListByUserID(ctx context.Context, userID uuid.UUID) (
 
 	var keys []*APIKey
 	for rows.Next() {
-		var key APIKey
-		err := rows.Scan(
-			&key.ID,
-			&key.UserID,
-			&key.LookupHash,
-			&key.VerificationHash,
-			&key.Name,
-			&key.RateLimitPerMinute,
-			&key.RateLimitPerDay,
-			&key.CreatedAt,
-			&key.ExpiresAt,
-			&key.IsActive,
-		)
+		key, err := scanAPIKey(rows)
 		if err != nil {
 			return nil, err
 		}
-		keys = append(keys, &key)
+		keys = append(keys, key)
 	}
 
 	return keys, rows.Err()
@@ -119,22 +171,58 @@ func (r *APIKeyRepository) ListByUserID(ctx context.Context, userID uuid.UUID) (
 func (r *APIKeyRepository) Update(ctx context.Context, key *APIKey) error {
 	query := `
 		UPDATE api_keys
-		SET name = $1, rate_limit_per_minute = $2, rate_limit_per_day = $3, expires_at = $4, is_active = $5
-		WHERE id = $6
+		SET name = $1, rate_limit_per_minute = $2, rate_limit_per_day = $3, expires_at = $4, is_active = $5, cache_ttl_seconds = $6, token_limit_per_minute = $7, token_limit_per_day = $8, hedge_percentile = $9, pii_policy = $10
+		WHERE id = $11
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	piiPolicyJSON, err := marshalPIIPolicy(key.PIIPolicy)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, query,
 		key.Name,
 		key.RateLimitPerMinute,
 		key.RateLimitPerDay,
 		key.ExpiresAt,
 		key.IsActive,
+		key.CacheTTLSeconds,
+		key.TokenLimitPerMinute,
+		key.TokenLimitPerDay,
+		key.HedgePercentile,
+		piiPolicyJSON,
 		key.ID,
 	)
 
 	return err
 }
 
+func (r *APIKeyRepository) UpdateCacheTTL(ctx context.Context, userID, keyID uuid.UUID, ttlSeconds int) (*APIKey, error) {
+	return r.updateSettings(ctx, userID, keyID, "cache_ttl_seconds = $3", ttlSeconds)
+}
+
+func (r *APIKeyRepository) UpdateHedgePercentile(ctx context.Context, userID, keyID uuid.UUID, percentile int) (*APIKey, error) {
+	return r.updateSettings(ctx, userID, keyID, "hedge_percentile = $3", percentile)
+}
+
+func (r *APIKeyRepository) UpdatePIIPolicy(ctx context.Context, userID, keyID uuid.UUID, policy *PIIPolicy) (*APIKey, error) {
+	piiPolicyJSON, err := marshalPIIPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	return r.updateSettings(ctx, userID, keyID, "pii_policy = $3", piiPolicyJSON)
+}
+
+// updateSettings applies set to a key owned by userID and returns the updated key, or
+// nil if there is none. Only the columns in set are written.
+func (r *APIKeyRepository) updateSettings(ctx context.Context, userID, keyID uuid.UUID, set string, values ...interface{}) (*APIKey, error) {
+	query := `UPDATE api_keys SET ` + set + ` WHERE id = $1 AND user_id = $2 RETURNING ` + apiKeyColumns
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, query, append([]interface{}{keyID, userID}, values...)...))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
 func (r *APIKeyRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `
 		UPDATE api_keys
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	BudgetPeriodDaily    = "daily"
+	BudgetPeriodMonthly  = "monthly"
+	BudgetPeriodLifetime = "lifetime"
+)
+
+// Budget caps the USD spend of one API key, or of all the user's requests when
+// APIKeyID is nil, over a period.
+type Budget struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	APIKeyID     *uuid.UUID `json:"api_key_id,omitempty" db:"api_key_id"`
+	Period       string     `json:"period" db:"period"`
+	SoftLimitUSD *float64   `json:"soft_limit_usd,omitempty" db:"soft_limit_usd"`
+	HardLimitUSD *float64   `json:"limit_usd,omitempty" db:"hard_limit_usd"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type BudgetRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewBudgetRepository(pool *pgxpool.Pool) *BudgetRepository {
+	return &BudgetRepository{
+		pool: pool,
+	}
+}
+
+const budgetColumns = `id, user_id, api_key_id, period, soft_limit_usd, hard_limit_usd, created_at, updated_at`
+
+// ListByUser returns the user-wide budgets and the budgets of every key of the user.
+func (r *BudgetRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*Budget, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+budgetColumns+` FROM budgets WHERE user_id = $1 ORDER BY api_key_id NULLS FIRST, period`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []*Budget
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// ReplaceBudgets replaces the budgets of an API key, or the user-wide budgets if
+// apiKeyID is nil. An empty list removes them.
+func (r *BudgetRepository) ReplaceBudgets(ctx context.Context, userID uuid.UUID, apiKeyID *uuid.UUID, budgets []*Budget) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if apiKeyID == nil {
+		_, err = tx.Exec(ctx, "DELETE FROM budgets WHERE user_id = $1 AND api_key_id IS NULL", userID)
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM budgets WHERE user_id = $1 AND api_key_id = $2", userID, apiKeyID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete existing budgets: %w", err)
+	}
+	if err := insertBudgets(ctx, tx, userID, apiKeyID, budgets); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func insertBudgets(ctx context.Context, tx pgx.Tx, userID uuid.UUID, apiKeyID *uuid.UUID, budgets []*Budget) error {
+	for _, b := range budgets {
+		if b.ID == uuid.Nil {
+			b.ID = uuid.New()
+		}
+		b.UserID = userID
+		b.APIKeyID = apiKeyID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO budgets (id, user_id, api_key_id, period, soft_limit_usd, hard_limit_usd)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING created_at, updated_at
+		`, b.ID, b.UserID, b.APIKeyID, b.Period, b.SoftLimitUSD, b.HardLimitUSD).Scan(&b.CreatedAt, &b.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert budget: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanBudget(row pgx.Row) (*Budget, error) {
+	var b Budget
+	err := row.Scan(
+		&b.ID,
+		&b.UserID,
+		&b.APIKeyID,
+		&b.Period,
+		&b.SoftLimitUSD,
+		&b.HardLimitUSD,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
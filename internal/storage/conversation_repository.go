@@ -63,7 +63,7 @@ func (r *ConversationRepository) GetConversation(ctx context.Context, conversati
 	conv := &Conversation{}
 
 	query := `
-		SELECT id, user_id, title, model, created_at, updated_at
+		SELECT id, user_id, title, model, summary, summary_message_count, created_at, updated_at
 		FROM conversations
 		WHERE id = $1 AND user_id = $2
 	`
@@ -73,6 +73,8 @@ func (r *ConversationRepository) GetConversation(ctx context.Context, conversati
 		&conv.UserID,
 		&conv.Title,
 		&conv.Model,
+		&conv.Summary,
+		&conv.SummaryMessageCount,
 		&conv.CreatedAt,
 		&conv.UpdatedAt,
 	)
@@ -86,7 +88,7 @@ func (r *ConversationRepository) GetConversation(ctx context.Context, conversati
 
 func (r *ConversationRepository) ListConversations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Conversation, error) {
 	query := `
-		SELECT id, user_id, title, model, created_at, updated_at
+		SELECT id, user_id, title, model, summary, summary_message_count, created_at, updated_at
 		FROM conversations
 		WHERE user_id = $1
 		ORDER BY updated_at DESC
@@ -107,6 +109,8 @@ func (r *ConversationRepository) ListConversations(ctx context.Context, userID u
 			&conv.UserID,
 			&conv.Title,
 			&conv.Model,
+			&conv.Summary,
+			&conv.SummaryMessageCount,
 			&conv.CreatedAt,
 			&conv.UpdatedAt,
 		)
@@ -140,6 +144,27 @@ func (r *ConversationRepository) UpdateConversation(ctx context.Context, convers
 	return nil
 }
 
+// UpdateConversationSummary stores the summary of the first messageCount messages.
+func (r *ConversationRepository) UpdateConversationSummary(ctx context.Context, conversationID uuid.UUID, userID uuid.UUID, summary string, messageCount int) error {
+	query := `
+		UPDATE conversations
+		SET summary = $3,
+		    summary_message_count = $4
+		WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.pool.Exec(ctx, query, conversationID, userID, summary, messageCount)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation summary: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("conversation not found or access denied")
+	}
+
+	return nil
+}
+
 func (r *ConversationRepository) DeleteConversation(ctx context.Context, conversationID uuid.UUID, userID uuid.UUID) error {
 	query := `
 		DELETE FROM conversations
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ExperimentVariant receives Weight/sum(weights) of the experiment's traffic, with
+// the request's model replaced by Model (a concrete model or an alias).
+type ExperimentVariant struct {
+	Name   string `json:"name"`
+	Model  string `json:"model"`
+	Weight int    `json:"weight"`
+}
+
+type Experiment struct {
+	ID          uuid.UUID           `json:"id" db:"id"`
+	Name        string              `json:"name" db:"name"`
+	Model       string              `json:"model" db:"model"`
+	Variants    []ExperimentVariant `json:"variants" db:"variants"`
+	StickyBy    string              `json:"sticky_by" db:"sticky_by"`
+	Enabled     bool                `json:"enabled" db:"enabled"`
+	Description *string             `json:"description,omitempty" db:"description"`
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" db:"updated_at"`
+	CreatedBy   *uuid.UUID          `json:"created_by,omitempty" db:"created_by"`
+}
+
+var ErrExperimentNotFound = errors.New("experiment not found")
+
+type ExperimentRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewExperimentRepository(pool *pgxpool.Pool) *ExperimentRepository {
+	return &ExperimentRepository{
+		pool: pool,
+	}
+}
+
+const experimentColumns = `id, name, model, variants, sticky_by, enabled, description, created_at, updated_at, created_by`
+
+func (r *ExperimentRepository) Create(ctx context.Context, exp *Experiment) error {
+	variantsJSON, err := json.Marshal(exp.Variants)
+	if err != nil {
+		return fmt.Errorf("failed to marshal variants: %w", err)
+	}
+	if exp.ID == uuid.Nil {
+		exp.ID = uuid.New()
+	}
+	exp.Model = strings.ToLower(exp.Model)
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO experiments (id, name, model, variants, sticky_by, enabled, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`, exp.ID, exp.Name, exp.Model, variantsJSON, exp.StickyBy, exp.Enabled, exp.Description, exp.CreatedBy).Scan(&exp.CreatedAt, &exp.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create experiment: %w", err)
+	}
+	return nil
+}
+
+func (r *ExperimentRepository) Update(ctx context.Context, exp *Experiment) error {
+	variantsJSON, err := json.Marshal(exp.Variants)
+	if err != nil {
+		return fmt.Errorf("failed to marshal variants: %w", err)
+	}
+	exp.Model = strings.ToLower(exp.Model)
+	err = r.pool.QueryRow(ctx, `
+		UPDATE experiments
+		SET name = $2, model = $3, variants = $4, sticky_by = $5, enabled = $6, description = $7, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at, created_by
+	`, exp.ID, exp.Name, exp.Model, variantsJSON, exp.StickyBy, exp.Enabled, exp.Description).Scan(&exp.CreatedAt, &exp.UpdatedAt, &exp.CreatedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrExperimentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update experiment: %w", err)
+	}
+	return nil
+}
+
+func (r *ExperimentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM experiments WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete experiment: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrExperimentNotFound
+	}
+	return nil
+}
+
+func (r *ExperimentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Experiment, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+experimentColumns+` FROM experiments WHERE id = $1`, id)
+	exp, err := scanExperiment(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrExperimentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
+	}
+	return exp, nil
+}
+
+func (r *ExperimentRepository) List(ctx context.Context) ([]*Experiment, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+experimentColumns+` FROM experiments ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiments: %w", err)
+	}
+	defer rows.Close()
+
+	var experiments []*Experiment
+	for rows.Next() {
+		exp, err := scanExperiment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan experiment: %w", err)
+		}
+		experiments = append(experiments, exp)
+	}
+	return experiments, rows.Err()
+}
+
+// FindActiveForModel returns the running experiment for model, or nil, nil if there is none.
+func (r *ExperimentRepository) FindActiveForModel(ctx context.Context, model string) (*Experiment, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+experimentColumns+`
+		FROM experiments
+		WHERE enabled = true AND model = $1
+		LIMIT 1
+	`, strings.ToLower(model))
+	exp, err := scanExperiment(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find experiment: %w", err)
+	}
+	return exp, nil
+}
+
+func scanExperiment(row pgx.Row) (*Experiment, error) {
+	var exp Experiment
+	var variantsJSON []byte
+	err := row.Scan(
+		&exp.ID,
+		&exp.Name,
+		&exp.Model,
+		&variantsJSON,
+		&exp.StickyBy,
+		&exp.Enabled,
+		&exp.Description,
+		&exp.CreatedAt,
+		&exp.UpdatedAt,
+		&exp.CreatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(variantsJSON) > 0 {
+		if err := json.Unmarshal(variantsJSON, &exp.Variants); err != nil {
+			return nil, fmt.Errorf("failed to parse variants JSON: %w", err)
+		}
+	}
+	return &exp, nil
+}
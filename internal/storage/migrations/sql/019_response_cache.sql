@@ -0,0 +1,3 @@
+-- Per-API-key response cache TTL (0 = caching disabled) and cache-hit flag on tracked requests
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS cache_ttl_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS cached BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,23 @@
+-- Migration: 020_model_aliases.sql
+-- Description: Adds virtual model names that resolve to an ordered chain of provider/model targets
+
+CREATE TABLE IF NOT EXISTS model_aliases (
+    id SERIAL PRIMARY KEY,
+    alias VARCHAR(100) NOT NULL, -- name clients send as "model", stored lowercase
+    targets JSONB NOT NULL, -- ordered [{"provider": "openai", "model": "gpt-4o-mini", "temperature": 0.2, "max_tokens": 512}]
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE, -- NULL = global/admin alias
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- One alias name per user, and one global alias per name
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_aliases_user_alias ON model_aliases(user_id, alias) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_aliases_global_alias ON model_aliases(alias) WHERE user_id IS NULL;
+
+COMMENT ON TABLE model_aliases IS 'Virtual model names resolved by the router before provider selection';
+COMMENT ON COLUMN model_aliases.targets IS 'Ordered fallback chain of provider/model targets with optional parameter overrides';
+COMMENT ON COLUMN model_aliases.user_id IS 'User ID for user-specific aliases. NULL means global/admin alias.';
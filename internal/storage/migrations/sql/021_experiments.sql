@@ -0,0 +1,27 @@
+-- Migration: 021_experiments.sql
+-- Description: Adds A/B experiments that split traffic for a model across weighted variants
+
+CREATE TABLE IF NOT EXISTS experiments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    model VARCHAR(255) NOT NULL, -- requested model or alias whose traffic is split, stored lowercase
+    variants JSONB NOT NULL, -- [{"name": "control", "model": "gpt-4o", "weight": 90}, {"name": "cheap", "model": "gpt-4o-mini", "weight": 10}]
+    sticky_by VARCHAR(20) NOT NULL DEFAULT 'user', -- 'user' or 'api_key'
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- At most one running experiment per model
+CREATE UNIQUE INDEX IF NOT EXISTS idx_experiments_enabled_model ON experiments(model) WHERE enabled = true;
+
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS experiment_id UUID REFERENCES experiments(id) ON DELETE SET NULL;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS experiment_variant VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_requests_experiment ON requests(experiment_id, experiment_variant) WHERE experiment_id IS NOT NULL;
+
+COMMENT ON TABLE experiments IS 'A/B experiments: requests for model are split across variants by weight';
+COMMENT ON COLUMN experiments.sticky_by IS 'Callers keep the same variant: per user or per API key';
+COMMENT ON COLUMN requests.experiment_variant IS 'Name of the experiment variant that served this request';
@@ -0,0 +1,22 @@
+-- Migration: 023_budgets.sql
+-- Description: Adds USD spend budgets for users and API keys
+
+CREATE TABLE IF NOT EXISTS budgets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE CASCADE, -- NULL = all of the user's requests
+    period VARCHAR(20) NOT NULL CHECK (period IN ('daily', 'monthly', 'lifetime')),
+    soft_limit_usd DECIMAL(12, 4), -- warn once spend reaches this amount
+    hard_limit_usd DECIMAL(12, 4), -- reject requests once spend reaches this amount
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (soft_limit_usd IS NOT NULL OR hard_limit_usd IS NOT NULL),
+    CHECK (soft_limit_usd IS NULL OR hard_limit_usd IS NULL OR soft_limit_usd <= hard_limit_usd)
+);
+
+-- One budget per period for each key and for the user as a whole
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_scope_period
+    ON budgets(user_id, COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'::uuid), period);
+
+COMMENT ON TABLE budgets IS 'USD spend budgets enforced on /v1 requests; spend is tracked in Redis';
+COMMENT ON COLUMN budgets.period IS 'daily and monthly budgets reset at UTC midnight and on the 1st of the month';
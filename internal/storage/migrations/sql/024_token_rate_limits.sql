@@ -0,0 +1,8 @@
+-- Migration: 024_token_rate_limits.sql
+-- Description: Adds per-API-key token limits (tokens per minute and per day)
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS token_limit_per_minute INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS token_limit_per_day INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN api_keys.token_limit_per_minute IS 'Prompt plus completion tokens allowed per sliding minute; 0 = unlimited';
+COMMENT ON COLUMN api_keys.token_limit_per_day IS 'Prompt plus completion tokens allowed per sliding 24 hours; 0 = unlimited';
@@ -0,0 +1,8 @@
+-- Migration: 026_request_comparisons.sql
+-- Description: Links the requests rows of a /v1/chat/compare fan-out
+
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS comparison_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_requests_comparison_id ON requests(comparison_id) WHERE comparison_id IS NOT NULL;
+
+COMMENT ON COLUMN requests.comparison_id IS 'Shared by the chat_compare requests of one comparison, one row per compared model';
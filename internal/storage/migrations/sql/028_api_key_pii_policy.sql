@@ -0,0 +1,6 @@
+-- Migration: 028_api_key_pii_policy.sql
+-- Description: Per-key PII redaction policy applied before prompts are sent to a provider
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS pii_policy JSONB;
+
+COMMENT ON COLUMN api_keys.pii_policy IS 'PII redaction policy ({"types": [...], "skip_providers": [...]}); NULL disables redaction';
@@ -0,0 +1,6 @@
+-- Migration: 030_provider_key_metadata.sql
+-- Description: Non-secret provider settings stored with BYOK keys, such as the Azure OpenAI endpoint and deployments
+
+ALTER TABLE user_provider_keys ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+COMMENT ON COLUMN user_provider_keys.metadata IS 'Provider settings for the key; for azure: {"endpoint", "api_version", "deployments"}';
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ModelAliasTarget is one step of an alias fallback chain. Nil overrides keep the caller's value.
+type ModelAliasTarget struct {
+	Provider    string   `json:"provider"`
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+}
+
+type ModelAlias struct {
+	ID          int                `json:"id" db:"id"`
+	Alias       string             `json:"alias" db:"alias"`
+	Targets     []ModelAliasTarget `json:"targets" db:"targets"`
+	Description *string            `json:"description,omitempty" db:"description"`
+	Enabled     bool               `json:"enabled" db:"enabled"`
+	UserID      *uuid.UUID         `json:"user_id,omitempty" db:"user_id"` // NULL = global/admin alias
+	CreatedAt   time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" db:"updated_at"`
+	CreatedBy   *uuid.UUID         `json:"created_by,omitempty" db:"created_by"`
+	UpdatedBy   *uuid.UUID         `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+type ModelAliasRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewModelAliasRepository(pool *pgxpool.Pool) *ModelAliasRepository {
+	return &ModelAliasRepository{
+		pool: pool,
+	}
+}
+
+const modelAliasColumns = `id, alias, targets, description, enabled, user_id, created_at, updated_at, created_by, updated_by`
+
+// FindActiveAlias returns the enabled alias with the given name, preferring the user's own
+// alias over a global one. It returns nil, nil when no alias matches.
+func (r *ModelAliasRepository) FindActiveAlias(ctx context.Context, userID *uuid.UUID, alias string) (*ModelAlias, error) {
+	query := `
+		SELECT ` + modelAliasColumns + `
+		FROM model_aliases
+		WHERE enabled = true AND alias = $1 AND (user_id = $2 OR user_id IS NULL)
+		ORDER BY user_id NULLS LAST
+		LIMIT 1
+	`
+	row := r.pool.QueryRow(ctx, query, strings.ToLower(alias), userID)
+	a, err := scanModelAlias(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find model alias: %w", err)
+	}
+	return a, nil
+}
+
+// If userID is nil, returns global/admin aliases only.
+// If userID is provided, returns the user's aliases followed by global aliases.
+func (r *ModelAliasRepository) GetAliasesForUser(ctx context.Context, userID *uuid.UUID) ([]*ModelAlias, error) {
+	var rows pgx.Rows
+	var err error
+	if userID == nil {
+		rows, err = r.pool.Query(ctx, `
+			SELECT `+modelAliasColumns+`
+			FROM model_aliases
+			WHERE user_id IS NULL
+			ORDER BY alias ASC
+		`)
+	} else {
+		rows, err = r.pool.Query(ctx, `
+			SELECT `+modelAliasColumns+`
+			FROM model_aliases
+			WHERE user_id = $1 OR user_id IS NULL
+			ORDER BY user_id NULLS LAST, alias ASC
+		`, userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model aliases: %w", err)
+	}
+	defer rows.Close()
+
+	var aliases []*ModelAlias
+	for rows.Next() {
+		a, err := scanModelAlias(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan model alias: %w", err)
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// If userID is nil, saves as global/admin aliases.
+// If userID is provided, replaces the user's existing aliases.
+func (r *ModelAliasRepository) SaveAliasesForUser(ctx context.Context, aliases []*ModelAlias, userID *uuid.UUID, updatedBy *uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if userID == nil {
+		_, err = tx.Exec(ctx, "DELETE FROM model_aliases WHERE user_id IS NULL")
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM model_aliases WHERE user_id = $1", userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete existing model aliases: %w", err)
+	}
+
+	for _, a := range aliases {
+		targetsJSON, err := json.Marshal(a.Targets)
+		if err != nil {
+			return fmt.Errorf("failed to marshal alias targets: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO model_aliases (alias, targets, description, enabled, user_id, created_by, updated_by, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6, NOW())
+		`, strings.ToLower(a.Alias), targetsJSON, a.Description, a.Enabled, userID, updatedBy)
+		if err != nil {
+			return fmt.Errorf("failed to insert model alias: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func scanModelAlias(row pgx.Row) (*ModelAlias, error) {
+	var a ModelAlias
+	var targetsJSON []byte
+	err := row.Scan(
+		&a.ID,
+		&a.Alias,
+		&targetsJSON,
+		&a.Description,
+		&a.Enabled,
+		&a.UserID,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		&a.CreatedBy,
+		&a.UpdatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(targetsJSON) > 0 {
+		if err := json.Unmarshal(targetsJSON, &a.Targets); err != nil {
+			return nil, fmt.Errorf("failed to parse targets JSON: %w", err)
+		}
+	}
+	return &a, nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ModelPrice is a price in USD per 1M tokens that overrides the built-in pricing catalog.
+type ModelPrice struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Provider        string     `json:"provider" db:"provider"`
+	Model           string     `json:"model" db:"model"`
+	InputCost       float64    `json:"input" db:"input_cost"`
+	OutputCost      float64    `json:"output" db:"output_cost"`
+	CachedInputCost float64    `json:"cached_input" db:"cached_input_cost"`
+	BatchInputCost  float64    `json:"batch_input" db:"batch_input_cost"`
+	BatchOutputCost float64    `json:"batch_output" db:"batch_output_cost"`
+	ImageCost       float64    `json:"image" db:"image_cost"`
+	EffectiveFrom   *time.Time `json:"effective_from,omitempty" db:"effective_from"`
+	EffectiveUntil  *time.Time `json:"effective_until,omitempty" db:"effective_until"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+}
+
+var ErrModelPriceNotFound = errors.New("model price not found")
+
+type ModelPriceRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewModelPriceRepository(pool *pgxpool.Pool) *ModelPriceRepository {
+	return &ModelPriceRepository{
+		pool: pool,
+	}
+}
+
+const modelPriceColumns = `id, provider, model, input_cost, output_cost, cached_input_cost, batch_input_cost, batch_output_cost, image_cost, effective_from, effective_until, created_at, updated_at, created_by`
+
+func (r *ModelPriceRepository) Create(ctx context.Context, price *ModelPrice) error {
+	if price.ID == uuid.Nil {
+		price.ID = uuid.New()
+	}
+	price.Provider = strings.ToLower(price.Provider)
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO model_prices (id, provider, model, input_cost, output_cost, cached_input_cost, batch_input_cost, batch_output_cost, image_cost, effective_from, effective_until, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING created_at, updated_at
+	`, price.ID, price.Provider, price.Model, price.InputCost, price.OutputCost, price.CachedInputCost, price.BatchInputCost, price.BatchOutputCost, price.ImageCost, price.EffectiveFrom, price.EffectiveUntil, price.CreatedBy).Scan(&price.CreatedAt, &price.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create model price: %w", err)
+	}
+	return nil
+}
+
+func (r *ModelPriceRepository) Update(ctx context.Context, price *ModelPrice) error {
+	price.Provider = strings.ToLower(price.Provider)
+	err := r.pool.QueryRow(ctx, `
+		UPDATE model_prices
+		SET provider = $2, model = $3, input_cost = $4, output_cost = $5, cached_input_cost = $6, batch_input_cost = $7,
+			batch_output_cost = $8, image_cost = $9, effective_from = $10, effective_until = $11, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at, created_by
+	`, price.ID, price.Provider, price.Model, price.InputCost, price.OutputCost, price.CachedInputCost, price.BatchInputCost, price.BatchOutputCost, price.ImageCost, price.EffectiveFrom, price.EffectiveUntil).Scan(&price.CreatedAt, &price.UpdatedAt, &price.CreatedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrModelPriceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update model price: %w", err)
+	}
+	return nil
+}
+
+func (r *ModelPriceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM model_prices WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete model price: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrModelPriceNotFound
+	}
+	return nil
+}
+
+func (r *ModelPriceRepository) GetByID(ctx context.Context, id uuid.UUID) (*ModelPrice, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+modelPriceColumns+` FROM model_prices WHERE id = $1`, id)
+	price, err := scanModelPrice(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrModelPriceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model price: %w", err)
+	}
+	return price, nil
+}
+
+func (r *ModelPriceRepository) List(ctx context.Context) ([]*ModelPrice, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+modelPriceColumns+` FROM model_prices ORDER BY provider, model, effective_from NULLS FIRST`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model prices: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []*ModelPrice
+	for rows.Next() {
+		price, err := scanModelPrice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan model price: %w", err)
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
+
+func scanModelPrice(row pgx.Row) (*ModelPrice, error) {
+	var price ModelPrice
+	err := row.Scan(
+		&price.ID,
+		&price.Provider,
+		&price.Model,
+		&price.InputCost,
+		&price.OutputCost,
+		&price.CachedInputCost,
+		&price.BatchInputCost,
+		&price.BatchOutputCost,
+		&price.ImageCost,
+		&price.EffectiveFrom,
+		&price.EffectiveUntil,
+		&price.CreatedAt,
+		&price.UpdatedAt,
+		&price.CreatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
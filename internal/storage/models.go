@@ -7,16 +7,29 @@ import (
 )
 
 type APIKey struct {
-	ID                 uuid.UUID  `db:"id"`
-	UserID             uuid.UUID  `db:"user_id"`
-	LookupHash         string     `db:"lookup_hash"`       // SHA256 hash for fast lookup
-	VerificationHash   string     `db:"verification_hash"` // bcrypt hash for verification
-	Name               string     `db:"name"`
-	RateLimitPerMinute int        `db:"rate_limit_per_minute"`
-	RateLimitPerDay    int        `db:"rate_limit_per_day"`
-	CreatedAt          time.Time  `db:"created_at"`
-	ExpiresAt          *time.Time `db:"expires_at"`
-	IsActive           bool       `db:"is_active"`
+	ID                  uuid.UUID  `db:"id"`
+	UserID              uuid.UUID  `db:"user_id"`
+	LookupHash          string     `db:"lookup_hash"`       // SHA256 hash for fast lookup
+	VerificationHash    string     `db:"verification_hash"` // bcrypt hash for verification
+	Name                string     `db:"name"`
+	RateLimitPerMinute  int        `db:"rate_limit_per_minute"`
+	RateLimitPerDay     int        `db:"rate_limit_per_day"`
+	CreatedAt           time.Time  `db:"created_at"`
+	ExpiresAt           *time.Time `db:"expires_at"`
+	IsActive            bool       `db:"is_active"`
+	CacheTTLSeconds     int        `db:"cache_ttl_seconds"`      // 0 disables response caching
+	TokenLimitPerMinute int        `db:"token_limit_per_minute"` // 0 = no token limit
+	TokenLimitPerDay    int        `db:"token_limit_per_day"`
+	HedgePercentile     int        `db:"hedge_percentile"` // 0 disables request hedging
+	PIIPolicy           *PIIPolicy `db:"pii_policy"`       // nil = prompts are sent unredacted
+}
+
+// PIIPolicy redacts personal data from prompts before they reach a provider. Types
+// limits detection (all types when empty); SkipProviders are sent the original prompt,
+// and nil means the on-prem providers (local, vllm).
+type PIIPolicy struct {
+	Types         []string `json:"types,omitempty"`
+	SkipProviders []string `json:"skip_providers"`
 }
 
 type User struct {
@@ -54,6 +67,7 @@ type UserProviderKey struct {
 	UserID          uuid.UUID  `db:"user_id"`
 	Provider        string     `db:"provider"` // 'openai', 'anthropic', 'google'
 	APIKeyEncrypted string     `db:"api_key_encrypted"` // Encrypted provider API key
+	Metadata        []byte     `db:"metadata"` // Provider settings as JSON, e.g. the Azure endpoint (nullable)
 	IsActive        bool       `db:"is_active"`
 	CreatedAt       time.Time  `db:"created_at"`
 	UpdatedAt       time.Time  `db:"updated_at"`
@@ -75,12 +89,16 @@ type ErrorLog struct {
 }
 
 type Conversation struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	UserID    uuid.UUID `json:"user_id" db:"user_id"`
-	Title     *string   `json:"title" db:"title"`
-	Model     *string   `json:"model" db:"model"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	Title  *string   `json:"title" db:"title"`
+	Model  *string   `json:"model" db:"model"`
+	// Summary of the oldest SummaryMessageCount messages, written when the conversation
+	// outgrew its model's context window.
+	Summary             *string   `json:"summary,omitempty" db:"summary"`
+	SummaryMessageCount int       `json:"summary_message_count,omitempty" db:"summary_message_count"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type Message struct {
@@ -20,11 +20,12 @@ func NewProviderKeyRepository(pool *pgxpool.Pool) *ProviderKeyRepository {
 
 func (r *ProviderKeyRepository) Create(ctx context.Context, key *UserProviderKey) error {
 	query := `
-		INSERT INTO user_provider_keys (id, user_id, provider, api_key_encrypted, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO user_provider_keys (id, user_id, provider, api_key_encrypted, is_active, created_at, updated_at, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		ON CONFLICT (user_id, provider) 
 		DO UPDATE SET 
 			api_key_encrypted = EXCLUDED.api_key_encrypted,
+			metadata = EXCLUDED.metadata,
 			is_active = EXCLUDED.is_active,
 			updated_at = EXCLUDED.updated_at
 	`
@@ -37,6 +38,7 @@ func (r *ProviderKeyRepository) Create(ctx context.Context, key *UserProviderKey
 		key.IsActive,
 		key.CreatedAt,
 		key.UpdatedAt,
+		key.Metadata,
 	)
 
 	return err
@@ -44,7 +46,7 @@ func (r *ProviderKeyRepository) Create(ctx context.Context, key *UserProviderKey
 
 func (r *ProviderKeyRepository) FindByUserAndProvider(ctx context.Context, userID uuid.UUID, provider string) (*UserProviderKey, error) {
 	query := `
-		SELECT id, user_id, provider, api_key_encrypted, is_active, created_at, updated_at
+		SELECT id, user_id, provider, api_key_encrypted, is_active, created_at, updated_at, metadata
 		FROM user_provider_keys
 		WHERE user_id = $1 AND provider = $2 AND is_active = true
 	`
@@ -58,6 +60,7 @@ func (r *ProviderKeyRepository) FindByUserAndProvider(ctx context.Context, userI
 		&key.IsActive,
 		&key.CreatedAt,
 		&key.UpdatedAt,
+		&key.Metadata,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -72,7 +75,7 @@ func (r *ProviderKeyRepository) FindByUserAndProvider(ctx context.Context, userI
 
 func (r *ProviderKeyRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*UserProviderKey, error) {
 	query := `
-		SELECT id, user_id, provider, api_key_encrypted, is_active, created_at, updated_at
+		SELECT id, user_id, provider, api_key_encrypted, is_active, created_at, updated_at, metadata
 		FROM user_provider_keys
 		WHERE user_id = $1 AND is_active = true
 		ORDER BY provider
@@ -95,6 +98,7 @@ func (r *ProviderKeyRepository) ListByUserID(ctx context.Context, userID uuid.UU
 			&key.IsActive,
 			&key.CreatedAt,
 			&key.UpdatedAt,
+			&key.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -107,7 +111,7 @@ func (r *ProviderKeyRepository) ListByUserID(ctx context.Context, userID uuid.UU
 func (r *ProviderKeyRepository) Update(ctx context.Context, key *UserProviderKey) error {
 	query := `
 		UPDATE user_provider_keys
-		SET api_key_encrypted = $1, is_active = $2, updated_at = $3
+		SET api_key_encrypted = $1, is_active = $2, updated_at = $3, metadata = $6
 		WHERE id = $4 AND user_id = $5
 	`
 
@@ -117,6 +121,7 @@ func (r *ProviderKeyRepository) Update(ctx context.Context, key *UserProviderKey
 		key.UpdatedAt,
 		key.ID,
 		key.UserID,
+		key.Metadata,
 	)
 
 	return err
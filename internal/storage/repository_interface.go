@@ -7,10 +7,13 @@ import (
 )
 
 type APIKeyRepositoryInterface interface {
-	Create(ctx context.Context, key *APIKey) error
+	Create(ctx context.Context, key *APIKey, budgets []*Budget) error
 	FindByLookupHash(ctx context.Context, lookupHash string) (*APIKey, error)
 	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*APIKey, error)
 	Update(ctx context.Context, key *APIKey) error
+	UpdateCacheTTL(ctx context.Context, userID, keyID uuid.UUID, ttlSeconds int) (*APIKey, error)
+	UpdateHedgePercentile(ctx context.Context, userID, keyID uuid.UUID, percentile int) (*APIKey, error)
+	UpdatePIIPolicy(ctx context.Context, userID, keyID uuid.UUID, policy *PIIPolicy) (*APIKey, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	DeletePermanently(ctx context.Context, id uuid.UUID) error
 }
@@ -23,6 +23,15 @@ type Request struct {
 	LatencyMs    int
 	StatusCode   int
 	ErrorMessage *string
+	Cached       bool
+	// Set when the request was part of an A/B experiment
+	ExperimentID      *uuid.UUID
+	ExperimentVariant *string
+	// Shared by the rows of a hedged request: the winning call and the losing
+	// "chat_hedge" calls
+	HedgeID *uuid.UUID
+	// Shared by the rows of one /v1/chat/compare request
+	ComparisonID *uuid.UUID
 	CreatedAt    time.Time
 }
 
@@ -41,9 +50,9 @@ func (r *RequestRepository) Create(ctx context.Context, req *Request) error {
 		INSERT INTO requests (
 			id, api_key_id, user_id, provider, model, request_type,
 			input_tokens, output_tokens, total_tokens, cost, latency_ms,
-			status_code, error_message, created_at
+			status_code, error_message, cached, experiment_id, experiment_variant, hedge_id, comparison_id, created_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
 
 	_, err := r.pool.Exec(ctx, query,
@@ -60,6 +69,11 @@ func (r *RequestRepository) Create(ctx context.Context, req *Request) error {
 		req.LatencyMs,
 		req.StatusCode,
 		req.ErrorMessage,
+		req.Cached,
+		req.ExperimentID,
+		req.ExperimentVariant,
+		req.HedgeID,
+		req.ComparisonID,
 		req.CreatedAt,
 	)
 
@@ -73,7 +87,9 @@ type UsageStats struct {
 	AverageLatencyMs   float64
 	RequestsByProvider map[string]int64
 	RequestsByModel    map[string]int64
+	RequestsByType     map[string]int64
 	CostByProvider     map[string]float64
+	CostByType         map[string]float64
 }
 
 func (r *RequestRepository) GetUsageStats(ctx context.Context, userID *uuid.UUID, startTime, endTime time.Time) (*UsageStats, error) {
@@ -170,6 +186,36 @@ func (r *RequestRepository) GetUsageStats(ctx context.Context, userID *uuid.UUID
 		stats.RequestsByModel[model] = count
 	}
 
+	// Request type stats query (chat, chat_stream, embedding, ...)
+	typeQuery := fmt.Sprintf(`
+		SELECT 
+			COALESCE(request_type, 'chat'),
+			COUNT(*) as count,
+			COALESCE(SUM(cost), 0) as total_cost
+		FROM requests
+		%s
+		GROUP BY request_type
+	`, baseWhere)
+
+	rows, err = r.pool.Query(ctx, typeQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats.RequestsByType = make(map[string]int64)
+	stats.CostByType = make(map[string]float64)
+	for rows.Next() {
+		var requestType string
+		var count int64
+		var cost float64
+		if err := rows.Scan(&requestType, &count, &cost); err != nil {
+			continue
+		}
+		stats.RequestsByType[requestType] += count
+		stats.CostByType[requestType] += cost
+	}
+
 	return &stats, nil
 }
 
@@ -177,7 +223,7 @@ func (r *RequestRepository) GetRequests(ctx context.Context, userID *uuid.UUID,
 	query := `
 		SELECT id, api_key_id, user_id, provider, model, request_type,
 		       input_tokens, output_tokens, total_tokens, cost, latency_ms,
-		       status_code, error_message, created_at
+		       status_code, error_message, cached, experiment_id, experiment_variant, hedge_id, comparison_id, created_at
 		FROM requests
 		WHERE 1=1
 	`
@@ -217,6 +263,11 @@ func (r *RequestRepository) GetRequests(ctx context.Context, userID *uuid.UUID,
 			&req.LatencyMs,
 			&req.StatusCode,
 			&req.ErrorMessage,
+			&req.Cached,
+			&req.ExperimentID,
+			&req.ExperimentVariant,
+			&req.HedgeID,
+			&req.ComparisonID,
 			&req.CreatedAt,
 		)
 		if err != nil {
@@ -227,3 +278,73 @@ func (r *RequestRepository) GetRequests(ctx context.Context, userID *uuid.UUID,
 
 	return requests, rows.Err()
 }
+
+type VariantStats struct {
+	Variant          string  `json:"variant"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	ErrorRate        float64 `json:"error_rate"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+	P95LatencyMs     float64 `json:"p95_latency_ms"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	TotalCost        float64 `json:"total_cost"`
+	AverageCost      float64 `json:"average_cost"`
+}
+
+// GetExperimentStats breaks down the requests of one experiment by variant.
+func (r *RequestRepository) GetExperimentStats(ctx context.Context, experimentID uuid.UUID, startTime, endTime time.Time) ([]*VariantStats, error) {
+	query := `
+		SELECT
+			experiment_variant,
+			COUNT(*) as requests,
+			COUNT(*) FILTER (WHERE status_code >= 400) as errors,
+			COALESCE(AVG(latency_ms), 0) as avg_latency,
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY latency_ms), 0) as p95_latency,
+			COALESCE(SUM(input_tokens), 0) as input_tokens,
+			COALESCE(SUM(output_tokens), 0) as output_tokens,
+			COALESCE(SUM(total_tokens), 0) as total_tokens,
+			COALESCE(SUM(cost), 0) as total_cost
+		FROM requests
+		WHERE experiment_id = $1 AND created_at >= $2 AND created_at <= $3
+		GROUP BY experiment_variant
+		ORDER BY experiment_variant
+	`
+
+	rows, err := r.pool.Query(ctx, query, experimentID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*VariantStats
+	for rows.Next() {
+		var s VariantStats
+		var variant *string
+		err := rows.Scan(
+			&variant,
+			&s.Requests,
+			&s.Errors,
+			&s.AverageLatencyMs,
+			&s.P95LatencyMs,
+			&s.InputTokens,
+			&s.OutputTokens,
+			&s.TotalTokens,
+			&s.TotalCost,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if variant != nil {
+			s.Variant = *variant
+		}
+		if s.Requests > 0 {
+			s.ErrorRate = float64(s.Errors) / float64(s.Requests)
+			s.AverageCost = s.TotalCost / float64(s.Requests)
+		}
+		stats = append(stats, &s)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ShadowRequest is a live response stored next to the response of a shadow provider
+// to the same request.
+type ShadowRequest struct {
+	ID                  uuid.UUID  `json:"id"`
+	UserID              *uuid.UUID `json:"user_id,omitempty"`
+	Model               string     `json:"model"`
+	PrimaryProvider     string     `json:"primary_provider"`
+	PrimaryModel        string     `json:"primary_model"`
+	PrimaryContent      string     `json:"primary_content"`
+	PrimaryInputTokens  int        `json:"primary_input_tokens"`
+	PrimaryOutputTokens int        `json:"primary_output_tokens"`
+	PrimaryCost         float64    `json:"primary_cost"`
+	PrimaryLatencyMs    int        `json:"primary_latency_ms"`
+	ShadowProvider      string     `json:"shadow_provider"`
+	ShadowModel         string     `json:"shadow_model"`
+	ShadowContent       string     `json:"shadow_content"`
+	ShadowInputTokens   int        `json:"shadow_input_tokens"`
+	ShadowOutputTokens  int        `json:"shadow_output_tokens"`
+	ShadowCost          float64    `json:"shadow_cost"`
+	ShadowLatencyMs     int        `json:"shadow_latency_ms"`
+	ShadowError         *string    `json:"shadow_error,omitempty"`
+	Similarity          *float64   `json:"similarity,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+type ShadowRequestFilters struct {
+	Model          string
+	ShadowProvider string
+	Limit          int
+	Offset         int
+}
+
+// ShadowSummary aggregates the shadow requests of one model and shadow target.
+// Averages are over successful shadow calls.
+type ShadowSummary struct {
+	Model                  string   `json:"model"`
+	ShadowProvider         string   `json:"shadow_provider"`
+	ShadowModel            string   `json:"shadow_model"`
+	Requests               int64    `json:"requests"`
+	Errors                 int64    `json:"errors"`
+	AvgPrimaryLatencyMs    float64  `json:"avg_primary_latency_ms"`
+	AvgShadowLatencyMs     float64  `json:"avg_shadow_latency_ms"`
+	PrimaryCost            float64  `json:"primary_cost"`
+	ShadowCost             float64  `json:"shadow_cost"`
+	AvgPrimaryLength       float64  `json:"avg_primary_length"`
+	AvgShadowLength        float64  `json:"avg_shadow_length"`
+	AvgPrimaryOutputTokens float64  `json:"avg_primary_output_tokens"`
+	AvgShadowOutputTokens  float64  `json:"avg_shadow_output_tokens"`
+	AvgSimilarity          *float64 `json:"avg_similarity,omitempty"`
+}
+
+type ShadowRequestRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewShadowRequestRepository(pool *pgxpool.Pool) *ShadowRequestRepository {
+	return &ShadowRequestRepository{pool: pool}
+}
+
+const shadowRequestColumns = `id, user_id, model, primary_provider, primary_model, primary_content,
+	primary_input_tokens, primary_output_tokens, primary_cost, primary_latency_ms,
+	shadow_provider, shadow_model, shadow_content, shadow_input_tokens, shadow_output_tokens,
+	shadow_cost, shadow_latency_ms, shadow_error, similarity, created_at`
+
+func (r *ShadowRequestRepository) Create(ctx context.Context, req *ShadowRequest) error {
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO shadow_requests (`+shadowRequestColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+	`, req.ID, req.UserID, req.Model, req.PrimaryProvider, req.PrimaryModel, req.PrimaryContent,
+		req.PrimaryInputTokens, req.PrimaryOutputTokens, req.PrimaryCost, req.PrimaryLatencyMs,
+		req.ShadowProvider, req.ShadowModel, req.ShadowContent, req.ShadowInputTokens, req.ShadowOutputTokens,
+		req.ShadowCost, req.ShadowLatencyMs, req.ShadowError, req.Similarity, req.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create shadow request: %w", err)
+	}
+	return nil
+}
+
+func (r *ShadowRequestRepository) List(ctx context.Context, filters ShadowRequestFilters) ([]*ShadowRequest, error) {
+	query := `SELECT ` + shadowRequestColumns + ` FROM shadow_requests WHERE 1=1`
+	args := []interface{}{}
+	argPos := 1
+	if filters.Model != "" {
+		query += fmt.Sprintf(" AND model = $%d", argPos)
+		args = append(args, filters.Model)
+		argPos++
+	}
+	if filters.ShadowProvider != "" {
+		query += fmt.Sprintf(" AND shadow_provider = $%d", argPos)
+		args = append(args, filters.ShadowProvider)
+		argPos++
+	}
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argPos, argPos+1)
+	args = append(args, limit, filters.Offset)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shadow requests: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*ShadowRequest
+	for rows.Next() {
+		var req ShadowRequest
+		if err := rows.Scan(
+			&req.ID, &req.UserID, &req.Model, &req.PrimaryProvider, &req.PrimaryModel, &req.PrimaryContent,
+			&req.PrimaryInputTokens, &req.PrimaryOutputTokens, &req.PrimaryCost, &req.PrimaryLatencyMs,
+			&req.ShadowProvider, &req.ShadowModel, &req.ShadowContent, &req.ShadowInputTokens, &req.ShadowOutputTokens,
+			&req.ShadowCost, &req.ShadowLatencyMs, &req.ShadowError, &req.Similarity, &req.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow request: %w", err)
+		}
+		out = append(out, &req)
+	}
+	return out, rows.Err()
+}
+
+// Summarize aggregates shadow requests created since the given time, per model and
+// shadow target. An empty model covers every model.
+func (r *ShadowRequestRepository) Summarize(ctx context.Context, model string, since time.Time) ([]ShadowSummary, error) {
+	query := `
+		SELECT model, shadow_provider, shadow_model,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE shadow_error IS NOT NULL),
+		       COALESCE(AVG(primary_latency_ms) FILTER (WHERE shadow_error IS NULL), 0),
+		       COALESCE(AVG(shadow_latency_ms) FILTER (WHERE shadow_error IS NULL), 0),
+		       COALESCE(SUM(primary_cost), 0),
+		       COALESCE(SUM(shadow_cost), 0),
+		       COALESCE(AVG(LENGTH(primary_content)) FILTER (WHERE shadow_error IS NULL), 0),
+		       COALESCE(AVG(LENGTH(shadow_content)) FILTER (WHERE shadow_error IS NULL), 0),
+		       COALESCE(AVG(primary_output_tokens) FILTER (WHERE shadow_error IS NULL), 0),
+		       COALESCE(AVG(shadow_output_tokens) FILTER (WHERE shadow_error IS NULL), 0),
+		       AVG(similarity)
+		FROM shadow_requests
+		WHERE created_at >= $1 AND ($2 = '' OR model = $2)
+		GROUP BY model, shadow_provider, shadow_model
+		ORDER BY model, shadow_provider, shadow_model
+	`
+	rows, err := r.pool.Query(ctx, query, since, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize shadow requests: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ShadowSummary
+	for rows.Next() {
+		var s ShadowSummary
+		if err := rows.Scan(
+			&s.Model, &s.ShadowProvider, &s.ShadowModel, &s.Requests, &s.Errors,
+			&s.AvgPrimaryLatencyMs, &s.AvgShadowLatencyMs, &s.PrimaryCost, &s.ShadowCost,
+			&s.AvgPrimaryLength, &s.AvgShadowLength, &s.AvgPrimaryOutputTokens, &s.AvgShadowOutputTokens,
+			&s.AvgSimilarity,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow summary: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
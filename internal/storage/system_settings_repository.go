@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -115,6 +116,30 @@ func (r *SystemSettingsRepository) SetRoutingStrategyLock(ctx context.Context, l
 	return r.SetSetting(ctx, "routing_strategy_locked", value, updatedBy)
 }
 
+// GetDisabledProviders returns the providers an admin has disabled.
+func (r *SystemSettingsRepository) GetDisabledProviders(ctx context.Context) ([]string, error) {
+	setting, err := r.GetSetting(ctx, "disabled_providers")
+	if err != nil || setting == nil {
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(setting.Value), &names); err != nil {
+		return nil, fmt.Errorf("failed to decode disabled providers: %w", err)
+	}
+	return names, nil
+}
+
+func (r *SystemSettingsRepository) SetDisabledProviders(ctx context.Context, names []string, updatedBy *uuid.UUID) error {
+	if names == nil {
+		names = []string{}
+	}
+	value, err := json.Marshal(names)
+	if err != nil {
+		return fmt.Errorf("failed to encode disabled providers: %w", err)
+	}
+	return r.SetSetting(ctx, "disabled_providers", string(value), updatedBy)
+}
+
 // Deprecated: Use GetDefaultRoutingStrategy instead
 func (r *SystemSettingsRepository) GetRoutingStrategy(ctx context.Context) (string, error) {
 	return r.GetDefaultRoutingStrategy(ctx)
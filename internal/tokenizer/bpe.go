@@ -0,0 +1,123 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BPE is a byte-level byte-pair encoder using tiktoken rank tables.
+type BPE struct {
+	name    string
+	ranks   map[string]int
+	decoder map[int]string
+	pre     *Pretokenizer
+}
+
+// LoadTiktoken reads a tiktoken rank file ("<base64 token> <rank>" per line).
+func LoadTiktoken(name string, r io.Reader, pre *Pretokenizer) (*BPE, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: line %d: expected \"<token> <rank>\"", name, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: invalid token: %w", name, line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: invalid rank: %w", name, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	// Byte-level BPE can encode any input only if every single byte is a token.
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("%s: rank table has no token for byte 0x%02x", name, b)
+		}
+	}
+	decoder := make(map[int]string, len(ranks))
+	for token, rank := range ranks {
+		decoder[rank] = token
+	}
+	return &BPE{name: name, ranks: ranks, decoder: decoder, pre: pre}, nil
+}
+
+func LoadTiktokenFile(name, path string, pre *Pretokenizer) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	return LoadTiktoken(name, f, pre)
+}
+
+func (b *BPE) Name() string {
+	return b.name
+}
+
+func (b *BPE) Count(text string) int {
+	return len(b.Encode(text))
+}
+
+func (b *BPE) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range b.pre.Split(text) {
+		if rank, ok := b.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, b.encodePiece(piece)...)
+	}
+	return tokens
+}
+
+func (b *BPE) Decode(tokens []int) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(b.decoder[token])
+	}
+	return sb.String()
+}
+
+// encodePiece repeatedly merges the adjacent pair with the lowest rank, as tiktoken does.
+func (b *BPE) encodePiece(piece string) []int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	tokens := make([]int, 0, len(bounds)-1)
+	for i := 0; i+1 < len(bounds); i++ {
+		tokens = append(tokens, b.ranks[piece[bounds[i]:bounds[i+1]]])
+	}
+	return tokens
+}
@@ -0,0 +1,73 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator approximates token counts for models whose tokenizer is not public
+// (Claude, Gemini) or whose tables are not loaded. It pretokenizes like cl100k and
+// charges each word by length (charsPerToken letters per token, leading space free),
+// which tracks real counts far better than chars/4.
+type Estimator struct {
+	name          string
+	charsPerToken float64
+	imageTokens   func(width, height int) int
+}
+
+func NewEstimator(name string, charsPerToken float64, imageTokens func(width, height int) int) *Estimator {
+	if charsPerToken <= 0 {
+		charsPerToken = 6
+	}
+	return &Estimator{name: name, charsPerToken: charsPerToken, imageTokens: imageTokens}
+}
+
+func (e *Estimator) Name() string {
+	return e.name
+}
+
+func (e *Estimator) Count(text string) int {
+	total := 0
+	for _, piece := range CL100KPretokenizer.Split(text) {
+		total += e.countPiece(piece)
+	}
+	return total
+}
+
+func (e *Estimator) ImageTokens(width, height int) int {
+	if e.imageTokens == nil {
+		return TiledImageTokens(width, height)
+	}
+	return e.imageTokens(width, height)
+}
+
+func (e *Estimator) countPiece(piece string) int {
+	alnum, symbols, wide, other := 0, 0, 0, 0
+	for _, r := range piece {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			alnum++
+		case r < utf8.RuneSelf:
+			symbols++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			wide++
+		default:
+			other++
+		}
+	}
+	// CJK is roughly a token per character; other non-ASCII scripts split into
+	// about two characters per token in byte-level vocabularies.
+	tokens := wide + (other+1)/2
+	switch {
+	case alnum > 0:
+		tokens += int(math.Ceil(float64(alnum) / e.charsPerToken))
+	case symbols > 0:
+		// Punctuation and whitespace runs merge into few tokens.
+		tokens += (symbols + 2) / 3
+	}
+	if tokens == 0 {
+		tokens = 1
+	}
+	return tokens
+}
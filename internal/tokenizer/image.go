@@ -0,0 +1,68 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+)
+
+// Assumed size of images whose dimensions cannot be read without fetching them.
+const (
+	DefaultImageWidth  = 1024
+	DefaultImageHeight = 1024
+)
+
+// TiledImageTokens is OpenAI's high-detail image cost: fit within 2048x2048, scale the
+// short side down to 768, then 170 tokens per 512px tile plus 85.
+func TiledImageTokens(width, height int) int {
+	if width <= 0 || height <= 0 {
+		width, height = DefaultImageWidth, DefaultImageHeight
+	}
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := math.Min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+	tiles := math.Ceil(w/512) * math.Ceil(h/512)
+	return 85 + 170*int(tiles)
+}
+
+// ClaudeImageTokens follows Anthropic's width*height/750 after fitting the long edge to 1568px.
+func ClaudeImageTokens(width, height int) int {
+	if width <= 0 || height <= 0 {
+		width, height = DefaultImageWidth, DefaultImageHeight
+	}
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 1568 {
+		w, h = w*1568/longest, h*1568/longest
+	}
+	return int(math.Ceil(w * h / 750))
+}
+
+// GeminiImageTokens is Gemini's flat per-image charge.
+func GeminiImageTokens(width, height int) int {
+	return 258
+}
+
+// ImageDimensions reads the size of a base64 data URL image. Remote URLs are not fetched.
+func ImageDimensions(url string) (width, height int, ok bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return 0, 0, false
+	}
+	comma := strings.IndexByte(url, ',')
+	if comma < 0 || !strings.HasSuffix(url[:comma], ";base64") {
+		return 0, 0, false
+	}
+	// DecodeConfig only reads the header, so the payload is decoded lazily.
+	config, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(url[comma+1:])))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
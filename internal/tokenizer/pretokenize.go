@@ -0,0 +1,77 @@
+package tokenizer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Go's \s only matches ASCII whitespace; tiktoken's patterns use the Unicode definition.
+const ws = `\t\n\v\f\r\x{85}\p{Z}`
+
+// The upstream patterns end in `\s+(?!\S)|\s+`. RE2 has no lookahead, so Split
+// emulates it by handing the last whitespace rune to the following piece.
+var (
+	cl100kPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)` +
+		`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + ws + `\p{L}\p{N}]+[\r\n]*` +
+		`|[` + ws + `]*[\r\n]+` +
+		`|[` + ws + `]+`)
+
+	o200kPattern = regexp.MustCompile(`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + ws + `\p{L}\p{N}]+[\r\n/]*` +
+		`|[` + ws + `]*[\r\n]+` +
+		`|[` + ws + `]+`)
+)
+
+// Pretokenizer splits text into the pieces BPE merges are applied within.
+type Pretokenizer struct {
+	re *regexp.Regexp
+}
+
+var (
+	CL100KPretokenizer = &Pretokenizer{re: cl100kPattern}
+	O200KPretokenizer  = &Pretokenizer{re: o200kPattern}
+)
+
+func (p *Pretokenizer) Split(text string) []string {
+	var pieces []string
+	for pos := 0; pos < len(text); {
+		loc := p.re.FindStringIndex(text[pos:])
+		if loc == nil || loc[1] == 0 {
+			// Unreachable with the built-in patterns, which match every rune.
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			pieces = append(pieces, text[pos:pos+size])
+			pos += size
+			continue
+		}
+		end := pos + loc[1]
+		piece := text[pos:end]
+		if end < len(text) && isTrailingSpaceRun(piece) {
+			_, size := utf8.DecodeLastRuneInString(piece)
+			piece = piece[:len(piece)-size]
+			end -= size
+		}
+		pieces = append(pieces, piece)
+		pos = end
+	}
+	return pieces
+}
+
+// isTrailingSpaceRun reports whether piece is a run of two or more whitespace
+// runes without line breaks, i.e. what `\s+(?!\S)` would have shortened.
+func isTrailingSpaceRun(piece string) bool {
+	if utf8.RuneCountInString(piece) < 2 || strings.ContainsAny(piece, "\r\n") {
+		return false
+	}
+	for _, r := range piece {
+		if !unicode.IsSpace(r) && !unicode.Is(unicode.Z, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tokenizer counts tokens the way a model family does.
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+// Encoder is a Tokenizer that produces real token IDs, so its counts are exact.
+type Encoder interface {
+	Tokenizer
+	Encode(text string) []int
+	Decode(tokens []int) string
+}
+
+// ImageCounter is implemented by tokenizers whose models charge images differently
+// from OpenAI's tiling scheme.
+type ImageCounter interface {
+	ImageTokens(width, height int) int
+}
+
+const (
+	CL100KBase = "cl100k_base"
+	O200KBase  = "o200k_base"
+)
+
+var encodingPretokenizers = map[string]*Pretokenizer{
+	CL100KBase: CL100KPretokenizer,
+	O200KBase:  O200KPretokenizer,
+}
+
+var (
+	gptEstimator     = NewEstimator("gpt-estimate", 6, nil)
+	claudeEstimator  = NewEstimator("claude-estimate", 5, ClaudeImageTokens)
+	geminiEstimator  = NewEstimator("gemini-estimate", 6, GeminiImageTokens)
+	genericEstimator = NewEstimator("estimate", 5.5, nil)
+)
+
+// Model name prefixes per OpenAI encoding, checked in order.
+var openAIEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", O200KBase},
+	{"chatgpt-4o", O200KBase},
+	{"gpt-4.1", O200KBase},
+	{"gpt-4.5", O200KBase},
+	{"gpt-5", O200KBase},
+	{"o1", O200KBase},
+	{"o3", O200KBase},
+	{"o4", O200KBase},
+	{"gpt-4", CL100KBase},
+	{"gpt-3.5", CL100KBase},
+	{"text-embedding-3", CL100KBase},
+	{"text-embedding-ada", CL100KBase},
+}
+
+// Registry picks a tokenizer per model. OpenAI models use BPE when the rank tables
+// have been loaded; everything else, and OpenAI without tables, gets an estimator.
+// Tokenizers registered for a model name or "prefix*" pattern take precedence.
+type Registry struct {
+	mu        sync.RWMutex
+	encodings map[string]*BPE
+	exact     map[string]Tokenizer
+	prefixes  []prefixTokenizer // longest prefix first
+}
+
+type prefixTokenizer struct {
+	prefix    string
+	tokenizer Tokenizer
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		encodings: make(map[string]*BPE),
+		exact:     make(map[string]Tokenizer),
+	}
+}
+
+// SetEncoding installs the rank table for a built-in OpenAI encoding.
+func (r *Registry) SetEncoding(name string, bpe *BPE) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.encodings[name] = bpe
+}
+
+// LoadDir loads cl100k_base.tiktoken and o200k_base.tiktoken from dir, skipping missing files.
+func (r *Registry) LoadDir(dir string) ([]string, error) {
+	var loaded []string
+	for _, name := range []string{CL100KBase, O200KBase} {
+		path := filepath.Join(dir, name+".tiktoken")
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		bpe, err := LoadTiktokenFile(name, path, encodingPretokenizers[name])
+		if err != nil {
+			return loaded, err
+		}
+		r.SetEncoding(name, bpe)
+		loaded = append(loaded, name)
+	}
+	return loaded, nil
+}
+
+// Register makes t the tokenizer for model, or for every model starting with the
+// prefix when pattern ends in "*". Matching is case-insensitive.
+func (r *Registry) Register(pattern string, t Tokenizer) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		r.prefixes = append(r.prefixes, prefixTokenizer{prefix: prefix, tokenizer: t})
+		sort.SliceStable(r.prefixes, func(i, j int) bool {
+			return len(r.prefixes[i].prefix) > len(r.prefixes[j].prefix)
+		})
+		return
+	}
+	r.exact[pattern] = t
+}
+
+// RegisterFile loads a tiktoken-format table (such as Llama 3's tokenizer.model) for
+// pattern, splitting text with the cl100k pretokenizer.
+func (r *Registry) RegisterFile(pattern, path string) error {
+	bpe, err := LoadTiktokenFile(filepath.Base(path), path, CL100KPretokenizer)
+	if err != nil {
+		return fmt.Errorf("tokenizer for %s: %w", pattern, err)
+	}
+	r.Register(pattern, bpe)
+	return nil
+}
+
+func (r *Registry) ForModel(model string) Tokenizer {
+	name := strings.ToLower(strings.TrimSpace(model))
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if t, ok := r.exact[name]; ok {
+		return t
+	}
+	for _, p := range r.prefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.tokenizer
+		}
+	}
+
+	// Hugging Face style IDs ("org/model") are matched on the model part.
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, e := range openAIEncodings {
+		if strings.HasPrefix(name, e.prefix) {
+			if bpe, ok := r.encodings[e.encoding]; ok {
+				return bpe
+			}
+			return gptEstimator
+		}
+	}
+	switch {
+	case strings.HasPrefix(name, "claude"):
+		return claudeEstimator
+	case strings.HasPrefix(name, "gemini"), strings.HasPrefix(name, "text-embedding-004"):
+		return geminiEstimator
+	}
+	return genericEstimator
+}
+
+// Exact reports whether t counts real tokens rather than estimating.
+func Exact(t Tokenizer) bool {
+	_, ok := t.(Encoder)
+	return ok
+}
+
+func ImageTokens(t Tokenizer, width, height int) int {
+	if counter, ok := t.(ImageCounter); ok {
+		return counter.ImageTokens(width, height)
+	}
+	return TiledImageTokens(width, height)
+}
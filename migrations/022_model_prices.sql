@@ -0,0 +1,28 @@
+-- Migration: 022_model_prices.sql
+-- Description: Adds model prices that override the gateway's built-in pricing catalog
+
+CREATE TABLE IF NOT EXISTS model_prices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider VARCHAR(100) NOT NULL,
+    model VARCHAR(255) NOT NULL, -- '*' prices every model of the provider without its own entry
+    input_cost DECIMAL(12, 6) NOT NULL DEFAULT 0, -- USD per 1M tokens
+    output_cost DECIMAL(12, 6) NOT NULL DEFAULT 0,
+    cached_input_cost DECIMAL(12, 6) NOT NULL DEFAULT 0, -- 0 = same as input_cost
+    batch_input_cost DECIMAL(12, 6) NOT NULL DEFAULT 0,
+    batch_output_cost DECIMAL(12, 6) NOT NULL DEFAULT 0,
+    image_cost DECIMAL(12, 6) NOT NULL DEFAULT 0, -- USD per input image
+    effective_from TIMESTAMP WITH TIME ZONE,
+    effective_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (effective_until IS NULL OR effective_from IS NULL OR effective_until > effective_from)
+);
+
+-- One price per model and start date (a missing start date counts as one value)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_prices_model_from
+    ON model_prices(provider, model, COALESCE(effective_from, 'epoch'::timestamptz));
+
+COMMENT ON TABLE model_prices IS 'Model prices layered over the built-in and file pricing catalogs';
+COMMENT ON COLUMN model_prices.effective_from IS 'Price applies from this time (inclusive); NULL = always';
+COMMENT ON COLUMN model_prices.effective_until IS 'Price applies until this time (exclusive); NULL = open-ended';
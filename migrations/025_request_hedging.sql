@@ -0,0 +1,11 @@
+-- Migration: 025_request_hedging.sql
+-- Description: Adds opt-in request hedging per API key and links the requests rows of a hedged request
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS hedge_percentile INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS hedge_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_requests_hedge_id ON requests(hedge_id) WHERE hedge_id IS NOT NULL;
+
+COMMENT ON COLUMN api_keys.hedge_percentile IS 'Hedge chat requests that take longer than this percentile of the provider''s recent latency; 0 = off';
+COMMENT ON COLUMN requests.hedge_id IS 'Shared by the winning request and its losing chat_hedge calls';
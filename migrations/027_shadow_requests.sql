@@ -0,0 +1,31 @@
+-- Migration: 027_shadow_requests.sql
+-- Description: Stores live responses next to the response of a shadow (candidate) provider to the same request
+
+CREATE TABLE IF NOT EXISTS shadow_requests (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    model VARCHAR(255) NOT NULL, -- model requested by the caller
+    primary_provider VARCHAR(50) NOT NULL,
+    primary_model VARCHAR(255) NOT NULL,
+    primary_content TEXT NOT NULL DEFAULT '',
+    primary_input_tokens INTEGER NOT NULL DEFAULT 0,
+    primary_output_tokens INTEGER NOT NULL DEFAULT 0,
+    primary_cost DECIMAL(10, 6) NOT NULL DEFAULT 0,
+    primary_latency_ms INTEGER NOT NULL DEFAULT 0,
+    shadow_provider VARCHAR(50) NOT NULL,
+    shadow_model VARCHAR(255) NOT NULL,
+    shadow_content TEXT NOT NULL DEFAULT '',
+    shadow_input_tokens INTEGER NOT NULL DEFAULT 0,
+    shadow_output_tokens INTEGER NOT NULL DEFAULT 0,
+    shadow_cost DECIMAL(10, 6) NOT NULL DEFAULT 0,
+    shadow_latency_ms INTEGER NOT NULL DEFAULT 0,
+    shadow_error TEXT,
+    similarity DOUBLE PRECISION, -- cosine similarity of the two responses' embeddings, if configured
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_shadow_requests_model_created ON shadow_requests(model, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_shadow_requests_shadow_provider ON shadow_requests(shadow_provider, created_at DESC);
+
+COMMENT ON TABLE shadow_requests IS 'Sampled live requests mirrored to a candidate provider for offline comparison (SHADOW_CONFIG_PATH)';
+COMMENT ON COLUMN shadow_requests.shadow_error IS 'Error of the shadow call. NULL when it succeeded.';
@@ -0,0 +1,7 @@
+-- Migration: 029_conversation_summary.sql
+-- Description: Summary of the older turns of conversations that outgrew their model's context window
+
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summary TEXT;
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summary_message_count INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN conversations.summary IS 'Summary of the first summary_message_count messages, used in place of them once the conversation exceeds the context window';
@@ -10,4 +10,5 @@ var (
 	ErrInvalidRequest      = fmt.Errorf("invalid request")
 	ErrProviderNotFound    = fmt.Errorf("provider not found")
 	ErrRateLimitExceeded   = fmt.Errorf("rate limit exceeded")
+	ErrBudgetExceeded      = fmt.Errorf("budget exceeded")
 )
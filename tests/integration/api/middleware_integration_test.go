@@ -96,7 +96,7 @@ func TestAuthMiddleware_Integration(t *testing.T) {
 	
 	// Create an API key
 	userID := uuid.New()
-	key, _, err := apiKeyService.CreateAPIKey(ctx, userID, "Integration Test", 60, 10000, nil)
+	key, _, err := apiKeyService.CreateAPIKey(ctx, userID, "Integration Test", 60, 10000, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey failed: %v", err)
 	}
@@ -204,7 +204,7 @@ func TestFullMiddlewareFlow_Integration(t *testing.T) {
 	userID := uuid.New()
 	
 	// Create API key
-	key, apiKey, err := apiKeyService.CreateAPIKey(ctx, userID, "Full Flow Test", 5, 100, nil)
+	key, apiKey, err := apiKeyService.CreateAPIKey(ctx, userID, "Full Flow Test", 5, 100, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey failed: %v", err)
 	}
@@ -96,7 +96,7 @@ func TestAPIKeyServiceV2_Integration(t *testing.T) {
 	userID := uuid.New()
 
 	// Create API key
-	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Integration Test Key", 60, 10000, nil)
+	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Integration Test Key", 60, 10000, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey failed: %v", err)
 	}
@@ -140,7 +140,7 @@ func TestAPIKeyServiceV2_Integration_Expiration(t *testing.T) {
 	expiresAt := time.Now().Add(-1 * time.Hour) // Expired
 
 	// Create expired API key
-	key, _, err := service.CreateAPIKey(ctx, userID, "Expired Key", 60, 10000, &expiresAt)
+	key, _, err := service.CreateAPIKey(ctx, userID, "Expired Key", 60, 10000, &expiresAt, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey failed: %v", err)
 	}
@@ -164,7 +164,7 @@ func TestAPIKeyServiceV2_Integration_Inactive(t *testing.T) {
 	userID := uuid.New()
 
 	// Create API key
-	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, nil)
+	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey failed: %v", err)
 	}
@@ -277,7 +277,7 @@ func TestFullFlow_Integration(t *testing.T) {
 	userID := uuid.New()
 
 	// Step 1: Create API key
-	key, apiKey, err := apiKeyService.CreateAPIKey(ctx, userID, "Full Flow Test", 10, 100, nil)
+	key, apiKey, err := apiKeyService.CreateAPIKey(ctx, userID, "Full Flow Test", 10, 100, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey failed: %v", err)
 	}
@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/api/handlers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type embedMockProvider struct {
+	compatMockProvider
+}
+
+func (m *embedMockProvider) Embed(ctx context.Context, req providers.EmbeddingRequest) (*providers.EmbeddingResponse, error) {
+	embeddings := make([][]float64, 0, len(req.Input))
+	for i := range req.Input {
+		embeddings = append(embeddings, []float64{float64(i), 0.5})
+	}
+	return &providers.EmbeddingResponse{
+		Model:      req.Model,
+		Embeddings: embeddings,
+		Usage:      providers.Usage{PromptTokens: 4, TotalTokens: 4},
+	}, nil
+}
+
+func (m *embedMockProvider) GetEmbeddingModels() []string { return []string{"mock-embed"} }
+
+func newEmbeddingsEngine() *gin.Engine {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&embedMockProvider{})
+	h := handlers.NewChatHandler(router, nil, nil, zerolog.Nop())
+	engine := setupTestRouter()
+	engine.POST("/v1/embeddings", h.HandleEmbeddings)
+	return engine
+}
+
+func postEmbeddings(engine *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleEmbeddings(t *testing.T) {
+	w := postEmbeddings(newEmbeddingsEngine(), `{"model":"mock-embed","input":["a","b"]}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "mock", w.Header().Get("X-UniRoute-Provider"))
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "list", resp["object"])
+	data := resp["data"].([]interface{})
+	require.Len(t, data, 2)
+	second := data[1].(map[string]interface{})
+	assert.Equal(t, float64(1), second["index"])
+	assert.Equal(t, []interface{}{float64(1), 0.5}, second["embedding"])
+	assert.Equal(t, float64(4), resp["usage"].(map[string]interface{})["prompt_tokens"])
+}
+
+func TestHandleEmbeddings_Base64(t *testing.T) {
+	w := postEmbeddings(newEmbeddingsEngine(), `{"model":"mock-embed","input":"a","encoding_format":"base64"}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	embedding := resp["data"].([]interface{})[0].(map[string]interface{})["embedding"]
+	// [0, 0.5] as little-endian float32
+	assert.Equal(t, "AAAAAAAAAD8=", embedding)
+}
+
+func TestHandleEmbeddings_InvalidInput(t *testing.T) {
+	engine := newEmbeddingsEngine()
+
+	w := postEmbeddings(engine, `{"model":"mock-embed","input":[1,2,3]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postEmbeddings(engine, `{"model":"unknown-embed","input":"a"}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
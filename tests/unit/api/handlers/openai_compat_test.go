@@ -0,0 +1,275 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/api/handlers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type compatMockProvider struct{}
+
+func (m *compatMockProvider) Name() string { return "mock" }
+
+func (m *compatMockProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	if len(req.Tools) > 0 {
+		return &providers.ChatResponse{
+			ID:    "resp-2",
+			Model: req.Model,
+			Choices: []providers.Choice{{
+				Message: providers.Message{Role: "assistant", Content: "", ToolCalls: []providers.ToolCall{
+					{ID: "call_1", Type: "function", Function: providers.ToolCallFunction{Name: req.Tools[0].Function.Name, Arguments: `{"city":"Lagos"}`}},
+				}},
+				FinishReason: providers.FinishReasonToolCalls,
+			}},
+		}, nil
+	}
+	return &providers.ChatResponse{
+		ID:    "resp-1",
+		Model: req.Model,
+		Choices: []providers.Choice{
+			{Message: providers.Message{Role: "assistant", Content: "Hello there"}},
+		},
+		Usage: providers.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+	}, nil
+}
+
+func (m *compatMockProvider) ChatStream(ctx context.Context, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	chunks := make(chan providers.StreamChunk, 3)
+	errs := make(chan error)
+	chunks <- providers.StreamChunk{ID: "resp-1", Content: "Hel"}
+	chunks <- providers.StreamChunk{ID: "resp-1", Content: "lo"}
+	chunks <- providers.StreamChunk{ID: "resp-1", Done: true, Usage: &providers.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}}
+	close(chunks)
+	close(errs)
+	return chunks, errs
+}
+
+func (m *compatMockProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (m *compatMockProvider) GetModels() []string { return []string{"mock-model"} }
+
+func (m *compatMockProvider) SupportsTools(model string) bool { return true }
+
+func newCompatEngine() *gin.Engine {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&compatMockProvider{})
+	h := handlers.NewChatHandler(router, nil, nil, zerolog.Nop())
+	engine := setupTestRouter()
+	engine.POST("/v1/chat/completions", h.HandleChatCompletions)
+	return engine
+}
+
+func TestHandleChatCompletions_NonStreaming(t *testing.T) {
+	engine := newCompatEngine()
+	body := `{"model":"mock-model","messages":[{"role":"developer","content":"be brief"},{"role":"user","content":"hi"}],"temperature":0}`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "chat.completion", resp["object"])
+	choices := resp["choices"].([]interface{})
+	require.Len(t, choices, 1)
+	choice := choices[0].(map[string]interface{})
+	assert.Equal(t, "stop", choice["finish_reason"])
+	assert.Equal(t, "Hello there", choice["message"].(map[string]interface{})["content"])
+	assert.Equal(t, float64(5), resp["usage"].(map[string]interface{})["total_tokens"])
+}
+
+func TestHandleChatCompletions_Streaming(t *testing.T) {
+	engine := newCompatEngine()
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"hi"}],"stream":true,"stream_options":{"include_usage":true}}`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	require.GreaterOrEqual(t, len(events), 5)
+	assert.Equal(t, "data: [DONE]", events[len(events)-1])
+
+	var content strings.Builder
+	for _, ev := range events[:len(events)-1] {
+		var chunk map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(ev, "data: ")), &chunk))
+		assert.Equal(t, "chat.completion.chunk", chunk["object"])
+		for _, ch := range chunk["choices"].([]interface{}) {
+			delta := ch.(map[string]interface{})["delta"].(map[string]interface{})
+			if s, ok := delta["content"].(string); ok {
+				content.WriteString(s)
+			}
+		}
+	}
+	assert.Equal(t, "Hello", content.String())
+
+	var usageChunk map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[len(events)-2], "data: ")), &usageChunk))
+	assert.Empty(t, usageChunk["choices"])
+	assert.Equal(t, float64(5), usageChunk["usage"].(map[string]interface{})["total_tokens"])
+}
+
+func TestHandleChatCompletions_InvalidRequest(t *testing.T) {
+	engine := newCompatEngine()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"messages":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "invalid_request_error", resp["error"].(map[string]interface{})["type"])
+}
+
+func TestHandleChatStream_RejectsBeforeStreaming(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&compatMockProvider{})
+	engine := setupTestRouter()
+	engine.POST("/chat/stream", handlers.NewChatHandler(router, nil, nil, zerolog.Nop()).HandleChatStream)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/chat/stream", bytes.NewBufferString(`{"model":"mock-model","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-UniRoute-Context-Strategy", "shorten")
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json", "errors before the stream starts are plain JSON")
+}
+
+func TestHandleChatCompletions_StreamRejectsBufferedOptions(t *testing.T) {
+	engine := newCompatEngine()
+	for header, value := range map[string]string{
+		"X-UniRoute-Hedge":          "true",
+		"X-UniRoute-Output-Retries": "2",
+		"X-UniRoute-Debug":          "true",
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"mock-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, value)
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, header)
+		assert.Contains(t, w.Body.String(), header)
+	}
+}
+
+func TestHandleChatCompletions_ToolCalls(t *testing.T) {
+	engine := newCompatEngine()
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"weather in Lagos?"}],` +
+		`"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}],"tool_choice":"auto"}`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	choice := resp["choices"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "tool_calls", choice["finish_reason"])
+	message := choice["message"].(map[string]interface{})
+	assert.Nil(t, message["content"])
+	calls := message["tool_calls"].([]interface{})
+	require.Len(t, calls, 1)
+	fn := calls[0].(map[string]interface{})["function"].(map[string]interface{})
+	assert.Equal(t, "get_weather", fn["name"])
+	assert.Equal(t, `{"city":"Lagos"}`, fn["arguments"])
+}
+
+type failingCompatProvider struct {
+	compatMockProvider
+	err error
+}
+
+func (m *failingCompatProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	return nil, m.err
+}
+
+func TestHandleChatCompletions_ProviderErrorStatus(t *testing.T) {
+	router := gateway.NewRouter()
+	router.SetRetryPolicy(gateway.RetryPolicy{})
+	router.RegisterProvider(&failingCompatProvider{err: &providers.ProviderError{
+		Provider:   "mock",
+		Kind:       providers.ErrorKindRateLimit,
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: 2 * time.Second,
+		Message:    "Rate limit reached",
+	}})
+	h := handlers.NewChatHandler(router, nil, nil, zerolog.Nop())
+	engine := setupTestRouter()
+	engine.POST("/v1/chat/completions", h.HandleChatCompletions)
+
+	w := postJSON(engine, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"hi"}]}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	errBody := resp["error"].(map[string]interface{})
+	assert.Equal(t, "rate_limit_error", errBody["type"])
+	assert.Equal(t, "Rate limit reached", errBody["message"])
+}
+
+type recordingCompatProvider struct {
+	compatMockProvider
+	req providers.ChatRequest
+}
+
+func (m *recordingCompatProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	m.req = req
+	return m.compatMockProvider.Chat(ctx, req)
+}
+
+func TestHandleChatCompletions_Sampling(t *testing.T) {
+	provider := &recordingCompatProvider{}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+	h := handlers.NewChatHandler(router, nil, nil, zerolog.Nop())
+	engine := setupTestRouter()
+	engine.POST("/v1/chat/completions", h.HandleChatCompletions)
+
+	w := postJSON(engine, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"hi"}],
+		"top_p":0.9,"stop":"END","presence_penalty":0.5,"frequency_penalty":0.25,"seed":7,"logit_bias":{"50256":-100},"n":1}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	req := provider.req
+	require.NotNil(t, req.TopP)
+	assert.Equal(t, 0.9, *req.TopP)
+	assert.Equal(t, []string{"END"}, req.Stop)
+	require.NotNil(t, req.PresencePenalty)
+	assert.Equal(t, 0.5, *req.PresencePenalty)
+	require.NotNil(t, req.FrequencyPenalty)
+	assert.Equal(t, 0.25, *req.FrequencyPenalty)
+	require.NotNil(t, req.Seed)
+	assert.Equal(t, 7, *req.Seed)
+	assert.Equal(t, map[string]float64{"50256": -100}, req.LogitBias)
+
+	for _, body := range []string{
+		`{"model":"mock-model","messages":[{"role":"user","content":"hi"}],"n":2}`,
+		`{"model":"mock-model","messages":[{"role":"user","content":"hi"}],"logprobs":true}`,
+		`{"model":"mock-model","messages":[{"role":"user","content":"hi"}],"top_logprobs":2}`,
+		`{"model":"mock-model","messages":[{"role":"user","content":"hi"}],"stop":[1]}`,
+	} {
+		w := postJSON(engine, "/v1/chat/completions", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), "invalid_request_error", body)
+	}
+}
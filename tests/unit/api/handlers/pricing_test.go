@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/api/handlers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPricingEngine(router *gateway.Router) *gin.Engine {
+	h := handlers.NewPricingHandler(router, nil, zerolog.Nop())
+	engine := setupTestRouter()
+	engine.GET("/admin/pricing", h.GetPricing)
+	engine.GET("/admin/pricing/lookup", h.LookupPrice)
+	engine.POST("/admin/pricing/reload", h.ReloadPricing)
+	return engine
+}
+
+func getJSON(t *testing.T, engine *gin.Engine, method, path string) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestPricingHandler_Lookup(t *testing.T) {
+	engine := newPricingEngine(gateway.NewRouter())
+
+	code, body := getJSON(t, engine, http.MethodGet, "/admin/pricing/lookup?provider=openai&model=gpt-4o&at=2024-06-01")
+	require.Equal(t, http.StatusOK, code)
+	price := body["price"].(map[string]interface{})
+	assert.Equal(t, 5.0, price["input"])
+	assert.Equal(t, "builtin", price["source"])
+
+	code, body = getJSON(t, engine, http.MethodGet, "/admin/pricing/lookup?provider=openai&model=gpt-99")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "unknown price", body["error"])
+
+	code, _ = getJSON(t, engine, http.MethodGet, "/admin/pricing/lookup?provider=openai")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestPricingHandler_GetPricingFiltersByProvider(t *testing.T) {
+	engine := newPricingEngine(gateway.NewRouter())
+
+	code, body := getJSON(t, engine, http.MethodGet, "/admin/pricing?provider=anthropic")
+	require.Equal(t, http.StatusOK, code)
+	prices := body["prices"].([]interface{})
+	require.NotEmpty(t, prices)
+	for _, p := range prices {
+		assert.Equal(t, "anthropic", p.(map[string]interface{})["provider"])
+	}
+	status := body["status"].(map[string]interface{})
+	assert.Equal(t, "builtin-2025-08", status["version"])
+}
+
+func TestPricingHandler_Reload(t *testing.T) {
+	router := gateway.NewRouter()
+	engine := newPricingEngine(router)
+
+	code, _ := getJSON(t, engine, http.MethodPost, "/admin/pricing/reload")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":"v1","prices":[{"provider":"openai","model":"gpt-99","input":1,"output":1}]}`), 0o644))
+	loader := gateway.NewPricingLoader(router.GetCostCalculator(), time.Minute, zerolog.Nop(), gateway.NewFilePricingSource(path))
+	_, err := loader.Reload(context.Background())
+	require.NoError(t, err)
+	router.SetPricingLoader(loader)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":"v2","prices":[{"provider":"openai","model":"gpt-99","input":2,"output":1}]}`), 0o644))
+	code, body := getJSON(t, engine, http.MethodPost, "/admin/pricing/reload")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["changed"])
+
+	code, body = getJSON(t, engine, http.MethodGet, "/admin/pricing/lookup?provider=openai&model=gpt-99")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2.0, body["price"].(map[string]interface{})["input"])
+	assert.Equal(t, "file", body["price"].(map[string]interface{})["source"])
+}
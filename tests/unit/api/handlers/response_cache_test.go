@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/api/handlers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type handlerCacheStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *handlerCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key], nil
+}
+
+func (s *handlerCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	return nil
+}
+
+func newCachingEngine(cacheTTLSeconds int) *gin.Engine {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&compatMockProvider{})
+	router.SetResponseCache(gateway.NewResponseCache(&handlerCacheStore{data: map[string][]byte{}}))
+	h := handlers.NewChatHandler(router, nil, nil, zerolog.Nop())
+
+	key := &storage.APIKey{ID: uuid.New(), CacheTTLSeconds: cacheTTLSeconds}
+	engine := setupTestRouter()
+	engine.Use(func(c *gin.Context) {
+		c.Set("api_key_id", key.ID.String())
+		c.Set("api_key_record", key)
+		c.Next()
+	})
+	engine.POST("/v1/chat/completions", h.HandleChatCompletions)
+	engine.POST("/v1/chat", h.HandleChat)
+	return engine
+}
+
+func postJSON(engine *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleChatCompletions_ResponseCache(t *testing.T) {
+	engine := newCachingEngine(60)
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"hi"}],"temperature":0}`
+
+	first := postJSON(engine, "/v1/chat/completions", body)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get("X-UniRoute-Cache"))
+
+	second := postJSON(engine, "/v1/chat/completions", body)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "HIT", second.Header().Get("X-UniRoute-Cache"))
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(second.Body.Bytes(), &resp))
+	assert.Equal(t, true, resp["cached"])
+	choices := resp["choices"].([]interface{})
+	message := choices[0].(map[string]interface{})["message"].(map[string]interface{})
+	assert.Equal(t, "Hello there", message["content"])
+}
+
+func TestHandleChat_ResponseCacheDisabledForKey(t *testing.T) {
+	engine := newCachingEngine(0)
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"hi"}]}`
+
+	for i := 0; i < 2; i++ {
+		w := postJSON(engine, "/v1/chat", body)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-UniRoute-Cache"))
+		assert.NotContains(t, w.Body.String(), `"cached"`)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/api/handlers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenizeHandler() *handlers.ChatHandler {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&compatMockProvider{})
+	return handlers.NewChatHandler(router, nil, nil, zerolog.Nop())
+}
+
+func TestHandleTokenize(t *testing.T) {
+	h := newTokenizeHandler()
+	engine := setupTestRouter()
+	engine.POST("/v1/tokenize", h.HandleTokenize)
+
+	w := postJSON(engine, "/v1/tokenize", `{"model":"claude-3-5-haiku-20241022","text":"Hello world"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "claude-estimate", resp["tokenizer"])
+	assert.Equal(t, false, resp["exact"])
+	assert.Equal(t, float64(2), resp["count"])
+	assert.NotContains(t, resp, "tokens")
+
+	w = postJSON(engine, "/v1/tokenize", `{"text":"Hello"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleCountTokens(t *testing.T) {
+	h := newTokenizeHandler()
+	engine := setupTestRouter()
+	engine.POST("/v1/count-tokens", h.HandleCountTokens)
+
+	body := `{"model":"gpt-4","max_tokens":8000,"messages":[{"role":"user","content":[{"type":"text","text":"Describe"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`
+	w := postJSON(engine, "/v1/count-tokens", body)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(1), resp["image_count"])
+	assert.Equal(t, float64(765), resp["image_tokens"])
+	assert.Equal(t, float64(8192), resp["context_window"])
+	assert.Equal(t, false, resp["fits"])
+	assert.Greater(t, resp["prompt_tokens"].(float64), float64(765))
+}
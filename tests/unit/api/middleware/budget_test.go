@@ -0,0 +1,190 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/api/middleware"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySpendStore struct {
+	mu     sync.Mutex
+	totals map[string]float64
+}
+
+func (s *memorySpendStore) Add(ctx context.Context, key string, amount float64, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals[key] += amount
+	return nil
+}
+
+func (s *memorySpendStore) Get(ctx context.Context, keys []string) ([]float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	totals := make([]float64, len(keys))
+	for i, key := range keys {
+		totals[i] = s.totals[key]
+	}
+	return totals, nil
+}
+
+type staticBudgets []*storage.Budget
+
+func (b staticBudgets) ListByUser(ctx context.Context, userID uuid.UUID) ([]*storage.Budget, error) {
+	var budgets []*storage.Budget
+	for _, budget := range b {
+		if budget.UserID == userID {
+			budgets = append(budgets, budget)
+		}
+	}
+	return budgets, nil
+}
+
+func usd(v float64) *float64 {
+	return &v
+}
+
+type failingSpendStore struct{}
+
+func (failingSpendStore) Add(ctx context.Context, key string, amount float64, ttl time.Duration) error {
+	return errors.New("redis unavailable")
+}
+
+func (failingSpendStore) Get(ctx context.Context, keys []string) ([]float64, error) {
+	return nil, errors.New("redis unavailable")
+}
+
+func serveWithBudget(tracker *security.BudgetTracker, key *storage.APIKey) *httptest.ResponseRecorder {
+	return serveBudgetRequest(tracker, "api_key_record", key)
+}
+
+// serveBudgetRequest serves a request after setting the context value an auth
+// middleware would have set.
+func serveBudgetRequest(tracker *security.BudgetTracker, name string, value interface{}) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(name, value)
+		c.Next()
+	})
+	r.Use(middleware.BudgetMiddleware(tracker, zerolog.Nop()))
+	r.POST("/v1/chat", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat", nil))
+	return w
+}
+
+func TestBudgetMiddleware_KeyBudget(t *testing.T) {
+	userID, keyID, otherKeyID := uuid.New(), uuid.New(), uuid.New()
+	tracker := security.NewBudgetTracker(&memorySpendStore{totals: map[string]float64{}}, staticBudgets{
+		{UserID: userID, APIKeyID: &keyID, Period: storage.BudgetPeriodMonthly, SoftLimitUSD: usd(8), HardLimitUSD: usd(10)},
+	})
+	key := &storage.APIKey{ID: keyID, UserID: userID}
+	ctx := context.Background()
+
+	w := serveWithBudget(tracker, key)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(middleware.BudgetWarningHeader))
+	assert.Equal(t, "10.0000", w.Header().Get(middleware.BudgetRemainingHeader))
+
+	require.NoError(t, tracker.RecordSpend(ctx, userID, &keyID, 8.5))
+	w = serveWithBudget(tracker, key)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get(middleware.BudgetWarningHeader), "monthly")
+	assert.Equal(t, "1.5000", w.Header().Get(middleware.BudgetRemainingHeader))
+
+	require.NoError(t, tracker.RecordSpend(ctx, userID, &keyID, 1.5))
+	w = serveWithBudget(tracker, key)
+	require.Equal(t, http.StatusPaymentRequired, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "BUDGET_EXCEEDED", body["code"])
+	budget := body["budget"].(map[string]interface{})
+	assert.Equal(t, security.BudgetScopeAPIKey, budget["scope"])
+	assert.Equal(t, 10.0, budget["spent_usd"])
+	assert.NotEmpty(t, budget["resets_at"])
+
+	w = serveWithBudget(tracker, &storage.APIKey{ID: otherKeyID, UserID: userID})
+	assert.Equal(t, http.StatusOK, w.Code, "another key of the user has its own budget")
+}
+
+func TestBudgetMiddleware_UserBudgetCoversAllKeys(t *testing.T) {
+	userID, keyA, keyB := uuid.New(), uuid.New(), uuid.New()
+	tracker := security.NewBudgetTracker(&memorySpendStore{totals: map[string]float64{}}, staticBudgets{
+		{UserID: userID, Period: storage.BudgetPeriodLifetime, HardLimitUSD: usd(5)},
+	})
+	ctx := context.Background()
+
+	require.NoError(t, tracker.RecordSpend(ctx, userID, &keyA, 3))
+	require.NoError(t, tracker.RecordSpend(ctx, userID, &keyB, 2))
+
+	statuses, err := tracker.Check(ctx, userID, &keyA)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, 5.0, statuses[0].SpentUSD)
+	assert.Nil(t, statuses[0].ResetsAt, "lifetime budgets never reset")
+
+	w := serveWithBudget(tracker, &storage.APIKey{ID: keyB, UserID: userID})
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+}
+
+func TestBudgetMiddleware_JWTUser(t *testing.T) {
+	userID := uuid.New()
+	tracker := security.NewBudgetTracker(&memorySpendStore{totals: map[string]float64{}}, staticBudgets{
+		{UserID: userID, Period: storage.BudgetPeriodMonthly, HardLimitUSD: usd(1)},
+	})
+	assert.Equal(t, http.StatusOK, serveBudgetRequest(tracker, "user_id", userID.String()).Code)
+
+	require.NoError(t, tracker.RecordSpend(context.Background(), userID, nil, 1))
+	assert.Equal(t, http.StatusPaymentRequired, serveBudgetRequest(tracker, "user_id", userID.String()).Code)
+}
+
+func TestBudgetMiddleware_AllowsRequestsWhenSpendIsUnavailable(t *testing.T) {
+	userID := uuid.New()
+	tracker := security.NewBudgetTracker(failingSpendStore{}, staticBudgets{
+		{UserID: userID, Period: storage.BudgetPeriodMonthly, HardLimitUSD: usd(1)},
+	})
+	w := serveWithBudget(tracker, &storage.APIKey{ID: uuid.New(), UserID: userID})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBudgetTracker_PeriodsAreSeparate(t *testing.T) {
+	userID := uuid.New()
+	store := &memorySpendStore{totals: map[string]float64{}}
+	tracker := security.NewBudgetTracker(store, staticBudgets{
+		{UserID: userID, Period: storage.BudgetPeriodDaily, HardLimitUSD: usd(1)},
+		{UserID: userID, Period: storage.BudgetPeriodMonthly, HardLimitUSD: usd(100)},
+	})
+	ctx := context.Background()
+
+	require.NoError(t, tracker.RecordSpend(ctx, userID, nil, 0.25))
+	assert.Len(t, store.totals, 3, "one counter per period for the user")
+
+	statuses, err := tracker.Check(ctx, userID, nil)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	for _, status := range statuses {
+		assert.Equal(t, 0.25, status.SpentUSD)
+		require.NotNil(t, status.ResetsAt)
+		assert.True(t, status.ResetsAt.After(time.Now()))
+	}
+	now := time.Now().UTC()
+	assert.Equal(t, time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC), *statuses[0].ResetsAt)
+	assert.Equal(t, 0.75, *statuses[0].RemainingUSD)
+}
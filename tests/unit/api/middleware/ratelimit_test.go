@@ -0,0 +1,188 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/api/middleware"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/security"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCounterStore struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newMemoryCounterStore() *memoryCounterStore {
+	return &memoryCounterStore{counts: map[string]int64{}}
+}
+
+func (s *memoryCounterStore) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[key] += delta
+	return s.counts[key], nil
+}
+
+func (s *memoryCounterStore) Get(ctx context.Context, keys []string) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make([]int64, len(keys))
+	for i, key := range keys {
+		counts[i] = s.counts[key]
+	}
+	return counts, nil
+}
+
+// total sums the counters whose key contains substr.
+func (s *memoryCounterStore) total(substr string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total int64
+	for key, count := range s.counts {
+		if strings.Contains(key, substr) {
+			total += count
+		}
+	}
+	return total
+}
+
+func TestRateLimitMiddleware_Headers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := security.NewRateLimiterWithStore(newMemoryCounterStore())
+	r := gin.New()
+	r.Use(middleware.RateLimitMiddleware(limiter, func(string) (int, int) { return 2, 100 }))
+	r.GET("/v1/models", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+		return w
+	}
+
+	w := do()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining-Requests"))
+	assert.Equal(t, "99", w.Header().Get("X-RateLimit-Remaining-PerDay"))
+	assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
+
+	require.Equal(t, http.StatusOK, do().Code)
+	w = do()
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.True(t, retryAfter >= 1 && retryAfter <= 120, "Retry-After %d", retryAfter)
+	assert.Equal(t, "98", w.Header().Get("X-RateLimit-Remaining-PerDay"), "rejected requests are not counted")
+}
+
+func TestTokenRateLimitMiddleware_ReservesAndReconciles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryCounterStore()
+	limiter := security.NewRateLimiterWithStore(store)
+	key := &storage.APIKey{ID: uuid.New(), UserID: uuid.New(), TokenLimitPerMinute: 1000}
+
+	used := 100
+	fail := false
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("api_key_record", key)
+		c.Next()
+	})
+	r.Use(middleware.TokenRateLimitMiddleware(limiter, func(*gin.Context) int { return 600 }, security.TokenLimits{}))
+	r.POST("/v1/chat", func(c *gin.Context) {
+		if fail {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		security.ReportTokenUsage(c.Request.Context(), used)
+		c.Status(http.StatusOK)
+	})
+	do := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat", nil))
+		return w
+	}
+
+	w := do()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1000", w.Header().Get("X-RateLimit-Limit-Tokens"))
+	assert.Equal(t, "400", w.Header().Get("X-RateLimit-Remaining-Tokens"))
+	assert.Equal(t, int64(100), store.total("tokens:key:"), "the estimate is replaced by the reported usage")
+
+	fail = true
+	require.Equal(t, http.StatusBadGateway, do().Code)
+	assert.Equal(t, int64(100), store.total("tokens:key:"), "failed requests give their estimate back")
+
+	fail = false
+	require.Equal(t, http.StatusOK, do().Code)
+	w = do()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(300), store.total("tokens:key:"))
+
+	used = 700
+	require.Equal(t, http.StatusOK, do().Code)
+	w = do()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "1000 tokens used, no room for another 600")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining-Tokens"))
+}
+
+func TestTokenRateLimitMiddleware_UserLimitSpansKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := security.NewRateLimiterWithStore(newMemoryCounterStore())
+	userID := uuid.New()
+
+	serve := func(key *storage.APIKey) int {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("api_key_record", key)
+			c.Next()
+		})
+		r.Use(middleware.TokenRateLimitMiddleware(limiter, func(*gin.Context) int { return 400 }, security.TokenLimits{PerDay: 1000}))
+		r.POST("/v1/chat", func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(&storage.APIKey{ID: uuid.New(), UserID: userID}))
+	assert.Equal(t, http.StatusOK, serve(&storage.APIKey{ID: uuid.New(), UserID: userID}))
+	assert.Equal(t, http.StatusTooManyRequests, serve(&storage.APIKey{ID: uuid.New(), UserID: userID}))
+	assert.Equal(t, http.StatusOK, serve(&storage.APIKey{ID: uuid.New(), UserID: uuid.New()}), "other users are not affected")
+}
+
+func TestRateLimiter_AdjustTakenBucket(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryCounterStore()
+	limiter := security.NewRateLimiterWithStore(store)
+	limits := []security.RateLimit{{Window: time.Minute, Limit: 1000}}
+
+	allowed, _, err := limiter.Take(ctx, "tokens:a", limits, 500)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.NoError(t, limiter.Adjust(ctx, "tokens:a", limits, -800, time.Now()))
+	assert.Equal(t, int64(0), store.total("tokens:a:"), "counters do not go below 0")
+
+	require.NoError(t, limiter.Adjust(ctx, "tokens:b", limits, 100, time.Now().Add(-time.Minute)))
+	windows, err := limiter.Peek(ctx, "tokens:b", limits)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), store.total("tokens:b:"))
+	assert.Less(t, windows[0].Remaining, int64(1000), "the previous bucket still counts, weighted")
+	assert.GreaterOrEqual(t, windows[0].Remaining, int64(900), "the current bucket is untouched")
+
+	require.NoError(t, limiter.Adjust(ctx, "tokens:b", limits, -50, time.Now().Add(-3*time.Minute)))
+	assert.Equal(t, int64(100), store.total("tokens:b:"), "buckets out of their window are left alone")
+}
@@ -0,0 +1,40 @@
+package gateway_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type metadataProviderKeys map[string][2]string
+
+func (k metadataProviderKeys) GetProviderKey(ctx context.Context, userID uuid.UUID, provider string) (string, error) {
+	return k[provider][0], nil
+}
+
+func (k metadataProviderKeys) GetProviderKeyWithMetadata(ctx context.Context, userID uuid.UUID, provider string) (string, []byte, error) {
+	return k[provider][0], []byte(k[provider][1]), nil
+}
+
+func TestRouter_AzureBYOK(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "local", available: true, models: []string{"llama3"}})
+	router.SetProviderKeyService(metadataProviderKeys{
+		"azure": {"user-azure-key", `{"endpoint":"https://contoso.openai.azure.com","deployments":{"gpt-4o":"team-gpt4o"}}`},
+	})
+	userID := uuid.New()
+
+	explanation := router.Explain(context.Background(), providers.ChatRequest{Model: "gpt-4o"}, &userID)
+	azure := findProvider(t, explanation, "azure")
+	assert.Equal(t, gateway.ProviderSourceBYOK, azure.Source)
+	assert.True(t, azure.SupportsModel, "models come from the stored deployments")
+	assert.Equal(t, "azure", explanation.Selected)
+
+	router.SetProviderKeyService(metadataProviderKeys{"azure": {"user-azure-key", ""}})
+	explanation = router.Explain(context.Background(), providers.ChatRequest{Model: "gpt-4o"}, &userID)
+	assert.NotEqual(t, gateway.ProviderSourceBYOK, findProvider(t, explanation, "azure").Source, "keys without an endpoint are not usable")
+}
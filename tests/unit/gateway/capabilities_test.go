@@ -0,0 +1,90 @@
+package gateway_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func imageRequest(model string) providers.ChatRequest {
+	return providers.ChatRequest{
+		Model: model,
+		Messages: []providers.Message{{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "What is in this picture?"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/png;base64,iVBORw0KGgo="}},
+		}}},
+	}
+}
+
+func TestRouter_Capabilities_ImagesOnlyToVisionModels(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "text-only", available: true, models: []string{"shared"}})
+	router.RegisterProvider(&mockProvider{name: "seeing", available: true, models: []string{"shared"}})
+	router.GetCapabilities().Set("text-only", map[string]providers.Capabilities{"shared": {}})
+	router.GetCapabilities().Set("seeing", map[string]providers.Capabilities{"shared": {Vision: true}})
+
+	resp, err := router.Route(context.Background(), imageRequest("shared"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "seeing", resp.Provider)
+
+	resp, err = router.Route(context.Background(), promptRequest("hello"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "text-only", resp.Provider, "text requests keep the strategy's choice")
+}
+
+func TestRouter_Capabilities_NoCapableProvider(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "local", available: true, models: []string{"llama3:8b"}})
+
+	_, err := router.Route(context.Background(), imageRequest("llama3:8b"), nil)
+	require.Error(t, err)
+	assert.Equal(t, "no available provider supports image input for model llama3:8b", err.Error())
+
+	explanation := router.Explain(context.Background(), imageRequest("llama3:8b"), nil)
+	assert.Equal(t, err.Error(), explanation.Error)
+	require.Len(t, explanation.Providers, 5, "local plus the BYOK providers without keys")
+	assert.Equal(t, "does not support image input", explanation.Providers[0].Excluded)
+}
+
+func TestModelBasedStrategy_ModelFamilies(t *testing.T) {
+	strategy := &gateway.ModelBasedStrategy{}
+	available := []providers.Provider{
+		&mockProvider{name: "openai", models: []string{"gpt-4o"}},
+		&mockProvider{name: "anthropic", models: []string{"claude-sonnet-4-6"}},
+		&mockProvider{name: "local", models: []string{"llama3.2:latest"}},
+		&mockProvider{name: "vllm", models: []string{"mistralai/Mistral-7B-Instruct-v0.2"}},
+	}
+
+	for model, want := range map[string]string{
+		"claude-3-5-haiku": "anthropic",
+		"llama3.2":         "local",
+		"mistral:7b":       "local",
+		"custom:7b":        "local",
+		"deepseek-chat":    "openai",
+		"myorg/finetune":   "vllm",
+		"unknown-model":    "openai",
+	} {
+		selected, err := strategy.SelectProvider(context.Background(), providers.ChatRequest{Model: model}, available)
+		require.NoError(t, err)
+		assert.Equal(t, want, selected.Name(), model)
+	}
+}
+
+func TestRouter_ListProviderDetails_IncludesCapabilities(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&toolMockProvider{mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}})
+
+	details := router.ListProviderDetailsForUser(context.Background(), nil)
+	require.Len(t, details, 1)
+	capabilities, ok := details[0]["capabilities"].(map[string]providers.Capabilities)
+	require.True(t, ok)
+	caps := capabilities["gpt-4o"]
+	assert.Equal(t, 128000, caps.ContextLength)
+	assert.True(t, caps.Vision)
+	assert.True(t, caps.Tools)
+	assert.False(t, caps.Streaming, "the mock does not stream")
+}
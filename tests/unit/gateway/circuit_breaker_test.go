@@ -0,0 +1,197 @@
+package gateway_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUpstream = errors.New("upstream 503")
+
+func testBreakerConfig() gateway.CircuitBreakerConfig {
+	return gateway.CircuitBreakerConfig{
+		FailureThreshold:   3,
+		ErrorRateThreshold: 0.5,
+		WindowSize:         10,
+		MinRequests:        6,
+		OpenTimeout:        20 * time.Millisecond,
+		HalfOpenProbes:     1,
+	}
+}
+
+func TestCircuitBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	b := gateway.NewCircuitBreaker("openai", testBreakerConfig())
+
+	b.RecordFailure(errUpstream)
+	b.RecordFailure(errUpstream)
+	assert.Equal(t, gateway.BreakerClosed, b.State())
+
+	b.RecordFailure(errUpstream)
+	assert.Equal(t, gateway.BreakerOpen, b.State())
+	assert.False(t, b.Allow())
+	assert.False(t, b.Available())
+
+	snap := b.Snapshot()
+	assert.Equal(t, 3, snap.ConsecutiveFailures)
+	assert.Equal(t, "upstream 503", snap.LastError)
+	assert.NotNil(t, snap.OpenedAt)
+}
+
+func TestCircuitBreaker_OpensOnErrorRate(t *testing.T) {
+	b := gateway.NewCircuitBreaker("openai", testBreakerConfig())
+
+	for i := 0; i < 3; i++ {
+		b.RecordSuccess()
+		b.RecordFailure(errUpstream)
+	}
+	assert.Equal(t, gateway.BreakerOpen, b.State())
+}
+
+func TestCircuitBreaker_HalfOpenProbe(t *testing.T) {
+	b := gateway.NewCircuitBreaker("openai", testBreakerConfig())
+	for i := 0; i < 3; i++ {
+		b.RecordFailure(errUpstream)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, gateway.BreakerHalfOpen, b.State())
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow(), "only one probe allowed while half-open")
+
+	b.RecordSuccess()
+	assert.Equal(t, gateway.BreakerClosed, b.State())
+	assert.Equal(t, 0, b.Snapshot().ConsecutiveFailures)
+}
+
+func TestCircuitBreaker_HalfOpenFailureReopens(t *testing.T) {
+	b := gateway.NewCircuitBreaker("openai", testBreakerConfig())
+	for i := 0; i < 3; i++ {
+		b.RecordFailure(errUpstream)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	require.True(t, b.Allow())
+	b.RecordFailure(context.DeadlineExceeded)
+	assert.Equal(t, gateway.BreakerOpen, b.State())
+	assert.Equal(t, int64(1), b.Snapshot().Timeouts)
+}
+
+func TestCircuitBreaker_IgnoresClientCancellation(t *testing.T) {
+	b := gateway.NewCircuitBreaker("openai", testBreakerConfig())
+	for i := 0; i < 5; i++ {
+		b.RecordFailure(context.Canceled)
+	}
+	assert.Equal(t, gateway.BreakerClosed, b.State())
+	assert.Equal(t, 0, b.Snapshot().WindowRequests)
+}
+
+func TestCircuitBreaker_ProbeMovesOpenToHalfOpen(t *testing.T) {
+	config := testBreakerConfig()
+	config.OpenTimeout = time.Hour
+	b := gateway.NewCircuitBreaker("openai", config)
+	for i := 0; i < 3; i++ {
+		b.RecordFailure(errUpstream)
+	}
+	require.Equal(t, gateway.BreakerOpen, b.State())
+
+	b.RecordProbe(nil)
+	assert.Equal(t, gateway.BreakerHalfOpen, b.State())
+	assert.NotNil(t, b.Snapshot().LastProbeAt)
+}
+
+type flakyProvider struct {
+	mockProvider
+	mu      sync.Mutex
+	fail    bool
+	calls   int
+	healthy bool
+}
+
+func (p *flakyProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	fail := p.fail
+	p.mu.Unlock()
+	if fail {
+		return nil, errUpstream
+	}
+	return p.mockProvider.Chat(ctx, req)
+}
+
+func (p *flakyProvider) HealthCheck(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.healthy {
+		return errUpstream
+	}
+	return nil
+}
+
+func (p *flakyProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func TestRouter_Route_SkipsOpenBreaker(t *testing.T) {
+	router := gateway.NewRouter()
+	router.SetCircuitBreakerConfig(testBreakerConfig())
+	flaky := &flakyProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}, fail: true, healthy: true}
+	backup := &mockProvider{name: "anthropic", available: true}
+	router.RegisterProvider(flaky)
+	router.RegisterProvider(backup)
+
+	req := providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	for i := 0; i < 3; i++ {
+		resp, err := router.Route(context.Background(), req, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic", resp.Provider)
+	}
+	require.Equal(t, 3, flaky.callCount())
+
+	health, err := router.ProviderHealth("openai")
+	require.NoError(t, err)
+	assert.Equal(t, gateway.BreakerOpen, health.State)
+
+	resp, err := router.Route(context.Background(), req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+	assert.Equal(t, 3, flaky.callCount(), "open breaker must not be called")
+}
+
+func TestRouter_ProviderHealth_UnknownProvider(t *testing.T) {
+	router := gateway.NewRouter()
+	_, err := router.ProviderHealth("missing")
+	assert.Error(t, err)
+}
+
+func TestHealthProber_ProbeOnce(t *testing.T) {
+	router := gateway.NewRouter()
+	config := testBreakerConfig()
+	config.FailureThreshold = 1
+	config.OpenTimeout = time.Hour
+	router.SetCircuitBreakerConfig(config)
+	provider := &flakyProvider{mockProvider: mockProvider{name: "openai", available: true}}
+	router.RegisterProvider(provider)
+
+	prober := gateway.NewHealthProber(router, time.Minute)
+	prober.ProbeOnce(context.Background())
+	health, err := router.ProviderHealth("openai")
+	require.NoError(t, err)
+	assert.Equal(t, gateway.BreakerOpen, health.State)
+
+	provider.mu.Lock()
+	provider.healthy = true
+	provider.mu.Unlock()
+	prober.ProbeOnce(context.Background())
+	health, err = router.ProviderHealth("openai")
+	require.NoError(t, err)
+	assert.Equal(t, gateway.BreakerHalfOpen, health.State)
+}
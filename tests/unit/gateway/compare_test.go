@@ -0,0 +1,79 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compareRouter() *gateway.Router {
+	router := gateway.NewRouter()
+	router.SetRetryPolicy(gateway.RetryPolicy{})
+	router.RegisterProvider(&scriptedProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}})
+	router.RegisterProvider(&scriptedProvider{
+		mockProvider: mockProvider{name: "local", available: true, models: []string{"llama3"}},
+		errs:         []error{providerErr(providers.ErrorKindInvalidRequest, http.StatusBadRequest)},
+	})
+	return router
+}
+
+func TestRouter_Compare_ToleratesPartialFailure(t *testing.T) {
+	router := compareRouter()
+	targets := []gateway.CompareTarget{
+		{Model: "gpt-4o"},
+		{Model: "llama3", Provider: "local"},
+		{Model: "gpt-4o", Provider: "missing"},
+	}
+
+	results := router.Compare(context.Background(), retryReq, targets, nil)
+	require.Len(t, results, 3)
+
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "openai", results[0].Response.Provider)
+	assert.Equal(t, "gpt-4o", results[0].Target.Model)
+
+	assert.Error(t, results[1].Err, "the pinned provider's error is not failed over")
+	assert.Nil(t, results[1].Response)
+
+	require.Error(t, results[2].Err)
+	assert.Contains(t, results[2].Err.Error(), "missing")
+}
+
+func TestRouter_Compare_PinnedProvider(t *testing.T) {
+	router := compareRouter()
+
+	results := router.Compare(context.Background(), retryReq, []gateway.CompareTarget{{Model: "gpt-4o", Provider: "OpenAI"}}, nil)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "openai", results[0].Response.Provider)
+}
+
+func TestRouter_CompareStream(t *testing.T) {
+	router := compareRouter()
+	targets := []gateway.CompareTarget{
+		{Model: "gpt-4o"},
+		{Model: "llama3", Provider: "local"},
+	}
+
+	done := map[int]bool{}
+	errs := map[int]error{}
+	for chunk := range router.CompareStream(context.Background(), retryReq, targets, nil) {
+		if chunk.Err != nil {
+			errs[chunk.Index] = chunk.Err
+			continue
+		}
+		if chunk.Chunk.Done {
+			done[chunk.Index] = true
+		}
+	}
+
+	assert.True(t, done[0])
+	assert.NoError(t, errs[0])
+	assert.False(t, done[1])
+	assert.Error(t, errs[1])
+}
@@ -0,0 +1,190 @@
+package gateway_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySummaryStore struct {
+	mu        sync.Mutex
+	summaries map[uuid.UUID]gateway.ConversationSummary
+}
+
+func (s *memorySummaryStore) GetConversationSummary(ctx context.Context, conversationID, userID uuid.UUID) (*gateway.ConversationSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary, ok := s.summaries[conversationID]
+	if !ok {
+		return nil, nil
+	}
+	return &summary, nil
+}
+
+func (s *memorySummaryStore) SaveConversationSummary(ctx context.Context, conversationID, userID uuid.UUID, summary gateway.ConversationSummary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries[conversationID] = summary
+	return nil
+}
+
+func (s *memorySummaryStore) get(conversationID uuid.UUID) (gateway.ConversationSummary, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary, ok := s.summaries[conversationID]
+	return summary, ok
+}
+
+func contextRouter(strategy string, outputs ...string) (*gateway.Router, *outputProvider) {
+	provider := &outputProvider{
+		mockProvider: mockProvider{name: "openai", available: true, models: []string{"tiny", "cheap"}},
+		outputs:      outputs,
+	}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+	router.SetContextConfig(gateway.ContextConfig{
+		Strategy:     strategy,
+		SummaryModel: "cheap",
+		Windows:      map[string]int{"tiny": 400},
+	})
+	return router, provider
+}
+
+// longConversation is a system message and turns user/assistant messages of about 50
+// tokens each, ending with a user message, for a model with a 400 token window.
+func longConversation(turns int) providers.ChatRequest {
+	messages := []providers.Message{{Role: "system", Content: "Be brief."}}
+	for i := 0; i < turns; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, providers.Message{Role: role, Content: fmt.Sprintf("turn %d: %s", i, strings.Repeat("word ", 45))})
+	}
+	return providers.ChatRequest{Model: "tiny", Messages: messages, MaxTokens: 100}
+}
+
+func TestRouter_ContextWindow_FittingRequestUnchanged(t *testing.T) {
+	router, provider := contextRouter(gateway.ContextStrategyTruncate, "ok")
+	req := longConversation(3)
+	_, err := router.Route(context.Background(), req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, req.Messages, provider.received()[0].Messages)
+}
+
+func TestRouter_ContextWindow_Reject(t *testing.T) {
+	router, provider := contextRouter(gateway.ContextStrategyReject, "ok")
+	_, err := router.Route(context.Background(), longConversation(11), nil)
+	require.Error(t, err)
+
+	perr, ok := providers.AsProviderError(err)
+	require.True(t, ok)
+	assert.Equal(t, providers.ErrorKindContextLength, perr.Kind)
+	assert.Contains(t, err.Error(), "context window of 400 tokens")
+	assert.Empty(t, provider.received(), "rejected before any provider is called")
+}
+
+func TestRouter_ContextWindow_AliasUsesSmallestTargetWindow(t *testing.T) {
+	router, provider := contextRouter(gateway.ContextStrategyReject, "ok")
+	router.SetModelAliasService(&staticAliasService{aliases: map[string]gateway.ModelAlias{
+		"smart": {Name: "smart", Targets: []gateway.AliasTarget{{Provider: "openai", Model: "cheap"}, {Provider: "openai", Model: "tiny"}}},
+	}})
+	req := longConversation(11)
+	req.Model = "smart"
+
+	_, err := router.Route(context.Background(), req, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model tiny has a context window of 400 tokens")
+	assert.Empty(t, provider.received())
+}
+
+func TestRouter_ContextWindow_TruncateKeepsSystemAndLatestTurns(t *testing.T) {
+	router, provider := contextRouter(gateway.ContextStrategyTruncate, "ok")
+	req := longConversation(11)
+	_, err := router.Route(context.Background(), req, nil)
+	require.NoError(t, err)
+
+	sent := provider.received()[0]
+	require.Less(t, len(sent.Messages), len(req.Messages))
+	assert.Equal(t, req.Messages[0], sent.Messages[0], "system message kept")
+	assert.Equal(t, req.Messages[len(req.Messages)-1], sent.Messages[len(sent.Messages)-1], "latest turn kept")
+	assert.Equal(t, req.Messages[len(req.Messages)-len(sent.Messages)+1:], sent.Messages[1:], "oldest turns dropped first")
+
+	window, _ := router.ModelContextWindow("tiny")
+	prompt := gateway.CountPromptTokens(router.GetTokenizers().ForModel("tiny"), sent).Total
+	assert.LessOrEqual(t, prompt+sent.MaxTokens, window)
+}
+
+func TestRouter_ContextWindow_TruncateDropsToolResultsWithTheirCall(t *testing.T) {
+	router, provider := contextRouter(gateway.ContextStrategyTruncate, "ok")
+	req := longConversation(11)
+	call := providers.Message{Role: "assistant", Content: "", ToolCalls: []providers.ToolCall{{ID: "call_1", Type: "function", Function: providers.ToolCallFunction{Name: "lookup", Arguments: `{"q":"x"}`}}}}
+	result := providers.Message{Role: "tool", ToolCallID: "call_1", Content: strings.Repeat("result ", 60)}
+	req.Messages = append(req.Messages[:2], append([]providers.Message{call, result}, req.Messages[2:]...)...)
+
+	_, err := router.Route(context.Background(), req, nil)
+	require.NoError(t, err)
+	for _, msg := range provider.received()[0].Messages {
+		assert.NotEqual(t, "tool", msg.Role, "tool result must not outlive its call")
+	}
+}
+
+func TestRouter_ContextWindow_SummarizeStoresAndReusesSummary(t *testing.T) {
+	router, provider := contextRouter(gateway.ContextStrategySummarize, "They discussed words.", "ok")
+	store := &memorySummaryStore{summaries: make(map[uuid.UUID]gateway.ConversationSummary)}
+	router.SetConversationSummaryStore(store)
+	conversationID, userID := uuid.New(), uuid.New()
+	ctx := gateway.WithContextPolicy(context.Background(), gateway.ContextPolicy{ConversationID: &conversationID})
+
+	req := longConversation(11)
+	_, err := router.Route(ctx, req, &userID)
+	require.NoError(t, err)
+
+	received := provider.received()
+	require.Len(t, received, 2)
+	assert.Equal(t, "cheap", received[0].Model, "summary written by the summary model")
+	assert.Contains(t, received[0].Messages[1].Content, "turn 0:")
+	sent := received[1].Messages
+	assert.Equal(t, "system", sent[1].Role)
+	assert.Equal(t, "Summary of the earlier conversation:\nThey discussed words.", sent[1].Content)
+	assert.Equal(t, req.Messages[len(req.Messages)-1], sent[len(sent)-1])
+
+	var stored gateway.ConversationSummary
+	require.Eventually(t, func() bool {
+		var ok bool
+		stored, ok = store.get(conversationID)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, len(req.Messages)-len(sent)+1, stored.MessageCount)
+
+	// The next turn reuses the stored summary instead of writing a new one.
+	req.Messages = append(req.Messages, providers.Message{Role: "assistant", Content: "ok"}, providers.Message{Role: "user", Content: "and then?"})
+	_, err = router.Route(ctx, req, &userID)
+	require.NoError(t, err)
+	received = provider.received()
+	require.Len(t, received, 3)
+	assert.Equal(t, "tiny", received[2].Model)
+	assert.Equal(t, "Summary of the earlier conversation:\nThey discussed words.", received[2].Messages[1].Content)
+}
+
+func TestRouter_ContextWindow_SummarizeFallsBackToTruncateWithoutSummaryModel(t *testing.T) {
+	router, provider := contextRouter(gateway.ContextStrategySummarize, "ok")
+	router.SetContextConfig(gateway.ContextConfig{Strategy: gateway.ContextStrategySummarize, Windows: map[string]int{"tiny": 400}})
+	_, err := router.Route(context.Background(), longConversation(11), nil)
+	require.NoError(t, err)
+
+	received := provider.received()
+	require.Len(t, received, 1)
+	for _, msg := range received[0].Messages[1:] {
+		assert.NotEqual(t, "system", msg.Role)
+	}
+}
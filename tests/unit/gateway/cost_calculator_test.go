@@ -22,13 +22,13 @@ func TestCostCalculator_EstimateCost(t *testing.T) {
 	}
 
 	// Test local (should be free)
-	cost := calculator.EstimateCost("local", "llama2", messages)
+	cost := calculator.EstimateCost("local", providers.ChatRequest{Model: "llama2", Messages: messages})
 	if cost != 0.0 {
 		t.Errorf("Expected local cost to be 0, got %f", cost)
 	}
 
 	// Test OpenAI (should have cost)
-	cost = calculator.EstimateCost("openai", "gpt-4", messages)
+	cost = calculator.EstimateCost("openai", providers.ChatRequest{Model: "gpt-4", Messages: messages})
 	if cost <= 0 {
 		t.Error("Expected OpenAI cost to be > 0")
 	}
@@ -56,6 +56,24 @@ func TestCostCalculator_CalculateActualCost(t *testing.T) {
 	}
 }
 
+func TestCostCalculator_ActualCost_NotRounded(t *testing.T) {
+	calculator := gateway.NewCostCalculator()
+
+	cost, err := calculator.ActualCost("openai", "gpt-4", providers.Usage{PromptTokens: 1, TotalTokens: 1})
+	if err != nil {
+		t.Fatalf("ActualCost failed: %v", err)
+	}
+	if cost <= 0 {
+		t.Errorf("Expected a single token to cost more than 0, got %g", cost)
+	}
+	if rounded := gateway.RoundCost(cost); rounded != 0 {
+		t.Errorf("Expected the displayed cost to round to 0, got %g", rounded)
+	}
+	if rounded := gateway.RoundCost(0.123456); rounded != 0.1235 {
+		t.Errorf("Expected 0.1235, got %g", rounded)
+	}
+}
+
 func TestCostCalculator_GetPricing(t *testing.T) {
 	calculator := gateway.NewCostCalculator()
 	
@@ -0,0 +1,68 @@
+package gateway_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_DisabledProvidersAreNotRouted(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "local", available: true, models: []string{"llama3"}})
+	router.RegisterProvider(&mockProvider{name: "groq", available: true, models: []string{"llama3"}})
+
+	require.NoError(t, router.SetProviderEnabled("local", false))
+	resp, err := router.Route(context.Background(), providers.ChatRequest{Model: "llama3", Messages: []providers.Message{{Role: "user", Content: "hi"}}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "groq", resp.Provider)
+	assert.Equal(t, []string{"local"}, router.DisabledProviders())
+
+	explanation := router.Explain(context.Background(), providers.ChatRequest{Model: "llama3"}, nil)
+	assert.Equal(t, "disabled by an admin", findProvider(t, explanation, "local").Excluded)
+
+	require.NoError(t, router.SetProviderEnabled("local", true))
+	assert.Empty(t, router.DisabledProviders())
+	assert.Error(t, router.SetProviderEnabled("missing", false), "unknown providers cannot be toggled")
+}
+
+func TestRouter_CompatibleBYOK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user-groq-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `{"id":"c1","model":"llama-3.3-70b-versatile","choices":[{"message":{"role":"assistant","content":"Hi"}}]}`)
+	}))
+	defer server.Close()
+
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "local", available: true, models: []string{"llama3"}})
+	router.SetCompatibleBYOK([]providers.OpenAICompatibleConfig{{
+		Name:    "groq",
+		BaseURL: server.URL,
+		Models:  []string{"llama-3.3-70b-versatile"},
+		BYOK:    true,
+	}})
+	router.SetProviderKeyService(staticProviderKeys{"groq": "user-groq-key"})
+	userID := uuid.New()
+
+	resp, err := router.Route(context.Background(), providers.ChatRequest{Model: "llama-3.3-70b-versatile", Messages: []providers.Message{{Role: "user", Content: "hi"}}}, &userID)
+	require.NoError(t, err)
+	assert.Equal(t, "groq", resp.Provider)
+
+	statuses := router.ProviderStatuses()
+	assert.Contains(t, statuses, gateway.ProviderStatus{Name: "groq", BYOK: true, Enabled: true})
+
+	require.NoError(t, router.SetProviderEnabled("groq", false))
+	resp, err = router.Route(context.Background(), providers.ChatRequest{Model: "llama-3.3-70b-versatile", Messages: []providers.Message{{Role: "user", Content: "hi"}}}, &userID)
+	require.NoError(t, err)
+	assert.Equal(t, "local", resp.Provider, "disabled BYOK providers are not used")
+}
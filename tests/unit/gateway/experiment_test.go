@@ -0,0 +1,175 @@
+package gateway_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticExperimentService struct {
+	experiments   map[string]*gateway.Experiment
+	invalidations int
+}
+
+func (s *staticExperimentService) Invalidate() {
+	s.invalidations++
+}
+
+func (s *staticExperimentService) ActiveExperiment(ctx context.Context, model string) (*gateway.Experiment, error) {
+	return s.experiments[model], nil
+}
+
+func cheaperModelExperiment() *gateway.Experiment {
+	return &gateway.Experiment{
+		ID:       uuid.New(),
+		Name:     "mini-rollout",
+		Model:    "gpt-4o",
+		StickyBy: gateway.StickyByUser,
+		Variants: []gateway.ExperimentVariant{
+			{Name: "control", Model: "gpt-4o", Weight: 90},
+			{Name: "mini", Model: "gpt-4o-mini", Weight: 10},
+		},
+	}
+}
+
+func TestExperiment_AssignVariant_Weights(t *testing.T) {
+	exp := cheaperModelExperiment()
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		variant, ok := exp.AssignVariant(fmt.Sprintf("user-%d", i))
+		require.True(t, ok)
+		counts[variant.Name]++
+	}
+	assert.InDelta(t, 1000, counts["mini"], 150)
+	assert.Equal(t, 10000, counts["control"]+counts["mini"])
+}
+
+func TestExperiment_AssignVariant_Sticky(t *testing.T) {
+	exp := cheaperModelExperiment()
+	first, ok := exp.AssignVariant("user-42")
+	require.True(t, ok)
+	for i := 0; i < 20; i++ {
+		again, _ := exp.AssignVariant("user-42")
+		assert.Equal(t, first.Name, again.Name)
+	}
+}
+
+func TestExperiment_AssignVariant_SkipsZeroWeight(t *testing.T) {
+	exp := cheaperModelExperiment()
+	exp.Variants[1].Weight = 0
+	for i := 0; i < 100; i++ {
+		variant, ok := exp.AssignVariant(fmt.Sprintf("user-%d", i))
+		require.True(t, ok)
+		assert.Equal(t, "control", variant.Name)
+	}
+
+	exp.Variants[0].Weight = 0
+	_, ok := exp.AssignVariant("user-1")
+	assert.False(t, ok)
+}
+
+func TestRouter_AssignExperiment(t *testing.T) {
+	exp := cheaperModelExperiment()
+	exp.Variants[0].Weight = 0
+	router := gateway.NewRouter()
+	router.SetExperimentService(&staticExperimentService{experiments: map[string]*gateway.Experiment{"gpt-4o": exp}})
+
+	req := providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	assigned, assignment := router.AssignExperiment(context.Background(), req, gateway.ExperimentSubject{UserID: "user-1"})
+	require.NotNil(t, assignment)
+	assert.Equal(t, "gpt-4o-mini", assigned.Model)
+	assert.Equal(t, exp.ID, assignment.ExperimentID)
+	assert.Equal(t, "mini", assignment.Variant)
+	assert.Equal(t, "gpt-4o", req.Model, "caller's request must not be modified")
+
+	other := providers.ChatRequest{Model: "claude-3-5-sonnet"}
+	unchanged, assignment := router.AssignExperiment(context.Background(), other, gateway.ExperimentSubject{UserID: "user-1"})
+	assert.Nil(t, assignment)
+	assert.Equal(t, "claude-3-5-sonnet", unchanged.Model)
+}
+
+func TestRouter_AssignExperiment_NoService(t *testing.T) {
+	router := gateway.NewRouter()
+	req := providers.ChatRequest{Model: "gpt-4o"}
+	assigned, assignment := router.AssignExperiment(context.Background(), req, gateway.ExperimentSubject{})
+	assert.Nil(t, assignment)
+	assert.Equal(t, "gpt-4o", assigned.Model)
+}
+
+func TestRouter_InvalidateExperiments(t *testing.T) {
+	service := &staticExperimentService{}
+	router := gateway.NewRouter()
+	router.SetExperimentService(service)
+	router.InvalidateExperiments()
+	assert.Equal(t, 1, service.invalidations)
+
+	gateway.NewRouter().InvalidateExperiments()
+}
+
+func TestRouter_AssignExperiment_StickyByAPIKey(t *testing.T) {
+	exp := cheaperModelExperiment()
+	exp.StickyBy = gateway.StickyByAPIKey
+	exp.Variants[0].Weight = 50
+	exp.Variants[1].Weight = 50
+	router := gateway.NewRouter()
+	router.SetExperimentService(&staticExperimentService{experiments: map[string]*gateway.Experiment{"gpt-4o": exp}})
+
+	req := providers.ChatRequest{Model: "gpt-4o"}
+	_, first := router.AssignExperiment(context.Background(), req, gateway.ExperimentSubject{APIKeyID: "key-1", UserID: "user-1"})
+	require.NotNil(t, first)
+	for i := 0; i < 20; i++ {
+		// Same key, different users: the key decides.
+		_, again := router.AssignExperiment(context.Background(), req, gateway.ExperimentSubject{APIKeyID: "key-1", UserID: fmt.Sprintf("user-%d", i)})
+		require.NotNil(t, again)
+		assert.Equal(t, first.Variant, again.Variant)
+	}
+}
+
+func TestLoadBalancedStrategy_RoundRobinConcurrent(t *testing.T) {
+	strategy := gateway.NewLoadBalancedStrategy()
+	available := []providers.Provider{
+		&mockProvider{name: "openai", available: true},
+		&mockProvider{name: "anthropic", available: true},
+	}
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := strategy.SelectProvider(context.Background(), providers.ChatRequest{Model: "unknown"}, available)
+			require.NoError(t, err)
+			mu.Lock()
+			counts[p.Name()]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50, counts["openai"])
+	assert.Equal(t, 50, counts["anthropic"])
+}
+
+func TestRouter_LoadBalancedAcrossRequests(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "openai", available: true})
+	router.RegisterProvider(&mockProvider{name: "anthropic", available: true})
+	router.SetStrategyType(gateway.StrategyLoadBalanced)
+
+	req := providers.ChatRequest{Model: "test-model", Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		resp, err := router.Route(context.Background(), req, nil)
+		require.NoError(t, err)
+		seen[resp.Provider] = true
+	}
+	assert.Len(t, seen, 2)
+}
@@ -0,0 +1,139 @@
+package gateway_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProviderKeys map[string]string
+
+func (k staticProviderKeys) GetProviderKey(ctx context.Context, userID uuid.UUID, provider string) (string, error) {
+	return k[provider], nil
+}
+
+type staticStrategies struct {
+	locked       bool
+	userStrategy string
+}
+
+func (s staticStrategies) GetDefaultRoutingStrategy(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func (s staticStrategies) IsRoutingStrategyLocked(ctx context.Context) (bool, error) {
+	return s.locked, nil
+}
+
+func (s staticStrategies) GetUserRoutingStrategy(ctx context.Context, userID uuid.UUID) (string, error) {
+	return s.userStrategy, nil
+}
+
+func findProvider(t *testing.T, explanation *gateway.RoutingExplanation, name string) gateway.ProviderExplanation {
+	t.Helper()
+	for _, p := range explanation.Providers {
+		if p.Name == name {
+			return p
+		}
+	}
+	t.Fatalf("provider %s not in explanation", name)
+	return gateway.ProviderExplanation{}
+}
+
+func TestRouter_Explain_ProvidersAndFailoverOrder(t *testing.T) {
+	router := gateway.NewRouter()
+	config := testBreakerConfig()
+	config.FailureThreshold = 1
+	config.OpenTimeout = time.Hour
+	router.SetCircuitBreakerConfig(config)
+	local := &flakyProvider{mockProvider: mockProvider{name: "local", available: true, models: []string{"llama3"}}, healthy: true}
+	anthropic := &flakyProvider{mockProvider: mockProvider{name: "anthropic", available: true, models: []string{"claude-3-5-sonnet-20241022"}}, healthy: true}
+	openai := &flakyProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}}
+	router.RegisterProvider(local)
+	router.RegisterProvider(anthropic)
+	router.RegisterProvider(openai)
+	gateway.NewHealthProber(router, time.Minute).ProbeOnce(context.Background())
+	router.GetLatencyTracker().RecordLatency("anthropic", 300*time.Millisecond)
+
+	req := providers.ChatRequest{Model: "claude-3-5-sonnet-20241022", MaxTokens: 100, Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	explanation := router.Explain(context.Background(), req, nil)
+
+	assert.Empty(t, explanation.Error)
+	assert.Equal(t, gateway.StrategyModelBased, explanation.Strategy)
+	assert.Equal(t, gateway.StrategySourceDefault, explanation.StrategySource)
+	assert.Equal(t, "anthropic", explanation.Selected)
+
+	require.Len(t, explanation.FailoverOrder, 2, "the open breaker keeps openai out")
+	primary := explanation.FailoverOrder[0]
+	assert.Equal(t, "anthropic", primary.Provider)
+	assert.Equal(t, gateway.ProviderSourceServer, primary.Source)
+	expectedCost, err := router.GetCostCalculator().Estimate("anthropic", req)
+	require.NoError(t, err)
+	require.NotNil(t, primary.EstimatedCostUSD)
+	assert.InDelta(t, expectedCost, *primary.EstimatedCostUSD, 1e-12)
+	require.NotNil(t, primary.AverageLatencyMs)
+	assert.Equal(t, int64(300), *primary.AverageLatencyMs)
+	assert.Equal(t, "local", explanation.FailoverOrder[1].Provider)
+	assert.Nil(t, explanation.FailoverOrder[1].AverageLatencyMs)
+
+	openaiExplained := findProvider(t, explanation, "openai")
+	assert.False(t, openaiExplained.Available)
+	assert.Equal(t, gateway.BreakerOpen, openaiExplained.Health)
+	assert.Equal(t, "circuit breaker open", openaiExplained.Excluded)
+	assert.True(t, findProvider(t, explanation, "anthropic").SupportsModel)
+	assert.Equal(t, "no server API key configured", findProvider(t, explanation, "google").Excluded)
+
+	assert.Zero(t, anthropic.callCount()+local.callCount()+openai.callCount(), "explain never calls a provider")
+}
+
+func TestRouter_Explain_BYOKAndUserStrategy(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}})
+	router.RegisterProvider(&mockProvider{name: "local", available: true, models: []string{"gpt-4o"}})
+	router.SetProviderKeyService(staticProviderKeys{"google": "user-key"})
+	router.SetUserRoutingStrategyService(staticStrategies{userStrategy: string(gateway.StrategyLoadBalanced)})
+	userID := uuid.New()
+
+	req := createTestChatRequest("gpt-4o")
+	first := router.Explain(context.Background(), req, &userID)
+	second := router.Explain(context.Background(), req, &userID)
+
+	assert.Equal(t, gateway.StrategyLoadBalanced, first.Strategy)
+	assert.Equal(t, gateway.StrategySourceUser, first.StrategySource)
+	assert.Equal(t, first.Selected, second.Selected, "explaining does not advance the rotation")
+
+	google := findProvider(t, first, "google")
+	assert.True(t, google.Available)
+	assert.Equal(t, gateway.ProviderSourceBYOK, google.Source)
+	assert.Equal(t, "no server API key and no provider key (BYOK) for this user", findProvider(t, first, "anthropic").Excluded)
+	require.Len(t, first.FailoverOrder, 3)
+	assert.Equal(t, first.Selected, first.FailoverOrder[0].Provider)
+}
+
+func TestRouter_Explain_LockedCustomRules(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}})
+	router.RegisterProvider(&mockProvider{name: "anthropic", available: true})
+	router.SetRoutingStrategyService(staticStrategies{locked: true})
+	router.SetUserRoutingStrategyService(staticStrategies{userStrategy: string(gateway.StrategyCostBased)})
+	router.SetCustomStrategy(gateway.NewCustomStrategy(router.GetRuleEngine().BuildRoutingRules([]gateway.CustomRule{
+		{Name: "long-prompts", ConditionType: gateway.ConditionPromptLength, ConditionValue: map[string]interface{}{"min_chars": 1000.0}, ProviderName: "anthropic"},
+		{Name: "default", ConditionType: gateway.ConditionModel, ConditionValue: map[string]interface{}{"model": "gpt-4o"}, ProviderName: "openai"},
+	})))
+	userID := uuid.New()
+
+	explanation := router.Explain(context.Background(), createTestChatRequest("gpt-4o"), &userID)
+	assert.Equal(t, gateway.StrategyCustom, explanation.Strategy)
+	assert.Equal(t, gateway.StrategySourceLocked, explanation.StrategySource, "the lock wins over the user's own strategy")
+	require.Len(t, explanation.Rules, 2)
+	assert.False(t, explanation.Rules[0].Matched)
+	assert.True(t, explanation.Rules[1].Selected)
+	assert.Equal(t, "openai", explanation.Selected)
+	assert.Equal(t, []string{"openai", "anthropic"}, []string{explanation.FailoverOrder[0].Provider, explanation.FailoverOrder[1].Provider})
+}
@@ -0,0 +1,161 @@
+package gateway_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowProvider answers after delay, or fails with err, unless its context is cancelled first.
+type slowProvider struct {
+	mockProvider
+	delay time.Duration
+	err   error
+
+	mu        sync.Mutex
+	calls     int
+	cancelled int
+}
+
+func (p *slowProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		p.mu.Lock()
+		p.cancelled++
+		p.mu.Unlock()
+		return nil, ctx.Err()
+	case <-time.After(p.delay):
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	resp, err := p.mockProvider.Chat(ctx, req)
+	if err == nil {
+		resp.Usage = providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	}
+	return resp, err
+}
+
+func (p *slowProvider) counts() (calls, cancelled int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls, p.cancelled
+}
+
+// hedgingRouter registers primary before backup and gives primary a p95 latency of 20ms.
+func hedgingRouter(primary, backup *slowProvider) *gateway.Router {
+	router := gateway.NewRouter()
+	router.RegisterProvider(primary)
+	router.RegisterProvider(backup)
+	for i := 0; i < 10; i++ {
+		router.GetLatencyTracker().RecordLatency(primary.name, 20*time.Millisecond)
+	}
+	return router
+}
+
+func hedged(percentile float64) context.Context {
+	return gateway.WithHedgePolicy(context.Background(), gateway.HedgePolicy{Percentile: percentile})
+}
+
+func TestRouter_Hedging_BackupWinsAndPrimaryIsCancelled(t *testing.T) {
+	primary := &slowProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}, delay: 2 * time.Second}
+	backup := &slowProvider{mockProvider: mockProvider{name: "local", available: true, models: []string{"gpt-4o"}}, delay: 10 * time.Millisecond}
+	router := hedgingRouter(primary, backup)
+	req := createTestChatRequest("gpt-4o")
+	req.Messages[0].Content = strings.Repeat("Summarise this paragraph. ", 400)
+
+	start := time.Now()
+	resp, err := router.Route(hedged(95), req, nil)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the slow primary is not waited for")
+	assert.Equal(t, "local", resp.Provider)
+
+	require.Len(t, resp.HedgeAttempts, 1)
+	loser := resp.HedgeAttempts[0]
+	assert.Equal(t, "openai", loser.Provider)
+	assert.True(t, loser.Cancelled)
+	assert.True(t, loser.Usage.Estimated)
+	assert.Greater(t, loser.Usage.PromptTokens, 0)
+	assert.Greater(t, loser.Cost, 0.0, "the cancelled call is charged for its prompt")
+	_, cancelled := primary.counts()
+	assert.Equal(t, 1, cancelled)
+
+	_, samples := router.GetLatencyTracker().Percentile("openai", 95)
+	assert.Equal(t, 10, samples, "a cancelled call is not a latency sample")
+}
+
+func TestRouter_Hedging_PrimaryWinsBeforeHedge(t *testing.T) {
+	primary := &slowProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}, delay: time.Millisecond}
+	backup := &slowProvider{mockProvider: mockProvider{name: "local", available: true, models: []string{"gpt-4o"}}}
+	router := hedgingRouter(primary, backup)
+
+	resp, err := router.Route(hedged(95), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.Provider)
+	assert.Empty(t, resp.HedgeAttempts)
+	calls, _ := backup.counts()
+	assert.Zero(t, calls)
+}
+
+func TestRouter_Hedging_PrimaryWinsRace(t *testing.T) {
+	primary := &slowProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}, delay: 60 * time.Millisecond}
+	backup := &slowProvider{mockProvider: mockProvider{name: "local", available: true, models: []string{"gpt-4o"}}, delay: 2 * time.Second}
+	router := hedgingRouter(primary, backup)
+
+	resp, err := router.Route(hedged(95), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.Provider)
+	require.Len(t, resp.HedgeAttempts, 1)
+	assert.Equal(t, "local", resp.HedgeAttempts[0].Provider)
+	assert.True(t, resp.HedgeAttempts[0].Cancelled)
+	calls, cancelled := backup.counts()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, cancelled)
+}
+
+func TestRouter_Hedging_BackupWinsAfterPrimaryFails(t *testing.T) {
+	primary := &slowProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}, delay: 60 * time.Millisecond, err: errUpstream}
+	backup := &slowProvider{mockProvider: mockProvider{name: "local", available: true, models: []string{"gpt-4o"}}, delay: 200 * time.Millisecond}
+	router := hedgingRouter(primary, backup)
+	router.SetRetryPolicy(gateway.RetryPolicy{})
+
+	resp, err := router.Route(hedged(95), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "local", resp.Provider)
+	require.Len(t, resp.HedgeAttempts, 1)
+	assert.False(t, resp.HedgeAttempts[0].Cancelled)
+	assert.Equal(t, errUpstream.Error(), resp.HedgeAttempts[0].Error)
+	assert.Zero(t, resp.HedgeAttempts[0].Cost)
+	calls, _ := backup.counts()
+	assert.Equal(t, 1, calls, "failover does not call the backup again")
+}
+
+func TestRouter_Hedging_RequiresPolicyAndHistory(t *testing.T) {
+	primary := &slowProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}, delay: 100 * time.Millisecond}
+	backup := &slowProvider{mockProvider: mockProvider{name: "local", available: true, models: []string{"gpt-4o"}}}
+
+	router := hedgingRouter(primary, backup)
+	resp, err := router.Route(context.Background(), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.Provider, "requests are not hedged without a policy")
+
+	router = gateway.NewRouter()
+	router.RegisterProvider(primary)
+	router.RegisterProvider(backup)
+	router.GetLatencyTracker().RecordLatency("openai", 20*time.Millisecond)
+	resp, err = router.Route(hedged(95), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.Provider, "one latency sample is too little history to hedge on")
+	calls, _ := backup.counts()
+	assert.Zero(t, calls)
+}
@@ -0,0 +1,231 @@
+package gateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// funcHook is a Hook built from optional functions.
+type funcHook struct {
+	gateway.NopHook
+	name   string
+	before func(req *providers.ChatRequest) error
+	chunk  func(chunk *providers.StreamChunk) error
+	after  func(resp *providers.ChatResponse) error
+
+	mu     sync.Mutex
+	errors []error
+}
+
+func (h *funcHook) Name() string { return h.name }
+
+func (h *funcHook) BeforeRequest(ctx context.Context, req *providers.ChatRequest) error {
+	if h.before == nil {
+		return nil
+	}
+	return h.before(req)
+}
+
+func (h *funcHook) OnChunk(ctx context.Context, req *providers.ChatRequest, chunk *providers.StreamChunk) error {
+	if h.chunk == nil {
+		return nil
+	}
+	return h.chunk(chunk)
+}
+
+func (h *funcHook) AfterResponse(ctx context.Context, req *providers.ChatRequest, resp *providers.ChatResponse) error {
+	if h.after == nil {
+		return nil
+	}
+	return h.after(resp)
+}
+
+func (h *funcHook) OnError(ctx context.Context, req *providers.ChatRequest, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errors = append(h.errors, err)
+}
+
+func hookRouter(hooks ...gateway.Hook) (*gateway.Router, *echoProvider) {
+	provider := &echoProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+	for _, hook := range hooks {
+		router.AddHook(hook)
+	}
+	return router, provider
+}
+
+func promptRequest(prompt string) providers.ChatRequest {
+	return providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: prompt}}}
+}
+
+func TestRouter_Hooks_RewriteAndAnnotate(t *testing.T) {
+	rewrite := &funcHook{name: "rewrite", before: func(req *providers.ChatRequest) error {
+		req.Messages[0].Content = "Be brief. " + req.Messages[0].Content.(string)
+		return nil
+	}}
+	annotate := &funcHook{name: "annotate", after: func(resp *providers.ChatResponse) error {
+		resp.Annotations = map[string]interface{}{"checked": true}
+		return nil
+	}}
+	router, provider := hookRouter(rewrite, annotate)
+
+	resp, err := router.Route(context.Background(), promptRequest("hello"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Be brief. hello", provider.lastPrompt())
+	assert.Equal(t, map[string]interface{}{"checked": true}, resp.Annotations)
+}
+
+func TestRouter_Hooks_BlockRequest(t *testing.T) {
+	guard := &funcHook{name: "guard", before: func(req *providers.ChatRequest) error {
+		return gateway.Block("prompt mentions a competitor")
+	}}
+	router, provider := hookRouter(guard)
+
+	_, err := router.Route(context.Background(), promptRequest("hello"), nil)
+	blocked, ok := gateway.AsHookBlockedError(err)
+	require.True(t, ok)
+	assert.Equal(t, "guard", blocked.Hook)
+	assert.Equal(t, "prompt mentions a competitor", blocked.Reason)
+	assert.Equal(t, "request blocked by guard: prompt mentions a competitor", err.Error())
+	assert.Empty(t, provider.lastPrompt(), "the provider is not called")
+	assert.Len(t, guard.errors, 1, "hooks are told about the rejection")
+}
+
+func TestRouter_Hooks_Stream(t *testing.T) {
+	upper := &funcHook{
+		name: "upper",
+		chunk: func(chunk *providers.StreamChunk) error {
+			chunk.Content = strings.ToUpper(chunk.Content)
+			return nil
+		},
+		after: func(resp *providers.ChatResponse) error {
+			resp.Annotations = map[string]interface{}{"length": len(resp.Choices[0].Message.Content.(string))}
+			return nil
+		},
+	}
+	router, _ := hookRouter(upper)
+
+	chunks, errs := router.RouteStream(context.Background(), promptRequest("hello world"), nil)
+	var content strings.Builder
+	var last providers.StreamChunk
+	for chunk := range chunks {
+		content.WriteString(chunk.Content)
+		last = chunk
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "HELLO WORLD", content.String())
+	assert.True(t, last.Done)
+	assert.Equal(t, map[string]interface{}{"length": 11}, last.Annotations)
+}
+
+func TestRouter_Hooks_StreamBlockedResponse(t *testing.T) {
+	guard := &funcHook{name: "guard", after: func(resp *providers.ChatResponse) error {
+		return gateway.Block("answer leaks a secret")
+	}}
+	router, _ := hookRouter(guard)
+
+	chunks, errs := router.RouteStream(context.Background(), promptRequest("hello"), nil)
+	for chunk := range chunks {
+		assert.False(t, chunk.Done, "the final chunk is withheld")
+	}
+	err := <-errs
+	assert.EqualError(t, err, "response blocked by guard: answer leaks a secret")
+}
+
+func TestParseHookConfig(t *testing.T) {
+	config, err := gateway.ParseHookConfig("hooks.yaml", []byte(`
+webhooks:
+  - name: compliance
+    url: https://policy.example.com/check
+    timeout_ms: 500
+    failure_mode: open
+    events: [before_request, after_response]
+`))
+	require.NoError(t, err)
+	require.Len(t, config.Webhooks, 1)
+	assert.Equal(t, gateway.WebhookFailOpen, config.Webhooks[0].FailureMode)
+
+	_, err = gateway.ParseHookConfig("hooks.json", []byte(`{"webhooks": [{"name": "x", "url": "ftp://policy"}]}`))
+	assert.ErrorContains(t, err, "url")
+	_, err = gateway.ParseHookConfig("hooks.json", []byte(`{"webhooks": [{"name": "x", "url": "http://policy", "failure_mode": "maybe"}]}`))
+	assert.ErrorContains(t, err, "failure_mode")
+	_, err = gateway.ParseHookConfig("hooks.json", []byte(`{"webhooks": [{"name": "x", "url": "http://policy", "events": ["on_chunk"]}]}`))
+	assert.ErrorContains(t, err, "on_chunk")
+}
+
+func TestWebhookHook(t *testing.T) {
+	var received gateway.WebhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		prompt := received.Request.Messages[0].Content.(string)
+		switch {
+		case received.Event == gateway.HookEventAfterResponse:
+			json.NewEncoder(w).Encode(gateway.WebhookResponse{Annotations: map[string]interface{}{"verdict": "clean"}})
+		case strings.Contains(prompt, "forbidden"):
+			json.NewEncoder(w).Encode(gateway.WebhookResponse{Action: "block", Reason: "forbidden topic"})
+		default:
+			rewritten := *received.Request
+			rewritten.Messages = []providers.Message{{Role: "user", Content: "[checked] " + prompt}}
+			json.NewEncoder(w).Encode(gateway.WebhookResponse{Action: "allow", Request: &rewritten})
+		}
+	}))
+	defer server.Close()
+	t.Setenv("POLICY_TOKEN", "secret")
+	hook := gateway.NewWebhookHook(gateway.WebhookHookConfig{
+		Name:    "policy",
+		URL:     server.URL,
+		Events:  []string{gateway.HookEventBeforeRequest, gateway.HookEventAfterResponse},
+		Headers: map[string]string{"Authorization": "Bearer ${POLICY_TOKEN}"},
+	}, zerolog.Nop())
+	router, provider := hookRouter(hook)
+
+	ctx := gateway.WithRequestAttributes(context.Background(), gateway.RequestAttributes{APIKeyID: "key-1"})
+	resp, err := router.Route(ctx, promptRequest("hello"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[checked] hello", provider.lastPrompt())
+	assert.Equal(t, "clean", resp.Annotations["verdict"])
+	assert.Equal(t, "key-1", received.APIKeyID)
+
+	_, err = router.Route(ctx, promptRequest("a forbidden question"), nil)
+	assert.EqualError(t, err, "request blocked by policy: forbidden topic")
+}
+
+func TestWebhookHook_FailureModes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	for _, mode := range []string{gateway.WebhookFailOpen, gateway.WebhookFailClosed} {
+		t.Run(mode, func(t *testing.T) {
+			hook := gateway.NewWebhookHook(gateway.WebhookHookConfig{Name: "slow", URL: server.URL, TimeoutMs: 20, FailureMode: mode}, zerolog.Nop())
+			router, _ := hookRouter(hook)
+
+			start := time.Now()
+			_, err := router.Route(context.Background(), promptRequest("hello"), nil)
+			assert.Less(t, time.Since(start), 150*time.Millisecond, "the timeout applies")
+			if mode == gateway.WebhookFailOpen {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, "request blocked by slow: policy service unavailable")
+			}
+		})
+	}
+}
@@ -84,3 +84,27 @@ func TestLatencyTracker_MaxSamples(t *testing.T) {
 	}
 }
 
+
+func TestLatencyTracker_Percentile(t *testing.T) {
+	tracker := gateway.NewLatencyTracker(100)
+
+	if _, samples := tracker.Percentile("provider1", 95); samples != 0 {
+		t.Errorf("Expected no samples, got %d", samples)
+	}
+
+	for i := 10; i >= 1; i-- {
+		tracker.RecordLatency("provider1", time.Duration(i)*100*time.Millisecond)
+	}
+	cases := map[float64]time.Duration{
+		50: 500 * time.Millisecond,
+		90: 900 * time.Millisecond,
+		95: time.Second,
+		1:  100 * time.Millisecond,
+	}
+	for p, expected := range cases {
+		got, samples := tracker.Percentile("provider1", p)
+		if got != expected || samples != 10 {
+			t.Errorf("p%.0f: expected %v from 10 samples, got %v from %d", p, expected, got, samples)
+		}
+	}
+}
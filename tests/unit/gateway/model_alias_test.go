@@ -0,0 +1,148 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticAliasService struct {
+	aliases       map[string]gateway.ModelAlias
+	invalidations int
+}
+
+func (s *staticAliasService) Invalidate() {
+	s.invalidations++
+}
+
+func (s *staticAliasService) ResolveAlias(ctx context.Context, userID *uuid.UUID, name string) (*gateway.ModelAlias, error) {
+	alias, ok := s.aliases[name]
+	if !ok {
+		return nil, nil
+	}
+	return &alias, nil
+}
+
+func (s *staticAliasService) ListAliases(ctx context.Context, userID *uuid.UUID) ([]gateway.ModelAlias, error) {
+	out := make([]gateway.ModelAlias, 0, len(s.aliases))
+	for _, alias := range s.aliases {
+		out = append(out, alias)
+	}
+	return out, nil
+}
+
+// recordingProvider remembers the requests it received and fails while err is set.
+type recordingProvider struct {
+	scriptedProvider
+	mu       sync.Mutex
+	requests []providers.ChatRequest
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+	return p.scriptedProvider.Chat(ctx, req)
+}
+
+func (p *recordingProvider) ChatStream(ctx context.Context, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+	return p.scriptedProvider.ChatStream(ctx, req)
+}
+
+func newAliasRouter(openaiErrs ...error) (*gateway.Router, *recordingProvider, *recordingProvider) {
+	router := gateway.NewRouter()
+	router.SetRetryPolicy(gateway.RetryPolicy{})
+	openai := &recordingProvider{scriptedProvider: scriptedProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o-mini"}}, errs: openaiErrs}}
+	anthropic := &recordingProvider{scriptedProvider: scriptedProvider{mockProvider: mockProvider{name: "anthropic", available: true, models: []string{"claude-3-5-haiku-20241022"}}}}
+	router.RegisterProvider(openai)
+	router.RegisterProvider(anthropic)
+
+	temp := 0.2
+	maxTokens := 256
+	router.SetModelAliasService(&staticAliasService{aliases: map[string]gateway.ModelAlias{
+		"fast": {Name: "fast", Targets: []gateway.AliasTarget{
+			{Provider: "openai", Model: "gpt-4o-mini", Temperature: &temp},
+			{Provider: "anthropic", Model: "claude-3-5-haiku-20241022", MaxTokens: &maxTokens},
+		}},
+		"offline": {Name: "offline", Targets: []gateway.AliasTarget{{Provider: "local", Model: "llama3.2:latest"}}},
+	}})
+	return router, openai, anthropic
+}
+
+func TestRouter_Route_ResolvesAlias(t *testing.T) {
+	router, openai, anthropic := newAliasRouter()
+
+	resp, err := router.Route(context.Background(), providers.ChatRequest{Model: "fast", Temperature: temperature(0.9), Messages: []providers.Message{{Role: "user", Content: "Hi"}}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.Provider)
+	assert.Equal(t, "gpt-4o-mini", resp.Model)
+	require.Len(t, openai.requests, 1)
+	assert.Equal(t, "gpt-4o-mini", openai.requests[0].Model)
+	assert.Equal(t, 0.2, *openai.requests[0].Temperature)
+	assert.Empty(t, anthropic.requests)
+}
+
+func TestRouter_Route_AliasFallsThroughChain(t *testing.T) {
+	router, _, anthropic := newAliasRouter(providerErr(providers.ErrorKindServer, http.StatusInternalServerError))
+
+	resp, err := router.Route(context.Background(), providers.ChatRequest{Model: "fast", Temperature: temperature(0.9), Messages: []providers.Message{{Role: "user", Content: "Hi"}}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+	require.Len(t, anthropic.requests, 1)
+	assert.Equal(t, "claude-3-5-haiku-20241022", anthropic.requests[0].Model)
+	assert.Equal(t, 256, anthropic.requests[0].MaxTokens)
+	assert.Equal(t, 0.9, *anthropic.requests[0].Temperature, "caller's value kept when the target has no override")
+}
+
+func TestRouter_Route_AliasWithoutAvailableTargets(t *testing.T) {
+	router, _, _ := newAliasRouter()
+
+	_, err := router.Route(context.Background(), providers.ChatRequest{Model: "offline", Messages: []providers.Message{{Role: "user", Content: "Hi"}}}, nil)
+	assert.Error(t, err)
+}
+
+func TestRouter_Route_ConcreteModelBypassesAliases(t *testing.T) {
+	router, openai, _ := newAliasRouter()
+
+	resp, err := router.Route(context.Background(), providers.ChatRequest{Model: "gpt-4o-mini", Temperature: temperature(0.9), Messages: []providers.Message{{Role: "user", Content: "Hi"}}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.Provider)
+	assert.Equal(t, 0.9, *openai.requests[0].Temperature)
+}
+
+func TestRouter_RouteStream_ResolvesAlias(t *testing.T) {
+	router, openai, anthropic := newAliasRouter(providerErr(providers.ErrorKindServer, http.StatusInternalServerError))
+
+	chunks, errs := router.RouteStream(context.Background(), providers.ChatRequest{Model: "fast", Messages: []providers.Message{{Role: "user", Content: "Hi"}}}, nil)
+	_, last := collectStream(t, chunks, errs)
+	assert.Equal(t, "anthropic", last.Provider)
+	assert.Equal(t, "claude-3-5-haiku-20241022", last.Model, "usage is priced with the target model, not the alias")
+	assert.Equal(t, "gpt-4o-mini", openai.requests[0].Model)
+	assert.Equal(t, "claude-3-5-haiku-20241022", anthropic.requests[0].Model)
+}
+
+func TestRouter_ListModelAliases(t *testing.T) {
+	router, _, _ := newAliasRouter()
+	assert.Len(t, router.ListModelAliases(context.Background(), nil), 2)
+	assert.Empty(t, gateway.NewRouter().ListModelAliases(context.Background(), nil))
+}
+
+func TestRouter_InvalidateModelAliases(t *testing.T) {
+	service := &staticAliasService{}
+	router := gateway.NewRouter()
+	router.SetModelAliasService(service)
+	router.InvalidateModelAliases()
+	assert.Equal(t, 1, service.invalidations)
+
+	gateway.NewRouter().InvalidateModelAliases()
+}
@@ -0,0 +1,179 @@
+package gateway_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostCalculator_UnknownPrice(t *testing.T) {
+	calculator := gateway.NewCostCalculator()
+	req := providers.ChatRequest{Model: "gpt-99-ultra", Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+
+	_, ok := calculator.GetPricing("openai", "gpt-99-ultra")
+	assert.False(t, ok, "unknown models must not borrow another model's price")
+
+	_, err := calculator.Estimate("openai", req)
+	assert.True(t, errors.Is(err, gateway.ErrUnknownPrice))
+	assert.Equal(t, gateway.UnknownPriceCost, calculator.EstimateCost("openai", req))
+
+	_, err = calculator.ActualCost("unknown-provider", "model", providers.Usage{PromptTokens: 10})
+	assert.True(t, errors.Is(err, gateway.ErrUnknownPrice))
+	assert.Equal(t, 0.0, calculator.CalculateActualCost("unknown-provider", "model", providers.Usage{PromptTokens: 10}))
+}
+
+func TestCostCalculator_VersionedModelNames(t *testing.T) {
+	calculator := gateway.NewCostCalculator()
+
+	base, ok := calculator.GetPricing("openai", "gpt-4o-mini")
+	require.True(t, ok)
+	dated, ok := calculator.GetPricing("openai", "gpt-4o-mini-2024-07-18")
+	require.True(t, ok)
+	assert.Equal(t, base, dated)
+
+	latest, ok := calculator.GetPricing("anthropic", "claude-3-5-sonnet-latest")
+	require.True(t, ok)
+	assert.Equal(t, 3.0, latest.InputCost)
+
+	gemini, ok := calculator.GetPricing("google", "models/gemini-1.5-pro-002")
+	require.True(t, ok)
+	assert.Equal(t, 1.25, gemini.InputCost)
+
+	local, ok := calculator.GetPricing("local", "any-ollama-model")
+	require.True(t, ok, "the provider's * price covers every model")
+	assert.Equal(t, 0.0, local.InputCost)
+}
+
+func TestCostCalculator_EffectiveDates(t *testing.T) {
+	calculator := gateway.NewCostCalculator()
+
+	before, ok := calculator.Lookup("openai", "gpt-4o", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, 5.0, before.InputCost)
+
+	after, ok := calculator.Lookup("openai", "gpt-4o", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, 2.5, after.InputCost)
+	assert.Equal(t, 1.25, after.CachedInputCost)
+}
+
+func TestPricing_Cost(t *testing.T) {
+	pricing := gateway.Pricing{InputCost: 2.0, OutputCost: 8.0, CachedInputCost: 0.5, BatchInputCost: 1.0, BatchOutputCost: 4.0}
+	usage := providers.Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000, CachedPromptTokens: 500_000}
+
+	assert.InDelta(t, 0.5*2.0+0.5*0.5+8.0, pricing.Cost(usage, false), 1e-9)
+	assert.InDelta(t, 0.5*1.0+0.5*0.5+4.0, pricing.Cost(usage, true), 1e-9)
+
+	noTiers := gateway.Pricing{InputCost: 2.0, OutputCost: 8.0}
+	assert.InDelta(t, 10.0, noTiers.Cost(usage, true), 1e-9, "missing tiers fall back to the regular price")
+}
+
+func TestCostCalculator_ImagePricing(t *testing.T) {
+	calculator := gateway.NewCostCalculator()
+	calculator.UpdatePricing("openai", "vision-per-image", gateway.Pricing{ImageCost: 0.01})
+	req := providers.ChatRequest{Model: "vision-per-image", MaxTokens: 1, Messages: []providers.Message{{Role: "user", Content: []interface{}{
+		map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+		map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/b.png"}},
+	}}}}
+
+	cost, err := calculator.Estimate("openai", req)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.02, cost, 1e-9)
+}
+
+func TestParsePricingCatalog(t *testing.T) {
+	yamlCatalog := `
+version: "2025-01"
+prices:
+  - provider: openai
+    model: gpt-4o
+    input: 2.5
+    output: 10
+    cached_input: 1.25
+    effective_from: 2025-01-01
+`
+	catalog, err := gateway.ParsePricingCatalog("pricing.yaml", []byte(yamlCatalog))
+	require.NoError(t, err)
+	require.Len(t, catalog.Prices, 1)
+	assert.Equal(t, "2025-01", catalog.Version)
+	assert.Equal(t, 1.25, catalog.Prices[0].CachedInputCost)
+	require.NotNil(t, catalog.Prices[0].EffectiveFrom)
+	assert.Equal(t, 2025, catalog.Prices[0].EffectiveFrom.Year())
+
+	jsonCatalog := `{"version":"v2","prices":[{"provider":"anthropic","model":"claude-x","input":1,"output":2,"effective_until":"2026-01-01"}]}`
+	catalog, err = gateway.ParsePricingCatalog("pricing.json", []byte(jsonCatalog))
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, catalog.Prices[0].OutputCost)
+	require.NotNil(t, catalog.Prices[0].EffectiveUntil)
+
+	_, err = gateway.ParsePricingCatalog("bad.json", []byte(`{"prices":[{"provider":"openai","model":"x","input":-1}]}`))
+	assert.Error(t, err)
+	_, err = gateway.ParsePricingCatalog("dup.yaml", []byte("prices:\n  - {provider: openai, model: x}\n  - {provider: openai, model: x}\n"))
+	assert.Error(t, err)
+}
+
+func TestMergePricingCatalogs(t *testing.T) {
+	base := &gateway.PricingCatalog{Version: "base", Prices: []gateway.PriceEntry{
+		{Provider: "openai", Model: "a", Pricing: gateway.Pricing{InputCost: 1}},
+		{Provider: "openai", Model: "b", Pricing: gateway.Pricing{InputCost: 2}},
+	}}
+	override := &gateway.PricingCatalog{Version: "file", Prices: []gateway.PriceEntry{
+		{Provider: "openai", Model: "b", Pricing: gateway.Pricing{InputCost: 3}},
+	}}
+
+	merged := gateway.MergePricingCatalogs(base, override)
+	assert.Equal(t, "base+file", merged.Version)
+	require.Len(t, merged.Prices, 2)
+	assert.Equal(t, 3.0, merged.Prices[1].InputCost)
+}
+
+func TestPricingLoader_HotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("version: v1\nprices:\n  - {provider: openai, model: my-finetune, input: 1, output: 2}\n")
+
+	calculator := gateway.NewCostCalculator()
+	loader := gateway.NewPricingLoader(calculator, time.Minute, zerolog.Nop(), gateway.NewFilePricingSource(path))
+
+	changed, err := loader.Reload(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	pricing, ok := calculator.GetPricing("openai", "my-finetune")
+	require.True(t, ok)
+	assert.Equal(t, 1.0, pricing.InputCost)
+	_, ok = calculator.GetPricing("openai", "gpt-4o")
+	assert.True(t, ok, "built-in prices stay underneath the file")
+
+	changed, err = loader.Reload(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	write("version: v2\nprices:\n  - {provider: openai, model: my-finetune, input: 4, output: 2}\n")
+	changed, err = loader.Reload(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	pricing, _ = calculator.GetPricing("openai", "my-finetune")
+	assert.Equal(t, 4.0, pricing.InputCost)
+
+	write("prices: [{provider: openai, model: my-finetune, input: -4}]\n")
+	_, err = loader.Reload(context.Background())
+	assert.Error(t, err)
+	pricing, _ = calculator.GetPricing("openai", "my-finetune")
+	assert.Equal(t, 4.0, pricing.InputCost, "a broken file keeps the previous catalog")
+
+	status := loader.Status()
+	assert.Equal(t, "builtin-2025-08+v2", status.Version)
+	assert.Equal(t, []string{gateway.PricingSourceBuiltin, gateway.PricingSourceFile}, status.Sources)
+	assert.NotEmpty(t, status.LastError)
+}
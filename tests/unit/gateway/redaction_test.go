@@ -0,0 +1,121 @@
+package gateway_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/pii"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProvider answers with the prompt it received, streamed three bytes per chunk.
+type echoProvider struct {
+	mockProvider
+	mu       sync.Mutex
+	received string
+}
+
+func (p *echoProvider) prompt(req providers.ChatRequest) string {
+	text, _ := providers.NormalizeMessageContent(req.Messages[len(req.Messages)-1].Content)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.received = text
+	return text
+}
+
+func (p *echoProvider) lastPrompt() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.received
+}
+
+func (p *echoProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	return &providers.ChatResponse{
+		ID:      "echo-1",
+		Model:   req.Model,
+		Choices: []providers.Choice{{Message: providers.Message{Role: "assistant", Content: p.prompt(req)}}},
+	}, nil
+}
+
+func (p *echoProvider) ChatStream(ctx context.Context, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	text := p.prompt(req)
+	chunks := make(chan providers.StreamChunk, len(text)+1)
+	errs := make(chan error)
+	for i := 0; i < len(text); i += 3 {
+		chunks <- providers.StreamChunk{ID: "echo-1", Content: text[i:min(i+3, len(text))]}
+	}
+	chunks <- providers.StreamChunk{ID: "echo-1", Done: true}
+	close(chunks)
+	close(errs)
+	return chunks, errs
+}
+
+const piiPrompt = "Email jane.doe@example.com or call +44 20 7946 0958 about card 4111 1111 1111 1111"
+
+func piiRequest() providers.ChatRequest {
+	return providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: piiPrompt}}}
+}
+
+func TestRouter_Redaction_RedactsPromptAndRestoresResponse(t *testing.T) {
+	provider := &echoProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+
+	ctx := gateway.WithRedactionPolicy(context.Background(), gateway.RedactionPolicy{})
+	resp, err := router.Route(ctx, piiRequest(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Email [PII_EMAIL_1] or call [PII_PHONE_1] about card [PII_CREDIT_CARD_1]", provider.lastPrompt())
+	assert.Equal(t, piiPrompt, resp.Choices[0].Message.Content)
+}
+
+func TestRouter_Redaction_OnlyPolicyTypes(t *testing.T) {
+	provider := &echoProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+
+	ctx := gateway.WithRedactionPolicy(context.Background(), gateway.RedactionPolicy{Types: []pii.Type{pii.TypeEmail}})
+	_, err := router.Route(ctx, piiRequest(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Email [PII_EMAIL_1] or call +44 20 7946 0958 about card 4111 1111 1111 1111", provider.lastPrompt())
+}
+
+func TestRouter_Redaction_SkipsOnPremProviders(t *testing.T) {
+	provider := &echoProvider{mockProvider: mockProvider{name: "local", available: true, models: []string{"gpt-4o"}}}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+
+	ctx := gateway.WithRedactionPolicy(context.Background(), gateway.RedactionPolicy{})
+	_, err := router.Route(ctx, piiRequest(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, piiPrompt, provider.lastPrompt())
+
+	ctx = gateway.WithRedactionPolicy(context.Background(), gateway.RedactionPolicy{SkipProviders: []string{}})
+	_, err = router.Route(ctx, piiRequest(), nil)
+	require.NoError(t, err)
+	assert.NotContains(t, provider.lastPrompt(), "jane.doe@example.com", "an empty skip list redacts for every provider")
+}
+
+func TestRouter_Redaction_RestoresSplitPlaceholdersInStream(t *testing.T) {
+	provider := &echoProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+
+	ctx := gateway.WithRedactionPolicy(context.Background(), gateway.RedactionPolicy{})
+	chunks, errs := router.RouteStream(ctx, piiRequest(), nil)
+	var content strings.Builder
+	for chunk := range chunks {
+		assert.NotContains(t, chunk.Content, "[PII_")
+		content.WriteString(chunk.Content)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Contains(t, provider.lastPrompt(), "[PII_EMAIL_1]")
+	assert.Equal(t, piiPrompt, content.String())
+}
@@ -0,0 +1,239 @@
+package gateway_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCacheStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func newMemoryCacheStore() *memoryCacheStore {
+	return &memoryCacheStore{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (s *memoryCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key], nil
+}
+
+func (s *memoryCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	s.ttls[key] = ttl
+	return nil
+}
+
+type countingProvider struct {
+	mockProvider
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *countingProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	resp, err := p.mockProvider.Chat(ctx, req)
+	if err == nil {
+		resp.Usage = providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	}
+	return resp, err
+}
+
+func (p *countingProvider) ChatStream(ctx context.Context, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	chunks := make(chan providers.StreamChunk, 3)
+	errs := make(chan error)
+	chunks <- providers.StreamChunk{ID: "stream-1", Content: "Streamed "}
+	chunks <- providers.StreamChunk{ID: "stream-1", Content: "answer"}
+	chunks <- providers.StreamChunk{ID: "stream-1", Done: true, Usage: &providers.Usage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}}
+	close(errs)
+	close(chunks)
+	return chunks, errs
+}
+
+func (p *countingProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func newCachingRouter() (*gateway.Router, *countingProvider, *memoryCacheStore) {
+	store := newMemoryCacheStore()
+	provider := &countingProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+	router.SetResponseCache(gateway.NewResponseCache(store))
+	return router, provider, store
+}
+
+func collectStream(t *testing.T, chunks <-chan providers.StreamChunk, errs <-chan error) (string, providers.StreamChunk) {
+	t.Helper()
+	var content string
+	var last providers.StreamChunk
+	for chunk := range chunks {
+		content += chunk.Content
+		last = chunk
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	return content, last
+}
+
+func TestCacheKey_Normalization(t *testing.T) {
+	base := providers.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: "user", Content: "What is Go?"}},
+	}
+	parts := providers.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: "user", Content: []interface{}{map[string]interface{}{"type": "text", "text": "What is Go? "}}}},
+	}
+	assert.Equal(t, gateway.CacheKey("key-1", base), gateway.CacheKey("key-1", parts))
+
+	otherModel := base
+	otherModel.Model = "gpt-4o-mini"
+	assert.NotEqual(t, gateway.CacheKey("key-1", base), gateway.CacheKey("key-1", otherModel))
+
+	otherLimit := base
+	otherLimit.MaxTokens = 100
+	assert.NotEqual(t, gateway.CacheKey("key-1", base), gateway.CacheKey("key-1", otherLimit))
+
+	assert.NotEqual(t, gateway.CacheKey("key-1", base), gateway.CacheKey("key-2", base))
+}
+
+func temperature(v float64) *float64 {
+	return &v
+}
+
+func TestCacheable(t *testing.T) {
+	assert.True(t, gateway.Cacheable(providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0)}))
+	assert.False(t, gateway.Cacheable(providers.ChatRequest{Model: "gpt-4o"}), "an unset temperature samples at the provider default")
+	assert.False(t, gateway.Cacheable(providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0.7)}))
+	assert.False(t, gateway.Cacheable(providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0), WebSearch: true}))
+}
+
+func TestRouter_Route_CacheHit(t *testing.T) {
+	router, provider, store := newCachingRouter()
+	req := providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0), Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	ctx := gateway.WithCachePolicy(context.Background(), gateway.CachePolicy{Scope: "key-1", TTL: time.Minute})
+
+	first, err := router.Route(ctx, req, nil)
+	require.NoError(t, err)
+	assert.False(t, first.Cached)
+
+	second, err := router.Route(ctx, req, nil)
+	require.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Equal(t, 0.0, second.Cost)
+	assert.Equal(t, "openai", second.Provider)
+	assert.Equal(t, "Test response", second.Choices[0].Message.Content)
+	assert.Equal(t, 1, provider.callCount())
+	assert.Equal(t, time.Minute, store.ttls[gateway.CacheKey("key-1", req)])
+}
+
+func TestRouter_Route_CacheDisabledWithoutPolicy(t *testing.T) {
+	router, provider, _ := newCachingRouter()
+	req := providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0), Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+
+	for i := 0; i < 2; i++ {
+		resp, err := router.Route(context.Background(), req, nil)
+		require.NoError(t, err)
+		assert.False(t, resp.Cached)
+	}
+	assert.Equal(t, 2, provider.callCount())
+}
+
+func TestRouter_Route_CacheBypass(t *testing.T) {
+	router, provider, _ := newCachingRouter()
+	req := providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0), Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	ctx := gateway.WithCachePolicy(context.Background(), gateway.CachePolicy{Scope: "key-1", TTL: time.Minute})
+
+	_, err := router.Route(ctx, req, nil)
+	require.NoError(t, err)
+	bypass := gateway.WithCachePolicy(context.Background(), gateway.CachePolicy{Scope: "key-1", TTL: time.Minute, Bypass: true})
+	resp, err := router.Route(bypass, req, nil)
+	require.NoError(t, err)
+	assert.False(t, resp.Cached)
+	assert.Equal(t, 2, provider.callCount())
+}
+
+func TestRouter_RouteStream_StoresAndReplays(t *testing.T) {
+	router, provider, _ := newCachingRouter()
+	req := providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0), Messages: []providers.Message{{Role: "user", Content: "Stream please"}}}
+	ctx := gateway.WithCachePolicy(context.Background(), gateway.CachePolicy{Scope: "key-1", TTL: time.Minute})
+
+	chunks, errs := router.RouteStream(ctx, req, nil)
+	content, last := collectStream(t, chunks, errs)
+	assert.Equal(t, "Streamed answer", content)
+	assert.False(t, last.Cached)
+
+	chunks, errs = router.RouteStream(ctx, req, nil)
+	content, last = collectStream(t, chunks, errs)
+	assert.Equal(t, "Streamed answer", content)
+	assert.True(t, last.Done)
+	assert.True(t, last.Cached)
+	require.NotNil(t, last.Usage)
+	assert.Equal(t, 6, last.Usage.TotalTokens)
+	assert.Equal(t, 1, provider.callCount())
+
+	// A non-streaming request for the same prompt is served from the same entry.
+	resp, err := router.Route(ctx, req, nil)
+	require.NoError(t, err)
+	assert.True(t, resp.Cached)
+	assert.Equal(t, 1, provider.callCount())
+}
+
+// truncatingProvider fails after the first chunk of every stream.
+type truncatingProvider struct {
+	countingProvider
+}
+
+func (p *truncatingProvider) ChatStream(ctx context.Context, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	chunks := make(chan providers.StreamChunk)
+	errs := make(chan error)
+	go func() {
+		chunks <- providers.StreamChunk{ID: "stream-1", Content: "Partial"}
+		errs <- &providers.ProviderError{Provider: "openai", Kind: providers.ErrorKindServer, StatusCode: 502, Message: "connection reset"}
+		close(errs)
+		close(chunks)
+	}()
+	return chunks, errs
+}
+
+func TestRouter_RouteStream_DoesNotCacheTruncatedStreams(t *testing.T) {
+	provider := &truncatingProvider{countingProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}}}
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+	router.SetResponseCache(gateway.NewResponseCache(newMemoryCacheStore()))
+	req := providers.ChatRequest{Model: "gpt-4o", Temperature: temperature(0), Messages: []providers.Message{{Role: "user", Content: "Stream please"}}}
+	ctx := gateway.WithCachePolicy(context.Background(), gateway.CachePolicy{Scope: "key-1", TTL: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		chunks, errs := router.RouteStream(ctx, req, nil)
+		content, last := collectStream(t, chunks, errs)
+		assert.Equal(t, "Partial", content)
+		assert.True(t, last.Incomplete)
+		assert.False(t, last.Cached)
+	}
+	assert.Equal(t, 2, provider.callCount(), "a stream cut off by an upstream error is not replayed")
+}
@@ -0,0 +1,195 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRetryPolicy() gateway.RetryPolicy {
+	return gateway.RetryPolicy{
+		MaxRetries:    2,
+		BaseDelay:     time.Millisecond,
+		MaxDelay:      5 * time.Millisecond,
+		MaxRetryAfter: 50 * time.Millisecond,
+	}
+}
+
+// scriptedProvider returns the queued errors in order, then succeeds.
+type scriptedProvider struct {
+	mockProvider
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (p *scriptedProvider) next() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.mockProvider.Chat(ctx, req)
+}
+
+func (p *scriptedProvider) ChatStream(ctx context.Context, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	chunks := make(chan providers.StreamChunk, 1)
+	errs := make(chan error, 1)
+	if err := p.next(); err != nil {
+		errs <- err
+	} else {
+		chunks <- providers.StreamChunk{ID: "stream-1", Content: "ok", Done: true}
+	}
+	close(errs)
+	close(chunks)
+	return chunks, errs
+}
+
+func (p *scriptedProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func providerErr(kind providers.ErrorKind, status int) *providers.ProviderError {
+	return &providers.ProviderError{Provider: "openai", Kind: kind, StatusCode: status, Message: string(kind)}
+}
+
+func newRetryRouter(primaryErrs ...error) (*gateway.Router, *scriptedProvider, *scriptedProvider) {
+	router := gateway.NewRouter()
+	router.SetRetryPolicy(testRetryPolicy())
+	primary := &scriptedProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}, errs: primaryErrs}
+	backup := &scriptedProvider{mockProvider: mockProvider{name: "anthropic", available: true}}
+	router.RegisterProvider(primary)
+	router.RegisterProvider(backup)
+	return router, primary, backup
+}
+
+var retryReq = providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+
+func TestRouter_Route_RetriesSameProvider(t *testing.T) {
+	router, primary, backup := newRetryRouter(
+		providerErr(providers.ErrorKindRateLimit, http.StatusTooManyRequests),
+		providerErr(providers.ErrorKindServer, http.StatusServiceUnavailable),
+	)
+
+	resp, err := router.Route(context.Background(), retryReq, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.Provider)
+	assert.Equal(t, 3, primary.callCount())
+	assert.Equal(t, 0, backup.callCount())
+}
+
+func TestRouter_Route_FailsOverAfterRetries(t *testing.T) {
+	router, primary, backup := newRetryRouter(
+		providerErr(providers.ErrorKindServer, http.StatusBadGateway),
+		providerErr(providers.ErrorKindServer, http.StatusBadGateway),
+		providerErr(providers.ErrorKindServer, http.StatusBadGateway),
+	)
+
+	resp, err := router.Route(context.Background(), retryReq, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+	assert.Equal(t, 3, primary.callCount())
+	assert.Equal(t, 1, backup.callCount())
+}
+
+func TestRouter_Route_LongRetryAfterFailsOver(t *testing.T) {
+	limited := providerErr(providers.ErrorKindRateLimit, http.StatusTooManyRequests)
+	limited.RetryAfter = time.Minute
+	router, primary, backup := newRetryRouter(limited)
+
+	resp, err := router.Route(context.Background(), retryReq, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+	assert.Equal(t, 1, primary.callCount())
+	assert.Equal(t, 1, backup.callCount())
+}
+
+func TestRouter_Route_ModelNotFoundFailsOver(t *testing.T) {
+	router, primary, backup := newRetryRouter(providerErr(providers.ErrorKindNotFound, http.StatusNotFound))
+
+	resp, err := router.Route(context.Background(), retryReq, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+	assert.Equal(t, 1, primary.callCount(), "a missing model is not retried")
+	assert.Equal(t, 1, backup.callCount())
+
+	health, err := router.ProviderHealth("openai")
+	require.NoError(t, err)
+	assert.Equal(t, 0, health.ConsecutiveFailures)
+}
+
+func TestRouter_Route_ClientErrorDoesNotFailOver(t *testing.T) {
+	router, primary, backup := newRetryRouter(providerErr(providers.ErrorKindContextLength, http.StatusBadRequest))
+
+	_, err := router.Route(context.Background(), retryReq, nil)
+	perr, ok := providers.AsProviderError(err)
+	require.True(t, ok, "expected the original provider error, got %v", err)
+	assert.Equal(t, http.StatusBadRequest, perr.HTTPStatus())
+	assert.Equal(t, 1, primary.callCount())
+	assert.Equal(t, 0, backup.callCount())
+
+	health, err := router.ProviderHealth("openai")
+	require.NoError(t, err)
+	assert.Equal(t, 0, health.ConsecutiveFailures, "client errors must not count against the breaker")
+}
+
+func TestRouter_Route_ReturnsOriginalStatus(t *testing.T) {
+	router := gateway.NewRouter()
+	router.SetRetryPolicy(testRetryPolicy())
+	router.RegisterProvider(&scriptedProvider{
+		mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}},
+		errs: []error{
+			providerErr(providers.ErrorKindRateLimit, http.StatusTooManyRequests),
+			providerErr(providers.ErrorKindRateLimit, http.StatusTooManyRequests),
+			providerErr(providers.ErrorKindRateLimit, http.StatusTooManyRequests),
+		},
+	})
+
+	_, err := router.Route(context.Background(), retryReq, nil)
+	perr, ok := providers.AsProviderError(err)
+	require.True(t, ok, "expected the original provider error, got %v", err)
+	assert.Equal(t, http.StatusTooManyRequests, perr.HTTPStatus())
+}
+
+func TestRouter_RouteStream_RetriesBeforeFirstChunk(t *testing.T) {
+	router, primary, backup := newRetryRouter(providerErr(providers.ErrorKindServer, http.StatusServiceUnavailable))
+
+	chunks, errs := router.RouteStream(context.Background(), retryReq, nil)
+	content, last := collectStream(t, chunks, errs)
+	assert.Equal(t, "ok", content)
+	assert.Equal(t, "openai", last.Provider)
+	assert.Equal(t, 2, primary.callCount())
+	assert.Equal(t, 0, backup.callCount())
+}
+
+func TestRouter_RouteStream_ClientErrorDoesNotFailOver(t *testing.T) {
+	router, _, backup := newRetryRouter(providerErr(providers.ErrorKindInvalidRequest, http.StatusUnprocessableEntity))
+
+	chunks, errs := router.RouteStream(context.Background(), retryReq, nil)
+	for range chunks {
+	}
+	err := <-errs
+	perr, ok := providers.AsProviderError(err)
+	require.True(t, ok, "expected the original provider error, got %v", err)
+	assert.Equal(t, http.StatusUnprocessableEntity, perr.HTTPStatus())
+	assert.Equal(t, 0, backup.callCount())
+}
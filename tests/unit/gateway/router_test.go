@@ -229,3 +229,51 @@ func TestRouter_SelectProvider_ByModel(t *testing.T) {
 		t.Error("Expected openai provider to be registered")
 	}
 }
+
+// toolMockProvider is a mockProvider that advertises tool support
+type toolMockProvider struct {
+	mockProvider
+}
+
+func (m *toolMockProvider) SupportsTools(model string) bool {
+	return true
+}
+
+func TestRouter_Route_ToolsOnlyToolCapableProviders(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "plain", available: true})
+	router.RegisterProvider(&toolMockProvider{mockProvider{name: "tooly", available: true}})
+
+	req := providers.ChatRequest{
+		Model:    "test-model",
+		Messages: []providers.Message{{Role: "user", Content: "What's the weather?"}},
+		Tools: []providers.Tool{
+			{Type: "function", Function: providers.ToolFunction{Name: "get_weather"}},
+		},
+	}
+
+	resp, err := router.Route(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("Route should succeed: %v", err)
+	}
+	if resp.Provider != "tooly" {
+		t.Errorf("Expected tool-capable provider 'tooly', got '%s'", resp.Provider)
+	}
+}
+
+func TestRouter_Route_ToolsNoCapableProvider(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "plain", available: true})
+
+	req := providers.ChatRequest{
+		Model:    "test-model",
+		Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+		Tools: []providers.Tool{
+			{Type: "function", Function: providers.ToolFunction{Name: "get_weather"}},
+		},
+	}
+
+	if _, err := router.Route(context.Background(), req, nil); err == nil {
+		t.Error("Expected error when no provider supports tools")
+	}
+}
@@ -0,0 +1,203 @@
+package gateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conditionValue decodes JSON the way rules arrive from the API and the database.
+func conditionValue(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var value map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &value))
+	return value
+}
+
+func compileRule(t *testing.T, conditionType, value, provider string) gateway.RoutingRule {
+	t.Helper()
+	engine := gateway.NewRuleEngine(gateway.NewCostCalculator(), gateway.NewLatencyTracker(10))
+	rule, err := engine.Compile(gateway.CustomRule{
+		Name:           conditionType + "-rule",
+		ConditionType:  conditionType,
+		ConditionValue: conditionValue(t, value),
+		ProviderName:   provider,
+	})
+	require.NoError(t, err)
+	return rule
+}
+
+func TestValidateRuleCondition(t *testing.T) {
+	valid := []struct {
+		conditionType string
+		value         string
+	}{
+		{gateway.ConditionModel, `{"model": "gpt-4o"}`},
+		{gateway.ConditionHasImage, `{}`},
+		{gateway.ConditionTimeOfDay, `{"start": "22:00", "end": "06:00", "timezone": "Europe/London"}`},
+		{gateway.ConditionHeader, `{"name": "X-Team"}`},
+		{gateway.ConditionAnd, `{"conditions": [
+			{"condition_type": "token_estimate", "condition_value": {"max_tokens": 2000}},
+			{"condition_type": "not", "condition_value": {"condition": {"condition_type": "web_search", "condition_value": {}}}}
+		]}`},
+	}
+	for _, tc := range valid {
+		assert.NoError(t, gateway.ValidateRuleCondition(tc.conditionType, conditionValue(t, tc.value)), tc.conditionType)
+	}
+
+	invalid := []struct {
+		conditionType string
+		value         string
+		message       string
+	}{
+		{"gpu_count", `{}`, `unknown condition_type "gpu_count"`},
+		{gateway.ConditionModel, `{}`, "model is required"},
+		{gateway.ConditionSystemPromptRegex, `{"pattern": "("}`, "invalid pattern"},
+		{gateway.ConditionPromptLength, `{"min_chars": 100, "max_chars": 10}`, "min_chars is greater than max_chars"},
+		{gateway.ConditionTimeOfDay, `{"start": "9am", "end": "17:00"}`, "start must be HH:MM"},
+		{gateway.ConditionAPIKey, `{"api_key_ids": ["not-a-uuid"]}`, "invalid API key ID"},
+		{gateway.ConditionOr, `{"conditions": []}`, "conditions must be a non-empty list"},
+		{gateway.ConditionAnd, `{"conditions": [{"condition_type": "user_role", "condition_value": {}}]}`, "conditions[0]: user_role: role or roles is required"},
+	}
+	for _, tc := range invalid {
+		err := gateway.ValidateRuleCondition(tc.conditionType, conditionValue(t, tc.value))
+		if assert.Error(t, err, tc.conditionType) {
+			assert.Contains(t, err.Error(), tc.message)
+		}
+	}
+
+	nested := `{"condition_type": "has_image", "condition_value": {}}`
+	for i := 0; i < 8; i++ {
+		nested = `{"condition_type": "not", "condition_value": {"condition": ` + nested + `}}`
+	}
+	err := gateway.ValidateRuleCondition(gateway.ConditionNot, conditionValue(t, `{"condition": `+nested+`}`))
+	assert.ErrorContains(t, err, "nested more than 8 levels")
+}
+
+func TestRuleEngine_Conditions(t *testing.T) {
+	keyID := uuid.New()
+	headers := http.Header{}
+	headers.Set("X-Team", "research")
+	ctx := gateway.WithRequestAttributes(context.Background(), gateway.RequestAttributes{
+		APIKeyID:  keyID.String(),
+		UserRoles: []string{"user", "admin"},
+		Headers:   headers,
+	})
+	now := time.Now().UTC()
+	clock := func(d time.Duration) string { return now.Add(d).Format("15:04") }
+
+	image := providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{
+		{Role: "system", Content: "You are a SQL expert."},
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "What is in this picture?"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+		}},
+	}}
+	search := providers.ChatRequest{Model: "gpt-4o", WebSearch: true, Messages: []providers.Message{{Role: "user", Content: "news today"}}}
+
+	cases := []struct {
+		conditionType string
+		value         string
+		req           providers.ChatRequest
+		matched       bool
+	}{
+		{gateway.ConditionHasImage, `{}`, image, true},
+		{gateway.ConditionHasAudio, `{}`, image, false},
+		{gateway.ConditionSystemPromptRegex, `{"pattern": "(?i)sql"}`, image, true},
+		{gateway.ConditionSystemPromptRegex, `{"pattern": "(?i)sql"}`, search, false},
+		{gateway.ConditionPromptLength, `{"max_chars": 20}`, search, true},
+		{gateway.ConditionPromptLength, `{"min_chars": 20}`, search, false},
+		{gateway.ConditionTokenEstimate, `{"max_tokens": 50}`, search, true},
+		{gateway.ConditionTokenEstimate, `{"min_tokens": 1000}`, search, false},
+		{gateway.ConditionAPIKey, `{"api_key_id": "` + keyID.String() + `"}`, search, true},
+		{gateway.ConditionAPIKey, `{"api_key_ids": ["` + uuid.NewString() + `"]}`, search, false},
+		{gateway.ConditionUserRole, `{"roles": ["admin"]}`, search, true},
+		{gateway.ConditionUserRole, `{"role": "billing"}`, search, false},
+		{gateway.ConditionHeader, `{"name": "x-team", "value": "research"}`, search, true},
+		{gateway.ConditionHeader, `{"name": "X-Team", "pattern": "^prod"}`, search, false},
+		{gateway.ConditionHeader, `{"name": "X-Missing"}`, search, false},
+		{gateway.ConditionTimeOfDay, `{"start": "` + clock(-time.Hour) + `", "end": "` + clock(time.Hour) + `"}`, search, true},
+		{gateway.ConditionTimeOfDay, `{"start": "` + clock(time.Hour) + `", "end": "` + clock(2*time.Hour) + `"}`, search, false},
+		{gateway.ConditionTimeOfDay, `{"start": "` + clock(time.Hour) + `", "end": "` + clock(-time.Hour) + `"}`, search, false},
+		{gateway.ConditionWebSearch, `{}`, search, true},
+		{gateway.ConditionWebSearch, `{"enabled": false}`, image, true},
+		{gateway.ConditionOr, `{"conditions": [
+			{"condition_type": "model", "condition_value": {"model": "claude-3-5-sonnet"}},
+			{"condition_type": "has_image", "condition_value": {}}
+		]}`, image, true},
+		{gateway.ConditionAnd, `{"conditions": [
+			{"condition_type": "has_image", "condition_value": {}},
+			{"condition_type": "web_search", "condition_value": {}}
+		]}`, image, false},
+		{gateway.ConditionNot, `{"condition": {"condition_type": "web_search", "condition_value": {}}}`, image, true},
+	}
+	for _, tc := range cases {
+		rule := compileRule(t, tc.conditionType, tc.value, "openai")
+		result := rule.Match(ctx, tc.req)
+		assert.Equal(t, tc.matched, result.Matched, "%s %s", tc.conditionType, tc.value)
+	}
+}
+
+func TestCustomStrategy_RecordsRuleTrace(t *testing.T) {
+	available := []providers.Provider{
+		&mockProvider{name: "openai", available: true},
+		&mockProvider{name: "anthropic", available: true},
+	}
+	strategy := gateway.NewCustomStrategy([]gateway.RoutingRule{
+		compileRule(t, gateway.ConditionModel, `{"model": "gpt-4o"}`, "google"),
+		compileRule(t, gateway.ConditionAnd, `{"conditions": [
+			{"condition_type": "model", "condition_value": {"model": "gpt-4o"}},
+			{"condition_type": "not", "condition_value": {"condition": {"condition_type": "has_image", "condition_value": {}}}}
+		]}`, "anthropic"),
+		compileRule(t, gateway.ConditionModel, `{"model": "gpt-4o"}`, "openai"),
+	})
+
+	ctx, trace := gateway.WithRoutingTrace(context.Background())
+	selected, err := strategy.SelectProvider(ctx, createTestChatRequest("gpt-4o"), available)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", selected.Name())
+
+	require.Len(t, trace.Rules, 2, "evaluation stops at the selected rule")
+	assert.True(t, trace.Rules[0].Matched)
+	assert.False(t, trace.Rules[0].Selected)
+	assert.Equal(t, "provider not available", trace.Rules[0].Reason)
+	assert.True(t, trace.Rules[1].Selected)
+	condition := trace.Rules[1].Condition
+	require.Len(t, condition.Conditions, 2)
+	assert.Equal(t, gateway.ConditionNot, condition.Conditions[1].Type)
+	assert.False(t, condition.Conditions[1].Conditions[0].Matched)
+}
+
+func TestRouter_RoutingTrace(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&mockProvider{name: "openai", available: true})
+	router.RegisterProvider(&mockProvider{name: "anthropic", available: true})
+	engine := router.GetRuleEngine()
+	rules := engine.BuildRoutingRules([]gateway.CustomRule{
+		{Name: "broken", ConditionType: gateway.ConditionTimeOfDay, ConditionValue: map[string]interface{}{"start": "noon"}, ProviderName: "openai"},
+		{Name: "admins", ConditionType: gateway.ConditionUserRole, ConditionValue: map[string]interface{}{"role": "admin"}, ProviderName: "anthropic"},
+	})
+	router.SetCustomStrategy(gateway.NewCustomStrategy(rules))
+
+	ctx := gateway.WithRequestAttributes(context.Background(), gateway.RequestAttributes{UserRoles: []string{"admin"}})
+	ctx, trace := gateway.WithRoutingTrace(ctx)
+	resp, err := router.Route(ctx, createTestChatRequest("test-model"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+
+	assert.Equal(t, gateway.StrategyCustom, trace.Strategy)
+	assert.Equal(t, "anthropic", trace.Provider)
+	require.Len(t, trace.Rules, 2)
+	assert.False(t, trace.Rules[0].Matched)
+	assert.Contains(t, trace.Rules[0].Condition.Detail, "invalid rule")
+	assert.Equal(t, "admins", trace.Rules[1].Name)
+	assert.True(t, trace.Rules[1].Selected)
+}
@@ -0,0 +1,180 @@
+package gateway_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryShadowRecorder struct {
+	mu      sync.Mutex
+	results []gateway.ShadowResult
+}
+
+func (r *memoryShadowRecorder) RecordShadow(ctx context.Context, result gateway.ShadowResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+	return nil
+}
+
+func (r *memoryShadowRecorder) recorded() []gateway.ShadowResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]gateway.ShadowResult(nil), r.results...)
+}
+
+// recordingEmbedder returns the same vector for every input and keeps the inputs.
+type recordingEmbedder struct {
+	mockProvider
+	mu     sync.Mutex
+	inputs []string
+}
+
+func (p *recordingEmbedder) Embed(ctx context.Context, req providers.EmbeddingRequest) (*providers.EmbeddingResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inputs = append(p.inputs, req.Input...)
+	embeddings := make([][]float64, len(req.Input))
+	for i := range embeddings {
+		embeddings[i] = []float64{1, 0}
+	}
+	return &providers.EmbeddingResponse{Model: req.Model, Embeddings: embeddings}, nil
+}
+
+func (p *recordingEmbedder) GetEmbeddingModels() []string {
+	return []string{"text-embedding-3-small"}
+}
+
+// shadowRouter routes gpt-4o to openai and mirrors it to candidate according to rule.
+func shadowRouter(candidate *slowProvider, rule gateway.ShadowRule) (*gateway.Router, *memoryShadowRecorder) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&slowProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}})
+	router.RegisterProvider(candidate)
+	recorder := &memoryShadowRecorder{}
+	router.SetShadowMirror(gateway.NewShadowMirror(&gateway.ShadowConfig{Shadows: []gateway.ShadowRule{rule}}, recorder, zerolog.Nop()))
+	return router, recorder
+}
+
+func TestParseShadowConfig(t *testing.T) {
+	config, err := gateway.ParseShadowConfig("shadow.yaml", []byte(`
+shadows:
+  - model: gpt-4o
+    provider: vllm
+    target_model: llama3
+    sample_rate: 0.1
+    max_per_minute: 30
+`))
+	require.NoError(t, err)
+	require.Len(t, config.Shadows, 1)
+	assert.Equal(t, gateway.ShadowRule{Model: "gpt-4o", Provider: "vllm", TargetModel: "llama3", SampleRate: 0.1, MaxPerMinute: 30}, config.Shadows[0])
+
+	_, err = gateway.ParseShadowConfig("shadow.json", []byte(`{"shadows": [{"model": "gpt-4o", "provider": "vllm", "sample_rate": 0}]}`))
+	assert.ErrorContains(t, err, "sample_rate")
+
+	_, err = gateway.ParseShadowConfig("shadow.json", []byte(`{"shadows": [
+		{"model": "gpt-4o", "provider": "vllm", "sample_rate": 0.5},
+		{"model": "GPT-4o", "provider": "local", "sample_rate": 0.5}
+	]}`))
+	assert.ErrorContains(t, err, "duplicate")
+}
+
+func TestRouter_Shadow_MirrorsWithoutDelayingCaller(t *testing.T) {
+	candidate := &slowProvider{mockProvider: mockProvider{name: "vllm", available: true, models: []string{"llama3"}}, delay: 300 * time.Millisecond}
+	router, recorder := shadowRouter(candidate, gateway.ShadowRule{Model: "gpt-4o", Provider: "vllm", TargetModel: "llama3", SampleRate: 1})
+
+	start := time.Now()
+	resp, err := router.Route(context.Background(), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 200*time.Millisecond, "the caller does not wait for the shadow call")
+	assert.Equal(t, "openai", resp.Provider)
+
+	router.GetShadowMirror().Wait()
+	results := recorder.recorded()
+	require.Len(t, results, 1)
+	result := results[0]
+	assert.Equal(t, "gpt-4o", result.Model)
+	assert.Equal(t, "openai", result.PrimaryProvider)
+	assert.Equal(t, "Test response", result.PrimaryContent)
+	assert.Equal(t, "vllm", result.ShadowProvider)
+	assert.Equal(t, "llama3", result.ShadowModel)
+	assert.Equal(t, "Test response", result.ShadowContent)
+	assert.Equal(t, 5, result.ShadowUsage.CompletionTokens)
+	assert.GreaterOrEqual(t, result.ShadowLatency, 300*time.Millisecond)
+	assert.Empty(t, result.ShadowError)
+	assert.Nil(t, result.Similarity)
+
+	_, samples := router.GetLatencyTracker().Percentile("vllm", 50)
+	assert.Zero(t, samples, "shadow latency does not feed live routing")
+}
+
+func TestRouter_Shadow_RateLimited(t *testing.T) {
+	candidate := &slowProvider{mockProvider: mockProvider{name: "vllm", available: true, models: []string{"llama3"}}}
+	router, recorder := shadowRouter(candidate, gateway.ShadowRule{Model: "gpt-4o", Provider: "vllm", SampleRate: 1, MaxPerMinute: 2})
+
+	for i := 0; i < 5; i++ {
+		_, err := router.Route(context.Background(), createTestChatRequest("gpt-4o"), nil)
+		require.NoError(t, err)
+	}
+	router.GetShadowMirror().Wait()
+	assert.Len(t, recorder.recorded(), 2)
+	calls, _ := candidate.counts()
+	assert.Equal(t, 2, calls)
+}
+
+func TestRouter_Shadow_OnlyConfiguredModels(t *testing.T) {
+	candidate := &slowProvider{mockProvider: mockProvider{name: "vllm", available: true, models: []string{"llama3"}}}
+	router, recorder := shadowRouter(candidate, gateway.ShadowRule{Model: "gpt-4o-mini", Provider: "vllm", SampleRate: 1})
+
+	_, err := router.Route(context.Background(), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err)
+	router.GetShadowMirror().Wait()
+	assert.Empty(t, recorder.recorded())
+}
+
+func TestRouter_Shadow_RecordsShadowErrors(t *testing.T) {
+	candidate := &slowProvider{mockProvider: mockProvider{name: "vllm", available: true, models: []string{"llama3"}}, err: errUpstream}
+	router, recorder := shadowRouter(candidate, gateway.ShadowRule{Model: "gpt-4o", Provider: "vllm", SampleRate: 1})
+
+	resp, err := router.Route(context.Background(), createTestChatRequest("gpt-4o"), nil)
+	require.NoError(t, err, "a failing shadow does not affect the caller")
+	assert.Equal(t, "openai", resp.Provider)
+
+	router.GetShadowMirror().Wait()
+	results := recorder.recorded()
+	require.Len(t, results, 1)
+	assert.Equal(t, errUpstream.Error(), results[0].ShadowError)
+	assert.Equal(t, "Test response", results[0].PrimaryContent)
+}
+
+func TestRouter_Shadow_RedactsSimilarityInputs(t *testing.T) {
+	router := gateway.NewRouter()
+	router.RegisterProvider(&echoProvider{mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}}})
+	router.RegisterProvider(&echoProvider{mockProvider: mockProvider{name: "vllm", available: true, models: []string{"llama3"}}})
+	embedder := &recordingEmbedder{mockProvider: mockProvider{name: "embedder", available: true}}
+	router.RegisterProvider(embedder)
+	recorder := &memoryShadowRecorder{}
+	rule := gateway.ShadowRule{Model: "gpt-4o", Provider: "vllm", SampleRate: 1, EmbeddingModel: "text-embedding-3-small"}
+	router.SetShadowMirror(gateway.NewShadowMirror(&gateway.ShadowConfig{Shadows: []gateway.ShadowRule{rule}}, recorder, zerolog.Nop()))
+
+	ctx := gateway.WithRedactionPolicy(context.Background(), gateway.RedactionPolicy{})
+	_, err := router.Route(ctx, piiRequest(), nil)
+	require.NoError(t, err)
+	router.GetShadowMirror().Wait()
+
+	results := recorder.recorded()
+	require.Len(t, results, 1)
+	require.NotNil(t, results[0].Similarity)
+	assert.InDelta(t, 1, *results[0].Similarity, 1e-9)
+	require.Len(t, embedder.inputs, 2)
+	for _, input := range embedder.inputs {
+		assert.Equal(t, "Email [PII_EMAIL_1] or call [PII_PHONE_1] about card [PII_CREDIT_CARD_1]", input)
+	}
+}
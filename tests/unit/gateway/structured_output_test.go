@@ -0,0 +1,196 @@
+package gateway_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outputProvider answers with outputs in turn, repeating the last one, and records
+// the requests it receives.
+type outputProvider struct {
+	mockProvider
+	mu       sync.Mutex
+	outputs  []string
+	requests []providers.ChatRequest
+}
+
+func (p *outputProvider) next(req providers.ChatRequest) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	output := p.outputs[0]
+	if len(p.outputs) > 1 {
+		p.outputs = p.outputs[1:]
+	}
+	return output
+}
+
+func (p *outputProvider) received() []providers.ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]providers.ChatRequest(nil), p.requests...)
+}
+
+func (p *outputProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	return &providers.ChatResponse{
+		ID:      "out-1",
+		Model:   req.Model,
+		Choices: []providers.Choice{{Message: providers.Message{Role: "assistant", Content: p.next(req)}}},
+		Usage:   providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func (p *outputProvider) ChatStream(ctx context.Context, req providers.ChatRequest) (<-chan providers.StreamChunk, <-chan error) {
+	output := p.next(req)
+	chunks := make(chan providers.StreamChunk, 2)
+	errs := make(chan error)
+	chunks <- providers.StreamChunk{ID: "out-1", Content: output}
+	chunks <- providers.StreamChunk{ID: "out-1", Done: true}
+	close(chunks)
+	close(errs)
+	return chunks, errs
+}
+
+type nativeOutputProvider struct {
+	outputProvider
+}
+
+func (p *nativeOutputProvider) SupportsResponseFormat(model string) bool {
+	return true
+}
+
+var invoiceFormat = &providers.ResponseFormat{
+	Type: providers.ResponseFormatJSONSchema,
+	JSONSchema: &providers.JSONSchema{
+		Name: "invoice",
+		Schema: map[string]interface{}{
+			"type":                 "object",
+			"properties":           map[string]interface{}{"number": map[string]interface{}{"type": "string"}, "total": map[string]interface{}{"type": "number"}},
+			"required":             []interface{}{"number", "total"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+func structuredRouter(provider providers.Provider) *gateway.Router {
+	router := gateway.NewRouter()
+	router.RegisterProvider(provider)
+	return router
+}
+
+func invoiceRequest() providers.ChatRequest {
+	req := promptRequest("Extract the invoice")
+	req.ResponseFormat = invoiceFormat
+	return req
+}
+
+func TestRouter_StructuredOutput_PromptFallback(t *testing.T) {
+	provider := &outputProvider{
+		mockProvider: mockProvider{name: "anthropic", available: true, models: []string{"gpt-4o"}},
+		outputs:      []string{"```json\n{\"number\": \"INV-1\", \"total\": 12.5}\n```"},
+	}
+	resp, err := structuredRouter(provider).Route(context.Background(), invoiceRequest(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"number": "INV-1", "total": 12.5}`, resp.Choices[0].Message.Content)
+
+	received := provider.received()
+	require.Len(t, received, 1)
+	assert.Nil(t, received[0].ResponseFormat)
+	require.Len(t, received[0].Messages, 2)
+	assert.Equal(t, "system", received[0].Messages[0].Role)
+	assert.Contains(t, received[0].Messages[0].Content, `"required":["number","total"]`)
+}
+
+func TestRouter_StructuredOutput_NativeProviderGetsFormat(t *testing.T) {
+	provider := &nativeOutputProvider{outputProvider{
+		mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}},
+		outputs:      []string{`{"number": "INV-1", "total": 12.5}`},
+	}}
+	_, err := structuredRouter(provider).Route(context.Background(), invoiceRequest(), nil)
+	require.NoError(t, err)
+
+	received := provider.received()
+	require.Len(t, received, 1)
+	assert.Equal(t, invoiceFormat, received[0].ResponseFormat)
+	assert.Len(t, received[0].Messages, 1)
+}
+
+func TestRouter_StructuredOutput_InvalidOutputFails(t *testing.T) {
+	provider := &nativeOutputProvider{outputProvider{
+		mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}},
+		outputs:      []string{`{"number": 42}`},
+	}}
+	_, err := structuredRouter(provider).Route(context.Background(), invoiceRequest(), nil)
+	require.Error(t, err)
+
+	soErr, ok := gateway.AsStructuredOutputError(err)
+	require.True(t, ok)
+	assert.Equal(t, 1, soErr.Attempts)
+	assert.Equal(t, []string{`$: missing required property "total"`, "$.number: expected string, got number"}, soErr.Errors)
+	assert.Len(t, provider.received(), 1, "no retries without a policy")
+}
+
+func TestRouter_StructuredOutput_RepairRetry(t *testing.T) {
+	provider := &nativeOutputProvider{outputProvider{
+		mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}},
+		outputs:      []string{"The invoice number is INV-1", `{"number": "INV-1", "total": 12.5}`},
+	}}
+	ctx := gateway.WithStructuredOutputPolicy(context.Background(), gateway.StructuredOutputPolicy{MaxRetries: 2, Repair: true})
+	resp, err := structuredRouter(provider).Route(ctx, invoiceRequest(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"number": "INV-1", "total": 12.5}`, resp.Choices[0].Message.Content)
+	assert.Equal(t, 30, resp.Usage.TotalTokens, "usage includes the rejected attempt")
+
+	received := provider.received()
+	require.Len(t, received, 2)
+	repair := received[1].Messages
+	require.Len(t, repair, 3)
+	assert.Equal(t, "assistant", repair[1].Role)
+	assert.Equal(t, "The invoice number is INV-1", repair[1].Content)
+	assert.Contains(t, repair[2].Content, "not valid JSON")
+}
+
+func TestRouter_StructuredOutput_RetriesAreBounded(t *testing.T) {
+	provider := &nativeOutputProvider{outputProvider{
+		mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}},
+		outputs:      []string{"not json"},
+	}}
+	ctx := gateway.WithStructuredOutputPolicy(context.Background(), gateway.StructuredOutputPolicy{MaxRetries: 10})
+	_, err := structuredRouter(provider).Route(ctx, invoiceRequest(), nil)
+
+	soErr, ok := gateway.AsStructuredOutputError(err)
+	require.True(t, ok)
+	assert.Equal(t, gateway.MaxStructuredOutputRetries+1, soErr.Attempts)
+	assert.Equal(t, "not json", soErr.Output)
+	assert.Len(t, provider.received(), gateway.MaxStructuredOutputRetries+1)
+}
+
+func TestRouter_StructuredOutput_StreamValidated(t *testing.T) {
+	provider := &nativeOutputProvider{outputProvider{
+		mockProvider: mockProvider{name: "openai", available: true, models: []string{"gpt-4o"}},
+		outputs:      []string{`{"number": "INV-1"}`},
+	}}
+	chunks, errs := structuredRouter(provider).RouteStream(context.Background(), invoiceRequest(), nil)
+
+	var content strings.Builder
+	for chunk := range chunks {
+		assert.False(t, chunk.Done, "the final chunk of invalid output is held back")
+		content.WriteString(chunk.Content)
+	}
+	assert.Equal(t, `{"number": "INV-1"}`, content.String())
+
+	var streamErr error
+	for err := range errs {
+		streamErr = err
+	}
+	soErr, ok := gateway.AsStructuredOutputError(streamErr)
+	require.True(t, ok)
+	assert.Equal(t, []string{`$: missing required property "total"`}, soErr.Errors)
+}
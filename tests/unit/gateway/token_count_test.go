@@ -0,0 +1,89 @@
+package gateway_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/gateway"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountPromptTokens(t *testing.T) {
+	tok := tokenizer.NewRegistry().ForModel("gpt-4o")
+	req := providers.ChatRequest{
+		Model: "gpt-4o",
+		Messages: []providers.Message{
+			{Role: "system", Content: "Be brief"},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "What is this?"},
+				map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/cat.png"}},
+			}},
+		},
+	}
+	count := gateway.CountPromptTokens(tok, req)
+	assert.Equal(t, 1, count.ImageCount)
+	assert.Equal(t, tokenizer.TiledImageTokens(tokenizer.DefaultImageWidth, tokenizer.DefaultImageHeight), count.Images)
+	assert.Greater(t, count.Text, 2*3+3, "per-message and reply overhead plus content")
+	assert.Equal(t, count.Text+count.Images, count.Total)
+
+	req.Tools = []providers.Tool{{Type: "function", Function: providers.ToolFunction{Name: "get_weather", Parameters: map[string]interface{}{"type": "object"}}}}
+	withTools := gateway.CountPromptTokens(tok, req)
+	assert.Greater(t, withTools.Tools, 0)
+	assert.Equal(t, count.Total+withTools.Tools, withTools.Total)
+}
+
+func TestCostCalculator_EstimateCostUsesMaxTokens(t *testing.T) {
+	calculator := gateway.NewCostCalculator()
+	req := providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	short := calculator.EstimateCost("openai", req)
+	req.MaxTokens = 4000
+	long := calculator.EstimateCost("openai", req)
+	assert.Greater(t, long, short)
+}
+
+func TestRouter_Route_RejectsMaxTokensBeyondContextWindow(t *testing.T) {
+	router, primary, backup := newRetryRouter()
+	req := providers.ChatRequest{
+		Model:     "gpt-4",
+		Messages:  []providers.Message{{Role: "user", Content: strings.Repeat("word ", 2000)}},
+		MaxTokens: 7000,
+	}
+	_, err := router.Route(context.Background(), req, nil)
+	require.Error(t, err)
+	perr, ok := providers.AsProviderError(err)
+	require.True(t, ok)
+	assert.Equal(t, providers.ErrorKindContextLength, perr.Kind)
+	assert.Equal(t, 400, perr.HTTPStatus())
+	assert.Equal(t, 0, primary.callCount()+backup.callCount(), "no provider is called")
+
+	req.MaxTokens = 1000
+	_, err = router.Route(context.Background(), req, nil)
+	assert.NoError(t, err)
+}
+
+func TestRouter_RouteStream_EstimatesMissingUsage(t *testing.T) {
+	router, _, _ := newRetryRouter()
+	req := providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: "Hello there"}}}
+	chunks, errs := router.RouteStream(context.Background(), req, nil)
+	content, last := collectStream(t, chunks, errs)
+	assert.Equal(t, "ok", content)
+	require.NotNil(t, last.Usage)
+	assert.True(t, last.Usage.Estimated)
+	assert.Equal(t, 1, last.Usage.CompletionTokens)
+	assert.Greater(t, last.Usage.PromptTokens, 0)
+	assert.Equal(t, last.Usage.PromptTokens+last.Usage.CompletionTokens, last.Usage.TotalTokens)
+}
+
+func TestRouter_RouteStream_KeepsReportedUsage(t *testing.T) {
+	router, _, _ := newCachingRouter()
+	req := providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: "Hello"}}}
+	chunks, errs := router.RouteStream(context.Background(), req, nil)
+	_, last := collectStream(t, chunks, errs)
+	require.NotNil(t, last.Usage)
+	assert.False(t, last.Usage.Estimated)
+	assert.Equal(t, 6, last.Usage.TotalTokens)
+}
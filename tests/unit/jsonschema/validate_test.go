@@ -0,0 +1,93 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/jsonschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func schemaFromJSON(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	return schema
+}
+
+const orderSchema = `{
+	"type": "object",
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"status": {"enum": ["open", "shipped"]},
+		"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"items": {"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/item"}},
+		"note": {"type": ["string", "null"], "maxLength": 5}
+	},
+	"required": ["id", "status", "items"],
+	"additionalProperties": false,
+	"$defs": {
+		"item": {
+			"type": "object",
+			"properties": {"sku": {"type": "string", "minLength": 1}, "qty": {"type": "integer", "exclusiveMinimum": 0}},
+			"required": ["sku", "qty"]
+		}
+	}
+}`
+
+func TestValidateJSON_Valid(t *testing.T) {
+	schema := schemaFromJSON(t, orderSchema)
+	errs := jsonschema.ValidateJSON(schema, []byte(`{"id": 7, "status": "open", "email": "a@b.co", "items": [{"sku": "X1", "qty": 2}], "note": null}`))
+	assert.Empty(t, errs)
+}
+
+func TestValidateJSON_ReportsEveryViolationWithPath(t *testing.T) {
+	schema := schemaFromJSON(t, orderSchema)
+	errs := jsonschema.ValidateJSON(schema, []byte(`{"id": 1.5, "status": "lost", "email": "nope", "items": [{"sku": "", "qty": 0}], "note": "too long", "extra": true}`))
+	assert.Equal(t, []string{
+		`$.email: must match pattern "^[^@]+@[^@]+$"`,
+		`$: unexpected property "extra"`,
+		"$.id: expected integer, got number",
+		`$.items[0].qty: must be > 0`,
+		`$.items[0].sku: must be at least 1 characters`,
+		`$.note: must be at most 5 characters`,
+		`$.status: must be one of ["open","shipped"]`,
+	}, errs)
+}
+
+func TestValidateJSON_MissingRequiredAndEmptyArray(t *testing.T) {
+	schema := schemaFromJSON(t, orderSchema)
+	errs := jsonschema.ValidateJSON(schema, []byte(`{"items": []}`))
+	assert.Equal(t, []string{
+		`$: missing required property "id"`,
+		`$: missing required property "status"`,
+		"$.items: must have at least 1 items",
+	}, errs)
+}
+
+func TestValidateJSON_InvalidJSON(t *testing.T) {
+	errs := jsonschema.ValidateJSON(map[string]interface{}{"type": "object"}, []byte(`{"id": `))
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "$: invalid JSON")
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	schema := map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "number", "multipleOf": 5},
+		},
+		"not": map[string]interface{}{"const": "forbidden"},
+	}
+	assert.Empty(t, jsonschema.Validate(schema, "hello"))
+	assert.Empty(t, jsonschema.Validate(schema, float64(10)))
+	assert.Equal(t, []string{"$: must match exactly one schema (oneOf), matches 0"}, jsonschema.Validate(schema, float64(7)))
+	assert.Equal(t, []string{"$: must not match the schema in not"}, jsonschema.Validate(schema, "forbidden"))
+}
+
+func TestValidate_UnresolvableRef(t *testing.T) {
+	schema := map[string]interface{}{"$ref": "#/$defs/missing"}
+	assert.Equal(t, []string{`$: unresolvable $ref "#/$defs/missing"`}, jsonschema.Validate(schema, "x"))
+}
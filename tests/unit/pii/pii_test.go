@@ -0,0 +1,69 @@
+package pii_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/pii"
+	"github.com/stretchr/testify/assert"
+)
+
+func detectedTypes(text string) []pii.Type {
+	var types []pii.Type
+	for _, m := range pii.Detect(text) {
+		types = append(types, m.Type)
+	}
+	return types
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []pii.Type
+	}{
+		{"email", "write to ops+alerts@mail.example.co.uk today", []pii.Type{pii.TypeEmail}},
+		{"valid card", "card 4242-4242-4242-4242 expires soon", []pii.Type{pii.TypeCreditCard}},
+		{"card failing luhn", "order 4242 4242 4242 4241", nil},
+		{"ssn", "SSN 123-45-6789", []pii.Type{pii.TypeNationalID}},
+		{"unissued ssn", "ref 666-45-6789", nil},
+		{"nino", "NI number QQ 12 34 56 C, AB123456C", []pii.Type{pii.TypeNationalID}},
+		{"international phone", "call +1 (415) 555-0132", []pii.Type{pii.TypePhone}},
+		{"national phone", "call 020 7946 0958", []pii.Type{pii.TypePhone}},
+		{"dates and years", "between 2024-01-15 and 2025, version 1.2.3", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectedTypes(tt.text))
+		})
+	}
+}
+
+func TestDetect_OnlyRequestedTypes(t *testing.T) {
+	matches := pii.Detect("a@b.io and 4111111111111111", pii.TypeCreditCard)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, "4111111111111111", matches[0].Value)
+	}
+}
+
+func TestRedactor_RoundTrip(t *testing.T) {
+	redactor := pii.NewRedactor()
+	redacted := redactor.Redact("from a@example.com to b@example.com, cc a@example.com")
+	assert.Equal(t, "from [PII_EMAIL_1] to [PII_EMAIL_2], cc [PII_EMAIL_1]", redacted)
+	assert.Equal(t, map[pii.Type]int{pii.TypeEmail: 2}, redactor.Redacted())
+
+	assert.Equal(t, "reply to b@example.com, not [PII_EMAIL_9]", redactor.Restore("reply to [PII_EMAIL_2], not [PII_EMAIL_9]"))
+}
+
+func TestStreamRestorer_SplitPlaceholders(t *testing.T) {
+	redactor := pii.NewRedactor()
+	redactor.Redact("a@example.com")
+	restorer := redactor.NewStreamRestorer()
+
+	var out strings.Builder
+	for _, piece := range []string{"Mail [P", "II_EM", "AIL_1] now [", "x] [PII_"} {
+		out.WriteString(restorer.Write(piece))
+	}
+	assert.Equal(t, "Mail a@example.com now [x] ", out.String())
+	assert.Equal(t, "[PII_", restorer.Flush())
+}
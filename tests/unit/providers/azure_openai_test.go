@@ -0,0 +1,211 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+func newAzureTestProvider(url string) *providers.AzureOpenAIProvider {
+	return providers.NewAzureOpenAIProvider("azure-key", providers.AzureOpenAIConfig{
+		Endpoint:    url,
+		Deployments: map[string]string{"gpt-4o": "prod-gpt4o"},
+	}, zerolog.Nop())
+}
+
+func TestParseAzureOpenAIMetadata(t *testing.T) {
+	config, err := providers.ParseAzureOpenAIMetadata([]byte(`{"endpoint":"https://contoso.openai.azure.com","deployments":{"gpt-4o":"prod-gpt4o"}}`))
+	if err != nil {
+		t.Fatalf("ParseAzureOpenAIMetadata failed: %v", err)
+	}
+	if config.Deployments["gpt-4o"] != "prod-gpt4o" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	for name, metadata := range map[string]string{
+		"missing":          ``,
+		"http endpoint":    `{"endpoint":"http://contoso.openai.azure.com"}`,
+		"bad deployment":   `{"endpoint":"https://contoso.openai.azure.com","deployments":{"gpt-4o":"a/b"}}`,
+		"foreign host":     `{"endpoint":"https://metadata.internal"}`,
+		"suffix lookalike": `{"endpoint":"https://contoso.openai.azure.com.evil.example"}`,
+	} {
+		if _, err := providers.ParseAzureOpenAIMetadata([]byte(metadata)); err == nil {
+			t.Errorf("Expected %s metadata to be rejected", name)
+		}
+	}
+}
+
+func TestParseAzureOpenAIMetadata_AllowedHosts(t *testing.T) {
+	providers.SetAzureBYOKHosts([]string{"ai.example.com", ".proxy.example.com"})
+	defer providers.SetAzureBYOKHosts(nil)
+
+	for _, endpoint := range []string{"https://ai.example.com", "https://eu.proxy.example.com", "https://contoso.cognitiveservices.azure.com"} {
+		if _, err := providers.ParseAzureOpenAIMetadata([]byte(`{"endpoint":"` + endpoint + `"}`)); err != nil {
+			t.Errorf("Expected %s to be allowed: %v", endpoint, err)
+		}
+	}
+	if _, err := providers.ParseAzureOpenAIMetadata([]byte(`{"endpoint":"https://other.example.com"}`)); err == nil {
+		t.Error("Expected a host outside the allowlist to be rejected")
+	}
+}
+
+func TestAzureOpenAIProvider_BYOKRefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	providers.SetAzureBYOKHosts([]string{"127.0.0.1"})
+	defer providers.SetAzureBYOKHosts(nil)
+	config, err := providers.ParseAzureOpenAIMetadata([]byte(`{"endpoint":"` + server.URL + `"}`))
+	if err != nil {
+		t.Fatalf("ParseAzureOpenAIMetadata failed: %v", err)
+	}
+
+	provider := providers.NewAzureOpenAIProvider("azure-key", config, zerolog.Nop())
+	_, err = provider.Chat(context.Background(), providers.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Fatalf("Expected the loopback endpoint to be refused, got %v", err)
+	}
+	if called {
+		t.Error("Expected no request to reach the loopback server")
+	}
+}
+
+func TestAzureOpenAIProvider_Chat_NonJSONErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, "internal-secret")
+	}))
+	defer server.Close()
+
+	_, err := newAzureTestProvider(server.URL).Chat(context.Background(), providers.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil || strings.Contains(err.Error(), "internal-secret") {
+		t.Fatalf("Expected an error without the upstream body, got %v", err)
+	}
+}
+
+func TestAzureOpenAIProvider_Chat(t *testing.T) {
+	var path, apiVersion string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "azure-key" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path, apiVersion = r.URL.Path, r.URL.Query().Get("api-version")
+		json.NewDecoder(r.Body).Decode(&body)
+		io.WriteString(w, `{"id":"c1","model":"gpt-4o-2024-08-06","prompt_filter_results":[{"prompt_index":0,"content_filter_results":{}}],"choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop","content_filter_results":{"hate":{"filtered":false,"severity":"safe"}}}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	provider := newAzureTestProvider(server.URL)
+	resp, err := provider.Chat(context.Background(), providers.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if path != "/openai/deployments/prod-gpt4o/chat/completions" || apiVersion != providers.DefaultAzureAPIVersion {
+		t.Errorf("Unexpected request URL: %s?api-version=%s", path, apiVersion)
+	}
+	if _, ok := body["model"]; ok {
+		t.Error("The deployment should pick the model, not the body")
+	}
+	if resp.Choices[0].Message.Content != "Hi" || resp.Usage.TotalTokens != 4 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if models := provider.GetModels(); len(models) != 1 || models[0] != "gpt-4o" {
+		t.Errorf("GetModels = %v, want the mapped models", models)
+	}
+}
+
+func TestAzureOpenAIProvider_PromptContentFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"message":"The response was filtered due to the prompt triggering content management policy.","code":"content_filter","status":400,"innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{"hate":{"filtered":false,"severity":"safe"},"jailbreak":{"filtered":true,"detected":true},"violence":{"filtered":true,"severity":"medium"}}}}}`)
+	}))
+	defer server.Close()
+
+	_, err := newAzureTestProvider(server.URL).Chat(context.Background(), providers.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+	})
+	filterErr, ok := providers.AsContentFilterError(err)
+	if !ok {
+		t.Fatalf("Expected a content filter error, got %v", err)
+	}
+	if filterErr.Stage != "prompt" || filterErr.Categories["violence"] != "medium" || filterErr.Categories["jailbreak"] != "detected" || len(filterErr.Categories) != 2 {
+		t.Errorf("Unexpected content filter error: %+v", filterErr)
+	}
+	if perr, ok := providers.AsProviderError(err); !ok || !perr.ClientError() || perr.HTTPStatus() != http.StatusBadRequest {
+		t.Errorf("Content filter errors should be client errors, got %v", err)
+	}
+}
+
+func TestAzureOpenAIProvider_ChatStream(t *testing.T) {
+	events := []string{
+		`data: {"id":"","choices":[],"prompt_filter_results":[{"prompt_index":0,"content_filter_results":{"hate":{"filtered":false,"severity":"safe"}}}]}`,
+		`data: {"id":"s1","choices":[{"index":0,"delta":{"role":"assistant","content":""},"content_filter_results":{}}]}`,
+		`data: {"id":"s1","choices":[{"index":0,"delta":{"content":"Hel"},"content_filter_results":{"hate":{"filtered":false,"severity":"safe"}}}]}`,
+		`data: {"id":"s1","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+		`data: {"id":"s1","choices":[],"usage":{"prompt_tokens":2,"completion_tokens":2,"total_tokens":4}}`,
+		`data: [DONE]`,
+	}
+	stream := func(t *testing.T, events []string) (string, providers.StreamChunk, error) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, strings.Join(events, "\n\n")+"\n\n")
+		}))
+		defer server.Close()
+
+		chunks, errs := newAzureTestProvider(server.URL).ChatStream(context.Background(), providers.ChatRequest{
+			Model:    "gpt-4o",
+			Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+		})
+		var content strings.Builder
+		var last providers.StreamChunk
+		for chunk := range chunks {
+			content.WriteString(chunk.Content)
+			last = chunk
+		}
+		return content.String(), last, <-errs
+	}
+
+	content, last, err := stream(t, events)
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if content != "Hello" || !last.Done || last.ID != "s1" || last.Usage == nil || last.Usage.TotalTokens != 4 {
+		t.Errorf("Unexpected stream: %q %+v", content, last)
+	}
+
+	filtered := append(append([]string{}, events[:3]...),
+		`data: {"id":"s1","choices":[{"index":0,"delta":{},"finish_reason":"content_filter","content_filter_results":{"sexual":{"filtered":true,"severity":"high"}}}]}`,
+		`data: [DONE]`)
+	content, _, err = stream(t, filtered)
+	var filterErr *providers.ContentFilterError
+	if !errors.As(err, &filterErr) || filterErr.Stage != "completion" || filterErr.Categories["sexual"] != "high" {
+		t.Fatalf("Expected a completion content filter error, got %v", err)
+	}
+	if content != "Hel" {
+		t.Errorf("Content before the filter should still be streamed, got %q", content)
+	}
+}
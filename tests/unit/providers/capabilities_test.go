@@ -0,0 +1,106 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+func TestRequiredCapabilities(t *testing.T) {
+	req := providers.ChatRequest{
+		Model: "gpt-4o",
+		Messages: []providers.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "What is this?"},
+				map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+			}},
+		},
+		Tools:     []providers.Tool{weatherTool},
+		WebSearch: true,
+	}
+	needs := providers.RequiredCapabilities(req)
+	if !needs.Vision || needs.Audio || !needs.Tools || !needs.WebSearch {
+		t.Errorf("Unexpected needs: %+v", needs)
+	}
+	if needs := providers.RequiredCapabilities(providers.ChatRequest{Messages: []providers.Message{{Role: "user", Content: "hi"}}}); needs != (providers.Capabilities{}) {
+		t.Errorf("Plain text request should need nothing, got %+v", needs)
+	}
+}
+
+func TestCapabilities_Missing(t *testing.T) {
+	caps := providers.Capabilities{Vision: true, Tools: true}
+	missing := caps.Missing(providers.Capabilities{Vision: true, Audio: true, WebSearch: true})
+	if len(missing) != 2 || missing[0] != "audio input" || missing[1] != "web search" {
+		t.Errorf("Unexpected missing capabilities: %v", missing)
+	}
+}
+
+func TestCapabilityRegistry_BuiltInData(t *testing.T) {
+	registry := providers.NewCapabilityRegistry()
+	openai := providers.NewOpenAIProvider("key", "", zerolog.Nop())
+	anthropic := providers.NewAnthropicProvider("key", "", zerolog.Nop())
+
+	caps := registry.Lookup(openai, "gpt-4o-mini")
+	if caps.ContextLength != 128000 || !caps.Vision || caps.Audio || !caps.Tools || !caps.JSONMode || !caps.Streaming || !caps.WebSearch {
+		t.Errorf("Unexpected gpt-4o-mini capabilities: %+v", caps)
+	}
+	if caps := registry.Lookup(openai, "o1-mini"); caps.Tools || caps.Vision {
+		t.Errorf("o1-mini takes neither tools nor images: %+v", caps)
+	}
+	if caps := registry.Lookup(anthropic, "claude-sonnet-4-5-20250929"); !caps.Vision || caps.Audio || caps.JSONMode {
+		t.Errorf("Unexpected Claude capabilities: %+v", caps)
+	}
+	if provider, ok := providers.ModelProvider("claude-3-5-haiku"); !ok || provider != "anthropic" {
+		t.Errorf("Expected anthropic for claude-3-5-haiku, got %q", provider)
+	}
+	if _, ok := providers.ModelContextLength("llama3:8b"); ok {
+		t.Error("Local model context lengths come from discovery, not built-in data")
+	}
+}
+
+func TestLocalProvider_DiscoverCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/tags":
+			io.WriteString(w, `{"models":[{"name":"llava:7b"},{"name":"llama3:8b"}]}`)
+		case "/api/show":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["model"] == "llava:7b" {
+				io.WriteString(w, `{"capabilities":["completion","vision"],"model_info":{"llama.context_length":32768}}`)
+				return
+			}
+			io.WriteString(w, `{"capabilities":["completion","tools"],"model_info":{"llama.context_length":8192}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := providers.NewLocalProvider(server.URL, zerolog.Nop())
+	registry := providers.NewCapabilityRegistry()
+	if err := registry.Discover(context.Background(), provider); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	llava := registry.Lookup(provider, "llava:7b")
+	if !llava.Vision || llava.Tools || llava.ContextLength != 32768 || !llava.Streaming {
+		t.Errorf("Unexpected llava capabilities: %+v", llava)
+	}
+	llama := registry.Lookup(provider, "llama3:8b")
+	if llama.Vision || !llama.Tools || llama.ContextLength != 8192 {
+		t.Errorf("Unexpected llama3 capabilities: %+v", llama)
+	}
+	if length, ok := registry.ContextLength("llama3:8b"); !ok || length != 8192 {
+		t.Errorf("Expected discovered context length 8192, got %d", length)
+	}
+}
@@ -0,0 +1,124 @@
+package providers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+	"github.com/rs/zerolog"
+)
+
+func statusServer(status int, header http.Header, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+}
+
+func chatRequest() providers.ChatRequest {
+	return providers.ChatRequest{Model: "gpt-4o", Messages: []providers.Message{{Role: "user", Content: "hi"}}}
+}
+
+func TestOpenAIProvider_Chat_ClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		kind       providers.ErrorKind
+		httpStatus int
+		retryAfter time.Duration
+	}{
+		{"rate limit", 429, http.Header{"Retry-After": {"3"}}, `{"error":{"message":"slow down"}}`, providers.ErrorKindRateLimit, 429, 3 * time.Second},
+		{"retry-after-ms", 429, http.Header{"Retry-After-Ms": {"1500"}}, `{"error":{"message":"slow down"}}`, providers.ErrorKindRateLimit, 429, 1500 * time.Millisecond},
+		{"auth", 401, nil, `{"error":{"message":"bad key"}}`, providers.ErrorKindAuth, 502, 0},
+		{"bad request", 400, nil, `{"error":{"message":"messages is required"}}`, providers.ErrorKindInvalidRequest, 400, 0},
+		{"model not found", 404, nil, `{"error":{"message":"The model 'gpt-5x' does not exist","code":"model_not_found"}}`, providers.ErrorKindNotFound, 404, 0},
+		{"context length", 400, nil, `{"error":{"message":"This model's maximum context length is 8192 tokens"}}`, providers.ErrorKindContextLength, 400, 0},
+		{"server", 503, nil, `upstream overloaded`, providers.ErrorKindServer, 503, 0},
+		{"gateway timeout", 504, nil, ``, providers.ErrorKindTimeout, 504, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := statusServer(tt.status, tt.header, tt.body)
+			defer server.Close()
+
+			provider := providers.NewOpenAIProvider("sk-test", server.URL, zerolog.Nop())
+			_, err := provider.Chat(context.Background(), chatRequest())
+			perr, ok := providers.AsProviderError(err)
+			if !ok {
+				t.Fatalf("Expected ProviderError, got %T: %v", err, err)
+			}
+			if perr.Kind != tt.kind {
+				t.Errorf("Expected kind %s, got %s", tt.kind, perr.Kind)
+			}
+			if perr.HTTPStatus() != tt.httpStatus {
+				t.Errorf("Expected HTTP status %d, got %d", tt.httpStatus, perr.HTTPStatus())
+			}
+			if perr.RetryAfter != tt.retryAfter {
+				t.Errorf("Expected Retry-After %v, got %v", tt.retryAfter, perr.RetryAfter)
+			}
+			if perr.Provider != "openai" {
+				t.Errorf("Expected provider 'openai', got '%s'", perr.Provider)
+			}
+		})
+	}
+}
+
+func TestProviderError_Policy(t *testing.T) {
+	if !(&providers.ProviderError{Kind: providers.ErrorKindRateLimit}).Retryable() {
+		t.Error("Expected rate limit errors to be retryable")
+	}
+	if (&providers.ProviderError{Kind: providers.ErrorKindInvalidRequest}).Retryable() {
+		t.Error("Expected invalid request errors not to be retryable")
+	}
+	if !(&providers.ProviderError{Kind: providers.ErrorKindContextLength}).ClientError() {
+		t.Error("Expected context length errors to be client errors")
+	}
+	if (&providers.ProviderError{Kind: providers.ErrorKindAuth}).ClientError() {
+		t.Error("Expected auth errors not to be client errors")
+	}
+}
+
+func TestAnthropicProvider_ChatStream_TypedError(t *testing.T) {
+	server := statusServer(529, nil, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+	defer server.Close()
+
+	provider := providers.NewAnthropicProvider("sk-test", server.URL, zerolog.Nop())
+	chunks, errs := provider.ChatStream(context.Background(), providers.ChatRequest{Model: "claude-3-5-sonnet-20241022", Messages: []providers.Message{{Role: "user", Content: "hi"}}})
+	for range chunks {
+		t.Error("Expected no chunks")
+	}
+	err := <-errs
+	perr, ok := providers.AsProviderError(err)
+	if !ok {
+		t.Fatalf("Expected ProviderError, got %T: %v", err, err)
+	}
+	if perr.Kind != providers.ErrorKindServer || perr.StatusCode != 529 {
+		t.Errorf("Expected server error with status 529, got %s/%d", perr.Kind, perr.StatusCode)
+	}
+}
+
+func TestLocalProvider_Chat_Unreachable(t *testing.T) {
+	server := statusServer(200, nil, `{}`)
+	url := server.URL
+	server.Close()
+
+	provider := providers.NewLocalProvider(url, zerolog.Nop())
+	_, err := provider.Chat(context.Background(), providers.ChatRequest{Model: "llama2", Messages: []providers.Message{{Role: "user", Content: "hi"}}})
+	perr, ok := providers.AsProviderError(err)
+	if !ok {
+		t.Fatalf("Expected ProviderError, got %T: %v", err, err)
+	}
+	if perr.Kind != providers.ErrorKindUnavailable || !perr.Retryable() {
+		t.Errorf("Expected retryable unavailable error, got %s", perr.Kind)
+	}
+}
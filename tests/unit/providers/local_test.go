@@ -1,6 +1,7 @@
 package providers_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/rs/zerolog"
@@ -37,3 +38,27 @@ func TestNewLocalProvider(t *testing.T) {
 		t.Error("Provider name should be 'local'")
 	}
 }
+
+func TestLocalProvider_Embed(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]],"prompt_eval_count":6}`, &captured)
+	defer server.Close()
+
+	provider := providers.NewLocalProvider(server.URL, zerolog.Nop())
+	resp, err := provider.Embed(context.Background(), providers.EmbeddingRequest{
+		Model: "nomic-embed-text",
+		Input: []string{"first", "second"},
+	})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if inputs, _ := captured["input"].([]interface{}); len(inputs) != 2 {
+		t.Errorf("Expected 2 inputs sent to /api/embed, got %v", captured["input"])
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[1][1] != 0.4 {
+		t.Errorf("Unexpected embeddings: %v", resp.Embeddings)
+	}
+	if resp.Usage.PromptTokens != 6 {
+		t.Errorf("Expected 6 prompt tokens, got %d", resp.Usage.PromptTokens)
+	}
+}
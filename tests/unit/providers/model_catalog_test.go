@@ -0,0 +1,174 @@
+package providers_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+// modelsServer answers list-models calls with the response for the request's key and
+// counts the calls.
+func modelsServer(t *testing.T, responses map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		key := r.Header.Get("x-api-key")
+		if key == "" {
+			key = r.URL.Query().Get("key")
+		}
+		if key == "" {
+			key = r.Header.Get("Authorization")
+		}
+		response, ok := responses[key]
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response)
+	}))
+	return server, &calls
+}
+
+func TestOpenAIProvider_DiscoversChatModels(t *testing.T) {
+	server, calls := modelsServer(t, map[string]string{
+		"Bearer sk-test": `{"data":[{"id":"gpt-4o"},{"id":"text-embedding-3-small"},{"id":"whisper-1"},{"id":"gpt-6"},{"id":"dall-e-3"}]}`,
+	})
+	defer server.Close()
+
+	provider := providers.NewOpenAIProvider("sk-test", server.URL, zerolog.Nop())
+	provider.SetModelCatalog(providers.NewModelCatalog(time.Hour))
+
+	want := []string{"gpt-4o", "gpt-6"}
+	if models := provider.GetModels(); !reflect.DeepEqual(models, want) {
+		t.Errorf("Expected %v, got %v", want, models)
+	}
+	provider.GetModels()
+	if calls.Load() != 1 {
+		t.Errorf("Expected the list to be cached, got %d calls", calls.Load())
+	}
+}
+
+func TestModelCatalog_FallsBackToStaticList(t *testing.T) {
+	server, _ := modelsServer(t, map[string]string{})
+	defer server.Close()
+
+	provider := providers.NewOpenAIProvider("sk-rejected", server.URL, zerolog.Nop())
+	static := provider.GetModels()
+	provider.SetModelCatalog(providers.NewModelCatalog(time.Hour))
+	if models := provider.GetModels(); !reflect.DeepEqual(models, static) {
+		t.Errorf("Expected the static list when discovery fails, got %v", models)
+	}
+}
+
+func TestModelCatalog_CachesPerKey(t *testing.T) {
+	server, calls := modelsServer(t, map[string]string{
+		"key-a": `{"data":[{"id":"claude-sonnet-4-6"}]}`,
+		"key-b": `{"data":[{"id":"claude-opus-4-6"},{"id":"claude-sonnet-4-6"}]}`,
+	})
+	defer server.Close()
+
+	catalog := providers.NewModelCatalog(time.Hour)
+	for i := 0; i < 2; i++ {
+		a := providers.NewAnthropicProvider("key-a", server.URL, zerolog.Nop())
+		a.SetModelCatalog(catalog)
+		b := providers.NewAnthropicProvider("key-b", server.URL, zerolog.Nop())
+		b.SetModelCatalog(catalog)
+		if models := a.GetModels(); len(models) != 1 {
+			t.Errorf("Expected key-a's models, got %v", models)
+		}
+		if models := b.GetModels(); len(models) != 2 {
+			t.Errorf("Expected key-b's models, got %v", models)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected one call per key, got %d", calls.Load())
+	}
+}
+
+func TestModelCatalog_SharesFirstDiscovery(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(w, `{"data":[{"id":"gpt-4o"}]}`)
+	}))
+	defer server.Close()
+
+	catalog := providers.NewModelCatalog(time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider := providers.NewOpenAIProvider("sk-test", server.URL, zerolog.Nop())
+			provider.SetModelCatalog(catalog)
+			if models := provider.GetModels(); len(models) != 1 {
+				t.Errorf("Expected the discovered model, got %v", models)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("Expected concurrent lookups to share one discovery, got %d calls", calls.Load())
+	}
+}
+
+func TestModelCatalog_AllowAndDenyLists(t *testing.T) {
+	server, _ := modelsServer(t, map[string]string{
+		"g-key": `{"models":[
+			{"name":"models/gemini-2.5-pro","supportedGenerationMethods":["generateContent","countTokens"]},
+			{"name":"models/gemini-2.5-flash","supportedGenerationMethods":["generateContent"]},
+			{"name":"models/gemini-2.5-flash-lite","supportedGenerationMethods":["generateContent"]},
+			{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}
+		]}`,
+	})
+	defer server.Close()
+
+	catalog := providers.NewModelCatalog(time.Hour)
+	catalog.SetModelFilter("google", []string{"gemini-2.5-*"}, []string{"*-lite"})
+	provider := providers.NewGoogleProvider("g-key", server.URL, zerolog.Nop())
+	provider.SetModelCatalog(catalog)
+
+	want := []string{"gemini-2.5-pro", "gemini-2.5-flash"}
+	if models := provider.GetModels(); !reflect.DeepEqual(models, want) {
+		t.Errorf("Expected %v, got %v", want, models)
+	}
+}
+
+func TestModelCatalog_RefreshesAfterTTL(t *testing.T) {
+	var response atomic.Value
+	response.Store(`{"data":[{"id":"gpt-4o"}]}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, response.Load().(string))
+	}))
+	defer server.Close()
+
+	provider := providers.NewOpenAIProvider("sk-test", server.URL, zerolog.Nop())
+	provider.SetModelCatalog(providers.NewModelCatalog(20 * time.Millisecond))
+	if models := provider.GetModels(); len(models) != 1 {
+		t.Fatalf("Expected one model, got %v", models)
+	}
+
+	response.Store(`{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`)
+	time.Sleep(30 * time.Millisecond)
+
+	// The stale list is served while it is refreshed in the background.
+	deadline := time.Now().Add(time.Second)
+	for len(provider.GetModels()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the refreshed list, got %v", provider.GetModels())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
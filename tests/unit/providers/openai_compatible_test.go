@@ -0,0 +1,138 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+func TestParseOpenAICompatibleConfig(t *testing.T) {
+	file, err := providers.ParseOpenAICompatibleConfig("upstreams.yaml", []byte(`
+providers:
+  - name: groq
+    base_url: https://api.groq.com/openai/v1
+    api_key_env: GROQ_API_KEY
+    models: [llama-3.3-70b-versatile]
+    byok: true
+  - name: lmstudio
+    base_url: http://localhost:1234/v1
+    discover_models: true
+    quirks:
+      no_stream_options: true
+    enabled: false
+`))
+	if err != nil {
+		t.Fatalf("ParseOpenAICompatibleConfig failed: %v", err)
+	}
+	if len(file.Providers) != 2 || !file.Providers[0].BYOK || !file.Providers[0].IsEnabled() {
+		t.Errorf("Unexpected groq config: %+v", file.Providers)
+	}
+	if lmstudio := file.Providers[1]; lmstudio.IsEnabled() || !lmstudio.Quirks.NoStreamOptions {
+		t.Errorf("Unexpected lmstudio config: %+v", lmstudio)
+	}
+
+	for name, config := range map[string]string{
+		"built-in name":  `{"providers":[{"name":"openai","base_url":"https://x.example.com","models":["m"]}]}`,
+		"no models":      `{"providers":[{"name":"groq","base_url":"https://x.example.com"}]}`,
+		"bad url":        `{"providers":[{"name":"groq","base_url":"api.groq.com","models":["m"]}]}`,
+		"uppercase name": `{"providers":[{"name":"Groq","base_url":"https://x.example.com","models":["m"]}]}`,
+		"duplicate": `{"providers":[{"name":"groq","base_url":"https://x.example.com","models":["m"]},
+			{"name":"groq","base_url":"https://y.example.com","models":["m"]}]}`,
+	} {
+		if _, err := providers.ParseOpenAICompatibleConfig("upstreams.json", []byte(config)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestOpenAICompatibleProvider_Chat(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gsk-test" || r.Header.Get("X-Title") != "UniRoute" {
+			http.Error(w, `{"error":{"message":"bad headers"}}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		io.WriteString(w, `{"id":"c1","model":"llama-3.3-70b-versatile","choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	provider := providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+		Name:    "groq",
+		BaseURL: server.URL + "/",
+		Models:  []string{"llama-3.3-70b-versatile"},
+		Headers: map[string]string{"X-Title": "UniRoute"},
+		Quirks:  providers.OpenAICompatibleQuirks{NoTools: true},
+	}, "gsk-test", zerolog.Nop())
+
+	resp, err := provider.Chat(context.Background(), providers.ChatRequest{
+		Model:    "llama-3.3-70b-versatile",
+		Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+		Tools:    []providers.Tool{weatherTool},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if provider.Name() != "groq" || resp.Choices[0].Message.Content != "Hi" || resp.Usage.TotalTokens != 4 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if _, ok := body["tools"]; ok {
+		t.Error("Tools should not be sent with the no_tools quirk")
+	}
+	if provider.SupportsTools("llama-3.3-70b-versatile") {
+		t.Error("SupportsTools should follow the no_tools quirk")
+	}
+}
+
+func TestOpenAICompatibleProvider_ChatStream(t *testing.T) {
+	for _, noStreamOptions := range []bool{false, true} {
+		var body map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, strings.Join([]string{
+				`data: {"id":"s1","choices":[{"delta":{"content":"Hel"}}]}`,
+				`data: {"id":"s1","choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+				`data: {"id":"s1","choices":[],"usage":{"prompt_tokens":2,"completion_tokens":2,"total_tokens":4}}`,
+				`data: [DONE]`,
+			}, "\n\n")+"\n\n")
+		}))
+
+		provider := providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+			Name:    "lmstudio",
+			BaseURL: server.URL,
+			Models:  []string{"qwen2.5-7b-instruct"},
+			Quirks:  providers.OpenAICompatibleQuirks{NoStreamOptions: noStreamOptions},
+		}, "", zerolog.Nop())
+
+		chunks, errs := provider.ChatStream(context.Background(), providers.ChatRequest{
+			Model:    "qwen2.5-7b-instruct",
+			Messages: []providers.Message{{Role: "user", Content: "Hello"}},
+		})
+		var content strings.Builder
+		var last providers.StreamChunk
+		for chunk := range chunks {
+			content.WriteString(chunk.Content)
+			last = chunk
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("ChatStream failed: %v", err)
+		}
+		server.Close()
+
+		if content.String() != "Hello" || !last.Done || last.FinishReason != "stop" || last.Usage == nil || last.Usage.TotalTokens != 4 {
+			t.Errorf("Unexpected stream: %q %+v", content.String(), last)
+		}
+		if _, sent := body["stream_options"]; sent == noStreamOptions {
+			t.Errorf("stream_options sent = %v with no_stream_options = %v", sent, noStreamOptions)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package providers_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/rs/zerolog"
@@ -58,3 +59,48 @@ func TestOpenAIProvider_Chat_NoAPIKey(t *testing.T) {
 		t.Error("Expected error when API key is not configured")
 	}
 }
+
+func TestOpenAIProvider_Embed(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"model":"text-embedding-3-small","data":[{"index":1,"embedding":[0.3]},{"index":0,"embedding":[0.1]}],"usage":{"prompt_tokens":3,"total_tokens":3}}`, &captured)
+	defer server.Close()
+
+	provider := providers.NewOpenAIProvider("test-key", server.URL, zerolog.Nop())
+	resp, err := provider.Embed(context.Background(), providers.EmbeddingRequest{
+		Model:      "text-embedding-3-small",
+		Input:      []string{"first", "second"},
+		Dimensions: 256,
+	})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if captured["dimensions"] != float64(256) {
+		t.Errorf("Expected dimensions 256 in request, got %v", captured["dimensions"])
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[0][0] != 0.1 || resp.Embeddings[1][0] != 0.3 {
+		t.Errorf("Embeddings should be ordered by index, got %v", resp.Embeddings)
+	}
+	if resp.Usage.TotalTokens != 3 {
+		t.Errorf("Expected 3 total tokens, got %d", resp.Usage.TotalTokens)
+	}
+}
+
+func TestOpenAIProvider_Chat_Temperature(t *testing.T) {
+	for name, temperature := range map[string]*float64{"explicit zero": new(float64), "unset": nil} {
+		var captured map[string]interface{}
+		server := captureServer(t, `{"id":"c1","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"Hi"}}]}`, &captured)
+		provider := providers.NewOpenAIProvider("test-key", server.URL, zerolog.Nop())
+		_, err := provider.Chat(context.Background(), providers.ChatRequest{
+			Model:       "gpt-4o",
+			Messages:    []providers.Message{{Role: "user", Content: "Hello"}},
+			Temperature: temperature,
+		})
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: Chat failed: %v", name, err)
+		}
+		if _, sent := captured["temperature"]; sent != (temperature != nil) {
+			t.Errorf("%s: temperature sent = %v, body %v", name, sent, captured)
+		}
+	}
+}
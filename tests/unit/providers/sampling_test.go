@@ -0,0 +1,107 @@
+package providers_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+func samplingRequest(model string) providers.ChatRequest {
+	topP, penalty, seed := 0.9, 0.5, 42
+	return providers.ChatRequest{
+		Model:            model,
+		Messages:         []providers.Message{{Role: "user", Content: "Hello"}},
+		TopP:             &topP,
+		Stop:             []string{"END"},
+		PresencePenalty:  &penalty,
+		FrequencyPenalty: &penalty,
+		Seed:             &seed,
+	}
+}
+
+func TestOpenAIProvider_Chat_Sampling(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"id":"c1","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"Hi"}}]}`, &captured)
+	defer server.Close()
+
+	req := samplingRequest("gpt-4o")
+	req.LogitBias = map[string]float64{"50256": -100}
+	provider := providers.NewOpenAIProvider("test-key", server.URL, zerolog.Nop())
+	if _, err := provider.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"top_p":             0.9,
+		"stop":              []interface{}{"END"},
+		"presence_penalty":  0.5,
+		"frequency_penalty": 0.5,
+		"seed":              float64(42),
+		"logit_bias":        map[string]interface{}{"50256": float64(-100)},
+	}
+	for key, value := range want {
+		if !reflect.DeepEqual(captured[key], value) {
+			t.Errorf("Expected %s %v, got %v", key, value, captured[key])
+		}
+	}
+}
+
+func TestAnthropicProvider_Chat_Sampling(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"id":"msg_1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":1,"output_tokens":1}}`, &captured)
+	defer server.Close()
+	provider := providers.NewAnthropicProvider("test-key", server.URL, zerolog.Nop())
+
+	req := samplingRequest("claude-sonnet-4-5")
+	req.PresencePenalty, req.FrequencyPenalty, req.Seed = nil, nil, nil
+	if _, err := provider.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if captured["top_p"] != 0.9 || !reflect.DeepEqual(captured["stop_sequences"], []interface{}{"END"}) {
+		t.Errorf("Expected top_p and stop_sequences, got %v", captured)
+	}
+
+	_, err := provider.Chat(context.Background(), samplingRequest("claude-sonnet-4-5"))
+	perr, ok := providers.AsProviderError(err)
+	if !ok || perr.Kind != providers.ErrorKindInvalidRequest || perr.HTTPStatus() != 400 {
+		t.Fatalf("Expected an invalid request error, got %v", err)
+	}
+	if perr.Message != "anthropic does not support presence_penalty, frequency_penalty, seed" {
+		t.Errorf("Unexpected message: %s", perr.Message)
+	}
+}
+
+func TestGoogleProvider_Chat_Sampling(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"candidates":[{"content":{"parts":[{"text":"Hi"}]},"finishReason":"STOP"}]}`, &captured)
+	defer server.Close()
+	provider := providers.NewGoogleProvider("test-key", server.URL, zerolog.Nop())
+
+	req := samplingRequest("gemini-2.5-flash")
+	temperature := 0.2
+	req.Temperature = &temperature
+	if _, err := provider.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	genConfig, _ := captured["generationConfig"].(map[string]interface{})
+	want := map[string]interface{}{
+		"temperature":      0.2,
+		"topP":             0.9,
+		"stopSequences":    []interface{}{"END"},
+		"presencePenalty":  0.5,
+		"frequencyPenalty": 0.5,
+		"seed":             float64(42),
+	}
+	if !reflect.DeepEqual(genConfig, want) {
+		t.Errorf("Expected generationConfig %v, got %v", want, genConfig)
+	}
+
+	req.LogitBias = map[string]float64{"1": 1}
+	if _, err := provider.Chat(context.Background(), req); err == nil {
+		t.Error("Expected logit_bias to be rejected")
+	}
+}
@@ -0,0 +1,270 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/providers"
+)
+
+var weatherTool = providers.Tool{
+	Type: "function",
+	Function: providers.ToolFunction{
+		Name:        "get_weather",
+		Description: "Get the weather for a city",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		},
+	},
+}
+
+// toolConversation is a user turn, an assistant tool call, and its result.
+func toolConversation() []providers.Message {
+	return []providers.Message{
+		{Role: "user", Content: "Weather in Lagos?"},
+		{Role: "assistant", Content: "", ToolCalls: []providers.ToolCall{
+			{ID: "call_1", Type: "function", Function: providers.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Lagos"}`}},
+		}},
+		{Role: "tool", ToolCallID: "call_1", Content: `{"temp_c":31}`},
+	}
+}
+
+// captureServer records the decoded request body and replies with response.
+func captureServer(t *testing.T, response string, captured *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, captured); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response)
+	}))
+}
+
+func TestToolChoice_JSON(t *testing.T) {
+	var choice providers.ToolChoice
+	if err := json.Unmarshal([]byte(`"required"`), &choice); err != nil || choice.Mode != providers.ToolChoiceRequired {
+		t.Errorf("Expected mode 'required', got %+v (err %v)", choice, err)
+	}
+	if err := json.Unmarshal([]byte(`{"type":"function","function":{"name":"get_weather"}}`), &choice); err != nil || choice.Function != "get_weather" {
+		t.Errorf("Expected function 'get_weather', got %+v (err %v)", choice, err)
+	}
+	if err := json.Unmarshal([]byte(`"sometimes"`), &choice); err == nil {
+		t.Error("Expected error for invalid tool_choice mode")
+	}
+
+	out, _ := json.Marshal(providers.ToolChoice{Function: "get_weather"})
+	if !strings.Contains(string(out), `"name":"get_weather"`) {
+		t.Errorf("Unexpected tool_choice encoding: %s", out)
+	}
+}
+
+func TestOpenAIProvider_Chat_Tools(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"id":"x","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_2","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Abuja\"}"}}]},"finish_reason":"tool_calls"}]}`, &captured)
+	defer server.Close()
+
+	provider := providers.NewOpenAIProvider("test-key", server.URL, zerolog.Nop())
+	resp, err := provider.Chat(context.Background(), providers.ChatRequest{
+		Model:      "gpt-4o",
+		Messages:   toolConversation(),
+		Tools:      []providers.Tool{weatherTool},
+		ToolChoice: &providers.ToolChoice{Mode: providers.ToolChoiceAuto},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if tools, _ := captured["tools"].([]interface{}); len(tools) != 1 {
+		t.Errorf("Expected 1 tool in request, got %v", captured["tools"])
+	}
+	if captured["tool_choice"] != "auto" {
+		t.Errorf("Expected tool_choice 'auto', got %v", captured["tool_choice"])
+	}
+	messages := captured["messages"].([]interface{})
+	if msg := messages[2].(map[string]interface{}); msg["tool_call_id"] != "call_1" {
+		t.Errorf("Expected tool_call_id on tool message, got %v", msg)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != providers.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"city":"Abuja"}` {
+		t.Errorf("Unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+}
+
+func TestAnthropicProvider_Chat_Tools(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"id":"msg_1","model":"claude-sonnet-4-5","stop_reason":"tool_use","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Abuja"}}],"usage":{"input_tokens":10,"output_tokens":5}}`, &captured)
+	defer server.Close()
+
+	provider := providers.NewAnthropicProvider("test-key", server.URL, zerolog.Nop())
+	resp, err := provider.Chat(context.Background(), providers.ChatRequest{
+		Model:      "claude-sonnet-4-5",
+		Messages:   toolConversation(),
+		Tools:      []providers.Tool{weatherTool},
+		ToolChoice: &providers.ToolChoice{Function: "get_weather"},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	tool := captured["tools"].([]interface{})[0].(map[string]interface{})
+	if tool["name"] != "get_weather" || tool["input_schema"] == nil {
+		t.Errorf("Unexpected Anthropic tool: %v", tool)
+	}
+	if choice := captured["tool_choice"].(map[string]interface{}); choice["type"] != "tool" || choice["name"] != "get_weather" {
+		t.Errorf("Unexpected tool_choice: %v", choice)
+	}
+	messages := captured["messages"].([]interface{})
+	assistant := messages[1].(map[string]interface{})["content"].([]interface{})
+	if block := assistant[0].(map[string]interface{}); block["type"] != "tool_use" || block["id"] != "call_1" {
+		t.Errorf("Expected tool_use block, got %v", block)
+	}
+	result := messages[2].(map[string]interface{})
+	if result["role"] != "user" {
+		t.Errorf("Expected tool result in a user turn, got role %v", result["role"])
+	}
+	if block := result["content"].([]interface{})[0].(map[string]interface{}); block["type"] != "tool_result" || block["tool_use_id"] != "call_1" {
+		t.Errorf("Expected tool_result block, got %v", block)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != providers.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "toolu_1" {
+		t.Errorf("Unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if choice.Message.Content != "Checking." {
+		t.Errorf("Expected text content to be kept, got %v", choice.Message.Content)
+	}
+}
+
+func TestGoogleProvider_Chat_Tools(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Abuja"}}}]},"finishReason":"STOP"}]}`, &captured)
+	defer server.Close()
+
+	provider := providers.NewGoogleProvider("test-key", server.URL, zerolog.Nop())
+	resp, err := provider.Chat(context.Background(), providers.ChatRequest{
+		Model:      "gemini-2.5-flash",
+		Messages:   toolConversation(),
+		Tools:      []providers.Tool{weatherTool},
+		ToolChoice: &providers.ToolChoice{Mode: providers.ToolChoiceRequired},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	tools := captured["tools"].([]interface{})
+	decls := tools[0].(map[string]interface{})["functionDeclarations"].([]interface{})
+	if len(decls) != 1 || decls[0].(map[string]interface{})["name"] != "get_weather" {
+		t.Errorf("Unexpected functionDeclarations: %v", decls)
+	}
+	config := captured["toolConfig"].(map[string]interface{})["functionCallingConfig"].(map[string]interface{})
+	if config["mode"] != "ANY" {
+		t.Errorf("Expected mode ANY, got %v", config["mode"])
+	}
+	contents := captured["contents"].([]interface{})
+	response := contents[2].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	if response["name"] != "get_weather" {
+		t.Errorf("Expected functionResponse named from the earlier call, got %v", response["name"])
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != providers.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"city":"Abuja"}` {
+		t.Errorf("Unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+}
+
+func TestLocalProvider_Chat_Tools(t *testing.T) {
+	var captured map[string]interface{}
+	server := captureServer(t, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Abuja"}}}]},"done":true}`, &captured)
+	defer server.Close()
+
+	provider := providers.NewLocalProvider(server.URL, zerolog.Nop())
+	resp, err := provider.Chat(context.Background(), providers.ChatRequest{
+		Model:    "llama3.1",
+		Messages: toolConversation(),
+		Tools:    []providers.Tool{weatherTool},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if tools, _ := captured["tools"].([]interface{}); len(tools) != 1 {
+		t.Errorf("Expected 1 tool in request, got %v", captured["tools"])
+	}
+	messages := captured["messages"].([]interface{})
+	if msg := messages[2].(map[string]interface{}); msg["tool_name"] != "get_weather" {
+		t.Errorf("Expected tool_name on tool message, got %v", msg)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != providers.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"city":"Abuja"}` {
+		t.Errorf("Unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+}
+
+func TestAnthropicProvider_ChatStream_ToolCallDeltas(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1"}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"Abuja\"}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range events {
+			io.WriteString(w, "data: "+ev+"\n\n")
+		}
+	}))
+	defer server.Close()
+
+	provider := providers.NewAnthropicProvider("test-key", server.URL, zerolog.Nop())
+	chunks, _ := provider.ChatStream(context.Background(), providers.ChatRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []providers.Message{{Role: "user", Content: "Weather in Abuja?"}},
+		Tools:    []providers.Tool{weatherTool},
+	})
+
+	var name, args, finishReason string
+	for chunk := range chunks {
+		for _, delta := range chunk.ToolCalls {
+			if delta.Function.Name != "" {
+				name = delta.Function.Name
+			}
+			args += delta.Function.Arguments
+		}
+		if chunk.Done {
+			finishReason = chunk.FinishReason
+		}
+	}
+
+	if name != "get_weather" || args != `{"city":"Abuja"}` {
+		t.Errorf("Unexpected streamed tool call: name=%q args=%q", name, args)
+	}
+	if finishReason != providers.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", finishReason)
+	}
+}
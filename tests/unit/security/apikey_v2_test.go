@@ -24,7 +24,7 @@ func newMockAPIKeyRepository() *mockAPIKeyRepository {
 	}
 }
 
-func (m *mockAPIKeyRepository) Create(ctx context.Context, key *storage.APIKey) error {
+func (m *mockAPIKeyRepository) Create(ctx context.Context, key *storage.APIKey, budgets []*storage.Budget) error {
 	m.keys[key.LookupHash] = key
 	return nil
 }
@@ -60,6 +60,28 @@ func (m *mockAPIKeyRepository) Update(ctx context.Context, key *storage.APIKey)
 	return nil
 }
 
+func (m *mockAPIKeyRepository) updateKey(userID, keyID uuid.UUID, update func(*storage.APIKey)) (*storage.APIKey, error) {
+	for _, key := range m.keys {
+		if key.ID == keyID && key.UserID == userID {
+			update(key)
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockAPIKeyRepository) UpdateCacheTTL(ctx context.Context, userID, keyID uuid.UUID, ttlSeconds int) (*storage.APIKey, error) {
+	return m.updateKey(userID, keyID, func(key *storage.APIKey) { key.CacheTTLSeconds = ttlSeconds })
+}
+
+func (m *mockAPIKeyRepository) UpdateHedgePercentile(ctx context.Context, userID, keyID uuid.UUID, percentile int) (*storage.APIKey, error) {
+	return m.updateKey(userID, keyID, func(key *storage.APIKey) { key.HedgePercentile = percentile })
+}
+
+func (m *mockAPIKeyRepository) UpdatePIIPolicy(ctx context.Context, userID, keyID uuid.UUID, policy *storage.PIIPolicy) (*storage.APIKey, error) {
+	return m.updateKey(userID, keyID, func(key *storage.APIKey) { key.PIIPolicy = policy })
+}
+
 func (m *mockAPIKeyRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	for hash, key := range m.keys {
 		if key.ID == id {
@@ -89,7 +111,7 @@ func TestAPIKeyServiceV2_CreateAPIKey(t *testing.T) {
 	rateLimitPerMinute := 60
 	rateLimitPerDay := 10000
 
-	key, apiKey, err := service.CreateAPIKey(ctx, userID, name, rateLimitPerMinute, rateLimitPerDay, nil)
+	key, apiKey, err := service.CreateAPIKey(ctx, userID, name, rateLimitPerMinute, rateLimitPerDay, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey returned error: %v", err)
 	}
@@ -130,7 +152,7 @@ func TestAPIKeyServiceV2_CreateAPIKey_WithExpiration(t *testing.T) {
 	userID := uuid.New()
 	expiresAt := time.Now().Add(24 * time.Hour)
 
-	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, &expiresAt)
+	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, &expiresAt, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey returned error: %v", err)
 	}
@@ -153,7 +175,7 @@ func TestAPIKeyServiceV2_ValidateAPIKey(t *testing.T) {
 	userID := uuid.New()
 
 	// Create an API key
-	key, _, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, nil)
+	key, _, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey returned error: %v", err)
 	}
@@ -215,7 +237,7 @@ func TestAPIKeyServiceV2_ValidateAPIKey_Inactive(t *testing.T) {
 	userID := uuid.New()
 
 	// Create an API key
-	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, nil)
+	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, nil, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey returned error: %v", err)
 	}
@@ -240,7 +262,7 @@ func TestAPIKeyServiceV2_ValidateAPIKey_Expired(t *testing.T) {
 	expiresAt := time.Now().Add(-1 * time.Hour) // Expired
 
 	// Create an expired API key
-	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, &expiresAt)
+	key, apiKey, err := service.CreateAPIKey(ctx, userID, "Test Key", 60, 10000, &expiresAt, security.APIKeySettings{})
 	if err != nil {
 		t.Fatalf("CreateAPIKey returned error: %v", err)
 	}
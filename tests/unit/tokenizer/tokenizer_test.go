@@ -0,0 +1,125 @@
+package tokenizer_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/Kizsoft-Solution-Limited/uniroute/internal/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCL100KPretokenizer_Split(t *testing.T) {
+	cases := map[string][]string{
+		"Hello world":    {"Hello", " world"},
+		"I'm here":       {"I", "'m", " here"},
+		"1234567":        {"123", "456", "7"},
+		"a  b":           {"a", " ", " b"},
+		"x\n\ny":         {"x", "\n\n", "y"},
+		"hi  ":           {"hi", "  "},
+		"end.\n":         {"end", ".\n"},
+		"foo(bar)":       {"foo", "(bar", ")"},
+		"  123":          {" ", " ", "123"},
+		"naïve café":     {"naïve", " café"},
+		"func main() {}": {"func", " main", "()", " {}"},
+	}
+	for input, want := range cases {
+		assert.Equal(t, want, tokenizer.CL100KPretokenizer.Split(input), input)
+	}
+}
+
+func TestO200KPretokenizer_SplitsCamelCase(t *testing.T) {
+	assert.Equal(t, []string{"Hello", "World"}, tokenizer.O200KPretokenizer.Split("HelloWorld"))
+	assert.Equal(t, []string{"don't"}, tokenizer.O200KPretokenizer.Split("don't"))
+}
+
+func tinyTable(merges ...string) string {
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, merge := range merges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	return sb.String()
+}
+
+func TestBPE_MergesByRank(t *testing.T) {
+	bpe, err := tokenizer.LoadTiktoken("tiny", strings.NewReader(tinyTable("he", "ll", "hell", " w")), tokenizer.CL100KPretokenizer)
+	require.NoError(t, err)
+
+	tokens := bpe.Encode("hello world")
+	// "hello" -> he + l + l + o -> he + ll + o -> hell + o; " world" -> " w" + o + r + l + d
+	assert.Equal(t, []int{258, 'o', 259, 'o', 'r', 'l', 'd'}, tokens)
+	assert.Equal(t, "hello world", bpe.Decode(tokens))
+	assert.Equal(t, 7, bpe.Count("hello world"))
+	assert.True(t, tokenizer.Exact(bpe))
+}
+
+func TestLoadTiktoken_RejectsIncompleteTable(t *testing.T) {
+	_, err := tokenizer.LoadTiktoken("broken", strings.NewReader("aGk= 0\n"), tokenizer.CL100KPretokenizer)
+	assert.Error(t, err)
+}
+
+func TestEstimator_Count(t *testing.T) {
+	e := tokenizer.NewEstimator("test", 6, nil)
+	assert.Equal(t, 2, e.Count("Hello world"))
+	assert.Equal(t, 4, e.Count("你好世界"))
+	assert.False(t, tokenizer.Exact(e))
+
+	long := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100)
+	count := e.Count(long)
+	assert.InDelta(t, 1000, count, 250, "English prose is roughly 10 tokens per sentence")
+}
+
+func TestRegistry_ForModel(t *testing.T) {
+	reg := tokenizer.NewRegistry()
+	assert.Equal(t, "gpt-estimate", reg.ForModel("gpt-4o").Name())
+	assert.Equal(t, "claude-estimate", reg.ForModel("claude-3-5-sonnet-20241022").Name())
+	assert.Equal(t, "gemini-estimate", reg.ForModel("gemini-1.5-pro").Name())
+	assert.Equal(t, "estimate", reg.ForModel("llama3:8b").Name())
+
+	bpe, err := tokenizer.LoadTiktoken(tokenizer.O200KBase, strings.NewReader(tinyTable()), tokenizer.O200KPretokenizer)
+	require.NoError(t, err)
+	reg.SetEncoding(tokenizer.O200KBase, bpe)
+	assert.Equal(t, tokenizer.O200KBase, reg.ForModel("gpt-4o-mini").Name())
+	assert.Equal(t, tokenizer.O200KBase, reg.ForModel("openai/o3-mini").Name())
+	assert.Equal(t, "gpt-estimate", reg.ForModel("gpt-4").Name(), "cl100k table not loaded")
+
+	local := tokenizer.NewEstimator("llama", 3, nil)
+	reg.Register("llama3*", local)
+	reg.Register("gpt-4o-custom", local)
+	assert.Equal(t, "llama", reg.ForModel("Llama3:8b").Name())
+	assert.Equal(t, "llama", reg.ForModel("gpt-4o-custom").Name())
+}
+
+func pngDataURL(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestImageTokens(t *testing.T) {
+	w, h, ok := tokenizer.ImageDimensions(pngDataURL(t, 512, 256))
+	require.True(t, ok)
+	assert.Equal(t, 512, w)
+	assert.Equal(t, 256, h)
+
+	_, _, ok = tokenizer.ImageDimensions("https://example.com/cat.png")
+	assert.False(t, ok)
+
+	assert.Equal(t, 255, tokenizer.TiledImageTokens(512, 512))
+	assert.Equal(t, 765, tokenizer.TiledImageTokens(1024, 1024))
+	assert.Equal(t, 1105, tokenizer.TiledImageTokens(4096, 2048))
+	assert.Equal(t, 350, tokenizer.ClaudeImageTokens(500, 525))
+
+	reg := tokenizer.NewRegistry()
+	assert.Equal(t, 258, tokenizer.ImageTokens(reg.ForModel("gemini-2.0-flash"), 1024, 1024))
+	assert.Equal(t, 765, tokenizer.ImageTokens(reg.ForModel("gpt-4o"), 1024, 1024))
+}